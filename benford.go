@@ -0,0 +1,188 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// DefaultMaxBenfordMAD is Nigrini's upper bound on the mean absolute
+// deviation of first-digit proportions for marginally acceptable
+// conformity, used when MaxMAD is zero.
+const DefaultMaxBenfordMAD = 0.015
+
+// DefaultBenfordMinCount is the fewest values a segment needs to be tested
+// when MinCount is zero.
+const DefaultBenfordMinCount = 100
+
+// Nigrini's first-digit conformity levels, as reported in Benford.Conformity.
+const (
+	ConformityClose         = "close"
+	ConformityAcceptable    = "acceptable"
+	ConformityMarginal      = "marginal"
+	ConformityNonconformity = "nonconformity"
+)
+
+// Benford is the first-digit distribution of a column, or of one segment of
+// it, tested against Benford's law.
+type Benford struct {
+	// Key is the segment's key value, for BenfordTest.TestGrouped.
+	Key   string `json:"key,omitempty"`
+	Count int64  `json:"count"`
+	// Digits holds the share of values with each first digit, 1 to 9, and
+	// Expected Benford's log10(1 + 1/d).
+	Digits   [9]float64 `json:"digits"`
+	Expected [9]float64 `json:"expected"`
+	// ChiSquare is the goodness-of-fit statistic with 8 degrees of freedom
+	// and PValue its p-value; MAD is the mean absolute deviation of the
+	// digit shares from Expected, graded by Conformity.
+	ChiSquare  float64 `json:"chi_square"`
+	PValue     float64 `json:"p_value"`
+	MAD        float64 `json:"mad"`
+	Conformity string  `json:"conformity"`
+	Deviates   bool    `json:"deviates"`
+}
+
+// BenfordTest checks whether the first significant digits of a numeric
+// column follow Benford's law, as naturally occurring amounts spanning
+// several orders of magnitude tend to; fabricated or manipulated figures
+// often do not. Zeros, nulls and non-finite values are left out, and signs
+// ignored. A column deviates when the chi-square p-value is below Alpha and
+// the MAD exceeds MaxMAD, so that the departure is both significant and
+// large enough to matter on large inputs.
+type BenfordTest struct {
+	// Alpha is the significance level; zero means DefaultAlpha.
+	Alpha float64
+	// MaxMAD bounds the mean absolute deviation; zero means
+	// DefaultMaxBenfordMAD.
+	MaxMAD float64
+	// MinCount is the fewest values a segment needs for TestGrouped to
+	// report it; zero means DefaultBenfordMinCount.
+	MinCount int64
+}
+
+// Test tests the first digits of col.
+func (t BenfordTest) Test(ctx context.Context, col arrow.Array) (*Benford, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	var counts [9]int64
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsValid(i) {
+			if d := firstDigit(floatCol.Value(i)); d > 0 {
+				counts[d-1]++
+			}
+		}
+	}
+	b := t.evaluate(counts)
+	if b.Count == 0 {
+		return nil, fmt.Errorf("benford: no nonzero values")
+	}
+	return b, nil
+}
+
+// TestGrouped tests the first digits of valueCol within each group of rec
+// sharing a keyCol value, ordered by key. Groups with fewer than MinCount
+// values are left out.
+func (t BenfordTest) TestGrouped(ctx context.Context, rec arrow.Record, valueCol, keyCol string) ([]Benford, error) {
+	vidx := rec.Schema().FieldIndices(valueCol)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", valueCol)
+	}
+	kidx := rec.Schema().FieldIndices(keyCol)
+	if len(kidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", keyCol)
+	}
+	floatCol, err := toFloat64(ctx, rec.Column(vidx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", valueCol, err)
+	}
+	defer floatCol.Release()
+	keys := rec.Column(kidx[0])
+
+	groups := make(map[string]*[9]int64)
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) || keys.IsNull(i) {
+			continue
+		}
+		d := firstDigit(floatCol.Value(i))
+		if d == 0 {
+			continue
+		}
+		k := keys.ValueStr(i)
+		c := groups[k]
+		if c == nil {
+			c = new([9]int64)
+			groups[k] = c
+		}
+		c[d-1]++
+	}
+	minCount := t.MinCount
+	if minCount <= 0 {
+		minCount = DefaultBenfordMinCount
+	}
+	out := make([]Benford, 0, len(groups))
+	for k, c := range groups {
+		b := t.evaluate(*c)
+		if b.Count < minCount {
+			continue
+		}
+		b.Key = k
+		out = append(out, *b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// evaluate tests first-digit counts against Benford's law.
+func (t BenfordTest) evaluate(counts [9]int64) *Benford {
+	b := &Benford{}
+	for _, c := range counts {
+		b.Count += c
+	}
+	if b.Count == 0 {
+		return b
+	}
+	n := float64(b.Count)
+	for d := range counts {
+		e := math.Log10(1 + 1/float64(d+1))
+		b.Expected[d] = e
+		b.Digits[d] = float64(counts[d]) / n
+		diff := float64(counts[d]) - n*e
+		b.ChiSquare += diff * diff / (n * e)
+		b.MAD += math.Abs(b.Digits[d]-e) / 9
+	}
+	b.PValue = chiSquareSF(b.ChiSquare, 8)
+	switch {
+	case b.MAD <= 0.006:
+		b.Conformity = ConformityClose
+	case b.MAD <= 0.012:
+		b.Conformity = ConformityAcceptable
+	case b.MAD <= 0.015:
+		b.Conformity = ConformityMarginal
+	default:
+		b.Conformity = ConformityNonconformity
+	}
+	maxMAD := t.MaxMAD
+	if maxMAD == 0 {
+		maxMAD = DefaultMaxBenfordMAD
+	}
+	b.Deviates = b.PValue < orDefaultAlpha(t.Alpha) && b.MAD > maxMAD
+	return b
+}
+
+// firstDigit returns the first significant digit of |x|, or 0 for zero and
+// non-finite values. It reads the shortest decimal representation, which
+// avoids the rounding of log10 near powers of ten.
+func firstDigit(x float64) int {
+	if x == 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+		return 0
+	}
+	return int(strconv.FormatFloat(math.Abs(x), 'e', -1, 64)[0] - '0')
+}
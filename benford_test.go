@@ -0,0 +1,75 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestFirstDigit(t *testing.T) {
+	for x, want := range map[float64]int{0.001: 1, 1000: 1, -467: 4, 9.99: 9, 0: 0, math.Inf(1): 0} {
+		if got := firstDigit(x); got != want {
+			t.Errorf("firstDigit(%v) = %d, want %d", x, got, want)
+		}
+	}
+	// The 5% critical value of chi-square with 8 degrees of freedom.
+	if p := chiSquareSF(15.507, 8); math.Abs(p-0.05) > 1e-4 {
+		t.Errorf("p = %v, want 0.05", p)
+	}
+}
+
+func TestBenford(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	rng := rand.New(rand.NewSource(1))
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "vendor", Type: arrow.BinaryTypes.String},
+		{Name: "amount", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	for i := 0; i < 5000; i++ {
+		// Log-uniform amounts over four decades follow Benford's law.
+		b.Field(0).(*array.StringBuilder).Append("honest")
+		b.Field(1).(*array.Float64Builder).Append(math.Pow(10, 4*rng.Float64()))
+		// Uniform amounts in [100, 1000) do not.
+		b.Field(0).(*array.StringBuilder).Append("fabricated")
+		b.Field(1).(*array.Float64Builder).Append(100 + 900*rng.Float64())
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := context.Background()
+	res, err := BenfordTest{}.TestGrouped(ctx, rec, "amount", "vendor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Key != "fabricated" || res[1].Key != "honest" {
+		t.Fatalf("groups = %+v", res)
+	}
+	if !res[0].Deviates || res[0].Conformity != ConformityNonconformity {
+		t.Errorf("fabricated: %+v", res[0])
+	}
+	if res[1].Deviates || res[1].Conformity != ConformityClose {
+		t.Errorf("honest: %+v", res[1])
+	}
+
+	all, err := BenfordTest{}.Test(ctx, rec.Column(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all.Count != 10000 || !all.Deviates {
+		t.Errorf("whole column: %+v", all)
+	}
+
+	res, err = BenfordTest{MinCount: 10001}.TestGrouped(ctx, rec, "amount", "vendor")
+	if err != nil || len(res) != 0 {
+		t.Errorf("groups below MinCount = %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,42 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// BoundedDetector flags values whose score under Base is at least Upper or
+// at most -Lower, in place of Base's symmetric threshold. A bound of zero or
+// less disables that side, so Upper alone flags only high outliers (such as
+// latency spikes) and Lower alone only low ones. Bounds are in Base's score
+// units: z-scores for the z-score, MAD, rolling and Hampel methods.
+type BoundedDetector struct {
+	Base         Detector
+	Upper, Lower float64
+}
+
+var _ Detector = BoundedDetector{}
+
+// Detect implements Detector.
+func (d BoundedDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if d.Upper <= 0 && d.Lower <= 0 {
+		return nil, fmt.Errorf("at least one of the upper and lower bounds must be positive")
+	}
+	base, err := d.Base.Detect(withScored(ctx), col)
+	if err != nil {
+		return nil, err
+	}
+	defer base.Release()
+
+	flag := make([]bool, base.Zscore.Len())
+	for i := range flag {
+		if base.Zscore.IsNull(i) {
+			continue
+		}
+		z := base.Zscore.Value(i)
+		flag[i] = (d.Upper > 0 && z >= d.Upper) || (d.Lower > 0 && z <= -d.Lower)
+	}
+	return reflag(ctx, col, base, flag)
+}
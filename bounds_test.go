@@ -0,0 +1,106 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestBoundedDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	// A spike at row 18 and a dip at row 19, both well past 2 standard
+	// deviations.
+	vals := []float64{10, 11, 10, 9, 10, 11, 10, 9, 10, 11, 10, 9, 10, 11, 10, 9, 10, 11, 30, -10}
+	b.AppendValues(vals, nil)
+	arr := b.NewFloat64Array()
+	defer arr.Release()
+
+	ctx := context.Background()
+	tests := []struct {
+		name         string
+		upper, lower float64
+		want         []int64
+	}{
+		{"high only", 2, 0, []int64{18}},
+		{"low only", 0, 2, []int64{19}},
+		{"asymmetric", 2, 100, []int64{18}},
+		{"both", 2, 2, []int64{18, 19}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := DetectAnomalies(ctx, arr, WithBounds(tt.upper, tt.lower))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Release()
+			got := res.AnomalousIndices()
+			if len(got) != len(tt.want) {
+				t.Fatalf("indices = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("indices = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	if _, err := NewDetector(WithBounds(3, 0), WithContamination(0.1)); err == nil {
+		t.Errorf("expected error combining bounds with contamination")
+	}
+}
+
+func TestBoundedNonNumeric(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// Short strings only, by length under the pattern method.
+	sb := array.NewStringBuilder(pool)
+	defer sb.Release()
+	for i := 0; i < 20; i++ {
+		sb.Append("abcde")
+	}
+	sb.AppendValues([]string{"ab", "abcdefghijklmnop"}, nil)
+	strs := sb.NewStringArray()
+	defer strs.Release()
+
+	// Rare categories of a boolean column, whose residuals are negative.
+	bb := array.NewBooleanBuilder(pool)
+	defer bb.Release()
+	for i := 0; i < 30; i++ {
+		bb.Append(true)
+	}
+	bb.Append(false)
+	bools := bb.NewBooleanArray()
+	defer bools.Release()
+
+	tests := []struct {
+		name   string
+		col    arrow.Array
+		method Method
+		want   int64
+		value  float64
+	}{
+		{"pattern", strs, MethodPattern, 20, 2},
+		{"category", bools, MethodCategory, 30, 1},
+	}
+	for _, tt := range tests {
+		res, err := DetectAnomalies(ctx, tt.col, WithMethod(tt.method), WithBounds(0, 2), WithAllocator(pool))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := res.AnomalousIndices(); len(got) != 1 || got[0] != tt.want || res.Values.Value(0) != tt.value {
+			t.Errorf("%s: indices = %v, values = %v, want row %d with value %v", tt.name, got, res.Values, tt.want, tt.value)
+		}
+		res.Release()
+	}
+}
@@ -0,0 +1,79 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// CategoryDetector flags rows of a categorical column, such as a string or
+// dictionary column, whose category is rare. Values of any type are compared
+// by their string form.
+//
+// Each row is scored by its category's Pearson residual (c - e) / sqrt(e),
+// where c is the category's count and e the count every category would have
+// if they were equally common; its square is the category's share of the
+// chi-square statistic. A row is flagged when its category was seen fewer
+// than MinCount times, or when the residual is at most -Threshold. Either
+// test is disabled by a zero value. On large inputs the residual flags any
+// significantly under-represented category, so MinCount is the better guide
+// to absolute rarity.
+//
+// The Result's Values hold each flagged row's category count; use
+// FilterOriginal for the categories themselves. Mean and StdDev describe
+// the per-row counts.
+type CategoryDetector struct {
+	MinCount  int64
+	Threshold float64
+}
+
+var _ Detector = CategoryDetector{}
+
+// Detect implements Detector.
+func (d CategoryDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if d.MinCount <= 0 && d.Threshold <= 0 {
+		return nil, fmt.Errorf("category detection needs a positive minimum count or threshold")
+	}
+	counts := make(map[string]int64)
+	var n int64
+	for i := 0; i < col.Len(); i++ {
+		if col.IsValid(i) {
+			counts[col.ValueStr(i)]++
+			n++
+		}
+	}
+	var expected float64
+	if len(counts) > 0 {
+		expected = float64(n) / float64(len(counts))
+	}
+
+	mem := compute.GetAllocator(ctx)
+	cb := array.NewFloat64Builder(mem)
+	defer cb.Release()
+	sb := array.NewFloat64Builder(mem)
+	defer sb.Release()
+	mb := array.NewBooleanBuilder(mem)
+	defer mb.Release()
+	var stats runningStats
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			cb.AppendNull()
+			sb.AppendNull()
+			mb.AppendNull()
+			continue
+		}
+		c := counts[col.ValueStr(i)]
+		residual := (float64(c) - expected) / math.Sqrt(expected)
+		cb.Append(float64(c))
+		sb.Append(residual)
+		mb.Append((d.MinCount > 0 && c < d.MinCount) || (d.Threshold > 0 && residual <= -d.Threshold))
+		stats.add(float64(c))
+	}
+	countCol := cb.NewFloat64Array()
+	defer countCol.Release()
+	return newResult(ctx, countCol, mb.NewBooleanArray(), sb.NewFloat64Array(), stats)
+}
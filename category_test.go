@@ -0,0 +1,58 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestCategoryDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewStringBuilder(pool)
+	defer b.Release()
+	for i := 0; i < 100; i++ {
+		b.Append([]string{"GET", "POST", "PUT"}[i%3])
+	}
+	b.Append("DELETE")
+	b.AppendNull()
+	b.Append("TRACE")
+	arr := b.NewStringArray()
+	defer arr.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	res, err := DetectAnomalies(ctx, arr, WithMethod(MethodCategory), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if got := res.AnomalousIndices(); len(got) != 2 || got[0] != 100 || got[1] != 102 {
+		t.Errorf("indices = %v, want [100 102]", got)
+	}
+	if res.Values.Value(0) != 1 || res.Nulls != 1 {
+		t.Errorf("values = %v, nulls = %d", res.Values, res.Nulls)
+	}
+
+	// A dictionary column, judged by count alone.
+	db := array.NewDictionaryBuilder(pool, &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String})
+	defer db.Release()
+	sb := db.(*array.BinaryDictionaryBuilder)
+	for _, v := range []string{"eu", "eu", "us", "us", "us", "ap"} {
+		sb.AppendString(v)
+	}
+	dict := db.NewArray()
+	defer dict.Release()
+	res2, err := DetectAnomalies(ctx, dict, WithMethod(MethodCategory), WithMinCount(2), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res2.Release()
+	if got := res2.AnomalousIndices(); len(got) != 1 || got[0] != 5 {
+		t.Errorf("dictionary indices = %v, want [5]", got)
+	}
+}
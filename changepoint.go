@@ -0,0 +1,247 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// DefaultCUSUMThreshold is the 5% critical value of the Kolmogorov
+// distribution, which the normalized CUSUM statistic follows when a segment
+// has no change.
+const DefaultCUSUMThreshold = 1.358
+
+// DefaultMinSegment is the shortest segment change-point detectors create
+// when MinSegment is zero.
+const DefaultMinSegment = 5
+
+// Segment describes the rows between two change points.
+type Segment struct {
+	// Start and End are row indices; End is exclusive.
+	Start  int64   `json:"start"`
+	End    int64   `json:"end"`
+	Count  int64   `json:"count"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"`
+}
+
+// ChangePoints is the output of a ChangePointDetector.
+type ChangePoints struct {
+	// Indices holds the first row of every segment after the first.
+	Indices  []int64   `json:"indices"`
+	Segments []Segment `json:"segments"`
+}
+
+// ChangePointDetector finds the rows of an ordered column where its
+// distribution changes. Nulls are skipped; indices refer to input rows.
+type ChangePointDetector interface {
+	ChangePoints(ctx context.Context, col arrow.Array) (*ChangePoints, error)
+}
+
+var (
+	_ ChangePointDetector = CUSUMDetector{}
+	_ ChangePointDetector = PELTDetector{}
+)
+
+// CUSUMDetector finds level shifts by binary segmentation on the cumulative
+// sum of deviations from the segment mean: a segment is split at the row
+// where the CUSUM peaks when the peak, normalized by sigma * sqrt(n), is at
+// least Threshold, and both halves are searched again. Each segment's sigma
+// is estimated from the MAD of its successive differences, which a level
+// shift barely affects.
+type CUSUMDetector struct {
+	// Threshold is the critical normalized CUSUM; zero means
+	// DefaultCUSUMThreshold.
+	Threshold float64
+	// MinSegment is the shortest segment to create; zero means
+	// DefaultMinSegment.
+	MinSegment int
+}
+
+// ChangePoints implements ChangePointDetector.
+func (d CUSUMDetector) ChangePoints(ctx context.Context, col arrow.Array) (*ChangePoints, error) {
+	rows, vals, err := orderedValues(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = DefaultCUSUMThreshold
+	}
+	minSeg := orDefaultMinSegment(d.MinSegment)
+
+	var splits []int
+	var search func(lo, hi int)
+	search = func(lo, hi int) {
+		n := hi - lo
+		if n < 2*minSeg {
+			return
+		}
+		sigma := diffSigma(vals[lo:hi])
+		if sigma == 0 {
+			return
+		}
+		var mean float64
+		for _, v := range vals[lo:hi] {
+			mean += v
+		}
+		mean /= float64(n)
+		best, peak, sum := -1, 0.0, 0.0
+		for k := lo; k < hi-1; k++ {
+			sum += vals[k] - mean
+			if k+1-lo >= minSeg && hi-k-1 >= minSeg && math.Abs(sum) > peak {
+				best, peak = k+1, math.Abs(sum)
+			}
+		}
+		if best < 0 || peak/(sigma*math.Sqrt(float64(n))) < threshold {
+			return
+		}
+		search(lo, best)
+		splits = append(splits, best)
+		search(best, hi)
+	}
+	search(0, len(vals))
+	return segmentsAt(rows, vals, col.Len(), splits), nil
+}
+
+// PELTDetector finds changes in mean and variance with the Pruned Exact
+// Linear Time algorithm (Killick et al., 2012), minimizing the Gaussian
+// negative log-likelihood of the segments plus Penalty per change point.
+type PELTDetector struct {
+	// Penalty is the cost of each change point; zero means the modified
+	// BIC penalty 3 * ln(n).
+	Penalty float64
+	// MinSegment is the shortest segment to create (at least 2); zero means
+	// DefaultMinSegment.
+	MinSegment int
+}
+
+// ChangePoints implements ChangePointDetector.
+func (d PELTDetector) ChangePoints(ctx context.Context, col arrow.Array) (*ChangePoints, error) {
+	rows, vals, err := orderedValues(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	n := len(vals)
+	minSeg := max(2, orDefaultMinSegment(d.MinSegment))
+	penalty := d.Penalty
+	if penalty == 0 {
+		penalty = 3 * math.Log(math.Max(float64(n), 2))
+	}
+
+	// Prefix sums give each segment's variance in constant time.
+	s1, s2 := make([]float64, n+1), make([]float64, n+1)
+	var total runningStats
+	for i, v := range vals {
+		s1[i+1], s2[i+1] = s1[i]+v, s2[i]+v*v
+		total.add(v)
+	}
+	// Floor variances so constant segments do not cost -Inf.
+	floor := math.Max(total.variance()*1e-6, 1e-12)
+	cost := func(a, b int) float64 {
+		m := float64(b - a)
+		sum := s1[b] - s1[a]
+		v := math.Max((s2[b]-s2[a]-sum*sum/m)/m, floor)
+		return m * math.Log(v)
+	}
+
+	f := make([]float64, n+1)
+	last := make([]int, n+1)
+	f[0] = -penalty
+	candidates := []int{0}
+	for t := minSeg; t <= n; t++ {
+		f[t] = math.Inf(1)
+		for _, tau := range candidates {
+			if t-tau < minSeg {
+				continue
+			}
+			if c := f[tau] + cost(tau, t) + penalty; c < f[t] {
+				f[t], last[t] = c, tau
+			}
+		}
+		kept := candidates[:0]
+		for _, tau := range candidates {
+			if t-tau < minSeg || f[tau]+cost(tau, t) <= f[t] {
+				kept = append(kept, tau)
+			}
+		}
+		candidates = append(kept, t)
+	}
+
+	var splits []int
+	if n >= minSeg {
+		for t := last[n]; t > 0; t = last[t] {
+			splits = append([]int{t}, splits...)
+		}
+	}
+	return segmentsAt(rows, vals, col.Len(), splits), nil
+}
+
+// diffSigma estimates the noise standard deviation of vals from the MAD of
+// its first differences.
+func diffSigma(vals []float64) float64 {
+	diffs := make([]float64, 0, len(vals))
+	for i := 1; i < len(vals); i++ {
+		diffs = append(diffs, vals[i]-vals[i-1])
+	}
+	if len(diffs) == 0 {
+		return 0
+	}
+	med := medianInPlace(append([]float64(nil), diffs...))
+	for i, v := range diffs {
+		diffs[i] = math.Abs(v - med)
+	}
+	return DefaultMADScale * medianInPlace(diffs) / math.Sqrt2
+}
+
+func orDefaultMinSegment(n int) int {
+	if n <= 0 {
+		return DefaultMinSegment
+	}
+	return n
+}
+
+// orderedValues returns the non-null values of col and their row indices.
+func orderedValues(ctx context.Context, col arrow.Array) ([]int64, []float64, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer floatCol.Release()
+	if floatCol.Len()-floatCol.NullN() == 0 {
+		return nil, nil, fmt.Errorf("no values")
+	}
+	rows := make([]int64, 0, floatCol.Len()-floatCol.NullN())
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsValid(i) {
+			rows = append(rows, int64(i))
+		}
+	}
+	return rows, nonNullValues(floatCol), nil
+}
+
+// segmentsAt builds ChangePoints from split positions into vals, mapping them
+// back to rows of an input of length n.
+func segmentsAt(rows []int64, vals []float64, n int, splits []int) *ChangePoints {
+	cp := &ChangePoints{}
+	bounds := append(append([]int{0}, splits...), len(vals))
+	for i := 0; i+1 < len(bounds); i++ {
+		lo, hi := bounds[i], bounds[i+1]
+		var s runningStats
+		for _, v := range vals[lo:hi] {
+			s.add(v)
+		}
+		seg := Segment{End: int64(n), Count: s.n, Mean: s.mean, StdDev: s.stdDev()}
+		if i > 0 {
+			seg.Start = rows[lo]
+			cp.Indices = append(cp.Indices, rows[lo])
+		}
+		if hi < len(vals) {
+			seg.End = rows[hi]
+		}
+		cp.Segments = append(cp.Segments, seg)
+	}
+	return cp
+}
@@ -0,0 +1,79 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// shiftedSeries returns 300 noisy values whose mean steps from 0 to 5 at row
+// 100 and whose spread widens tenfold at row 200.
+func shiftedSeries(t *testing.T) *array.Float64 {
+	t.Helper()
+	r := rand.New(rand.NewSource(3))
+	b := array.NewFloat64Builder(memory.NewGoAllocator())
+	defer b.Release()
+	for i := 0; i < 300; i++ {
+		switch {
+		case i < 100:
+			b.Append(r.NormFloat64())
+		case i < 200:
+			b.Append(5 + r.NormFloat64())
+		default:
+			b.Append(5 + 10*r.NormFloat64())
+		}
+	}
+	return b.NewFloat64Array()
+}
+
+func near(got, want, tol int64) bool {
+	return math.Abs(float64(got-want)) <= float64(tol)
+}
+
+func TestCUSUMDetector(t *testing.T) {
+	col := shiftedSeries(t)
+	defer col.Release()
+
+	head := array.NewSlice(col, 0, 200)
+	defer head.Release()
+	cp, err := CUSUMDetector{}.ChangePoints(context.Background(), head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Indices) != 1 || !near(cp.Indices[0], 100, 2) {
+		t.Fatalf("indices = %v, want [~100]", cp.Indices)
+	}
+	if len(cp.Segments) != 2 || cp.Segments[0].Start != 0 || cp.Segments[1].End != 200 {
+		t.Fatalf("segments = %+v", cp.Segments)
+	}
+	if m := cp.Segments[1].Mean; math.Abs(m-5) > 0.5 {
+		t.Errorf("second segment mean = %v, want ~5", m)
+	}
+}
+
+func TestPELTDetector(t *testing.T) {
+	col := shiftedSeries(t)
+	defer col.Release()
+
+	cp, err := PELTDetector{}.ChangePoints(context.Background(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cp.Indices) != 2 || !near(cp.Indices[0], 100, 2) || !near(cp.Indices[1], 200, 5) {
+		t.Fatalf("indices = %v, want [~100 ~200]", cp.Indices)
+	}
+	if s := cp.Segments[2].StdDev; s < 5 {
+		t.Errorf("last segment stddev = %v, want ~10", s)
+	}
+	var total int64
+	for _, seg := range cp.Segments {
+		total += seg.Count
+	}
+	if total != 300 {
+		t.Errorf("segments cover %d values, want 300", total)
+	}
+}
@@ -0,0 +1,244 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// fitted is a center, scale and threshold estimated over a whole column,
+// against which each chunk can be scored on its own.
+type fitted struct {
+	center, scale, threshold float64
+	stats                    runningStats
+}
+
+// fitter is implemented by detectors whose scores are (x - center) / scale
+// for a center and scale fitted once over the input. They can score a
+// chunked column chunk by chunk instead of concatenating it.
+type fitter interface {
+	fit(ctx context.Context, cols []*array.Float64) (fitted, error)
+}
+
+var (
+	_ fitter = ZScoreDetector{}
+	_ fitter = MADDetector{}
+	_ fitter = IQRDetector{}
+	_ fitter = PercentileDetector{}
+)
+
+func (d ZScoreDetector) fit(ctx context.Context, cols []*array.Float64) (fitted, error) {
+	s := accumulateAll(ctx, cols)
+	return fitted{center: s.mean, scale: math.Sqrt(s.variance()), threshold: d.Threshold, stats: s}, nil
+}
+
+// ChunkedResult holds the Results of detection over a chunked column, one per
+// chunk, all scored against statistics of the whole column. Each chunk's
+// Indices are relative to that chunk; use Indices for positions in the
+// column.
+type ChunkedResult struct {
+	Chunks []*Result
+	// Offsets holds the row offset of each chunk within the column.
+	Offsets []int64
+	// unmap frees the chunks spilled under WithMemoryLimit, which the
+	// results may share buffers with.
+	unmap []func()
+}
+
+// Release frees memory associated with the ChunkedResult.
+func (r *ChunkedResult) Release() {
+	for _, c := range r.Chunks {
+		if c != nil {
+			c.Release()
+		}
+	}
+	for _, u := range r.unmap {
+		u()
+	}
+	r.unmap = nil
+}
+
+// Indices returns the flagged row positions across all chunks, in order.
+func (r *ChunkedResult) Indices() []int64 {
+	var out []int64
+	for i, c := range r.Chunks {
+		for _, idx := range c.Indices.Int64Values() {
+			out = append(out, r.Offsets[i]+idx)
+		}
+	}
+	return out
+}
+
+// DetectAnomaliesChunked is DetectAnomalies for a chunked column. See
+// DetectChunked.
+func DetectAnomaliesChunked(ctx context.Context, col *arrow.Chunked, opts ...Option) (*ChunkedResult, error) {
+	o := newOptions(opts)
+	d, err := o.detector()
+	if err != nil {
+		return nil, err
+	}
+	return DetectChunked(o.context(ctx), d, col)
+}
+
+// DetectChunked runs d over a chunked column. For the z-score, MAD, IQR and
+// percentile detectors statistics are gathered across the chunks and each
+// chunk is scored in place, so the column is never concatenated. Other
+// detectors need the whole series at once; for them the chunks are
+// concatenated and the ChunkedResult holds a single chunk. Under
+// ContextWithParallelism chunks are cast, summarized and scored on a pool of
+// workers, and their partial statistics merged. Under ContextWithMemoryLimit
+// cast chunks may be spilled to disk; see WithMemoryLimit.
+func DetectChunked(ctx context.Context, d Detector, col *arrow.Chunked) (*ChunkedResult, error) {
+	ctx, end := traceDetect(ctx, "DetectChunked", d, col.Len())
+	res, err := detectChunked(ctx, d, col)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	anomalies := 0
+	for _, c := range res.Chunks {
+		anomalies += c.Indices.Len()
+	}
+	end(anomalies, nil)
+	return res, nil
+}
+
+func detectChunked(ctx context.Context, d Detector, col *arrow.Chunked) (*ChunkedResult, error) {
+	reserve := int64(col.Len()) * scoreBytesPerRow
+	var (
+		mu    sync.Mutex
+		unmap []func()
+	)
+	// Spilled chunks stay mapped until the results are released, or until
+	// detection fails.
+	done := false
+	defer func() {
+		if !done {
+			for _, u := range unmap {
+				u()
+			}
+		}
+	}()
+
+	if z, ok := d.(ZScoreDetector); ok {
+		if res, ok, err := detectFloat32Chunked(ctx, z, col); ok {
+			return res, err
+		}
+	}
+	f, ok := d.(fitter)
+	if !ok {
+		concat, err := array.Concatenate(col.Chunks(), compute.GetAllocator(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("concatenate chunks: %w", err)
+		}
+		concat, u, err := spillIfTight(ctx, concat, reserve)
+		if err != nil {
+			return nil, err
+		}
+		if u != nil {
+			unmap = append(unmap, u)
+		}
+		defer concat.Release()
+		if err := checkMemory(ctx, reserve); err != nil {
+			return nil, err
+		}
+		res, err := d.Detect(ctx, concat)
+		if err == nil {
+			err = checkMemory(ctx, 0)
+		}
+		if err != nil {
+			if res != nil {
+				res.Release()
+			}
+			return nil, err
+		}
+		res.Inexact = inexactInts(concat)
+		done = true
+		return &ChunkedResult{Chunks: []*Result{res}, Offsets: []int64{0}, unmap: unmap}, nil
+	}
+
+	workers := parallelismFrom(ctx)
+	chunks := make([]*array.Float64, len(col.Chunks()))
+	defer func() {
+		for _, c := range chunks {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	err := parallelFor(workers, len(chunks), func(i int) error {
+		if col.Chunk(i).DataType().ID() != arrow.FLOAT64 {
+			if err := checkMemory(ctx, int64(col.Chunk(i).Len()*arrow.Float64SizeBytes)); err != nil {
+				return err
+			}
+		}
+		fc, err := toFloat64(ctx, col.Chunk(i))
+		if err != nil || arrow.Array(fc) == col.Chunk(i) {
+			chunks[i] = fc
+			return err
+		}
+		// Only a copy made by the cast is worth spilling.
+		spilled, u, err := spillIfTight(ctx, fc, reserve)
+		if err != nil {
+			fc.Release()
+			return err
+		}
+		if u != nil {
+			mu.Lock()
+			unmap = append(unmap, u)
+			mu.Unlock()
+		}
+		chunks[i] = spilled.(*array.Float64)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	fit, err := f.fit(ctx, chunks)
+	if err != nil {
+		return nil, err
+	}
+	var exact *exactSums
+	if z, ok := d.(ZScoreDetector); ok && exactIntegersFrom(ctx) && wideInt(col.DataType()) {
+		exact = sumExact(ctx, col.Chunks())
+		fit = z.fitExact(exact, fit.stats.n)
+	}
+
+	out := &ChunkedResult{Chunks: make([]*Result, len(chunks)), Offsets: make([]int64, len(chunks))}
+	var offset int64
+	for i, c := range chunks {
+		out.Offsets[i] = offset
+		offset += int64(c.Len())
+	}
+	err = parallelFor(workers, len(chunks), func(i int) error {
+		if err := checkMemory(ctx, int64(chunks[i].Len())*scoreBytesPerRow); err != nil {
+			return err
+		}
+		var (
+			res *Result
+			err error
+		)
+		if exact != nil {
+			res, err = scoreExact(ctx, col.Chunk(i), chunks[i], exact, fit)
+		} else {
+			res, err = scoreAgainst(ctx, chunks[i], fit.center, fit.scale, fit.threshold, fit.stats)
+		}
+		if err != nil {
+			return err
+		}
+		res.Inexact = inexactInts(col.Chunk(i))
+		out.Chunks[i] = res
+		return checkMemory(ctx, 0)
+	})
+	if err != nil {
+		out.Release()
+		return nil, err
+	}
+	out.unmap, done = unmap, true
+	return out, nil
+}
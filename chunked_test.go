@@ -0,0 +1,69 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestDetectChunked(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	vals := []float64{10, 11, 9, 10, 12, 10, 9, 11, 60, 10, 11, 9}
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues(vals, nil)
+	whole := b.NewFloat64Array()
+	defer whole.Release()
+
+	parts := []arrow.Array{
+		array.NewSlice(whole, 0, 5),
+		array.NewSlice(whole, 5, 9),
+		array.NewSlice(whole, 9, 12),
+	}
+	chunked := arrow.NewChunked(arrow.PrimitiveTypes.Float64, parts)
+	defer chunked.Release()
+	for _, p := range parts {
+		p.Release()
+	}
+
+	for _, d := range []Detector{
+		ZScoreDetector{Threshold: 3},
+		MADDetector{Threshold: 3},
+		IQRDetector{},
+		PercentileDetector{Q: 0.95},
+		HampelDetector{HalfWindow: 2, Threshold: 3},
+	} {
+		want, err := d.Detect(ctx, whole)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DetectChunked(ctx, d, chunked)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantIdx := want.Indices.Int64Values()
+		gotIdx := got.Indices()
+		if len(gotIdx) != len(wantIdx) {
+			t.Errorf("%T: flagged %v, want %v", d, gotIdx, wantIdx)
+		} else {
+			for i := range wantIdx {
+				if gotIdx[i] != wantIdx[i] {
+					t.Errorf("%T: flagged %v, want %v", d, gotIdx, wantIdx)
+					break
+				}
+			}
+		}
+		if got.Chunks[0].Mean != want.Mean {
+			t.Errorf("%T: mean %v, want %v", d, got.Chunks[0].Mean, want.Mean)
+		}
+		want.Release()
+		got.Release()
+	}
+}
@@ -0,0 +1,162 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// CleanColumn names the column of cleaned values written next to
+// ScoreColumn and AnomalyColumn.
+const CleanColumn = "cleaned"
+
+// CleanMode says how Clean replaces the flagged values of a column.
+type CleanMode string
+
+// Supported clean modes. The replacements are computed from the unflagged,
+// finite values, the ones the detector accepted.
+const (
+	// CleanClip winsorizes: flagged values above the largest unflagged
+	// value are set to it, and those below the smallest to that. This is
+	// the range of the data kept, not the detector's boundary of center ±
+	// threshold × scale, which lies beyond it unless a value sits right at
+	// the threshold. Values flagged by a local method such as rolling or
+	// hampel may lie within that range and are kept.
+	CleanClip CleanMode = "clip"
+	// CleanNull replaces flagged values with null.
+	CleanNull CleanMode = "null"
+	// CleanMean and CleanMedian replace flagged values with the mean or
+	// median of the unflagged ones.
+	CleanMean   CleanMode = "mean"
+	CleanMedian CleanMode = "median"
+	// CleanInterpolate replaces each flagged value by linear interpolation
+	// between the nearest unflagged rows before and after it, or the
+	// nearest one at either end, for columns in time order.
+	CleanInterpolate CleanMode = "interpolate"
+)
+
+func (m CleanMode) validate() error {
+	switch m {
+	case CleanClip, CleanNull, CleanMean, CleanMedian, CleanInterpolate:
+		return nil
+	default:
+		return fmt.Errorf("unknown clean mode %q", m)
+	}
+}
+
+// Clean returns a float64 copy of col with the rows res flags replaced as
+// mode says, for writing a cleaned column alongside the anomalies or
+// feeding it to later stages. res must come from col. Unflagged rows keep
+// their value, or null; a flagged row is null when there is nothing to
+// replace it with, such as a flagged null under CleanClip or a column with
+// no unflagged values. The caller must Release the result.
+func Clean(ctx context.Context, col arrow.Array, res *Result, mode CleanMode) (*array.Float64, error) {
+	if err := mode.validate(); err != nil {
+		return nil, err
+	}
+	if col.Len() != res.Mask.Len() {
+		return nil, fmt.Errorf("column has %d rows, result has %d", col.Len(), res.Mask.Len())
+	}
+	floatCol, err := castFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+
+	flagged := func(i int) bool { return res.Mask.IsValid(i) && res.Mask.Value(i) }
+	n := floatCol.Len()
+	vals := append([]float64(nil), floatCol.Float64Values()...)
+	valid := make([]bool, n)
+	keep := make([]bool, n)
+	var kept []float64
+	for i, v := range vals {
+		valid[i] = floatCol.IsValid(i)
+		keep[i] = valid[i] && !isNonFinite(v) && !flagged(i)
+		if keep[i] {
+			kept = append(kept, v)
+		}
+	}
+
+	switch mode {
+	case CleanClip:
+		lo, hi := math.Inf(1), math.Inf(-1)
+		for _, v := range kept {
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		for i := range vals {
+			if flagged(i) {
+				valid[i] = valid[i] && len(kept) > 0 && !math.IsNaN(vals[i])
+				vals[i] = min(max(vals[i], lo), hi)
+			}
+		}
+	case CleanNull:
+		for i := range vals {
+			if flagged(i) {
+				valid[i] = false
+			}
+		}
+	case CleanMean, CleanMedian:
+		var fill float64
+		if mode == CleanMean {
+			var s runningStats
+			for _, v := range kept {
+				s.add(v)
+			}
+			fill = s.mean
+		} else if len(kept) > 0 {
+			fill = medianInPlace(kept)
+		}
+		for i := range vals {
+			if flagged(i) {
+				vals[i], valid[i] = fill, len(kept) > 0
+			}
+		}
+	case CleanInterpolate:
+		interpolate(vals, valid, keep, flagged)
+	}
+
+	b := array.NewFloat64Builder(compute.GetAllocator(ctx))
+	defer b.Release()
+	b.AppendValues(vals, valid)
+	return b.NewFloat64Array(), nil
+}
+
+// interpolate sets each flagged row of vals by linear interpolation between
+// the nearest kept rows on either side, or the nearest kept row at an end,
+// clearing valid when there is none.
+func interpolate(vals []float64, valid, keep []bool, flagged func(int) bool) {
+	n := len(vals)
+	next := make([]int, n+1)
+	next[n] = n
+	for i := n - 1; i >= 0; i-- {
+		next[i] = next[i+1]
+		if keep[i] {
+			next[i] = i
+		}
+	}
+	prev := -1
+	for i := 0; i < n; i++ {
+		if keep[i] {
+			prev = i
+			continue
+		}
+		if !flagged(i) {
+			continue
+		}
+		switch q := next[i]; {
+		case prev >= 0 && q < n:
+			frac := float64(i-prev) / float64(q-prev)
+			vals[i], valid[i] = vals[prev]+frac*(vals[q]-vals[prev]), true
+		case prev >= 0:
+			vals[i], valid[i] = vals[prev], true
+		case q < n:
+			vals[i], valid[i] = vals[q], true
+		default:
+			valid[i] = false
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestClean(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// Rows 0 and 4 are flagged, row 2 is a null left alone.
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{100, 2, 0, 4, -50, 6}, []bool{true, true, false, true, true, true})
+	col := b.NewFloat64Array()
+	defer col.Release()
+	mb := array.NewBooleanBuilder(pool)
+	defer mb.Release()
+	mb.AppendValues([]bool{true, false, false, false, true, false}, nil)
+	res := &Result{Mask: mb.NewBooleanArray()}
+	defer res.Mask.Release()
+
+	null := math.NaN()
+	tests := []struct {
+		mode CleanMode
+		want []float64
+	}{
+		{CleanClip, []float64{6, 2, null, 4, 2, 6}},
+		{CleanNull, []float64{null, 2, null, 4, null, 6}},
+		{CleanMean, []float64{4, 2, null, 4, 4, 6}},
+		{CleanMedian, []float64{4, 2, null, 4, 4, 6}},
+		{CleanInterpolate, []float64{2, 2, null, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		got, err := Clean(ctx, col, res, tt.mode)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.mode, err)
+		}
+		for i, want := range tt.want {
+			if math.IsNaN(want) != got.IsNull(i) || (got.IsValid(i) && got.Value(i) != want) {
+				t.Errorf("%s: row %d = %v (valid %v), want %v", tt.mode, i, got.Value(i), got.IsValid(i), want)
+			}
+		}
+		got.Release()
+	}
+
+	if _, err := Clean(ctx, col, res, "trim"); err == nil {
+		t.Error("unknown mode: want error")
+	}
+	short := array.NewSlice(col, 0, 3)
+	defer short.Release()
+	if _, err := Clean(ctx, short, res, CleanNull); err == nil {
+		t.Error("length mismatch: want error")
+	}
+}
+
+func TestCleanDetected(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewInt64Builder(pool)
+	defer b.Release()
+	for i := 0; i < 100; i++ {
+		b.Append(int64(i % 5))
+	}
+	b.Append(1000)
+	col := b.NewInt64Array()
+	defer col.Release()
+	res, err := DetectAnomalies(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	cleaned, err := Clean(ctx, col, res, CleanClip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleaned.Release()
+	if got := cleaned.Value(100); got != 4 {
+		t.Errorf("clipped outlier = %v, want 4", got)
+	}
+	if got := cleaned.Value(3); got != 3 {
+		t.Errorf("unflagged row = %v, want 3", got)
+	}
+}
@@ -4,83 +4,1131 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 
 	anomaly "github.com/TFMV/supercharged"
-	"github.com/TFMV/supercharged/csvreader"
-	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/TFMV/supercharged/sink"
 )
 
 var analyzeCmd = &cobra.Command{
 	Use:   "analyze",
-	Short: "Run anomaly detection on a CSV column",
+	Short: "Run anomaly detection on a CSV or Parquet column",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		path := viper.GetString("file")
-		if path == "" {
-			return fmt.Errorf("--file is required")
+		if entries := columnEntries(); entries != nil {
+			viper.Set("columns", append(viper.GetStringSlice("columns"), entries...))
+			viper.Set("column", "")
+		}
+		if !viper.GetBool("merge") {
+			ds, err := batchInputs()
+			if err != nil {
+				return err
+			}
+			if ds != nil {
+				return analyzeFiles(ds)
+			}
+		}
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		if columns := viper.GetStringSlice("columns"); len(columns) > 0 {
+			return analyzeColumns(src, columns, false)
 		}
 		column := viper.GetString("column")
 		if column == "" {
-			return fmt.Errorf("--column is required")
+			columns := numericColumns(src.Schema())
+			if len(columns) == 0 {
+				return fmt.Errorf("no numeric columns to analyze; set --column")
+			}
+			return analyzeColumns(src, columns, true)
 		}
 
-		f, err := os.Open(path)
-		if err != nil {
-			return fmt.Errorf("open: %w", err)
+		if bucket := viper.GetDuration("bucket"); bucket > 0 {
+			out, err := analyzeBucketed(src, column, viper.GetString("time-column"), bucket, viper.GetInt("period"))
+			if err != nil {
+				return err
+			}
+			return writeOutput(out)
+		}
+
+		if key := viper.GetString("group-by"); key != "" {
+			out, err := analyzeGrouped(src, column, key, viper.GetString("time-column"), viper.GetFloat64("threshold"))
+			if err != nil {
+				return err
+			}
+			return writeOutput(out)
+		}
+
+		if period := viper.GetInt("period"); period > 0 {
+			out, err := analyzeSeasonal(src, column, viper.GetString("time-column"), period)
+			if err != nil {
+				return err
+			}
+			return writeOutput(out)
+		}
+
+		output, format := viper.GetString("output"), viper.GetString("output-format")
+		if output == "" && format != "" {
+			output = "-"
+		}
+		if output != "" && !ndjsonOutput() {
+			if viper.GetBool("stream") || viper.GetBool("context") {
+				return fmt.Errorf("--output cannot be combined with --stream or --context")
+			}
+			out, err := writeRows(src, column, output, format)
+			if err != nil {
+				return err
+			}
+			if output == "-" {
+				// The rows went to stdout; keep it a clean stream.
+				return checkBudget(out.flagged, out.Count)
+			}
+			return writeOutput(out)
+		}
+
+		if viper.GetBool("context") {
+			if viper.GetBool("stream") {
+				return fmt.Errorf("--context cannot be combined with --stream")
+			}
+			return analyzeRows(src, column)
+		}
+
+		if viper.GetBool("stream") {
+			out, err := streamColumn(src, column, viper.GetString("time-column"), viper.GetFloat64("threshold"))
+			if err != nil {
+				return err
+			}
+			return writeOutput(out)
+		}
+
+		if timeColumn := viper.GetString("time-column"); timeColumn != "" {
+			out, err := analyzeTimed(src, column, timeColumn)
+			if err != nil {
+				return err
+			}
+			return writeOutput(out)
 		}
-		defer f.Close()
 
-		schema, err := csvreader.InferSchemaFromCSV(f)
+		out, err := analyzeWhole(src, column)
 		if err != nil {
-			return fmt.Errorf("infer: %w", err)
+			return err
+		}
+		return writeOutput(out)
+	},
+}
+
+// analyzeWhole scores column of src, read whole in its record batches.
+func analyzeWhole(src source, column string) (analyzeOutput, error) {
+	col, err := src.ReadChunked(commandCtx, column)
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("read column: %w", err)
+	}
+	defer col.Release()
+
+	detector, err := newDetector(viper.GetString("method"))
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	res, err := anomaly.DetectChunked(detectContext(), detector, col)
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+
+	out := analyzeOutput{Count: int64(col.Len())}
+	for i, r := range res.Chunks {
+		out.add(r, res.Offsets[i])
+	}
+	for _, c := range col.Chunks() {
+		out.summarize(c)
+	}
+	if viper.GetFloat64("contamination") > 0 {
+		out.rank()
+	}
+	return out, nil
+}
+
+// outputSchemaVersion versions the analyze JSON output. Version 2 added
+// schema_version, summary and rows.
+const outputSchemaVersion = 2
+
+type analyzeOutput struct {
+	SchemaVersion int   `json:"schema_version"`
+	Count         int64 `json:"count"`
+	// Summary describes the scored values; it is omitted for non-numeric
+	// columns.
+	Summary *outputSummary `json:"summary,omitempty"`
+	// Anomalies holds the score of each flagged row, as Rows does.
+	Anomalies []score     `json:"anomalies"`
+	Rows      []outputRow `json:"rows"`
+	PValues   []float64   `json:"p_values,omitempty"`
+	Nulls     int64       `json:"nulls,omitempty"`
+	NaNs      int64       `json:"nans,omitempty"`
+	Infs      int64       `json:"infs,omitempty"`
+	// Inexact counts the integer values float64 rounds; see
+	// --exact-integers.
+	Inexact int64 `json:"inexact,omitempty"`
+	// Points places each anomaly in time when --time-column is set.
+	Points []anomaly.Point `json:"points,omitempty"`
+	// Omitted counts the flagged rows left out by --top and --min-score.
+	Omitted int64 `json:"omitted,omitempty"`
+	// Episodes groups the flagged rows into incidents with --episodes.
+	Episodes []anomaly.Episode `json:"episodes,omitzero"`
+
+	// events describes the flagged rows for the notification sinks.
+	events []sink.Event
+	// emitted is set once the anomalies have gone out as they were found.
+	emitted bool
+	// flagged counts the anomalies found, whatever is reported.
+	flagged int64
+	// values accumulates the summary.
+	values *summarizer
+	// plot accumulates the --plot sparkline.
+	plot *plotter
+}
+
+// outputRow is one flagged row: its position, value and score.
+type outputRow struct {
+	Row    int64    `json:"row"`
+	Value  score    `json:"value"`
+	Score  score    `json:"score"`
+	PValue *float64 `json:"p_value,omitempty"`
+}
+
+// outputSummary holds descriptive statistics of the scored values and the
+// share of rows flagged. Median is exact, except for streamed input, where it
+// is estimated with a t-digest and MedianApproximate is set.
+type outputSummary struct {
+	Values            int64   `json:"values"`
+	Mean              float64 `json:"mean"`
+	StdDev            float64 `json:"stddev"`
+	Median            float64 `json:"median"`
+	MedianApproximate bool    `json:"median_approximate,omitempty"`
+	Min               float64 `json:"min"`
+	Max               float64 `json:"max"`
+	AnomalyCount      int64   `json:"anomaly_count"`
+	AnomalyRate       float64 `json:"anomaly_rate"`
+}
+
+// add appends the scores, and p-values where the method has them, of the
+// rows flagged in res, numbering rows from base, and counts the values it
+// left out.
+func (o *analyzeOutput) add(res *anomaly.Result, base int64) {
+	o.Nulls += res.Nulls
+	o.NaNs += res.NaNs
+	o.Infs += res.Infs
+	o.Inexact += res.Inexact
+	o.flagged += int64(res.Indices.Len())
+	if viper.GetString("plot") != "" {
+		if o.plot == nil {
+			o.plot = &plotter{}
 		}
-		if _, err := f.Seek(0, 0); err != nil {
-			return fmt.Errorf("seek: %w", err)
+		o.plot.add(res)
+	}
+	for j := 0; j < res.Indices.Len(); j++ {
+		i := int(res.Indices.Value(j))
+		row := outputRow{Row: base + int64(i), Value: score(res.Values.Value(j)), Score: score(res.Zscore.Value(i))}
+		o.Anomalies = append(o.Anomalies, row.Score)
+		if res.PValues != nil {
+			p := res.PValues.Value(i)
+			row.PValue = &p
+			o.PValues = append(o.PValues, p)
+		}
+		o.Rows = append(o.Rows, row)
+	}
+}
+
+// summarize folds the scored values col into the summary. Columns that are
+// not numeric are skipped.
+func (o *analyzeOutput) summarize(col arrow.Array) {
+	if o.values == nil {
+		o.values = newSummarizer(false)
+	}
+	o.values.add(col)
+}
+
+// finish sets the fields computed once all results are in, and applies
+// --min-score and --top once the summary and episodes have counted every
+// flagged row.
+func (o *analyzeOutput) finish() {
+	o.SchemaVersion = outputSchemaVersion
+	if o.values != nil && o.values.numeric {
+		o.Summary = o.values.summary(int64(len(o.Rows)), o.Count)
+	}
+	if viper.GetBool("episodes") {
+		o.group(viper.GetInt64("episode-gap"))
+	}
+	o.limit(viper.GetFloat64("min-score"), viper.GetInt("top"))
+}
+
+// group sets Episodes from the flagged rows, with their times when there
+// are points. The rows are put back in row order first, as --contamination
+// has ranked them by score.
+func (o *analyzeOutput) group(gap int64) {
+	points := make([]anomaly.Point, len(o.Rows))
+	if len(o.Points) == len(o.Rows) {
+		copy(points, o.Points)
+	} else {
+		for i, r := range o.Rows {
+			points[i] = anomaly.Point{Row: r.Row, Value: float64(r.Value), Score: float64(r.Score)}
 		}
+	}
+	sort.SliceStable(points, func(a, b int) bool { return points[a].Row < points[b].Row })
+	o.Episodes = anomaly.GroupEpisodes(points, gap)
+	if o.Episodes == nil {
+		o.Episodes = []anomaly.Episode{}
+	}
+}
+
+// limit keeps the anomalies scoring at least minScore in magnitude and then,
+// when top is positive, the top with the highest absolute scores, most
+// anomalous first. The rest are counted in Omitted.
+func (o *analyzeOutput) limit(minScore float64, top int) {
+	if minScore > 0 {
+		keep := make([]int, 0, len(o.Rows))
+		for i, r := range o.Rows {
+			if math.Abs(float64(r.Score)) >= minScore {
+				keep = append(keep, i)
+			}
+		}
+		o.keep(keep)
+	}
+	if top > 0 {
+		o.rank()
+		if len(o.Rows) > top {
+			keep := make([]int, top)
+			for i := range keep {
+				keep[i] = i
+			}
+			o.keep(keep)
+		}
+	}
+}
+
+// keep retains the anomalies at positions idx, in that order, and their
+// p-values and points, counting the others in Omitted.
+func (o *analyzeOutput) keep(idx []int) {
+	o.Omitted += int64(len(o.Rows) - len(idx))
+	rows := make([]outputRow, len(idx))
+	scores := make([]score, len(idx))
+	for i, j := range idx {
+		rows[i], scores[i] = o.Rows[j], o.Anomalies[j]
+	}
+	o.Rows, o.Anomalies = rows, scores
+	if o.PValues != nil {
+		pvalues := make([]float64, len(idx))
+		for i, j := range idx {
+			pvalues[i] = o.PValues[j]
+		}
+		o.PValues = pvalues
+	}
+	if o.Points != nil {
+		points := make([]anomaly.Point, len(idx))
+		for i, j := range idx {
+			points[i] = o.Points[j]
+		}
+		o.Points = points
+	}
+}
+
+// summarizer accumulates running statistics and a t-digest of values, and
+// the values themselves for an exact median unless they are streamed.
+type summarizer struct {
+	numeric bool
+	n       int64
+	mean    float64
+	m2      float64
+	min     float64
+	max     float64
+	digest  *anomaly.TDigest
+	// streamed leaves the median to the digest; otherwise vals holds every
+	// value added.
+	streamed bool
+	vals     []float64
+}
 
-		arr, err := csvreader.NewCSVReader(f, schema).ReadSingleColumn(f, column)
+func newSummarizer(streamed bool) *summarizer {
+	return &summarizer{numeric: true, digest: anomaly.NewTDigest(200), streamed: streamed}
+}
+
+func (s *summarizer) add(col arrow.Array) {
+	if !s.numeric {
+		return
+	}
+	if dict, ok := col.(*array.Dictionary); ok {
+		decoded, err := compute.TakeArray(context.Background(), dict.Dictionary(), dict.Indices())
 		if err != nil {
-			return fmt.Errorf("read column: %w", err)
+			s.numeric = false
+			return
+		}
+		defer decoded.Release()
+		col = decoded
+	}
+	cast, err := compute.CastArray(context.Background(), col, compute.SafeCastOptions(arrow.PrimitiveTypes.Float64))
+	if err != nil {
+		s.numeric = false
+		return
+	}
+	defer cast.Release()
+	vals := cast.(*array.Float64)
+	for i := 0; i < vals.Len(); i++ {
+		x := vals.Value(i)
+		if vals.IsNull(i) || math.IsNaN(x) || math.IsInf(x, 0) {
+			continue
+		}
+		if s.n == 0 || x < s.min {
+			s.min = x
+		}
+		if s.n == 0 || x > s.max {
+			s.max = x
+		}
+		s.n++
+		d := x - s.mean
+		s.mean += d / float64(s.n)
+		s.m2 += d * (x - s.mean)
+		s.digest.Add(x)
+		if !s.streamed {
+			s.vals = append(s.vals, x)
 		}
-		defer arr.Release()
+	}
+}
 
-		colArr, ok := arr.(*array.Float64)
-		if !ok {
-			return fmt.Errorf("unsupported array type: %T", arr)
+// summary returns the statistics so far, with anomalies flagged of rows.
+func (s *summarizer) summary(anomalies, rows int64) *outputSummary {
+	out := &outputSummary{Values: s.n, AnomalyCount: anomalies}
+	if rows > 0 {
+		out.AnomalyRate = float64(anomalies) / float64(rows)
+	}
+	if s.n > 0 {
+		out.Mean, out.StdDev = s.mean, math.Sqrt(s.m2/float64(s.n))
+		out.Median, out.Min, out.Max = s.median(), s.min, s.max
+		out.MedianApproximate = s.streamed
+	}
+	return out
+}
+
+// median returns the median of the values, estimated by the digest when
+// they are streamed.
+func (s *summarizer) median() float64 {
+	if s.streamed {
+		return s.digest.Quantile(0.5)
+	}
+	sort.Float64s(s.vals)
+	n := len(s.vals)
+	if n%2 == 1 {
+		return s.vals[n/2]
+	}
+	return (s.vals[n/2-1] + s.vals[n/2]) / 2
+}
+
+// addPoints appends the flagged rows of res as points in time taken from
+// timeCol, numbering rows from base.
+func (o *analyzeOutput) addPoints(res *anomaly.Result, timeCol arrow.Array, base int64) error {
+	points, err := res.Points(timeCol)
+	if err != nil {
+		return fmt.Errorf("time column: %w", err)
+	}
+	for i := range points {
+		points[i].Row += base
+	}
+	o.Points = append(o.Points, points...)
+	return nil
+}
+
+// rank orders the anomalies, and their rows, p-values and points, by
+// descending absolute score.
+func (o *analyzeOutput) rank() {
+	order := make([]int, len(o.Anomalies))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return math.Abs(float64(o.Anomalies[order[a]])) > math.Abs(float64(o.Anomalies[order[b]]))
+	})
+	o.keep(order)
+}
+
+func writeOutput(out analyzeOutput) error {
+	out.finish()
+	if out.Inexact > 0 && !viper.GetBool("exact-integers") {
+		fmt.Fprintf(os.Stderr, "warning: %d integer values lose precision as float64; use --exact-integers for exact zscore statistics\n", out.Inexact)
+	}
+	if ndjsonOutput() || viper.GetBool("json") {
+		// Keep stdout machine-readable.
+		if err := writePlot(os.Stderr, &out); err != nil {
+			return err
+		}
+	}
+	if ndjsonOutput() {
+		if !out.emitted {
+			if err := writeNDJSON(viper.GetString("output"), []string{""}, map[string]analyzeOutput{"": out}); err != nil {
+				return err
+			}
+		}
+		return checkBudget(out.flagged, out.Count)
+	}
+	if viper.GetBool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+		return checkBudget(out.flagged, out.Count)
+	}
+	if err := printText(&out); err != nil {
+		return err
+	}
+	return checkBudget(out.flagged, out.Count)
+}
+
+// printText writes out to stdout as text, with its --plot.
+func printText(out *analyzeOutput) error {
+	if out.Episodes != nil {
+		printEpisodes(out)
+		return writePlot(os.Stdout, out)
+	}
+	if out.Points != nil {
+		fmt.Printf("Total: %d\nAnomalies:\n", out.Count)
+		for _, p := range out.Points {
+			fmt.Printf("  %s value=%v score=%.2f\n", p.Time.Format(time.RFC3339Nano), p.Value, p.Score)
+		}
+	} else {
+		fmt.Printf("Total: %d\nAnomalies: %v\n", out.Count, out.Anomalies)
+	}
+	if out.PValues != nil {
+		fmt.Printf("P-values: %v\n", out.PValues)
+	}
+	if out.Nulls+out.NaNs+out.Infs > 0 {
+		fmt.Printf("Skipped: %d null, %d NaN, %d Inf\n", out.Nulls, out.NaNs, out.Infs)
+	}
+	if out.Omitted > 0 {
+		fmt.Printf("Omitted: %d more anomalies\n", out.Omitted)
+	}
+	return writePlot(os.Stdout, out)
+}
+
+// printEpisodes writes the episodes of out in place of its anomalies, by
+// time when there are points.
+func printEpisodes(out *analyzeOutput) {
+	fmt.Printf("Total: %d\nEpisodes: %d (%d anomalies)\n", out.Count, len(out.Episodes), out.flagged)
+	for _, e := range out.Episodes {
+		span := fmt.Sprintf("rows %d-%d", e.Start, e.End)
+		if out.Points != nil {
+			span = e.From.Format(time.RFC3339Nano) + " to " + e.To.Format(time.RFC3339Nano)
+		}
+		fmt.Printf("  %s: %d flagged, peak row %d value=%v score=%.2f\n", span, e.Count, e.Peak, e.PeakValue, e.PeakScore)
+	}
+	if out.Nulls+out.NaNs+out.Infs > 0 {
+		fmt.Printf("Skipped: %d null, %d NaN, %d Inf\n", out.Nulls, out.NaNs, out.Infs)
+	}
+}
+
+// checkBudget fails the run when flagged anomalies in rows exceed the
+// budget set by --fail-on-anomaly or --max-anomaly-rate, so a data-quality
+// check can gate a CI pipeline or an Airflow task. Results are written
+// before it is called, and the usage is not printed.
+func checkBudget(flagged, rows int64) error {
+	var rate float64
+	if rows > 0 {
+		rate = float64(flagged) / float64(rows)
+	}
+	switch limit := viper.GetFloat64("max-anomaly-rate"); {
+	case viper.GetBool("fail-on-anomaly") && flagged > 0:
+		rootCmd.SilenceUsage = true
+		return fmt.Errorf("%d anomalies found", flagged)
+	case limit > 0 && rate > limit:
+		rootCmd.SilenceUsage = true
+		return fmt.Errorf("anomaly rate %.4g (%d of %d rows) exceeds --max-anomaly-rate %g", rate, flagged, rows, limit)
+	}
+	return nil
+}
+
+// multivariateDetector scores several columns jointly rather than one by one.
+type multivariateDetector interface {
+	DetectColumns(ctx context.Context, rec arrow.Record, columns []string) (*anomaly.Result, error)
+}
+
+// jointDetector returns d as a multivariateDetector if it scores the columns
+// jointly, looking through --contamination to the method underneath.
+func jointDetector(d anomaly.Detector) (multivariateDetector, bool) {
+	if c, ok := d.(anomaly.ContaminationDetector); ok {
+		if _, ok := c.Base.(multivariateDetector); !ok {
+			return nil, false
 		}
+	}
+	md, ok := d.(multivariateDetector)
+	return md, ok
+}
 
-		res, err := anomaly.DetectAnomalies(context.Background(), colArr, viper.GetFloat64("threshold"))
+// numericColumns returns the names of the integer and floating-point columns
+// of schema, plain or dictionary-encoded, which is nil for sources that only
+// know it once read.
+func numericColumns(schema *arrow.Schema) []string {
+	if schema == nil {
+		return nil
+	}
+	var names []string
+	for _, f := range schema.Fields() {
+		t := f.Type
+		if dt, ok := t.(*arrow.DictionaryType); ok {
+			t = dt.ValueType
+		}
+		if arrow.IsInteger(t.ID()) || arrow.IsFloating(t.ID()) {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// analyzeColumns runs the detector over several columns read in one pass.
+// Entries may be name=threshold to override --threshold for that column, as
+// may the config file's thresholds map. When lenient, as for the numeric
+// columns analyzed by default, a column the detector cannot score, such as a
+// constant one, is reported on stderr and left out rather than failing the
+// run.
+func analyzeColumns(src source, entries []string, lenient bool) error {
+	columns, thresholds, err := columnThresholds(entries)
+	if err != nil {
+		return err
+	}
+	detector, err := newDetector(viper.GetString("method"))
+	if err != nil {
+		return err
+	}
+	rec, err := src.ReadColumns(commandCtx, columns)
+	if err != nil {
+		return fmt.Errorf("read columns: %w", err)
+	}
+	defer rec.Release()
+
+	if md, ok := jointDetector(detector); ok {
+		if len(thresholds) > 0 {
+			return fmt.Errorf("per-column thresholds do not apply to --method %s, which scores the columns jointly", viper.GetString("method"))
+		}
+		res, err := md.DetectColumns(detectContext(), rec, columns)
+		if err != nil {
+			return fmt.Errorf("detect anomalies: %w", err)
+		}
+		defer res.Release()
+		out := analyzeOutput{Count: rec.NumRows()}
+		out.add(res, 0)
+		return writeOutput(out)
+	}
+
+	outs := make(map[string]analyzeOutput, len(columns))
+	for _, name := range columns {
+		d := detector
+		if t, ok := thresholds[name]; ok {
+			if d, err = newDetector(viper.GetString("method"), anomaly.WithThreshold(t)); err != nil {
+				return err
+			}
+		}
+		results, err := anomaly.DetectRecord(detectContext(), d, rec, []string{name})
+		if err != nil && lenient {
+			fmt.Fprintf(os.Stderr, "Skipped %v\n", err)
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("detect anomalies: %w", err)
 		}
-		defer res.Mask.Release()
-		defer res.Zscore.Release()
+		out := analyzeOutput{Count: rec.NumRows()}
+		out.add(results[name], 0)
+		out.summarize(rec.Column(rec.Schema().FieldIndices(name)[0]))
+		out.finish()
+		outs[name] = out
+		results[name].Release()
+	}
 
-		type Out struct {
-			Count     int64     `json:"count"`
-			Anomalies []float64 `json:"anomalies"`
+	switch {
+	case ndjsonOutput():
+		err = writeNDJSON(viper.GetString("output"), columns, outs)
+	case viper.GetBool("json"):
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(outs)
+	default:
+		for _, name := range columns {
+			out, ok := outs[name]
+			if !ok {
+				continue
+			}
+			fmt.Printf("Column: %s\nTotal: %d\nAnomalies: %v\n", name, out.Count, out.Anomalies)
+			if err = writePlot(os.Stdout, &out); err != nil {
+				break
+			}
 		}
-		out := Out{Count: int64(colArr.Len())}
-		for i := 0; i < int(res.Mask.Len()); i++ {
-			if res.Mask.Value(i) {
-				out.Anomalies = append(out.Anomalies, res.Zscore.Value(i))
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range columns {
+		if out, ok := outs[name]; ok {
+			if err := checkBudget(out.flagged, out.Count); err != nil {
+				return fmt.Errorf("column %s: %w", name, err)
 			}
 		}
+	}
+	return nil
+}
+
+// columnEntries returns the values of a repeated --column, or of one given
+// as name=threshold, which analyze reads as --columns entries, or nil.
+func columnEntries() []string {
+	if len(columnName) > 1 || len(columnName) == 1 && strings.Contains(columnName[0], "=") {
+		return columnName
+	}
+	return nil
+}
 
-		if viper.GetBool("json") {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			return enc.Encode(out)
+// columnThresholds splits --columns entries of the form name or
+// name=threshold into the column names and their threshold overrides. The
+// config file's thresholds map applies to columns given without one; viper
+// lowercases its keys, so they match column names case-insensitively.
+func columnThresholds(entries []string) ([]string, map[string]float64, error) {
+	configured := viper.GetStringMap("thresholds")
+	columns := make([]string, 0, len(entries))
+	thresholds := map[string]float64{}
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		columns = append(columns, name)
+		if !ok {
+			v, found := configured[strings.ToLower(name)]
+			if !found {
+				continue
+			}
+			value = fmt.Sprint(v)
 		}
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("threshold for column %s: %w", name, err)
+		}
+		thresholds[name] = t
+	}
+	return columns, thresholds, nil
+}
 
-		fmt.Printf("Total: %d\nAnomalies: %v\n", out.Count, out.Anomalies)
-		return nil
-	},
+// analyzeTimed scores column like the default path, reading timeColumn
+// alongside it so anomalies are reported with the time they occurred.
+func analyzeTimed(src source, column, timeColumn string) (analyzeOutput, error) {
+	detector, err := newDetector(viper.GetString("method"))
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	rec, err := src.ReadColumns(commandCtx, []string{column, timeColumn})
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("read columns: %w", err)
+	}
+	defer rec.Release()
+
+	res, err := anomaly.Detect(detectContext(), detector, rec.Column(0))
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+
+	out := analyzeOutput{Count: rec.NumRows()}
+	out.add(res, 0)
+	out.summarize(rec.Column(0))
+	if err := out.addPoints(res, rec.Column(1), 0); err != nil {
+		return analyzeOutput{}, err
+	}
+	if viper.GetFloat64("contamination") > 0 {
+		out.rank()
+	}
+	return out, nil
+}
+
+// analyzeBucketed aggregates column into buckets of timeColumn with --agg and
+// scores the resulting series, removing seasonality of the given period (in
+// buckets) first when it is positive. Anomalies are reported by bucket time.
+func analyzeBucketed(src source, column, timeColumn string, bucket time.Duration, period int) (analyzeOutput, error) {
+	if timeColumn == "" {
+		return analyzeOutput{}, fmt.Errorf("--bucket requires --time-column")
+	}
+	detector, err := newDetector(viper.GetString("method"))
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	rec, err := src.ReadColumns(commandCtx, []string{column, timeColumn})
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("read columns: %w", err)
+	}
+	defer rec.Release()
+
+	ctx := detectContext()
+	series, err := anomaly.Resample(ctx, rec, column, timeColumn, bucket, anomaly.Aggregation(viper.GetString("agg")))
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("resample: %w", err)
+	}
+	defer series.Release()
+
+	if period > 0 {
+		detector = anomaly.SeasonalDetector{Period: period, Residual: detector}
+	}
+	res, err := anomaly.Detect(ctx, detector, series.Column(1))
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+
+	out := analyzeOutput{Count: series.NumRows()}
+	out.add(res, 0)
+	out.summarize(series.Column(1))
+	if err := out.addPoints(res, series.Column(0), 0); err != nil {
+		return analyzeOutput{}, err
+	}
+	if viper.GetFloat64("contamination") > 0 {
+		out.rank()
+	}
+	return out, nil
+}
+
+// analyzeGrouped scores the rows of each group of column on their own with
+// the --method detector, or compares each group's flag rate with the
+// overall one for flag-rate, reporting the times of anomalies when
+// timeColumn is set.
+func analyzeGrouped(src source, column, key, timeColumn string, threshold float64) (analyzeOutput, error) {
+	columns := []string{column, key}
+	if timeColumn != "" {
+		columns = append(columns, timeColumn)
+	}
+	rec, err := src.ReadColumns(commandCtx, columns)
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("read columns: %w", err)
+	}
+	defer rec.Release()
+
+	var res *anomaly.Result
+	if anomaly.Method(viper.GetString("method")) == anomaly.MethodFlagRate {
+		res, err = anomaly.DetectFlagRateGrouped(detectContext(), rec, column, key, threshold)
+	} else {
+		var detector anomaly.Detector
+		if detector, err = newDetector(viper.GetString("method")); err != nil {
+			return analyzeOutput{}, err
+		}
+		res, err = anomaly.DetectGrouped(detectContext(), detector, rec, column, key)
+	}
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+
+	out := analyzeOutput{Count: rec.NumRows()}
+	out.add(res, 0)
+	out.summarize(rec.Column(0))
+	if timeColumn != "" {
+		if err := out.addPoints(res, rec.Column(2), 0); err != nil {
+			return analyzeOutput{}, err
+		}
+	}
+	return out, nil
+}
+
+// analyzeSeasonal removes trend and seasonality of the given period before
+// scoring the residuals with the --method detector. Rows are put in order of
+// timeColumn first when it is set, and anomalies are reported with their
+// times.
+func analyzeSeasonal(src source, column, timeColumn string, period int) (analyzeOutput, error) {
+	residual, err := newDetector(viper.GetString("method"))
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	columns := []string{column}
+	if timeColumn != "" {
+		columns = append(columns, timeColumn)
+	}
+	rec, err := src.ReadColumns(commandCtx, columns)
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("read columns: %w", err)
+	}
+	defer rec.Release()
+
+	var res *anomaly.Result
+	if timeColumn != "" {
+		res, err = anomaly.DetectAnomaliesSeasonal(detectContext(), rec, column, timeColumn, period, residual)
+	} else {
+		res, err = anomaly.SeasonalDetector{Period: period, Residual: residual}.Detect(detectContext(), rec.Column(0))
+	}
+	if err != nil {
+		return analyzeOutput{}, fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+
+	out := analyzeOutput{Count: rec.NumRows()}
+	out.add(res, 0)
+	out.summarize(rec.Column(0))
+	if timeColumn != "" {
+		if err := out.addPoints(res, rec.Column(1), 0); err != nil {
+			return analyzeOutput{}, err
+		}
+	}
+	return out, nil
+}
+
+// anomalyRow is one flagged row with the values of every column.
+type anomalyRow struct {
+	Row    int64          `json:"row"`
+	Score  score          `json:"score"`
+	Fields map[string]any `json:"fields"`
+}
+
+// analyzeRows reads every column so flagged rows can be reported in full.
+func analyzeRows(src source, column string) error {
+	detector, err := newDetector(viper.GetString("method"))
+	if err != nil {
+		return err
+	}
+	rec, err := src.ReadColumns(commandCtx, nil)
+	if err != nil {
+		return fmt.Errorf("read columns: %w", err)
+	}
+	defer rec.Release()
+
+	rows, res, err := anomaly.AnomalousRows(detectContext(), detector, rec, column)
+	if err != nil {
+		return fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer rows.Release()
+	defer res.Release()
+
+	out := make([]anomalyRow, rows.NumRows())
+	for i := range out {
+		row := res.Indices.Value(i)
+		out[i] = anomalyRow{Row: row, Score: score(res.Zscore.Value(int(row))), Fields: make(map[string]any, rows.NumCols())}
+		for j, f := range rows.Schema().Fields() {
+			out[i].Fields[f.Name] = rows.Column(j).GetOneForMarshal(i)
+		}
+	}
+
+	if viper.GetBool("json") {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(struct {
+			Count int64        `json:"count"`
+			Rows  []anomalyRow `json:"rows"`
+		}{rec.NumRows(), out}); err != nil {
+			return err
+		}
+		return checkBudget(rows.NumRows(), rec.NumRows())
+	}
+	fmt.Printf("Total: %d\n", rec.NumRows())
+	for i, r := range out {
+		parts := make([]string, 0, rows.NumCols())
+		for j, f := range rows.Schema().Fields() {
+			parts = append(parts, fmt.Sprintf("%s=%s", f.Name, rows.Column(j).ValueStr(i)))
+		}
+		fmt.Printf("row %d: %s (score %.2f)\n", r.Row, strings.Join(parts, ", "), r.Score)
+	}
+	return checkBudget(rows.NumRows(), rec.NumRows())
+}
+
+// batchDetector scores record batches against state carried across them,
+// which it can snapshot and restore for --checkpoint.
+type batchDetector interface {
+	Update(ctx context.Context, rec arrow.Record) (*anomaly.Result, error)
+	State() anomaly.StreamState
+	Restore(anomaly.StreamState) error
+}
+
+// newBatchDetector returns the streaming detector for --method: running
+// z-scores for zscore and a t-digest for percentile. Other methods need the
+// whole column and fail rather than scoring by z-score.
+func newBatchDetector(column string, threshold float64) (batchDetector, error) {
+	switch m := anomaly.Method(viper.GetString("method")); m {
+	case anomaly.MethodZScore:
+		return anomaly.NewStreamingDetector(column, threshold), nil
+	case anomaly.MethodPercentile:
+		return anomaly.NewStreamingPercentileDetector(column, viper.GetFloat64("q")), nil
+	default:
+		return nil, fmt.Errorf("streaming detection supports --method zscore and percentile, not %s", m)
+	}
+}
+
+// streamColumn scores the column batch by batch with running statistics
+// instead of materializing it, so the input need not fit in memory. The
+// zscore method keeps running statistics and percentile a t-digest. With
+// timeColumn set, anomalies are reported with their times. NDJSON output is
+// written as each batch is scored.
+func streamColumn(src source, column, timeColumn string, threshold float64) (analyzeOutput, error) {
+	ctx, cancel := context.WithCancel(detectContext())
+	defer cancel()
+
+	// NDJSON goes out batch by batch, unless --top has to see every score
+	// or --episodes every row.
+	var live *ndjsonWriter
+	if ndjsonOutput() && viper.GetInt("top") == 0 && !viper.GetBool("episodes") {
+		var err error
+		if live, err = newNDJSONWriter(viper.GetString("output")); err != nil {
+			return analyzeOutput{}, err
+		}
+		defer live.Close()
+	}
+
+	detector, err := newBatchDetector(column, threshold)
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	recs, errs := src.Chan(ctx)
+	out := analyzeOutput{values: newSummarizer(true)}
+	for rec := range recs {
+		res, err := detector.Update(ctx, rec)
+		if err == nil && timeColumn != "" {
+			err = addBatchPoints(&out, res, rec, timeColumn)
+		}
+		base := out.Count
+		out.Count += rec.NumRows()
+		if err == nil {
+			if idx := rec.Schema().FieldIndices(column); len(idx) > 0 {
+				out.summarize(rec.Column(idx[0]))
+			}
+		}
+		rec.Release()
+		if err != nil {
+			return out, fmt.Errorf("detect anomalies: %w", err)
+		}
+		out.add(res, base)
+		res.Release()
+		if live != nil {
+			if err := out.emit(live); err != nil {
+				return out, err
+			}
+		}
+	}
+	if err := <-errs; err != nil {
+		return out, fmt.Errorf("read column: %w", err)
+	}
+	if live != nil {
+		return out, live.Close()
+	}
+	return out, nil
+}
+
+// addBatchPoints adds the points of res, the result for rec, numbering rows
+// after those already counted in out. It releases res on error.
+func addBatchPoints(out *analyzeOutput, res *anomaly.Result, rec arrow.Record, timeColumn string) error {
+	idx := rec.Schema().FieldIndices(timeColumn)
+	if len(idx) == 0 {
+		res.Release()
+		return fmt.Errorf("column %s not found", timeColumn)
+	}
+	if err := out.addPoints(res, rec.Column(idx[0]), out.Count); err != nil {
+		res.Release()
+		return err
+	}
+	return nil
+}
+
+// detectContext returns the context detection runs under: the command's
+// trace, carrying the --null-policy, --nan-policy, --parallel, --fast-path
+// and --memory-limit.
+func detectContext() context.Context {
+	ctx := anomaly.ContextWithNullPolicy(commandCtx, anomaly.NullPolicy(viper.GetString("null-policy")))
+	ctx = anomaly.ContextWithParallelism(ctx, viper.GetInt("parallel"))
+	ctx = anomaly.ContextWithFastPath(ctx, viper.GetBool("fast-path"))
+	ctx = anomaly.ContextWithExactIntegers(ctx, viper.GetBool("exact-integers"))
+	if mib := viper.GetInt64("memory-limit"); mib > 0 {
+		ctx = anomaly.ContextWithMemoryLimit(ctx, mib<<20)
+	}
+	return anomaly.ContextWithNonFinitePolicy(ctx, anomaly.NonFinitePolicy(viper.GetString("nan-policy")))
+}
+
+// newDetector maps the --method flags to a Detector. For iqr the threshold is
+// the Tukey fence multiplier k and for lof the outlier factor. For category
+// with --min-count, the residual test only applies when --threshold is given.
+// extra, such as a per-column WithThreshold, applies over the flags.
+func newDetector(method string, extra ...anomaly.Option) (anomaly.Detector, error) {
+	return anomaly.NewDetector(append(detectorOptions(method), extra...)...)
+}
+
+// newPipeline is newDetector built into a Pipeline, which also carries the
+// --parallel, --fast-path, --exact-integers and --memory-limit settings
+// detectContext puts on a context.
+func newPipeline(method string, extra ...anomaly.Option) (*anomaly.Pipeline, error) {
+	opts := append(detectorOptions(method),
+		anomaly.WithParallelism(viper.GetInt("parallel")),
+		anomaly.WithFastPath(viper.GetBool("fast-path")),
+		anomaly.WithExactIntegers(viper.GetBool("exact-integers")),
+		anomaly.WithMemoryLimit(viper.GetInt64("memory-limit")<<20),
+	)
+	return anomaly.NewPipeline(append(opts, extra...)...)
+}
+
+// detectorOptions returns the options of the --method flags. --threshold is
+// passed on only when it is set, so each method otherwise keeps its own
+// default, such as the Tukey multiplier for iqr.
+func detectorOptions(method string) []anomaly.Option {
+	opts := []anomaly.Option{
+		anomaly.WithMethod(anomaly.Method(method)),
+		anomaly.WithNullPolicy(anomaly.NullPolicy(viper.GetString("null-policy"))),
+		anomaly.WithNonFinitePolicy(anomaly.NonFinitePolicy(viper.GetString("nan-policy"))),
+		anomaly.WithWindow(viper.GetInt("window")),
+		anomaly.WithApproximate(viper.GetBool("approx")),
+		anomaly.WithNeighbors(viper.GetInt("neighbors")),
+		anomaly.WithAlpha(viper.GetFloat64("alpha")),
+		anomaly.WithQuantile(viper.GetFloat64("q")),
+		anomaly.WithMinCount(viper.GetInt64("min-count")),
+		anomaly.WithPatternShare(viper.GetFloat64("pattern-share")),
+		anomaly.WithContamination(viper.GetFloat64("contamination")),
+		anomaly.WithBounds(viper.GetFloat64("upper"), viper.GetFloat64("lower")),
+	}
+	if viper.IsSet("threshold") {
+		opts = append(opts, anomaly.WithThreshold(viper.GetFloat64("threshold")))
+	}
+	return opts
 }
 
 func init() {
+	analyzeCmd.Flags().String("group-by", "", "Key column whose groups are each scored on their own by --method, or with --method flag-rate are each compared with the overall rate")
+	viper.BindPFlag("group-by", analyzeCmd.Flags().Lookup("group-by"))
+	analyzeCmd.Flags().Int("period", 0, "Season length in rows; remove trend and seasonality before detection")
+	viper.BindPFlag("period", analyzeCmd.Flags().Lookup("period"))
+	analyzeCmd.Flags().String("time-column", "", "Timestamp column to report anomalies by (and the row order for --period)")
+	viper.BindPFlag("time-column", analyzeCmd.Flags().Lookup("time-column"))
+	analyzeCmd.Flags().Duration("bucket", 0, "Aggregate the column into buckets of --time-column this wide (e.g. 5m) before detection")
+	viper.BindPFlag("bucket", analyzeCmd.Flags().Lookup("bucket"))
+	analyzeCmd.Flags().String("agg", "mean", "Bucket aggregation for --bucket: mean, max, min, sum or count")
+	viper.BindPFlag("agg", analyzeCmd.Flags().Lookup("agg"))
+	analyzeCmd.Flags().Bool("context", false, "Report every column of each anomalous row")
+	viper.BindPFlag("context", analyzeCmd.Flags().Lookup("context"))
+	analyzeCmd.Flags().StringP("output", "o", "", "Write the flagged rows, with zscore and is_anomaly columns, to this file (- for stdout)")
+	viper.BindPFlag("output", analyzeCmd.Flags().Lookup("output"))
+	analyzeCmd.Flags().String("output-format", "", "Output format: csv, parquet, arrow (IPC stream), or ndjson for one JSON object per anomaly; without --output, rows are written to stdout")
+	viper.BindPFlag("output-format", analyzeCmd.Flags().Lookup("output-format"))
+	analyzeCmd.Flags().Bool("all-rows", false, "With --output, write every row instead of only the flagged ones")
+	viper.BindPFlag("all-rows", analyzeCmd.Flags().Lookup("all-rows"))
+	analyzeCmd.Flags().String("clean", "", "With --output, add a cleaned copy of the column with anomalies clipped to the largest and smallest unflagged values (clip, not the detector's center ± threshold × scale), or replaced by null, mean, median or interpolate")
+	viper.BindPFlag("clean", analyzeCmd.Flags().Lookup("clean"))
+	analyzeCmd.Flags().Int("top", 0, "Report only the N anomalies with the highest absolute scores, most anomalous first (0 = all)")
+	viper.BindPFlag("top", analyzeCmd.Flags().Lookup("top"))
+	analyzeCmd.Flags().Float64("min-score", 0, "Report only anomalies whose absolute score is at least this")
+	viper.BindPFlag("min-score", analyzeCmd.Flags().Lookup("min-score"))
+	analyzeCmd.Flags().Bool("episodes", false, "Group flagged rows no more than --episode-gap rows apart into episodes, reported with their start, end and peak instead of each row")
+	viper.BindPFlag("episodes", analyzeCmd.Flags().Lookup("episodes"))
+	analyzeCmd.Flags().Int64("episode-gap", 0, "Unflagged rows allowed between the flagged rows of an episode (0 = adjacent rows only)")
+	viper.BindPFlag("episode-gap", analyzeCmd.Flags().Lookup("episode-gap"))
+	analyzeCmd.Flags().Bool("merge", false, "Analyze the files of a --file glob or directory as one dataset instead of reporting each file")
+	viper.BindPFlag("merge", analyzeCmd.Flags().Lookup("merge"))
+	analyzeCmd.Flags().Int("jobs", 1, "Files of a --file glob or directory to analyze in parallel (0 = GOMAXPROCS)")
+	viper.BindPFlag("jobs", analyzeCmd.Flags().Lookup("jobs"))
+	analyzeCmd.Flags().String("plot", "", "Draw a histogram of the column and a sparkline of scores, anomalies highlighted, in the terminal (unicode, or ascii)")
+	analyzeCmd.Flags().Lookup("plot").NoOptDefVal = "unicode"
+	viper.BindPFlag("plot", analyzeCmd.Flags().Lookup("plot"))
+	analyzeCmd.Flags().Bool("fail-on-anomaly", false, "Exit non-zero when any anomaly is found, after writing the results")
+	viper.BindPFlag("fail-on-anomaly", analyzeCmd.Flags().Lookup("fail-on-anomaly"))
+	analyzeCmd.Flags().Float64("max-anomaly-rate", 0, "Exit non-zero when more than this fraction of rows is flagged, e.g. 0.01 (0 = no limit)")
+	viper.BindPFlag("max-anomaly-rate", analyzeCmd.Flags().Lookup("max-anomaly-rate"))
 	rootCmd.AddCommand(analyzeCmd)
 }
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+// run executes the command line args, with every flag back at its default,
+// and returns what it wrote to stdout.
+func run(t *testing.T, args ...string) string {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	resetFlags(t)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	rootCmd.SetArgs(args)
+	err = rootCmd.Execute()
+	os.Stdout = stdout
+	w.Close()
+	got := <-out
+	if err != nil {
+		t.Fatalf("%s: %v", strings.Join(args, " "), err)
+	}
+	return got
+}
+
+// resetFlags puts the flags of every command back to their defaults, as
+// cobra keeps the values of an earlier run.
+func resetFlags(t *testing.T) {
+	t.Helper()
+	columnName = nil
+	reset := func(f *pflag.Flag) {
+		if f.Name == "column" {
+			f.Changed = false
+			return
+		}
+		var err error
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			err = sv.Replace(nil)
+		} else {
+			err = f.Value.Set(f.DefValue)
+		}
+		if err != nil {
+			t.Fatalf("reset --%s: %v", f.Name, err)
+		}
+		f.Changed = false
+	}
+	for _, c := range append(rootCmd.Commands(), rootCmd) {
+		c.Flags().VisitAll(reset)
+		c.PersistentFlags().VisitAll(reset)
+	}
+}
+
+// writeCSV writes a column v of vals to a CSV file in a temporary directory
+// and returns its path.
+func writeCSV(t *testing.T, vals []float64) string {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("v\n")
+	for _, v := range vals {
+		fmt.Fprintf(&b, "%v\n", v)
+	}
+	path := filepath.Join(t.TempDir(), "in.csv")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAnalyzeContaminationEpisodes(t *testing.T) {
+	// Spikes at row 20 and at rows 150-151, the largest last, so ranking
+	// by score puts the rows out of order.
+	vals := make([]float64, 200)
+	for i := range vals {
+		vals[i] = float64(i % 5)
+	}
+	vals[20], vals[150], vals[151] = 90, 95, 100
+	path := writeCSV(t, vals)
+
+	var out struct {
+		Rows     []outputRow       `json:"rows"`
+		Episodes []anomaly.Episode `json:"episodes"`
+	}
+	got := run(t, "analyze", "-f", path, "-c", "v", "--contamination", "0.015", "--episodes", "--json", "--quiet")
+	if err := json.Unmarshal([]byte(got), &out); err != nil {
+		t.Fatalf("%v in %s", err, got)
+	}
+	if len(out.Rows) != 3 || out.Rows[0].Row != 151 {
+		t.Errorf("rows = %+v, want the three spikes ranked by score", out.Rows)
+	}
+	want := [][2]int64{{20, 20}, {150, 151}}
+	if len(out.Episodes) != len(want) {
+		t.Fatalf("episodes = %+v, want %v", out.Episodes, want)
+	}
+	for i, e := range out.Episodes {
+		if e.Start != want[i][0] || e.End != want[i][1] {
+			t.Errorf("episode %d = rows %d-%d, want %d-%d", i, e.Start, e.End, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestAnalyzeNonFiniteJSON(t *testing.T) {
+	// The outlier among many duplicates has an infinite outlier factor,
+	// which is written as null rather than failing the report.
+	vals := make([]float64, 31)
+	for i := range vals {
+		vals[i] = 5
+	}
+	vals[30] = 9
+	path := writeCSV(t, vals)
+
+	got := run(t, "analyze", "-f", path, "-c", "v", "--method", "lof", "--json", "--quiet")
+	var out struct {
+		Anomalies []*float64 `json:"anomalies"`
+		Rows      []struct {
+			Row   int64    `json:"row"`
+			Score *float64 `json:"score"`
+		} `json:"rows"`
+	}
+	if err := json.Unmarshal([]byte(got), &out); err != nil {
+		t.Fatalf("%v in %s", err, got)
+	}
+	if len(out.Rows) != 1 || out.Rows[0].Row != 30 || out.Rows[0].Score != nil || out.Anomalies[0] != nil {
+		t.Errorf("got %s, want row 30 with a null score", got)
+	}
+}
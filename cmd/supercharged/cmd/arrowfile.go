@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/TFMV/supercharged/internal/mmap"
+	"github.com/TFMV/supercharged/parquetreader"
+)
+
+// arrowFileMagic opens an Arrow IPC file (and a Feather v2 file), as opposed
+// to an IPC stream.
+var arrowFileMagic = []byte("ARROW1")
+
+// isArrowFile reports whether the local file f holds the Arrow IPC file
+// format.
+func isArrowFile(f *os.File) bool {
+	head := make([]byte, len(arrowFileMagic))
+	_, err := f.ReadAt(head, 0)
+	return err == nil && bytes.Equal(head, arrowFileMagic)
+}
+
+// peekArrowFile reports whether the piped input in holds the Arrow IPC file
+// format, buffering what it peeks at so it is read again.
+func peekArrowFile(in *input) bool {
+	br := bufio.NewReader(in.r)
+	in.r = br
+	head, err := br.Peek(len(arrowFileMagic))
+	return err == nil && bytes.Equal(head, arrowFileMagic)
+}
+
+// useMmap reports whether local Arrow IPC and Parquet files are to be
+// memory-mapped: unless --no-mmap is set, where the system supports it.
+func useMmap() bool {
+	return mmap.Supported && !viper.GetBool("no-mmap")
+}
+
+// arrowFileSource reads a local Arrow IPC file, whose footer indexes its
+// record batches. When mapped, the batches reference the file's memory
+// mapping directly, so their buffers are paged in from disk rather than
+// copied onto the heap, and must not be used once the source is closed.
+type arrowFileSource struct {
+	in    io.Closer
+	r     *ipc.FileReader
+	unmap func() error
+}
+
+// openArrowFile opens the Arrow IPC file f, memory-mapped per useMmap.
+func openArrowFile(f *os.File) (*arrowFileSource, error) {
+	s := &arrowFileSource{in: f}
+	var err error
+	if useMmap() {
+		var data []byte
+		if data, s.unmap, err = mmap.Map(f); err == nil {
+			s.r, err = ipc.NewMappedFileReader(data, ipc.WithAllocator(memory.DefaultAllocator))
+		}
+	} else {
+		s.r, err = ipc.NewFileReader(f, ipc.WithAllocator(memory.DefaultAllocator))
+	}
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("read arrow file: %w", err)
+	}
+	return s, nil
+}
+
+// readArrowFile reads the piped Arrow IPC file in into memory, since the
+// format needs random access, and serves its batches from there without
+// copying them again.
+func readArrowFile(in *input) (*arrowFileSource, error) {
+	data, err := io.ReadAll(in.r)
+	if err != nil {
+		in.Close()
+		return nil, fmt.Errorf("read stdin: %w", err)
+	}
+	r, err := ipc.NewMappedFileReader(data, ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		in.Close()
+		return nil, fmt.Errorf("read arrow file: %w", err)
+	}
+	return &arrowFileSource{in: in, r: r}, nil
+}
+
+func (s *arrowFileSource) Schema() *arrow.Schema { return s.r.Schema() }
+
+func (s *arrowFileSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	rec, err := s.ReadColumns(ctx, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+// ReadChunked returns the column with one chunk per record batch, which for
+// a mapped file are views of the mapping.
+func (s *arrowFileSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	recs, errs := s.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, column)
+}
+
+func (s *arrowFileSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range s.Schema().Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	recs, errs := s.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, memory.DefaultAllocator)
+}
+
+func (s *arrowFileSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for i := 0; i < s.r.NumRecords(); i++ {
+			rec, err := s.r.RecordAt(i)
+			if err != nil {
+				errs <- fmt.Errorf("arrow read error: %w", err)
+				return
+			}
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+	}()
+	return recs, errs
+}
+
+func (s *arrowFileSource) Close() error {
+	var err error
+	if s.r != nil {
+		err = s.r.Close()
+	}
+	if s.unmap != nil {
+		if uerr := s.unmap(); err == nil {
+			err = uerr
+		}
+	}
+	if cerr := s.in.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// mappedParquet opens the local Parquet file at path, memory-mapped per
+// useMmap.
+func mappedParquet(path string) (source, error) {
+	if useMmap() {
+		return parquetreader.MapParquetFile(path, nil, memory.DefaultAllocator)
+	}
+	return parquetreader.OpenParquetFile(path, nil, memory.DefaultAllocator)
+}
@@ -0,0 +1,444 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/internal/colspec"
+	"github.com/TFMV/supercharged/internal/partition"
+	"github.com/TFMV/supercharged/objstore"
+)
+
+// dataExts are the extensions of the files read from a directory input.
+var dataExts = map[string]bool{
+	".csv": true, ".tsv": true, ".tab": true, ".txt": true,
+	".parquet": true, ".pq": true,
+	".jsonl": true, ".ndjson": true,
+	".arrow": true, ".arrows": true, ".ipc": true,
+}
+
+// dataset is the files a --file glob such as 'data/2024-*.csv', or a
+// directory, stands for, with the Hive partition values of each.
+type dataset struct {
+	files  []string
+	values [][]partition.Value
+	// fields are the partition columns added to every file.
+	fields []arrow.Field
+}
+
+// expandInput returns the dataset of a glob or directory path, in lexical
+// order, or nil for a plain file, stdin or a URL. Every data file under a
+// directory is included, however deep. Directories named key=value below the
+// directory, or below the glob's fixed prefix, become partition columns, and
+// files are kept only when their partitions match the --partition filters.
+func expandInput(path string) (*dataset, error) {
+	filters, err := partition.ParseFilters(viper.GetStringSlice("partition"))
+	if err != nil {
+		return nil, fmt.Errorf("--partition: %w", err)
+	}
+	files, root, err := listInput(path)
+	if err != nil || files == nil {
+		if err == nil && len(filters) > 0 {
+			err = fmt.Errorf("--partition requires a directory or glob --file")
+		}
+		return nil, err
+	}
+
+	ds := &dataset{}
+	all := make([][]partition.Value, len(files))
+	for i, f := range files {
+		rel, err := filepath.Rel(root, filepath.Dir(f))
+		if err != nil {
+			rel = ""
+		}
+		if all[i], err = partition.Parse(rel); err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+	}
+	if ds.fields, err = partition.Fields(all); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, f := range filters {
+		if !slices.ContainsFunc(ds.fields, func(field arrow.Field) bool { return field.Name == f.Key }) {
+			return nil, fmt.Errorf("--partition: %s is not a partition key of %s", f.Key, path)
+		}
+	}
+	for i, f := range files {
+		if partition.Match(filters, all[i]) {
+			ds.files = append(ds.files, f)
+			ds.values = append(ds.values, all[i])
+		}
+	}
+	if len(ds.files) == 0 {
+		return nil, fmt.Errorf("no partitions of %s match --partition", path)
+	}
+	return ds, nil
+}
+
+// listInput returns the files of a glob or directory path and the directory
+// their partitions are read below, or no files for any other path.
+func listInput(path string) ([]string, string, error) {
+	if path == "-" || objstore.IsURL(path) {
+		return nil, "", nil
+	}
+	fi, err := os.Stat(path)
+	if err == nil && !fi.IsDir() {
+		return nil, "", nil
+	}
+	if err == nil {
+		var files []string
+		err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := d.Name()
+			if p != path && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				// Skip hidden files and _SUCCESS-style markers.
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() && dataExts[dataExt(p)] {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("list %s: %w", path, err)
+		}
+		if len(files) == 0 {
+			return nil, "", fmt.Errorf("no data files in directory %s", path)
+		}
+		return files, path, nil
+	}
+	if !strings.ContainsAny(path, "*?[") {
+		return nil, "", nil
+	}
+	matches, err := filepath.Glob(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("--file %s: %w", path, err)
+	}
+	var files []string
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err == nil && !fi.IsDir() {
+			files = append(files, m)
+		}
+	}
+	if len(files) == 0 {
+		return nil, "", fmt.Errorf("no files match %s", path)
+	}
+	sort.Strings(files)
+	// The root is the glob's directories up to the first with a pattern.
+	root := path
+	for strings.ContainsAny(root, "*?[") {
+		root = filepath.Dir(root)
+	}
+	return files, root, nil
+}
+
+// open opens the i-th file of ds with its partition columns.
+func (ds *dataset) open(i int) (source, error) {
+	src, err := openSource(ds.files[i])
+	if err != nil || len(ds.fields) == 0 {
+		return src, err
+	}
+	return newPartitionSource(src, ds.fields, ds.values[i])
+}
+
+// batchInputs returns the dataset of a --file glob or directory, or nil when
+// the input is a single file or comes from another source. Progress bars
+// are turned off for batches, as they would be drawn per file.
+func batchInputs() (*dataset, error) {
+	for _, key := range []string{"dsn", "prometheus", "flight"} {
+		if viper.GetString(key) != "" {
+			return nil, nil
+		}
+	}
+	ds, err := expandInput(viper.GetString("file"))
+	if err != nil || ds == nil {
+		return nil, err
+	}
+	viper.Set("quiet", true)
+	return ds, nil
+}
+
+// multiSource reads several inputs with the same schema as one, in order.
+type multiSource struct {
+	srcs []source
+}
+
+// openMulti opens every file of ds. They must all have the same schema;
+// CSV files whose inferred types differ can be aligned with --schema.
+func openMulti(ds *dataset) (source, error) {
+	m := &multiSource{}
+	for i, path := range ds.files {
+		src, err := ds.open(i)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		m.srcs = append(m.srcs, src)
+		if first := m.srcs[0].Schema(); !first.Equal(src.Schema()) {
+			m.Close()
+			return nil, fmt.Errorf("%s: schema %s differs from %s in %s", path, src.Schema(), first, ds.files[0])
+		}
+	}
+	return m, nil
+}
+
+func (m *multiSource) Schema() *arrow.Schema { return m.srcs[0].Schema() }
+
+func (m *multiSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	arrs := make([]arrow.Array, 0, len(m.srcs))
+	defer func() {
+		for _, a := range arrs {
+			a.Release()
+		}
+	}()
+	for _, src := range m.srcs {
+		a, err := src.ReadSingleColumn(ctx, column)
+		if err != nil {
+			return nil, err
+		}
+		arrs = append(arrs, a)
+	}
+	return array.Concatenate(arrs, memory.DefaultAllocator)
+}
+
+func (m *multiSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	var chunks []arrow.Array
+	defer func() {
+		for _, c := range chunks {
+			c.Release()
+		}
+	}()
+	var dt arrow.DataType
+	for _, src := range m.srcs {
+		chunked, err := src.ReadChunked(ctx, column)
+		if err != nil {
+			return nil, err
+		}
+		dt = chunked.DataType()
+		for _, c := range chunked.Chunks() {
+			c.Retain()
+			chunks = append(chunks, c)
+		}
+		chunked.Release()
+	}
+	return arrow.NewChunked(dt, chunks), nil
+}
+
+func (m *multiSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	recs := make([]arrow.Record, 0, len(m.srcs))
+	defer func() {
+		for _, r := range recs {
+			r.Release()
+		}
+	}()
+	var rows int64
+	for _, src := range m.srcs {
+		rec, err := src.ReadColumns(ctx, columns)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+		rows += rec.NumRows()
+	}
+	cols := make([]arrow.Array, recs[0].NumCols())
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	for i := range cols {
+		parts := make([]arrow.Array, len(recs))
+		for j, r := range recs {
+			parts[j] = r.Column(i)
+		}
+		col, err := array.Concatenate(parts, memory.DefaultAllocator)
+		if err != nil {
+			return nil, fmt.Errorf("concatenate %s: %w", recs[0].ColumnName(i), err)
+		}
+		cols[i] = col
+	}
+	return array.NewRecord(recs[0].Schema(), cols, rows), nil
+}
+
+// Chan streams the records of each input in turn.
+func (m *multiSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for _, src := range m.srcs {
+			in, inErrs := src.Chan(ctx)
+			for rec := range in {
+				select {
+				case recs <- rec:
+				case <-ctx.Done():
+					rec.Release()
+				}
+			}
+			if err := <-inErrs; err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	return recs, errs
+}
+
+func (m *multiSource) Close() error {
+	var first error
+	for _, src := range m.srcs {
+		if err := src.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// analyzeFiles scores --column in each file of ds on its own, --jobs files
+// at a time, and reports them file by file.
+func analyzeFiles(ds *dataset) error {
+	paths := ds.files
+	column := viper.GetString("column")
+	if column == "" {
+		return fmt.Errorf("--column is required to analyze several files (or use --merge)")
+	}
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"columns", len(viper.GetStringSlice("columns")) > 0},
+		{"bucket", viper.GetDuration("bucket") > 0},
+		{"group-by", viper.GetString("group-by") != ""},
+		{"period", viper.GetInt("period") > 0},
+		{"stream", viper.GetBool("stream")},
+		{"context", viper.GetBool("context")},
+		{"output", (viper.GetString("output") != "" || viper.GetString("output-format") != "") && !ndjsonOutput()},
+	} {
+		if f.set {
+			return fmt.Errorf("--%s with several files requires --merge", f.name)
+		}
+	}
+
+	jobs := viper.GetInt("jobs")
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	outs := make([]analyzeOutput, len(paths))
+	errs := make([]error, len(paths))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			outs[i], errs[i] = analyzeFile(ds, i, column)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%s: %w", paths[i], err)
+		}
+	}
+	for i := range outs {
+		outs[i].finish()
+	}
+
+	var err error
+	switch {
+	case ndjsonOutput():
+		var w *ndjsonWriter
+		if w, err = newNDJSONWriter(viper.GetString("output")); err != nil {
+			return err
+		}
+		for i := range outs {
+			w.input = paths[i]
+			if err = w.write("", &outs[i]); err != nil {
+				break
+			}
+		}
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	case viper.GetBool("json"):
+		byFile := make(map[string]analyzeOutput, len(paths))
+		for i, path := range paths {
+			byFile[path] = outs[i]
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(byFile)
+	default:
+		for i, path := range paths {
+			fmt.Printf("File: %s\n", path)
+			if err = printText(&outs[i]); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for i, out := range outs {
+		if err := checkBudget(out.flagged, out.Count); err != nil {
+			return fmt.Errorf("file %s: %w", paths[i], err)
+		}
+	}
+	return nil
+}
+
+// analyzeFile opens the i-th file of ds as openConfigured would and scores
+// column in it, by --time-column when set.
+func analyzeFile(ds *dataset, i int, column string) (analyzeOutput, error) {
+	src, err := ds.open(i)
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	if src, err = withTimes(src); err != nil {
+		return analyzeOutput{}, err
+	}
+	if src, err = withFilter(src); err != nil {
+		return analyzeOutput{}, err
+	}
+	if src, err = withExpr(src); err != nil {
+		return analyzeOutput{}, err
+	}
+	if src, err = withSample(src); err != nil {
+		return analyzeOutput{}, err
+	}
+	defer src.Close()
+	if colspec.IsPattern(column) {
+		if column, err = colspec.ResolveOne(src.Schema(), column); err != nil {
+			return analyzeOutput{}, fmt.Errorf("--column: %w", err)
+		}
+	}
+	src = traceSource(src)
+	if timeColumn := viper.GetString("time-column"); timeColumn != "" {
+		return analyzeTimed(src, column, timeColumn)
+	}
+	return analyzeWhole(src, column)
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+var benfordCmd = &cobra.Command{
+	Use:   "benford",
+	Short: "Test a column's first digits against Benford's law",
+	Long: `Test a column's first digits against Benford's law.
+
+Reports the share of values of --column with each first digit next to
+Benford's expectation, the chi-square statistic and p-value, and Nigrini's
+mean absolute deviation (MAD) with its conformity level. With --group-by,
+each segment of at least 100 values is tested on its own. A column or
+segment deviates when the p-value is below --alpha and the MAD exceeds
+--max-mad; the command exits with an error when any does.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		column := viper.GetString("column")
+		if column == "" {
+			return fmt.Errorf("--column is required")
+		}
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		column = viper.GetString("column")
+
+		test := anomaly.BenfordTest{Alpha: viper.GetFloat64("alpha"), MaxMAD: viper.GetFloat64("max-mad")}
+		// --group-by is read from this command's flags, since viper binds
+		// the key to analyze's.
+		key, _ := cmd.Flags().GetString("group-by")
+		var results []anomaly.Benford
+		if key != "" {
+			rec, err := src.ReadColumns(commandCtx, []string{column, key})
+			if err != nil {
+				return fmt.Errorf("read columns: %w", err)
+			}
+			defer rec.Release()
+			if results, err = test.TestGrouped(detectContext(), rec, column, key); err != nil {
+				return err
+			}
+		} else {
+			col, err := src.ReadSingleColumn(commandCtx, column)
+			if err != nil {
+				return fmt.Errorf("read column: %w", err)
+			}
+			defer col.Release()
+			res, err := test.Test(detectContext(), col)
+			if err != nil {
+				return err
+			}
+			results = []anomaly.Benford{*res}
+		}
+
+		var deviating int
+		for _, r := range results {
+			if r.Deviates {
+				deviating++
+			}
+		}
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if key == "" {
+				err = enc.Encode(results[0])
+			} else {
+				err = enc.Encode(results)
+			}
+			if err != nil {
+				return err
+			}
+		} else if key == "" {
+			r := results[0]
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "DIGIT\tSHARE\tEXPECTED")
+			for d := range r.Digits {
+				fmt.Fprintf(w, "%d\t%.4f\t%.4f\n", d+1, r.Digits[d], r.Expected[d])
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			fmt.Printf("Values: %d\nChi-square: %.2f (p=%.4g)\nMAD: %.4f (%s)\nDeviates: %v\n", r.Count, r.ChiSquare, r.PValue, r.MAD, r.Conformity, r.Deviates)
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUES\tCHI-SQUARE\tP-VALUE\tMAD\tCONFORMITY\tDEVIATES")
+			for _, r := range results {
+				fmt.Fprintf(w, "%s\t%d\t%.2f\t%.4g\t%.4f\t%s\t%v\n", r.Key, r.Count, r.ChiSquare, r.PValue, r.MAD, r.Conformity, r.Deviates)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		if deviating > 0 {
+			cmd.SilenceUsage = true
+			if key == "" {
+				return fmt.Errorf("%s deviates from Benford's law", column)
+			}
+			return fmt.Errorf("%d segments deviate from Benford's law", deviating)
+		}
+		return nil
+	},
+}
+
+func init() {
+	benfordCmd.Flags().String("group-by", "", "Key column whose segments are tested separately")
+	benfordCmd.Flags().Float64("max-mad", anomaly.DefaultMaxBenfordMAD, "Mean absolute deviation of digit shares above which a column deviates")
+	viper.BindPFlag("max-mad", benfordCmd.Flags().Lookup("max-mad"))
+	rootCmd.AddCommand(benfordCmd)
+}
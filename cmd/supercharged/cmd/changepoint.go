@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+var changepointCmd = &cobra.Command{
+	Use:   "changepoint",
+	Short: "Find level shifts and variance changes in an ordered column",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		column := viper.GetString("column")
+		if column == "" {
+			return fmt.Errorf("--column is required")
+		}
+
+		var detector anomaly.ChangePointDetector
+		switch algo := viper.GetString("algorithm"); algo {
+		case "cusum":
+			detector = anomaly.CUSUMDetector{Threshold: viper.GetFloat64("critical"), MinSegment: viper.GetInt("min-segment")}
+		case "pelt":
+			detector = anomaly.PELTDetector{Penalty: viper.GetFloat64("penalty"), MinSegment: viper.GetInt("min-segment")}
+		default:
+			return fmt.Errorf("unknown algorithm %q", algo)
+		}
+
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		column = viper.GetString("column")
+		arr, err := src.ReadSingleColumn(commandCtx, column)
+		if err != nil {
+			return fmt.Errorf("read column: %w", err)
+		}
+		defer arr.Release()
+
+		cp, err := detector.ChangePoints(detectContext(), arr)
+		if err != nil {
+			return fmt.Errorf("detect change points: %w", err)
+		}
+
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(cp)
+		}
+		fmt.Printf("Change points: %v\n", cp.Indices)
+		for i, s := range cp.Segments {
+			fmt.Printf("Segment %d: rows %d-%d, count=%d, mean=%.4g, stddev=%.4g\n", i, s.Start, s.End-1, s.Count, s.Mean, s.StdDev)
+		}
+		return nil
+	},
+}
+
+func init() {
+	changepointCmd.Flags().String("algorithm", "cusum", "Change-point algorithm: cusum (level shifts) or pelt (mean and variance changes)")
+	changepointCmd.Flags().Float64("critical", anomaly.DefaultCUSUMThreshold, "Critical normalized CUSUM for cusum")
+	changepointCmd.Flags().Float64("penalty", 0, "Cost per change point for pelt (default 3*ln(n))")
+	changepointCmd.Flags().Int("min-segment", anomaly.DefaultMinSegment, "Shortest segment to create")
+	for _, name := range []string{"algorithm", "critical", "penalty", "min-segment"} {
+		viper.BindPFlag(name, changepointCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(changepointCmd)
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+	"github.com/TFMV/supercharged/kafkareader"
+)
+
+// checkpointVersion is the format version of --checkpoint files.
+const checkpointVersion = 1
+
+// fileOffsetKey is the schema metadata key under which the tailer marks the
+// last record of each poll with the file offset following its lines.
+const fileOffsetKey = "watch.offset"
+
+// checkpoint is the content of a --checkpoint file: the streaming detector's
+// statistics and the position in the stream they cover.
+type checkpoint struct {
+	Version int            `json:"version"`
+	Input   string         `json:"input"`
+	Column  string         `json:"column"`
+	Method  anomaly.Method `json:"method"`
+	// Rows is the number of rows scored, from which row numbers continue.
+	Rows  int64               `json:"rows"`
+	State anomaly.StreamState `json:"state"`
+	// Offset is the file offset to resume a tailed file at.
+	Offset int64 `json:"offset,omitempty"`
+	// Partitions maps Kafka partitions to the offset to resume them at.
+	Partitions map[int]int64 `json:"partitions,omitempty"`
+	Saved      time.Time     `json:"saved"`
+}
+
+// checkpointer saves the state of a live detection to the --checkpoint file
+// every --checkpoint-interval and when the stream ends. It only saves after
+// a record that ends a batch of the stream, so the statistics saved cover
+// the input up to the saved position exactly.
+type checkpointer struct {
+	path  string
+	every time.Duration
+	last  time.Time
+	// loaded is set when the file held a checkpoint to resume from.
+	loaded bool
+	// settled is set when the last record seen ended a batch, and pending
+	// when it has not been saved yet.
+	settled, pending bool
+	cp               checkpoint
+}
+
+// openCheckpoint returns the checkpointer for --checkpoint, or nil when it
+// is unset, with the checkpoint already in the file loaded. input names the
+// stream, a file path or Kafka address, which a loaded checkpoint must be
+// for, as it must be for column and the detector --method selects.
+func openCheckpoint(input, column string) (*checkpointer, error) {
+	path := viper.GetString("checkpoint")
+	if path == "" {
+		return nil, nil
+	}
+	c := &checkpointer{
+		path:  path,
+		every: viper.GetDuration("checkpoint-interval"),
+		last:  time.Now(),
+		cp:    checkpoint{Version: checkpointVersion, Input: input, Column: column, Method: streamMethod()},
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("decode checkpoint %s: %w", path, err)
+	}
+	switch {
+	case cp.Version != checkpointVersion:
+		return nil, fmt.Errorf("checkpoint %s has version %d, want %d", path, cp.Version, checkpointVersion)
+	case cp.Input != input:
+		return nil, fmt.Errorf("checkpoint %s is for %s, not %s; remove it to start over", path, cp.Input, input)
+	case cp.Column != column || cp.Method != c.cp.Method:
+		return nil, fmt.Errorf("checkpoint %s is for column %s by %s, not %s by %s; remove it to start over", path, cp.Column, cp.Method, column, c.cp.Method)
+	}
+	c.cp, c.loaded = cp, true
+	return c, nil
+}
+
+// streamMethod returns the method of the detector newBatchDetector returns.
+func streamMethod() anomaly.Method {
+	return anomaly.Method(viper.GetString("method"))
+}
+
+// restore loads the checkpoint into d and returns the number of rows it
+// covers; it returns 0 when there was nothing to resume.
+func (c *checkpointer) restore(d batchDetector) (int64, error) {
+	if !c.loaded {
+		return 0, nil
+	}
+	if err := d.Restore(c.cp.State); err != nil {
+		return 0, fmt.Errorf("checkpoint %s: %w", c.path, err)
+	}
+	return c.cp.Rows, nil
+}
+
+// update notes the stream position rec carries, if any, once d has scored
+// it and rows have been scored in all, and saves the checkpoint when one is
+// due.
+func (c *checkpointer) update(rec arrow.Record, d batchDetector, rows int64) error {
+	md := rec.Schema().Metadata()
+	c.settled = false
+	if v, ok := md.GetValue(fileOffsetKey); ok {
+		offset, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("stream position %q: %w", v, err)
+		}
+		c.cp.Offset, c.settled = offset, true
+	}
+	if offsets, ok := kafkareader.Offsets(rec); ok {
+		if c.cp.Partitions == nil {
+			c.cp.Partitions = map[int]int64{}
+		}
+		for p, o := range offsets {
+			c.cp.Partitions[p] = o
+		}
+		c.settled = true
+	}
+	if !c.settled {
+		return nil
+	}
+	c.cp.Rows, c.cp.State, c.pending = rows, d.State(), true
+	if time.Since(c.last) < c.every {
+		return nil
+	}
+	return c.save()
+}
+
+// close saves the last checkpoint not saved yet, when the stream stopped at
+// the end of a batch.
+func (c *checkpointer) close() error {
+	if !c.pending {
+		return nil
+	}
+	return c.save()
+}
+
+// save writes the checkpoint to a temporary file renamed over the old one,
+// so a crash leaves either the old checkpoint or the new one.
+func (c *checkpointer) save() error {
+	c.cp.Saved = time.Now().UTC()
+	data, err := json.MarshalIndent(c.cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode checkpoint: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("write checkpoint: %w", err)
+	}
+	c.last, c.pending = time.Now(), false
+	return nil
+}
+
+// withMetadata returns rec with key set to value in its schema metadata,
+// releasing rec.
+func withMetadata(rec arrow.Record, key, value string) arrow.Record {
+	defer rec.Release()
+	md := rec.Schema().Metadata()
+	keys := append(append([]string(nil), md.Keys()...), key)
+	values := append(append([]string(nil), md.Values()...), value)
+	md = arrow.NewMetadata(keys, values)
+	return array.NewRecord(arrow.NewSchema(rec.Schema().Fields(), &md), rec.Columns(), rec.NumRows())
+}
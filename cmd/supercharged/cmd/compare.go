@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare column distributions between two datasets",
+	Long: `Compare column distributions between two datasets.
+
+Compares each numeric column of --against with the same column of the input,
+taken as the reference, and reports the Kolmogorov-Smirnov statistic and
+p-value, the population stability index and the Jensen-Shannon divergence.
+--columns restricts the comparison, which otherwise covers every numeric
+column the two share. A column drifted when the KS p-value is below --alpha
+or PSI or JS exceed --max-psi or --max-js; the command exits with an error
+when any did.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		against := viper.GetString("against")
+		if against == "" {
+			return fmt.Errorf("--against is required")
+		}
+		ref, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer ref.Close()
+		columns := viper.GetStringSlice("columns")
+		var read []string
+		if len(columns) > 0 {
+			read = columns
+		}
+		cur, err := openSource(against)
+		if err != nil {
+			return err
+		}
+		if cur, err = withExpr(cur); err != nil {
+			return err
+		}
+		defer cur.Close()
+
+		refRec, err := ref.ReadColumns(commandCtx, read)
+		if err != nil {
+			return fmt.Errorf("read reference: %w", err)
+		}
+		defer refRec.Release()
+		curRec, err := cur.ReadColumns(commandCtx, read)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", against, err)
+		}
+		defer curRec.Release()
+
+		d := anomaly.DriftDetector{
+			Bins:   viper.GetInt("bins"),
+			Alpha:  viper.GetFloat64("alpha"),
+			MaxPSI: viper.GetFloat64("max-psi"),
+			MaxJS:  viper.GetFloat64("max-js"),
+		}
+		drifts, err := d.CompareRecords(detectContext(), refRec, curRec, columns)
+		if err != nil {
+			return fmt.Errorf("compare: %w", err)
+		}
+
+		var drifted int
+		for _, c := range drifts {
+			if c.Drifted {
+				drifted++
+			}
+		}
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(drifts); err != nil {
+				return err
+			}
+		} else {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "COLUMN\tREF ROWS\tCUR ROWS\tKS\tP-VALUE\tPSI\tJS\tDRIFTED")
+			for _, c := range drifts {
+				fmt.Fprintf(w, "%s\t%d\t%d\t%.4f\t%.4g\t%.4f\t%.4f\t%v\n", c.Column, c.ReferenceCount, c.CurrentCount, c.KS, c.PValue, c.PSI, c.JS, c.Drifted)
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+		if drifted > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d columns drifted", drifted)
+		}
+		return nil
+	},
+}
+
+func init() {
+	compareCmd.Flags().String("against", "", "Current dataset to compare with the input, which is the reference")
+	viper.BindPFlag("against", compareCmd.Flags().Lookup("against"))
+	compareCmd.Flags().Int("bins", anomaly.DefaultDriftBins, "Reference quantile bins for PSI and JS")
+	viper.BindPFlag("bins", compareCmd.Flags().Lookup("bins"))
+	compareCmd.Flags().Float64("max-psi", anomaly.DefaultMaxPSI, "Population stability index above which a column drifted")
+	viper.BindPFlag("max-psi", compareCmd.Flags().Lookup("max-psi"))
+	compareCmd.Flags().Float64("max-js", anomaly.DefaultMaxJS, "Jensen-Shannon divergence (bits) above which a column drifted")
+	viper.BindPFlag("max-js", compareCmd.Flags().Lookup("max-js"))
+	rootCmd.AddCommand(compareCmd)
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// readConfig reads the --config file, or $HOME/.supercharged.yaml when it
+// exists, and applies the --profile named in it. A --config file that cannot
+// be read is an error; a missing default one is not.
+func readConfig() error {
+	if err := viper.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if cfgFile != "" || !errors.As(err, &notFound) {
+			return fmt.Errorf("read config: %w", err)
+		}
+	} else if cfgFile != "" {
+		// Notes go to stderr so --json output stays clean.
+		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+	}
+	if name := viper.GetString("profile"); name != "" {
+		return applyProfile(name)
+	}
+	return nil
+}
+
+// applyProfile merges the config file's profiles.<name> section, such as
+//
+//	profiles:
+//	  nightly-latency:
+//	    file: s3://metrics/latency.parquet
+//	    columns: [p50, p99=4]
+//	    method: mad
+//	    threshold: 3.5
+//	    output: anomalies.ndjson
+//
+// over its top level, so the profile's keys take the place of the flags of
+// the same names. Flags given on the command line and SC_ environment
+// variables still take precedence.
+func applyProfile(name string) error {
+	profiles := viper.GetStringMap("profiles")
+	// viper lowercases keys, so profile names match case-insensitively.
+	settings, ok := profiles[strings.ToLower(name)].(map[string]any)
+	if !ok {
+		names := make([]string, 0, len(profiles))
+		for n := range profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("--profile %s: the config file defines no profiles", name)
+		}
+		return fmt.Errorf("--profile %s not found in the config file; have %s", name, strings.Join(names, ", "))
+	}
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("--profile %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/dedup"
+)
+
+var dedupCmd = &cobra.Command{
+	Use:   "dedup-check",
+	Short: "Find duplicate and near-duplicate rows",
+	Long: `Find duplicate and near-duplicate rows.
+
+Hashes every row of the input in one streaming pass. Rows identical to an
+earlier row are exact duplicates; rows that differ but match an earlier row
+on --columns (every column by default), after lower-casing strings, dropping
+punctuation, collapsing whitespace and rounding floats to --digits
+significant digits, are near-duplicates. The largest groups of each kind are
+reported with example rows, and the command exits with an error when any
+duplicates are found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		f := dedup.NewFinder(dedup.Config{
+			Columns:   viper.GetStringSlice("columns"),
+			Digits:    viper.GetInt("digits"),
+			MaxGroups: viper.GetInt("max-groups"),
+		})
+		ctx, cancel := context.WithCancel(commandCtx)
+		defer cancel()
+		recs, errs := src.Chan(ctx)
+		for rec := range recs {
+			err := f.Add(rec)
+			rec.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if err := <-errs; err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+
+		r := f.Report()
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("Rows: %d\n", r.Rows)
+			fmt.Printf("Exact duplicates: %d rows in %d groups\n", r.ExactDuplicates, r.ExactGroups)
+			for _, g := range r.Exact {
+				rows := make([]string, len(g.Examples))
+				for i, ex := range g.Examples {
+					rows[i] = fmt.Sprint(ex.Row)
+				}
+				fmt.Printf("  %d rows (%s): %s\n", g.Count, strings.Join(rows, ", "), g.Examples[0].Values)
+			}
+			on := "all columns"
+			if len(r.NearColumns) > 0 {
+				on = strings.Join(r.NearColumns, ", ")
+			}
+			fmt.Printf("Near duplicates on %s: %d rows in %d groups\n", on, r.NearDuplicates, r.NearGroups)
+			for _, g := range r.Near {
+				fmt.Printf("  %d rows:\n", g.Count)
+				for _, ex := range g.Examples {
+					fmt.Printf("    row %d: %s\n", ex.Row, ex.Values)
+				}
+			}
+		}
+		if n := r.ExactDuplicates + r.NearDuplicates; n > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d duplicate rows", n)
+		}
+		return nil
+	},
+}
+
+func init() {
+	dedupCmd.Flags().Int("digits", dedup.DefaultDigits, "Significant digits floats are compared at for near-duplicates")
+	viper.BindPFlag("digits", dedupCmd.Flags().Lookup("digits"))
+	dedupCmd.Flags().Int("max-groups", dedup.DefaultMaxGroups, "Largest groups of each kind to report")
+	viper.BindPFlag("max-groups", dedupCmd.Flags().Lookup("max-groups"))
+	rootCmd.AddCommand(dedupCmd)
+}
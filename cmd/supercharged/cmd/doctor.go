@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/csvreader"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose why a CSV file fails to parse",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := inputPath()
+		if err != nil {
+			return err
+		}
+		in, err := openInput(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+
+		d, err := csvreader.Diagnose(in.r, viper.GetInt("sniff-bytes"))
+		if err != nil {
+			return fmt.Errorf("diagnose: %w", err)
+		}
+
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(d)
+		}
+
+		if d.Compression != "" {
+			fmt.Printf("Compression:  %s\n", d.Compression)
+		}
+		fmt.Printf("Encoding:     %s\n", d.Encoding)
+		fmt.Printf("Delimiter:    %q\n", d.Delimiter)
+		fmt.Printf("Quoting:      %s\n", d.Quoting)
+		fmt.Printf("Header:       %t\n", d.HasHeader)
+		fmt.Printf("Line endings: lf=%d crlf=%d cr=%d\n", d.LineEndings["lf"], d.LineEndings["crlf"], d.LineEndings["cr"])
+		fmt.Printf("Fields:       %d (%d rows sampled)\n", d.Fields, d.RowsSampled)
+		if len(d.RaggedRows) > 0 {
+			fmt.Printf("Ragged rows:  %s\n", formatLines(d.RaggedRows))
+		}
+		if len(d.BadQuoteRows) > 0 {
+			fmt.Printf("Bad quotes:   %s\n", formatLines(d.BadQuoteRows))
+		}
+		if len(d.Suggestions) == 0 {
+			fmt.Println("No problems found.")
+			return nil
+		}
+		fmt.Println("Suggestions:")
+		for _, s := range d.Suggestions {
+			fmt.Printf("  - %s\n", s)
+		}
+		return nil
+	},
+}
+
+// formatLines renders line numbers, eliding the middle of long lists.
+func formatLines(lines []int) string {
+	const max = 10
+	parts := make([]string, 0, max+1)
+	for i, l := range lines {
+		if i == max {
+			parts = append(parts, fmt.Sprintf("... (%d more)", len(lines)-max))
+			break
+		}
+		parts = append(parts, fmt.Sprint(l))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func init() {
+	doctorCmd.Flags().Int("sniff-bytes", csvreader.DefaultSniffBytes, "Number of bytes to inspect")
+	viper.BindPFlag("sniff-bytes", doctorCmd.Flags().Lookup("sniff-bytes"))
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+// exprSource adds the --expr column, named by the expression itself, to the
+// wrapped source. It is evaluated per record batch from the columns it reads,
+// so every read path, streaming included, sees it like any other column.
+type exprSource struct {
+	source
+	expr *anomaly.Expr
+}
+
+// withExpr wraps src with the --expr column when the flag is set.
+func withExpr(src source) (source, error) {
+	expr, err := configuredExpr()
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	if expr == nil {
+		return src, nil
+	}
+	return exprSource{source: src, expr: expr}, nil
+}
+
+// name returns the computed column's name.
+func (s exprSource) name() string {
+	return s.expr.String()
+}
+
+func (s exprSource) Schema() *arrow.Schema {
+	schema := s.source.Schema()
+	if schema == nil {
+		return nil
+	}
+	fields := append(schema.Fields(), arrow.Field{Name: s.name(), Type: arrow.PrimitiveTypes.Float64, Nullable: true})
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+func (s exprSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	if column != s.name() {
+		return s.source.ReadSingleColumn(ctx, column)
+	}
+	rec, err := s.ReadColumns(ctx, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+func (s exprSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	if column != s.name() {
+		return s.source.ReadChunked(ctx, column)
+	}
+	col, err := s.ReadSingleColumn(ctx, column)
+	if err != nil {
+		return nil, err
+	}
+	defer col.Release()
+	return arrow.NewChunked(col.DataType(), []arrow.Array{col}), nil
+}
+
+// ReadColumns reads the requested columns, evaluating the expression from the
+// columns it references when it is among them. A nil columns slice keeps
+// every column plus the expression.
+func (s exprSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	wanted := columns == nil
+	for _, c := range columns {
+		wanted = wanted || c == s.name()
+	}
+	if !wanted {
+		return s.source.ReadColumns(ctx, columns)
+	}
+
+	var read []string
+	if columns != nil {
+		seen := map[string]bool{}
+		for _, c := range append(append([]string(nil), columns...), s.expr.Columns()...) {
+			if c != s.name() && !seen[c] {
+				seen[c] = true
+				read = append(read, c)
+			}
+		}
+	}
+	rec, err := s.source.ReadColumns(ctx, read)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	full, err := s.append(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if columns == nil {
+		return full, nil
+	}
+	defer full.Release()
+	fields := make([]arrow.Field, len(columns))
+	cols := make([]arrow.Array, len(columns))
+	for i, c := range columns {
+		j := full.Schema().FieldIndices(c)[0]
+		fields[i], cols[i] = full.Schema().Field(j), full.Column(j)
+	}
+	return array.NewRecord(arrow.NewSchema(fields, nil), cols, full.NumRows()), nil
+}
+
+// append returns rec with the expression evaluated over it as a final
+// column.
+func (s exprSource) append(ctx context.Context, rec arrow.Record) (arrow.Record, error) {
+	vals, err := s.expr.Eval(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	defer vals.Release()
+	fields := append(rec.Schema().Fields(), arrow.Field{Name: s.name(), Type: arrow.PrimitiveTypes.Float64, Nullable: true})
+	cols := append(append([]arrow.Array(nil), rec.Columns()...), vals)
+	md := rec.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &md), cols, rec.NumRows()), nil
+}
+
+// Chan streams the wrapped records with the expression column added.
+func (s exprSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	in, inErrs := s.source.Chan(ctx)
+	return exprStream(ctx, s.expr, in, inErrs)
+}
+
+// exprStream adds the expression column to each record of in; records are
+// passed through unchanged when expr is nil.
+func exprStream(ctx context.Context, expr *anomaly.Expr, in <-chan arrow.Record, inErrs <-chan error) (<-chan arrow.Record, <-chan error) {
+	if expr == nil {
+		return in, inErrs
+	}
+	s := exprSource{expr: expr}
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for rec := range in {
+			out, err := s.append(ctx, rec)
+			rec.Release()
+			if err != nil {
+				errs <- err
+				// Drain so the producer can finish.
+				for rec := range in {
+					rec.Release()
+				}
+				return
+			}
+			select {
+			case recs <- out:
+			case <-ctx.Done():
+				out.Release()
+			}
+		}
+		if err := <-inErrs; err != nil {
+			errs <- err
+		}
+	}()
+	return recs, errs
+}
+
+// configuredExpr parses --expr, returning nil when it is unset.
+func configuredExpr() (*anomaly.Expr, error) {
+	text := viper.GetString("expr")
+	if text == "" {
+		return nil, nil
+	}
+	expr, err := anomaly.ParseExpr(text)
+	if err != nil {
+		return nil, fmt.Errorf("--expr: %w", err)
+	}
+	return expr, nil
+}
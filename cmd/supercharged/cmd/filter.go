@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+	"github.com/TFMV/supercharged/internal/collect"
+)
+
+// filterSource keeps the rows of the wrapped source for which the --filter
+// predicate holds. It is evaluated per record batch from the columns it
+// reads, so detection only ever sees the kept rows, and the row numbers it
+// reports count them alone.
+type filterSource struct {
+	source
+	filter *anomaly.Filter
+}
+
+// withFilter wraps src with the --filter predicate when the flag is set.
+func withFilter(src source) (source, error) {
+	filter, err := configuredFilter()
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	if filter == nil {
+		return src, nil
+	}
+	if schema := src.Schema(); schema != nil {
+		for _, c := range filter.Columns() {
+			if !schema.HasField(c) {
+				src.Close()
+				return nil, fmt.Errorf("--filter: column %s not found", c)
+			}
+		}
+	}
+	return filterSource{source: src, filter: filter}, nil
+}
+
+func (s filterSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	rec, err := s.ReadColumns(ctx, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+func (s filterSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	recs, errs := s.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, column)
+}
+
+// ReadColumns reads the requested columns together with those the filter
+// references, and returns the requested ones of the kept rows. A nil
+// columns slice keeps every column.
+func (s filterSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	var read []string
+	if columns != nil {
+		seen := map[string]bool{}
+		for _, c := range append(append([]string(nil), columns...), s.filter.Columns()...) {
+			if !seen[c] {
+				seen[c] = true
+				read = append(read, c)
+			}
+		}
+	}
+	rec, err := s.source.ReadColumns(ctx, read)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	kept, err := s.filter.Apply(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	if columns == nil || len(read) == len(columns) {
+		return kept, nil
+	}
+	defer kept.Release()
+	fields := make([]arrow.Field, len(columns))
+	cols := make([]arrow.Array, len(columns))
+	for i, c := range columns {
+		j := kept.Schema().FieldIndices(c)[0]
+		fields[i], cols[i] = kept.Schema().Field(j), kept.Column(j)
+	}
+	return array.NewRecord(arrow.NewSchema(fields, nil), cols, kept.NumRows()), nil
+}
+
+// Chan streams the kept rows of the wrapped records.
+func (s filterSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	in, inErrs := s.source.Chan(ctx)
+	return filterStream(ctx, s.filter, in, inErrs)
+}
+
+// filterStream keeps the rows of each record of in for which filter holds;
+// records are passed through unchanged when filter is nil. Records left
+// empty are still sent, as they may mark a stream position to checkpoint.
+func filterStream(ctx context.Context, filter *anomaly.Filter, in <-chan arrow.Record, inErrs <-chan error) (<-chan arrow.Record, <-chan error) {
+	if filter == nil {
+		return in, inErrs
+	}
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for rec := range in {
+			out, err := filter.Apply(ctx, rec)
+			rec.Release()
+			if err != nil {
+				errs <- err
+				// Drain so the producer can finish.
+				for rec := range in {
+					rec.Release()
+				}
+				return
+			}
+			select {
+			case recs <- out:
+			case <-ctx.Done():
+				out.Release()
+			}
+		}
+		if err := <-inErrs; err != nil {
+			errs <- err
+		}
+	}()
+	return recs, errs
+}
+
+// configuredFilter parses --filter, returning nil when it is unset.
+func configuredFilter() (*anomaly.Filter, error) {
+	text := viper.GetString("filter")
+	if text == "" {
+		return nil, nil
+	}
+	filter, err := anomaly.ParseFilter(text)
+	if err != nil {
+		return nil, fmt.Errorf("--filter: %w", err)
+	}
+	return filter, nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+var gapsCmd = &cobra.Command{
+	Use:   "gaps",
+	Short: "Find missing samples and irregular sampling in a time series",
+	Long: `Find missing samples and irregular sampling in a time series.
+
+Walks --time-column in row order and compares each step with --interval (by
+default the median step). A step longer than the interval by more than
+--tolerance of it is a gap, reported with the number of samples missing; a
+shorter one is irregular. Repeated and out-of-order timestamps are counted
+too. The command exits with an error when any gap is found.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// --time-column and --interval are read from this command's flags,
+		// since viper binds the keys to analyze's and watch's.
+		timeColumn, _ := cmd.Flags().GetString("time-column")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if timeColumn == "" {
+			return fmt.Errorf("--time-column is required")
+		}
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		col, err := src.ReadSingleColumn(commandCtx, timeColumn)
+		if err != nil {
+			return fmt.Errorf("read column: %w", err)
+		}
+		defer col.Release()
+
+		d := anomaly.GapDetector{Interval: interval, Tolerance: viper.GetFloat64("tolerance")}
+		g, err := d.Find(detectContext(), col)
+		if err != nil {
+			return err
+		}
+
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(g); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("Samples: %d from %s to %s every %s\n", g.Count, g.Start.Format(time.RFC3339Nano), g.End.Format(time.RFC3339Nano), g.Interval)
+			fmt.Printf("Gaps: %d, %d samples missing\n", len(g.Gaps), g.Missing)
+			for _, gap := range g.Gaps {
+				fmt.Printf("  row %d: %s to %s (%s, %d missing)\n", gap.Row, gap.From.Format(time.RFC3339Nano), gap.To.Format(time.RFC3339Nano), gap.To.Sub(gap.From), gap.Missing)
+			}
+			fmt.Printf("Irregular: %d\nDuplicates: %d\nOut of order: %d\n", g.Irregular, g.Duplicates, g.OutOfOrder)
+			if g.Nulls > 0 {
+				fmt.Printf("Skipped: %d null\n", g.Nulls)
+			}
+		}
+		if len(g.Gaps) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d gaps found", len(g.Gaps))
+		}
+		return nil
+	},
+}
+
+func init() {
+	gapsCmd.Flags().String("time-column", "", "Timestamp column to check (required)")
+	gapsCmd.Flags().Duration("interval", 0, "Expected step between samples (default: the median step)")
+	gapsCmd.Flags().Float64("tolerance", anomaly.DefaultGapTolerance, "Fraction of the interval a step may differ by and still be regular")
+	viper.BindPFlag("tolerance", gapsCmd.Flags().Lookup("tolerance"))
+	rootCmd.AddCommand(gapsCmd)
+}
@@ -0,0 +1,729 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/csvreader"
+	"github.com/TFMV/supercharged/dbreader"
+	"github.com/TFMV/supercharged/flightreader"
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/TFMV/supercharged/internal/colspec"
+	"github.com/TFMV/supercharged/internal/typespec"
+	"github.com/TFMV/supercharged/jsonreader"
+	"github.com/TFMV/supercharged/objstore"
+	"github.com/TFMV/supercharged/parquetreader"
+	"github.com/TFMV/supercharged/promreader"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// source is the common surface of the input readers used by the CLI.
+type source interface {
+	Schema() *arrow.Schema
+	ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error)
+	ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error)
+	ReadColumns(ctx context.Context, columns []string) (arrow.Record, error)
+	Chan(ctx context.Context) (<-chan arrow.Record, <-chan error)
+	Close() error
+}
+
+// inputPath returns the --file value, or "-" when it is empty and stdin is
+// a pipe or redirected file.
+func inputPath() (string, error) {
+	path := viper.GetString("file")
+	if path != "" {
+		return path, nil
+	}
+	if fi, err := os.Stdin.Stat(); err == nil && fi.Mode()&os.ModeCharDevice == 0 {
+		return "-", nil
+	}
+	return "", fmt.Errorf("--file is required")
+}
+
+// openConfigured opens the input named by the flags: the --dsn query,
+// --prometheus range query or --flight endpoint when set, otherwise --file
+// (every file of a glob or directory, read as one) or stdin, with the
+// --time-format columns parsed, the --filter rows kept, the --expr column
+// added and --limit and --sample applied. Reads are traced under the
+// command's span.
+// --column and --columns selectors are resolved against its schema.
+func openConfigured() (source, error) {
+	src, err := openFlagged()
+	if err != nil {
+		return nil, err
+	}
+	if src, err = withTimes(src); err != nil {
+		return nil, err
+	}
+	if src, err = withFilter(src); err != nil {
+		return nil, err
+	}
+	if src, err = withExpr(src); err != nil {
+		return nil, err
+	}
+	if src, err = withSample(src); err != nil {
+		return nil, err
+	}
+	if err := resolveColumns(src.Schema()); err != nil {
+		src.Close()
+		return nil, err
+	}
+	if src, err = withTransform(src); err != nil {
+		return nil, err
+	}
+	return traceSource(src), nil
+}
+
+// resolveColumns replaces a --column given by index or pattern with the name
+// of the one column it selects, and expands each --columns entry to the
+// columns it selects, keeping any =threshold suffix, so that commands
+// reading the flags after opening the input only see plain names. Sources
+// whose schema is not known up front are left alone.
+func resolveColumns(schema *arrow.Schema) error {
+	if schema == nil {
+		return nil
+	}
+	if sel := viper.GetString("column"); sel != "" && colspec.IsPattern(sel) {
+		name, err := colspec.ResolveOne(schema, sel)
+		if err != nil {
+			return fmt.Errorf("--column: %w", err)
+		}
+		viper.Set("column", name)
+	}
+	entries := viper.GetStringSlice("columns")
+	if len(entries) == 0 {
+		return nil
+	}
+	var expanded []string
+	for _, entry := range entries {
+		sel, threshold, hasThreshold := strings.Cut(entry, "=")
+		if !colspec.IsPattern(sel) {
+			expanded = append(expanded, entry)
+			continue
+		}
+		names, err := colspec.Resolve(schema, sel)
+		if err != nil {
+			return fmt.Errorf("--columns: %w", err)
+		}
+		for _, name := range names {
+			if hasThreshold {
+				name += "=" + threshold
+			}
+			expanded = append(expanded, name)
+		}
+	}
+	viper.Set("columns", expanded)
+	return nil
+}
+
+func openFlagged() (source, error) {
+	if dsn := viper.GetString("dsn"); dsn != "" {
+		query := viper.GetString("query")
+		if query == "" {
+			return nil, fmt.Errorf("--query is required with --dsn")
+		}
+		return dbreader.NewDBReader(commandCtx, viper.GetString("driver"), dsn, query, memory.DefaultAllocator)
+	}
+	if server := viper.GetString("prometheus"); server != "" {
+		expr := viper.GetString("promql")
+		if expr == "" {
+			return nil, fmt.Errorf("--promql is required with --prometheus")
+		}
+		end := time.Now()
+		q := promreader.Query{Expr: expr, Start: end.Add(-viper.GetDuration("since")), End: end, Step: viper.GetDuration("step")}
+		return promreader.NewPromReader(commandCtx, server, q, nil, memory.DefaultAllocator)
+	}
+	if location := viper.GetString("flight"); location != "" {
+		ticket := viper.GetString("ticket")
+		if ticket == "" {
+			return nil, fmt.Errorf("--ticket is required with --flight")
+		}
+		return flightreader.NewFlightReader(commandCtx, location, ticket, memory.DefaultAllocator)
+	}
+	path, err := inputPath()
+	if err != nil {
+		return nil, err
+	}
+	ds, err := expandInput(path)
+	if err != nil {
+		return nil, err
+	}
+	if ds != nil {
+		viper.Set("quiet", true)
+		return openMulti(ds)
+	}
+	return openSource(path)
+}
+
+// inputFormat returns the --format value, or guesses it from the extension.
+func inputFormat(path string) string {
+	if format := viper.GetString("format"); format != "" {
+		return format
+	}
+	switch dataExt(path) {
+	case ".parquet", ".pq":
+		return "parquet"
+	case ".jsonl", ".ndjson":
+		return "jsonl"
+	case ".arrow", ".arrows", ".ipc", ".feather":
+		return "arrow"
+	default:
+		return "csv"
+	}
+}
+
+// dataExt returns the lower-cased extension of path, or of an object URL's
+// path, looking past a compression suffix.
+func dataExt(path string) string {
+	if objstore.IsURL(path) {
+		if u, err := url.Parse(path); err == nil {
+			path = u.Path
+		}
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" || ext == ".zst" {
+		// data.csv.gz and data.jsonl.zst are decompressed by the readers.
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+	}
+	return ext
+}
+
+// objectConfig returns the object store credentials from the environment,
+// overridden by the s3.* and gcs.* keys of the config file.
+func objectConfig() objstore.Config {
+	cfg := objstore.ConfigFromEnv()
+	for key, dst := range map[string]*string{
+		"s3.access-key-id":     &cfg.AccessKeyID,
+		"s3.secret-access-key": &cfg.SecretAccessKey,
+		"s3.session-token":     &cfg.SessionToken,
+		"s3.region":            &cfg.Region,
+		"s3.endpoint":          &cfg.Endpoint,
+		"gcs.token":            &cfg.GCSToken,
+	} {
+		if v := viper.GetString(key); v != "" {
+			*dst = v
+		}
+	}
+	return cfg
+}
+
+// openSource opens path, or stdin for "-", with the reader matching its
+// format. Remote Parquet objects are read with range requests; other remote
+// inputs are streamed.
+func openSource(path string) (source, error) {
+	if objstore.IsURL(path) && inputFormat(path) == "parquet" {
+		obj, err := objstore.Open(commandCtx, path, objectConfig())
+		if err != nil {
+			return nil, err
+		}
+		if obj.Size() >= 0 {
+			return parquetreader.NewParquetReader(obj, nil, memory.DefaultAllocator)
+		}
+	}
+	in, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	return newSource(in, inputFormat(path), path)
+}
+
+// newSource wraps in with the reader for format. path is only used to reopen
+// seekable Parquet files.
+func newSource(in *input, format, path string) (source, error) {
+	switch format {
+	case "csv":
+		return newCSVSource(in, path)
+	case "jsonl", "ndjson":
+		return newJSONSource(in)
+	case "parquet":
+		return openParquet(path, in)
+	case "arrow":
+		if f, ok := in.r.(*os.File); ok && in.seeker != nil {
+			if isArrowFile(f) {
+				return openArrowFile(f)
+			}
+		} else if peekArrowFile(in) {
+			return readArrowFile(in)
+		}
+		return newArrowSource(in)
+	default:
+		in.Close()
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// input hands out readers positioned at the start of the data. Seekable files
+// are rewound on every call; a pipe can only be read once, so the bytes
+// consumed by schema inference are buffered and replayed ahead of the rest.
+type input struct {
+	r      io.Reader
+	closer io.Closer
+	seeker io.Seeker
+	replay *bytes.Buffer
+	used   bool
+	// size is the length of the input in bytes, or zero when unknown.
+	size int64
+}
+
+func openInput(path string) (*input, error) {
+	if path == "-" {
+		return &input{r: os.Stdin, closer: io.NopCloser(os.Stdin)}, nil
+	}
+	if objstore.IsURL(path) {
+		obj, err := objstore.Open(commandCtx, path, objectConfig())
+		if err != nil {
+			return nil, err
+		}
+		body, err := obj.Stream()
+		if err != nil {
+			return nil, err
+		}
+		return &input{r: body, closer: body, size: max(obj.Size(), 0)}, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open: %w", err)
+	}
+	in := &input{r: f, closer: f, seeker: f}
+	if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+		in.size = fi.Size()
+	}
+	return in, nil
+}
+
+// sample returns a reader for schema inference; everything it consumes is
+// replayed by the next call to reader.
+func (in *input) sample() io.Reader {
+	if in.seeker != nil {
+		return in.r
+	}
+	in.replay = new(bytes.Buffer)
+	return io.TeeReader(in.r, in.replay)
+}
+
+func (in *input) reader() (io.Reader, error) {
+	if in.seeker != nil {
+		if _, err := in.seeker.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("seek: %w", err)
+		}
+		return in.r, nil
+	}
+	if in.used {
+		return nil, fmt.Errorf("input stream can only be read once")
+	}
+	in.used = true
+	if in.replay == nil {
+		return in.r, nil
+	}
+	return io.MultiReader(in.replay, in.r), nil
+}
+
+// readerInput wraps a one-shot stream, such as a request body.
+func readerInput(r io.Reader) *input {
+	return &input{r: r, closer: io.NopCloser(r)}
+}
+
+func (in *input) Close() error { return in.closer.Close() }
+
+// csvDialect returns the dialect set by --delimiter, --quote, --comment,
+// --no-header, --decimal and --null-values. A .tsv or .tab path is
+// tab-separated unless --delimiter is given.
+func csvDialect(path string) (csvreader.Dialect, error) {
+	var d csvreader.Dialect
+	for _, f := range []struct {
+		key string
+		dst *rune
+	}{
+		{"delimiter", &d.Delimiter},
+		{"quote", &d.Quote},
+		{"comment", &d.Comment},
+		{"decimal", &d.Decimal},
+	} {
+		v := viper.GetString(f.key)
+		switch v {
+		case "":
+			continue
+		case `\t`, "tab":
+			v = "\t"
+		}
+		r := []rune(v)
+		if len(r) != 1 {
+			return d, fmt.Errorf("--%s must be a single character, got %q", f.key, v)
+		}
+		*f.dst = r[0]
+	}
+	if ext := dataExt(path); d.Delimiter == 0 && (ext == ".tsv" || ext == ".tab") {
+		d.Delimiter = '\t'
+	}
+	d.NoHeader = viper.GetBool("no-header")
+	if viper.IsSet("null-values") {
+		d.Nulls = viper.GetStringSlice("null-values")
+	}
+	d.SkipBadRows = viper.GetBool("skip-bad-rows")
+	d.InferenceRows = viper.GetInt("infer-rows")
+	types, err := columnTypes()
+	if err != nil {
+		return d, err
+	}
+	d.Types = types
+	return d, nil
+}
+
+// columnTypes returns the types forced by --schema, if any.
+func columnTypes() (map[string]arrow.DataType, error) {
+	spec := viper.GetString("schema")
+	if spec == "" {
+		return nil, nil
+	}
+	types, err := typespec.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("--schema: %w", err)
+	}
+	return types, nil
+}
+
+// overrideTypes returns schema with the types of the named columns replaced.
+// Every name must be a column of schema.
+func overrideTypes(schema *arrow.Schema, types map[string]arrow.DataType) (*arrow.Schema, error) {
+	if len(types) == 0 {
+		return schema, nil
+	}
+	for name := range types {
+		if !schema.HasField(name) {
+			return nil, fmt.Errorf("--schema: column %s not found", name)
+		}
+	}
+	fields := schema.Fields()
+	for i, f := range fields {
+		if dt, ok := types[f.Name]; ok {
+			fields[i].Type = dt
+		}
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md), nil
+}
+
+// csvSource infers the schema from the first chunk and keeps streaming from
+// there, so CSV input is read in a single pass. Unless the input is a request
+// body, progress is drawn on stderr while it is read. Rows dropped by
+// --skip-bad-rows are reported on stderr when it is closed.
+type csvSource struct {
+	in  *input
+	cr  *csvreader.CSVReader
+	bar *progressBar
+	bad *badRows
+}
+
+func newCSVSource(in *input, path string) (*csvSource, error) {
+	d, err := csvDialect(path)
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	r, err := in.reader()
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	bad := &badRows{}
+	if d.SkipBadRows {
+		d.OnBadRow = bad.add
+	}
+	var bar *progressBar
+	if path != "" {
+		bar = startProgress(in.size)
+		r = bar.reader(r)
+	}
+	r, opts, err := d.Apply(r)
+	if err != nil {
+		bar.Close()
+		in.Close()
+		return nil, err
+	}
+	cr, err := csvreader.NewInferringCSVReader(r, memory.DefaultAllocator, opts...)
+	if err != nil {
+		bar.Close()
+		bad.report(os.Stderr)
+		in.Close()
+		return nil, fmt.Errorf("infer: %w", err)
+	}
+	if _, err := overrideTypes(cr.Schema(), d.Types); err != nil {
+		bar.Close()
+		in.Close()
+		return nil, err
+	}
+	cr.SetProgress(func(p csvreader.Progress) { bar.setRows(p.Rows) })
+	return &csvSource{in: in, cr: cr, bar: bar, bad: bad}, nil
+}
+
+func (s *csvSource) Schema() *arrow.Schema { return s.cr.Schema() }
+
+func (s *csvSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	defer s.bar.Close()
+	rec, err := s.cr.ReadRecord(ctx, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+func (s *csvSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	defer s.bar.Close()
+	return s.cr.ReadChunked(ctx, column)
+}
+
+func (s *csvSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	defer s.bar.Close()
+	return s.cr.ReadRecord(ctx, columns)
+}
+
+func (s *csvSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs, errs := s.cr.Chan(ctx)
+	if s.bar == nil {
+		return recs, errs
+	}
+	// Forward the records so that the bar is cleared once the last is read,
+	// before the caller prints anything.
+	out := make(chan arrow.Record)
+	go func() {
+		defer close(out)
+		defer s.bar.Close()
+		for rec := range recs {
+			select {
+			case out <- rec:
+			case <-ctx.Done():
+				rec.Release()
+			}
+		}
+	}()
+	return out, errs
+}
+
+func (s *csvSource) Close() error {
+	s.bar.Close()
+	s.bad.report(os.Stderr)
+	return s.in.Close()
+}
+
+// maxBadRows is how many skipped rows are listed; the rest are counted.
+const maxBadRows = 20
+
+// badRows collects the rows skipped by --skip-bad-rows as they are read.
+type badRows struct {
+	mu    sync.Mutex
+	n     int
+	first []csvreader.BadRow
+}
+
+func (b *badRows) add(row csvreader.BadRow) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.n++
+	if len(b.first) < maxBadRows {
+		b.first = append(b.first, row)
+	}
+}
+
+// report writes the skipped rows to w, if there were any.
+func (b *badRows) report(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.n == 0 {
+		return
+	}
+	fmt.Fprintf(w, "Skipped %d bad rows:\n", b.n)
+	for _, row := range b.first {
+		fmt.Fprintf(w, "  line %d: %s\n", row.Line, row.Reason)
+	}
+	if more := b.n - len(b.first); more > 0 {
+		fmt.Fprintf(w, "  ... and %d more\n", more)
+	}
+	b.n, b.first = 0, nil
+}
+
+// jsonSource infers the schema of an NDJSON input up front.
+type jsonSource struct {
+	in     *input
+	schema *arrow.Schema
+}
+
+func newJSONSource(in *input) (*jsonSource, error) {
+	schema, err := jsonreader.InferSchemaFromJSON(in.sample(), 0)
+	if err != nil {
+		in.Close()
+		return nil, fmt.Errorf("infer: %w", err)
+	}
+	types, err := columnTypes()
+	if err == nil {
+		schema, err = overrideTypes(schema, types)
+	}
+	if err != nil {
+		in.Close()
+		return nil, err
+	}
+	return &jsonSource{in: in, schema: schema}, nil
+}
+
+func (s *jsonSource) reader() (*jsonreader.JSONReader, error) {
+	r, err := s.in.reader()
+	if err != nil {
+		return nil, err
+	}
+	return jsonreader.NewJSONReader(r, s.schema, memory.DefaultAllocator), nil
+}
+
+func (s *jsonSource) Schema() *arrow.Schema { return s.schema }
+
+func (s *jsonSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	jr, err := s.reader()
+	if err != nil {
+		return nil, err
+	}
+	return jr.ReadSingleColumn(ctx, column)
+}
+
+func (s *jsonSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	jr, err := s.reader()
+	if err != nil {
+		return nil, err
+	}
+	return jr.ReadChunked(ctx, column)
+}
+
+func (s *jsonSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	jr, err := s.reader()
+	if err != nil {
+		return nil, err
+	}
+	return jr.ReadColumns(ctx, columns)
+}
+
+func (s *jsonSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	jr, err := s.reader()
+	if err != nil {
+		return failedChan(err)
+	}
+	return jr.Chan(ctx)
+}
+
+func (s *jsonSource) Close() error { return s.in.Close() }
+
+// arrowSource reads an Arrow IPC stream, taking the schema from its first
+// message.
+type arrowSource struct {
+	in     *input
+	schema *arrow.Schema
+}
+
+func newArrowSource(in *input) (*arrowSource, error) {
+	r, err := ipc.NewReader(in.sample())
+	if err != nil {
+		in.Close()
+		return nil, fmt.Errorf("read arrow schema: %w", err)
+	}
+	schema := r.Schema()
+	r.Release()
+	return &arrowSource{in: in, schema: schema}, nil
+}
+
+func (s *arrowSource) Schema() *arrow.Schema { return s.schema }
+
+func (s *arrowSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	rec, err := s.ReadColumns(ctx, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+func (s *arrowSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	recs, errs := s.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, column)
+}
+
+func (s *arrowSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range s.schema.Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	recs, errs := s.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, memory.DefaultAllocator)
+}
+
+func (s *arrowSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	in, err := s.in.reader()
+	if err != nil {
+		return failedChan(err)
+	}
+	r, err := ipc.NewReader(in, ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		return failedChan(fmt.Errorf("arrow read error: %w", err))
+	}
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		defer r.Release()
+		for r.Next() {
+			rec := r.Record()
+			rec.Retain()
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+		if err := r.Err(); err != nil {
+			errs <- fmt.Errorf("arrow read error: %w", err)
+		}
+	}()
+	return recs, errs
+}
+
+func (s *arrowSource) Close() error { return s.in.Close() }
+
+// openParquet opens a Parquet input. Parquet needs random access, so piped
+// input is read fully into memory first; a local file is memory-mapped
+// unless --no-mmap is set.
+func openParquet(path string, in *input) (source, error) {
+	if in.seeker != nil {
+		in.Close()
+		return mappedParquet(path)
+	}
+	data, err := io.ReadAll(in.r)
+	if err != nil {
+		return nil, fmt.Errorf("read stdin: %w", err)
+	}
+	return parquetreader.NewParquetReader(bytes.NewReader(data), nil, memory.DefaultAllocator)
+}
+
+// failedChan returns a closed record channel and an error channel holding err.
+func failedChan(err error) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	close(recs)
+	errs := make(chan error, 1)
+	errs <- err
+	close(errs)
+	return recs, errs
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/segmentio/kafka-go"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/kafkareader"
+	"github.com/TFMV/supercharged/sink"
+)
+
+// watchKafka consumes the --kafka topic until ctx is done and emits each
+// anomaly to stdout, or as a JSON message to --sink-topic.
+func watchKafka(ctx context.Context, address, column string) error {
+	brokers, topic, err := kafkareader.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+	cfg := kafkareader.Config{
+		Brokers:      brokers,
+		Topic:        topic,
+		GroupID:      viper.GetString("kafka-group"),
+		Format:       kafkareader.Format(viper.GetString("kafka-format")),
+		Framed:       viper.GetBool("schema-registry"),
+		BatchTimeout: viper.GetDuration("interval"),
+	}
+	cp, err := openCheckpoint(address, column)
+	if err != nil {
+		return err
+	}
+	if cp != nil && cfg.GroupID == "" {
+		// With a group, its committed offsets decide where reading resumes.
+		cfg.Offset = cp.cp.Partitions[0]
+	}
+	if path := viper.GetString("avro-schema"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read avro schema: %w", err)
+		}
+		cfg.AvroSchema = string(b)
+	}
+	kr, err := kafkareader.NewKafkaReader(cfg, memory.DefaultAllocator)
+	if err != nil {
+		return err
+	}
+	defer kr.Close()
+
+	out := printEvents
+	if topic := viper.GetString("sink-topic"); topic != "" {
+		w := &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			BatchTimeout: 10 * time.Millisecond,
+		}
+		defer w.Close()
+		out = func(events []sink.Event) error {
+			return sink.Kafka{Writer: w}.Send(ctx, events)
+		}
+	}
+	recs, errs := kr.Chan(ctx)
+	return detectLive(ctx, recs, errs, column, cp, liveReporter(ctx, out))
+}
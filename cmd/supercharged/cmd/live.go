@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+	"github.com/TFMV/supercharged/sink"
+)
+
+// detectLive scores each record from recs as it arrives, against statistics
+// carried across records, and passes the flagged rows of each record to
+// report, keeping the --filter rows and adding the --expr column first
+// when they are set. With cp set, it starts from the checkpointed
+// statistics and row count and checkpoints as it goes. It returns when
+// recs is closed, reporting the first error from errs.
+func detectLive(ctx context.Context, recs <-chan arrow.Record, errs <-chan error, column string, cp *checkpointer, report func([]sink.Event) error) error {
+	expr, err := configuredExpr()
+	if err != nil {
+		return err
+	}
+	filter, err := configuredFilter()
+	if err != nil {
+		return err
+	}
+	recs, errs = filterStream(ctx, filter, recs, errs)
+	recs, errs = exprStream(ctx, expr, recs, errs)
+	detector, err := newBatchDetector(column, viper.GetFloat64("threshold"))
+	if err != nil {
+		return err
+	}
+	var rows int64
+	if cp != nil {
+		if rows, err = cp.restore(detector); err != nil {
+			return err
+		}
+	}
+	for rec := range recs {
+		err := detectRecord(ctx, detector, rec, column, &rows, report)
+		// Checkpoint once the record's events are out, so none are lost
+		// between a checkpoint and a restart.
+		if err == nil && cp != nil {
+			err = cp.update(rec, detector, rows)
+		}
+		rec.Release()
+		if err != nil {
+			return err
+		}
+	}
+	if err := <-errs; err != nil {
+		return err
+	}
+	if cp != nil {
+		return cp.close()
+	}
+	return nil
+}
+
+// detectRecord scores rec, the rows of which follow *rows others, and
+// reports its flagged rows.
+func detectRecord(ctx context.Context, detector batchDetector, rec arrow.Record, column string, rows *int64, report func([]sink.Event) error) error {
+	start := time.Now()
+	res, err := detector.Update(ctx, rec)
+	if err != nil {
+		return fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+	base := *rows
+	*rows += rec.NumRows()
+	metrics.observe(rec.NumRows(), int64(res.Indices.Len()), time.Since(start))
+	if events := eventsOf(res, column, base); len(events) > 0 {
+		return report(events)
+	}
+	return nil
+}
+
+// eventsOf describes the rows flagged in res, numbering them from base.
+func eventsOf(res *anomaly.Result, column string, base int64) []sink.Event {
+	now := time.Now().UTC()
+	events := make([]sink.Event, res.Indices.Len())
+	for i := range events {
+		idx := res.Indices.Value(i)
+		events[i] = sink.Event{
+			Column: column,
+			Row:    base + idx,
+			Value:  res.Values.Value(i),
+			Score:  res.Zscore.Value(int(idx)),
+			Mean:   res.Mean,
+			StdDev: res.StdDev,
+			Time:   now,
+		}
+	}
+	return events
+}
+
+// printEvents writes each event to stdout as a line of text, or of JSON with
+// --json.
+func printEvents(events []sink.Event) error {
+	enc := json.NewEncoder(os.Stdout)
+	for _, ev := range events {
+		var err error
+		if viper.GetBool("json") {
+			err = enc.Encode(ev)
+		} else {
+			_, err = fmt.Printf("row %d: value=%v score=%.2f\n", ev.Row, ev.Value, ev.Score)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configuredSinks returns the sinks named by --webhook, --slack-webhook,
+// --pagerduty-key and --alert-file, or nil when none is set.
+func configuredSinks() sink.Sink {
+	var sinks sink.Multi
+	for _, url := range viper.GetStringSlice("webhook") {
+		sinks = append(sinks, sink.Webhook{URL: url})
+	}
+	if url := viper.GetString("slack-webhook"); url != "" {
+		sinks = append(sinks, sink.Slack{WebhookURL: url})
+	}
+	if key := viper.GetString("pagerduty-key"); key != "" {
+		sinks = append(sinks, sink.PagerDuty{RoutingKey: key})
+	}
+	if path := viper.GetString("alert-file"); path != "" {
+		sinks = append(sinks, sink.File{Path: path})
+	}
+	if len(sinks) == 0 {
+		return nil
+	}
+	return sinks
+}
+
+// notify delivers events to s, in one call per anomaly or, with
+// --notify batch, one call for all of them.
+func notify(ctx context.Context, s sink.Sink, events []sink.Event) error {
+	switch mode := viper.GetString("notify"); mode {
+	case "batch":
+		return s.Send(ctx, events)
+	case "anomaly":
+		for i := range events {
+			if err := s.Send(ctx, events[i:i+1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown --notify mode %q", mode)
+	}
+}
+
+// liveReporter returns the report function for detectLive: events go to out
+// and then to the configured sinks. Sink failures are logged rather than
+// stopping detection.
+func liveReporter(ctx context.Context, out func([]sink.Event) error) func([]sink.Event) error {
+	sinks := configuredSinks()
+	return func(events []sink.Event) error {
+		if err := out(events); err != nil {
+			return err
+		}
+		if sinks != nil {
+			if err := notify(ctx, sinks, events); err != nil {
+				fmt.Fprintln(os.Stderr, "notify:", err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the detection latency
+// histogram.
+var latencyBuckets = []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10}
+
+// detectionMetrics counts the work done by serve and watch for the
+// /metrics endpoint.
+type detectionMetrics struct {
+	mu        sync.Mutex
+	rows      int64
+	anomalies int64
+	runs      int64
+	seconds   float64
+	buckets   []int64
+}
+
+// metrics is the process-wide registry.
+var metrics = &detectionMetrics{buckets: make([]int64, len(latencyBuckets))}
+
+// observe records one detection over rows rows that flagged anomalies of
+// them and took elapsed.
+func (m *detectionMetrics) observe(rows, anomalies int64, elapsed time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rows += rows
+	m.anomalies += anomalies
+	m.runs++
+	s := elapsed.Seconds()
+	m.seconds += s
+	for i, le := range latencyBuckets {
+		if s <= le {
+			m.buckets[i]++
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *detectionMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprintln(w, "# HELP supercharged_rows_processed_total Rows scored for anomalies.")
+	fmt.Fprintln(w, "# TYPE supercharged_rows_processed_total counter")
+	fmt.Fprintf(w, "supercharged_rows_processed_total %d\n", m.rows)
+	fmt.Fprintln(w, "# HELP supercharged_anomalies_total Rows flagged as anomalies.")
+	fmt.Fprintln(w, "# TYPE supercharged_anomalies_total counter")
+	fmt.Fprintf(w, "supercharged_anomalies_total %d\n", m.anomalies)
+	fmt.Fprintln(w, "# HELP supercharged_detection_duration_seconds Time spent reading and scoring each request or batch.")
+	fmt.Fprintln(w, "# TYPE supercharged_detection_duration_seconds histogram")
+	for i, le := range latencyBuckets {
+		fmt.Fprintf(w, "supercharged_detection_duration_seconds_bucket{le=\"%g\"} %d\n", le, m.buckets[i])
+	}
+	fmt.Fprintf(w, "supercharged_detection_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.runs)
+	fmt.Fprintf(w, "supercharged_detection_duration_seconds_sum %g\n", m.seconds)
+	fmt.Fprintf(w, "supercharged_detection_duration_seconds_count %d\n", m.runs)
+	fmt.Fprintln(w, "# HELP supercharged_memory_allocated_bytes Heap bytes currently allocated.")
+	fmt.Fprintln(w, "# TYPE supercharged_memory_allocated_bytes gauge")
+	fmt.Fprintf(w, "supercharged_memory_allocated_bytes %d\n", mem.HeapAlloc)
+	fmt.Fprintln(w, "# HELP supercharged_memory_allocated_bytes_total Heap bytes allocated since start.")
+	fmt.Fprintln(w, "# TYPE supercharged_memory_allocated_bytes_total counter")
+	fmt.Fprintf(w, "supercharged_memory_allocated_bytes_total %d\n", mem.TotalAlloc)
+}
+
+// serveMetrics serves /metrics on addr until the process exits, logging
+// why it stopped if it fails.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	log.Printf("serving metrics on %s", addr)
+	log.Printf("metrics server: %v", http.ListenAndServe(addr, mux))
+}
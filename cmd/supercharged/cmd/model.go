@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+var fitCmd = &cobra.Command{
+	Use:   "fit",
+	Short: "Compute a baseline model from reference data",
+	Long: `Compute a baseline model from reference data.
+
+Fits the --method detector (zscore, mad, iqr or percentile) to --column of
+the input and writes its statistics to --out as JSON, for score to apply to
+later data. With --group-by, a z-score baseline is fitted for each key.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		column := viper.GetString("column")
+		if column == "" {
+			return fmt.Errorf("--column is required")
+		}
+		path := viper.GetString("out")
+		if path == "" {
+			return fmt.Errorf("--out is required")
+		}
+
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		column = viper.GetString("column")
+
+		// --group-by is read from this command's flags, since viper binds
+		// the key to analyze's.
+		key, _ := cmd.Flags().GetString("group-by")
+		var m *anomaly.Model
+		if key != "" {
+			rec, err := src.ReadColumns(commandCtx, []string{column, key})
+			if err != nil {
+				return fmt.Errorf("read columns: %w", err)
+			}
+			defer rec.Release()
+			if m, err = anomaly.FitGrouped(detectContext(), rec, column, key, viper.GetFloat64("threshold")); err != nil {
+				return fmt.Errorf("fit model: %w", err)
+			}
+		} else {
+			detector, err := newDetector(viper.GetString("method"))
+			if err != nil {
+				return err
+			}
+			col, err := src.ReadSingleColumn(commandCtx, column)
+			if err != nil {
+				return fmt.Errorf("read column: %w", err)
+			}
+			defer col.Release()
+			if m, err = anomaly.Fit(detectContext(), detector, col); err != nil {
+				return fmt.Errorf("fit model: %w", err)
+			}
+			m.Column = column
+		}
+		if err := m.Save(path); err != nil {
+			return err
+		}
+
+		b := m.Baseline
+		fmt.Printf("Fitted %s model of %s over %d values: center=%g scale=%g threshold=%g\n", m.Method, column, b.Count, b.Center, b.Scale, m.Threshold)
+		if m.GroupBy != "" {
+			fmt.Printf("Groups: %d by %s\n", len(m.Groups), m.GroupBy)
+		}
+		fmt.Printf("Wrote %s\n", path)
+		return nil
+	},
+}
+
+var scoreCmd = &cobra.Command{
+	Use:   "score",
+	Short: "Score a dataset against a baseline model",
+	Long: `Score a dataset against a baseline model.
+
+Reads the model written by fit and flags values of its column in the input
+that are anomalous against the reference statistics, which are not
+recomputed. --column scores a differently named column, and --threshold,
+when given, replaces the model's.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := viper.GetString("model")
+		if path == "" {
+			return fmt.Errorf("--model is required")
+		}
+		m, err := anomaly.LoadModel(path)
+		if err != nil {
+			return err
+		}
+		if viper.GetString("column") == "" && m.Column == "" {
+			return fmt.Errorf("model %s names no column; set --column", path)
+		}
+		if viper.IsSet("threshold") {
+			m.Threshold = viper.GetFloat64("threshold")
+		}
+
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if column := viper.GetString("column"); column != "" {
+			m.Column = column
+		}
+
+		columns := []string{m.Column}
+		if m.GroupBy != "" {
+			columns = append(columns, m.GroupBy)
+		}
+		rec, err := src.ReadColumns(commandCtx, columns)
+		if err != nil {
+			return fmt.Errorf("read columns: %w", err)
+		}
+		defer rec.Release()
+
+		res, err := m.DetectRecord(detectContext(), rec)
+		if err != nil {
+			return fmt.Errorf("detect anomalies: %w", err)
+		}
+		defer res.Release()
+
+		out := analyzeOutput{Count: rec.NumRows()}
+		out.add(res, 0)
+		out.summarize(rec.Column(0))
+		return writeOutput(out)
+	},
+}
+
+func init() {
+	fitCmd.Flags().String("out", "", "File to write the fitted model to")
+	viper.BindPFlag("out", fitCmd.Flags().Lookup("out"))
+	fitCmd.Flags().String("group-by", "", "Key column whose groups get their own z-score baseline")
+	rootCmd.AddCommand(fitCmd)
+
+	scoreCmd.Flags().String("model", "", "Model file written by fit")
+	viper.BindPFlag("model", scoreCmd.Flags().Lookup("model"))
+	rootCmd.AddCommand(scoreCmd)
+}
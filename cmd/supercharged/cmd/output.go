@@ -0,0 +1,387 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+	"github.com/TFMV/supercharged/csvwriter"
+	"github.com/TFMV/supercharged/parquetwriter"
+)
+
+// writeRows scores column and writes the flagged rows, or every row with
+// --all-rows, with every input column plus zscore and is_anomaly, and the
+// cleaned column with --clean, to path ("-" for stdout) in format. It
+// returns the usual summary.
+func writeRows(src source, column, path, format string) (analyzeOutput, error) {
+	p, err := newPipeline(viper.GetString("method"))
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	rows, out, err := annotatedRows(commandCtx, src, p, column, viper.GetBool("all-rows"), anomaly.CleanMode(viper.GetString("clean")))
+	if err != nil {
+		return analyzeOutput{}, err
+	}
+	defer rows.Release()
+	if err := writeRecord(path, format, rows); err != nil {
+		return analyzeOutput{}, err
+	}
+	return out, nil
+}
+
+// annotatedRows reads every column of src, scores column through p and
+// returns the flagged rows, or all of them, with zscore and
+// is_anomaly appended, and column cleaned by clean unless it is empty,
+// together with the summary. The caller must Release the record. Failures
+// to read src, a missing column and an unknown clean mode are inputErrors.
+func annotatedRows(ctx context.Context, src source, p *anomaly.Pipeline, column string, all bool, clean anomaly.CleanMode) (arrow.Record, analyzeOutput, error) {
+	rec, err := src.ReadColumns(ctx, nil)
+	if err != nil {
+		return nil, analyzeOutput{}, inputError{fmt.Errorf("read columns: %w", err)}
+	}
+	defer rec.Release()
+
+	idx := rec.Schema().FieldIndices(column)
+	if len(idx) == 0 {
+		return nil, analyzeOutput{}, inputError{fmt.Errorf("column %s not found", column)}
+	}
+	res, err := p.Detect(ctx, rec.Column(idx[0]))
+	if err != nil {
+		return nil, analyzeOutput{}, fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+
+	annotated, err := anomaly.Annotate(rec, res)
+	if err != nil {
+		return nil, analyzeOutput{}, err
+	}
+	if clean != "" {
+		withClean, err := appendCleaned(ctx, annotated, rec.Column(idx[0]), res, clean)
+		annotated.Release()
+		if err != nil {
+			return nil, analyzeOutput{}, err
+		}
+		annotated = withClean
+	}
+	out := analyzeOutput{Count: rec.NumRows(), events: eventsOf(res, column, 0)}
+	out.add(res, 0)
+	out.summarize(rec.Column(idx[0]))
+	if all {
+		return annotated, out, nil
+	}
+	defer annotated.Release()
+	rows, err := anomaly.FilterRecord(ctx, annotated, res.Mask)
+	if err != nil {
+		return nil, analyzeOutput{}, fmt.Errorf("filter rows: %w", err)
+	}
+	return rows, out, nil
+}
+
+// inputError is a failure caused by the input or the settings rather than by
+// detection itself, which serve reports as the client's.
+type inputError struct{ error }
+
+func (e inputError) Unwrap() error { return e.error }
+
+// appendCleaned returns rec with col, cleaned of the anomalies res flags by
+// mode, appended as the cleaned column.
+func appendCleaned(ctx context.Context, rec arrow.Record, col arrow.Array, res *anomaly.Result, mode anomaly.CleanMode) (arrow.Record, error) {
+	cleaned, err := anomaly.Clean(ctx, col, res, mode)
+	if err != nil {
+		return nil, inputError{fmt.Errorf("clean: %w", err)}
+	}
+	defer cleaned.Release()
+	fields := append(rec.Schema().Fields(), arrow.Field{Name: anomaly.CleanColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true})
+	cols := append(append([]arrow.Array(nil), rec.Columns()...), cleaned)
+	return array.NewRecord(arrow.NewSchema(fields, nil), cols, rec.NumRows()), nil
+}
+
+// recordWriter is the common surface of the output writers.
+type recordWriter interface {
+	Write(rec arrow.Record) error
+	Close() error
+}
+
+// csvRecordWriter adapts CSVWriter, which flushes rather than closes.
+type csvRecordWriter struct{ *csvwriter.CSVWriter }
+
+func (w csvRecordWriter) Close() error { return w.Flush() }
+
+// outputBatchRows is the number of rows per record batch in Arrow output.
+const outputBatchRows = 64 * 1024
+
+// outputFormat returns format, or guesses it from the extension of path.
+func outputFormat(path, format string) string {
+	if format != "" {
+		return format
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".parquet", ".pq":
+		return "parquet"
+	case ".arrow", ".arrows", ".ipc":
+		return "arrow"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	default:
+		return "csv"
+	}
+}
+
+// newRecordWriter returns a writer of format for records of schema.
+func newRecordWriter(w io.Writer, format string, schema *arrow.Schema) (recordWriter, error) {
+	switch format {
+	case "csv":
+		return csvRecordWriter{csvwriter.NewCSVWriter(w, schema)}, nil
+	case "parquet":
+		return parquetwriter.NewParquetWriter(w, schema, memory.DefaultAllocator)
+	case "arrow":
+		return ipc.NewWriter(w, ipc.WithSchema(schema), ipc.WithAllocator(memory.DefaultAllocator)), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// writeRecord writes rec to the file at path, or to stdout for "-", in
+// format, or the format matching the extension when format is empty. Arrow
+// output is split into record batches of outputBatchRows.
+func writeRecord(path, format string, rec arrow.Record) error {
+	var file *os.File
+	out := io.Writer(os.Stdout)
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("create output: %w", err)
+		}
+		defer f.Close()
+		file, out = f, f
+	}
+	format = outputFormat(path, format)
+	w, err := newRecordWriter(out, format, rec.Schema())
+	if err != nil {
+		return err
+	}
+	if err := writeBatches(w, rec, format); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if file != nil {
+		return file.Close()
+	}
+	return nil
+}
+
+// writeBatches writes rec whole, or in outputBatchRows slices for Arrow.
+func writeBatches(w recordWriter, rec arrow.Record, format string) error {
+	if format != "arrow" || rec.NumRows() <= outputBatchRows {
+		return w.Write(rec)
+	}
+	for lo := int64(0); lo < rec.NumRows(); lo += outputBatchRows {
+		part := rec.NewSlice(lo, min(lo+outputBatchRows, rec.NumRows()))
+		err := w.Write(part)
+		part.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ndjsonOutput reports whether results go out as NDJSON, by --output-format
+// or the extension of --output.
+func ndjsonOutput() bool {
+	output, format := viper.GetString("output"), viper.GetString("output-format")
+	return (output != "" || format != "") && outputFormat(output, format) == "ndjson"
+}
+
+// score is a value or score of the JSON output, written as null when it is
+// NaN or infinite, which JSON cannot hold, so one such row does not fail the
+// whole report.
+type score float64
+
+// MarshalJSON implements json.Marshaler.
+func (s score) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(s)) || math.IsInf(float64(s), 0) {
+		return []byte("null"), nil
+	}
+	return json.Marshal(float64(s))
+}
+
+// outputPoint and outputEpisode are anomaly.Point and anomaly.Episode as
+// written to JSON, with their values and scores as score.
+type outputPoint struct {
+	anomaly.Point
+	Value score `json:"value"`
+	Score score `json:"score"`
+}
+
+type outputEpisode struct {
+	anomaly.Episode
+	PeakValue score `json:"peak_value"`
+	PeakScore score `json:"peak_score"`
+}
+
+func newOutputEpisode(e anomaly.Episode) outputEpisode {
+	return outputEpisode{Episode: e, PeakValue: score(e.PeakValue), PeakScore: score(e.PeakScore)}
+}
+
+// MarshalJSON implements json.Marshaler, writing the points and episodes
+// of o as outputPoint and outputEpisode.
+func (o analyzeOutput) MarshalJSON() ([]byte, error) {
+	type plain analyzeOutput
+	out := struct {
+		plain
+		Points   []outputPoint   `json:"points,omitempty"`
+		Episodes []outputEpisode `json:"episodes,omitzero"`
+	}{plain: plain(o)}
+	for _, p := range o.Points {
+		out.Points = append(out.Points, outputPoint{Point: p, Value: score(p.Value), Score: score(p.Score)})
+	}
+	if o.Episodes != nil {
+		out.Episodes = make([]outputEpisode, len(o.Episodes))
+		for i, e := range o.Episodes {
+			out.Episodes[i] = newOutputEpisode(e)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// ndjsonRow is one anomaly of the NDJSON output.
+type ndjsonRow struct {
+	File   string `json:"file,omitempty"`
+	Column string `json:"column,omitempty"`
+	outputRow
+	Time *time.Time `json:"time,omitempty"`
+}
+
+// writeNDJSON writes the anomalies of outs, one JSON object per line and
+// column by column in the order of columns, to the file at path, or to
+// stdout for "" or "-". Unlike --json, nothing is held back to build a
+// single document, so the output can be piped into jq or a log shipper.
+func writeNDJSON(path string, columns []string, outs map[string]analyzeOutput) error {
+	w, err := newNDJSONWriter(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range columns {
+		if o, ok := outs[name]; ok {
+			if err := w.write(name, &o); err != nil {
+				w.Close()
+				return err
+			}
+		}
+	}
+	return w.Close()
+}
+
+// ndjsonWriter writes anomalies as NDJSON while they are being found.
+type ndjsonWriter struct {
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+	// input names the file of the anomalies written when several are
+	// analyzed.
+	input string
+}
+
+// newNDJSONWriter creates the file at path, or writes to stdout for "" or
+// "-".
+func newNDJSONWriter(path string) (*ndjsonWriter, error) {
+	w := &ndjsonWriter{}
+	out := io.Writer(os.Stdout)
+	if path != "" && path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("create output: %w", err)
+		}
+		w.file, out = f, f
+	}
+	w.buf = bufio.NewWriter(out)
+	w.enc = json.NewEncoder(w.buf)
+	return w, nil
+}
+
+// write writes the anomalies of o, which belong to column ("" for the only
+// one), and flushes them so readers see each batch as it is scored.
+func (w *ndjsonWriter) write(column string, o *analyzeOutput) error {
+	if o.Episodes != nil {
+		return w.writeEpisodes(column, o.Episodes)
+	}
+	for i, r := range o.Rows {
+		row := ndjsonRow{File: w.input, Column: column, outputRow: r}
+		if len(o.Points) == len(o.Rows) {
+			row.Time = &o.Points[i].Time
+		}
+		if err := w.enc.Encode(row); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// ndjsonEpisode is one episode of the NDJSON output under --episodes.
+type ndjsonEpisode struct {
+	File   string `json:"file,omitempty"`
+	Column string `json:"column,omitempty"`
+	outputEpisode
+}
+
+// writeEpisodes writes one object per episode in place of the anomalies.
+func (w *ndjsonWriter) writeEpisodes(column string, episodes []anomaly.Episode) error {
+	for _, e := range episodes {
+		if err := w.enc.Encode(ndjsonEpisode{File: w.input, Column: column, outputEpisode: newOutputEpisode(e)}); err != nil {
+			return fmt.Errorf("write output: %w", err)
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the output and closes the file, if any. Closing twice is
+// harmless.
+func (w *ndjsonWriter) Close() error {
+	err := w.buf.Flush()
+	if w.file != nil {
+		if cerr := w.file.Close(); err == nil {
+			err = cerr
+		}
+		w.file = nil
+	}
+	if err != nil {
+		return fmt.Errorf("write output: %w", err)
+	}
+	return nil
+}
+
+// emit writes the anomalies found so far that pass --min-score to w and
+// drops them, so a --stream run holds none of them in memory.
+func (o *analyzeOutput) emit(w *ndjsonWriter) error {
+	o.limit(viper.GetFloat64("min-score"), 0)
+	if err := w.write("", o); err != nil {
+		return err
+	}
+	o.Rows, o.Anomalies, o.PValues, o.Points = nil, nil, nil, nil
+	o.emitted = true
+	return nil
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	"github.com/TFMV/supercharged/internal/partition"
+)
+
+// partitionSource appends the Hive partition values of a file to the
+// wrapped source as constant columns, after its own.
+type partitionSource struct {
+	source
+	fields []arrow.Field
+	values []partition.Value
+}
+
+func newPartitionSource(src source, fields []arrow.Field, values []partition.Value) (source, error) {
+	for _, f := range fields {
+		if src.Schema().HasField(f.Name) {
+			src.Close()
+			return nil, fmt.Errorf("partition key %s is also a column of the file", f.Name)
+		}
+	}
+	return &partitionSource{source: src, fields: fields, values: values}, nil
+}
+
+func (s *partitionSource) Schema() *arrow.Schema {
+	schema := s.source.Schema()
+	md := schema.Metadata()
+	return arrow.NewSchema(append(schema.Fields(), s.fields...), &md)
+}
+
+// partition returns the index of the partition column named column, or -1.
+func (s *partitionSource) partition(column string) int {
+	for i, f := range s.fields {
+		if f.Name == column {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *partitionSource) constant(i, n int) (arrow.Array, error) {
+	return partition.Array(memory.DefaultAllocator, s.fields[i].Type, s.values[i], n)
+}
+
+func (s *partitionSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	i := s.partition(column)
+	if i < 0 {
+		return s.source.ReadSingleColumn(ctx, column)
+	}
+	// Read the file's first column for the row count.
+	col, err := s.source.ReadSingleColumn(ctx, s.source.Schema().Field(0).Name)
+	if err != nil {
+		return nil, err
+	}
+	defer col.Release()
+	return s.constant(i, col.Len())
+}
+
+func (s *partitionSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	i := s.partition(column)
+	if i < 0 {
+		return s.source.ReadChunked(ctx, column)
+	}
+	chunked, err := s.source.ReadChunked(ctx, s.source.Schema().Field(0).Name)
+	if err != nil {
+		return nil, err
+	}
+	defer chunked.Release()
+	chunks := make([]arrow.Array, 0, len(chunked.Chunks()))
+	defer func() {
+		for _, c := range chunks {
+			c.Release()
+		}
+	}()
+	for _, c := range chunked.Chunks() {
+		col, err := s.constant(i, c.Len())
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, col)
+	}
+	return arrow.NewChunked(s.fields[i].Type, chunks), nil
+}
+
+func (s *partitionSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		// nil reads every column.
+		for _, f := range s.Schema().Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	var own []string
+	for _, c := range columns {
+		if s.partition(c) < 0 {
+			own = append(own, c)
+		}
+	}
+	if len(own) == 0 {
+		own = []string{s.source.Schema().Field(0).Name}
+	}
+	rec, err := s.source.ReadColumns(ctx, own)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+
+	fields := make([]arrow.Field, len(columns))
+	cols := make([]arrow.Array, len(columns))
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	for j, c := range columns {
+		if i := s.partition(c); i >= 0 {
+			col, err := s.constant(i, int(rec.NumRows()))
+			if err != nil {
+				return nil, err
+			}
+			fields[j], cols[j] = s.fields[i], col
+			continue
+		}
+		idx := rec.Schema().FieldIndices(c)
+		if len(idx) == 0 {
+			return nil, fmt.Errorf("column %s not found", c)
+		}
+		fields[j], cols[j] = rec.Schema().Field(idx[0]), rec.Column(idx[0])
+		cols[j].Retain()
+	}
+	return array.NewRecord(arrow.NewSchema(fields, nil), cols, rec.NumRows()), nil
+}
+
+// Chan streams the wrapped records with the partition columns appended.
+func (s *partitionSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	in, inErrs := s.source.Chan(ctx)
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for rec := range in {
+			out, err := s.extend(rec)
+			rec.Release()
+			if err != nil {
+				errs <- err
+				// Drain so the producer can finish.
+				for rec := range in {
+					rec.Release()
+				}
+				return
+			}
+			select {
+			case recs <- out:
+			case <-ctx.Done():
+				out.Release()
+			}
+		}
+		if err := <-inErrs; err != nil {
+			errs <- err
+		}
+	}()
+	return recs, errs
+}
+
+// extend returns rec with the partition columns appended.
+func (s *partitionSource) extend(rec arrow.Record) (arrow.Record, error) {
+	cols := make([]arrow.Array, 0, int(rec.NumCols())+len(s.fields))
+	defer func() {
+		for _, c := range cols {
+			c.Release()
+		}
+	}()
+	for _, c := range rec.Columns() {
+		c.Retain()
+		cols = append(cols, c)
+	}
+	for i := range s.fields {
+		col, err := s.constant(i, int(rec.NumRows()))
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	md := rec.Schema().Metadata()
+	schema := arrow.NewSchema(append(rec.Schema().Fields(), s.fields...), &md)
+	return array.NewRecord(schema, cols, rec.NumRows()), nil
+}
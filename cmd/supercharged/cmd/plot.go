@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+// Terminal plot geometry, in characters.
+const (
+	plotColumns  = 72
+	plotBins     = 12
+	plotBarWidth = 40
+)
+
+// plotter accumulates what --plot draws: the peak absolute score of each
+// run of rows, for the sparkline, and the values of the flagged rows, which
+// the histogram counts exactly. The histogram's other counts come from the
+// summary's t-digest, so memory stays bounded on streamed input.
+type plotter struct {
+	// buckets holds the sparkline, step rows to a bucket. When it fills
+	// up, neighbouring buckets are merged and step doubles.
+	buckets []sparkBucket
+	step    int64
+	fill    int64
+	flagged []float64
+}
+
+type sparkBucket struct {
+	peak    float64
+	flagged bool
+}
+
+// plotStyle is a --plot value: the characters a plot is drawn with.
+type plotStyle struct {
+	levels []string
+	// eighths draws the fractional part of a bar, from one eighth up.
+	eighths []string
+	full    string
+	color   bool
+}
+
+// newPlotStyle returns the style named by --plot. Flagged bins and scores
+// are drawn in red when w is a terminal and NO_COLOR is unset.
+func newPlotStyle(name string, w io.Writer) (plotStyle, error) {
+	var s plotStyle
+	switch name {
+	case "unicode":
+		s = plotStyle{
+			levels:  strings.Split("▁▂▃▄▅▆▇█", ""),
+			eighths: strings.Split("▏▎▍▌▋▊▉", ""),
+			full:    "█",
+		}
+	case "ascii":
+		s = plotStyle{levels: strings.Split("_.-=+*#@", ""), full: "#"}
+	default:
+		return s, fmt.Errorf("--plot must be unicode or ascii, got %q", name)
+	}
+	if f, ok := w.(*os.File); ok && os.Getenv("NO_COLOR") == "" {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			s.color = true
+		}
+	}
+	return s, nil
+}
+
+func (s plotStyle) red(text string) string {
+	if !s.color {
+		return text
+	}
+	return "\x1b[31m" + text + "\x1b[0m"
+}
+
+// add folds the scores of res, in row order, into the plot.
+func (p *plotter) add(res *anomaly.Result) {
+	if p.step == 0 {
+		p.step = 1
+	}
+	next := 0
+	for i := 0; i < res.Zscore.Len(); i++ {
+		if p.fill == p.step || len(p.buckets) == 0 {
+			if len(p.buckets) == 2*plotColumns {
+				p.halve()
+			}
+			p.buckets = append(p.buckets, sparkBucket{})
+			p.fill = 0
+		}
+		b := &p.buckets[len(p.buckets)-1]
+		p.fill++
+		if res.Zscore.IsValid(i) {
+			b.peak = math.Max(b.peak, math.Abs(res.Zscore.Value(i)))
+		}
+		if next < res.Indices.Len() && int(res.Indices.Value(next)) == i {
+			b.flagged = true
+			p.flagged = append(p.flagged, res.Values.Value(next))
+			next++
+		}
+	}
+}
+
+// halve merges neighbouring buckets, doubling the rows each one covers.
+func (p *plotter) halve() {
+	for i := 0; i < len(p.buckets)/2; i++ {
+		a, b := p.buckets[2*i], p.buckets[2*i+1]
+		p.buckets[i] = sparkBucket{peak: math.Max(a.peak, b.peak), flagged: a.flagged || b.flagged}
+	}
+	p.buckets = p.buckets[:len(p.buckets)/2]
+	p.step *= 2
+}
+
+// writePlot draws the histogram and score sparkline of o to w, in the
+// --plot style. It draws nothing when --plot is unset.
+func writePlot(w io.Writer, o *analyzeOutput) error {
+	name := viper.GetString("plot")
+	if name == "" || o.plot == nil {
+		return nil
+	}
+	style, err := newPlotStyle(name, w)
+	if err != nil {
+		return err
+	}
+	if o.values != nil && o.values.numeric && o.values.n > 0 {
+		o.plot.histogram(w, o.values, style)
+	}
+	o.plot.sparkline(w, style)
+	return nil
+}
+
+// histogram draws plotBins equal-width bins between the smallest and
+// largest values, with the flagged values in each bin counted beside it.
+func (p *plotter) histogram(w io.Writer, s *summarizer, style plotStyle) {
+	lo, hi := s.min, s.max
+	bins := plotBins
+	if hi == lo {
+		bins = 1
+	}
+	width := (hi - lo) / float64(bins)
+	flagged := make([]int64, bins)
+	for _, v := range p.flagged {
+		if b := p.bin(v, lo, width, bins); b >= 0 {
+			flagged[b]++
+		}
+	}
+	counts := make([]int64, bins)
+	var most int64
+	below := 0.0
+	for b := range counts {
+		above := 1.0
+		if b < bins-1 {
+			above = s.digest.CDF(lo + float64(b+1)*width)
+		}
+		counts[b] = max(int64(math.Round((above-below)*float64(s.n))), flagged[b])
+		below = above
+		most = max(most, counts[b])
+	}
+
+	fmt.Fprintln(w, "Histogram:")
+	for b, c := range counts {
+		label := fmt.Sprintf("%10.4g .. %-10.4g", lo+float64(b)*width, lo+float64(b+1)*width)
+		bar := style.bar(c, most)
+		pad := strings.Repeat(" ", plotBarWidth-len([]rune(bar)))
+		if flagged[b] > 0 {
+			fmt.Fprintf(w, "  %s %s%s %d (%d flagged)\n", label, style.red(bar), pad, c, flagged[b])
+		} else {
+			fmt.Fprintf(w, "  %s %s%s %d\n", label, bar, pad, c)
+		}
+	}
+}
+
+// bin returns the histogram bin of v, or -1 when it falls outside them.
+func (p *plotter) bin(v, lo, width float64, bins int) int {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return -1
+	}
+	if width == 0 {
+		return 0
+	}
+	return min(bins-1, max(0, int((v-lo)/width)))
+}
+
+// bar returns a bar of count against the largest count most, at least a
+// sliver long when count is not zero so that lone outliers show.
+func (s plotStyle) bar(count, most int64) string {
+	if count == 0 || most == 0 {
+		return ""
+	}
+	if s.eighths == nil {
+		return strings.Repeat(s.full, max(1, int(math.Round(float64(count)/float64(most)*plotBarWidth))))
+	}
+	eighths := max(1, int(math.Round(float64(count)/float64(most)*plotBarWidth*8)))
+	bar := strings.Repeat(s.full, eighths/8)
+	if eighths%8 > 0 {
+		bar += s.eighths[eighths%8-1]
+	}
+	return bar
+}
+
+// sparkline draws the peak absolute score of each run of rows, with the
+// runs holding a flagged row marked underneath and, in color, in red.
+func (p *plotter) sparkline(w io.Writer, style plotStyle) {
+	if len(p.buckets) == 0 {
+		return
+	}
+	// Merge down to at most plotColumns columns.
+	group := (len(p.buckets) + plotColumns - 1) / plotColumns
+	var cols []sparkBucket
+	for i := 0; i < len(p.buckets); i += group {
+		var c sparkBucket
+		for _, b := range p.buckets[i:min(i+group, len(p.buckets))] {
+			c.peak = math.Max(c.peak, b.peak)
+			c.flagged = c.flagged || b.flagged
+		}
+		cols = append(cols, c)
+	}
+	var peak float64
+	for _, c := range cols {
+		peak = math.Max(peak, c.peak)
+	}
+
+	var line, marks strings.Builder
+	var marked bool
+	for _, c := range cols {
+		level := 0
+		if peak > 0 {
+			level = min(len(style.levels)-1, int(c.peak/peak*float64(len(style.levels)-1)+0.5))
+		}
+		if c.flagged {
+			line.WriteString(style.red(style.levels[level]))
+			marks.WriteString("^")
+			marked = true
+		} else {
+			line.WriteString(style.levels[level])
+			marks.WriteString(" ")
+		}
+	}
+	fmt.Fprintf(w, "Scores (peak |score| %.3g, %d rows per column):\n", peak, p.step*int64(group))
+	fmt.Fprintf(w, "  %s\n", line.String())
+	if marked {
+		fmt.Fprintf(w, "  %s\n", strings.TrimRight(marks.String(), " "))
+	}
+}
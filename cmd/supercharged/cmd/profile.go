@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Summarize each column of a dataset before running detection",
+	Long: `Summarize each column of a dataset before running detection.
+
+Prints the type, row and null counts and an estimated distinct count of every
+column, and the min, max, mean and standard deviation of numeric columns. The
+input is streamed, so memory does not grow with the number of rows.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		ctx, cancel := context.WithCancel(commandCtx)
+		defer cancel()
+		recs, errs := src.Chan(ctx)
+		var p *anomaly.Profiler
+		for rec := range recs {
+			if p == nil {
+				p = anomaly.NewProfiler(rec.Schema())
+			}
+			err := p.Add(rec)
+			rec.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if err := <-errs; err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+		if p == nil {
+			p = anomaly.NewProfiler(src.Schema())
+		}
+		profiles := p.Profiles()
+
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(profiles)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COLUMN\tTYPE\tROWS\tNULLS\tDISTINCT\tMIN\tMAX\tMEAN\tSTDDEV")
+		for _, c := range profiles {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t~%d", c.Name, c.Type, c.Rows, c.Nulls, c.Distinct)
+			if s := c.Stats; s != nil {
+				fmt.Fprintf(w, "\t%g\t%g\t%.4g\t%.4g", s.Min, s.Max, s.Mean, s.StdDev)
+			} else {
+				fmt.Fprint(w, "\t\t\t\t")
+			}
+			fmt.Fprintln(w)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(profileCmd)
+}
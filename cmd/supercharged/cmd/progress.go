@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// progressDelay keeps the bar off the screen for inputs read faster than
+// this, and progressTick is how often it is redrawn.
+const (
+	progressDelay = 500 * time.Millisecond
+	progressTick  = 200 * time.Millisecond
+	progressWidth = 30
+)
+
+// progressBar draws the bytes and rows read so far on stderr, as a bar
+// against the input size when it is known.
+type progressBar struct {
+	total int64
+	bytes atomic.Int64
+	rows  atomic.Int64
+	start time.Time
+	stop  chan struct{}
+	once  sync.Once
+	done  sync.WaitGroup
+}
+
+// startProgress starts a bar for an input of total bytes (zero when
+// unknown). It returns nil, on which every method is a no-op, with --quiet
+// or when stderr is not a terminal.
+func startProgress(total int64) *progressBar {
+	if viper.GetBool("quiet") {
+		return nil
+	}
+	if fi, err := os.Stderr.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	p := &progressBar{total: total, start: time.Now(), stop: make(chan struct{})}
+	p.done.Add(1)
+	go p.run()
+	return p
+}
+
+func (p *progressBar) run() {
+	defer p.done.Done()
+	delay := time.NewTimer(progressDelay)
+	defer delay.Stop()
+	select {
+	case <-p.stop:
+		return
+	case <-delay.C:
+	}
+	tick := time.NewTicker(progressTick)
+	defer tick.Stop()
+	for {
+		p.draw()
+		select {
+		case <-p.stop:
+			// Clear the line for the command's own output.
+			fmt.Fprint(os.Stderr, "\r\033[K")
+			return
+		case <-tick.C:
+		}
+	}
+}
+
+func (p *progressBar) draw() {
+	n, rows := p.bytes.Load(), p.rows.Load()
+	rate := float64(n) / time.Since(p.start).Seconds()
+	var b strings.Builder
+	b.WriteString("\r")
+	if p.total > 0 {
+		frac := min(float64(n)/float64(p.total), 1)
+		filled := int(frac * progressWidth)
+		fmt.Fprintf(&b, "%3.0f%% [%s%s] %s/%s", frac*100, strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), byteSize(n), byteSize(p.total))
+	} else {
+		b.WriteString(byteSize(n))
+	}
+	fmt.Fprintf(&b, "  %d rows  %s/s\033[K", rows, byteSize(int64(rate)))
+	fmt.Fprint(os.Stderr, b.String())
+}
+
+// reader counts the bytes read through r.
+func (p *progressBar) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return progressReader{r: r, p: p}
+}
+
+// setRows records the rows decoded so far.
+func (p *progressBar) setRows(n int64) {
+	if p != nil {
+		p.rows.Store(n)
+	}
+}
+
+// Close stops the bar and clears it.
+func (p *progressBar) Close() {
+	if p == nil {
+		return
+	}
+	p.once.Do(func() { close(p.stop) })
+	p.done.Wait()
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progressBar
+}
+
+func (r progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	r.p.bytes.Add(int64(n))
+	return n, err
+}
+
+// byteSize formats n bytes with a binary unit.
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,498 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render an HTML report of a detection run",
+	Long: `Render an HTML report of a detection run.
+
+Scores --column with --method as analyze does and writes a single
+self-contained HTML page, with no scripts or assets to fetch, to --out: the
+summary statistics, a histogram of the values with the flagged ones
+stacked in red, the series in row order (or by --time-column) with the
+flagged points marked, and the most anomalous rows. Hovering over a bar or
+point shows its values. Long series are drawn from the minimum and maximum
+of each of about 1000 slices of rows, so spikes stay visible.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if viper.GetString("column") == "" {
+			return fmt.Errorf("--column is required")
+		}
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		column := viper.GetString("column")
+		// --time-column is read from this command's flags, since viper
+		// binds the key to analyze's.
+		timeColumn, _ := cmd.Flags().GetString("time-column")
+		path, _ := cmd.Flags().GetString("out")
+		bins, _ := cmd.Flags().GetInt("bins")
+		if bins < 1 {
+			return fmt.Errorf("--bins must be at least 1, got %d", bins)
+		}
+
+		data, err := buildReport(detectContext(), src, column, timeColumn, bins)
+		if err != nil {
+			return err
+		}
+		var out io.Writer = os.Stdout
+		if path != "-" {
+			f, err := os.Create(path)
+			if err != nil {
+				return fmt.Errorf("create report: %w", err)
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := reportTemplate.Execute(out, data); err != nil {
+			return fmt.Errorf("write report: %w", err)
+		}
+		if f, ok := out.(*os.File); ok && f != os.Stdout {
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("write report: %w", err)
+			}
+			fmt.Fprintf(os.Stderr, "Wrote %s: %d rows, %d anomalies\n", path, data.Summary.Values, data.Summary.AnomalyCount)
+		}
+		return nil
+	},
+}
+
+// Report plot geometry, in SVG user units.
+const (
+	plotWidth   = 900.0
+	plotHeight  = 260.0
+	plotMargin  = 50.0
+	plotSlices  = 1000
+	reportTop   = 50
+	reportTicks = 5
+)
+
+// reportData is what the report template renders.
+type reportData struct {
+	Column, Method, Source string
+	Threshold              float64
+	Generated              string
+	Summary                *outputSummary
+	Nulls                  int64
+	Width, Height          float64
+	// Series is the SVG path of the values and Points the flagged rows.
+	Series         string
+	Points         []reportPoint
+	XTicks, YTicks []reportTick
+	Bars           []reportBar
+	BinTicks       []reportTick
+	Top            []reportRow
+	Timed          bool
+}
+
+type reportPoint struct {
+	X, Y  float64
+	Label string
+}
+
+type reportTick struct {
+	Pos   float64
+	Label string
+}
+
+// reportBar is a histogram bin, with the flagged share of it stacked on
+// top in red.
+type reportBar struct {
+	X, W, Y, H, FlagY, FlagH float64
+	Label                    string
+}
+
+type reportRow struct {
+	Row          int64
+	Time         string
+	Value, Score float64
+}
+
+// buildReport scores column of src and lays out the report.
+func buildReport(ctx context.Context, src source, column, timeColumn string, bins int) (*reportData, error) {
+	detector, err := newDetector(viper.GetString("method"))
+	if err != nil {
+		return nil, err
+	}
+	columns := []string{column}
+	if timeColumn != "" {
+		columns = append(columns, timeColumn)
+	}
+	rec, err := src.ReadColumns(ctx, columns)
+	if err != nil {
+		return nil, fmt.Errorf("read columns: %w", err)
+	}
+	defer rec.Release()
+	res, err := anomaly.Detect(ctx, detector, rec.Column(0))
+	if err != nil {
+		return nil, fmt.Errorf("detect anomalies: %w", err)
+	}
+	defer res.Release()
+
+	values, err := reportValues(ctx, rec.Column(0))
+	if err != nil {
+		return nil, err
+	}
+	var times []int64
+	if timeColumn != "" {
+		if times, err = reportTimes(ctx, rec.Column(1)); err != nil {
+			return nil, fmt.Errorf("time column: %w", err)
+		}
+	}
+
+	s := newSummarizer(false)
+	s.add(rec.Column(0))
+	data := &reportData{
+		Column:    column,
+		Method:    viper.GetString("method"),
+		Source:    viper.GetString("file"),
+		Threshold: viper.GetFloat64("threshold"),
+		Generated: time.Now().UTC().Format(time.RFC3339),
+		Summary:   s.summary(int64(res.Indices.Len()), rec.NumRows()),
+		Nulls:     res.Nulls + res.NaNs + res.Infs,
+		Width:     plotWidth,
+		Height:    plotHeight,
+		Timed:     times != nil,
+	}
+	flagged := make(map[int]bool, res.Indices.Len())
+	for i := 0; i < res.Indices.Len(); i++ {
+		flagged[int(res.Indices.Value(i))] = true
+	}
+	data.plotSeries(values, times, flagged, res)
+	data.plotHistogram(values, flagged, bins)
+	data.Top = topRows(values, times, res)
+	return data, nil
+}
+
+// reportValues returns col as float64 values, with NaN at nulls.
+func reportValues(ctx context.Context, col arrow.Array) ([]float64, error) {
+	if dict, ok := col.(*array.Dictionary); ok {
+		decoded, err := compute.TakeArray(ctx, dict.Dictionary(), dict.Indices())
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", col.DataType(), err)
+		}
+		defer decoded.Release()
+		col = decoded
+	}
+	cast, err := compute.CastArray(ctx, col, compute.UnsafeCastOptions(arrow.PrimitiveTypes.Float64))
+	if err != nil {
+		return nil, fmt.Errorf("cannot plot %s column: %w", col.DataType(), err)
+	}
+	defer cast.Release()
+	f := cast.(*array.Float64)
+	out := make([]float64, f.Len())
+	for i := range out {
+		if f.IsNull(i) {
+			out[i] = math.NaN()
+		} else {
+			out[i] = f.Value(i)
+		}
+	}
+	return out, nil
+}
+
+// reportTimes returns col as Unix nanoseconds, read as --time-format's
+// default format does, with math.MinInt64 at nulls.
+func reportTimes(ctx context.Context, col arrow.Array) ([]int64, error) {
+	ts, err := anomaly.ParseTimes(ctx, col, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer ts.Release()
+	t := ts.(*array.Timestamp)
+	out := make([]int64, t.Len())
+	for i := range out {
+		if t.IsNull(i) {
+			out[i] = math.MinInt64
+		} else {
+			out[i] = int64(t.Value(i))
+		}
+	}
+	return out, nil
+}
+
+// plotSeries lays out the values in row order, or in time order when times
+// is set, as the minimum and maximum of each slice of rows.
+func (d *reportData) plotSeries(values []float64, times []int64, flagged map[int]bool, res *anomaly.Result) {
+	order := make([]int, 0, len(values))
+	for i, v := range values {
+		if !isFinite(v) || (times != nil && times[i] == math.MinInt64) {
+			continue
+		}
+		order = append(order, i)
+	}
+	if len(order) == 0 {
+		return
+	}
+	if times != nil {
+		sort.SliceStable(order, func(a, b int) bool { return times[order[a]] < times[order[b]] })
+	}
+	xOf := func(i int) float64 { return float64(i) }
+	if times != nil {
+		xOf = func(i int) float64 { return float64(times[i]) }
+	}
+	xlo, xhi := xOf(order[0]), xOf(order[len(order)-1])
+	ylo, yhi := values[order[0]], values[order[0]]
+	for _, i := range order {
+		ylo, yhi = math.Min(ylo, values[i]), math.Max(yhi, values[i])
+	}
+	xs := newScale(xlo, xhi, plotMargin, d.Width-plotMargin/2)
+	ys := newScale(ylo, yhi, d.Height-plotMargin/2, plotMargin/2)
+
+	var path strings.Builder
+	step := max(1, (len(order)+plotSlices-1)/plotSlices)
+	for lo := 0; lo < len(order); lo += step {
+		slice := order[lo:min(lo+step, len(order))]
+		first, last := slice[0], slice[0]
+		for _, i := range slice {
+			if values[i] < values[first] {
+				first = i
+			}
+			if values[i] > values[last] {
+				last = i
+			}
+		}
+		if first > last {
+			first, last = last, first
+		}
+		for _, i := range []int{first, last} {
+			cmd := "L"
+			if path.Len() == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&path, "%s%.1f %.1f", cmd, xs.at(xOf(i)), ys.at(values[i]))
+		}
+	}
+	d.Series = path.String()
+
+	for j := 0; j < res.Indices.Len(); j++ {
+		i := int(res.Indices.Value(j))
+		if !isFinite(values[i]) || (times != nil && times[i] == math.MinInt64) {
+			continue
+		}
+		label := fmt.Sprintf("row %d: %v (score %.2f)", i, values[i], res.Zscore.Value(i))
+		if times != nil {
+			label = fmt.Sprintf("%s: %v (row %d, score %.2f)", formatNanos(times[i]), values[i], i, res.Zscore.Value(i))
+		}
+		d.Points = append(d.Points, reportPoint{X: xs.at(xOf(i)), Y: ys.at(values[i]), Label: label})
+	}
+	for _, v := range ticks(xlo, xhi) {
+		label := fmt.Sprintf("%.0f", v)
+		if times != nil {
+			label = formatNanos(int64(v))
+		}
+		d.XTicks = append(d.XTicks, reportTick{Pos: xs.at(v), Label: label})
+	}
+	for _, v := range ticks(ylo, yhi) {
+		d.YTicks = append(d.YTicks, reportTick{Pos: ys.at(v), Label: fmt.Sprintf("%.4g", v)})
+	}
+}
+
+// plotHistogram lays out bins equal-width bins over the finite values.
+func (d *reportData) plotHistogram(values []float64, flagged map[int]bool, bins int) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		if isFinite(v) {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+	}
+	if lo > hi {
+		return
+	}
+	width := (hi - lo) / float64(bins)
+	if width == 0 {
+		bins, width = 1, 1
+	}
+	counts := make([]int, bins)
+	flags := make([]int, bins)
+	for i, v := range values {
+		if !isFinite(v) {
+			continue
+		}
+		b := min(bins-1, int((v-lo)/width))
+		counts[b]++
+		if flagged[i] {
+			flags[b]++
+		}
+	}
+	var most int
+	for _, c := range counts {
+		most = max(most, c)
+	}
+	xs := newScale(lo, lo+float64(bins)*width, plotMargin, d.Width-plotMargin/2)
+	ys := newScale(0, float64(most), d.Height-plotMargin/2, plotMargin/2)
+	for b, c := range counts {
+		from, to := lo+float64(b)*width, lo+float64(b+1)*width
+		bar := reportBar{
+			X:     xs.at(from),
+			W:     math.Max(xs.at(to)-xs.at(from)-1, 1),
+			Y:     ys.at(float64(c)),
+			FlagY: ys.at(float64(flags[b])),
+			Label: fmt.Sprintf("%.4g to %.4g: %d rows, %d flagged", from, to, c, flags[b]),
+		}
+		bar.H = ys.at(0) - bar.Y
+		bar.FlagH = ys.at(0) - bar.FlagY
+		d.Bars = append(d.Bars, bar)
+	}
+	for _, v := range ticks(lo, lo+float64(bins)*width) {
+		d.BinTicks = append(d.BinTicks, reportTick{Pos: xs.at(v), Label: fmt.Sprintf("%.4g", v)})
+	}
+}
+
+// topRows returns the reportTop flagged rows with the highest absolute
+// scores.
+func topRows(values []float64, times []int64, res *anomaly.Result) []reportRow {
+	rows := make([]reportRow, 0, res.Indices.Len())
+	for j := 0; j < res.Indices.Len(); j++ {
+		i := res.Indices.Value(j)
+		row := reportRow{Row: i, Value: values[i], Score: res.Zscore.Value(int(i))}
+		if times != nil && times[i] != math.MinInt64 {
+			row.Time = formatNanos(times[i])
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(a, b int) bool { return math.Abs(rows[a].Score) > math.Abs(rows[b].Score) })
+	return rows[:min(len(rows), reportTop)]
+}
+
+// scale maps data values in [lo, hi] linearly onto [from, to], rounded to
+// a tenth of a unit to keep the page small.
+type scale struct{ lo, hi, from, to float64 }
+
+func newScale(lo, hi, from, to float64) scale {
+	if hi == lo {
+		lo, hi = lo-1, hi+1
+	}
+	return scale{lo, hi, from, to}
+}
+
+func (s scale) at(v float64) float64 {
+	return math.Round((s.from+(v-s.lo)/(s.hi-s.lo)*(s.to-s.from))*10) / 10
+}
+
+// ticks returns about reportTicks evenly spaced round values in [lo, hi].
+func ticks(lo, hi float64) []float64 {
+	if hi <= lo {
+		return []float64{lo}
+	}
+	raw := (hi - lo) / reportTicks
+	step := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{2, 5, 10} {
+		if step*m > raw {
+			break
+		}
+		step *= m
+	}
+	if step < raw {
+		step *= 2
+	}
+	var out []float64
+	for v := math.Ceil(lo/step) * step; v <= hi; v += step {
+		out = append(out, v)
+	}
+	return out
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func formatNanos(ns int64) string {
+	return time.Unix(0, ns).UTC().Format(time.RFC3339)
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Anomaly report: {{.Column}}</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 960px; color: #222; }
+h1 { font-size: 1.5em; margin-bottom: 0.2em; }
+.meta { color: #666; margin-top: 0; }
+table { border-collapse: collapse; }
+th, td { padding: 0.25em 0.8em; text-align: right; border-bottom: 1px solid #eee; }
+th { background: #f6f6f6; }
+.stats td:first-child, .stats th:first-child { text-align: left; }
+svg { background: #fcfcfc; border: 1px solid #eee; }
+.axis { stroke: #999; }
+.tick { fill: #666; font-size: 11px; }
+.series { fill: none; stroke: #3b6fb6; stroke-width: 1; }
+.bar { fill: #9bb7dd; }
+.flag { fill: #d33; }
+.bar:hover, .flag:hover { opacity: 0.7; }
+circle.flag:hover { r: 6; }
+</style>
+</head>
+<body>
+<h1>Anomaly report: {{.Column}}</h1>
+<p class="meta">{{if .Source}}{{.Source}}, {{end}}method {{.Method}}, threshold {{.Threshold}}, generated {{.Generated}}</p>
+
+<h2>Summary</h2>
+<table class="stats">
+<tr><th>Statistic</th><th>Value</th></tr>
+<tr><td>Values</td><td>{{.Summary.Values}}</td></tr>
+<tr><td>Anomalies</td><td>{{.Summary.AnomalyCount}} ({{printf "%.3g" .Summary.AnomalyRate}} of rows)</td></tr>
+<tr><td>Mean</td><td>{{printf "%.6g" .Summary.Mean}}</td></tr>
+<tr><td>Standard deviation</td><td>{{printf "%.6g" .Summary.StdDev}}</td></tr>
+<tr><td>Median{{if .Summary.MedianApproximate}} (estimate){{end}}</td><td>{{printf "%.6g" .Summary.Median}}</td></tr>
+<tr><td>Minimum</td><td>{{printf "%.6g" .Summary.Min}}</td></tr>
+<tr><td>Maximum</td><td>{{printf "%.6g" .Summary.Max}}</td></tr>
+{{if .Nulls}}<tr><td>Skipped (null, NaN, Inf)</td><td>{{.Nulls}}</td></tr>{{end}}
+</table>
+
+{{if .Series}}
+<h2>Series</h2>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" role="img" aria-label="{{.Column}} over {{if .Timed}}time{{else}}rows{{end}}">
+{{range .YTicks}}<text class="tick" x="44" y="{{.Pos}}" text-anchor="end" dominant-baseline="middle">{{.Label}}</text>{{end}}
+{{range .XTicks}}<text class="tick" x="{{.Pos}}" y="{{$.Height}}" dy="-6" text-anchor="middle">{{.Label}}</text>{{end}}
+<path class="series" d="{{.Series}}"/>
+{{range .Points}}<circle class="flag" cx="{{.X}}" cy="{{.Y}}" r="3"><title>{{.Label}}</title></circle>{{end}}
+</svg>
+{{end}}
+
+{{if .Bars}}
+<h2>Distribution</h2>
+<svg width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" role="img" aria-label="Histogram of {{.Column}}">
+{{range .BinTicks}}<text class="tick" x="{{.Pos}}" y="{{$.Height}}" dy="-6" text-anchor="middle">{{.Label}}</text>{{end}}
+{{range .Bars}}<g><title>{{.Label}}</title><rect class="bar" x="{{.X}}" y="{{.Y}}" width="{{.W}}" height="{{.H}}"/>{{if .FlagH}}<rect class="flag" x="{{.X}}" y="{{.FlagY}}" width="{{.W}}" height="{{.FlagH}}"/>{{end}}</g>{{end}}
+</svg>
+{{end}}
+
+{{if .Top}}
+<h2>Most anomalous rows</h2>
+<table>
+<tr><th>Row</th>{{if .Timed}}<th>Time</th>{{end}}<th>Value</th><th>Score</th></tr>
+{{range .Top}}<tr><td>{{.Row}}</td>{{if $.Timed}}<td>{{.Time}}</td>{{end}}<td>{{printf "%.6g" .Value}}</td><td>{{printf "%.2f" .Score}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+func init() {
+	reportCmd.Flags().String("time-column", "", "Timestamp column to plot the series against (default: row order)")
+	reportCmd.Flags().String("out", "report.html", "File to write the report to (- for stdout)")
+	reportCmd.Flags().Int("bins", 40, "Histogram bins")
+	rootCmd.AddCommand(reportCmd)
+}
@@ -1,29 +1,87 @@
 package cmd
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 var (
-	cfgFile    string
-	inputFile  string
-	threshold  float64
-	columnName string
-	jsonOut    bool
-	rootCmd    = &cobra.Command{
+	cfgFile       string
+	partitions    []string
+	profileName   string
+	inputFile     string
+	threshold     float64
+	columnName    columnValues
+	columnList    []string
+	jsonOut       bool
+	method        string
+	approx        bool
+	stream        bool
+	window        int
+	neighbors     int
+	alpha         float64
+	quantile      float64
+	minCount      int64
+	patternShare  float64
+	contamination float64
+	upper         float64
+	lower         float64
+	nullPolicy    string
+	nanPolicy     string
+	parallel      int
+	fastPath      bool
+	exactInts     bool
+	memoryLimit   int64
+	noMmap        bool
+	flightURI     string
+	ticket        string
+	dsn           string
+	query         string
+	driver        string
+	promURL       string
+	promQL        string
+	since         time.Duration
+	step          time.Duration
+	webhooks      []string
+	slackHook     string
+	pagerKey      string
+	alertFile     string
+	notifyMode    string
+	otelExport    string
+	otelURL       string
+	format        string
+	exprText      string
+	transformSpec string
+	filterText    string
+	delimiter     string
+	quoteChar     string
+	commentChar   string
+	noHeader      bool
+	decimalSep    string
+	nullValues    []string
+	skipBadRows   bool
+	schemaSpec    string
+	inferRows     int
+	timeFormats   []string
+	timezone      string
+	quiet         bool
+	rowLimit      int64
+	sampleRate    float64
+	sampleSeed    int64
+	rootCmd       = &cobra.Command{
 		Use:   "supercharged",
 		Short: "Detect anomalies in a CSV column",
-		PersistentPreRun: func(cmd *cobra.Command, args []string) {
-			// viper config setup
-			if cfgFile != "" {
-				viper.SetConfigFile(cfgFile)
-				if err := viper.ReadInConfig(); err == nil {
-					fmt.Println("Using config file:", viper.ConfigFileUsed())
-				}
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := readConfig(); err != nil {
+				return err
 			}
+			if e := viper.GetString("expr"); e != "" && viper.GetString("column") == "" {
+				// The --expr column is named by the expression.
+				viper.Set("column", e)
+			}
+			return startTelemetry(cmd)
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			// default action if no subcommand
@@ -34,22 +92,138 @@ var (
 
 // Execute executes the root command.
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	finishTelemetry(err)
+	return err
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.supercharged.yaml)")
-	rootCmd.PersistentFlags().StringVarP(&inputFile, "file", "f", "", "CSV file path (required)")
-	rootCmd.PersistentFlags().Float64VarP(&threshold, "threshold", "t", 3.0, "Z-score threshold")
-	rootCmd.PersistentFlags().StringVarP(&columnName, "column", "c", "", "Column name to analyze (required)")
+	rootCmd.PersistentFlags().StringVar(&profileName, "profile", "", "Run with the settings of this named profile from the config file's profiles section")
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+	rootCmd.PersistentFlags().StringVarP(&inputFile, "file", "f", "", "Input file path (required)")
+	rootCmd.PersistentFlags().StringSliceVar(&partitions, "partition", nil, "Read only the partitions of a Hive-partitioned --file directory or glob whose key=value directories match, e.g. 'dt=2024-01-*' (glob patterns; repeatable)")
+	viper.BindPFlag("partition", rootCmd.PersistentFlags().Lookup("partition"))
+	rootCmd.PersistentFlags().StringVar(&format, "format", "", "Input format: csv, parquet, jsonl or arrow (default: from file extension)")
+	rootCmd.PersistentFlags().StringVar(&delimiter, "delimiter", "", "CSV field delimiter, e.g. ';' or '\\t' (default: ',', or tab for .tsv files)")
+	viper.BindPFlag("delimiter", rootCmd.PersistentFlags().Lookup("delimiter"))
+	rootCmd.PersistentFlags().StringVar(&quoteChar, "quote", "", "CSV quote character (default: '\"')")
+	viper.BindPFlag("quote", rootCmd.PersistentFlags().Lookup("quote"))
+	rootCmd.PersistentFlags().StringVar(&commentChar, "comment", "", "Skip CSV lines starting with this character")
+	viper.BindPFlag("comment", rootCmd.PersistentFlags().Lookup("comment"))
+	rootCmd.PersistentFlags().BoolVar(&noHeader, "no-header", false, "CSV input has no header row; columns are named f0, f1, ...")
+	viper.BindPFlag("no-header", rootCmd.PersistentFlags().Lookup("no-header"))
+	rootCmd.PersistentFlags().StringVar(&decimalSep, "decimal", "", "CSV decimal separator, e.g. ',' for European files (default: '.')")
+	viper.BindPFlag("decimal", rootCmd.PersistentFlags().Lookup("decimal"))
+	rootCmd.PersistentFlags().StringSliceVar(&nullValues, "null-values", nil, "CSV fields read as null, replacing NULL,null,,N/A,n/a; numbers such as -999 also match equal values")
+	viper.BindPFlag("null-values", rootCmd.PersistentFlags().Lookup("null-values"))
+	rootCmd.PersistentFlags().BoolVar(&skipBadRows, "skip-bad-rows", false, "Skip CSV rows that do not parse, reporting their lines on stderr, instead of failing")
+	viper.BindPFlag("skip-bad-rows", rootCmd.PersistentFlags().Lookup("skip-bad-rows"))
+	rootCmd.PersistentFlags().StringVar(&schemaSpec, "schema", "", "Force CSV and NDJSON column types instead of inferring them, e.g. 'value:float64,ts:timestamp[ms]'")
+	viper.BindPFlag("schema", rootCmd.PersistentFlags().Lookup("schema"))
+	rootCmd.PersistentFlags().IntVar(&inferRows, "infer-rows", 0, "Infer CSV column types from this many rows, widening int64 to float64 and mixed columns to utf8 (0 = first row only, -1 = whole input, held in memory)")
+	viper.BindPFlag("infer-rows", rootCmd.PersistentFlags().Lookup("infer-rows"))
+	rootCmd.PersistentFlags().StringArrayVar(&timeFormats, "time-format", nil, "Read a column as timestamps: column=epoch_s, epoch_ms, epoch_us, epoch_ns, rfc3339 or a Go layout such as '02/01/2006 15:04'; a bare column takes RFC 3339 or '2006-01-02 15:04:05' (repeatable)")
+	viper.BindPFlag("time-format", rootCmd.PersistentFlags().Lookup("time-format"))
+	rootCmd.PersistentFlags().StringVar(&timezone, "timezone", "", "Read naive times (without a zone) as wall-clock times in this IANA zone, e.g. Europe/Paris, normalizing them to UTC")
+	viper.BindPFlag("timezone", rootCmd.PersistentFlags().Lookup("timezone"))
+	rootCmd.PersistentFlags().BoolVar(&quiet, "quiet", false, "Do not draw a progress bar on stderr while reading large inputs")
+	viper.BindPFlag("quiet", rootCmd.PersistentFlags().Lookup("quiet"))
+	rootCmd.PersistentFlags().Int64Var(&rowLimit, "limit", 0, "Read only the first N rows of the input (0 = all)")
+	viper.BindPFlag("limit", rootCmd.PersistentFlags().Lookup("limit"))
+	rootCmd.PersistentFlags().Float64Var(&sampleRate, "sample", 0, "Analyze a random sample: a fraction of rows (e.g. 0.1) or, from 1 up, a reservoir of that many rows")
+	viper.BindPFlag("sample", rootCmd.PersistentFlags().Lookup("sample"))
+	rootCmd.PersistentFlags().Int64Var(&sampleSeed, "seed", 0, "Random seed for --sample (0 = different every run)")
+	viper.BindPFlag("seed", rootCmd.PersistentFlags().Lookup("seed"))
+	rootCmd.PersistentFlags().Float64VarP(&threshold, "threshold", "t", 3.0, "Z-score threshold; unset, iqr uses the Tukey multiplier 1.5 and lof an outlier factor of 1.5")
+	rootCmd.PersistentFlags().VarP(&columnName, "column", "c", "Column to analyze, by name, 0-based index, glob or /regexp/ (required); for analyze, name=threshold overrides --threshold and, repeated, analyzes each column as --columns does")
+	rootCmd.PersistentFlags().StringSliceVar(&columnList, "columns", nil, "Comma-separated columns to analyze in one pass, by name, index, glob or /regexp/; name=threshold overrides --threshold for a column (repeatable)")
 	rootCmd.PersistentFlags().BoolVarP(&jsonOut, "json", "j", false, "Output results in JSON format")
+	rootCmd.PersistentFlags().StringVarP(&method, "method", "m", "zscore", "Detection method: zscore, mad, iqr, rolling, mahalanobis, lof, grubbs, esd, percentile, hampel, category, pattern or flag-rate")
+	rootCmd.PersistentFlags().BoolVar(&approx, "approx", false, "Use approximate (t-digest) quantiles where applicable")
 
 	viper.BindPFlag("file", rootCmd.PersistentFlags().Lookup("file"))
+	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
 	viper.BindPFlag("threshold", rootCmd.PersistentFlags().Lookup("threshold"))
 	viper.BindPFlag("column", rootCmd.PersistentFlags().Lookup("column"))
+	viper.BindPFlag("columns", rootCmd.PersistentFlags().Lookup("columns"))
+	rootCmd.PersistentFlags().StringVar(&exprText, "expr", "", "Analyze an expression over columns, e.g. \"bytes_out / bytes_in\" (default --column)")
+	viper.BindPFlag("expr", rootCmd.PersistentFlags().Lookup("expr"))
+	rootCmd.PersistentFlags().StringVar(&transformSpec, "transform", "", "Transform the analyzed columns before detection: zscore, robust, minmax, log, boxcox[=lambda], diff, diff2 or detrend; comma-separated to chain, e.g. log,diff")
+	viper.BindPFlag("transform", rootCmd.PersistentFlags().Lookup("transform"))
+	rootCmd.PersistentFlags().StringVar(&filterText, "filter", "", "Analyze only the rows matching a condition, e.g. \"region == 'us-east' && value > 0\"; row numbers count the matching rows")
+	viper.BindPFlag("filter", rootCmd.PersistentFlags().Lookup("filter"))
 	viper.BindPFlag("json", rootCmd.PersistentFlags().Lookup("json"))
+	rootCmd.PersistentFlags().BoolVar(&stream, "stream", false, "Score batches with running statistics instead of loading the column")
+	rootCmd.PersistentFlags().IntVar(&window, "window", 30, "Window size for the rolling and flag-rate (trailing) and hampel (centered) methods")
+	rootCmd.PersistentFlags().IntVar(&neighbors, "neighbors", 20, "Neighborhood size for the lof method")
+	rootCmd.PersistentFlags().Float64Var(&alpha, "alpha", 0.05, "Significance level for the grubbs and esd methods")
+	rootCmd.PersistentFlags().Float64Var(&quantile, "q", 0.999, "Upper quantile for the percentile method; values beyond q or 1-q are flagged")
+	rootCmd.PersistentFlags().StringVar(&nullPolicy, "null-policy", "skip", "Null handling: skip, flag, error or impute-mean")
+	rootCmd.PersistentFlags().StringVar(&nanPolicy, "nan-policy", "drop", "NaN and Inf handling: drop, flag or error")
+	viper.BindPFlag("nan-policy", rootCmd.PersistentFlags().Lookup("nan-policy"))
+	rootCmd.PersistentFlags().IntVar(&parallel, "parallel", 1, "Worker goroutines for zscore, mad, iqr and percentile detection (0 = GOMAXPROCS)")
+	viper.BindPFlag("parallel", rootCmd.PersistentFlags().Lookup("parallel"))
+	rootCmd.PersistentFlags().BoolVar(&fastPath, "fast-path", false, "Score in a single loop over the values instead of Arrow compute kernels")
+	viper.BindPFlag("fast-path", rootCmd.PersistentFlags().Lookup("fast-path"))
+	rootCmd.PersistentFlags().BoolVar(&exactInts, "exact-integers", false, "Compute zscore statistics of int64 and uint64 columns exactly instead of from rounded float64 values")
+	viper.BindPFlag("exact-integers", rootCmd.PersistentFlags().Lookup("exact-integers"))
+	rootCmd.PersistentFlags().Int64Var(&memoryLimit, "memory-limit", 0, "Cap detection's memory at this many MiB, spilling cast chunks to temporary files before failing (0 = no limit)")
+	viper.BindPFlag("memory-limit", rootCmd.PersistentFlags().Lookup("memory-limit"))
+	rootCmd.PersistentFlags().BoolVar(&noMmap, "no-mmap", false, "Read local Arrow IPC and Parquet files with read calls instead of memory-mapping them")
+	viper.BindPFlag("no-mmap", rootCmd.PersistentFlags().Lookup("no-mmap"))
+	rootCmd.PersistentFlags().StringVar(&flightURI, "flight", "", "Read input from an Arrow Flight endpoint (grpc://host:port or grpc+tls://host:port) instead of --file")
+	viper.BindPFlag("flight", rootCmd.PersistentFlags().Lookup("flight"))
+	rootCmd.PersistentFlags().StringVar(&ticket, "ticket", "", "Ticket to fetch from the --flight endpoint")
+	viper.BindPFlag("ticket", rootCmd.PersistentFlags().Lookup("ticket"))
+	rootCmd.PersistentFlags().StringVar(&dsn, "dsn", "", "Read input from a database query via ADBC (postgres://, duckdb://, sqlite://, snowflake://)")
+	viper.BindPFlag("dsn", rootCmd.PersistentFlags().Lookup("dsn"))
+	rootCmd.PersistentFlags().StringVar(&query, "query", "", "SQL query to run against --dsn")
+	viper.BindPFlag("query", rootCmd.PersistentFlags().Lookup("query"))
+	rootCmd.PersistentFlags().StringVar(&driver, "driver", "", "ADBC driver library for --dsn (default: from the DSN scheme)")
+	viper.BindPFlag("driver", rootCmd.PersistentFlags().Lookup("driver"))
+	rootCmd.PersistentFlags().StringVar(&promURL, "prometheus", "", "Read input from a Prometheus range query against this server (http://host:9090)")
+	viper.BindPFlag("prometheus", rootCmd.PersistentFlags().Lookup("prometheus"))
+	rootCmd.PersistentFlags().StringVar(&promQL, "promql", "", "PromQL expression to query from --prometheus")
+	viper.BindPFlag("promql", rootCmd.PersistentFlags().Lookup("promql"))
+	rootCmd.PersistentFlags().DurationVar(&since, "since", time.Hour, "How far back the --promql range starts")
+	viper.BindPFlag("since", rootCmd.PersistentFlags().Lookup("since"))
+	rootCmd.PersistentFlags().DurationVar(&step, "step", time.Minute, "Resolution of the --promql range")
+	viper.BindPFlag("step", rootCmd.PersistentFlags().Lookup("step"))
+	rootCmd.PersistentFlags().StringSliceVar(&webhooks, "webhook", nil, "POST anomalies as JSON to these URLs (serve and watch)")
+	viper.BindPFlag("webhook", rootCmd.PersistentFlags().Lookup("webhook"))
+	rootCmd.PersistentFlags().StringVar(&slackHook, "slack-webhook", "", "Post anomalies to this Slack incoming webhook (serve and watch)")
+	viper.BindPFlag("slack-webhook", rootCmd.PersistentFlags().Lookup("slack-webhook"))
+	rootCmd.PersistentFlags().StringVar(&pagerKey, "pagerduty-key", "", "Trigger PagerDuty incidents with this Events API v2 routing key (serve and watch)")
+	viper.BindPFlag("pagerduty-key", rootCmd.PersistentFlags().Lookup("pagerduty-key"))
+	rootCmd.PersistentFlags().StringVar(&alertFile, "alert-file", "", "Append anomalies as JSON lines to this file (serve and watch)")
+	viper.BindPFlag("alert-file", rootCmd.PersistentFlags().Lookup("alert-file"))
+	rootCmd.PersistentFlags().StringVar(&notifyMode, "notify", "anomaly", "Send one notification per anomaly or per batch")
+	viper.BindPFlag("notify", rootCmd.PersistentFlags().Lookup("notify"))
+	rootCmd.PersistentFlags().StringVar(&otelExport, "otel-exporter", "none", "OpenTelemetry trace and metric exporter: none, stdout (to stderr) or otlp")
+	viper.BindPFlag("otel-exporter", rootCmd.PersistentFlags().Lookup("otel-exporter"))
+	rootCmd.PersistentFlags().StringVar(&otelURL, "otel-endpoint", "", "OTLP/gRPC collector endpoint (default: from OTEL_EXPORTER_OTLP_ENDPOINT)")
+	viper.BindPFlag("otel-endpoint", rootCmd.PersistentFlags().Lookup("otel-endpoint"))
+	viper.BindPFlag("null-policy", rootCmd.PersistentFlags().Lookup("null-policy"))
+	viper.BindPFlag("q", rootCmd.PersistentFlags().Lookup("q"))
+	rootCmd.PersistentFlags().Int64Var(&minCount, "min-count", 0, "Flag categories seen fewer than this many times for the category method")
+	viper.BindPFlag("min-count", rootCmd.PersistentFlags().Lookup("min-count"))
+	rootCmd.PersistentFlags().Float64Var(&patternShare, "pattern-share", 0.01, "Flag strings whose character pattern is rarer than this share of rows for the pattern method (0 = length only)")
+	viper.BindPFlag("pattern-share", rootCmd.PersistentFlags().Lookup("pattern-share"))
+	rootCmd.PersistentFlags().Float64Var(&contamination, "contamination", 0, "Flag this fraction of rows with the highest scores instead of applying --threshold, ranked by score")
+	viper.BindPFlag("contamination", rootCmd.PersistentFlags().Lookup("contamination"))
+	rootCmd.PersistentFlags().Float64Var(&upper, "upper", 0, "Flag scores at or above this instead of applying --threshold to both sides (alone: high outliers only)")
+	viper.BindPFlag("upper", rootCmd.PersistentFlags().Lookup("upper"))
+	rootCmd.PersistentFlags().Float64Var(&lower, "lower", 0, "Flag scores at or below minus this instead of applying --threshold to both sides (alone: low outliers only)")
+	viper.BindPFlag("lower", rootCmd.PersistentFlags().Lookup("lower"))
+	viper.BindPFlag("alpha", rootCmd.PersistentFlags().Lookup("alpha"))
+	viper.BindPFlag("neighbors", rootCmd.PersistentFlags().Lookup("neighbors"))
+	viper.BindPFlag("method", rootCmd.PersistentFlags().Lookup("method"))
+	viper.BindPFlag("approx", rootCmd.PersistentFlags().Lookup("approx"))
+	viper.BindPFlag("stream", rootCmd.PersistentFlags().Lookup("stream"))
+	viper.BindPFlag("window", rootCmd.PersistentFlags().Lookup("window"))
 }
 
 func initConfig() {
@@ -62,3 +236,22 @@ func initConfig() {
 		viper.SetConfigName(".supercharged")
 	}
 }
+
+// columnValues is the --column flag. It reads as its last value, as a string
+// flag does, and keeps every value given for analyze, which takes repeated
+// --column name=threshold entries as --columns entries.
+type columnValues []string
+
+func (c *columnValues) String() string {
+	if len(*c) == 0 {
+		return ""
+	}
+	return (*c)[len(*c)-1]
+}
+
+func (c *columnValues) Set(v string) error {
+	*c = append(*c, v)
+	return nil
+}
+
+func (c *columnValues) Type() string { return "string" }
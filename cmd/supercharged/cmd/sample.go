@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/internal/collect"
+)
+
+// sampleSource reads at most limit rows of the wrapped source and, when
+// rate is set, keeps a random sample of them: each row with probability
+// rate when it is below 1, otherwise a reservoir of rate rows. Rows keep
+// their input order. Every read streams the whole (limited) input.
+type sampleSource struct {
+	source
+	limit int64
+	rate  float64
+	seed  int64
+}
+
+// withSample wraps src with the --limit and --sample options, when set.
+func withSample(src source) (source, error) {
+	limit, rate := viper.GetInt64("limit"), viper.GetFloat64("sample")
+	switch {
+	case limit < 0:
+		return nil, fmt.Errorf("--limit must not be negative")
+	case rate < 0 || (rate >= 1 && rate != math.Trunc(rate)):
+		return nil, fmt.Errorf("--sample must be a fraction below 1 or a whole number of rows, got %v", rate)
+	case limit == 0 && rate == 0:
+		return src, nil
+	}
+	seed := viper.GetInt64("seed")
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return sampleSource{source: src, limit: limit, rate: rate, seed: seed}, nil
+}
+
+func (s sampleSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	rec, err := s.ReadColumns(ctx, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+func (s sampleSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	recs, errs := s.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, column)
+}
+
+func (s sampleSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		schema := s.Schema()
+		if schema == nil {
+			return nil, fmt.Errorf("schema not known before reading")
+		}
+		for _, f := range schema.Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	recs, errs := s.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, memory.DefaultAllocator)
+}
+
+// Chan streams the limited, sampled records.
+func (s sampleSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	ctx, cancel := context.WithCancel(ctx)
+	in, inErrs := s.source.Chan(ctx)
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer cancel()
+		defer close(recs)
+		early, err := s.run(ctx, in, recs)
+		if !early {
+			err = errors.Join(err, <-inErrs)
+		} else {
+			// Stop the source. It may finish without reporting once
+			// cancelled, so only an error already sent is taken.
+			cancel()
+			for rec := range in {
+				rec.Release()
+			}
+			select {
+			case e := <-inErrs:
+				if !errors.Is(e, context.Canceled) {
+					err = errors.Join(err, e)
+				}
+			default:
+			}
+		}
+		if err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+	return recs, errs
+}
+
+// run forwards the records of in to out, limited and sampled. It reports
+// whether it stopped before in was drained, at the limit or because ctx was
+// done.
+func (s sampleSource) run(ctx context.Context, in <-chan arrow.Record, out chan<- arrow.Record) (early bool, err error) {
+	rng := rand.New(rand.NewSource(s.seed))
+	res := &reservoir{size: int(s.rate)}
+	defer res.release()
+	var seen int64
+	send := func(rec arrow.Record) bool {
+		select {
+		case out <- rec:
+			return true
+		case <-ctx.Done():
+			rec.Release()
+			return false
+		}
+	}
+	for rec := range in {
+		if s.limit > 0 && seen+rec.NumRows() > s.limit {
+			cut := rec.NewSlice(0, s.limit-seen)
+			rec.Release()
+			rec = cut
+		}
+		first := seen
+		seen += rec.NumRows()
+		switch {
+		case s.rate >= 1:
+			err := res.add(ctx, rec, first, rng)
+			rec.Release()
+			if err != nil {
+				return true, err
+			}
+		case s.rate > 0:
+			var rows []int64
+			for i := int64(0); i < rec.NumRows(); i++ {
+				if rng.Float64() < s.rate {
+					rows = append(rows, i)
+				}
+			}
+			kept, err := takeRows(ctx, rec, rows)
+			rec.Release()
+			if err != nil {
+				return true, err
+			}
+			if kept.NumRows() == 0 {
+				kept.Release()
+			} else if !send(kept) {
+				return true, nil
+			}
+		default:
+			if !send(rec) {
+				return true, nil
+			}
+		}
+		if s.limit > 0 && seen >= s.limit {
+			early = true
+			break
+		}
+	}
+	if s.rate >= 1 {
+		for _, rec := range res.records() {
+			if !send(rec) {
+				return true, nil
+			}
+		}
+	}
+	return early, nil
+}
+
+// reservoir keeps a uniform sample of size rows over every row added
+// (Algorithm R). The rows entering it from each batch are copied out, so a
+// batch is only held while some of its rows are in the sample.
+type reservoir struct {
+	size  int
+	seen  int64
+	slots []slot
+}
+
+type slot struct {
+	seq  int64
+	rows *heldRows
+	row  int64
+}
+
+// heldRows is a batch of sampled rows shared by the slots that point into
+// it, released when the last is replaced.
+type heldRows struct {
+	rec  arrow.Record
+	refs int
+}
+
+func (h *heldRows) unref() {
+	if h.refs--; h.refs == 0 {
+		h.rec.Release()
+	}
+}
+
+// add offers the rows of rec, the first of which is row first of the input.
+func (r *reservoir) add(ctx context.Context, rec arrow.Record, first int64, rng *rand.Rand) error {
+	type entry struct{ slot, row int64 }
+	var entries []entry
+	for i := int64(0); i < rec.NumRows(); i++ {
+		r.seen++
+		if r.seen <= int64(r.size) {
+			entries = append(entries, entry{slot: r.seen - 1, row: i})
+		} else if j := rng.Int63n(r.seen); j < int64(r.size) {
+			entries = append(entries, entry{slot: j, row: i})
+		}
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+	rows := make([]int64, len(entries))
+	for i, e := range entries {
+		rows[i] = e.row
+	}
+	taken, err := takeRows(ctx, rec, rows)
+	if err != nil {
+		return err
+	}
+	held := &heldRows{rec: taken, refs: 1}
+	for i, e := range entries {
+		s := slot{seq: first + e.row, rows: held, row: int64(i)}
+		held.refs++
+		if e.slot == int64(len(r.slots)) {
+			r.slots = append(r.slots, s)
+			continue
+		}
+		r.slots[e.slot].rows.unref()
+		r.slots[e.slot] = s
+	}
+	held.unref()
+	return nil
+}
+
+// records returns the sample in input order, as slices of the held batches.
+func (r *reservoir) records() []arrow.Record {
+	sort.Slice(r.slots, func(i, j int) bool { return r.slots[i].seq < r.slots[j].seq })
+	var out []arrow.Record
+	for i := 0; i < len(r.slots); {
+		j := i + 1
+		for j < len(r.slots) && r.slots[j].rows == r.slots[i].rows && r.slots[j].row == r.slots[j-1].row+1 {
+			j++
+		}
+		out = append(out, r.slots[i].rows.rec.NewSlice(r.slots[i].row, r.slots[j-1].row+1))
+		i = j
+	}
+	return out
+}
+
+func (r *reservoir) release() {
+	for _, s := range r.slots {
+		s.rows.unref()
+	}
+	r.slots = nil
+}
+
+// takeRows returns the given rows of rec.
+func takeRows(ctx context.Context, rec arrow.Record, rows []int64) (arrow.Record, error) {
+	bld := array.NewInt64Builder(memory.DefaultAllocator)
+	defer bld.Release()
+	bld.AppendValues(rows, nil)
+	idx := bld.NewArray()
+	defer idx.Release()
+	out, err := compute.Take(ctx, *compute.DefaultTakeOptions(), compute.NewDatumWithoutOwning(rec), compute.NewDatumWithoutOwning(idx))
+	if err != nil {
+		return nil, fmt.Errorf("sample rows: %w", err)
+	}
+	return out.(*compute.RecordDatum).Value, nil
+}
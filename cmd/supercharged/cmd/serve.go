@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+// arrowStreamType is the media type of an Arrow IPC stream.
+const arrowStreamType = "application/vnd.apache.arrow.stream"
+
+// readHeaderTimeout bounds how long a client may take to send the request
+// headers, and idleTimeout how long a kept-alive connection may sit unused.
+const (
+	readHeaderTimeout = 10 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve anomaly detection over HTTP",
+	Long: `Serve anomaly detection over HTTP.
+
+POST /detect?column=value runs detection over the request body, which may be
+CSV, newline-delimited JSON, Parquet or an Arrow IPC stream, chosen by the
+format parameter or the Content-Type header. With --allow-files, GET
+/detect?file=path reads a file on the server instead. The method, threshold
+and all parameters override the corresponding flags. Bodies larger than
+--max-body are rejected with 413, and a request must be read within
+--read-timeout.
+
+Results are JSON, or the annotated rows as an Arrow IPC stream when the
+request accepts ` + arrowStreamType + `. Each request's
+anomalies are also sent to the --webhook, --slack-webhook, --pagerduty-key
+and --alert-file sinks. GET /metrics exposes Prometheus counters for rows,
+anomalies, latency and memory.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
+		if servePipeline, err = newPipeline(viper.GetString("method")); err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/detect", handleDetect)
+		mux.Handle("/metrics", metrics)
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, "ok")
+		})
+		srv := &http.Server{
+			Addr:              viper.GetString("addr"),
+			Handler:           mux,
+			ReadHeaderTimeout: readHeaderTimeout,
+			ReadTimeout:       viper.GetDuration("read-timeout"),
+			IdleTimeout:       idleTimeout,
+		}
+		// Shut down cleanly on a signal so buffered telemetry is flushed.
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			srv.Shutdown(context.Background())
+		}()
+		log.Printf("listening on %s", srv.Addr)
+		if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+// servePipeline scores the requests that keep the method and threshold of
+// the flags; it is built once, when the server starts.
+var servePipeline *anomaly.Pipeline
+
+// httpError is an error with the status code to report it under.
+type httpError struct {
+	status int
+	err    error
+}
+
+func (e *httpError) Error() string { return e.err.Error() }
+
+func badRequest(format string, args ...any) error {
+	return &httpError{status: http.StatusBadRequest, err: fmt.Errorf(format, args...)}
+}
+
+// handleDetect serves /detect under a span that continues the caller's
+// trace from its traceparent header.
+func handleDetect(w http.ResponseWriter, r *http.Request) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, r.Method+" /detect", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+	r = r.WithContext(ctx)
+	if err := detectRequest(w, r); err != nil {
+		status := statusOf(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+}
+
+// statusOf is the status to report err under: the one an httpError carries,
+// 413 for a body past --max-body, 400 for an input that cannot be read or
+// lacks the column, 422 for a column that cannot be scored, 503 when
+// detection runs out of its --memory-limit, and 500 for anything else.
+func statusOf(err error) int {
+	var he *httpError
+	var tooLarge *http.MaxBytesError
+	var input inputError
+	switch {
+	case errors.As(err, &he):
+		return he.status
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &input):
+		return http.StatusBadRequest
+	case errors.Is(err, anomaly.ErrZeroScale):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, anomaly.ErrMemoryLimit):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func detectRequest(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	column := q.Get("column")
+	if column == "" {
+		return badRequest("column is required")
+	}
+
+	src, err := requestSource(w, r)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	p := servePipeline
+	if q.Has("method") || q.Has("threshold") {
+		method := viper.GetString("method")
+		if m := q.Get("method"); m != "" {
+			method = m
+		}
+		var extra []anomaly.Option
+		if t := q.Get("threshold"); t != "" {
+			threshold, err := strconv.ParseFloat(t, 64)
+			if err != nil {
+				return badRequest("threshold: %w", err)
+			}
+			extra = append(extra, anomaly.WithThreshold(threshold))
+		}
+		if p, err = newPipeline(method, extra...); err != nil {
+			return badRequest("%w", err)
+		}
+	}
+
+	wantArrow := acceptsArrow(r)
+	start := time.Now()
+	rows, out, err := annotatedRows(r.Context(), src, p, column, wantArrow && q.Get("all") == "true", anomaly.CleanMode(q.Get("clean")))
+	if err != nil {
+		return err
+	}
+	metrics.observe(out.Count, int64(len(out.Anomalies)), time.Since(start))
+	defer rows.Release()
+	if sinks := configuredSinks(); sinks != nil && len(out.events) > 0 {
+		if err := notify(r.Context(), sinks, out.events); err != nil {
+			log.Printf("notify: %v", err)
+		}
+	}
+
+	if !wantArrow {
+		w.Header().Set("Content-Type", "application/json")
+		out.finish()
+		return json.NewEncoder(w).Encode(out)
+	}
+	w.Header().Set("Content-Type", arrowStreamType)
+	rw, err := newRecordWriter(w, "arrow", rows.Schema())
+	if err != nil {
+		return err
+	}
+	if err := writeBatches(rw, rows, "arrow"); err != nil {
+		return err
+	}
+	return rw.Close()
+}
+
+// requestSource opens the request body, or the file parameter when
+// --allow-files is set, as a source, limiting the body to --max-body.
+func requestSource(w http.ResponseWriter, r *http.Request) (source, error) {
+	q := r.URL.Query()
+	if path := q.Get("file"); path != "" {
+		if !viper.GetBool("allow-files") {
+			return nil, &httpError{status: http.StatusForbidden, err: fmt.Errorf("file paths are disabled; start the server with --allow-files")}
+		}
+		src, err := openSource(path)
+		if err != nil {
+			return nil, badRequest("%w", err)
+		}
+		return traceSource(src), nil
+	}
+	if r.Method != http.MethodPost {
+		return nil, &httpError{status: http.StatusMethodNotAllowed, err: fmt.Errorf("POST a payload or pass file")}
+	}
+	format := q.Get("format")
+	if format == "" {
+		format = bodyFormat(r.Header.Get("Content-Type"))
+	}
+	body := r.Body
+	if mib := viper.GetInt64("max-body"); mib > 0 {
+		body = http.MaxBytesReader(w, body, mib<<20)
+	}
+	src, err := newSource(readerInput(body), format, "")
+	if err != nil {
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return nil, err
+		}
+		return nil, badRequest("%w", err)
+	}
+	return traceSource(src), nil
+}
+
+// bodyFormat maps a Content-Type to an input format, defaulting to CSV.
+func bodyFormat(contentType string) string {
+	mt, _, _ := mime.ParseMediaType(contentType)
+	switch mt {
+	case "application/json", "application/x-ndjson", "application/jsonl":
+		return "jsonl"
+	case arrowStreamType:
+		return "arrow"
+	case "application/vnd.apache.parquet", "application/x-parquet":
+		return "parquet"
+	default:
+		return "csv"
+	}
+}
+
+// acceptsArrow reports whether the request asks for an Arrow IPC response.
+func acceptsArrow(r *http.Request) bool {
+	for _, part := range r.Header.Values("Accept") {
+		for _, t := range splitAccept(part) {
+			if t == arrowStreamType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func splitAccept(header string) []string {
+	var out []string
+	for _, t := range strings.Split(header, ",") {
+		if mt, _, err := mime.ParseMediaType(strings.TrimSpace(t)); err == nil {
+			out = append(out, mt)
+		}
+	}
+	return out
+}
+
+func init() {
+	serveCmd.Flags().String("addr", ":8080", "Address to listen on")
+	viper.BindPFlag("addr", serveCmd.Flags().Lookup("addr"))
+	serveCmd.Flags().Bool("allow-files", false, "Allow requests to read files on the server by path")
+	viper.BindPFlag("allow-files", serveCmd.Flags().Lookup("allow-files"))
+	serveCmd.Flags().Int64("max-body", 256, "Reject request bodies larger than this many MiB (0 = no limit)")
+	viper.BindPFlag("max-body", serveCmd.Flags().Lookup("max-body"))
+	serveCmd.Flags().Duration("read-timeout", 5*time.Minute, "Longest a client may take to send a request, body included (0 = no limit)")
+	viper.BindPFlag("read-timeout", serveCmd.Flags().Lookup("read-timeout"))
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutmetric"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	tracer = otel.Tracer("github.com/TFMV/supercharged/cmd/supercharged")
+
+	// commandCtx carries the span of the running command, so detection and
+	// read spans nest under it.
+	commandCtx = context.Background()
+	// finishTelemetry ends the command span and flushes the exporters.
+	finishTelemetry = func(error) {}
+)
+
+// startTelemetry installs the --otel-exporter providers and starts the
+// command span, continuing the trace in $TRACEPARENT when it is set.
+func startTelemetry(cmd *cobra.Command) error {
+	var shutdown []func(context.Context) error
+	switch exporter := viper.GetString("otel-exporter"); exporter {
+	case "none", "":
+		return nil
+	case "stdout", "otlp":
+		tp, mp, err := newProviders(exporter, viper.GetString("otel-endpoint"))
+		if err != nil {
+			return err
+		}
+		otel.SetTracerProvider(tp)
+		otel.SetMeterProvider(mp)
+		shutdown = append(shutdown, tp.Shutdown, mp.Shutdown)
+	default:
+		return fmt.Errorf("unknown --otel-exporter %q", exporter)
+	}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier{
+		"traceparent": os.Getenv("TRACEPARENT"),
+		"tracestate":  os.Getenv("TRACESTATE"),
+	})
+	ctx, span := tracer.Start(ctx, cmd.CommandPath())
+	commandCtx = ctx
+	finishTelemetry = func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		for _, fn := range shutdown {
+			if err := fn(ctx); err != nil {
+				fmt.Fprintln(os.Stderr, "telemetry:", err)
+			}
+		}
+	}
+	return nil
+}
+
+// newProviders builds trace and meter providers exporting to stderr or over
+// OTLP/gRPC. An empty endpoint leaves OTLP to the OTEL_EXPORTER_OTLP_*
+// environment variables.
+func newProviders(exporter, endpoint string) (*sdktrace.TracerProvider, *sdkmetric.MeterProvider, error) {
+	ctx := context.Background()
+	var (
+		spans   sdktrace.SpanExporter
+		metrics sdkmetric.Exporter
+		err     error
+	)
+	if exporter == "stdout" {
+		spans, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+		if err == nil {
+			metrics, err = stdoutmetric.New(stdoutmetric.WithWriter(os.Stderr))
+		}
+	} else {
+		var traceOpts []otlptracegrpc.Option
+		var metricOpts []otlpmetricgrpc.Option
+		if endpoint != "" {
+			if !strings.Contains(endpoint, "://") {
+				endpoint = "http://" + endpoint
+			}
+			traceOpts = append(traceOpts, otlptracegrpc.WithEndpointURL(endpoint))
+			metricOpts = append(metricOpts, otlpmetricgrpc.WithEndpointURL(endpoint))
+		}
+		spans, err = otlptracegrpc.New(ctx, traceOpts...)
+		if err == nil {
+			metrics, err = otlpmetricgrpc.New(ctx, metricOpts...)
+		}
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("telemetry exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", "supercharged")))
+	if err != nil {
+		return nil, nil, fmt.Errorf("telemetry resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spans), sdktrace.WithResource(res))
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metrics)), sdkmetric.WithResource(res))
+	return tp, mp, nil
+}
+
+// tracedSource records a span for each read of the wrapped source, under the
+// span of the context the read is given.
+type tracedSource struct {
+	source
+}
+
+// traceSource wraps src so its reads are traced.
+func traceSource(src source) source {
+	return tracedSource{source: src}
+}
+
+func (s tracedSource) start(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attribute.String("supercharged.source", fmt.Sprintf("%T", s.source))))
+}
+
+func endRead(span trace.Span, rows int64, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int64("supercharged.rows", rows))
+	}
+	span.End()
+}
+
+func (s tracedSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	ctx, span := s.start(ctx, "ReadSingleColumn")
+	arr, err := s.source.ReadSingleColumn(ctx, column)
+	var rows int64
+	if err == nil {
+		rows = int64(arr.Len())
+	}
+	endRead(span, rows, err)
+	return arr, err
+}
+
+func (s tracedSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	ctx, span := s.start(ctx, "ReadChunked")
+	col, err := s.source.ReadChunked(ctx, column)
+	var rows int64
+	if err == nil {
+		rows = int64(col.Len())
+	}
+	endRead(span, rows, err)
+	return col, err
+}
+
+func (s tracedSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	ctx, span := s.start(ctx, "ReadColumns")
+	rec, err := s.source.ReadColumns(ctx, columns)
+	var rows int64
+	if err == nil {
+		rows = rec.NumRows()
+	}
+	endRead(span, rows, err)
+	return rec, err
+}
+
+// Chan forwards the wrapped stream, ending its span when the stream does.
+func (s tracedSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	ctx, span := s.start(ctx, "Chan")
+	in, inErrs := s.source.Chan(ctx)
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		var rows int64
+		for rec := range in {
+			rows += rec.NumRows()
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+			}
+		}
+		err := <-inErrs
+		endRead(span, rows, err)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return recs, errs
+}
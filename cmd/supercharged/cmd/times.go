@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/spf13/viper"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+// timeSource reads the --time-format columns of the wrapped source as UTC
+// timestamps, converting each record batch as it is read. With --timezone,
+// naive times in them, and timestamp columns without a time zone, are read
+// as wall-clock times in loc.
+type timeSource struct {
+	source
+	formats map[string]anomaly.TimeFormat
+	loc     *time.Location
+}
+
+// withTimes wraps src with the --time-format and --timezone conversions,
+// when set.
+func withTimes(src source) (source, error) {
+	entries := viper.GetStringSlice("time-format")
+	tz := viper.GetString("timezone")
+	if len(entries) == 0 && tz == "" {
+		return src, nil
+	}
+	loc := time.UTC
+	if tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			src.Close()
+			return nil, fmt.Errorf("--timezone: %w", err)
+		}
+	}
+	formats := map[string]anomaly.TimeFormat{}
+	schema := src.Schema()
+	for _, e := range entries {
+		// A bare column name takes the default formats.
+		name, format, _ := strings.Cut(e, "=")
+		if name == "" {
+			src.Close()
+			return nil, fmt.Errorf("--time-format entry %q: want column=format", e)
+		}
+		if schema != nil && !schema.HasField(name) {
+			src.Close()
+			return nil, fmt.Errorf("--time-format: column %s not found", name)
+		}
+		formats[name] = anomaly.TimeFormat(format)
+	}
+	if schema != nil && loc != time.UTC {
+		for _, f := range schema.Fields() {
+			if ts, ok := f.Type.(*arrow.TimestampType); ok && ts.TimeZone == "" {
+				if _, ok := formats[f.Name]; !ok {
+					formats[f.Name] = ""
+				}
+			}
+		}
+	}
+	return timeSource{source: src, formats: formats, loc: loc}, nil
+}
+
+func (s timeSource) Schema() *arrow.Schema {
+	schema := s.source.Schema()
+	if schema == nil {
+		return nil
+	}
+	fields := schema.Fields()
+	for i, f := range fields {
+		if _, ok := s.formats[f.Name]; ok {
+			fields[i].Type = anomaly.TimestampType
+		}
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+func (s timeSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	col, err := s.source.ReadSingleColumn(ctx, column)
+	if err != nil {
+		return nil, err
+	}
+	defer col.Release()
+	return s.parse(ctx, column, col)
+}
+
+func (s timeSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	chunked, err := s.source.ReadChunked(ctx, column)
+	if err != nil {
+		return nil, err
+	}
+	defer chunked.Release()
+	if _, ok := s.formats[column]; !ok {
+		chunked.Retain()
+		return chunked, nil
+	}
+	chunks := make([]arrow.Array, 0, len(chunked.Chunks()))
+	defer func() {
+		for _, c := range chunks {
+			c.Release()
+		}
+	}()
+	for _, c := range chunked.Chunks() {
+		parsed, err := s.parse(ctx, column, c)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, parsed)
+	}
+	return arrow.NewChunked(anomaly.TimestampType, chunks), nil
+}
+
+func (s timeSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	rec, err := s.source.ReadColumns(ctx, columns)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	return s.convert(ctx, rec)
+}
+
+// Chan streams the wrapped records with the time columns converted.
+func (s timeSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	in, inErrs := s.source.Chan(ctx)
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for rec := range in {
+			out, err := s.convert(ctx, rec)
+			rec.Release()
+			if err != nil {
+				errs <- err
+				// Drain so the producer can finish.
+				for rec := range in {
+					rec.Release()
+				}
+				return
+			}
+			select {
+			case recs <- out:
+			case <-ctx.Done():
+				out.Release()
+			}
+		}
+		if err := <-inErrs; err != nil {
+			errs <- err
+		}
+	}()
+	return recs, errs
+}
+
+// parse returns col, the column named column, read in its format if it has
+// one. The result is a new reference either way.
+func (s timeSource) parse(ctx context.Context, column string, col arrow.Array) (arrow.Array, error) {
+	format, ok := s.formats[column]
+	if !ok {
+		col.Retain()
+		return col, nil
+	}
+	out, err := anomaly.ParseTimes(ctx, col, format, s.loc)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", column, err)
+	}
+	return out, nil
+}
+
+// convert returns rec with its time columns parsed.
+func (s timeSource) convert(ctx context.Context, rec arrow.Record) (arrow.Record, error) {
+	fields := rec.Schema().Fields()
+	cols := make([]arrow.Array, len(fields))
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	for i, f := range fields {
+		col, err := s.parse(ctx, f.Name, rec.Column(i))
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = col
+		fields[i].Type = col.DataType()
+	}
+	md := rec.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &md), cols, rec.NumRows()), nil
+}
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/transform"
+)
+
+// transformSource replaces the analyzed columns of the wrapped source with
+// their --transform, computed over each whole column in input order, so
+// differences and trends span record batches. Chan reads the whole input
+// and sends it as one record.
+type transformSource struct {
+	source
+	fn      transform.Func
+	columns map[string]bool
+}
+
+// withTransform wraps src with the --transform of the analyzed columns:
+// --column, the --columns entries, or without either every numeric column
+// but --time-column. It runs after the column flags are resolved to names.
+func withTransform(src source) (source, error) {
+	spec := viper.GetString("transform")
+	if spec == "" {
+		return src, nil
+	}
+	fn, err := transform.Parse(spec)
+	if err != nil {
+		src.Close()
+		return nil, fmt.Errorf("--transform: %w", err)
+	}
+	columns := map[string]bool{}
+	if c := viper.GetString("column"); c != "" {
+		columns[c] = true
+	}
+	for _, entry := range viper.GetStringSlice("columns") {
+		name, _, _ := strings.Cut(entry, "=")
+		columns[name] = true
+	}
+	if len(columns) == 0 && src.Schema() != nil {
+		for _, c := range numericColumns(src.Schema()) {
+			if c != viper.GetString("time-column") {
+				columns[c] = true
+			}
+		}
+	}
+	return transformSource{source: src, fn: fn, columns: columns}, nil
+}
+
+func (s transformSource) Schema() *arrow.Schema {
+	schema := s.source.Schema()
+	if schema == nil {
+		return nil
+	}
+	fields := append([]arrow.Field(nil), schema.Fields()...)
+	for i, f := range fields {
+		if s.columns[f.Name] {
+			fields[i].Type, fields[i].Nullable = arrow.PrimitiveTypes.Float64, true
+		}
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+func (s transformSource) ReadSingleColumn(ctx context.Context, column string) (arrow.Array, error) {
+	col, err := s.source.ReadSingleColumn(ctx, column)
+	if err != nil || !s.columns[column] {
+		return col, err
+	}
+	defer col.Release()
+	out, err := s.fn(ctx, col)
+	if err != nil {
+		return nil, fmt.Errorf("transform %s: %w", column, err)
+	}
+	return out, nil
+}
+
+func (s transformSource) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	if !s.columns[column] {
+		return s.source.ReadChunked(ctx, column)
+	}
+	col, err := s.ReadSingleColumn(ctx, column)
+	if err != nil {
+		return nil, err
+	}
+	defer col.Release()
+	return arrow.NewChunked(col.DataType(), []arrow.Array{col}), nil
+}
+
+func (s transformSource) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	rec, err := s.source.ReadColumns(ctx, columns)
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	fns := map[string]transform.Func{}
+	for _, f := range rec.Schema().Fields() {
+		if s.columns[f.Name] {
+			fns[f.Name] = s.fn
+		}
+	}
+	if len(fns) == 0 {
+		rec.Retain()
+		return rec, nil
+	}
+	return transform.Apply(ctx, rec, fns)
+}
+
+// Chan sends the whole input, transformed, as a single record.
+func (s transformSource) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record, 1)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(recs)
+		rec, err := s.ReadColumns(ctx, nil)
+		if err != nil {
+			errs <- err
+			return
+		}
+		recs <- rec
+	}()
+	return recs, errs
+}
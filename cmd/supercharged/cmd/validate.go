@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/rules"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check a dataset against data-quality rules",
+	Long: `Check a dataset against data-quality rules.
+
+The --rules YAML file declares expectations per column: min and max,
+a regular expression pattern, unique, not_null, monotonic ordering and
+max_null_fraction, and with detect: true statistical anomalies as well. The
+input is streamed through every rule in one pass, and each broken rule is
+reported with its failing row count and the first failing rows. With
+--column, that column is also checked for anomalies at --threshold. The
+command exits with an error when any rule fails.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := viper.GetString("rules")
+		if path == "" {
+			return fmt.Errorf("--rules is required")
+		}
+		spec, err := rules.Load(path)
+		if err != nil {
+			return err
+		}
+		src, err := openConfigured()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if column := viper.GetString("column"); column != "" {
+			spec.Columns = append(spec.Columns, rules.Column{Name: column, Detect: true, Threshold: viper.GetFloat64("threshold")})
+		}
+		v, err := rules.NewValidator(spec)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(detectContext())
+		defer cancel()
+		recs, errs := src.Chan(ctx)
+		for rec := range recs {
+			err := v.Add(ctx, rec)
+			rec.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if err := <-errs; err != nil {
+			return fmt.Errorf("read input: %w", err)
+		}
+
+		failures := v.Failures()
+		if viper.GetBool("json") {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(struct {
+				Rows     int64           `json:"rows"`
+				Failures []rules.Failure `json:"failures"`
+			}{v.Rows(), failures}); err != nil {
+				return err
+			}
+		} else {
+			fmt.Printf("Rows: %d\n", v.Rows())
+			for _, f := range failures {
+				if f.Rule == rules.RuleMaxNullFraction {
+					fmt.Printf("FAIL %s %s: %s\n", f.Column, f.Rule, f.Message)
+					continue
+				}
+				fmt.Printf("FAIL %s %s: %d %s\n", f.Column, f.Rule, f.Count, f.Message)
+				for _, ex := range f.Examples {
+					if f.Rule == rules.RuleAnomaly {
+						fmt.Printf("  row %d: %s (score %.2f)\n", ex.Row, ex.Value, ex.Score)
+					} else {
+						fmt.Printf("  row %d: %s\n", ex.Row, ex.Value)
+					}
+				}
+			}
+			if len(failures) == 0 {
+				fmt.Println("All rules passed")
+			}
+		}
+		if len(failures) > 0 {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("%d rules failed", len(failures))
+		}
+		return nil
+	},
+}
+
+func init() {
+	validateCmd.Flags().String("rules", "", "YAML file of per-column data-quality rules")
+	viper.BindPFlag("rules", validateCmd.Flags().Lookup("rules"))
+	rootCmd.AddCommand(validateCmd)
+}
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/TFMV/supercharged/csvreader"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Tail a growing CSV file or a Kafka topic and report anomalies as rows arrive",
+	Long: `Tail a growing CSV file or a Kafka topic and report anomalies as rows arrive.
+
+The file is polled every --interval for complete new lines, which are scored
+against running statistics carried across batches (--method percentile keeps
+a t-digest, anything else running z-scores). Rows already in the file are
+scored first unless --from-end is set. A truncated or replaced file, as left
+by log rotation, is reopened from the start. Stop with Ctrl-C.
+
+With --kafka brokers/topic, JSON (or, with --kafka-format avro and
+--avro-schema, Avro) messages are consumed instead, in batches of up to 1024
+collected over at most --interval, and anomalies can be published as JSON to
+--sink-topic rather than printed.
+
+Anomalies are also sent to the --webhook, --slack-webhook, --pagerduty-key
+and --alert-file sinks, one notification per anomaly or, with --notify
+batch, per batch.
+With --metrics-addr, Prometheus metrics are served at /metrics.
+
+With --checkpoint, the running statistics and the position in the file or
+topic are saved every --checkpoint-interval and on exit, and a restarted
+watch resumes from them instead of reading the history again. A tailed file
+resumes after the last line checkpointed, unless it was rotated since; a
+topic resumes from the consumer group's committed offsets or, without a
+group, from the checkpointed offset of partition 0.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		column := viper.GetString("column")
+		if column == "" {
+			return fmt.Errorf("--column is required")
+		}
+		ctx, stop := signal.NotifyContext(detectContext(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if addr := viper.GetString("metrics-addr"); addr != "" {
+			go serveMetrics(addr)
+		}
+		if address := viper.GetString("kafka"); address != "" {
+			return watchKafka(ctx, address, column)
+		}
+
+		path := viper.GetString("file")
+		if path == "" || path == "-" {
+			return fmt.Errorf("--file must name a file to watch")
+		}
+		d, err := csvDialect(path)
+		if err != nil {
+			return err
+		}
+		if d.SkipBadRows {
+			d.OnBadRow = func(row csvreader.BadRow) {
+				fmt.Fprintf(os.Stderr, "Skipped bad row: %s\n", row.Reason)
+			}
+		}
+		cp, err := openCheckpoint(path, column)
+		if err != nil {
+			return err
+		}
+		t := &tailer{path: path, interval: viper.GetDuration("interval"), skip: viper.GetBool("from-end"), dialect: d}
+		if cp != nil {
+			t.resume = cp.cp.Offset
+		}
+		if err := t.open(); err != nil {
+			return err
+		}
+		recs, errs := t.Chan(ctx)
+		return detectLive(ctx, recs, errs, column, cp, liveReporter(ctx, printEvents))
+	},
+}
+
+// tailer follows a CSV file as it grows and decodes each poll's complete new
+// lines into records. The schema is inferred from the first lines seen, with
+// integer columns widened to float64 so later fractional values still parse.
+type tailer struct {
+	path     string
+	interval time.Duration
+	// skip starts reading at the end of the file instead of after the header.
+	skip bool
+	// resume starts reading at this offset, the end of a line read before,
+	// as saved by a checkpoint; it takes precedence over skip.
+	resume  int64
+	dialect csvreader.Dialect
+
+	file   *os.File
+	offset int64
+	header []byte
+	// partial holds a trailing line the writer has not finished yet.
+	partial []byte
+	// discard drops the line cut by seeking to the end of the file.
+	discard bool
+	schema  *arrow.Schema
+}
+
+// open opens the file from the start, from t.resume when it is set and the
+// file still ends a line there, or from its end when t.skip is set.
+func (t *tailer) open() error {
+	f, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("open input: %w", err)
+	}
+	if t.file != nil {
+		t.file.Close()
+	}
+	t.file, t.offset, t.header, t.partial, t.discard = f, 0, nil, nil, false
+	if t.dialect.NoHeader {
+		// Every line is data; an empty header marks it as already read.
+		t.header = []byte{}
+	}
+	resume := t.resume
+	if !t.skip && resume == 0 {
+		return nil
+	}
+	t.skip, t.resume = false, 0
+	header := t.header
+	if header == nil {
+		if header, err = bufio.NewReader(f).ReadBytes('\n'); err != nil {
+			// No complete header yet; read everything once it arrives.
+			_, err = f.Seek(0, io.SeekStart)
+			return err
+		}
+	}
+	end, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if resume > 0 {
+		last := make([]byte, 1)
+		if resume < int64(len(header)) || resume > end {
+			// Not the file the checkpoint was taken of; read it all.
+			_, err = f.Seek(0, io.SeekStart)
+			return err
+		}
+		if _, err := f.ReadAt(last, resume-1); err != nil || last[0] != '\n' {
+			_, err = f.Seek(0, io.SeekStart)
+			return err
+		}
+		t.header, t.offset = header, resume
+		_, err = f.Seek(resume, io.SeekStart)
+		return err
+	}
+	t.header, t.offset = header, end
+	if end > int64(len(header)) {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, end-1); err != nil {
+			return err
+		}
+		t.discard = last[0] != '\n'
+	}
+	return nil
+}
+
+// rotated reports whether the path now names a different or shorter file
+// than the one being read.
+func (t *tailer) rotated() bool {
+	fi, err := os.Stat(t.path)
+	if err != nil {
+		// Moved away and not yet replaced; keep reading the old file.
+		return false
+	}
+	cur, err := t.file.Stat()
+	return err != nil || !os.SameFile(fi, cur) || fi.Size() < t.offset
+}
+
+// pollBytes bounds how much of the file one poll reads, so that a large
+// backlog is decoded a few megabytes at a time rather than all at once.
+const pollBytes = 4 << 20
+
+// poll returns the complete lines appended since the last call, up to about
+// pollBytes of them, reopening the file first when it has been rotated.
+func (t *tailer) poll() ([]byte, error) {
+	if t.rotated() {
+		header := t.header
+		if err := t.open(); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if t.header != nil && !bytes.Equal(t.header, header) {
+				t.schema = nil
+			}
+		}()
+	}
+	buf := make([]byte, 64*1024)
+	for read := 0; ; {
+		n, err := t.file.Read(buf)
+		t.partial = append(t.partial, buf[:n]...)
+		t.offset += int64(n)
+		read += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read input: %w", err)
+		}
+		t.skipLeading()
+		// The rest is left for the next poll once a whole line is in.
+		if read >= pollBytes && t.header != nil && !t.discard && bytes.IndexByte(t.partial, '\n') >= 0 {
+			break
+		}
+	}
+	t.skipLeading()
+	if t.header == nil || t.discard {
+		return nil, nil
+	}
+	i := bytes.LastIndexByte(t.partial, '\n')
+	if i < 0 {
+		return nil, nil
+	}
+	lines := t.partial[:i+1]
+	t.partial = append([]byte(nil), t.partial[i+1:]...)
+	return lines, nil
+}
+
+// skipLeading takes the header, then the line cut by seeking, off the front
+// of partial as soon as each is complete.
+func (t *tailer) skipLeading() {
+	if t.header == nil {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			return
+		}
+		t.header = append([]byte(nil), t.partial[:i+1]...)
+		t.partial = t.partial[i+1:]
+	}
+	if t.discard {
+		i := bytes.IndexByte(t.partial, '\n')
+		if i < 0 {
+			return
+		}
+		t.partial, t.discard = t.partial[i+1:], false
+	}
+}
+
+// Chan polls the file until ctx is done and sends the new rows of each poll
+// as records; the caller must Release each.
+func (t *tailer) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer t.file.Close()
+		if err := t.run(ctx, recs); err != nil {
+			errs <- err
+		}
+		close(errs)
+	}()
+	return recs, errs
+}
+
+func (t *tailer) run(ctx context.Context, recs chan<- arrow.Record) error {
+	for {
+		lines, err := t.poll()
+		if err != nil {
+			return err
+		}
+		if len(lines) > 0 {
+			if err := t.decode(ctx, lines, t.offset-int64(len(t.partial)), recs); err != nil {
+				return err
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(t.interval):
+		}
+	}
+}
+
+// decode parses lines under the header and sends the resulting records, the
+// last marked with end, the file offset following lines.
+func (t *tailer) decode(ctx context.Context, lines []byte, end int64, recs chan<- arrow.Record) error {
+	data := append(append([]byte(nil), t.header...), lines...)
+	if t.schema == nil {
+		// Bad rows are reported once, when the lines are decoded below.
+		d := t.dialect
+		d.OnBadRow = nil
+		r, opts, err := d.Apply(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		schema, err := csvreader.InferSchemaFromCSV(r, opts...)
+		if err != nil {
+			return fmt.Errorf("infer schema: %w", err)
+		}
+		if _, err := overrideTypes(schema, t.dialect.Types); err != nil {
+			return err
+		}
+		t.schema = widenIntegers(schema, t.dialect.Types)
+	}
+	d := t.dialect
+	d.Schema = t.schema
+	r, opts, err := d.Apply(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	send := func(rec arrow.Record) {
+		select {
+		case recs <- rec:
+		case <-ctx.Done():
+			rec.Release()
+		}
+	}
+	// Hold each record back until the next arrives, to mark the last.
+	var last arrow.Record
+	for rec, err := range csvreader.NewCSVReader(r, t.schema, memory.DefaultAllocator, opts...).Records(ctx) {
+		if err != nil {
+			if last != nil {
+				last.Release()
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if last != nil {
+			send(last)
+		}
+		last = rec
+	}
+	if last != nil {
+		send(withMetadata(last, fileOffsetKey, strconv.FormatInt(end, 10)))
+	}
+	return nil
+}
+
+// widenIntegers returns schema with its integer fields replaced by float64,
+// except those whose type is forced by --schema.
+func widenIntegers(schema *arrow.Schema, forced map[string]arrow.DataType) *arrow.Schema {
+	fields := schema.Fields()
+	for i, f := range fields {
+		if _, ok := forced[f.Name]; !ok && arrow.IsInteger(f.Type.ID()) {
+			fields[i].Type = arrow.PrimitiveTypes.Float64
+		}
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+func init() {
+	watchCmd.Flags().Duration("interval", time.Second, "How often to poll the file for new rows")
+	watchCmd.Flags().Bool("from-end", false, "Skip the rows already in the file")
+	watchCmd.Flags().String("kafka", "", "Consume a Kafka topic instead of --file (broker1:9092,broker2:9092/topic)")
+	watchCmd.Flags().String("kafka-format", "json", "Kafka message encoding: json or avro")
+	watchCmd.Flags().String("kafka-group", "supercharged", "Kafka consumer group (empty reads partition 0 without committing)")
+	watchCmd.Flags().String("avro-schema", "", "Avro schema file (.avsc) for --kafka-format avro")
+	watchCmd.Flags().Bool("schema-registry", false, "Avro messages carry the Confluent schema-registry header")
+	watchCmd.Flags().String("sink-topic", "", "Publish anomalies as JSON to this topic on the --kafka brokers instead of printing")
+	watchCmd.Flags().String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address (e.g. :9090)")
+	watchCmd.Flags().String("checkpoint", "", "Save the detector state and stream position to this file, and resume from it when it exists")
+	watchCmd.Flags().Duration("checkpoint-interval", 30*time.Second, "How often to save the --checkpoint")
+	for _, name := range []string{"interval", "from-end", "metrics-addr", "kafka", "kafka-format", "kafka-group", "avro-schema", "schema-registry", "sink-topic", "checkpoint", "checkpoint-interval"} {
+		viper.BindPFlag(name, watchCmd.Flags().Lookup(name))
+	}
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,57 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// toFloat64 returns col as a Float64 array, casting signed and unsigned
+// integers, Float32 and Decimal128 columns with Arrow's cast kernel and
+// decoding dictionary-encoded ones, and applies the non-finite and null
+// policies carried by ctx. The result is always a new reference; the caller
+// must Release it.
+func toFloat64(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	out, err := castFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	if out, err = applyNonFinitePolicy(ctx, out); err != nil {
+		return nil, err
+	}
+	return applyNullPolicy(ctx, out)
+}
+
+// castFloat64 is toFloat64 without the null policy, for columns such as
+// timestamps that only order or group the data.
+func castFloat64(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	switch col.DataType().ID() {
+	case arrow.FLOAT64:
+		col.Retain()
+		return col.(*array.Float64), nil
+	case arrow.INT8, arrow.INT16, arrow.INT32, arrow.INT64,
+		arrow.UINT8, arrow.UINT16, arrow.UINT32, arrow.UINT64,
+		arrow.FLOAT32, arrow.DECIMAL128:
+		// Unsafe: large integers lose precision rather than failing the cast.
+		out, err := compute.CastArray(ctx, col, compute.UnsafeCastOptions(arrow.PrimitiveTypes.Float64))
+		if err != nil {
+			return nil, fmt.Errorf("cast %s to float64: %w", col.DataType(), err)
+		}
+		return out.(*array.Float64), nil
+	case arrow.DICTIONARY:
+		// Arrow only unpacks dictionaries cast to their own value type, so
+		// decode first and cast the values.
+		dict := col.(*array.Dictionary)
+		decoded, err := compute.TakeArray(ctx, dict.Dictionary(), dict.Indices())
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", col.DataType(), err)
+		}
+		defer decoded.Release()
+		return castFloat64(ctx, decoded)
+	default:
+		return nil, fmt.Errorf("unsupported array type %s", col.DataType())
+	}
+}
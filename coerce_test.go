@@ -0,0 +1,116 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/decimal128"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestDetectAnomaliesCoercesNumericTypes(t *testing.T) {
+	pool := memory.NewGoAllocator()
+
+	ib := array.NewInt32Builder(pool)
+	defer ib.Release()
+	ib.AppendValues([]int32{1, 2, 3, 100, 2}, nil)
+
+	ub := array.NewUint16Builder(pool)
+	defer ub.Release()
+	ub.AppendValues([]uint16{1, 2, 3, 100, 2}, nil)
+
+	fb := array.NewFloat32Builder(pool)
+	defer fb.Release()
+	fb.AppendValues([]float32{1, 2, 3, 100, 2}, nil)
+
+	db := array.NewDecimal128Builder(pool, &arrow.Decimal128Type{Precision: 10, Scale: 2})
+	defer db.Release()
+	for _, v := range []int64{100, 200, 300, 10000, 200} {
+		db.Append(decimal128.FromI64(v))
+	}
+
+	for _, col := range []arrow.Array{ib.NewArray(), ub.NewArray(), fb.NewArray(), db.NewArray()} {
+		res, err := DetectAnomalies(context.Background(), col, WithThreshold(1.99))
+		if err != nil {
+			t.Fatalf("%s: %v", col.DataType(), err)
+		}
+		if !res.Mask.Value(3) {
+			t.Errorf("%s: expected index 3 to be anomalous", col.DataType())
+		}
+		res.Release()
+		col.Release()
+	}
+}
+
+func TestDetectAnomaliesRejectsStrings(t *testing.T) {
+	sb := array.NewStringBuilder(memory.NewGoAllocator())
+	defer sb.Release()
+	sb.AppendValues([]string{"a", "b"}, nil)
+	col := sb.NewArray()
+	defer col.Release()
+
+	if _, err := DetectAnomalies(context.Background(), col); err == nil {
+		t.Errorf("expected error for string column")
+	}
+}
+
+func TestDetectAnomaliesDecodesDictionaries(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.PrimitiveTypes.Int32}
+	bld := array.NewDictionaryBuilder(pool, dt).(*array.Int32DictionaryBuilder)
+	defer bld.Release()
+	for _, v := range []int32{1, 2, 3, 100, 2, 1} {
+		if err := bld.Append(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bld.AppendNull()
+	col := bld.NewArray()
+	defer col.Release()
+
+	res, err := DetectAnomalies(compute.WithAllocator(context.Background(), pool), col, WithThreshold(1.99))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if res.Indices.Len() != 1 || res.Indices.Value(0) != 3 || res.Values.Value(0) != 100 {
+		t.Errorf("flagged %v, want row 3 with value 100", res.Indices)
+	}
+	if res.Nulls != 1 {
+		t.Errorf("got %d nulls, want 1", res.Nulls)
+	}
+}
+
+func TestExtractFlaggedDictionary(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.PrimitiveTypes.Int32}
+	bld := array.NewDictionaryBuilder(pool, dt).(*array.Int32DictionaryBuilder)
+	defer bld.Release()
+	for _, v := range []int32{1, 2, 100, 2} {
+		if err := bld.Append(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	col := bld.NewArray()
+	defer col.Release()
+
+	mb := array.NewBooleanBuilder(pool)
+	defer mb.Release()
+	mb.AppendValues([]bool{false, false, true, false}, nil)
+	res := &Result{Mask: mb.NewBooleanArray()}
+	defer res.Release()
+	if err := res.extractFlagged(ctx, col); err != nil {
+		t.Fatal(err)
+	}
+	if res.Indices.Len() != 1 || res.Indices.Value(0) != 2 || res.Values.Value(0) != 100 {
+		t.Errorf("indices = %v, values = %v, want row 2 with value 100", res.Indices, res.Values)
+	}
+}
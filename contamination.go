@@ -0,0 +1,130 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// ContaminationDetector flags the Fraction of values with the largest
+// absolute scores under Base, ignoring Base's own threshold. It suits data
+// whose expected anomaly rate is known when a good threshold is not. At
+// least one value is flagged when any is scored; ties at the cut-off go to
+// the earlier rows. Nulls are still resolved by the null policy.
+type ContaminationDetector struct {
+	Base     Detector
+	Fraction float64
+}
+
+var _ Detector = ContaminationDetector{}
+
+// Detect implements Detector.
+func (d ContaminationDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if d.Fraction <= 0 || d.Fraction >= 1 {
+		return nil, fmt.Errorf("contamination must be in (0, 1), got %v", d.Fraction)
+	}
+	base, err := d.Base.Detect(withScored(ctx), col)
+	if err != nil {
+		return nil, err
+	}
+	defer base.Release()
+
+	var scored []int
+	for i := 0; i < base.Zscore.Len(); i++ {
+		if base.Zscore.IsValid(i) && !math.IsNaN(base.Zscore.Value(i)) {
+			scored = append(scored, i)
+		}
+	}
+	sort.SliceStable(scored, func(a, b int) bool {
+		return math.Abs(base.Zscore.Value(scored[a])) > math.Abs(base.Zscore.Value(scored[b]))
+	})
+	k := int(math.Ceil(d.Fraction * float64(len(scored))))
+	top := make([]bool, base.Zscore.Len())
+	for _, i := range scored[:k] {
+		top[i] = true
+	}
+
+	return reflag(ctx, col, base, top)
+}
+
+// DetectColumns runs d over the named columns of rec jointly, for a Base
+// that scores several columns at once, such as MahalanobisDetector.
+func (d ContaminationDetector) DetectColumns(ctx context.Context, rec arrow.Record, columns []string) (*Result, error) {
+	if _, ok := d.Base.(interface {
+		DetectColumns(context.Context, arrow.Record, []string) (*Result, error)
+	}); !ok {
+		return nil, fmt.Errorf("%T does not score columns jointly", d.Base)
+	}
+	st, err := structOfColumns(rec, columns)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Release()
+	return d.Detect(ctx, st)
+}
+
+// reflag rebuilds base, the result of scoring col, with flag as the mask,
+// keeping its scores, p-values, statistics and counts. The mask is built
+// from base alone, so any Base works whatever col's type: rows base left
+// unscored keep base's resolution of the null policy. Values are taken from
+// the column base scored when it was run under withScored, and from col
+// otherwise.
+func reflag(ctx context.Context, col arrow.Array, base *Result, flag []bool) (*Result, error) {
+	mb := array.NewBooleanBuilder(compute.GetAllocator(ctx))
+	defer mb.Release()
+	mb.Reserve(len(flag))
+	for i, f := range flag {
+		switch {
+		case base.Zscore.IsValid(i):
+			mb.Append(f)
+		case base.Mask.IsValid(i):
+			mb.Append(base.Mask.Value(i))
+		default:
+			mb.Append(false)
+		}
+	}
+
+	base.Zscore.Retain()
+	res := &Result{
+		Mask: mb.NewBooleanArray(), Zscore: base.Zscore,
+		Mean: base.Mean, StdDev: base.StdDev,
+		Nulls: base.Nulls, NaNs: base.NaNs, Infs: base.Infs, Inexact: base.Inexact,
+	}
+	if base.PValues != nil {
+		base.PValues.Retain()
+		res.PValues = base.PValues
+	}
+	values := col
+	if base.scored != nil {
+		values = base.scored
+	}
+	if err := res.extractFlagged(ctx, values); err != nil {
+		res.Release()
+		return nil, err
+	}
+	return res, nil
+}
+
+type scoredKey struct{}
+
+// withScored returns a copy of ctx under which newResult keeps the column
+// it was given on the Result, for reflag to take Values from when col is
+// not numeric, as for CategoryDetector and StringDetector.
+func withScored(ctx context.Context) context.Context {
+	return context.WithValue(ctx, scoredKey{}, true)
+}
+
+// Ranked returns the flagged row positions ordered by descending absolute
+// score, most anomalous first, for any detector.
+func (r *Result) Ranked() []int64 {
+	idx := r.AnomalousIndices()
+	sort.SliceStable(idx, func(a, b int) bool {
+		return math.Abs(r.Zscore.Value(int(idx[a]))) > math.Abs(r.Zscore.Value(int(idx[b])))
+	})
+	return idx
+}
@@ -0,0 +1,110 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestContaminationDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	// 20 values: two mild outliers that a threshold of 3 would miss.
+	vals := []float64{10, 11, 10, 9, 10, 11, 10, 9, 10, 11, 10, 9, 10, 11, 10, 9, 10, 11, 14, 5}
+	b.AppendValues(vals, nil)
+	arr := b.NewFloat64Array()
+	defer arr.Release()
+
+	ctx := context.Background()
+	res, err := DetectAnomalies(ctx, arr, WithMethod(MethodMAD), WithContamination(0.1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	// 10% of 20 is 2 rows; row 19 (5) deviates more than row 18 (14).
+	if got := res.Ranked(); len(got) != 2 || got[0] != 19 || got[1] != 18 {
+		t.Errorf("Ranked() = %v, want [19 18]", got)
+	}
+	if res.Indices.Len() != 2 || res.Values.Value(0) != 14 || res.Values.Value(1) != 5 {
+		t.Errorf("indices = %v, values = %v", res.Indices, res.Values)
+	}
+
+	if _, err := NewDetector(WithContamination(1.5)); err == nil {
+		t.Errorf("expected error for contamination >= 1")
+	}
+}
+
+func TestContaminationNonNumeric(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewStringBuilder(pool)
+	defer b.Release()
+	for i := 0; i < 40; i++ {
+		b.Append("a")
+	}
+	b.Append("z")
+	b.AppendNull()
+	for i := 0; i < 40; i++ {
+		b.Append("b")
+	}
+	arr := b.NewStringArray()
+	defer arr.Release()
+
+	// The rare category is the top 1%, and the null row stays flagged by
+	// the null policy.
+	ctx := compute.WithAllocator(context.Background(), pool)
+	res, err := DetectAnomalies(ctx, arr, WithMethod(MethodCategory), WithContamination(0.01),
+		WithNullPolicy(NullPolicyFlagAsAnomaly), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if got := res.AnomalousIndices(); len(got) != 2 || got[0] != 40 || got[1] != 41 {
+		t.Errorf("indices = %v, want [40 41]", got)
+	}
+	if res.Values.Value(0) != 1 || res.Nulls != 1 {
+		t.Errorf("values = %v, nulls = %d", res.Values, res.Nulls)
+	}
+}
+
+func TestContaminationDetectColumns(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "height", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "weight", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	// As in TestMahalanobisDetector, only the last row is off the trend.
+	b.Field(0).(*array.Float64Builder).AppendValues([]float64{150, 155, 160, 165, 170, 175, 180, 185, 190, 152, 188, 150}, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{50, 55, 60, 65, 70, 75, 80, 85, 90, 52, 88, 90}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	d := ContaminationDetector{Base: MahalanobisDetector{}, Fraction: 0.05}
+	res, err := d.DetectColumns(ctx, rec, []string{"height", "weight"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if got := res.AnomalousIndices(); len(got) != 1 || got[0] != 11 {
+		t.Errorf("indices = %v, want [11]", got)
+	}
+
+	d.Base = ZScoreDetector{}
+	if _, err := d.DetectColumns(ctx, rec, []string{"height", "weight"}); err == nil {
+		t.Error("expected error for a base that scores columns one by one")
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"iter"
 
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/TFMV/supercharged/internal/colspec"
+	"github.com/TFMV/supercharged/internal/decompress"
 	"github.com/apache/arrow-go/v18/arrow"
-	"github.com/apache/arrow-go/v18/arrow/array"
 	"github.com/apache/arrow-go/v18/arrow/csv"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 )
@@ -17,31 +20,169 @@ type CSVReader struct {
 	allocator memory.Allocator
 	schema    *arrow.Schema
 	reader    *csv.Reader
+	// pending holds the record consumed during schema inference, if any.
+	pending arrow.Record
+
+	counter  *countingReader
+	progress func(Progress)
+	rows     int64
 }
 
-// NewCSVReader creates a streaming CSVReader with provided schema.
-func NewCSVReader(r io.Reader, schema *arrow.Schema, opts ...csv.Option) *CSVReader {
-	allocator := memory.NewGoAllocator()
-	defaultOpts := []csv.Option{
+// Progress reports how far a CSVReader has read.
+type Progress struct {
+	// Rows is the number of rows decoded so far.
+	Rows int64
+	// Bytes is the number of bytes read from the reader the CSVReader was
+	// created with, before decompression.
+	Bytes int64
+}
+
+// SetProgress registers fn to be called after each record batch is decoded,
+// from the goroutine reading the input. It must be called before reading
+// starts; ReadSingleColumn and ReadColumns report through it too.
+func (cr *CSVReader) SetProgress(fn func(Progress)) {
+	cr.progress = fn
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// advance counts the rows of rec and reports progress.
+func (cr *CSVReader) advance(rec arrow.Record) {
+	cr.rows += rec.NumRows()
+	if cr.progress != nil {
+		cr.progress(Progress{Rows: cr.rows, Bytes: cr.counter.n})
+	}
+}
+
+// orDefault returns mem, or a new Go allocator when mem is nil.
+func orDefault(mem memory.Allocator) memory.Allocator {
+	if mem == nil {
+		return memory.NewGoAllocator()
+	}
+	return mem
+}
+
+// DefaultNullValues are the fields read as null unless the caller passes its
+// own csv.WithNullReader option or Dialect.Nulls.
+var DefaultNullValues = []string{"NULL", "null", "", "N/A", "n/a"}
+
+// defaultOptions are applied before caller-supplied options.
+func defaultOptions(allocator memory.Allocator) []csv.Option {
+	return []csv.Option{
 		csv.WithAllocator(allocator),
 		csv.WithHeader(true),
-		csv.WithNullReader(true, "NULL", "null", "", "N/A", "n/a"),
+		csv.WithNullReader(true, DefaultNullValues...),
 		csv.WithChunk(1024),
 	}
-	allOpts := append(defaultOpts, opts...)
+}
+
+// NewCSVReader creates a streaming CSVReader with provided schema. Records and
+// concatenated columns are allocated from mem; nil means a new Go allocator.
+// Gzip and zstd input is decompressed, and non-UTF-8 input transcoded to
+// UTF-8, automatically.
+func NewCSVReader(r io.Reader, schema *arrow.Schema, mem memory.Allocator, opts ...csv.Option) *CSVReader {
+	counter := &countingReader{r: r}
+	r = NewDecodingReader(decompress.NewReader(counter))
+	allocator := orDefault(mem)
+	allOpts := append(defaultOptions(allocator), opts...)
 	reader := csv.NewReader(r, schema, allOpts...)
-	return &CSVReader{allocator: allocator, schema: schema, reader: reader}
+	return &CSVReader{allocator: allocator, schema: schema, reader: reader, counter: counter}
 }
 
-// Chan returns a channel of records; caller must Release each.
-func (cr *CSVReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
-	recs := make(chan arrow.Record)
+// NewInferringCSVReader infers the schema from the first chunk of r and
+// returns a CSVReader that continues streaming from there, so the input is
+// read exactly once and never needs to be rewound. This works for pipes and
+// network streams where InferSchemaFromCSV followed by a seek cannot.
+func NewInferringCSVReader(r io.Reader, mem memory.Allocator, opts ...csv.Option) (*CSVReader, error) {
+	counter := &countingReader{r: r}
+	r = NewDecodingReader(decompress.NewReader(counter))
+	allocator := orDefault(mem)
+	allOpts := append(defaultOptions(allocator), opts...)
+	reader := csv.NewInferringReader(r, allOpts...)
+	if !inferNext(reader) {
+		defer reader.Release()
+		if err := reader.Err(); err != nil {
+			return nil, fmt.Errorf("error inferring schema: %w", err)
+		}
+		return nil, fmt.Errorf("no data found for schema inference")
+	}
+	first := reader.Record()
+	first.Retain()
+	return &CSVReader{allocator: allocator, schema: reader.Schema(), reader: reader, pending: first, counter: counter}, nil
+}
+
+// inferNext reads the first record of an inferring reader. Arrow's reader
+// panics when the input ends after the header, before any type is inferred;
+// that is reported as no record.
+func inferNext(reader *csv.Reader) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return reader.Next()
+}
+
+// Schema returns the schema records are decoded with.
+func (cr *CSVReader) Schema() *arrow.Schema {
+	return cr.schema
+}
+
+// ChanOption configures Chan.
+type ChanOption func(*chanConfig)
+
+type chanConfig struct {
+	buffer int
+}
+
+// WithChannelBuffer gives the channel Chan returns room for n records, so
+// decoding runs up to n batches ahead of a slow consumer, each holding its
+// memory until received. The default, zero, hands each record over only
+// when the consumer takes it.
+func WithChannelBuffer(n int) ChanOption {
+	return func(c *chanConfig) {
+		c.buffer = max(n, 0)
+	}
+}
+
+// Chan returns a channel of records; caller must Release each, including
+// records drained after cancelling ctx. The error channel receives at most
+// one read error and is closed along with the records channel, whether the
+// input ended or ctx was done.
+func (cr *CSVReader) Chan(ctx context.Context, opts ...ChanOption) (<-chan arrow.Record, <-chan error) {
+	var cfg chanConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	recs := make(chan arrow.Record, cfg.buffer)
 	errs := make(chan error, 1)
 	go func() {
 		defer close(recs)
+		defer close(errs)
+		if rec := cr.pending; rec != nil {
+			cr.pending = nil
+			cr.advance(rec)
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
 		for cr.reader.Next() {
 			rec := cr.reader.Record()
 			rec.Retain()
+			cr.advance(rec)
 			select {
 			case recs <- rec:
 			case <-ctx.Done():
@@ -52,66 +193,96 @@ func (cr *CSVReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan erro
 		if err := cr.reader.Err(); err != nil {
 			errs <- fmt.Errorf("csv read error: %w", err)
 		}
-		close(errs)
 	}()
 	return recs, errs
 }
 
-// ReadSingleColumn concatenates all chunks for a named column.
-func (cr *CSVReader) ReadSingleColumn(r io.Reader, columnName string, opts ...csv.Option) (arrow.Array, error) {
-	// rewind reader externally before calling
-	reader := NewCSVReader(r, cr.schema, opts...)
-	ctx := context.Background()
-	recs, errs := reader.Chan(ctx)
-	var chunks []arrow.Array
-	for rec := range recs {
-		idx := rec.Schema().FieldIndices(columnName)
-		if len(idx) == 0 {
-			rec.Release()
-			return nil, fmt.Errorf("column %s not found", columnName)
+// Records returns an iterator over the records, an alternative to Chan that
+// needs no goroutine and reports the error in line:
+//
+//	for rec, err := range cr.Records(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//		rec.Release()
+//	}
+//
+// The caller must Release each record. Iteration stops after an error, which
+// is yielded with a nil record, including ctx's error once it is done.
+// Breaking out of the loop stops reading.
+func (cr *CSVReader) Records(ctx context.Context) iter.Seq2[arrow.Record, error] {
+	return func(yield func(arrow.Record, error) bool) {
+		if rec := cr.pending; rec != nil {
+			cr.pending = nil
+			cr.advance(rec)
+			if !yield(rec, nil) {
+				return
+			}
+		}
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !cr.reader.Next() {
+				break
+			}
+			rec := cr.reader.Record()
+			rec.Retain()
+			cr.advance(rec)
+			if !yield(rec, nil) {
+				return
+			}
 		}
-		col := rec.Column(idx[0])
-		col.Retain()
-		chunks = append(chunks, col)
-		rec.Release()
-	}
-	if err := <-errs; err != nil {
-		for _, c := range chunks {
-			c.Release()
+		if err := cr.reader.Err(); err != nil {
+			yield(nil, fmt.Errorf("csv read error: %w", err))
 		}
-		return nil, err
-	}
-	if len(chunks) == 0 {
-		return nil, fmt.Errorf("no data for column %s", columnName)
 	}
-	// concatenate
-	concat, err := array.Concatenate(chunks, memory.DefaultAllocator)
-	for _, c := range chunks {
-		c.Release()
+}
+
+// ReadSingleColumn concatenates all chunks for a named column. The column
+// may also be given by 0-based index, or by a glob or /regexp/ matching only
+// it.
+func (cr *CSVReader) ReadSingleColumn(ctx context.Context, r io.Reader, columnName string, opts ...csv.Option) (arrow.Array, error) {
+	// rewind reader externally before calling
+	columnName, err := colspec.ResolveOne(cr.schema, columnName)
+	if err != nil {
+		return nil, err
 	}
+	reader := NewCSVReader(r, cr.schema, cr.allocator, opts...)
+	reader.progress = cr.progress
+	recs, errs := reader.Chan(ctx)
+	rec, err := collect.Columns(ctx, recs, errs, []string{columnName}, cr.allocator)
 	if err != nil {
 		return nil, err
 	}
-	return concat, nil
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
 }
 
 // InferSchemaFromCSV attempts to infer the schema from the first few rows of CSV
+// input. Arrow infers each column's type from its first value; prepare r with
+// a Dialect whose InferenceRows is set to look further.
 func InferSchemaFromCSV(r io.Reader, opts ...csv.Option) (*arrow.Schema, error) {
 	// Use Arrow's built-in schema inference
 	defaultOpts := []csv.Option{
 		csv.WithAllocator(memory.NewGoAllocator()),
 		csv.WithHeader(true),
-		csv.WithNullReader(true, "NULL", "null", "", "N/A", "n/a"),
+		csv.WithNullReader(true, DefaultNullValues...),
 	}
 
 	allOpts := append(defaultOpts, opts...)
 
 	// Create an inferring reader
+	r = NewDecodingReader(decompress.NewReader(r))
 	inferringReader := csv.NewInferringReader(r, allOpts...)
 	defer inferringReader.Release()
 
 	// Read one record to trigger schema inference
-	if !inferringReader.Next() {
+	if !inferNext(inferringReader) {
 		if err := inferringReader.Err(); err != nil {
 			return nil, fmt.Errorf("error inferring schema: %w", err)
 		}
@@ -120,3 +291,56 @@ func InferSchemaFromCSV(r io.Reader, opts ...csv.Option) (*arrow.Schema, error)
 
 	return inferringReader.Schema(), nil
 }
+
+// ReadRecord drains cr's own stream and returns a single record holding the
+// named columns, each concatenated across chunks. Columns may also be given
+// by 0-based index, or by globs or /regexp/ patterns that expand to every
+// column they match. A nil columns slice keeps every column of the schema.
+// Unlike ReadColumns it does not re-read the input, so it suits readers from
+// NewInferringCSVReader.
+func (cr *CSVReader) ReadRecord(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range cr.schema.Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	columns, err := colspec.ResolveAll(cr.schema, columns)
+	if err != nil {
+		return nil, err
+	}
+	recs, errs := cr.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, cr.allocator)
+}
+
+// ReadChunked drains the reader's own stream and returns the named column
+// with one chunk per batch, avoiding the copy ReadRecord makes to
+// concatenate them.
+func (cr *CSVReader) ReadChunked(ctx context.Context, column string) (*arrow.Chunked, error) {
+	column, err := colspec.ResolveOne(cr.schema, column)
+	if err != nil {
+		return nil, err
+	}
+	recs, errs := cr.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, column)
+}
+
+// ReadColumns reads the whole input and returns a single record holding the
+// named columns, each concatenated across chunks. Columns are selected as by
+// ReadRecord. A nil columns slice keeps every column of the schema.
+func (cr *CSVReader) ReadColumns(ctx context.Context, r io.Reader, columns []string, opts ...csv.Option) (arrow.Record, error) {
+	// rewind reader externally before calling
+	if columns == nil {
+		for _, f := range cr.schema.Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	columns, err := colspec.ResolveAll(cr.schema, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := NewCSVReader(r, cr.schema, cr.allocator, opts...)
+	reader.progress = cr.progress
+	recs, errs := reader.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, cr.allocator)
+}
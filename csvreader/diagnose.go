@@ -0,0 +1,284 @@
+package csvreader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/TFMV/supercharged/internal/decompress"
+)
+
+// DefaultSniffBytes is the number of bytes Diagnose inspects when no limit is given.
+const DefaultSniffBytes = 1 << 20
+
+// candidateDelimiters are the separators Diagnose considers, in order of preference.
+var candidateDelimiters = []rune{',', '\t', ';', '|'}
+
+// Diagnosis describes the layout of a delimited text input.
+type Diagnosis struct {
+	Compression  string         `json:"compression,omitempty"`
+	Encoding     string         `json:"encoding"`
+	Delimiter    string         `json:"delimiter"`
+	Quoting      string         `json:"quoting"`
+	HasHeader    bool           `json:"has_header"`
+	LineEndings  map[string]int `json:"line_endings"`
+	Fields       int            `json:"fields"`
+	RowsSampled  int            `json:"rows_sampled"`
+	RaggedRows   []int          `json:"ragged_rows,omitempty"`
+	BadQuoteRows []int          `json:"bad_quote_rows,omitempty"`
+	Suggestions  []string       `json:"suggestions,omitempty"`
+}
+
+// Diagnose sniffs up to limit bytes of r, after any gzip or zstd
+// decompression (DefaultSniffBytes if limit <= 0), and reports the
+// delimiter, encoding, quoting style, header presence, line-ending mix and
+// ragged rows, along with the reader options likely needed to parse the
+// input.
+func Diagnose(r io.Reader, limit int) (*Diagnosis, error) {
+	if limit <= 0 {
+		limit = DefaultSniffBytes
+	}
+	dr := decompress.NewReader(r)
+	buf, err := io.ReadAll(io.LimitReader(dr, int64(limit)+1))
+	if err != nil {
+		return nil, fmt.Errorf("read sample: %w", err)
+	}
+	truncated := len(buf) > limit
+	if truncated {
+		buf = buf[:limit]
+	}
+	if len(buf) == 0 {
+		return nil, fmt.Errorf("no data found for diagnosis")
+	}
+
+	d := &Diagnosis{Compression: dr.Compression(), Encoding: DetectEncoding(buf)}
+	buf = decodeBytes(buf, d.Encoding)
+	d.LineEndings = countLineEndings(buf)
+
+	// Drop a partial trailing line so it is not reported as ragged.
+	if truncated {
+		if i := bytes.LastIndexAny(buf, "\r\n"); i > 0 {
+			buf = buf[:i]
+		}
+	}
+
+	delim, rows := sniffDelimiter(buf)
+	d.Delimiter = string(delim)
+	d.RowsSampled = len(rows)
+	d.Fields = modeFieldCount(rows)
+	for _, row := range rows {
+		if len(row.fields) != d.Fields {
+			d.RaggedRows = append(d.RaggedRows, row.line)
+		}
+		if row.badQuote {
+			d.BadQuoteRows = append(d.BadQuoteRows, row.line)
+		}
+	}
+	d.Quoting = sniffQuoting(buf, delim)
+	d.HasHeader = sniffHeader(rows)
+	d.Suggestions = d.suggest()
+	return d, nil
+}
+
+func countLineEndings(buf []byte) map[string]int {
+	counts := map[string]int{"lf": 0, "crlf": 0, "cr": 0}
+	for i := 0; i < len(buf); i++ {
+		switch buf[i] {
+		case '\r':
+			if i+1 < len(buf) && buf[i+1] == '\n' {
+				counts["crlf"]++
+				i++
+			} else {
+				counts["cr"]++
+			}
+		case '\n':
+			counts["lf"]++
+		}
+	}
+	return counts
+}
+
+type sampledRow struct {
+	line     int
+	fields   []string
+	badQuote bool
+}
+
+// parseRows splits buf into rows using delim, tolerating bad quotes and
+// ragged rows so that they can be reported rather than aborting.
+func parseRows(buf []byte, delim rune) []sampledRow {
+	bad := badQuoteLines(buf, delim)
+
+	lazy := csv.NewReader(bytes.NewReader(buf))
+	lazy.Comma = delim
+	lazy.FieldsPerRecord = -1
+	lazy.LazyQuotes = true
+
+	var rows []sampledRow
+	for {
+		fields, err := lazy.Read()
+		if err != nil {
+			break
+		}
+		line, _ := lazy.FieldPos(0)
+		rows = append(rows, sampledRow{line: line, fields: fields, badQuote: bad[line]})
+	}
+	return rows
+}
+
+// badQuoteLines returns the starting lines of records that fail strict
+// RFC 4180 quote parsing.
+func badQuoteLines(buf []byte, delim rune) map[int]bool {
+	strict := csv.NewReader(bytes.NewReader(buf))
+	strict.Comma = delim
+	strict.FieldsPerRecord = -1
+
+	bad := make(map[int]bool)
+	for {
+		_, err := strict.Read()
+		if err == nil {
+			continue
+		}
+		perr, ok := err.(*csv.ParseError)
+		if !ok {
+			break
+		}
+		bad[perr.StartLine] = true
+	}
+	return bad
+}
+
+// sniffDelimiter picks the candidate delimiter producing the most consistent
+// field count greater than one.
+func sniffDelimiter(buf []byte) (rune, []sampledRow) {
+	best := candidateDelimiters[0]
+	var bestRows []sampledRow
+	bestScore := -1.0
+	for _, c := range candidateDelimiters {
+		rows := parseRows(buf, c)
+		if len(rows) == 0 {
+			continue
+		}
+		mode := modeFieldCount(rows)
+		if mode < 2 {
+			continue
+		}
+		consistent := 0
+		for _, row := range rows {
+			if len(row.fields) == mode {
+				consistent++
+			}
+		}
+		score := float64(consistent) / float64(len(rows))
+		if score > bestScore {
+			best, bestRows, bestScore = c, rows, score
+		}
+	}
+	if bestRows == nil {
+		bestRows = parseRows(buf, best)
+	}
+	return best, bestRows
+}
+
+func modeFieldCount(rows []sampledRow) int {
+	counts := make(map[int]int)
+	mode, modeCount := 0, 0
+	for _, row := range rows {
+		n := len(row.fields)
+		counts[n]++
+		if counts[n] > modeCount || (counts[n] == modeCount && n > mode) {
+			mode, modeCount = n, counts[n]
+		}
+	}
+	return mode
+}
+
+// sniffQuoting classifies the quoting style as "none", "minimal" or "all".
+func sniffQuoting(buf []byte, delim rune) string {
+	var quoted, total int
+	for _, line := range strings.FieldsFunc(string(buf), func(r rune) bool { return r == '\n' || r == '\r' }) {
+		for _, field := range strings.Split(line, string(delim)) {
+			if field == "" {
+				continue
+			}
+			total++
+			if strings.HasPrefix(strings.TrimSpace(field), `"`) {
+				quoted++
+			}
+		}
+	}
+	switch {
+	case quoted == 0:
+		return "none"
+	case quoted == total:
+		return "all"
+	default:
+		return "minimal"
+	}
+}
+
+// sniffHeader reports whether the first row looks like a header: it holds no
+// numeric fields while at least one later row has a number in the same column.
+func sniffHeader(rows []sampledRow) bool {
+	if len(rows) < 2 {
+		return len(rows) == 1 && !anyNumeric(rows[0].fields)
+	}
+	first := rows[0].fields
+	if anyNumeric(first) {
+		return false
+	}
+	for _, row := range rows[1:] {
+		for i, f := range row.fields {
+			if i < len(first) && isNumeric(f) {
+				return true
+			}
+		}
+	}
+	// All-text data: assume a header when the first row has no duplicates.
+	seen := make(map[string]bool, len(first))
+	for _, f := range first {
+		if seen[f] {
+			return false
+		}
+		seen[f] = true
+	}
+	return true
+}
+
+func anyNumeric(fields []string) bool {
+	for _, f := range fields {
+		if isNumeric(f) {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumeric(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil
+}
+
+// suggest lists the reader options needed to parse the diagnosed input.
+func (d *Diagnosis) suggest() []string {
+	var out []string
+	if d.Delimiter != "," {
+		out = append(out, fmt.Sprintf("csv.WithComma(%q)", []rune(d.Delimiter)[0]))
+	}
+	if !d.HasHeader {
+		out = append(out, "csv.WithHeader(false)")
+	}
+	if len(d.BadQuoteRows) > 0 {
+		out = append(out, "csv.WithLazyQuotes(true)")
+	}
+	if len(d.RaggedRows) > 0 {
+		out = append(out, fmt.Sprintf("fix or drop %d ragged row(s); expected %d fields", len(d.RaggedRows), d.Fields))
+	}
+	if d.LineEndings["cr"] > 0 {
+		out = append(out, "convert bare CR line endings to LF")
+	}
+	return out
+}
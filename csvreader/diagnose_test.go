@@ -0,0 +1,151 @@
+package csvreader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		delimiter   string
+		quoting     string
+		header      bool
+		fields      int
+		ragged      []int
+		badQuotes   []int
+		lineEndings map[string]int
+		suggest     []string
+	}{
+		{
+			name: "comma", in: "a,b\n1,2\n3,4\n",
+			delimiter: ",", quoting: "none", header: true, fields: 2,
+			lineEndings: map[string]int{"lf": 3, "crlf": 0, "cr": 0},
+		},
+		{
+			name: "tab", in: "a\tb\tc\n1\t2\t3\n",
+			delimiter: "\t", quoting: "none", header: true, fields: 3,
+			suggest: []string{`csv.WithComma('\t')`},
+		},
+		{
+			name: "semicolon with decimal commas", in: "a;b\n1,5;2\n3,5;4\n",
+			delimiter: ";", quoting: "none", header: true, fields: 2,
+			suggest: []string{"csv.WithComma(';')"},
+		},
+		{
+			name: "pipe", in: "a|b\nx|1\ny|2\n",
+			delimiter: "|", quoting: "none", header: true, fields: 2,
+			suggest: []string{"csv.WithComma('|')"},
+		},
+		{
+			name: "minimal quoting", in: "name,v\n\"x, y\",1\nz,2\n",
+			delimiter: ",", quoting: "minimal", header: true, fields: 2,
+		},
+		{
+			name: "all quoted", in: "\"a\",\"b\"\n\"1\",\"2\"\n",
+			delimiter: ",", quoting: "all", header: true, fields: 2,
+		},
+		{
+			name: "bad quote", in: "a,b\n1,x\"y\n2,3\n",
+			delimiter: ",", quoting: "none", header: true, fields: 2, badQuotes: []int{2},
+			suggest: []string{"csv.WithLazyQuotes(true)"},
+		},
+		{
+			name: "ragged rows", in: "a,b,c\n1,2,3\n4,5\n6,7,8\n9\n",
+			delimiter: ",", quoting: "none", header: true, fields: 3, ragged: []int{3, 5},
+			suggest: []string{"fix or drop 2 ragged row(s); expected 3 fields"},
+		},
+		{
+			name: "mixed line endings", in: "a,b\r\n1,2\n3,4\r\n",
+			delimiter: ",", quoting: "none", header: true, fields: 2,
+			lineEndings: map[string]int{"lf": 1, "crlf": 2, "cr": 0},
+		},
+		{
+			name: "no header", in: "1,2\n3,4\n",
+			delimiter: ",", quoting: "none", header: false, fields: 2,
+			suggest: []string{"csv.WithHeader(false)"},
+		},
+		{
+			name: "text only with repeated first row values", in: "x,x\ny,z\n",
+			delimiter: ",", quoting: "none", header: false, fields: 2,
+			suggest: []string{"csv.WithHeader(false)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := Diagnose(strings.NewReader(tt.in), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Delimiter != tt.delimiter || d.Quoting != tt.quoting || d.HasHeader != tt.header || d.Fields != tt.fields {
+				t.Errorf("got delimiter %q, quoting %s, header %t, fields %d; want %q, %s, %t, %d",
+					d.Delimiter, d.Quoting, d.HasHeader, d.Fields, tt.delimiter, tt.quoting, tt.header, tt.fields)
+			}
+			if !slices.Equal(d.RaggedRows, tt.ragged) || !slices.Equal(d.BadQuoteRows, tt.badQuotes) {
+				t.Errorf("ragged %v, bad quotes %v; want %v, %v", d.RaggedRows, d.BadQuoteRows, tt.ragged, tt.badQuotes)
+			}
+			if tt.lineEndings != nil {
+				for k, v := range tt.lineEndings {
+					if d.LineEndings[k] != v {
+						t.Errorf("line endings %v, want %v", d.LineEndings, tt.lineEndings)
+						break
+					}
+				}
+			}
+			if !slices.Equal(d.Suggestions, tt.suggest) {
+				t.Errorf("suggestions %q, want %q", d.Suggestions, tt.suggest)
+			}
+			if d.Encoding != EncodingUTF8 || d.Compression != "" {
+				t.Errorf("encoding %s, compression %q", d.Encoding, d.Compression)
+			}
+		})
+	}
+}
+
+func TestDiagnoseBareCR(t *testing.T) {
+	d, err := Diagnose(strings.NewReader("a,b\r1,2\r3,4\r"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.LineEndings["cr"] != 3 || !slices.Contains(d.Suggestions, "convert bare CR line endings to LF") {
+		t.Errorf("line endings %v, suggestions %q", d.LineEndings, d.Suggestions)
+	}
+}
+
+func TestDiagnoseTruncated(t *testing.T) {
+	// The limit cuts the last row short; it must not be reported as ragged.
+	in := "a,b,c\n1,2,3\n4,5,6\n7,8,9\n"
+	d, err := Diagnose(strings.NewReader(in), len(in)-3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.RowsSampled != 3 || d.RaggedRows != nil {
+		t.Errorf("rows %d, ragged %v; want 3, none", d.RowsSampled, d.RaggedRows)
+	}
+}
+
+func TestDiagnoseGzipAndEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	// "a;b" then "é;1" in UTF-16LE with a byte-order mark.
+	zw.Write([]byte{0xFF, 0xFE, 'a', 0, ';', 0, 'b', 0, '\n', 0, 0xE9, 0, ';', 0, '1', 0, '\n', 0})
+	zw.Close()
+
+	d, err := Diagnose(&buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Compression != "gzip" || d.Encoding != EncodingUTF16LE || d.Delimiter != ";" || d.Fields != 2 {
+		t.Errorf("got compression %q, encoding %s, delimiter %q, fields %d", d.Compression, d.Encoding, d.Delimiter, d.Fields)
+	}
+}
+
+func TestDiagnoseEmpty(t *testing.T) {
+	if _, err := Diagnose(strings.NewReader(""), 0); err == nil {
+		t.Error("expected an error for empty input")
+	}
+}
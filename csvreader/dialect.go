@@ -0,0 +1,505 @@
+package csvreader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	arrowcsv "github.com/apache/arrow-go/v18/arrow/csv"
+
+	"github.com/TFMV/supercharged/internal/decompress"
+)
+
+// Dialect describes how a delimited text file is laid out. The zero value is
+// standard CSV: comma-separated, double-quoted, without comments, with a
+// header row and '.' as the decimal separator.
+type Dialect struct {
+	// Delimiter separates fields; zero means ','.
+	Delimiter rune
+	// Quote encloses fields holding delimiters or newlines, and is escaped
+	// inside them by doubling; zero means '"'.
+	Quote rune
+	// Comment, when set, starts a line that is ignored.
+	Comment rune
+	// NoHeader reads the first line as data; columns are named f0, f1, ...
+	NoHeader bool
+	// Decimal separates the fraction of numbers, e.g. ',' for European
+	// files; zero means '.'. Digit grouping separators are not removed.
+	Decimal rune
+	// Nulls are the fields read as null, replacing DefaultNullValues when
+	// non-nil. A token that is a number, such as -999, also matches fields
+	// of equal value, such as -999.0.
+	Nulls []string
+	// SkipBadRows drops the rows Arrow's reader would fail on instead of
+	// failing: rows with another number of fields than the first, and rows
+	// with a value that does not parse as its column's type. The types are
+	// those of Schema when set, otherwise those of Types or, for the other
+	// columns, those Arrow infers from the first data row.
+	SkipBadRows bool
+	// OnBadRow, when set, is called with each row SkipBadRows drops.
+	OnBadRow func(BadRow)
+	// Schema is the schema the input is read with, when it is not inferred.
+	Schema *arrow.Schema
+	// Types forces the types of the named columns when the schema is
+	// inferred, by NewInferringCSVReader or InferSchemaFromCSV.
+	Types map[string]arrow.DataType
+	// InferenceRows is how many data rows the other column types are
+	// inferred from, promoting int64 to float64 and mixed types to string
+	// as values disagree; a negative value reads the whole input, holding
+	// it in memory. Zero leaves inference to Arrow, which only looks at the
+	// first row. The rows are read ahead by the reader Apply returns, which
+	// is why this is a Dialect field and not an option of the readers,
+	// whose options are Arrow's.
+	InferenceRows int
+}
+
+// BadRow is a row dropped by Dialect.SkipBadRows.
+type BadRow struct {
+	// Line is the 1-based line of the input the row starts on.
+	Line int64
+	// Reason says why the row would not parse.
+	Reason string
+}
+
+func (d Dialect) delimiter() rune { return orRune(d.Delimiter, ',') }
+func (d Dialect) quote() rune     { return orRune(d.Quote, '"') }
+func (d Dialect) decimal() rune   { return orRune(d.Decimal, '.') }
+
+func orRune(r, def rune) rune {
+	if r == 0 {
+		return def
+	}
+	return r
+}
+
+// validate rejects dialects whose characters collide.
+func (d Dialect) validate() error {
+	delim, quote, dec := d.delimiter(), d.quote(), d.decimal()
+	for _, c := range []rune{delim, quote, dec} {
+		if c == '\r' || c == '\n' {
+			return fmt.Errorf("dialect: %q cannot be a delimiter, quote or decimal separator", c)
+		}
+	}
+	switch {
+	case delim == quote:
+		return fmt.Errorf("dialect: delimiter and quote are both %q", delim)
+	case delim == dec:
+		return fmt.Errorf("dialect: delimiter and decimal separator are both %q", delim)
+	case d.Comment != 0 && (d.Comment == delim || d.Comment == quote):
+		return fmt.Errorf("dialect: comment character %q is also the delimiter or quote", d.Comment)
+	}
+	return nil
+}
+
+// rewrites reports whether the input must be rewritten as standard CSV,
+// because Arrow's reader supports neither another quote character nor
+// another decimal separator, nor null tokens matched by value, nor skipping
+// bad rows. Headerless input is rewritten too, with a header of generated
+// names, since Arrow's inferring reader drops the first row when told there
+// is no header.
+func (d Dialect) rewrites() bool {
+	return d.quote() != '"' || d.decimal() != '.' || d.NoHeader || d.SkipBadRows || d.infers() || len(d.sentinels()) > 0
+}
+
+// infers reports whether Apply infers the column types itself.
+func (d Dialect) infers() bool {
+	return d.InferenceRows != 0 && d.Schema == nil
+}
+
+// sentinel is a null token that is a number.
+type sentinel struct {
+	value float64
+	token string
+}
+
+// sentinels returns the null tokens that are numbers.
+func (d Dialect) sentinels() []sentinel {
+	var out []sentinel
+	for _, tok := range d.Nulls {
+		if v, err := strconv.ParseFloat(strings.TrimSpace(tok), 64); err == nil && !math.IsNaN(v) {
+			out = append(out, sentinel{value: v, token: tok})
+		}
+	}
+	return out
+}
+
+// Apply returns r prepared for d, with the options that configure
+// NewCSVReader, NewInferringCSVReader and InferSchemaFromCSV to read it. When
+// Arrow's reader cannot read d directly, r is decompressed, decoded and
+// rewritten as standard CSV: numbers are normalized to '.', fields equal in
+// value to a numeric null token are replaced by it, bad rows are dropped
+// for SkipBadRows and, for NoHeader, a header of the names f0, f1, ... is
+// added. Otherwise r is returned as is. For InferenceRows, those rows are
+// read before Apply returns.
+func (d Dialect) Apply(r io.Reader) (io.Reader, []arrowcsv.Option, error) {
+	if err := d.validate(); err != nil {
+		return nil, nil, err
+	}
+	var opts []arrowcsv.Option
+	if d.Nulls != nil {
+		opts = append(opts, arrowcsv.WithNullReader(true, d.Nulls...))
+	}
+	if !d.rewrites() {
+		opts = append(opts, arrowcsv.WithComma(d.delimiter()))
+		if d.Comment != 0 {
+			opts = append(opts, arrowcsv.WithComment(d.Comment))
+		}
+		if len(d.Types) > 0 && d.Schema == nil {
+			opts = append(opts, arrowcsv.WithColumnTypes(d.Types))
+		}
+		return r, opts, nil
+	}
+	dr := &dialectReader{
+		d:         d,
+		src:       bufio.NewReader(NewDecodingReader(decompress.NewReader(r))),
+		sentinels: d.sentinels(),
+	}
+	dr.nulls = d.Nulls
+	if dr.nulls == nil {
+		dr.nulls = DefaultNullValues
+	}
+	dr.w = csv.NewWriter(&dr.buf)
+	if plain := d; d.infers() {
+		// When only inference needs the rows, the rest of the input is
+		// passed to Arrow's reader as it is, once they are out.
+		plain.InferenceRows = 0
+		if dr.raw = !plain.rewrites(); dr.raw {
+			dr.w.Comma = d.delimiter()
+			opts = append(opts, arrowcsv.WithComma(d.delimiter()))
+			if d.Comment != 0 {
+				opts = append(opts, arrowcsv.WithComment(d.Comment))
+			}
+		}
+		types := dr.infer(d.InferenceRows)
+		for name, dt := range d.Types {
+			types[name] = dt
+		}
+		dr.d.Types = types
+	}
+	if len(dr.d.Types) > 0 && d.Schema == nil {
+		opts = append(opts, arrowcsv.WithColumnTypes(dr.d.Types))
+	}
+	return dr, opts, nil
+}
+
+// dialectReader re-emits a file in dialect d as standard CSV, one record at
+// a time as it is read.
+type dialectReader struct {
+	d         Dialect
+	src       *bufio.Reader
+	buf       bytes.Buffer
+	w         *csv.Writer
+	err       error
+	sentinels []sentinel
+	// named is set once the generated header of a NoHeader dialect is out.
+	named bool
+
+	// line counts the newlines read, and start is the line the last record
+	// began on.
+	line, start int64
+	// names and types are those of the columns, once known, for
+	// SkipBadRows; nulls are the tokens that pass as any type.
+	names []string
+	types []arrow.DataType
+	nulls []string
+	// ahead holds the records read by infer, to be emitted first, and raw
+	// is set when the input after them is read unchanged.
+	ahead []pending
+	raw   bool
+}
+
+// pending is a record read ahead, or the error that ended reading ahead.
+type pending struct {
+	rec  []string
+	line int64
+	err  error
+}
+
+func (dr *dialectReader) Read(p []byte) (int, error) {
+	if dr.raw && len(dr.ahead) == 0 && dr.buf.Len() == 0 {
+		return dr.src.Read(p)
+	}
+	for dr.buf.Len() == 0 && dr.err == nil && (!dr.raw || len(dr.ahead) > 0) {
+		var rec []string
+		rec, dr.err = dr.next()
+		if dr.err == errUnterminated && dr.d.SkipBadRows {
+			// The quote runs to the end of the input: one last bad row.
+			if dr.d.OnBadRow != nil {
+				dr.d.OnBadRow(BadRow{Line: dr.start, Reason: dr.err.Error()})
+			}
+			dr.err = io.EOF
+		}
+		if rec == nil {
+			break
+		}
+		if dr.d.NoHeader && !dr.named {
+			dr.w.Write(fieldNames(len(rec)))
+			dr.named = true
+		}
+		if dr.d.SkipBadRows {
+			if reason := dr.check(rec); reason != "" {
+				if dr.d.OnBadRow != nil {
+					dr.d.OnBadRow(BadRow{Line: dr.start, Reason: reason})
+				}
+				continue
+			}
+		}
+		dr.w.Write(rec)
+		dr.w.Flush()
+		if err := dr.w.Error(); err != nil {
+			dr.err = err
+		}
+	}
+	if dr.buf.Len() > 0 {
+		return dr.buf.Read(p)
+	}
+	return 0, dr.err
+}
+
+// fieldNames returns the names f0, f1, ... of n headerless columns.
+func fieldNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("f%d", i)
+	}
+	return names
+}
+
+// next returns the next record, starting with those read ahead.
+func (dr *dialectReader) next() ([]string, error) {
+	if len(dr.ahead) == 0 {
+		return dr.record()
+	}
+	p := dr.ahead[0]
+	dr.ahead = dr.ahead[1:]
+	dr.start = p.line
+	return p.rec, p.err
+}
+
+// infer reads ahead the header and up to n data rows, or all of them when n
+// is negative, and returns the types of the columns holding non-null values
+// in them. Rows with another number of fields than the header are left out.
+func (dr *dialectReader) infer(n int) map[string]arrow.DataType {
+	var (
+		names []string
+		types []arrow.DataType
+	)
+	for rows := 0; n < 0 || rows < n; {
+		rec, err := dr.record()
+		if err == io.EOF {
+			break
+		}
+		dr.ahead = append(dr.ahead, pending{rec: rec, line: dr.start, err: err})
+		if err != nil {
+			break
+		}
+		if names == nil {
+			names = rec
+			if dr.d.NoHeader {
+				names = fieldNames(len(rec))
+			}
+			types = make([]arrow.DataType, len(names))
+			if !dr.d.NoHeader {
+				continue
+			}
+		}
+		if len(rec) != len(names) {
+			continue
+		}
+		rows++
+		for i, v := range rec {
+			if !slices.Contains(dr.nulls, v) {
+				types[i] = promote(types[i], inferType(v))
+			}
+		}
+	}
+	out := map[string]arrow.DataType{}
+	for i, dt := range types {
+		if dt != nil {
+			out[names[i]] = dt
+		}
+	}
+	return out
+}
+
+var errUnterminated = errors.New("dialect: unterminated quoted field")
+
+// record reads the next record, skipping comment lines. It returns io.EOF
+// once the input is exhausted.
+func (dr *dialectReader) record() ([]string, error) {
+	delim, quote := dr.d.delimiter(), dr.d.quote()
+	var (
+		fields  []string
+		field   strings.Builder
+		inQuote bool
+		start   = true // at the start of a field
+		line    = true // at the start of a line
+	)
+	end := func() {
+		fields = append(fields, dr.null(dr.normalize(field.String())))
+		field.Reset()
+		start = true
+	}
+	dr.start = dr.line + 1
+	for {
+		c, _, err := dr.src.ReadRune()
+		if err == io.EOF {
+			if inQuote {
+				return nil, errUnterminated
+			}
+			if line {
+				return nil, io.EOF
+			}
+			end()
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if c == '\n' {
+			dr.line++
+		}
+		switch {
+		case inQuote:
+			if c != quote {
+				field.WriteRune(c)
+				continue
+			}
+			if next, _, err := dr.src.ReadRune(); err == nil && next == quote {
+				field.WriteRune(quote)
+				continue
+			} else if err == nil {
+				dr.src.UnreadRune()
+			}
+			inQuote = false
+		case line && dr.d.Comment != 0 && c == dr.d.Comment:
+			if _, err := dr.src.ReadString('\n'); err == nil {
+				dr.line++
+			} else if err != io.EOF {
+				return nil, err
+			}
+			dr.start = dr.line + 1
+		case line && (c == '\n' || c == '\r'):
+			// Blank lines are skipped, as by Arrow's reader.
+			dr.start = dr.line + 1
+		case c == '\n':
+			end()
+			return fields, nil
+		case c == '\r':
+			// Dropped before the '\n' of a CRLF line ending.
+			if next, _, err := dr.src.ReadRune(); err == nil && next != '\n' {
+				dr.src.UnreadRune()
+				field.WriteRune(c)
+			} else if err == nil {
+				dr.src.UnreadRune()
+			}
+			line = false
+		case c == delim:
+			end()
+			line = false
+		case start && c == quote:
+			inQuote, start, line = true, false, false
+		default:
+			field.WriteRune(c)
+			start, line = false, false
+		}
+	}
+}
+
+// check returns why Arrow's reader would fail on rec, or "" when it would
+// not. The first record sets the number of fields and, unless it is a
+// header, the column types.
+func (dr *dialectReader) check(rec []string) string {
+	if dr.names == nil {
+		dr.names = rec
+		if !dr.d.NoHeader {
+			return ""
+		}
+		dr.names = fieldNames(len(rec))
+	}
+	if len(rec) != len(dr.names) {
+		return fmt.Sprintf("expected %d fields, got %d", len(dr.names), len(rec))
+	}
+	if dr.types == nil {
+		dr.types = make([]arrow.DataType, len(rec))
+		for i, v := range rec {
+			if dr.d.Schema != nil && i < dr.d.Schema.NumFields() {
+				dr.types[i] = dr.d.Schema.Field(i).Type
+			} else if dt, ok := dr.d.Types[dr.names[i]]; ok && dr.d.Schema == nil {
+				dr.types[i] = dt
+			} else {
+				dr.types[i] = inferType(v)
+			}
+		}
+	}
+	for i, v := range rec {
+		if slices.Contains(dr.nulls, v) {
+			continue
+		}
+		if err := parse(v, dr.types[i]); err != nil {
+			return fmt.Sprintf("column %s: cannot parse %q as %s", dr.names[i], v, dr.types[i])
+		}
+	}
+	return ""
+}
+
+// null returns the numeric null token s equals in value, or s.
+func (dr *dialectReader) null(s string) string {
+	if len(dr.sentinels) == 0 {
+		return s
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return s
+	}
+	for _, sv := range dr.sentinels {
+		if v == sv.value {
+			return sv.token
+		}
+	}
+	return s
+}
+
+// normalize rewrites s with '.' as its decimal separator when it is a
+// number written with d's separator, and returns it unchanged otherwise.
+func (dr *dialectReader) normalize(s string) string {
+	dec := dr.d.decimal()
+	if dec == '.' || !strings.ContainsRune(s, dec) {
+		return s
+	}
+	t := strings.TrimSpace(s)
+	seen, digits := false, false
+	for i, c := range t {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c == dec && !seen:
+			seen = true
+		case (c == '+' || c == '-') && i == 0:
+		case (c == 'e' || c == 'E') && digits:
+			// An exponent must follow the mantissa with digits.
+			exp := strings.TrimLeft(t[i+1:], "+-")
+			if len(t[i+1:])-len(exp) > 1 {
+				return s
+			}
+			if exp == "" || strings.Trim(exp, "0123456789") != "" {
+				return s
+			}
+			return strings.Replace(t[:i], string(dec), ".", 1) + t[i:]
+		default:
+			return s
+		}
+	}
+	if !digits {
+		return s
+	}
+	return strings.Replace(t, string(dec), ".", 1)
+}
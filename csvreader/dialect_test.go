@@ -0,0 +1,313 @@
+package csvreader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// rewrite returns in as d's Apply rewrites it.
+func rewrite(t *testing.T, d Dialect, in string) string {
+	t.Helper()
+	r, _, err := d.Apply(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestDialectRewrite(t *testing.T) {
+	tests := []struct {
+		name string
+		d    Dialect
+		in   string
+		want string
+	}{
+		{
+			name: "single quote", d: Dialect{Quote: '\''},
+			in:   "a,b\n'x,y',1\n'it''s',2\n",
+			want: "a,b\n\"x,y\",1\nit's,2\n",
+		},
+		{
+			name: "quoted delimiter and newline", d: Dialect{Delimiter: ';', Quote: '\''},
+			in:   "a;b\n'x;\ny';1\n",
+			want: "a,b\n\"x;\ny\",1\n",
+		},
+		{
+			name: "comments and blank lines", d: Dialect{Quote: '\'', Comment: '#'},
+			in:   "# exported\na,b\n\n# more\n1,2\n",
+			want: "a,b\n1,2\n",
+		},
+		{
+			name: "no header", d: Dialect{NoHeader: true},
+			in:   "1,2\n3,4\n",
+			want: "f0,f1\n1,2\n3,4\n",
+		},
+		{
+			name: "decimal comma", d: Dialect{Delimiter: ';', Decimal: ','},
+			in:   "a;b;c\n1,5;x,y;-2,5e3\n",
+			want: "a,b,c\n1.5,\"x,y\",-2.5e3\n",
+		},
+		{
+			name: "crlf", d: Dialect{Quote: '\''},
+			in:   "a,b\r\n1,2\r\n",
+			want: "a,b\n1,2\n",
+		},
+		{
+			name: "no final newline", d: Dialect{Quote: '\''},
+			in:   "a,b\n1,2",
+			want: "a,b\n1,2\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := rewrite(t, tt.d, tt.in); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDialectPassThrough(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	// Arrow's reader handles another delimiter and comments itself.
+	in := strings.NewReader("# exported\na\tb\n1\tx\n2\ty\n")
+	r, opts, err := Dialect{Delimiter: '\t', Comment: '#'}.Apply(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != io.Reader(in) {
+		t.Error("input was rewritten")
+	}
+	cr, err := NewInferringCSVReader(r, pool, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := cr.ReadRecord(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	if rec.NumRows() != 2 || rec.NumCols() != 2 || rec.ColumnName(1) != "b" {
+		t.Fatalf("got %d rows of %v", rec.NumRows(), rec.Schema())
+	}
+	if got := rec.Column(0).(*array.Int64).Value(1); got != 2 {
+		t.Errorf("a[1] = %d, want 2", got)
+	}
+}
+
+func TestDialectValidate(t *testing.T) {
+	for _, d := range []Dialect{
+		{Delimiter: '"'},
+		{Delimiter: '\n'},
+		{Delimiter: ';', Decimal: ';'},
+		{Comment: ','},
+		{Quote: '\'', Comment: '\''},
+	} {
+		if _, _, err := d.Apply(strings.NewReader("a\n1\n")); err == nil {
+			t.Errorf("%+v: want error", d)
+		}
+	}
+}
+
+func TestDialectUnterminated(t *testing.T) {
+	r, _, err := Dialect{Quote: '\''}.Apply(strings.NewReader("a,b\n'x,1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); !errors.Is(err, errUnterminated) {
+		t.Errorf("err = %v, want %v", err, errUnterminated)
+	}
+}
+
+func TestDialectNulls(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	// -999 matches -999.0 by value, but not -9990; NA matches only itself.
+	d := Dialect{Nulls: []string{"-999", "NA"}}
+	in := "a,b\n-999.0,NA\n1,-999\n-9990,x\n"
+	if got, want := rewrite(t, d, in), "a,b\n-999,NA\n1,-999\n-9990,x\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	r, opts, err := d.Apply(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "a", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "b", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	rec, err := NewCSVReader(r, schema, pool, opts...).ReadRecord(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	a, b := rec.Column(0).(*array.Float64), rec.Column(1).(*array.String)
+	if !a.IsNull(0) || a.Value(1) != 1 || a.Value(2) != -9990 {
+		t.Errorf("a = %v", a)
+	}
+	if !b.IsNull(0) || !b.IsNull(1) || b.Value(2) != "x" {
+		t.Errorf("b = %v", b)
+	}
+}
+
+func TestDialectNullsPassThrough(t *testing.T) {
+	// Tokens that are not numbers are left to Arrow's reader, and replace
+	// the defaults: the empty field is read as a string.
+	in := strings.NewReader("a\nNA\n\"\"\n")
+	r, opts, err := Dialect{Nulls: []string{"NA"}}.Apply(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != io.Reader(in) {
+		t.Error("input was rewritten")
+	}
+	schema := arrow.NewSchema([]arrow.Field{{Name: "a", Type: arrow.BinaryTypes.String, Nullable: true}}, nil)
+	rec, err := NewCSVReader(r, schema, nil, opts...).ReadRecord(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	if a := rec.Column(0); !a.IsNull(0) || a.IsNull(1) {
+		t.Errorf("a = %v, want NA alone null", a)
+	}
+}
+
+func TestDialectSkipBadRows(t *testing.T) {
+	var bad []BadRow
+	d := Dialect{SkipBadRows: true, OnBadRow: func(b BadRow) { bad = append(bad, b) }}
+	// The first data row makes a an int64; nulls pass as any type, and the
+	// unterminated quote on line 9 is the last bad row.
+	in := "a,b\n1,x\nNULL,3\n4\n5,6,7\ny,9\n\n10,z\n\"11,w\n"
+	if got, want := rewrite(t, d, in), "a,b\n1,x\nNULL,3\n10,z\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	want := []BadRow{
+		{Line: 4, Reason: "expected 2 fields, got 1"},
+		{Line: 5, Reason: "expected 2 fields, got 3"},
+		{Line: 6, Reason: `column a: cannot parse "y" as int64`},
+		{Line: 9, Reason: errUnterminated.Error()},
+	}
+	if !reflect.DeepEqual(bad, want) {
+		t.Errorf("bad rows:\n got %+v\nwant %+v", bad, want)
+	}
+}
+
+func TestDialectSkipBadRowsTyped(t *testing.T) {
+	var lines []int64
+	onBad := func(b BadRow) { lines = append(lines, b.Line) }
+	in := "a,b\n1,x\n2,3\n"
+
+	// Schema types every column, so the first data row can be bad too.
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "a", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "b", Type: arrow.PrimitiveTypes.Int64},
+	}, nil)
+	d := Dialect{SkipBadRows: true, OnBadRow: onBad, Schema: schema}
+	if got := rewrite(t, d, in); got != "a,b\n2,3\n" || !reflect.DeepEqual(lines, []int64{2}) {
+		t.Errorf("schema: got %q, bad lines %v", got, lines)
+	}
+
+	// Types the named columns, the others from the first data row.
+	lines = nil
+	d = Dialect{SkipBadRows: true, OnBadRow: onBad, Types: map[string]arrow.DataType{"b": arrow.BinaryTypes.String}}
+	if got := rewrite(t, d, in+"z,4\n"); got != "a,b\n1,x\n2,3\n" || !reflect.DeepEqual(lines, []int64{4}) {
+		t.Errorf("types: got %q, bad lines %v", got, lines)
+	}
+
+	// Without SkipBadRows the row is left for Arrow's reader to fail on.
+	r, opts, err := Dialect{}.Apply(strings.NewReader(in + "4\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr, err := NewInferringCSVReader(r, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec, err := cr.ReadRecord(context.Background(), nil); err == nil {
+		rec.Release()
+		t.Error("ragged row: want error")
+	}
+}
+
+// inferred returns the column types d's Apply infers from in.
+func inferred(t *testing.T, d Dialect, in string) map[string]arrow.DataType {
+	t.Helper()
+	r, _, err := d.Apply(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r.(*dialectReader).d.Types
+}
+
+func TestDialectInferenceRows(t *testing.T) {
+	var (
+		i64 = arrow.PrimitiveTypes.Int64
+		f64 = arrow.PrimitiveTypes.Float64
+		str = arrow.BinaryTypes.String
+	)
+	// b promotes from int64 to float64 to string; d holds only nulls and is
+	// left to Arrow, as are ragged rows.
+	in := "a,b,c,d\n1,1,x,\n2,2.5,y,\n9\n3,z,,\n"
+	tests := []struct {
+		name string
+		d    Dialect
+		want map[string]arrow.DataType
+	}{
+		{"all rows", Dialect{InferenceRows: -1}, map[string]arrow.DataType{"a": i64, "b": str, "c": str}},
+		{"two rows", Dialect{InferenceRows: 2}, map[string]arrow.DataType{"a": i64, "b": f64, "c": str}},
+		{"one row", Dialect{InferenceRows: 1}, map[string]arrow.DataType{"a": i64, "b": i64, "c": str}},
+		{"forced type", Dialect{InferenceRows: -1, Types: map[string]arrow.DataType{"a": f64}}, map[string]arrow.DataType{"a": f64, "b": str, "c": str}},
+		{"no header", Dialect{InferenceRows: -1, NoHeader: true}, map[string]arrow.DataType{"f0": str, "f1": str, "f2": str, "f3": str}},
+	}
+	for _, tt := range tests {
+		if got := inferred(t, tt.d, in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// Rows read ahead are emitted first, and the rest passes unchanged when
+	// nothing else needs rewriting.
+	in = "a;b\n1;x\n2;y\n3;z\n"
+	if got := rewrite(t, Dialect{Delimiter: ';', InferenceRows: 1}, in); got != in {
+		t.Errorf("got %q, want %q", got, in)
+	}
+}
+
+func TestDialectInferenceRowsRead(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	// The decimal in the last row makes a a float64.
+	in := "a,b\n" + strings.Repeat("1,x\n", 2000) + "2.5,y\n"
+	r, opts, err := Dialect{InferenceRows: -1}.Apply(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr, err := NewInferringCSVReader(r, pool, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := cr.ReadRecord(context.Background(), []string{"a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	a, ok := rec.Column(0).(*array.Float64)
+	if !ok || a.Len() != 2001 || a.Value(2000) != 2.5 {
+		t.Errorf("a = %s of %d rows", rec.Column(0).DataType(), rec.Column(0).Len())
+	}
+}
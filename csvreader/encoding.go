@@ -0,0 +1,165 @@
+package csvreader
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encoding names reported by DetectEncoding.
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingUTF8BOM     = "utf-8-bom"
+	EncodingUTF16LE     = "utf-16le"
+	EncodingUTF16BE     = "utf-16be"
+	EncodingLatin1      = "iso-8859-1"
+	EncodingWindows1252 = "windows-1252"
+)
+
+// encodingSniffBytes is how much of the input is peeked to detect its encoding.
+const encodingSniffBytes = 64 << 10
+
+// DetectEncoding guesses the character encoding of sample. Byte-order marks
+// are trusted first; BOM-less UTF-16 is recognised by its NUL byte pattern.
+// Input that is not valid UTF-8 is reported as Windows-1252 when it uses the
+// 0x80-0x9F range (C1 controls in Latin-1) and as Latin-1 otherwise.
+func DetectEncoding(sample []byte) string {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return EncodingUTF8BOM
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return EncodingUTF16LE
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return EncodingUTF16BE
+	}
+	if enc := sniffUTF16(sample); enc != "" {
+		return enc
+	}
+	if utf8.Valid(trimPartialRune(sample)) {
+		return EncodingUTF8
+	}
+	for _, b := range sample {
+		if b >= 0x80 && b <= 0x9F {
+			return EncodingWindows1252
+		}
+	}
+	return EncodingLatin1
+}
+
+// sniffUTF16 detects BOM-less UTF-16 text, which for mostly-ASCII data has a
+// NUL in every other byte.
+func sniffUTF16(sample []byte) string {
+	if len(sample) < 4 {
+		return ""
+	}
+	var even, odd int
+	n := len(sample) &^ 1
+	for i := 0; i < n; i += 2 {
+		if sample[i] == 0 {
+			even++
+		}
+		if sample[i+1] == 0 {
+			odd++
+		}
+	}
+	pairs := n / 2
+	switch {
+	case odd*10 >= pairs*9 && even*10 < pairs:
+		return EncodingUTF16LE
+	case even*10 >= pairs*9 && odd*10 < pairs:
+		return EncodingUTF16BE
+	}
+	return ""
+}
+
+// decoderFor returns the decoder converting name to UTF-8, or nil when the
+// input is already plain UTF-8.
+func decoderFor(name string) *encoding.Decoder {
+	switch name {
+	case EncodingUTF8BOM:
+		return unicode.UTF8BOM.NewDecoder()
+	case EncodingUTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder()
+	case EncodingUTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder()
+	case EncodingLatin1:
+		return charmap.ISO8859_1.NewDecoder()
+	case EncodingWindows1252:
+		return charmap.Windows1252.NewDecoder()
+	}
+	return nil
+}
+
+// DecodingReader transcodes its underlying reader to UTF-8. The encoding is
+// detected lazily on the first Read, so constructing one consumes no input.
+type DecodingReader struct {
+	src  io.Reader
+	out  io.Reader
+	name string
+}
+
+// NewDecodingReader returns a reader producing UTF-8 from r, with any
+// byte-order mark removed. Read errors encountered while sniffing surface
+// from Read.
+func NewDecodingReader(r io.Reader) *DecodingReader {
+	return &DecodingReader{src: r}
+}
+
+func (d *DecodingReader) init() {
+	if d.out != nil {
+		return
+	}
+	br := bufio.NewReaderSize(d.src, encodingSniffBytes)
+	sample, _ := br.Peek(encodingSniffBytes)
+	d.name = DetectEncoding(sample)
+	if dec := decoderFor(d.name); dec != nil {
+		d.out = transform.NewReader(br, dec)
+	} else {
+		d.out = br
+	}
+}
+
+// Read implements io.Reader.
+func (d *DecodingReader) Read(p []byte) (int, error) {
+	d.init()
+	return d.out.Read(p)
+}
+
+// Encoding returns the detected encoding of the underlying input, sniffing
+// it first if nothing has been read yet.
+func (d *DecodingReader) Encoding() string {
+	d.init()
+	return d.name
+}
+
+// decodeBytes converts buf from the named encoding to UTF-8.
+func decodeBytes(buf []byte, name string) []byte {
+	dec := decoderFor(name)
+	if dec == nil {
+		return buf
+	}
+	out, _, err := transform.Bytes(dec, buf)
+	if err != nil {
+		return buf
+	}
+	return out
+}
+
+// trimPartialRune drops an incomplete multi-byte sequence at the end of buf.
+func trimPartialRune(buf []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(buf); i++ {
+		if utf8.RuneStart(buf[len(buf)-i]) {
+			if !utf8.FullRune(buf[len(buf)-i:]) {
+				return buf[:len(buf)-i]
+			}
+			break
+		}
+	}
+	return buf
+}
@@ -0,0 +1,101 @@
+package csvreader
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// utf16 encodes ASCII s as UTF-16 in the given byte order, without a BOM.
+func utf16(s string, bigEndian bool) []byte {
+	out := make([]byte, 0, 2*len(s))
+	for _, c := range []byte(s) {
+		if bigEndian {
+			out = append(out, 0, c)
+		} else {
+			out = append(out, c, 0)
+		}
+	}
+	return out
+}
+
+func TestDetectEncoding(t *testing.T) {
+	const text = "name,city\nana,porto\n"
+	tests := []struct {
+		name string
+		in   []byte
+		want string
+	}{
+		{"ascii", []byte(text), EncodingUTF8},
+		{"utf-8", []byte("name\nJosé\n"), EncodingUTF8},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, text...), EncodingUTF8BOM},
+		{"utf-16le bom", append([]byte{0xFF, 0xFE}, utf16(text, false)...), EncodingUTF16LE},
+		{"utf-16be bom", append([]byte{0xFE, 0xFF}, utf16(text, true)...), EncodingUTF16BE},
+		{"utf-16le", utf16(text, false), EncodingUTF16LE},
+		{"utf-16be", utf16(text, true), EncodingUTF16BE},
+		{"latin-1", []byte("name\nJos\xe9\n"), EncodingLatin1},
+		{"windows-1252", []byte("price\n\x8010\n"), EncodingWindows1252},
+		// A sample cut in the middle of a multi-byte rune is still UTF-8.
+		{"utf-8 cut short", []byte("name\nJos\xc3"), EncodingUTF8},
+	}
+	for _, tt := range tests {
+		if got := DetectEncoding(tt.in); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodingReader(t *testing.T) {
+	const text = "name,city\nJosé,Kraków\n"
+	tests := []struct {
+		name string
+		in   []byte
+		enc  string
+		want string
+	}{
+		{"utf-8", []byte(text), EncodingUTF8, text},
+		{"utf-8 bom", append([]byte{0xEF, 0xBB, 0xBF}, text...), EncodingUTF8BOM, text},
+		{"utf-16le bom", append([]byte{0xFF, 0xFE}, utf16("a,b\n1,2\n", false)...), EncodingUTF16LE, "a,b\n1,2\n"},
+		{"utf-16be bom", append([]byte{0xFE, 0xFF}, utf16("a,b\n1,2\n", true)...), EncodingUTF16BE, "a,b\n1,2\n"},
+		{"utf-16le", utf16("a,b\n1,2\n", false), EncodingUTF16LE, "a,b\n1,2\n"},
+		{"utf-16be", utf16("a,b\n1,2\n", true), EncodingUTF16BE, "a,b\n1,2\n"},
+		{"latin-1", []byte("name\nJos\xe9\n"), EncodingLatin1, "name\nJosé\n"},
+		{"windows-1252", []byte("price\n\x8010\n"), EncodingWindows1252, "price\n€10\n"},
+	}
+	for _, tt := range tests {
+		// One byte per Read splits every BOM and multi-byte sequence across
+		// reads of the underlying input.
+		for _, r := range []io.Reader{bytes.NewReader(tt.in), iotest.OneByteReader(bytes.NewReader(tt.in))} {
+			dr := NewDecodingReader(r)
+			got, err := io.ReadAll(dr)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if string(got) != tt.want || dr.Encoding() != tt.enc {
+				t.Errorf("%s: got %q as %s, want %q as %s", tt.name, got, dr.Encoding(), tt.want, tt.enc)
+			}
+		}
+	}
+}
+
+func TestDecodingReaderSampleBoundary(t *testing.T) {
+	// The encoding sample ends inside "é"; the input is still UTF-8.
+	in := strings.Repeat("a", encodingSniffBytes-1) + "é\n"
+	dr := NewDecodingReader(strings.NewReader(in))
+	got, err := io.ReadAll(dr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dr.Encoding() != EncodingUTF8 || string(got) != in {
+		t.Errorf("encoding %s, output intact %t", dr.Encoding(), string(got) == in)
+	}
+}
+
+func TestDecodingReaderError(t *testing.T) {
+	dr := NewDecodingReader(iotest.ErrReader(io.ErrUnexpectedEOF))
+	if _, err := io.ReadAll(dr); err != io.ErrUnexpectedEOF {
+		t.Errorf("err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
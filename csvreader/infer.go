@@ -0,0 +1,94 @@
+package csvreader
+
+import (
+	"encoding/base64"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// inferType returns the type Arrow's inferring reader gives a column whose
+// first value is v: the first of int64, boolean, date32, time32, timestamp
+// and float64 that v parses as, else string when it is valid UTF-8 and
+// binary otherwise.
+func inferType(v string) arrow.DataType {
+	candidates := []arrow.DataType{
+		arrow.PrimitiveTypes.Int64,
+		arrow.FixedWidthTypes.Boolean,
+		arrow.FixedWidthTypes.Date32,
+		arrow.FixedWidthTypes.Time32s,
+		&arrow.TimestampType{Unit: arrow.Second},
+		&arrow.TimestampType{Unit: arrow.Nanosecond},
+		&arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"},
+		&arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"},
+		arrow.PrimitiveTypes.Float64,
+	}
+	for _, dt := range candidates {
+		if parse(v, dt) == nil {
+			return dt
+		}
+	}
+	if utf8.ValidString(v) {
+		return arrow.BinaryTypes.String
+	}
+	return arrow.BinaryTypes.Binary
+}
+
+// parse returns the error Arrow's reader fails with when it reads v as a
+// value of dt. Strings, and types it is not checked for, are accepted.
+func parse(v string, dt arrow.DataType) error {
+	var err error
+	switch dt := dt.(type) {
+	case *arrow.Int8Type, *arrow.Int16Type, *arrow.Int32Type, *arrow.Int64Type:
+		_, err = strconv.ParseInt(v, 10, dt.(arrow.FixedWidthDataType).BitWidth())
+	case *arrow.Uint8Type, *arrow.Uint16Type, *arrow.Uint32Type, *arrow.Uint64Type:
+		_, err = strconv.ParseUint(v, 10, dt.(arrow.FixedWidthDataType).BitWidth())
+	case *arrow.Float16Type, *arrow.Float32Type:
+		_, err = strconv.ParseFloat(v, 32)
+	case *arrow.Float64Type:
+		_, err = strconv.ParseFloat(v, 64)
+	case *arrow.BooleanType:
+		_, err = strconv.ParseBool(v)
+	case *arrow.Date32Type, *arrow.Date64Type:
+		_, err = time.Parse("2006-01-02", v)
+	case *arrow.Time32Type:
+		_, err = arrow.Time32FromString(v, dt.Unit)
+	case *arrow.TimestampType:
+		_, err = arrow.TimestampFromString(v, dt.Unit)
+	case *arrow.BinaryType, *arrow.LargeBinaryType, *arrow.FixedSizeBinaryType:
+		_, err = base64.StdEncoding.DecodeString(v)
+	}
+	return err
+}
+
+// promote returns the type holding values inferred as a and as b, a being
+// nil before the first: float64 for mixed numbers, the finer of two
+// timestamps in the same zone or a timestamp for dates, and otherwise
+// string, or binary when either is.
+func promote(a, b arrow.DataType) arrow.DataType {
+	switch {
+	case a == nil || arrow.TypeEqual(a, b):
+		return b
+	case isNumber(a) && isNumber(b):
+		return arrow.PrimitiveTypes.Float64
+	case a.ID() == arrow.BINARY || b.ID() == arrow.BINARY:
+		return arrow.BinaryTypes.Binary
+	}
+	ta, aOK := a.(*arrow.TimestampType)
+	tb, bOK := b.(*arrow.TimestampType)
+	switch {
+	case aOK && bOK && ta.TimeZone == tb.TimeZone:
+		return &arrow.TimestampType{Unit: max(ta.Unit, tb.Unit), TimeZone: ta.TimeZone}
+	case aOK && b.ID() == arrow.DATE32:
+		return a
+	case bOK && a.ID() == arrow.DATE32:
+		return b
+	}
+	return arrow.BinaryTypes.String
+}
+
+func isNumber(dt arrow.DataType) bool {
+	return dt.ID() == arrow.INT64 || dt.ID() == arrow.FLOAT64
+}
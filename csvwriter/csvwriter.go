@@ -0,0 +1,133 @@
+// Package csvwriter writes Arrow records, such as annotated anomaly rows, as
+// CSV.
+package csvwriter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/csv"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// CSVWriter writes records of a fixed schema as CSV with a header row.
+type CSVWriter struct {
+	writer *csv.Writer
+	// converted is the schema written, with dictionary-encoded fields
+	// replaced by their value types and timestamps by strings, or nil when
+	// there are none.
+	converted *arrow.Schema
+}
+
+// NewCSVWriter creates a CSVWriter for records of schema. Nulls are written
+// as empty fields, dictionary-encoded columns as their values and timestamps
+// in RFC 3339, in their time zone or in UTC when they have none; opts are
+// applied after the defaults.
+func NewCSVWriter(w io.Writer, schema *arrow.Schema, opts ...csv.Option) *CSVWriter {
+	defaultOpts := []csv.Option{
+		csv.WithHeader(true),
+		csv.WithNullWriter(""),
+	}
+	allOpts := append(defaultOpts, opts...)
+	cw := &CSVWriter{}
+	fields := schema.Fields()
+	for i, f := range fields {
+		switch dt := f.Type.(type) {
+		case *arrow.DictionaryType:
+			fields[i].Type = dt.ValueType
+		case *arrow.TimestampType:
+			fields[i].Type = arrow.BinaryTypes.String
+		default:
+			continue
+		}
+		md := schema.Metadata()
+		cw.converted = arrow.NewSchema(fields, &md)
+	}
+	if cw.converted != nil {
+		schema = cw.converted
+	}
+	cw.writer = csv.NewWriter(w, schema, allOpts...)
+	return cw
+}
+
+// Write writes the rows of rec, which must match the writer's schema.
+func (cw *CSVWriter) Write(rec arrow.Record) error {
+	if cw.converted != nil {
+		converted, err := cw.convert(rec)
+		if err != nil {
+			return fmt.Errorf("csv write error: %w", err)
+		}
+		defer converted.Release()
+		rec = converted
+	}
+	if err := cw.writer.Write(rec); err != nil {
+		return fmt.Errorf("csv write error: %w", err)
+	}
+	return nil
+}
+
+// convert returns rec with its dictionary-encoded columns decoded and its
+// timestamps formatted.
+func (cw *CSVWriter) convert(rec arrow.Record) (arrow.Record, error) {
+	cols := make([]arrow.Array, 0, rec.NumCols())
+	defer func() {
+		for _, c := range cols {
+			c.Release()
+		}
+	}()
+	for _, col := range rec.Columns() {
+		switch col := col.(type) {
+		case *array.Dictionary:
+			out, err := compute.TakeArray(context.Background(), col.Dictionary(), col.Indices())
+			if err != nil {
+				return nil, fmt.Errorf("decode %s: %w", col.DataType(), err)
+			}
+			cols = append(cols, out)
+		case *array.Timestamp:
+			out, err := formatTimes(col)
+			if err != nil {
+				return nil, err
+			}
+			cols = append(cols, out)
+		default:
+			col.Retain()
+			cols = append(cols, col)
+		}
+	}
+	return array.NewRecord(cw.converted, cols, rec.NumRows()), nil
+}
+
+// formatTimes returns the values of col in RFC 3339, in its time zone or in
+// UTC when it has none, keeping its nulls.
+func formatTimes(col *array.Timestamp) (arrow.Array, error) {
+	dt := col.DataType().(*arrow.TimestampType)
+	loc, err := dt.GetZone()
+	if err != nil {
+		return nil, fmt.Errorf("format %s: %w", dt, err)
+	}
+	b := array.NewStringBuilder(memory.DefaultAllocator)
+	defer b.Release()
+	b.Reserve(col.Len())
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		b.Append(col.Value(i).ToTime(dt.Unit).In(loc).Format(time.RFC3339Nano))
+	}
+	return b.NewArray(), nil
+}
+
+// Flush writes any buffered data to the underlying writer.
+func (cw *CSVWriter) Flush() error {
+	cw.writer.Flush()
+	if err := cw.writer.Error(); err != nil {
+		return fmt.Errorf("csv write error: %w", err)
+	}
+	return nil
+}
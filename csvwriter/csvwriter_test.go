@@ -0,0 +1,41 @@
+package csvwriter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestWriteTimestamps(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	at := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "naive", Type: &arrow.TimestampType{Unit: arrow.Second}, Nullable: true},
+		{Name: "zoned", Type: &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "+02:00"}, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{arrow.Timestamp(at.Unix()), 0}, []bool{true, false})
+	b.Field(1).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{arrow.Timestamp(at.UnixNano()), 0}, []bool{true, false})
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf, schema)
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "naive,zoned\n2024-03-01T12:30:00Z,2024-03-01T14:30:00.0000005+02:00\n,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,192 @@
+// Package dbreader streams Arrow records from a query against an
+// ADBC-compatible database such as PostgreSQL, DuckDB, SQLite or Snowflake.
+package dbreader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DBReader streams the result of one query. The query runs in NewDBReader
+// and its result can be consumed once.
+type DBReader struct {
+	allocator memory.Allocator
+	reader    array.RecordReader
+	closers   []io.Closer
+	used      bool
+}
+
+// NewDBReader connects to dsn and runs query. driver names the ADBC driver
+// shared library (e.g. adbc_driver_postgresql, or a path to it); empty means
+// guess it from the DSN scheme: postgres://, postgresql://, sqlite://,
+// duckdb:// or snowflake://. Concatenated columns are allocated from mem; nil
+// means a new Go allocator.
+func NewDBReader(ctx context.Context, driver, dsn, query string, mem memory.Allocator) (*DBReader, error) {
+	allocator := mem
+	if allocator == nil {
+		allocator = memory.NewGoAllocator()
+	}
+	opts, err := databaseOptions(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := newDatabase(opts)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	dr := &DBReader{allocator: allocator, closers: []io.Closer{db}}
+
+	cnxn, err := db.Open(ctx)
+	if err != nil {
+		dr.Close()
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	dr.closers = append(dr.closers, cnxn)
+	stmt, err := cnxn.NewStatement()
+	if err != nil {
+		dr.Close()
+		return nil, fmt.Errorf("new statement: %w", err)
+	}
+	dr.closers = append(dr.closers, stmt)
+	if err := stmt.SetSqlQuery(query); err != nil {
+		dr.Close()
+		return nil, fmt.Errorf("set query: %w", err)
+	}
+	rdr, _, err := stmt.ExecuteQuery(ctx)
+	if err != nil {
+		dr.Close()
+		return nil, fmt.Errorf("execute query: %w", err)
+	}
+	dr.reader = rdr
+	return dr, nil
+}
+
+// databaseOptions builds the driver manager options for dsn.
+func databaseOptions(driver, dsn string) (map[string]string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("dsn: %w", err)
+	}
+	opts := map[string]string{adbc.OptionKeyURI: dsn}
+	guessed := ""
+	switch strings.ToLower(u.Scheme) {
+	case "postgres", "postgresql":
+		guessed = "adbc_driver_postgresql"
+	case "sqlite", "file":
+		guessed = "adbc_driver_sqlite"
+	case "snowflake":
+		guessed = "adbc_driver_snowflake"
+	case "duckdb":
+		// DuckDB ships its ADBC entrypoint in libduckdb and takes a path.
+		guessed = "duckdb"
+		opts = map[string]string{"entrypoint": "duckdb_adbc_init", "path": strings.TrimPrefix(dsn, u.Scheme+"://")}
+	}
+	if driver == "" {
+		driver = guessed
+	}
+	if driver == "" {
+		return nil, fmt.Errorf("cannot infer an ADBC driver from %q; set the driver explicitly", u.Scheme)
+	}
+	opts["driver"] = driver
+	return opts, nil
+}
+
+// Schema returns the schema of the query result.
+func (dr *DBReader) Schema() *arrow.Schema {
+	return dr.reader.Schema()
+}
+
+// Chan returns a channel of records; caller must Release each.
+func (dr *DBReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	if dr.used {
+		close(recs)
+		errs <- fmt.Errorf("query result can only be read once")
+		close(errs)
+		return recs, errs
+	}
+	dr.used = true
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for dr.reader.Next() {
+			rec := dr.reader.Record()
+			rec.Retain()
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+		if err := dr.reader.Err(); err != nil && !errors.Is(err, io.EOF) {
+			errs <- fmt.Errorf("query read error: %w", err)
+		}
+	}()
+	return recs, errs
+}
+
+// ReadSingleColumn concatenates all batches for a named column.
+func (dr *DBReader) ReadSingleColumn(ctx context.Context, columnName string) (arrow.Array, error) {
+	rec, err := dr.ReadColumns(ctx, []string{columnName})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+// ReadChunked returns the named column with one chunk per batch, without
+// concatenating them.
+func (dr *DBReader) ReadChunked(ctx context.Context, columnName string) (*arrow.Chunked, error) {
+	if len(dr.Schema().FieldIndices(columnName)) == 0 {
+		return nil, fmt.Errorf("column %s not found", columnName)
+	}
+	recs, errs := dr.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, columnName)
+}
+
+// ReadColumns drains the result and returns a single record holding the
+// named columns, each concatenated across batches. A nil columns slice keeps
+// every column.
+func (dr *DBReader) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range dr.Schema().Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	for _, name := range columns {
+		if len(dr.Schema().FieldIndices(name)) == 0 {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+	}
+	recs, errs := dr.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, dr.allocator)
+}
+
+// Close releases the result, statement, connection and database.
+func (dr *DBReader) Close() error {
+	if dr.reader != nil {
+		dr.reader.Release()
+		dr.reader = nil
+	}
+	var errs []error
+	for i := len(dr.closers) - 1; i >= 0; i-- {
+		errs = append(errs, dr.closers[i].Close())
+	}
+	dr.closers = nil
+	return errors.Join(errs...)
+}
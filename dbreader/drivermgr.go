@@ -0,0 +1,14 @@
+//go:build cgo
+
+package dbreader
+
+import (
+	"github.com/apache/arrow-adbc/go/adbc"
+	"github.com/apache/arrow-adbc/go/adbc/drivermgr"
+)
+
+// newDatabase loads the driver named in opts through the ADBC driver
+// manager.
+func newDatabase(opts map[string]string) (adbc.Database, error) {
+	return drivermgr.Driver{}.NewDatabase(opts)
+}
@@ -0,0 +1,14 @@
+//go:build !cgo
+
+package dbreader
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow-adbc/go/adbc"
+)
+
+// newDatabase fails: the ADBC driver manager loads C drivers and needs cgo.
+func newDatabase(map[string]string) (adbc.Database, error) {
+	return nil, fmt.Errorf("database input requires a build with cgo enabled")
+}
@@ -0,0 +1,274 @@
+// Package dedup finds exact duplicate rows, and near-duplicates that match on
+// selected columns once normalized, over streams of Arrow records.
+package dedup
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// Defaults used when the corresponding Config field is zero.
+const (
+	DefaultDigits      = 6
+	DefaultMaxGroups   = 10
+	DefaultMaxExamples = 5
+)
+
+// Config selects what counts as a near-duplicate and how much is reported.
+type Config struct {
+	// Columns are compared for near-duplicates, after normalization: strings
+	// are lower-cased with punctuation removed and whitespace collapsed, and
+	// floating-point values rounded to Digits significant digits. Empty means
+	// every column. Exact duplicates always compare every column.
+	Columns []string
+	// Digits is the significant digits floats are compared at; zero means
+	// DefaultDigits.
+	Digits int
+	// MaxGroups bounds the groups reported of each kind, largest first, and
+	// MaxExamples the rows kept per group; zero means DefaultMaxGroups and
+	// DefaultMaxExamples.
+	MaxGroups   int
+	MaxExamples int
+}
+
+// Example is one row of a group, with its values as name=value pairs.
+type Example struct {
+	Row    int64  `json:"row"`
+	Values string `json:"values"`
+}
+
+// Group is a set of rows that duplicate one another.
+type Group struct {
+	Count    int64     `json:"count"`
+	Examples []Example `json:"examples"`
+}
+
+// Report summarizes the duplicates found. ExactDuplicates counts the rows
+// identical to an earlier row, and NearDuplicates the rows that are not but
+// match an earlier row on the normalized Columns; each duplicated row is
+// counted once, under the first kind that applies.
+type Report struct {
+	Rows            int64    `json:"rows"`
+	ExactDuplicates int64    `json:"exact_duplicates"`
+	ExactGroups     int      `json:"exact_groups"`
+	NearDuplicates  int64    `json:"near_duplicates"`
+	NearGroups      int      `json:"near_groups"`
+	NearColumns     []string `json:"near_columns,omitempty"`
+	Exact           []Group  `json:"exact,omitempty"`
+	Near            []Group  `json:"near,omitempty"`
+}
+
+type digest [16]byte
+
+// group tracks the rows sharing a digest. Examples of an exact group are
+// only rendered from its second row, so unique rows cost a map entry each.
+type group struct {
+	count    int64
+	first    int64
+	variants int64
+	examples []Example
+}
+
+// Finder hashes the rows of record batches, in input order, to find
+// duplicates. Memory grows with the number of distinct rows.
+type Finder struct {
+	cfg   Config
+	rows  int64
+	exact map[digest]*group
+	near  map[digest]*group
+	h     hash.Hash
+	buf   []byte
+	// exactDups and nearDups count the duplicated rows of each kind.
+	exactDups, nearDups int64
+}
+
+// NewFinder returns a Finder for cfg.
+func NewFinder(cfg Config) *Finder {
+	if cfg.Digits <= 0 {
+		cfg.Digits = DefaultDigits
+	}
+	if cfg.MaxGroups <= 0 {
+		cfg.MaxGroups = DefaultMaxGroups
+	}
+	if cfg.MaxExamples <= 0 {
+		cfg.MaxExamples = DefaultMaxExamples
+	}
+	return &Finder{
+		cfg:   cfg,
+		exact: make(map[digest]*group),
+		near:  make(map[digest]*group),
+		h:     fnv.New128a(),
+	}
+}
+
+// Add checks the rows of rec, which continue those of earlier calls.
+func (f *Finder) Add(rec arrow.Record) error {
+	all := make([]int, rec.NumCols())
+	for i := range all {
+		all[i] = i
+	}
+	near := all
+	if len(f.cfg.Columns) > 0 {
+		near = make([]int, len(f.cfg.Columns))
+		for i, name := range f.cfg.Columns {
+			idx := rec.Schema().FieldIndices(name)
+			if len(idx) == 0 {
+				return fmt.Errorf("column %s not found", name)
+			}
+			near[i] = idx[0]
+		}
+	}
+
+	for i := 0; i < int(rec.NumRows()); i++ {
+		row := f.rows + int64(i)
+		eh := f.digest(rec, all, i, false)
+		g := f.exact[eh]
+		isNew := g == nil
+		if isNew {
+			f.exact[eh] = &group{count: 1, first: row}
+		} else {
+			if g.count == 1 {
+				g.examples = append(g.examples, Example{Row: g.first, Values: render(rec, all, i)})
+			}
+			g.count++
+			f.exactDups++
+			if len(g.examples) < f.cfg.MaxExamples {
+				g.examples = append(g.examples, Example{Row: row, Values: g.examples[0].Values})
+			}
+		}
+
+		nh := f.digest(rec, near, i, true)
+		ng := f.near[nh]
+		if ng == nil {
+			f.near[nh] = &group{count: 1, first: row, examples: []Example{{Row: row, Values: render(rec, near, i)}}}
+			continue
+		}
+		ng.count++
+		if isNew {
+			ng.variants++
+			f.nearDups++
+			if len(ng.examples) < f.cfg.MaxExamples {
+				ng.examples = append(ng.examples, Example{Row: row, Values: render(rec, near, i)})
+			}
+		}
+	}
+	f.rows += rec.NumRows()
+	return nil
+}
+
+// Report returns the duplicates found so far.
+func (f *Finder) Report() Report {
+	r := Report{
+		Rows:            f.rows,
+		ExactDuplicates: f.exactDups,
+		NearDuplicates:  f.nearDups,
+		NearColumns:     f.cfg.Columns,
+	}
+	var exact, near []*group
+	for _, g := range f.exact {
+		if g.count > 1 {
+			exact = append(exact, g)
+		}
+	}
+	for _, g := range f.near {
+		if g.variants > 0 {
+			near = append(near, g)
+		}
+	}
+	r.ExactGroups, r.NearGroups = len(exact), len(near)
+	r.Exact = f.largest(exact)
+	r.Near = f.largest(near)
+	return r
+}
+
+// largest returns the MaxGroups groups with the most rows, earliest first
+// among equals.
+func (f *Finder) largest(groups []*group) []Group {
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].count != groups[j].count {
+			return groups[i].count > groups[j].count
+		}
+		return groups[i].first < groups[j].first
+	})
+	if len(groups) > f.cfg.MaxGroups {
+		groups = groups[:f.cfg.MaxGroups]
+	}
+	out := make([]Group, len(groups))
+	for i, g := range groups {
+		out[i] = Group{Count: g.count, Examples: g.examples}
+	}
+	return out
+}
+
+// digest hashes row i of the given columns, normalized for near-duplicates
+// when normalize is set. Values are length-prefixed so that adjacent columns
+// cannot run together, and nulls are distinct from empty strings.
+func (f *Finder) digest(rec arrow.Record, cols []int, i int, normalize bool) digest {
+	f.h.Reset()
+	for _, c := range cols {
+		col := rec.Column(c)
+		f.buf = f.buf[:0]
+		if col.IsNull(i) {
+			f.buf = append(f.buf, 0)
+		} else {
+			s := col.ValueStr(i)
+			if normalize {
+				s = f.normalize(col, i, s)
+			}
+			f.buf = append(f.buf, 1)
+			f.buf = binary.AppendUvarint(f.buf, uint64(len(s)))
+			f.buf = append(f.buf, s...)
+		}
+		f.h.Write(f.buf)
+	}
+	var d digest
+	f.h.Sum(d[:0])
+	return d
+}
+
+// normalize returns the near-duplicate form of value i of col, whose string
+// form is s.
+func (f *Finder) normalize(col arrow.Array, i int, s string) string {
+	switch col := col.(type) {
+	case *array.Float64:
+		return strconv.FormatFloat(col.Value(i), 'g', f.cfg.Digits, 64)
+	case *array.Float32:
+		return strconv.FormatFloat(float64(col.Value(i)), 'g', f.cfg.Digits, 32)
+	case *array.String, *array.LargeString, *array.StringView, *array.Dictionary:
+		s = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) {
+				return -1
+			}
+			return unicode.ToLower(r)
+		}, s)
+		return strings.Join(strings.Fields(s), " ")
+	}
+	return s
+}
+
+// render formats row i of the given columns as name=value pairs.
+func render(rec arrow.Record, cols []int, i int) string {
+	var b strings.Builder
+	for j, c := range cols {
+		if j > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(rec.Schema().Field(c).Name)
+		b.WriteByte('=')
+		if col := rec.Column(c); col.IsNull(i) {
+			b.WriteString("null")
+		} else {
+			b.WriteString(col.ValueStr(i))
+		}
+	}
+	return b.String()
+}
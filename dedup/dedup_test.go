@@ -0,0 +1,140 @@
+package dedup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+var schema = arrow.NewSchema([]arrow.Field{
+	{Name: "name", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "amount", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+}, nil)
+
+// find adds each batch of rows, a JSON array decoded with schema, to a Finder
+// for cfg and returns its report.
+func find(t *testing.T, cfg Config, batches ...string) Report {
+	t.Helper()
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	f := NewFinder(cfg)
+	for _, rows := range batches {
+		rec, _, err := array.RecordFromJSON(pool, schema, strings.NewReader(rows))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = f.Add(rec)
+		rec.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f.Report()
+}
+
+func TestExactDuplicates(t *testing.T) {
+	// Rows 0, 2 and 4 are identical, across batches; row 3 differs from
+	// them only in case, so is a near duplicate.
+	r := find(t, Config{},
+		`[{"name": "Ana", "amount": 1.5}, {"name": "Bo", "amount": 2}, {"name": "Ana", "amount": 1.5}]`,
+		`[{"name": "ana", "amount": 1.5}, {"name": "Ana", "amount": 1.5}]`,
+	)
+	want := Report{
+		Rows: 5, ExactDuplicates: 2, ExactGroups: 1, NearDuplicates: 1, NearGroups: 1,
+		Exact: []Group{{Count: 3, Examples: []Example{
+			{Row: 0, Values: "name=Ana, amount=1.5"},
+			{Row: 2, Values: "name=Ana, amount=1.5"},
+			{Row: 4, Values: "name=Ana, amount=1.5"},
+		}}},
+		Near: []Group{{Count: 4, Examples: []Example{
+			{Row: 0, Values: "name=Ana, amount=1.5"},
+			{Row: 3, Values: "name=ana, amount=1.5"},
+		}}},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("got  %+v\nwant %+v", r, want)
+	}
+}
+
+func TestDigestBoundaries(t *testing.T) {
+	// Values must not run together across columns, and a null is not an
+	// empty string.
+	r := find(t, Config{}, `[
+		{"name": "ab", "amount": 1},
+		{"name": "a", "amount": 1},
+		{"name": "", "amount": 1},
+		{"name": null, "amount": 1},
+		{"name": "ab", "amount": null}
+	]`)
+	if r.ExactDuplicates != 0 || r.NearDuplicates != 0 {
+		t.Errorf("got %d exact and %d near duplicates, want none", r.ExactDuplicates, r.NearDuplicates)
+	}
+}
+
+func TestNearDuplicates(t *testing.T) {
+	// Punctuation, case and spacing are ignored in strings, and floats are
+	// compared at Digits significant digits.
+	r := find(t, Config{Columns: []string{"name", "amount"}, Digits: 3}, `[
+		{"name": "O'Brien,  Pat", "amount": 10.01},
+		{"name": "obrien pat", "amount": 10.04},
+		{"name": "OBRIEN PAT!", "amount": 10.0},
+		{"name": "obrien pat", "amount": 10.6}
+	]`)
+	if r.NearDuplicates != 2 || r.NearGroups != 1 || r.Near[0].Count != 3 {
+		t.Fatalf("got %+v", r)
+	}
+	if !reflect.DeepEqual(r.NearColumns, []string{"name", "amount"}) {
+		t.Errorf("near columns = %v", r.NearColumns)
+	}
+
+	// A subset of columns matches rows that differ elsewhere.
+	r = find(t, Config{Columns: []string{"name"}}, `[
+		{"name": "Pat", "amount": 1},
+		{"name": "pat.", "amount": 2},
+		{"name": "Pat", "amount": 1}
+	]`)
+	// Row 2 is an exact duplicate of row 0, so counts only as that.
+	if r.ExactDuplicates != 1 || r.NearDuplicates != 1 || r.Near[0].Count != 3 {
+		t.Errorf("got %+v", r)
+	}
+	if got := r.Near[0].Examples; len(got) != 2 || got[1] != (Example{Row: 1, Values: "name=pat."}) {
+		t.Errorf("near examples = %+v", got)
+	}
+}
+
+func TestReportLimits(t *testing.T) {
+	// Groups are reported largest first, earliest among equals, up to
+	// MaxGroups, with at most MaxExamples rows each.
+	r := find(t, Config{MaxGroups: 2, MaxExamples: 2}, `[
+		{"name": "a", "amount": 1}, {"name": "b", "amount": 1}, {"name": "c", "amount": 1},
+		{"name": "c", "amount": 1}, {"name": "b", "amount": 1}, {"name": "c", "amount": 1},
+		{"name": "a", "amount": 1}, {"name": "d", "amount": 1}, {"name": "d", "amount": 1}
+	]`)
+	if r.ExactGroups != 4 || r.ExactDuplicates != 5 || len(r.Exact) != 2 {
+		t.Fatalf("got %+v", r)
+	}
+	if c := r.Exact[0]; c.Count != 3 || len(c.Examples) != 2 || c.Examples[0].Row != 2 || c.Examples[1].Row != 3 {
+		t.Errorf("largest group = %+v", c)
+	}
+	if a := r.Exact[1]; a.Count != 2 || a.Examples[0].Row != 0 {
+		t.Errorf("second group = %+v, want the a rows", a)
+	}
+}
+
+func TestMissingColumn(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	rec, _, err := array.RecordFromJSON(pool, schema, strings.NewReader(`[{"name": "a", "amount": 1}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	if err := NewFinder(Config{Columns: []string{"email"}}).Add(rec); err == nil {
+		t.Error("want error")
+	}
+}
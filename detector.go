@@ -0,0 +1,17 @@
+package supercharged
+
+import (
+	"context"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// Detector flags anomalous values in an Arrow array. Implementations return a
+// Result whose Mask and Zscore arrays are aligned with the input; callers must
+// Release the Result. Arrays are allocated from compute.GetAllocator(ctx), so
+// compute.WithAllocator controls where detection memory comes from.
+type Detector interface {
+	Detect(ctx context.Context, col arrow.Array) (*Result, error)
+}
+
+var _ Detector = ZScoreDetector{}
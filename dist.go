@@ -0,0 +1,163 @@
+package supercharged
+
+import "math"
+
+// studentTCDF returns P(T <= t) for Student's t distribution with v degrees
+// of freedom.
+func studentTCDF(t, v float64) float64 {
+	x := v / (v + t*t)
+	tail := 0.5 * regIncBeta(v/2, 0.5, x)
+	if t > 0 {
+		return 1 - tail
+	}
+	return tail
+}
+
+// studentTQuantile returns the t with studentTCDF(t, v) = p, by bisection.
+func studentTQuantile(p, v float64) float64 {
+	lo, hi := -1.0, 1.0
+	for studentTCDF(lo, v) > p {
+		lo *= 2
+	}
+	for studentTCDF(hi, v) < p {
+		hi *= 2
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12*math.Max(1, math.Abs(lo)); i++ {
+		mid := (lo + hi) / 2
+		if studentTCDF(mid, v) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// regIncBeta is the regularized incomplete beta function I_x(a, b), evaluated
+// with Lentz's continued fraction (Numerical Recipes, 6.4).
+func regIncBeta(a, b, x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 1:
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x > (a+1)/(a+b+2) {
+		return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+	}
+	return front * betaContinuedFraction(a, b, x) / a
+}
+
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		tiny = 1e-300
+		eps  = 1e-15
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	f := d
+	for m := 1; m <= 300; m++ {
+		fm := float64(m)
+		num := fm * (b - fm) * x / ((a + 2*fm - 1) * (a + 2*fm))
+		for i := 0; i < 2; i++ {
+			d = 1 + num*d
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = 1 + num/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			f *= d * c
+			num = -(a + fm) * (a + b + fm) * x / ((a + 2*fm) * (a + 2*fm + 1))
+		}
+		if math.Abs(d*c-1) < eps {
+			break
+		}
+	}
+	return f
+}
+
+// kolmogorovQ returns the asymptotic probability that the two-sample
+// Kolmogorov-Smirnov statistic reaches d for effective sample size ne =
+// n*m/(n+m), with Stephens' small-sample correction (Numerical Recipes,
+// 14.3).
+func kolmogorovQ(d, ne float64) float64 {
+	sq := math.Sqrt(ne)
+	lambda := (sq + 0.12 + 0.11/sq) * d
+	if lambda < 0.2 {
+		return 1
+	}
+	var sum float64
+	sign := 1.0
+	for k := 1; k <= 100; k++ {
+		term := sign * 2 * math.Exp(-2*float64(k*k)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Min(1, math.Max(0, sum))
+}
+
+// chiSquareSF returns P(X >= x) for the chi-square distribution with k
+// degrees of freedom.
+func chiSquareSF(x float64, k int) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return regUpperGamma(float64(k)/2, x/2)
+}
+
+// regUpperGamma is the regularized upper incomplete gamma function Q(a, x),
+// evaluated by its series for x < a+1 and by Lentz's continued fraction
+// otherwise (Numerical Recipes, 6.2).
+func regUpperGamma(a, x float64) float64 {
+	const (
+		tiny = 1e-300
+		eps  = 1e-15
+	)
+	lg, _ := math.Lgamma(a)
+	front := math.Exp(-x + a*math.Log(x) - lg)
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n <= 500; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return math.Max(0, 1-front*sum)
+	}
+	b := x + 1 - a
+	c, d := 1/tiny, 1/b
+	f := d
+	for i := 1; i <= 500; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		f *= d * c
+		if math.Abs(d*c-1) < eps {
+			break
+		}
+	}
+	return front * f
+}
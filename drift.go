@@ -0,0 +1,221 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// DefaultDriftBins is the number of reference quantile bins PSI and
+// Jensen-Shannon divergence are computed over when Bins is zero.
+const DefaultDriftBins = 10
+
+// DefaultMaxPSI is the population stability index above which a column is
+// reported as drifted when MaxPSI is zero; 0.2 is the customary boundary of
+// a significant shift.
+const DefaultMaxPSI = 0.2
+
+// DefaultMaxJS is the Jensen-Shannon divergence, in bits, above which a
+// column is reported as drifted when MaxJS is zero.
+const DefaultMaxJS = 0.1
+
+// Drift compares the distribution of a column in a reference dataset with
+// that in a current one.
+type Drift struct {
+	Column         string `json:"column"`
+	ReferenceCount int64  `json:"reference_count"`
+	CurrentCount   int64  `json:"current_count"`
+	// KS is the two-sample Kolmogorov-Smirnov statistic, the largest gap
+	// between the two empirical distribution functions, and PValue its
+	// asymptotic p-value.
+	KS     float64 `json:"ks"`
+	PValue float64 `json:"p_value"`
+	// PSI is the population stability index and JS the Jensen-Shannon
+	// divergence in bits (between 0 and 1), both over bins at reference
+	// quantiles.
+	PSI     float64 `json:"psi"`
+	JS      float64 `json:"js"`
+	Drifted bool    `json:"drifted"`
+}
+
+// DriftDetector compares distributions across two datasets. A column has
+// drifted when the KS p-value is below Alpha, or PSI or Jensen-Shannon
+// divergence exceed MaxPSI or MaxJS. On large inputs the KS test finds even
+// negligible shifts significant, so PSI and JS are the better guide to how
+// far a column has moved.
+type DriftDetector struct {
+	// Bins is the number of reference quantile bins; zero means
+	// DefaultDriftBins.
+	Bins int
+	// Alpha is the KS significance level; zero means DefaultAlpha.
+	Alpha float64
+	// MaxPSI and MaxJS bound the divergences; zero means DefaultMaxPSI and
+	// DefaultMaxJS.
+	MaxPSI float64
+	MaxJS  float64
+}
+
+// Compare compares the numeric column ref with cur. Nulls, and non-finite
+// values under the context's policy, are left out.
+func (d DriftDetector) Compare(ctx context.Context, ref, cur arrow.Array) (*Drift, error) {
+	refVals, err := driftValues(ctx, ref)
+	if err != nil {
+		return nil, fmt.Errorf("reference: %w", err)
+	}
+	curVals, err := driftValues(ctx, cur)
+	if err != nil {
+		return nil, fmt.Errorf("current: %w", err)
+	}
+	if len(refVals) == 0 || len(curVals) == 0 {
+		return nil, fmt.Errorf("drift needs values in both datasets")
+	}
+	sort.Float64s(refVals)
+	sort.Float64s(curVals)
+
+	bins, alpha, maxPSI, maxJS := d.Bins, d.Alpha, d.MaxPSI, d.MaxJS
+	if bins <= 0 {
+		bins = DefaultDriftBins
+	}
+	if alpha == 0 {
+		alpha = DefaultAlpha
+	}
+	if maxPSI == 0 {
+		maxPSI = DefaultMaxPSI
+	}
+	if maxJS == 0 {
+		maxJS = DefaultMaxJS
+	}
+
+	out := &Drift{ReferenceCount: int64(len(refVals)), CurrentCount: int64(len(curVals))}
+	out.KS = ksStatistic(refVals, curVals)
+	n, m := float64(len(refVals)), float64(len(curVals))
+	out.PValue = kolmogorovQ(out.KS, n*m/(n+m))
+
+	edges := quantileEdges(refVals, bins)
+	p, q := binShares(refVals, edges), binShares(curVals, edges)
+	out.PSI = psi(p, q)
+	out.JS = jensenShannon(p, q)
+	out.Drifted = out.PValue < alpha || out.PSI > maxPSI || out.JS > maxJS
+	return out, nil
+}
+
+// CompareRecords compares each of columns between ref and cur, or every
+// numeric column the two share when columns is empty, in schema order.
+func (d DriftDetector) CompareRecords(ctx context.Context, ref, cur arrow.Record, columns []string) ([]Drift, error) {
+	if len(columns) == 0 {
+		for _, f := range ref.Schema().Fields() {
+			if !isNumericType(f.Type) {
+				continue
+			}
+			if idx := cur.Schema().FieldIndices(f.Name); len(idx) > 0 && isNumericType(cur.Schema().Field(idx[0]).Type) {
+				columns = append(columns, f.Name)
+			}
+		}
+		if len(columns) == 0 {
+			return nil, fmt.Errorf("no numeric columns in common")
+		}
+	}
+	out := make([]Drift, 0, len(columns))
+	for _, name := range columns {
+		ridx := ref.Schema().FieldIndices(name)
+		if len(ridx) == 0 {
+			return nil, fmt.Errorf("column %s not found in reference", name)
+		}
+		cidx := cur.Schema().FieldIndices(name)
+		if len(cidx) == 0 {
+			return nil, fmt.Errorf("column %s not found in current", name)
+		}
+		drift, err := d.Compare(ctx, ref.Column(ridx[0]), cur.Column(cidx[0]))
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", name, err)
+		}
+		drift.Column = name
+		out = append(out, *drift)
+	}
+	return out, nil
+}
+
+// driftValues returns the non-null float64 values of col.
+func driftValues(ctx context.Context, col arrow.Array) ([]float64, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	return nonNullValues(floatCol), nil
+}
+
+// ksStatistic returns the largest distance between the empirical
+// distribution functions of two sorted samples.
+func ksStatistic(a, b []float64) float64 {
+	var i, j int
+	var d float64
+	n, m := float64(len(a)), float64(len(b))
+	for i < len(a) && j < len(b) {
+		x := math.Min(a[i], b[j])
+		for i < len(a) && a[i] == x {
+			i++
+		}
+		for j < len(b) && b[j] == x {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/n-float64(j)/m))
+	}
+	return d
+}
+
+// quantileEdges returns the distinct inner edges of bins equally populated
+// by the sorted reference values.
+func quantileEdges(sorted []float64, bins int) []float64 {
+	var edges []float64
+	for i := 1; i < bins; i++ {
+		e := quantileSorted(sorted, float64(i)/float64(bins))
+		if len(edges) == 0 || e > edges[len(edges)-1] {
+			edges = append(edges, e)
+		}
+	}
+	return edges
+}
+
+// binShares returns the share of sorted values in each bin, where bin k
+// holds the values in (edges[k-1], edges[k]].
+func binShares(sorted, edges []float64) []float64 {
+	shares := make([]float64, len(edges)+1)
+	for _, v := range sorted {
+		shares[sort.SearchFloat64s(edges, v)]++
+	}
+	for k := range shares {
+		shares[k] /= float64(len(sorted))
+	}
+	return shares
+}
+
+// psi returns the population stability index of q against p. Empty bins
+// are given a small share so that the index stays finite.
+func psi(p, q []float64) float64 {
+	const floor = 1e-4
+	var s float64
+	for k := range p {
+		pk, qk := math.Max(p[k], floor), math.Max(q[k], floor)
+		s += (qk - pk) * math.Log(qk/pk)
+	}
+	return s
+}
+
+// jensenShannon returns the Jensen-Shannon divergence of p and q in bits.
+func jensenShannon(p, q []float64) float64 {
+	var s float64
+	for k := range p {
+		m := (p[k] + q[k]) / 2
+		if p[k] > 0 {
+			s += p[k] * math.Log2(p[k]/m) / 2
+		}
+		if q[k] > 0 {
+			s += q[k] * math.Log2(q[k]/m) / 2
+		}
+	}
+	return s
+}
@@ -0,0 +1,98 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestKSStatistic(t *testing.T) {
+	if d := ksStatistic([]float64{1, 2, 3, 4}, []float64{3, 4, 5, 6}); d != 0.5 {
+		t.Errorf("ks = %v, want 0.5", d)
+	}
+	if d := ksStatistic([]float64{1, 2, 2, 3}, []float64{1, 2, 2, 3}); d != 0 {
+		t.Errorf("ks of identical samples = %v, want 0", d)
+	}
+	// The 5% critical value of the Kolmogorov distribution.
+	if p := kolmogorovQ(1.358/math.Sqrt(1e6), 1e6); math.Abs(p-0.05) > 0.001 {
+		t.Errorf("p = %v, want 0.05", p)
+	}
+}
+
+func TestDriftDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	rng := rand.New(rand.NewSource(1))
+	sample := func(mean float64) arrow.Array {
+		b := array.NewFloat64Builder(pool)
+		defer b.Release()
+		for i := 0; i < 2000; i++ {
+			b.Append(mean + rng.NormFloat64())
+		}
+		return b.NewArray()
+	}
+	ref, same, shifted := sample(0), sample(0), sample(1)
+	defer ref.Release()
+	defer same.Release()
+	defer shifted.Release()
+
+	ctx := context.Background()
+	d, err := DriftDetector{}.Compare(ctx, ref, same)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Drifted || d.PSI > 0.05 || d.JS > 0.01 {
+		t.Errorf("same distribution reported as drifted: %+v", d)
+	}
+	d, err = DriftDetector{}.Compare(ctx, ref, shifted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !d.Drifted || d.PValue > 1e-6 || d.PSI < DefaultMaxPSI || d.JS < DefaultMaxJS {
+		t.Errorf("shifted distribution not reported as drifted: %+v", d)
+	}
+}
+
+func TestDriftRecords(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "name", Type: arrow.BinaryTypes.String},
+		{Name: "a", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "b", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	build := func(offset float64) arrow.Record {
+		b := array.NewRecordBuilder(pool, schema)
+		defer b.Release()
+		for i := 0; i < 500; i++ {
+			b.Field(0).(*array.StringBuilder).Append("x")
+			b.Field(1).(*array.Int64Builder).Append(int64(i % 50))
+			b.Field(2).(*array.Float64Builder).Append(float64(i%50) + offset)
+		}
+		return b.NewRecord()
+	}
+	ref, cur := build(0), build(20)
+	defer ref.Release()
+	defer cur.Release()
+
+	drifts, err := DriftDetector{}.CompareRecords(context.Background(), ref, cur, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 2 || drifts[0].Column != "a" || drifts[1].Column != "b" {
+		t.Fatalf("drifts = %+v, want columns a and b", drifts)
+	}
+	if drifts[0].Drifted || !drifts[1].Drifted {
+		t.Errorf("drifted = %v, %v; want false, true", drifts[0].Drifted, drifts[1].Drifted)
+	}
+	if _, err := (DriftDetector{}).CompareRecords(context.Background(), ref, cur, []string{"c"}); err == nil {
+		t.Errorf("expected error for a missing column")
+	}
+}
@@ -0,0 +1,61 @@
+package supercharged
+
+import (
+	"math"
+	"time"
+)
+
+// Episode is a run of flagged rows close enough together to be one
+// incident, such as a spike spanning several samples.
+type Episode struct {
+	// Start and End are the first and last flagged rows; Count is the
+	// number of rows flagged between them, inclusive.
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Count int64 `json:"count"`
+	// Peak is the row with the largest absolute score, and PeakValue and
+	// PeakScore its value and score.
+	Peak      int64   `json:"peak"`
+	PeakValue float64 `json:"peak_value"`
+	PeakScore float64 `json:"peak_score"`
+	// From and To are the times of Start and End, for points placed in
+	// time.
+	From time.Time `json:"from,omitzero"`
+	To   time.Time `json:"to,omitzero"`
+}
+
+// GroupEpisodes groups points, flagged rows in ascending row order as
+// Result.Points returns them, into episodes: a point joins the episode of
+// the one before it when at most gap unflagged rows lie between them, so a
+// gap of zero groups only adjacent rows.
+func GroupEpisodes(points []Point, gap int64) []Episode {
+	var episodes []Episode
+	for _, p := range points {
+		if n := len(episodes); n > 0 && p.Row-episodes[n-1].End-1 <= gap {
+			e := &episodes[n-1]
+			e.End, e.To = p.Row, p.Time
+			e.Count++
+			if math.Abs(p.Score) > math.Abs(e.PeakScore) {
+				e.Peak, e.PeakValue, e.PeakScore = p.Row, p.Value, p.Score
+			}
+			continue
+		}
+		episodes = append(episodes, Episode{
+			Start: p.Row, End: p.Row, Count: 1,
+			Peak: p.Row, PeakValue: p.Value, PeakScore: p.Score,
+			From: p.Time, To: p.Time,
+		})
+	}
+	return episodes
+}
+
+// Episodes groups the flagged rows of r into episodes as GroupEpisodes
+// does, without times; use GroupEpisodes on Points to place them in time.
+func (r *Result) Episodes(gap int64) []Episode {
+	points := make([]Point, r.Indices.Len())
+	for i := range points {
+		row := r.Indices.Value(i)
+		points[i] = Point{Row: row, Value: r.Values.Value(i), Score: r.Zscore.Value(int(row))}
+	}
+	return GroupEpisodes(points, gap)
+}
@@ -0,0 +1,77 @@
+package supercharged
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestGroupEpisodes(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(row int64) time.Time { return t0.Add(time.Duration(row) * time.Minute) }
+	points := []Point{
+		{Row: 10, Time: at(10), Value: 50, Score: 4},
+		{Row: 11, Time: at(11), Value: -90, Score: -7},
+		{Row: 12, Time: at(12), Value: 60, Score: 5},
+		{Row: 15, Time: at(15), Value: 55, Score: 4.5},
+		{Row: 40, Time: at(40), Value: 70, Score: 6},
+	}
+
+	got := GroupEpisodes(points, 0)
+	want := []Episode{
+		{Start: 10, End: 12, Count: 3, Peak: 11, PeakValue: -90, PeakScore: -7, From: at(10), To: at(12)},
+		{Start: 15, End: 15, Count: 1, Peak: 15, PeakValue: 55, PeakScore: 4.5, From: at(15), To: at(15)},
+		{Start: 40, End: 40, Count: 1, Peak: 40, PeakValue: 70, PeakScore: 6, From: at(40), To: at(40)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gap 0:\n got %+v\nwant %+v", got, want)
+	}
+
+	// Rows 13 and 14 are unflagged, so a gap of 2 joins row 15.
+	got = GroupEpisodes(points, 2)
+	if len(got) != 2 || got[0].End != 15 || got[0].Count != 4 || got[0].To != at(15) || got[0].Peak != 11 {
+		t.Errorf("gap 2: got %+v", got)
+	}
+	if got := GroupEpisodes(nil, 0); got != nil {
+		t.Errorf("no points: got %+v", got)
+	}
+}
+
+func TestResultEpisodes(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	for i := 0; i < 200; i++ {
+		v := float64(i % 5)
+		if i >= 100 && i < 103 {
+			v = 100 + float64(i)
+		}
+		b.Append(v)
+	}
+	col := b.NewFloat64Array()
+	defer col.Release()
+	res, err := DetectAnomalies(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	got := res.Episodes(0)
+	if len(got) != 1 || got[0].Start != 100 || got[0].End != 102 || got[0].Count != 3 {
+		t.Fatalf("episodes = %+v, want one of rows 100-102", got)
+	}
+	if got[0].Peak != 102 || got[0].PeakValue != 202 || got[0].PeakScore != res.Zscore.Value(102) {
+		t.Errorf("peak = row %d value %v score %v, want row 102", got[0].Peak, got[0].PeakValue, got[0].PeakScore)
+	}
+	if !got[0].From.IsZero() {
+		t.Errorf("From = %v, want zero without times", got[0].From)
+	}
+}
@@ -0,0 +1,186 @@
+package supercharged
+
+import (
+	"context"
+	"math/big"
+	"math/bits"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+type exactIntegersKey struct{}
+
+// WithExactIntegers makes the z-score method compute the statistics and
+// scores of int64 and uint64 columns in integer arithmetic, so values beyond
+// 2^53, such as IDs or nanosecond timestamps, are not rounded by the float64
+// cast first. Each value's deviation from the mean is exact and rounded once
+// to float64; the flagged Values are still the rounded ones. It costs a
+// big.Int operation per value, and other methods and column types ignore
+// it. Result.Inexact counts the values it matters for either way.
+func WithExactIntegers(enabled bool) Option {
+	return func(o *options) { o.exactIntegers = enabled }
+}
+
+// ContextWithExactIntegers returns a copy of ctx carrying the
+// WithExactIntegers setting.
+func ContextWithExactIntegers(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, exactIntegersKey{}, enabled)
+}
+
+func exactIntegersFrom(ctx context.Context) bool {
+	enabled, _ := ctx.Value(exactIntegersKey{}).(bool)
+	return enabled
+}
+
+// wideInt reports whether t is an integer type with values float64 cannot
+// hold exactly.
+func wideInt(t arrow.DataType) bool {
+	return t.ID() == arrow.INT64 || t.ID() == arrow.UINT64
+}
+
+// exactFloat reports whether float64 holds u exactly: its significant bits
+// fit the 53-bit mantissa.
+func exactFloat(u uint64) bool {
+	return u == 0 || bits.Len64(u)-bits.TrailingZeros64(u) <= 53
+}
+
+// magnitude returns |v|, which for math.MinInt64 only fits a uint64.
+func magnitude(v int64) uint64 {
+	if v < 0 {
+		return -uint64(v)
+	}
+	return uint64(v)
+}
+
+// inexactInts counts the valid values of col that the float64 cast rounds;
+// only int64 and uint64 columns have any.
+func inexactInts(col arrow.Array) int64 {
+	var n int64
+	switch c := col.(type) {
+	case *array.Int64:
+		for i, v := range c.Int64Values() {
+			if c.IsValid(i) && !exactFloat(magnitude(v)) {
+				n++
+			}
+		}
+	case *array.Uint64:
+		for i, v := range c.Uint64Values() {
+			if c.IsValid(i) && !exactFloat(v) {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// setValue sets x to row i of col, an int64 or uint64 column.
+func setValue(x *big.Int, col arrow.Array, i int) {
+	switch c := col.(type) {
+	case *array.Int64:
+		x.SetInt64(c.Value(i))
+	case *array.Uint64:
+		x.SetUint64(c.Value(i))
+	}
+}
+
+// exactSums holds the count, sum and sum of squares of the valid values of
+// int64 or uint64 columns, without rounding.
+type exactSums struct {
+	n          int64
+	sum, sumsq big.Int
+}
+
+func (s *exactSums) add(col arrow.Array) {
+	var x big.Int
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			continue
+		}
+		setValue(&x, col, i)
+		s.n++
+		s.sum.Add(&s.sum, &x)
+		s.sumsq.Add(&s.sumsq, x.Mul(&x, &x))
+	}
+}
+
+func (s *exactSums) merge(o *exactSums) {
+	s.n += o.n
+	s.sum.Add(&s.sum, &o.sum)
+	s.sumsq.Add(&s.sumsq, &o.sumsq)
+}
+
+// sumExact is exactSums over cols, one worker per column.
+func sumExact(ctx context.Context, cols []arrow.Array) *exactSums {
+	partial := make([]exactSums, len(cols))
+	_ = parallelFor(parallelismFrom(ctx), len(cols), func(i int) error {
+		partial[i].add(cols[i])
+		return nil
+	})
+	s := &exactSums{}
+	for i := range partial {
+		s.merge(&partial[i])
+	}
+	return s
+}
+
+// stats returns the mean and M2 of the values summed, each rounded once to
+// float64. rows is the number of values scored, more than s.n when
+// NullPolicyImputeMean fills nulls in with the mean, which adds nothing to
+// M2.
+func (s *exactSums) stats(rows int64) runningStats {
+	if s.n == 0 {
+		return runningStats{n: rows}
+	}
+	n := big.NewInt(s.n)
+	mean, _ := new(big.Rat).SetFrac(&s.sum, n).Float64()
+	// M2 = sumsq - sum²/n = (n*sumsq - sum²) / n
+	var num, sq big.Int
+	num.Mul(n, &s.sumsq)
+	num.Sub(&num, sq.Mul(&s.sum, &s.sum))
+	m2, _ := new(big.Rat).SetFrac(&num, n).Float64()
+	return runningStats{n: rows, mean: mean, m2: m2}
+}
+
+// fitExact is ZScoreDetector.fit from sums, for columns where col is the
+// float64 cast of the data summed.
+func (d ZScoreDetector) fitExact(s *exactSums, rows int64) fitted {
+	stats := s.stats(rows)
+	return fitted{center: stats.mean, scale: stats.stdDev(), threshold: d.Threshold, stats: stats}
+}
+
+// scoreExact is scoreAgainst for orig, an int64 or uint64 column, fitted
+// from its exact sums s: each value's deviation from the mean is computed as
+// (n*x - sum) / n and rounded once, where the float path subtracts two
+// values already rounded. col is orig through toFloat64; rows it leaves null
+// get null scores, and nulls it filled in under NullPolicyImputeMean score
+// as the mean does. A zero scale fails with ErrZeroScale, as in
+// scoreAgainst.
+func scoreExact(ctx context.Context, orig arrow.Array, col *array.Float64, s *exactSums, fit fitted) (*Result, error) {
+	if err := checkScale(fit.scale); err != nil {
+		return nil, err
+	}
+	n := big.NewInt(s.n)
+	var num big.Int
+	var dev big.Rat
+	b := array.NewFloat64Builder(compute.GetAllocator(ctx))
+	defer b.Release()
+	b.Reserve(col.Len())
+	for i := 0; i < col.Len(); i++ {
+		switch {
+		case col.IsNull(i):
+			b.AppendNull()
+		case orig.IsNull(i):
+			b.Append(0 / fit.scale)
+		default:
+			setValue(&num, orig, i)
+			num.Sub(num.Mul(&num, n), &s.sum)
+			d, _ := dev.SetFrac(&num, n).Float64()
+			b.Append(d / fit.scale)
+		}
+	}
+	zscore := b.NewFloat64Array()
+	mask := thresholdMask(ctx, zscore, fit.threshold)
+	return newResult(ctx, col, mask, zscore, fit.stats)
+}
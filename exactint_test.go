@@ -0,0 +1,115 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestExactIntegers(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// IDs a few units apart above 2^60, where float64 values are 256 apart:
+	// cast, they are all equal. Exact scores are those of the offsets.
+	const base = int64(1) << 60
+	b := array.NewInt64Builder(pool)
+	defer b.Release()
+	offsets := array.NewFloat64Builder(pool)
+	defer offsets.Release()
+	var inexact int64
+	for i := 0; i < 200; i++ {
+		off := int64(i % 3)
+		if i == 150 {
+			off = 60
+		}
+		if off != 0 {
+			inexact++
+		}
+		b.Append(base + off)
+		offsets.Append(float64(off))
+	}
+	col := b.NewInt64Array()
+	defer col.Release()
+	shifted := offsets.NewFloat64Array()
+	defer shifted.Release()
+	want, err := DetectAnomalies(ctx, shifted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Release()
+
+	for _, opts := range [][]Option{{WithExactIntegers(true)}, {WithExactIntegers(true), WithParallelism(3)}} {
+		res, err := DetectAnomalies(ctx, col, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(res.AnomalousIndices(), []int64{150}) || res.Inexact != inexact {
+			t.Errorf("exact: indices = %v, inexact = %d; want [150], %d", res.AnomalousIndices(), res.Inexact, inexact)
+		}
+		if math.Abs(res.StdDev-want.StdDev) > 1e-12 || math.Abs(res.Zscore.Value(150)-want.Zscore.Value(150)) > 1e-12 {
+			t.Errorf("exact: sd = %v, score = %v; want %v, %v", res.StdDev, res.Zscore.Value(150), want.StdDev, want.Zscore.Value(150))
+		}
+		res.Release()
+	}
+
+	chunked := arrow.NewChunked(col.DataType(), []arrow.Array{array.NewSlice(col, 0, 100), array.NewSlice(col, 100, 200)})
+	for _, c := range chunked.Chunks() {
+		c.Release()
+	}
+	defer chunked.Release()
+	cres, err := DetectAnomaliesChunked(ctx, chunked, WithExactIntegers(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cres.Indices(), []int64{150}) {
+		t.Errorf("chunked: indices = %v, want [150]", cres.Indices())
+	}
+	cres.Release()
+}
+
+func TestExactIntegersZeroScale(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewInt64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]int64{1 << 60, 1 << 60, 1 << 60}, nil)
+	col := b.NewInt64Array()
+	defer col.Release()
+	if _, err := DetectAnomalies(ctx, col, WithExactIntegers(true)); !errors.Is(err, ErrZeroScale) {
+		t.Errorf("err = %v, want ErrZeroScale", err)
+	}
+}
+
+func TestExactSums(t *testing.T) {
+	b := array.NewUint64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.AppendValues([]uint64{math.MaxUint64, math.MaxUint64 - 2, 3}, []bool{true, true, true})
+	b.AppendNull()
+	col := b.NewUint64Array()
+	defer col.Release()
+
+	var s exactSums
+	s.add(col)
+	st := s.stats(3)
+	wantMean := (float64(math.MaxUint64)*2 + 3) / 3
+	if st.n != 3 || math.Abs(st.mean-wantMean)/wantMean > 1e-15 {
+		t.Errorf("n = %d, mean = %v; want 3, %v", st.n, st.mean, wantMean)
+	}
+	if got := inexactInts(col); got != 2 {
+		t.Errorf("inexact = %d, want 2", got)
+	}
+	if !exactFloat(magnitude(math.MinInt64)) || exactFloat(1<<53+1) || !exactFloat(1<<53) {
+		t.Error("exactFloat misjudges powers of two")
+	}
+}
@@ -0,0 +1,301 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/scalar"
+)
+
+// Expr is an arithmetic expression over the numeric columns of a record,
+// such as "bytes_out / bytes_in" or "abs(latency_p99 - latency_p50)", for
+// detecting anomalies in derived values. It supports + - * / and ^, unary
+// minus, parentheses, numbers, and the functions abs, sqrt, ln and log10.
+// Column names are identifiers (letters, digits, _ and .) or double-quoted
+// strings for any other name. Division by zero and logarithms of
+// non-positive values produce Inf or NaN, which the non-finite policy then
+// handles.
+type Expr struct {
+	src     string
+	root    exprNode
+	columns []string
+}
+
+// exprNode is a node of a parsed expression.
+type exprNode interface {
+	eval(ctx context.Context, rec arrow.Record) (compute.Datum, error)
+}
+
+type (
+	exprColumn string
+	exprNumber float64
+	exprCall   struct {
+		fn   string
+		args []exprNode
+	}
+)
+
+// exprFuncs maps expression functions to their Arrow compute kernels.
+var exprFuncs = map[string]string{
+	"abs":   "abs",
+	"sqrt":  "sqrt_unchecked",
+	"ln":    "ln_unchecked",
+	"log10": "log10_unchecked",
+}
+
+// exprOps maps binary operators to their Arrow compute kernels.
+var exprOps = map[byte]string{
+	'+': "add",
+	'-': "subtract",
+	'*': "multiply",
+	'/': "divide_unchecked",
+	'^': "power_unchecked",
+}
+
+// ParseExpr parses s into an Expr.
+func ParseExpr(s string) (*Expr, error) {
+	p := &exprParser{src: s, seen: map[string]bool{}}
+	root, err := p.parseSum()
+	if err == nil && p.skipSpace() < len(p.src) {
+		err = fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse expression %q: %w", s, err)
+	}
+	if len(p.columns) == 0 {
+		return nil, fmt.Errorf("parse expression %q: no columns referenced", s)
+	}
+	return &Expr{src: s, root: root, columns: p.columns}, nil
+}
+
+// String returns the expression as it was parsed.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Columns returns the names of the columns the expression reads, in order of
+// first use.
+func (e *Expr) Columns() []string {
+	return append([]string(nil), e.columns...)
+}
+
+// Eval evaluates the expression over rec, which must hold every column in
+// Columns, and returns one float64 value per row; rows where any input is
+// null are null. The caller must Release the result.
+func (e *Expr) Eval(ctx context.Context, rec arrow.Record) (*array.Float64, error) {
+	d, err := e.root.eval(ctx, rec)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate %q: %w", e.src, err)
+	}
+	defer d.Release()
+	// Every operation with a column operand yields an array, and ParseExpr
+	// requires a column, so the result is never a scalar.
+	return array.MakeFromData(d.(*compute.ArrayDatum).Value).(*array.Float64), nil
+}
+
+func (c exprColumn) eval(ctx context.Context, rec arrow.Record) (compute.Datum, error) {
+	idx := rec.Schema().FieldIndices(string(c))
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("column %s not found", string(c))
+	}
+	col, err := castFloat64(ctx, rec.Column(idx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", string(c), err)
+	}
+	defer col.Release()
+	return compute.NewDatum(col), nil
+}
+
+func (n exprNumber) eval(context.Context, arrow.Record) (compute.Datum, error) {
+	return compute.NewDatum(scalar.NewFloat64Scalar(float64(n))), nil
+}
+
+func (c exprCall) eval(ctx context.Context, rec arrow.Record) (compute.Datum, error) {
+	args := make([]compute.Datum, 0, len(c.args))
+	defer func() {
+		for _, a := range args {
+			a.Release()
+		}
+	}()
+	for _, n := range c.args {
+		d, err := n.eval(ctx, rec)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, d)
+	}
+	out, err := compute.CallFunction(ctx, c.fn, nil, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.fn, err)
+	}
+	return out, nil
+}
+
+// exprParser is a recursive-descent parser over the expression grammar:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = power { ("*" | "/") power }
+//	power   = unary [ "^" power ]
+//	unary   = "-" unary | primary
+//	primary = number | column | func "(" sum ")" | "(" sum ")"
+type exprParser struct {
+	src     string
+	pos     int
+	columns []string
+	seen    map[string]bool
+}
+
+// skipSpace advances past white space and returns the new position.
+func (p *exprParser) skipSpace() int {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.pos
+}
+
+// peek returns the next non-space byte, or 0 at the end.
+func (p *exprParser) peek() byte {
+	if p.skipSpace() == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *exprParser) parseSum() (exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '+' || op == '-'; op = p.peek() {
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = exprCall{fn: exprOps[op], args: []exprNode{left, right}}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseProduct() (exprNode, error) {
+	left, err := p.parsePower()
+	if err != nil {
+		return nil, err
+	}
+	for op := p.peek(); op == '*' || op == '/'; op = p.peek() {
+		p.pos++
+		right, err := p.parsePower()
+		if err != nil {
+			return nil, err
+		}
+		left = exprCall{fn: exprOps[op], args: []exprNode{left, right}}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parsePower() (exprNode, error) {
+	base, err := p.parseUnary()
+	if err != nil || p.peek() != '^' {
+		return base, err
+	}
+	p.pos++
+	exp, err := p.parsePower()
+	if err != nil {
+		return nil, err
+	}
+	return exprCall{fn: exprOps['^'], args: []exprNode{base, exp}}, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek() != '-' {
+		return p.parsePrimary()
+	}
+	p.pos++
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return exprCall{fn: "negate", args: []exprNode{operand}}, nil
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		n, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at offset %d", p.pos)
+		}
+		p.pos++
+		return n, nil
+	case c == '"':
+		end := strings.IndexByte(p.src[p.pos+1:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated column name at offset %d", p.pos)
+		}
+		name := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return p.column(name), nil
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (isDigit(p.src[p.pos]) || p.src[p.pos] == '.' ||
+			p.src[p.pos] == 'e' || p.src[p.pos] == 'E' ||
+			(p.pos > start && (p.src[p.pos] == '+' || p.src[p.pos] == '-') && (p.src[p.pos-1] == 'e' || p.src[p.pos-1] == 'E'))) {
+			p.pos++
+		}
+		v, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q", p.src[start:p.pos])
+		}
+		return exprNumber(v), nil
+	case isIdentStart(c):
+		start := p.pos
+		for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || isDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		name := p.src[start:p.pos]
+		if fn, ok := exprFuncs[name]; ok && p.peek() == '(' {
+			p.pos++
+			arg, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() != ')' {
+				return nil, fmt.Errorf("missing ) after %s argument", name)
+			}
+			p.pos++
+			return exprCall{fn: fn, args: []exprNode{arg}}, nil
+		}
+		return p.column(name), nil
+	}
+	return nil, fmt.Errorf("unexpected %q at offset %d", c, p.pos)
+}
+
+// column records a reference to the named column.
+func (p *exprParser) column(name string) exprNode {
+	if !p.seen[name] {
+		p.seen[name] = true
+		p.columns = append(p.columns, name)
+	}
+	return exprColumn(name)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
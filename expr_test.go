@@ -0,0 +1,68 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestExprEval(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "bytes_in", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "bytes out", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.Int64Builder).AppendValues([]int64{2, 4, 0}, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{4, 2, 1}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	tests := []struct {
+		expr string
+		want []float64
+	}{
+		{`"bytes out" / bytes_in`, []float64{2, 0.5, math.Inf(1)}},
+		{`-bytes_in + 2 * "bytes out"`, []float64{6, 0, 2}},
+		{`abs(bytes_in - "bytes out") ^ 2`, []float64{4, 4, 1}},
+		{`sqrt(bytes_in * 4)`, []float64{math.Sqrt(8), 4, 0}},
+	}
+	ctx := compute.WithAllocator(context.Background(), pool)
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := e.Eval(ctx, rec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		for i, want := range tt.want {
+			if got.Value(i) != want {
+				t.Errorf("%s row %d = %v, want %v", tt.expr, i, got.Value(i), want)
+			}
+		}
+		got.Release()
+	}
+
+	e, err := ParseExpr("bytes_in / 2 + bytes_in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols := e.Columns(); len(cols) != 1 || cols[0] != "bytes_in" {
+		t.Errorf("Columns() = %v", cols)
+	}
+	for _, bad := range []string{"", "bytes_in +", "(bytes_in", "2 * 3", `"open`} {
+		if _, err := ParseExpr(bad); err == nil {
+			t.Errorf("ParseExpr(%q): expected error", bad)
+		}
+	}
+}
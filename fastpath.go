@@ -0,0 +1,101 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/bitutil"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+type fastPathKey struct{}
+
+// ContextWithFastPath returns a copy of ctx under which scores and masks are
+// computed by a single loop over the value buffer instead of a chain of Arrow
+// compute kernels.
+func ContextWithFastPath(ctx context.Context, enabled bool) context.Context {
+	return context.WithValue(ctx, fastPathKey{}, enabled)
+}
+
+func fastPathFrom(ctx context.Context) bool {
+	enabled, _ := ctx.Value(fastPathKey{}).(bool)
+	return enabled
+}
+
+// scoreDirect is scoreAgainst as one pass over col: it writes the score and
+// mask buffers directly, skipping the intermediate differences and the
+// dispatch of the subtract and divide kernels. Null rows are null in both
+// outputs, as with the kernels.
+func scoreDirect(ctx context.Context, col *array.Float64, center, scale, threshold float64, stats runningStats) (*Result, error) {
+	mem := compute.GetAllocator(ctx)
+	n := col.Len()
+	values := col.Float64Values()
+
+	scores := memory.NewResizableBuffer(mem)
+	scores.Resize(arrow.Float64Traits.BytesRequired(n))
+	defer scores.Release()
+	z := arrow.Float64Traits.CastFromBytes(scores.Bytes())
+
+	flags := memory.NewResizableBuffer(mem)
+	flags.Resize(int(bitutil.BytesForBits(int64(n))))
+	defer flags.Release()
+	bits := flags.Bytes()
+	clear(bits)
+
+	var validity *memory.Buffer
+	if col.NullN() > 0 {
+		validity = memory.NewResizableBuffer(mem)
+		validity.Resize(int(bitutil.BytesForBits(int64(n))))
+		defer validity.Release()
+		clear(validity.Bytes())
+	}
+
+	for i, x := range values {
+		if validity != nil {
+			if col.IsNull(i) {
+				z[i] = 0
+				continue
+			}
+			bitutil.SetBit(validity.Bytes(), i)
+		}
+		s := (x - center) / scale
+		z[i] = s
+		if math.Abs(s) >= threshold {
+			bitutil.SetBit(bits, i)
+		}
+	}
+
+	nulls := col.NullN()
+	zdata := array.NewData(arrow.PrimitiveTypes.Float64, n, []*memory.Buffer{validity, scores}, nil, nulls, 0)
+	defer zdata.Release()
+	mdata := array.NewData(arrow.FixedWidthTypes.Boolean, n, []*memory.Buffer{validity, flags}, nil, nulls, 0)
+	defer mdata.Release()
+	return newResult(ctx, col, array.NewBooleanData(mdata), array.NewFloat64Data(zdata), stats)
+}
+
+// thresholdMask flags the values of z whose absolute value is at least
+// threshold. It is abs followed by greater_equal fused into one pass writing
+// the mask bits, so the absolute values are never materialized. The mask
+// shares z's validity bitmap, and with it z's offset.
+func thresholdMask(ctx context.Context, z *array.Float64, threshold float64) *array.Boolean {
+	data := z.Data()
+	offset := data.Offset()
+
+	flags := memory.NewResizableBuffer(compute.GetAllocator(ctx))
+	flags.Resize(int(bitutil.BytesForBits(int64(offset + z.Len()))))
+	defer flags.Release()
+	bits := flags.Bytes()
+	clear(bits)
+	for i, s := range z.Float64Values() {
+		if math.Abs(s) >= threshold {
+			bitutil.SetBit(bits, offset+i)
+		}
+	}
+
+	mdata := array.NewData(arrow.FixedWidthTypes.Boolean, z.Len(), []*memory.Buffer{data.Buffers()[0], flags}, nil, data.NullN(), offset)
+	defer mdata.Release()
+	return array.NewBooleanData(mdata)
+}
@@ -0,0 +1,128 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestFastPathMatchesCompute(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{1, 2, 2, 3, 2, 1, 40, 2, 3, 2, 1, 2}, nil)
+	b.AppendNull()
+	b.AppendValues([]float64{2, 3, -30}, nil)
+	full := b.NewFloat64Array()
+	defer full.Release()
+	// A slice exercises a non-zero array offset.
+	col := array.NewSlice(full, 1, int64(full.Len())).(*array.Float64)
+	defer col.Release()
+
+	for _, m := range []Method{MethodZScore, MethodMAD, MethodIQR, MethodPercentile} {
+		want, err := DetectAnomalies(ctx, col, WithMethod(m), WithThreshold(2), WithQuantile(0.9))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DetectAnomalies(ctx, col, WithMethod(m), WithThreshold(2), WithQuantile(0.9), WithFastPath(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < col.Len(); i++ {
+			if got.Mask.IsNull(i) != want.Mask.IsNull(i) || got.Zscore.IsNull(i) != want.Zscore.IsNull(i) {
+				t.Fatalf("%s: row %d: validity differs", m, i)
+			}
+			if want.Mask.IsNull(i) {
+				continue
+			}
+			if got.Mask.Value(i) != want.Mask.Value(i) {
+				t.Errorf("%s: row %d: flagged %t, want %t", m, i, got.Mask.Value(i), want.Mask.Value(i))
+			}
+			if math.Abs(got.Zscore.Value(i)-want.Zscore.Value(i)) > 1e-12 {
+				t.Errorf("%s: row %d: score %v, want %v", m, i, got.Zscore.Value(i), want.Zscore.Value(i))
+			}
+		}
+		if got.Indices.Len() != want.Indices.Len() || got.Nulls != want.Nulls {
+			t.Errorf("%s: %d flagged, %d nulls; want %d, %d", m, got.Indices.Len(), got.Nulls, want.Indices.Len(), want.Nulls)
+		}
+		want.Release()
+		got.Release()
+	}
+}
+
+// peakAllocator records the most memory it has had outstanding at once.
+type peakAllocator struct {
+	memory.Allocator
+	used, peak int
+}
+
+func (a *peakAllocator) Allocate(size int) []byte {
+	a.grow(size)
+	return a.Allocator.Allocate(size)
+}
+
+func (a *peakAllocator) Reallocate(size int, buf []byte) []byte {
+	a.grow(size - len(buf))
+	return a.Allocator.Reallocate(size, buf)
+}
+
+func (a *peakAllocator) Free(buf []byte) {
+	a.used -= len(buf)
+	a.Allocator.Free(buf)
+}
+
+func (a *peakAllocator) grow(n int) {
+	a.used += n
+	a.peak = max(a.peak, a.used)
+}
+
+func TestScoreAgainstPeakMemory(t *testing.T) {
+	const n = 100000
+	b := array.NewFloat64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	for i := 0; i < n; i++ {
+		b.Append(float64(i % 10))
+	}
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	mem := &peakAllocator{Allocator: memory.NewGoAllocator()}
+	ctx := compute.WithAllocator(context.Background(), mem)
+	res, err := scoreAgainst(ctx, col, 4.5, 2.9, 3, accumulate(col))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Release()
+	// The differences and the scores are the only float64 arrays alive at
+	// once; the mask takes one bit per row.
+	if limit := 2*n*arrow.Float64SizeBytes + n; mem.peak > limit {
+		t.Errorf("peak = %d bytes, want at most %d", mem.peak, limit)
+	}
+}
+
+func TestZeroScale(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{7, 7, 7, 7}, nil)
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	for _, fast := range []bool{false, true} {
+		if _, err := DetectAnomalies(ctx, col, WithFastPath(fast)); !errors.Is(err, ErrZeroScale) {
+			t.Errorf("fast path %v: err = %v, want ErrZeroScale", fast, err)
+		}
+	}
+}
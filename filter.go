@@ -0,0 +1,231 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/scalar"
+)
+
+// Filter is a row predicate over the columns of a record, such as
+// "region == 'us-east' && value > 0", for scoping detection to some rows.
+// Comparisons (== != < <= > >=) take arithmetic expressions as Expr parses
+// them, single-quoted strings, or columns of any comparable type, strings
+// and dictionaries included. They combine with && and ||, negate with !,
+// and group with parentheses; a boolean column can stand on its own. A
+// comparison with a null is null, and null rows are dropped along with the
+// false ones.
+type Filter struct {
+	src     string
+	root    exprNode
+	columns []string
+}
+
+type (
+	// exprRaw is a column compared as it is, without a cast to float64.
+	exprRaw string
+	// exprString is a string literal.
+	exprString string
+)
+
+// filterOps maps comparison operators to their Arrow compute kernels,
+// longest first so that <= is not read as <.
+var filterOps = []struct{ op, fn string }{
+	{"==", "equal"},
+	{"!=", "not_equal"},
+	{"<=", "less_equal"},
+	{">=", "greater_equal"},
+	{"<", "less"},
+	{">", "greater"},
+}
+
+// ParseFilter parses s into a Filter.
+func ParseFilter(s string) (*Filter, error) {
+	p := &exprParser{src: s, seen: map[string]bool{}}
+	root, err := p.parseOr()
+	if err == nil && p.skipSpace() < len(p.src) {
+		err = fmt.Errorf("unexpected %q", p.src[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse filter %q: %w", s, err)
+	}
+	if len(p.columns) == 0 {
+		return nil, fmt.Errorf("parse filter %q: no columns referenced", s)
+	}
+	return &Filter{src: s, root: root, columns: p.columns}, nil
+}
+
+// String returns the filter as it was parsed.
+func (f *Filter) String() string {
+	return f.src
+}
+
+// Columns returns the names of the columns the filter reads, in order of
+// first use.
+func (f *Filter) Columns() []string {
+	return append([]string(nil), f.columns...)
+}
+
+// Eval evaluates the filter over rec, which must hold every column in
+// Columns, and returns one boolean per row. The caller must Release the
+// result.
+func (f *Filter) Eval(ctx context.Context, rec arrow.Record) (*array.Boolean, error) {
+	d, err := f.root.eval(ctx, rec)
+	if err != nil {
+		return nil, fmt.Errorf("evaluate %q: %w", f.src, err)
+	}
+	defer d.Release()
+	ad, ok := d.(*compute.ArrayDatum)
+	if !ok || ad.Type().ID() != arrow.BOOL {
+		return nil, fmt.Errorf("evaluate %q: not a condition", f.src)
+	}
+	return array.MakeFromData(ad.Value).(*array.Boolean), nil
+}
+
+// Apply returns the rows of rec for which the filter holds. The caller must
+// Release the result.
+func (f *Filter) Apply(ctx context.Context, rec arrow.Record) (arrow.Record, error) {
+	mask, err := f.Eval(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	defer mask.Release()
+	return FilterRecord(ctx, rec, mask)
+}
+
+func (c exprRaw) eval(_ context.Context, rec arrow.Record) (compute.Datum, error) {
+	idx := rec.Schema().FieldIndices(string(c))
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("column %s not found", string(c))
+	}
+	return compute.NewDatum(rec.Column(idx[0])), nil
+}
+
+func (s exprString) eval(context.Context, arrow.Record) (compute.Datum, error) {
+	return compute.NewDatum(scalar.NewStringScalar(string(s))), nil
+}
+
+// The filter grammar extends the expression grammar:
+//
+//	or      = and { "||" and }
+//	and     = not { "&&" not }
+//	not     = "!" not | compare | "(" or ")"
+//	compare = operand [ op operand ]
+//	operand = "'" string "'" | sum
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = exprCall{fn: "or_kleene", args: []exprNode{left, right}}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("&&") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = exprCall{fn: "and_kleene", args: []exprNode{left, right}}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseNot() (exprNode, error) {
+	if p.peek() == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return exprCall{fn: "not", args: []exprNode{operand}}, nil
+	}
+	// A parenthesis opens either arithmetic, as in (a + b) > 1, or a group
+	// of conditions; try the first and fall back to the second.
+	start, seen := p.pos, len(p.columns)
+	n, err := p.parseCompare()
+	if err == nil || start == len(p.src) || p.src[start] != '(' {
+		return n, err
+	}
+	for _, c := range p.columns[seen:] {
+		delete(p.seen, c)
+	}
+	p.columns = p.columns[:seen]
+	p.pos = start + 1
+	n, err = p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek() != ')' {
+		return nil, fmt.Errorf("missing ) at offset %d", p.pos)
+	}
+	p.pos++
+	return n, nil
+}
+
+func (p *exprParser) parseCompare() (exprNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range filterOps {
+		if p.consume(o.op) {
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return exprCall{fn: o.fn, args: []exprNode{left, right}}, nil
+		}
+	}
+	if _, ok := left.(exprRaw); !ok {
+		return nil, fmt.Errorf("want a comparison or boolean column at offset %d", p.pos)
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseOperand() (exprNode, error) {
+	if p.peek() == '\'' {
+		end := strings.IndexByte(p.src[p.pos+1:], '\'')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated string at offset %d", p.pos)
+		}
+		s := p.src[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+		return exprString(s), nil
+	}
+	n, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	// A bare column keeps its type, so strings compare as strings.
+	if c, ok := n.(exprColumn); ok {
+		return exprRaw(c), nil
+	}
+	return n, nil
+}
+
+// consume advances past tok when it comes next.
+func (p *exprParser) consume(tok string) bool {
+	p.skipSpace()
+	if !strings.HasPrefix(p.src[p.pos:], tok) {
+		return false
+	}
+	p.pos += len(tok)
+	return true
+}
@@ -0,0 +1,83 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestFilter(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "region", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "limit", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "ok", Type: arrow.FixedWidthTypes.Boolean},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"us-east", "eu", "us-east", "us-east"}, nil)
+	b.Field(1).(*array.Int64Builder).AppendValues([]int64{5, 7, -1, 0}, []bool{true, true, true, false})
+	b.Field(2).(*array.Float64Builder).AppendValues([]float64{2, 10, 1, 1}, nil)
+	b.Field(3).(*array.BooleanBuilder).AppendValues([]bool{true, false, false, true}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	tests := []struct {
+		filter string
+		want   []int64
+	}{
+		{`region == 'us-east' && value > 0`, []int64{5}},
+		{`region != 'us-east' || value <= 0`, []int64{7, -1}},
+		{`value * 2 >= "limit" + 3`, []int64{5, 7}},
+		{`(value + 1) > 1 && !(region == 'eu')`, []int64{5}},
+		{`ok && value > 0 || value < 0`, []int64{5, -1}},
+		{`!ok`, []int64{7, -1}},
+	}
+	ctx := compute.WithAllocator(context.Background(), pool)
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := f.Apply(ctx, rec)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.filter, err)
+		}
+		values := out.Column(1).(*array.Int64)
+		if values.Len() != len(tt.want) {
+			t.Errorf("%s: kept %d rows, want %v", tt.filter, values.Len(), tt.want)
+		} else {
+			for i, want := range tt.want {
+				if values.Value(i) != want {
+					t.Errorf("%s row %d = %d, want %d", tt.filter, i, values.Value(i), want)
+				}
+			}
+		}
+		out.Release()
+	}
+
+	f, err := ParseFilter(`region == 'eu' && value > "limit"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cols := f.Columns(); len(cols) != 3 || cols[0] != "region" || cols[2] != "limit" {
+		t.Errorf("Columns() = %v", cols)
+	}
+	for _, bad := range []string{"", "value >", "value = 1", "value + 1", "(value > 1", "region == 'eu", "1 < 2"} {
+		if _, err := ParseFilter(bad); err == nil {
+			t.Errorf("ParseFilter(%q): expected error", bad)
+		}
+	}
+	if f, err := ParseFilter("value"); err == nil {
+		if _, err := f.Eval(ctx, rec); err == nil {
+			t.Error("an integer column as a condition: expected error")
+		}
+	}
+}
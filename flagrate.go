@@ -0,0 +1,221 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// FlagRateDetector flags bursts in a boolean column, such as an error flag,
+// where the anomaly is a change in how often the flag is set rather than any
+// single value. Numeric columns are read as flags that are set when non-zero.
+//
+// Each row is scored by the binomial z-score (k/n - p) / sqrt(p(1-p)/n) of
+// the trailing window of the last Window non-null flags, the row's own
+// included, where k of them are set and p is the rate over the whole column.
+// Rows without a full window get a null score and are never flagged. A row
+// is flagged when its score is at least Threshold in magnitude and its flag
+// is on the side of the change: set in a burst of true, unset in a drought.
+//
+// The Result's Values hold each flagged row's window rate. Mean and StdDev
+// describe the flags as 0 and 1, so Mean is the overall rate.
+type FlagRateDetector struct {
+	Window    int
+	Threshold float64
+}
+
+var _ Detector = FlagRateDetector{}
+
+// Detect implements Detector.
+func (d FlagRateDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if d.Window < 1 {
+		return nil, fmt.Errorf("window must be at least 1, got %d", d.Window)
+	}
+	flags, err := toFlags(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer flags.Release()
+	stats := accumulate(flags)
+
+	mem := compute.GetAllocator(ctx)
+	rates := array.NewFloat64Builder(mem)
+	defer rates.Release()
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	rates.Reserve(flags.Len())
+	scores.Reserve(flags.Len())
+	mask.Reserve(flags.Len())
+
+	ring := make([]float64, d.Window)
+	var head, n int
+	var set float64
+	for i := 0; i < flags.Len(); i++ {
+		if flags.IsNull(i) {
+			appendNullOf(rates, flags, i)
+			scores.AppendNull()
+			mask.Append(false)
+			continue
+		}
+		x := flags.Value(i)
+		if n == d.Window {
+			set -= ring[head]
+		} else {
+			n++
+		}
+		ring[head] = x
+		head = (head + 1) % d.Window
+		set += x
+
+		rate := set / float64(n)
+		rates.Append(rate)
+		if n < d.Window {
+			scores.AppendNull()
+			mask.Append(false)
+			continue
+		}
+		z := rateScore(rate, stats.mean, n)
+		scores.Append(z)
+		mask.Append(flaggedRate(z, x, d.Threshold))
+	}
+
+	rateCol := rates.NewFloat64Array()
+	defer rateCol.Release()
+	return newResult(ctx, rateCol, mask.NewBooleanArray(), scores.NewFloat64Array(), stats)
+}
+
+// DetectFlagRateGrouped scores the flags in valueCol, read as
+// FlagRateDetector does, by how far the rate of the rows sharing their keyCol
+// value departs from the rate over the whole column, so a host or region
+// whose error flag is set unusually often stands out. Every row of a group
+// gets the group's binomial z-score, and its rows whose flag is on the side
+// of the departure are flagged when the score is at least threshold in
+// magnitude. The Result's Values hold the group rates of the flagged rows.
+func DetectFlagRateGrouped(ctx context.Context, rec arrow.Record, valueCol, keyCol string, threshold float64) (*Result, error) {
+	vidx := rec.Schema().FieldIndices(valueCol)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", valueCol)
+	}
+	kidx := rec.Schema().FieldIndices(keyCol)
+	if len(kidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", keyCol)
+	}
+	flags, err := toFlags(ctx, rec.Column(vidx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", valueCol, err)
+	}
+	defer flags.Release()
+	keys := rec.Column(kidx[0])
+	stats := accumulate(flags)
+
+	keyOf := func(i int) groupKey {
+		if keys.IsNull(i) {
+			return groupKey{null: true}
+		}
+		return groupKey{value: keys.ValueStr(i)}
+	}
+	groups := make(map[groupKey]*runningStats)
+	for i := 0; i < flags.Len(); i++ {
+		if flags.IsNull(i) {
+			continue
+		}
+		k := keyOf(i)
+		s, ok := groups[k]
+		if !ok {
+			s = &runningStats{}
+			groups[k] = s
+		}
+		s.add(flags.Value(i))
+	}
+
+	mem := compute.GetAllocator(ctx)
+	rates := array.NewFloat64Builder(mem)
+	defer rates.Release()
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	rates.Reserve(flags.Len())
+	scores.Reserve(flags.Len())
+	mask.Reserve(flags.Len())
+	for i := 0; i < flags.Len(); i++ {
+		if flags.IsNull(i) {
+			appendNullOf(rates, flags, i)
+			scores.AppendNull()
+			mask.AppendNull()
+			continue
+		}
+		s := groups[keyOf(i)]
+		z := rateScore(s.mean, stats.mean, int(s.n))
+		rates.Append(s.mean)
+		scores.Append(z)
+		mask.Append(flaggedRate(z, flags.Value(i), threshold))
+	}
+
+	rateCol := rates.NewFloat64Array()
+	defer rateCol.Release()
+	return newResult(ctx, rateCol, mask.NewBooleanArray(), scores.NewFloat64Array(), stats)
+}
+
+// rateScore returns the binomial z-score of a rate seen over n flags against
+// the baseline rate p. A column whose flags are all alike cannot depart from
+// its rate, so it scores zero.
+func rateScore(rate, p float64, n int) float64 {
+	if p <= 0 || p >= 1 {
+		return 0
+	}
+	return (rate - p) / math.Sqrt(p*(1-p)/float64(n))
+}
+
+// flaggedRate reports whether a row with flag x (0 or 1) scored z is flagged:
+// the score must reach threshold and the flag must push the rate the way it
+// moved.
+func flaggedRate(z, x, threshold float64) bool {
+	return math.Abs(z) >= threshold && (z > 0) == (x == 1)
+}
+
+// toFlags returns col as a Float64 array of 0 and 1 flags, with the
+// non-finite and null policies carried by ctx applied. Boolean columns map
+// true to 1; numeric columns map every non-zero value to 1. The caller must
+// Release the result.
+func toFlags(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	if col.DataType().ID() == arrow.BOOL {
+		out, err := compute.CastArray(ctx, col, compute.SafeCastOptions(arrow.PrimitiveTypes.Float64))
+		if err != nil {
+			return nil, fmt.Errorf("cast %s to float64: %w", col.DataType(), err)
+		}
+		defer out.Release()
+		return toFloat64(ctx, out)
+	}
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	bld := array.NewFloat64Builder(compute.GetAllocator(ctx))
+	defer bld.Release()
+	bld.Reserve(floatCol.Len())
+	for i := 0; i < floatCol.Len(); i++ {
+		switch {
+		case floatCol.IsNull(i):
+			appendNullOf(bld, floatCol, i)
+		case floatCol.Value(i) != 0:
+			bld.Append(1)
+		default:
+			bld.Append(0)
+		}
+	}
+	return bld.NewFloat64Array(), nil
+}
+
+// appendNullOf appends a null to b that keeps the value under row i of col,
+// so newResult still tells nulls from the NaNs and infinities made null.
+func appendNullOf(b *array.Float64Builder, col *array.Float64, i int) {
+	b.AppendValues(col.Float64Values()[i:i+1], []bool{false})
+}
@@ -0,0 +1,143 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestFlagRateDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// An error flag set every 20th row, with a burst of 8 at rows 100-107
+	// and a null at row 50.
+	b := array.NewBooleanBuilder(pool)
+	defer b.Release()
+	for i := 0; i < 200; i++ {
+		switch {
+		case i == 50:
+			b.AppendNull()
+		default:
+			b.Append(i%20 == 0 || (i >= 100 && i < 108))
+		}
+	}
+	col := b.NewArray()
+	defer col.Release()
+
+	res, err := FlagRateDetector{Window: 10, Threshold: 3}.Detect(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	for j := 0; j < res.Indices.Len(); j++ {
+		i := res.Indices.Value(j)
+		if i < 100 || i >= 108 {
+			t.Errorf("row %d flagged outside the burst", i)
+		}
+	}
+	if res.Indices.Len() < 5 {
+		t.Errorf("flagged %d rows of the burst, want at least 5", res.Indices.Len())
+	}
+	if res.Zscore.IsValid(8) || !res.Zscore.IsValid(9) {
+		t.Error("want null scores only before the window fills")
+	}
+	if res.Nulls != 1 || res.Zscore.IsValid(50) {
+		t.Errorf("got %d nulls, want the null row unscored", res.Nulls)
+	}
+	if want := 17.0 / 199; res.Mean < want-1e-9 || res.Mean > want+1e-9 {
+		t.Errorf("mean %v, want overall rate %v", res.Mean, want)
+	}
+	for j := 0; j < res.Values.Len(); j++ {
+		if v := res.Values.Value(j); v <= 3*res.Mean || v > 1 {
+			t.Errorf("window rate %v of a burst row, want well above %v", v, res.Mean)
+		}
+	}
+
+	// Numeric flags are set when non-zero.
+	ib := array.NewInt64Builder(pool)
+	defer ib.Release()
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			ib.AppendNull()
+		} else if col.(*array.Boolean).Value(i) {
+			ib.Append(int64(i%3 + 1))
+		} else {
+			ib.Append(0)
+		}
+	}
+	ints := ib.NewArray()
+	defer ints.Release()
+	numeric, err := FlagRateDetector{Window: 10, Threshold: 3}.Detect(ctx, ints)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer numeric.Release()
+	if numeric.Indices.Len() != res.Indices.Len() {
+		t.Errorf("numeric flags: flagged %d rows, want %d", numeric.Indices.Len(), res.Indices.Len())
+	}
+
+	if _, err := (FlagRateDetector{Window: 0, Threshold: 3}).Detect(ctx, col); err == nil {
+		t.Error("want an error for window < 1")
+	}
+}
+
+func TestDetectFlagRateGrouped(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// Hosts a and b set the flag on 1 row in 10, host c on 1 in 2.
+	kb := array.NewStringBuilder(pool)
+	defer kb.Release()
+	fb := array.NewBooleanBuilder(pool)
+	defer fb.Release()
+	for i := 0; i < 300; i++ {
+		host := []string{"a", "b", "c"}[i%3]
+		kb.Append(host)
+		if host == "c" {
+			fb.Append(i/3%2 == 0)
+		} else {
+			fb.Append(i/3%10 == 0)
+		}
+	}
+	keys, flags := kb.NewArray(), fb.NewArray()
+	defer keys.Release()
+	defer flags.Release()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "error", Type: arrow.FixedWidthTypes.Boolean},
+	}, nil)
+	rec := array.NewRecord(schema, []arrow.Array{keys, flags}, 300)
+	defer rec.Release()
+
+	res, err := DetectFlagRateGrouped(ctx, rec, "error", "host", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	var c int
+	for j := 0; j < res.Indices.Len(); j++ {
+		i := int(res.Indices.Value(j))
+		if keys.(*array.String).Value(i) == "c" {
+			if !flags.(*array.Boolean).Value(i) {
+				t.Errorf("row %d of host c flagged with its flag unset", i)
+			}
+			c++
+		}
+	}
+	if c != 50 {
+		t.Errorf("flagged %d set rows of host c, want 50", c)
+	}
+
+	if _, err := DetectFlagRateGrouped(ctx, rec, "error", "region", 3); err == nil {
+		t.Error("want an error for a missing key column")
+	}
+}
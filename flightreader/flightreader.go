@@ -0,0 +1,159 @@
+// Package flightreader streams Arrow records from an Arrow Flight endpoint.
+package flightreader
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/flight"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// FlightReader streams the records behind one Flight ticket. The stream is
+// opened by NewFlightReader and can be consumed once.
+type FlightReader struct {
+	allocator memory.Allocator
+	client    flight.Client
+	reader    *flight.Reader
+	used      bool
+}
+
+// NewFlightReader connects to location, a grpc:// or grpc+tls:// URI (a bare
+// host:port means grpc://), and starts DoGet for ticket. Records are
+// allocated from mem; nil means a new Go allocator.
+func NewFlightReader(ctx context.Context, location, ticket string, mem memory.Allocator) (*FlightReader, error) {
+	allocator := mem
+	if allocator == nil {
+		allocator = memory.NewGoAllocator()
+	}
+	addr, creds, err := dialTarget(location)
+	if err != nil {
+		return nil, err
+	}
+	client, err := flight.NewClientWithMiddlewareCtx(ctx, addr, nil, nil, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("flight connect: %w", err)
+	}
+	stream, err := client.DoGet(ctx, &flight.Ticket{Ticket: []byte(ticket)})
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("flight do get: %w", err)
+	}
+	reader, err := flight.NewRecordReader(stream, ipc.WithAllocator(allocator))
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("flight read schema: %w", err)
+	}
+	return &FlightReader{allocator: allocator, client: client, reader: reader}, nil
+}
+
+// dialTarget splits a Flight location into a gRPC address and transport
+// credentials.
+func dialTarget(location string) (string, credentials.TransportCredentials, error) {
+	if !strings.Contains(location, "://") {
+		return location, insecure.NewCredentials(), nil
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return "", nil, fmt.Errorf("flight location: %w", err)
+	}
+	switch u.Scheme {
+	case "grpc", "grpc+tcp":
+		return u.Host, insecure.NewCredentials(), nil
+	case "grpc+tls":
+		return u.Host, credentials.NewTLS(&tls.Config{}), nil
+	default:
+		return "", nil, fmt.Errorf("flight location: unsupported scheme %q", u.Scheme)
+	}
+}
+
+// Schema returns the schema of the stream.
+func (fr *FlightReader) Schema() *arrow.Schema {
+	return fr.reader.Schema()
+}
+
+// Chan returns a channel of records; caller must Release each.
+func (fr *FlightReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	if fr.used {
+		close(recs)
+		errs <- fmt.Errorf("flight stream can only be read once")
+		close(errs)
+		return recs, errs
+	}
+	fr.used = true
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for fr.reader.Next() {
+			rec := fr.reader.Record()
+			rec.Retain()
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+		if err := fr.reader.Err(); err != nil {
+			errs <- fmt.Errorf("flight read error: %w", err)
+		}
+	}()
+	return recs, errs
+}
+
+// ReadSingleColumn concatenates all batches for a named column.
+func (fr *FlightReader) ReadSingleColumn(ctx context.Context, columnName string) (arrow.Array, error) {
+	rec, err := fr.ReadColumns(ctx, []string{columnName})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+// ReadChunked returns the named column with one chunk per batch, without
+// concatenating them.
+func (fr *FlightReader) ReadChunked(ctx context.Context, columnName string) (*arrow.Chunked, error) {
+	if len(fr.Schema().FieldIndices(columnName)) == 0 {
+		return nil, fmt.Errorf("column %s not found", columnName)
+	}
+	recs, errs := fr.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, columnName)
+}
+
+// ReadColumns drains the stream and returns a single record holding the
+// named columns, each concatenated across batches. A nil columns slice keeps
+// every column of the schema.
+func (fr *FlightReader) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range fr.Schema().Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	for _, name := range columns {
+		if len(fr.Schema().FieldIndices(name)) == 0 {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+	}
+	recs, errs := fr.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, fr.allocator)
+}
+
+// Close releases the stream and the connection.
+func (fr *FlightReader) Close() error {
+	fr.reader.Release()
+	return fr.client.Close()
+}
@@ -0,0 +1,138 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/bitutil"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// nativeFloat32 returns col as a Float32 array when the z-score method can
+// score it as is, without the float64 copy toFloat64 makes: it has no nulls
+// and only finite values, so the null and non-finite policies have nothing
+// to rebuild. Widening a float32 is exact, so the statistics and scores are
+// those of the cast column.
+func nativeFloat32(col arrow.Array) (*array.Float32, bool) {
+	f, ok := col.(*array.Float32)
+	if !ok || f.NullN() > 0 {
+		return nil, false
+	}
+	for _, v := range f.Float32Values() {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			return nil, false
+		}
+	}
+	return f, true
+}
+
+// accumulate32 is accumulate for a Float32 array without nulls.
+func accumulate32(col *array.Float32) runningStats {
+	var s runningStats
+	for _, v := range col.Float32Values() {
+		s.add(float64(v))
+	}
+	return s
+}
+
+// scoreFloat32 is scoreDirect for a column from nativeFloat32: one pass
+// over the float32 values writes the float64 scores and the mask. Only the
+// flagged values are widened into the Result's Values. A zero scale fails
+// with ErrZeroScale, as in scoreAgainst.
+func scoreFloat32(ctx context.Context, col *array.Float32, center, scale, threshold float64, stats runningStats) (*Result, error) {
+	if err := checkScale(scale); err != nil {
+		return nil, err
+	}
+	mem := compute.GetAllocator(ctx)
+	n := col.Len()
+
+	scores := memory.NewResizableBuffer(mem)
+	scores.Resize(arrow.Float64Traits.BytesRequired(n))
+	defer scores.Release()
+	z := arrow.Float64Traits.CastFromBytes(scores.Bytes())
+
+	flags := memory.NewResizableBuffer(mem)
+	flags.Resize(int(bitutil.BytesForBits(int64(n))))
+	defer flags.Release()
+	bits := flags.Bytes()
+	clear(bits)
+
+	for i, x := range col.Float32Values() {
+		s := (float64(x) - center) / scale
+		z[i] = s
+		if math.Abs(s) >= threshold {
+			bitutil.SetBit(bits, i)
+		}
+	}
+
+	zdata := array.NewData(arrow.PrimitiveTypes.Float64, n, []*memory.Buffer{nil, scores}, nil, 0, 0)
+	defer zdata.Release()
+	mdata := array.NewData(arrow.FixedWidthTypes.Boolean, n, []*memory.Buffer{nil, flags}, nil, 0, 0)
+	defer mdata.Release()
+	res := &Result{
+		Mask:   array.NewBooleanData(mdata),
+		Zscore: array.NewFloat64Data(zdata),
+		Mean:   stats.mean,
+		StdDev: stats.stdDev(),
+	}
+	if err := res.extractFlagged(ctx, col); err != nil {
+		res.Release()
+		return nil, err
+	}
+	return res, nil
+}
+
+// detectFloat32Chunked is detectChunked for the z-score method over a column
+// whose chunks all pass nativeFloat32: the statistics are accumulated and
+// each chunk scored straight from the float32 values, so no chunk is cast.
+func detectFloat32Chunked(ctx context.Context, d ZScoreDetector, col *arrow.Chunked) (*ChunkedResult, bool, error) {
+	chunks := make([]*array.Float32, len(col.Chunks()))
+	for i, c := range col.Chunks() {
+		f, ok := nativeFloat32(c)
+		if !ok {
+			return nil, false, nil
+		}
+		chunks[i] = f
+	}
+
+	workers := parallelismFrom(ctx)
+	partial := make([]runningStats, len(chunks))
+	_ = parallelFor(workers, len(chunks), func(i int) error {
+		partial[i] = accumulate32(chunks[i])
+		return nil
+	})
+	var stats runningStats
+	for _, p := range partial {
+		stats.merge(p)
+	}
+	scale := math.Sqrt(stats.variance())
+	if err := checkScale(scale); err != nil {
+		return nil, true, err
+	}
+
+	out := &ChunkedResult{Chunks: make([]*Result, len(chunks)), Offsets: make([]int64, len(chunks))}
+	var offset int64
+	for i, c := range chunks {
+		out.Offsets[i] = offset
+		offset += int64(c.Len())
+	}
+	err := parallelFor(workers, len(chunks), func(i int) error {
+		if err := checkMemory(ctx, int64(chunks[i].Len())*scoreBytesPerRow); err != nil {
+			return err
+		}
+		res, err := scoreFloat32(ctx, chunks[i], stats.mean, scale, d.Threshold, stats)
+		out.Chunks[i] = res
+		if err == nil {
+			err = checkMemory(ctx, 0)
+		}
+		return err
+	})
+	if err != nil {
+		out.Release()
+		return nil, true, err
+	}
+	return out, true, nil
+}
@@ -0,0 +1,124 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestFloat32Native(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat32Builder(pool)
+	defer b.Release()
+	for i := 0; i < 1000; i++ {
+		v := float32(i%7) + 0.1
+		if i == 500 {
+			v = 90.3
+		}
+		b.Append(v)
+	}
+	col := b.NewFloat32Array()
+	defer col.Release()
+	wide, err := castFloat64(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wide.Release()
+
+	for _, opts := range [][]Option{nil, {WithParallelism(4)}} {
+		want, err := DetectAnomalies(ctx, wide, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DetectAnomalies(ctx, col, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got.AnomalousIndices(), []int64{500}) {
+			t.Errorf("indices = %v, want [500]", got.AnomalousIndices())
+		}
+		if !slices.Equal(got.Zscore.Float64Values(), want.Zscore.Float64Values()) {
+			t.Error("scores differ from those of the float64 column")
+		}
+		if got.Mean != want.Mean || got.StdDev != want.StdDev || got.Values.Value(0) != want.Values.Value(0) {
+			t.Errorf("got mean %v, sd %v, value %v; want %v, %v, %v",
+				got.Mean, got.StdDev, got.Values.Value(0), want.Mean, want.StdDev, want.Values.Value(0))
+		}
+		want.Release()
+		got.Release()
+	}
+
+	// A NaN leaves the column to the cast path and its non-finite policy.
+	b.AppendValues([]float32{1, 2, float32(math.NaN()), 3}, nil)
+	nan := b.NewFloat32Array()
+	defer nan.Release()
+	if _, ok := nativeFloat32(nan); ok {
+		t.Fatal("column with NaN taken as native")
+	}
+	res, err := DetectAnomalies(ctx, nan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.NaNs != 1 || res.Zscore.IsValid(2) {
+		t.Errorf("NaNs = %d, score valid %t; want 1, false", res.NaNs, res.Zscore.IsValid(2))
+	}
+	res.Release()
+}
+
+func TestFloat32NativeMemory(t *testing.T) {
+	const n = 100000
+	b := array.NewFloat32Builder(memory.DefaultAllocator)
+	defer b.Release()
+	for i := 0; i < n; i++ {
+		b.Append(float32(i % 10))
+	}
+	col := b.NewFloat32Array()
+	defer col.Release()
+	chunked := arrow.NewChunked(arrow.PrimitiveTypes.Float32, []arrow.Array{col})
+	defer chunked.Release()
+
+	mem := &peakAllocator{Allocator: memory.NewGoAllocator()}
+	ctx := compute.WithAllocator(context.Background(), mem)
+	res, err := DetectChunked(ctx, ZScoreDetector{Threshold: 3}, chunked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Release()
+	// Only the scores and the mask are allocated, not a float64 copy.
+	if limit := n*arrow.Float64SizeBytes + n; mem.peak > limit {
+		t.Errorf("peak = %d bytes, want at most %d", mem.peak, limit)
+	}
+}
+
+func TestFloat32ZeroScale(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat32Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float32{2.5, 2.5, 2.5, 2.5}, nil)
+	col := b.NewFloat32Array()
+	defer col.Release()
+
+	for i, opts := range [][]Option{nil, {WithFastPath(true)}, {WithParallelism(2)}} {
+		if _, err := DetectAnomalies(ctx, col, opts...); !errors.Is(err, ErrZeroScale) {
+			t.Errorf("case %d: err = %v, want ErrZeroScale", i, err)
+		}
+	}
+	chunked := arrow.NewChunked(col.DataType(), []arrow.Array{col, col})
+	defer chunked.Release()
+	if _, err := DetectAnomaliesChunked(ctx, chunked); !errors.Is(err, ErrZeroScale) {
+		t.Errorf("chunked: err = %v, want ErrZeroScale", err)
+	}
+}
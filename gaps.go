@@ -0,0 +1,145 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// DefaultGapTolerance is the fraction of the interval by which a step may
+// differ from it and still be regular, when Tolerance is zero.
+const DefaultGapTolerance = 0.5
+
+// Gap is a stretch of a time series with samples missing.
+type Gap struct {
+	// Row is the first row after the gap; From and To are the timestamps on
+	// either side of it.
+	Row  int64     `json:"row"`
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// Missing is the number of samples the interval implies between From
+	// and To.
+	Missing int64 `json:"missing"`
+}
+
+// Gaps describes the sampling of a time series.
+type Gaps struct {
+	// Interval is the expected step between samples, given or inferred.
+	Interval time.Duration `json:"interval"`
+	Count    int64         `json:"count"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	// Missing totals the samples missing in Gaps.
+	Missing int64 `json:"missing"`
+	Gaps    []Gap `json:"gaps"`
+	// Irregular counts steps shorter than the interval by more than the
+	// tolerance, Duplicates repeated timestamps, and OutOfOrder timestamps
+	// earlier than one before them, which are otherwise skipped.
+	Irregular  int64 `json:"irregular"`
+	Duplicates int64 `json:"duplicates"`
+	OutOfOrder int64 `json:"out_of_order"`
+	Nulls      int64 `json:"nulls,omitempty"`
+}
+
+// GapDetector finds missing samples and irregular sampling in a timestamp
+// column, in row order. Each step between successive timestamps is compared
+// with Interval: a step longer than Interval * (1 + Tolerance) is a gap, one
+// shorter than Interval * (1 - Tolerance) is irregular. Timestamp, Date32,
+// Date64 and string columns are accepted, as for Result.Points.
+type GapDetector struct {
+	// Interval is the expected step; zero infers it as the median step.
+	Interval time.Duration
+	// Tolerance is a fraction of Interval; zero means DefaultGapTolerance.
+	Tolerance float64
+}
+
+// Find reports the gaps in col.
+func (d GapDetector) Find(ctx context.Context, col arrow.Array) (*Gaps, error) {
+	if d.Interval < 0 {
+		return nil, fmt.Errorf("gaps: negative interval %s", d.Interval)
+	}
+	tol := d.Tolerance
+	if tol == 0 {
+		tol = DefaultGapTolerance
+	}
+	if tol < 0 || tol >= 1 {
+		return nil, fmt.Errorf("gaps: tolerance must be in [0, 1), got %v", tol)
+	}
+
+	out := &Gaps{}
+	rows := make([]int64, 0, col.Len())
+	times := make([]time.Time, 0, col.Len())
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			out.Nulls++
+			continue
+		}
+		t, err := timeAt(col, i)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		rows = append(rows, int64(i))
+		times = append(times, t)
+	}
+	out.Count = int64(len(times))
+	if len(times) < 2 {
+		return nil, fmt.Errorf("gaps: need at least 2 timestamps, got %d", len(times))
+	}
+
+	interval := d.Interval
+	if interval == 0 {
+		interval = medianStep(times)
+		if interval <= 0 {
+			return nil, fmt.Errorf("gaps: cannot infer an interval from timestamps that do not increase")
+		}
+	}
+	out.Interval = interval
+	long := time.Duration(float64(interval) * (1 + tol))
+	short := time.Duration(float64(interval) * (1 - tol))
+
+	out.Start, out.End = times[0], times[0]
+	last := times[0]
+	for i := 1; i < len(times); i++ {
+		t := times[i]
+		step := t.Sub(last)
+		switch {
+		case step < 0:
+			out.OutOfOrder++
+			continue
+		case step == 0:
+			out.Duplicates++
+		case step > long:
+			missing := int64(math.Round(float64(step)/float64(interval))) - 1
+			if missing < 1 {
+				missing = 1
+			}
+			out.Gaps = append(out.Gaps, Gap{Row: rows[i], From: last, To: t, Missing: missing})
+			out.Missing += missing
+		case step < short:
+			out.Irregular++
+		}
+		last = t
+		out.End = t
+	}
+	return out, nil
+}
+
+// medianStep returns the median of the positive steps between successive
+// times.
+func medianStep(times []time.Time) time.Duration {
+	steps := make([]time.Duration, 0, len(times)-1)
+	for i := 1; i < len(times); i++ {
+		if s := times[i].Sub(times[i-1]); s > 0 {
+			steps = append(steps, s)
+		}
+	}
+	if len(steps) == 0 {
+		return 0
+	}
+	sort.Slice(steps, func(i, j int) bool { return steps[i] < steps[j] })
+	return steps[len(steps)/2]
+}
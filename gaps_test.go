@@ -0,0 +1,60 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestGapDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Minutes 0-4, a three-minute hole, 8-9, a duplicate, an early sample,
+	// one out of order and a final regular step.
+	offsets := []time.Duration{0, 1, 2, 3, 4, 8, 9, 9, 9*60 + 10, 5 * 60, 10*60 + 10}
+	b := array.NewTimestampBuilder(pool, &arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"})
+	defer b.Release()
+	for i, off := range offsets {
+		if i < 8 {
+			off *= time.Minute
+		} else {
+			off *= time.Second
+		}
+		b.Append(arrow.Timestamp(start.Add(off).Unix()))
+	}
+	b.AppendNull()
+	col := b.NewArray()
+	defer col.Release()
+
+	g, err := GapDetector{}.Find(context.Background(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Interval != time.Minute {
+		t.Errorf("interval = %s, want 1m", g.Interval)
+	}
+	if len(g.Gaps) != 1 || g.Gaps[0].Row != 5 || g.Gaps[0].Missing != 3 || g.Missing != 3 {
+		t.Errorf("gaps = %+v", g.Gaps)
+	}
+	if g.Duplicates != 1 || g.Irregular != 1 || g.OutOfOrder != 1 || g.Nulls != 1 || g.Count != 11 {
+		t.Errorf("duplicates=%d irregular=%d out of order=%d nulls=%d count=%d", g.Duplicates, g.Irregular, g.OutOfOrder, g.Nulls, g.Count)
+	}
+	if !g.End.Equal(start.Add(10*time.Minute + 10*time.Second)) {
+		t.Errorf("end = %s", g.End)
+	}
+
+	// A longer expected interval absorbs the hole.
+	g, err = GapDetector{Interval: 5 * time.Minute}.Find(context.Background(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Gaps) != 0 {
+		t.Errorf("gaps at 5m = %+v", g.Gaps)
+	}
+}
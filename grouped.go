@@ -0,0 +1,199 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// groupKey identifies a group; null keys form a group of their own.
+type groupKey struct {
+	null  bool
+	value string
+}
+
+// DetectAnomaliesGrouped computes z-scores of valueCol against the mean and
+// standard deviation of the rows sharing the same keyCol value, so each group
+// (e.g. host or region) is judged against its own baseline. Keys of any type
+// are compared by their string form, and rows of a group whose values do not
+// vary get a null score and are never flagged. The Result's Mean and StdDev
+// describe the whole column.
+func DetectAnomaliesGrouped(ctx context.Context, rec arrow.Record, valueCol, keyCol string, threshold float64) (*Result, error) {
+	vidx := rec.Schema().FieldIndices(valueCol)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", valueCol)
+	}
+	kidx := rec.Schema().FieldIndices(keyCol)
+	if len(kidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", keyCol)
+	}
+	floatCol, err := toFloat64(ctx, rec.Column(vidx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", valueCol, err)
+	}
+	defer floatCol.Release()
+	keys := rec.Column(kidx[0])
+
+	keyOf := func(i int) groupKey {
+		if keys.IsNull(i) {
+			return groupKey{null: true}
+		}
+		return groupKey{value: keys.ValueStr(i)}
+	}
+	groups := make(map[groupKey]*runningStats)
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			continue
+		}
+		k := keyOf(i)
+		s, ok := groups[k]
+		if !ok {
+			s = &runningStats{}
+			groups[k] = s
+		}
+		s.add(floatCol.Value(i))
+	}
+
+	mem := compute.GetAllocator(ctx)
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	scores.Reserve(floatCol.Len())
+	mask.Reserve(floatCol.Len())
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			scores.AppendNull()
+			mask.AppendNull()
+			continue
+		}
+		s := groups[keyOf(i)]
+		if s.stdDev() == 0 {
+			scores.AppendNull()
+			mask.Append(false)
+			continue
+		}
+		z := (floatCol.Value(i) - s.mean) / s.stdDev()
+		scores.Append(z)
+		mask.Append(math.Abs(z) >= threshold)
+	}
+
+	return newResult(ctx, floatCol, mask.NewBooleanArray(), scores.NewFloat64Array(), accumulate(floatCol))
+}
+
+// DetectGrouped runs d over the rows of each keyCol group on their own, in
+// row order, so any method judges each group (e.g. host or region) against
+// its own baseline, as DetectAnomaliesGrouped does for z-scores. A group
+// whose values do not vary is left unflagged with null scores rather than
+// failing with ErrZeroScale. Keys are compared by their string form. The
+// Result's Mean and StdDev describe the whole column, and its counts of
+// values left out sum those of the groups.
+func DetectGrouped(ctx context.Context, d Detector, rec arrow.Record, valueCol, keyCol string) (*Result, error) {
+	vidx := rec.Schema().FieldIndices(valueCol)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", valueCol)
+	}
+	kidx := rec.Schema().FieldIndices(keyCol)
+	if len(kidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", keyCol)
+	}
+	col, keys := rec.Column(vidx[0]), rec.Column(kidx[0])
+
+	// Rows of each group, groups in order of first appearance.
+	var order []groupKey
+	rows := make(map[groupKey][]int64)
+	for i := 0; i < keys.Len(); i++ {
+		k := groupKey{null: true}
+		if keys.IsValid(i) {
+			k = groupKey{value: keys.ValueStr(i)}
+		}
+		if _, ok := rows[k]; !ok {
+			order = append(order, k)
+		}
+		rows[k] = append(rows[k], int64(i))
+	}
+
+	n := col.Len()
+	scores := make([]float64, n)
+	scored := make([]bool, n)
+	flags := make([]bool, n)
+	var pvalues []float64
+	var nulls, nans, infs int64
+	mem := compute.GetAllocator(ctx)
+	for _, k := range order {
+		res, err := detectGroup(ctx, d, col, rows[k])
+		if errors.Is(err, ErrZeroScale) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("group %s: %w", k.value, err)
+		}
+		for j, row := range rows[k] {
+			if res.Zscore.IsValid(j) {
+				scores[row], scored[row] = res.Zscore.Value(j), true
+			}
+			flags[row] = res.Mask.IsValid(j) && res.Mask.Value(j)
+			if res.PValues != nil {
+				if pvalues == nil {
+					pvalues = make([]float64, n)
+					for i := range pvalues {
+						pvalues[i] = 1
+					}
+				}
+				pvalues[row] = res.PValues.Value(j)
+			}
+		}
+		nulls, nans, infs = nulls+res.Nulls, nans+res.NaNs, infs+res.Infs
+		res.Release()
+	}
+
+	floatCol, err := castFloat64(ctx, col)
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", valueCol, err)
+	}
+	defer floatCol.Release()
+	stats := accumulate(floatCol)
+
+	sb := array.NewFloat64Builder(mem)
+	defer sb.Release()
+	sb.AppendValues(scores, scored)
+	mb := array.NewBooleanBuilder(mem)
+	defer mb.Release()
+	mb.AppendValues(flags, nil)
+	res := &Result{
+		Mask: mb.NewBooleanArray(), Zscore: sb.NewFloat64Array(),
+		Mean: stats.mean, StdDev: stats.stdDev(),
+		Nulls: nulls, NaNs: nans, Infs: infs,
+	}
+	if pvalues != nil {
+		pb := array.NewFloat64Builder(mem)
+		defer pb.Release()
+		pb.AppendValues(pvalues, nil)
+		res.PValues = pb.NewFloat64Array()
+	}
+	if err := res.extractFlagged(ctx, floatCol); err != nil {
+		res.Release()
+		return nil, err
+	}
+	return res, nil
+}
+
+// detectGroup runs d over the rows of col at rows.
+func detectGroup(ctx context.Context, d Detector, col arrow.Array, rows []int64) (*Result, error) {
+	ib := array.NewInt64Builder(compute.GetAllocator(ctx))
+	defer ib.Release()
+	ib.AppendValues(rows, nil)
+	idx := ib.NewInt64Array()
+	defer idx.Release()
+	group, err := takeArray(ctx, col, idx)
+	if err != nil {
+		return nil, fmt.Errorf("take rows: %w", err)
+	}
+	defer group.Release()
+	return d.Detect(ctx, group)
+}
@@ -0,0 +1,139 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestDetectAnomaliesGrouped(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	// Host b runs a hundred times hotter than host a; 150 is only unusual
+	// for a, and 1000 only unusual for b.
+	hosts := []string{"a", "a", "a", "a", "a", "a", "b", "b", "b", "b", "b", "b"}
+	vals := []float64{10, 11, 9, 10, 150, 10, 1000, 1010, 990, 1000, 100000, 1005}
+	b.Field(0).(*array.StringBuilder).AppendValues(hosts, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues(vals, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	res, err := DetectAnomaliesGrouped(ctx, rec, "value", "host", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	for i := 0; i < res.Mask.Len(); i++ {
+		if want := i == 4 || i == 10; res.Mask.Value(i) != want {
+			t.Errorf("mask[%d] = %t, want %t", i, res.Mask.Value(i), want)
+		}
+	}
+
+	if _, err := DetectAnomaliesGrouped(ctx, rec, "value", "missing", 2); err == nil {
+		t.Errorf("expected error for missing key column")
+	}
+}
+
+func TestDetectGrouped(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	// As above, plus host c, which never varies and cannot be scored, and a
+	// null value for host a.
+	hosts := []string{"a", "a", "a", "a", "a", "a", "b", "b", "b", "b", "b", "b", "c", "c", "a"}
+	vals := []int64{10, 11, 9, 10, 150, 10, 1000, 1010, 990, 1000, 100000, 1005, 7, 7, 0}
+	valid := []bool{true, true, true, true, true, true, true, true, true, true, true, true, true, true, false}
+	b.Field(0).(*array.StringBuilder).AppendValues(hosts, nil)
+	b.Field(1).(*array.Int64Builder).AppendValues(vals, valid)
+	rec := b.NewRecord()
+	defer rec.Release()
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	for _, d := range []Detector{ZScoreDetector{Threshold: 2}, MADDetector{Threshold: 3.5}} {
+		res, err := DetectGrouped(ctx, d, rec, "value", "host")
+		if err != nil {
+			t.Fatalf("%T: %v", d, err)
+		}
+		for i := 0; i < res.Mask.Len(); i++ {
+			if want := i == 4 || i == 10; res.Mask.Value(i) != want {
+				t.Errorf("%T: mask[%d] = %t, want %t", d, i, res.Mask.Value(i), want)
+			}
+		}
+		if res.Zscore.IsValid(12) || res.Zscore.IsValid(14) || res.Nulls != 1 {
+			t.Errorf("%T: constant group and null scored, or nulls = %d", d, res.Nulls)
+		}
+		if got := res.Values.Value(1); got != 100000 {
+			t.Errorf("%T: values[1] = %v, want 100000", d, got)
+		}
+		res.Release()
+	}
+
+	if _, err := DetectGrouped(ctx, ZScoreDetector{Threshold: 2}, rec, "value", "missing"); err == nil {
+		t.Errorf("expected error for missing key column")
+	}
+}
+
+func TestDetectGroupedDictionary(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.PrimitiveTypes.Int32}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: dt},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	// Host c never varies, so is left unscored by either function.
+	hosts := []string{"a", "a", "a", "a", "a", "a", "c", "c", "c"}
+	b.Field(0).(*array.StringBuilder).AppendValues(hosts, nil)
+	vb := b.Field(1).(*array.Int32DictionaryBuilder)
+	for _, v := range []int32{10, 11, 9, 10, 150, 10, 7, 7, 7} {
+		if err := vb.Append(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	grouped, err := DetectGrouped(ctx, ZScoreDetector{Threshold: 2}, rec, "value", "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer grouped.Release()
+	scores, err := DetectAnomaliesGrouped(ctx, rec, "value", "host", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer scores.Release()
+	for _, res := range []*Result{grouped, scores} {
+		if res.Indices.Len() != 1 || res.Indices.Value(0) != 4 || res.Values.Value(0) != 150 {
+			t.Errorf("flagged %v, want row 4 with value 150", res.Indices)
+		}
+		for i := 6; i < 9; i++ {
+			if res.Zscore.IsValid(i) || res.Mask.Value(i) {
+				t.Errorf("row %d of the constant group scored %v", i, res.Zscore.Value(i))
+			}
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// DefaultAlpha is the significance level used when a test's Alpha is zero.
+const DefaultAlpha = 0.05
+
+// GrubbsDetector applies the two-sided Grubbs test for a single outlier,
+// assuming the data are otherwise normally distributed. Zscore holds each
+// value's studentized deviation (x - mean) / s with the sample standard
+// deviation s, and PValues the Bonferroni-adjusted probability of a deviation
+// at least that large among n normal values. Only the most extreme value can
+// be flagged, and only when its p-value is below Alpha; use ESDDetector when
+// several outliers may be present.
+type GrubbsDetector struct {
+	// Alpha is the significance level; zero means DefaultAlpha.
+	Alpha float64
+}
+
+var _ Detector = GrubbsDetector{}
+
+// Detect implements Detector.
+func (d GrubbsDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	alpha := orDefaultAlpha(d.Alpha)
+
+	stats := accumulate(floatCol)
+	if stats.n < 3 {
+		return nil, fmt.Errorf("grubbs: need at least 3 values, got %d", stats.n)
+	}
+	s := sampleStdDev(stats)
+	extreme, worst := -1, -1.0
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsValid(i) {
+			if g := math.Abs(floatCol.Value(i) - stats.mean); g > worst {
+				extreme, worst = i, g
+			}
+		}
+	}
+
+	mem := compute.GetAllocator(ctx)
+	scores, mask, pvals := array.NewFloat64Builder(mem), array.NewBooleanBuilder(mem), array.NewFloat64Builder(mem)
+	defer scores.Release()
+	defer mask.Release()
+	defer pvals.Release()
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			scores.AppendNull()
+			mask.AppendNull()
+			pvals.AppendNull()
+			continue
+		}
+		g := (floatCol.Value(i) - stats.mean) / s
+		p := grubbsPValue(math.Abs(g), stats.n)
+		scores.Append(g)
+		pvals.Append(p)
+		mask.Append(i == extreme && p < alpha)
+	}
+
+	res, err := newResult(ctx, floatCol, mask.NewBooleanArray(), scores.NewFloat64Array(), stats)
+	if err != nil {
+		return nil, err
+	}
+	res.PValues = pvals.NewFloat64Array()
+	return res, nil
+}
+
+// ESDDetector applies Rosner's generalized extreme Studentized deviate test,
+// which tests for up to MaxOutliers outliers at once without the masking
+// that repeated Grubbs tests suffer from. The most extreme value is removed
+// and the test repeated on the remainder; the number of outliers is the
+// largest step whose statistic exceeds its critical value. Zscore and
+// PValues hold each removed value's statistic and Grubbs p-value at the step
+// it was removed, and the remaining values' against the sample left after
+// the last step.
+type ESDDetector struct {
+	// Alpha is the significance level; zero means DefaultAlpha.
+	Alpha float64
+	// MaxOutliers bounds the number of outliers; zero means a tenth of the
+	// non-null values (at least one).
+	MaxOutliers int
+}
+
+var _ Detector = ESDDetector{}
+
+// Detect implements Detector.
+func (d ESDDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	alpha := orDefaultAlpha(d.Alpha)
+
+	all := accumulate(floatCol)
+	n := int(all.n)
+	if n < 3 {
+		return nil, fmt.Errorf("esd: need at least 3 values, got %d", n)
+	}
+	r := d.MaxOutliers
+	if r <= 0 {
+		r = max(1, n/10)
+	}
+	r = min(r, n-2)
+
+	removed := make([]bool, floatCol.Len())
+	score := make([]float64, floatCol.Len())
+	pval := make([]float64, floatCol.Len())
+	order := make([]int, 0, r)
+	outliers := 0
+	remaining := all
+	for step := 1; step <= r; step++ {
+		s := sampleStdDev(remaining)
+		extreme, worst := -1, -1.0
+		for i := 0; i < floatCol.Len(); i++ {
+			if floatCol.IsValid(i) && !removed[i] {
+				if g := math.Abs(floatCol.Value(i) - remaining.mean); g > worst {
+					extreme, worst = i, g
+				}
+			}
+		}
+		m := n - step + 1
+		x := floatCol.Value(extreme)
+		score[extreme] = (x - remaining.mean) / s
+		pval[extreme] = grubbsPValue(worst/s, int64(m))
+		if worst/s > esdCritical(alpha, m) {
+			outliers = step
+		}
+		removed[extreme] = true
+		order = append(order, extreme)
+		remaining = removeValue(remaining, x)
+	}
+	s := sampleStdDev(remaining)
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsValid(i) && !removed[i] {
+			score[i] = (floatCol.Value(i) - remaining.mean) / s
+			pval[i] = grubbsPValue(math.Abs(score[i]), remaining.n)
+		}
+	}
+	flagged := make([]bool, floatCol.Len())
+	for _, i := range order[:outliers] {
+		flagged[i] = true
+	}
+
+	mem := compute.GetAllocator(ctx)
+	scores, mask, pvals := array.NewFloat64Builder(mem), array.NewBooleanBuilder(mem), array.NewFloat64Builder(mem)
+	defer scores.Release()
+	defer mask.Release()
+	defer pvals.Release()
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			scores.AppendNull()
+			mask.AppendNull()
+			pvals.AppendNull()
+			continue
+		}
+		scores.Append(score[i])
+		pvals.Append(pval[i])
+		mask.Append(flagged[i])
+	}
+
+	res, err := newResult(ctx, floatCol, mask.NewBooleanArray(), scores.NewFloat64Array(), all)
+	if err != nil {
+		return nil, err
+	}
+	res.PValues = pvals.NewFloat64Array()
+	return res, nil
+}
+
+func orDefaultAlpha(alpha float64) float64 {
+	if alpha == 0 {
+		return DefaultAlpha
+	}
+	return alpha
+}
+
+// sampleStdDev is the Bessel-corrected standard deviation of s.
+func sampleStdDev(s runningStats) float64 {
+	return math.Sqrt(s.m2 / float64(s.n-1))
+}
+
+// removeValue reverses runningStats.add for x.
+func removeValue(s runningStats, x float64) runningStats {
+	w := slidingStats{runningStats: s}
+	w.remove(x)
+	return w.runningStats
+}
+
+// grubbsPValue returns the two-sided, Bonferroni-adjusted p-value of the
+// Grubbs statistic g = |x - mean| / s among n values.
+func grubbsPValue(g float64, n int64) float64 {
+	fn := float64(n)
+	den := (fn-1)*(fn-1) - fn*g*g
+	if den <= 0 {
+		return 0
+	}
+	t := math.Sqrt(fn * (fn - 2) * g * g / den)
+	return math.Min(1, 2*fn*(1-studentTCDF(t, fn-2)))
+}
+
+// esdCritical is Rosner's critical value for a step with m values remaining.
+func esdCritical(alpha float64, m int) float64 {
+	fm := float64(m)
+	t := studentTQuantile(1-alpha/(2*fm), fm-2)
+	return (fm - 1) * t / math.Sqrt((fm-2+t*t)*fm)
+}
@@ -0,0 +1,95 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestStudentT(t *testing.T) {
+	for _, c := range []struct{ t, v, cdf float64 }{
+		{2, 10, 0.963306},
+		{-1, 3, 0.195501},
+		{1, 1, 0.75},
+	} {
+		if got := studentTCDF(c.t, c.v); math.Abs(got-c.cdf) > 1e-6 {
+			t.Errorf("cdf(%v, %v) = %v, want %v", c.t, c.v, got, c.cdf)
+		}
+	}
+	if got := studentTQuantile(0.975, 10); math.Abs(got-2.228139) > 1e-6 {
+		t.Errorf("quantile(0.975, 10) = %v, want 2.228139", got)
+	}
+}
+
+// rosner is the example data set from Rosner (1983), known to contain three
+// outliers at the 0.05 level: the last three values.
+var rosner = []float64{
+	-0.25, 0.68, 0.94, 1.15, 1.20, 1.26, 1.26, 1.34, 1.38, 1.43, 1.49, 1.49, 1.55, 1.56,
+	1.58, 1.65, 1.69, 1.70, 1.76, 1.77, 1.81, 1.91, 1.94, 1.96, 1.99, 2.06, 2.09, 2.10,
+	2.14, 2.15, 2.23, 2.24, 2.26, 2.35, 2.37, 2.40, 2.47, 2.54, 2.62, 2.64, 2.90, 2.92,
+	2.92, 2.93, 3.21, 3.26, 3.30, 3.59, 3.68, 4.30, 4.64, 5.34, 5.42, 6.01,
+}
+
+func TestESDDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues(rosner, nil)
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	res, err := ESDDetector{Alpha: 0.05, MaxOutliers: 10}.Detect(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	n := len(rosner)
+	for i := 0; i < n; i++ {
+		if want := i >= n-3; res.Mask.Value(i) != want {
+			t.Errorf("mask[%d] = %t, want %t", i, res.Mask.Value(i), want)
+		}
+	}
+	// 6.01 alone is not significant; 5.34, tested third once the larger
+	// two are removed, is.
+	if p := res.PValues.Value(n - 1); p < 0.05 {
+		t.Errorf("p-value of 6.01 = %v, want >= 0.05", p)
+	}
+	if p := res.PValues.Value(n - 3); p >= 0.05 {
+		t.Errorf("p-value of 5.34 = %v, want < 0.05", p)
+	}
+	if p := res.PValues.Value(n / 2); p < 0.5 {
+		t.Errorf("p-value of a central value = %v, want large", p)
+	}
+}
+
+func TestGrubbsDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{10, 11, 9, 10, 12, 8, 10, 30, 11, 9}, nil)
+	b.AppendNull()
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	res, err := GrubbsDetector{}.Detect(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if res.Indices.Len() != 1 || res.Indices.Value(0) != 7 {
+		t.Errorf("indices = %v, want [7]", res.Indices)
+	}
+	if res.PValues.IsValid(10) {
+		t.Errorf("expected null p-value for null input")
+	}
+}
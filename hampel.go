@@ -0,0 +1,128 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// DefaultHampelHalfWindow is the number of neighbors on each side used when
+// HampelDetector.HalfWindow is zero.
+const DefaultHampelHalfWindow = 3
+
+// HampelDetector is the Hampel filter for spikes in ordered data: each value
+// is compared with the median of the centered window of HalfWindow non-null
+// neighbors on either side, and flagged when it is at least Threshold scaled
+// MADs from it. Scores are those robust z-scores. A row whose window has a
+// MAD of zero, as when most of its values are equal, gets a null score and
+// is left as it is. Filter additionally replaces each flagged value with its
+// window median.
+type HampelDetector struct {
+	// HalfWindow is the number of rows on each side; zero means
+	// DefaultHampelHalfWindow.
+	HalfWindow int
+	// Threshold is in scaled MADs; zero means DefaultThreshold.
+	Threshold float64
+}
+
+var _ Detector = HampelDetector{}
+
+// Detect implements Detector.
+func (d HampelDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	res, repaired, err := d.Filter(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	repaired.Release()
+	return res, nil
+}
+
+// Filter runs the filter over col and returns the Result along with a copy of
+// col in which every flagged value is replaced by its window median. The
+// caller must Release both.
+func (d HampelDetector) Filter(ctx context.Context, col arrow.Array) (*Result, *array.Float64, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer floatCol.Release()
+	half := d.HalfWindow
+	if half == 0 {
+		half = DefaultHampelHalfWindow
+	}
+	if half < 1 {
+		return nil, nil, fmt.Errorf("half window must be at least 1, got %d", half)
+	}
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = DefaultThreshold
+	}
+
+	// Positions of the non-null values, so windows count values, not rows.
+	valid := make([]int, 0, floatCol.Len()-floatCol.NullN())
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsValid(i) {
+			valid = append(valid, i)
+		}
+	}
+
+	mem := compute.GetAllocator(ctx)
+	scores, mask, repaired := array.NewFloat64Builder(mem), array.NewBooleanBuilder(mem), array.NewFloat64Builder(mem)
+	defer scores.Release()
+	defer mask.Release()
+	defer repaired.Release()
+	scores.Reserve(floatCol.Len())
+	mask.Reserve(floatCol.Len())
+	repaired.Reserve(floatCol.Len())
+
+	window := make([]float64, 0, 2*half+1)
+	next := 0
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			scores.AppendNull()
+			mask.AppendNull()
+			repaired.AppendNull()
+			continue
+		}
+		window = window[:0]
+		for _, j := range valid[max(0, next-half):min(len(valid), next+half+1)] {
+			window = append(window, floatCol.Value(j))
+		}
+		next++
+		median := medianInPlace(window)
+		for k, v := range window {
+			window[k] = math.Abs(v - median)
+		}
+		scale := DefaultMADScale * medianInPlace(window)
+
+		x := floatCol.Value(i)
+		if scale == 0 {
+			scores.AppendNull()
+			mask.Append(false)
+			repaired.Append(x)
+			continue
+		}
+		z := (x - median) / scale
+		if x == median {
+			z = 0
+		}
+		flagged := math.Abs(z) >= threshold
+		scores.Append(z)
+		mask.Append(flagged)
+		if flagged {
+			repaired.Append(median)
+		} else {
+			repaired.Append(x)
+		}
+	}
+
+	res, err := newResult(ctx, floatCol, mask.NewBooleanArray(), scores.NewFloat64Array(), accumulate(floatCol))
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, repaired.NewFloat64Array(), nil
+}
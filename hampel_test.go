@@ -0,0 +1,73 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestHampelFilter(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// A ramp with a spike at row 5; the ramp itself is never a spike even
+	// though its later values are far from the global median.
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{1, 2, 3, 4, 5, 60, 7, 8, 9, 10, 11, 12}, nil)
+	b.AppendNull()
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	res, repaired, err := HampelDetector{HalfWindow: 2}.Filter(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	defer repaired.Release()
+
+	if res.Indices.Len() != 1 || res.Indices.Value(0) != 5 {
+		t.Errorf("indices = %v, want [5]", res.Indices)
+	}
+	if got := repaired.Value(5); got != 7 {
+		t.Errorf("repaired[5] = %v, want the window median 7", got)
+	}
+	if got := repaired.Value(4); got != 5 {
+		t.Errorf("repaired[4] = %v, want it unchanged", got)
+	}
+	if repaired.IsValid(12) {
+		t.Errorf("expected null to stay null")
+	}
+}
+
+func TestHampelFlatWindow(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// Most values are 5, so every window's MAD is zero and no row can be
+	// scored: the odd values are neither flagged nor replaced.
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{5, 5, 5, 6, 5, 5, 5, 5, 9, 5, 5, 5}, nil)
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	res, repaired, err := HampelDetector{HalfWindow: 3}.Filter(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	defer repaired.Release()
+
+	if res.Indices.Len() != 0 || res.Zscore.NullN() != col.Len() {
+		t.Errorf("flagged %v with %d null scores, want none flagged and all null", res.Indices, res.Zscore.NullN())
+	}
+	if repaired.Value(3) != 6 || repaired.Value(8) != 9 {
+		t.Errorf("repaired = %v, want the input unchanged", repaired)
+	}
+}
@@ -0,0 +1,63 @@
+package supercharged
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// hllPrecision is the number of hash bits choosing a register; 2^14
+// registers give a standard error of about 0.8%.
+const hllPrecision = 14
+
+// hyperLogLog estimates the number of distinct values added to it in fixed
+// memory.
+type hyperLogLog struct {
+	seed      maphash.Seed
+	registers [1 << hllPrecision]uint8
+}
+
+func newHyperLogLog() *hyperLogLog {
+	return &hyperLogLog{seed: maphash.MakeSeed()}
+}
+
+// addString adds a value by its byte representation.
+func (h *hyperLogLog) addString(s string) {
+	h.addHash(maphash.String(h.seed, s))
+}
+
+// addUint64 adds a fixed-width value by its bits.
+func (h *hyperLogLog) addUint64(v uint64) {
+	var b [8]byte
+	for i := range b {
+		b[i] = byte(v >> (8 * i))
+	}
+	h.addHash(maphash.Bytes(h.seed, b[:]))
+}
+
+func (h *hyperLogLog) addHash(x uint64) {
+	idx := x >> (64 - hllPrecision)
+	rank := uint8(bits.LeadingZeros64(x<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	if rank > h.registers[idx] {
+		h.registers[idx] = rank
+	}
+}
+
+// estimate returns the approximate distinct count, using linear counting
+// while many registers are still empty.
+func (h *hyperLogLog) estimate() int64 {
+	const m = float64(len(h.registers))
+	var sum float64
+	var zeros int
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	e := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if e <= 2.5*m && zeros > 0 {
+		e = m * math.Log(m/float64(zeros))
+	}
+	return int64(math.Round(e))
+}
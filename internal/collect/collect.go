@@ -0,0 +1,114 @@
+// Package collect gathers streamed Arrow records into a single record.
+package collect
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// Columns drains recs and returns one record holding the named columns, each
+// concatenated across batches, then reports the first error from errs, or
+// ctx's error when it ended the stream early. Each received record is
+// released.
+func Columns(ctx context.Context, recs <-chan arrow.Record, errs <-chan error, columns []string, mem memory.Allocator) (arrow.Record, error) {
+	fields, chunks, rows, err := gather(ctx, recs, errs, columns)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseAll(chunks)
+
+	cols := make([]arrow.Array, len(columns))
+	for i, cs := range chunks {
+		concat, err := array.Concatenate(cs, mem)
+		if err != nil {
+			for _, c := range cols[:i] {
+				c.Release()
+			}
+			return nil, err
+		}
+		cols[i] = concat
+	}
+	rec := array.NewRecord(arrow.NewSchema(fields, nil), cols, rows)
+	for _, c := range cols {
+		c.Release()
+	}
+	return rec, nil
+}
+
+// Chunked drains recs and returns the named column as one chunk per batch,
+// without concatenating, then reports errors as Columns does. Each received
+// record is released.
+func Chunked(ctx context.Context, recs <-chan arrow.Record, errs <-chan error, column string) (*arrow.Chunked, error) {
+	fields, chunks, _, err := gather(ctx, recs, errs, []string{column})
+	if err != nil {
+		return nil, err
+	}
+	defer releaseAll(chunks)
+	return arrow.NewChunked(fields[0].Type, chunks[0]), nil
+}
+
+// gather drains recs, retaining the named columns of every batch.
+func gather(ctx context.Context, recs <-chan arrow.Record, errs <-chan error, columns []string) ([]arrow.Field, [][]arrow.Array, int64, error) {
+	chunks := make([][]arrow.Array, len(columns))
+	var fields []arrow.Field
+	var rows int64
+	var missing error
+	for rec := range recs {
+		if missing != nil {
+			rec.Release()
+			continue
+		}
+		if fields == nil {
+			fields = make([]arrow.Field, len(columns))
+			for i, name := range columns {
+				idx := rec.Schema().FieldIndices(name)
+				if len(idx) == 0 {
+					missing = fmt.Errorf("column %s not found", name)
+					break
+				}
+				fields[i] = rec.Schema().Field(idx[0])
+			}
+			if missing != nil {
+				rec.Release()
+				continue
+			}
+		}
+		for i, name := range columns {
+			col := rec.Column(rec.Schema().FieldIndices(name)[0])
+			col.Retain()
+			chunks[i] = append(chunks[i], col)
+		}
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	err := <-errs
+	if err == nil {
+		// A cancelled stream ends early without an error of its own.
+		err = ctx.Err()
+	}
+	if err != nil {
+		releaseAll(chunks)
+		return nil, nil, 0, err
+	}
+	if missing != nil {
+		releaseAll(chunks)
+		return nil, nil, 0, missing
+	}
+	if rows == 0 {
+		releaseAll(chunks)
+		return nil, nil, 0, fmt.Errorf("no data for columns %v", columns)
+	}
+	return fields, chunks, rows, nil
+}
+
+func releaseAll(chunks [][]arrow.Array) {
+	for _, cs := range chunks {
+		for _, c := range cs {
+			c.Release()
+		}
+	}
+}
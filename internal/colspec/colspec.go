@@ -0,0 +1,106 @@
+// Package colspec resolves column selectors against a schema. A selector is
+// a column name, a 0-based column index, a glob such as latency_* or a
+// regular expression between slashes such as /^latency_p(50|99)$/. A column
+// whose name equals the selector always wins, so a column named "3" or "a*"
+// can still be selected by name.
+package colspec
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// IsPattern reports whether sel selects columns by index or pattern rather
+// than by name.
+func IsPattern(sel string) bool {
+	if _, err := strconv.Atoi(sel); err == nil {
+		return true
+	}
+	return isRegexp(sel) || strings.ContainsAny(sel, "*?[")
+}
+
+func isRegexp(sel string) bool {
+	return len(sel) > 2 && strings.HasPrefix(sel, "/") && strings.HasSuffix(sel, "/")
+}
+
+// Resolve returns the names of the columns of schema that sel selects, in
+// schema order. It is an error for sel to select none.
+func Resolve(schema *arrow.Schema, sel string) ([]string, error) {
+	if schema.HasField(sel) {
+		return []string{sel}, nil
+	}
+	fields := schema.Fields()
+	if i, err := strconv.Atoi(sel); err == nil {
+		if i < 0 || i >= len(fields) {
+			return nil, fmt.Errorf("column index %d out of range [0, %d)", i, len(fields))
+		}
+		return []string{fields[i].Name}, nil
+	}
+
+	var match func(string) bool
+	switch {
+	case isRegexp(sel):
+		re, err := regexp.Compile(sel[1 : len(sel)-1])
+		if err != nil {
+			return nil, fmt.Errorf("column pattern %s: %w", sel, err)
+		}
+		match = re.MatchString
+	case strings.ContainsAny(sel, "*?["):
+		if _, err := path.Match(sel, ""); err != nil {
+			return nil, fmt.Errorf("column pattern %s: %w", sel, err)
+		}
+		match = func(name string) bool {
+			ok, _ := path.Match(sel, name)
+			return ok
+		}
+	default:
+		return nil, fmt.Errorf("column %s not found", sel)
+	}
+	var names []string
+	for _, f := range fields {
+		if match(f.Name) {
+			names = append(names, f.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no columns match %s", sel)
+	}
+	return names, nil
+}
+
+// ResolveAll resolves each selector in sels and returns the selected names
+// in order, each once.
+func ResolveAll(schema *arrow.Schema, sels []string) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, sel := range sels {
+		matched, err := Resolve(schema, sel)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range matched {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names, nil
+}
+
+// ResolveOne resolves sel to a single column name.
+func ResolveOne(schema *arrow.Schema, sel string) (string, error) {
+	names, err := Resolve(schema, sel)
+	if err != nil {
+		return "", err
+	}
+	if len(names) > 1 {
+		return "", fmt.Errorf("%s matches %d columns (%s)", sel, len(names), strings.Join(names, ", "))
+	}
+	return names[0], nil
+}
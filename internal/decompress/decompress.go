@@ -0,0 +1,76 @@
+// Package decompress transparently unwraps gzip- and zstd-compressed input.
+package decompress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Reader decompresses its underlying reader when it starts with a gzip or
+// zstd frame and passes it through otherwise. The format is detected lazily
+// on the first Read, so constructing one consumes no input.
+type Reader struct {
+	src  io.Reader
+	out  io.Reader
+	zstd *zstd.Decoder
+	name string
+	err  error
+}
+
+// NewReader returns a Reader over r. Errors opening the compressed stream
+// surface from Read.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{src: r}
+}
+
+func (d *Reader) init() {
+	if d.out != nil || d.err != nil {
+		return
+	}
+	br := bufio.NewReader(d.src)
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		d.name = "gzip"
+		d.out, d.err = gzip.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		d.name = "zstd"
+		// A single-goroutine decoder runs synchronously, so an abandoned
+		// Reader leaks nothing.
+		d.zstd, d.err = zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		d.out = d.zstd
+	default:
+		d.out = br
+	}
+}
+
+// Read implements io.Reader.
+func (d *Reader) Read(p []byte) (int, error) {
+	d.init()
+	if d.err != nil {
+		return 0, d.err
+	}
+	n, err := d.out.Read(p)
+	if err == io.EOF && d.zstd != nil {
+		// A closed decoder errors on reuse; keep reporting EOF instead.
+		d.zstd.Close()
+		d.zstd, d.out = nil, bytes.NewReader(nil)
+	}
+	return n, err
+}
+
+// Compression returns "gzip", "zstd" or "" for uncompressed input, sniffing
+// it first if nothing has been read yet.
+func (d *Reader) Compression() string {
+	d.init()
+	return d.name
+}
@@ -0,0 +1,19 @@
+// Package mmap maps files into memory read-only, so their bytes are paged in
+// from disk on demand instead of being read onto the Go heap.
+package mmap
+
+import "os"
+
+// Map maps the whole of f and returns its bytes and the func that unmaps
+// them, after which neither the bytes nor anything sharing them may be used.
+// The mapping outlives f being closed. An empty file maps to a nil slice.
+func Map(f *os.File) ([]byte, func() error, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.Size() == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	return mapFile(f, info.Size())
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package mmap
+
+import (
+	"errors"
+	"os"
+)
+
+// Supported reports whether Map is available on this system.
+const Supported = false
+
+func mapFile(*os.File, int64) ([]byte, func() error, error) {
+	return nil, nil, errors.New("memory mapping is not supported on this system")
+}
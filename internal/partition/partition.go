@@ -0,0 +1,189 @@
+// Package partition reads the Hive-style partitions of a dataset laid out as
+// key=value directories, such as dt=2024-01-01/region=us-east/part-0.parquet,
+// and selects partitions by glob patterns on their values.
+package partition
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DefaultPartition is the directory value Hive writes for a null key.
+const DefaultPartition = "__HIVE_DEFAULT_PARTITION__"
+
+// Value is one key=value directory of a file's path.
+type Value struct {
+	Key   string
+	Value string
+	Null  bool
+}
+
+// Parse returns the partition values of the directories of dir, a path
+// relative to the dataset root, in order. Values are unescaped as Hive
+// escapes them; directories that are not key=value are skipped.
+func Parse(dir string) ([]Value, error) {
+	var values []Value
+	for _, seg := range strings.Split(filepath.ToSlash(dir), "/") {
+		key, raw, ok := strings.Cut(seg, "=")
+		if !ok || key == "" {
+			continue
+		}
+		if raw == DefaultPartition {
+			values = append(values, Value{Key: key, Null: true})
+			continue
+		}
+		v, err := url.PathUnescape(raw)
+		if err != nil {
+			return nil, fmt.Errorf("partition %s: %w", seg, err)
+		}
+		values = append(values, Value{Key: key, Value: v})
+	}
+	return values, nil
+}
+
+// Fields returns the partition columns shared by the files whose values are
+// given, typed int64 when every value is an integer, float64 when every one
+// is a number, and utf8 otherwise. Every file must have the same keys in the
+// same order.
+func Fields(files [][]Value) ([]arrow.Field, error) {
+	if len(files) == 0 || len(files[0]) == 0 {
+		for _, values := range files {
+			if len(values) > 0 {
+				return nil, fmt.Errorf("some files are partitioned by %s and some are not", keys(values))
+			}
+		}
+		return nil, nil
+	}
+	first := files[0]
+	ints := make([]bool, len(first))
+	floats := make([]bool, len(first))
+	for i := range first {
+		ints[i], floats[i] = true, true
+	}
+	for _, values := range files {
+		if keys(values) != keys(first) {
+			return nil, fmt.Errorf("inconsistent partition keys: %s and %s", keys(first), keys(values))
+		}
+		for i, v := range values {
+			if v.Null {
+				continue
+			}
+			if _, err := strconv.ParseInt(v.Value, 10, 64); err != nil {
+				ints[i] = false
+			}
+			if _, err := strconv.ParseFloat(v.Value, 64); err != nil {
+				floats[i] = false
+			}
+		}
+	}
+	fields := make([]arrow.Field, len(first))
+	for i, v := range first {
+		var dt arrow.DataType = arrow.BinaryTypes.String
+		switch {
+		case ints[i]:
+			dt = arrow.PrimitiveTypes.Int64
+		case floats[i]:
+			dt = arrow.PrimitiveTypes.Float64
+		}
+		fields[i] = arrow.Field{Name: v.Key, Type: dt, Nullable: true}
+	}
+	return fields, nil
+}
+
+func keys(values []Value) string {
+	names := make([]string, len(values))
+	for i, v := range values {
+		names[i] = v.Key
+	}
+	return strings.Join(names, "/")
+}
+
+// Array returns a column of n copies of v, of type dt as chosen by Fields.
+func Array(mem memory.Allocator, dt arrow.DataType, v Value, n int) (arrow.Array, error) {
+	b := array.NewBuilder(mem, dt)
+	defer b.Release()
+	b.Reserve(n)
+	if v.Null {
+		b.AppendNulls(n)
+		return b.NewArray(), nil
+	}
+	switch b := b.(type) {
+	case *array.Int64Builder:
+		x, err := strconv.ParseInt(v.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("partition %s=%s: %w", v.Key, v.Value, err)
+		}
+		for range n {
+			b.Append(x)
+		}
+	case *array.Float64Builder:
+		x, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("partition %s=%s: %w", v.Key, v.Value, err)
+		}
+		for range n {
+			b.Append(x)
+		}
+	case *array.StringBuilder:
+		for range n {
+			b.Append(v.Value)
+		}
+	default:
+		return nil, fmt.Errorf("partition %s: unsupported type %s", v.Key, dt)
+	}
+	return b.NewArray(), nil
+}
+
+// Filter selects the partitions whose Key value matches the glob Pattern,
+// in the syntax of path.Match. A null value matches as DefaultPartition.
+type Filter struct {
+	Key     string
+	Pattern string
+}
+
+// ParseFilters parses key=pattern entries, such as dt=2024-01-*.
+func ParseFilters(entries []string) ([]Filter, error) {
+	filters := make([]Filter, 0, len(entries))
+	for _, e := range entries {
+		key, pattern, ok := strings.Cut(e, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("partition filter %q: want key=pattern", e)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("partition filter %q: %w", e, err)
+		}
+		filters = append(filters, Filter{Key: key, Pattern: pattern})
+	}
+	return filters, nil
+}
+
+// Match reports whether values pass every filter. A filter on a key that
+// values lack fails.
+func Match(filters []Filter, values []Value) bool {
+	for _, f := range filters {
+		matched := false
+		for _, v := range values {
+			if v.Key != f.Key {
+				continue
+			}
+			s := v.Value
+			if v.Null {
+				s = DefaultPartition
+			}
+			matched, _ = path.Match(f.Pattern, s)
+			break
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,180 @@
+package partition
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want []Value
+	}{
+		{"", nil},
+		{".", nil},
+		{"dt=2024-01-01/region=us-east", []Value{{Key: "dt", Value: "2024-01-01"}, {Key: "region", Value: "us-east"}}},
+		// Directories that are not key=value are skipped.
+		{"raw/dt=2024-01-01/extra", []Value{{Key: "dt", Value: "2024-01-01"}}},
+		{"=x/k=v", []Value{{Key: "k", Value: "v"}}},
+		// An empty value is an empty string; Hive's default partition is null.
+		{"k=", []Value{{Key: "k"}}},
+		{"k=" + DefaultPartition, []Value{{Key: "k", Null: true}}},
+		{"ts=2024-01-01 10%3A00%3A00/a=b=c", []Value{{Key: "ts", Value: "2024-01-01 10:00:00"}, {Key: "a", Value: "b=c"}}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.dir)
+		if err != nil {
+			t.Errorf("%q: %v", tt.dir, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %+v, want %+v", tt.dir, got, tt.want)
+		}
+	}
+
+	if _, err := Parse("k=%zz"); err == nil {
+		t.Error("bad escape: want error")
+	}
+}
+
+func TestFields(t *testing.T) {
+	files := [][]Value{
+		{{Key: "n", Value: "1"}, {Key: "x", Value: "1"}, {Key: "s", Value: "a"}, {Key: "z", Null: true}},
+		{{Key: "n", Null: true}, {Key: "x", Value: "2.5"}, {Key: "s", Value: "2"}, {Key: "z", Null: true}},
+		{{Key: "n", Value: "-3"}, {Key: "x", Value: "1e3"}, {Key: "s", Value: ""}, {Key: "z", Null: true}},
+	}
+	fields, err := Fields(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nulls do not decide a type, so a column of nulls alone is int64.
+	want := []arrow.Field{
+		{Name: "n", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+		{Name: "x", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "s", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "z", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got  %v\nwant %v", fields, want)
+	}
+
+	for _, files := range [][][]Value{nil, {nil, nil}} {
+		if fields, err := Fields(files); fields != nil || err != nil {
+			t.Errorf("%v: got %v, %v, want no fields", files, fields, err)
+		}
+	}
+}
+
+func TestFieldsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files [][]Value
+		want  string
+	}{
+		{"unpartitioned first", [][]Value{nil, {{Key: "dt", Value: "1"}}}, "some files are partitioned by dt"},
+		{"unpartitioned later", [][]Value{{{Key: "dt", Value: "1"}}, nil}, "inconsistent partition keys: dt and "},
+		{"other key", [][]Value{{{Key: "dt", Value: "1"}}, {{Key: "day", Value: "1"}}}, "dt and day"},
+		{"other order", [][]Value{
+			{{Key: "a", Value: "1"}, {Key: "b", Value: "1"}},
+			{{Key: "b", Value: "1"}, {Key: "a", Value: "1"}},
+		}, "a/b and b/a"},
+	}
+	for _, tt := range tests {
+		_, err := Fields(tt.files)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestArray(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	tests := []struct {
+		dt   arrow.DataType
+		v    Value
+		want string
+	}{
+		{arrow.PrimitiveTypes.Int64, Value{Key: "n", Value: "7"}, "[7 7 7]"},
+		{arrow.PrimitiveTypes.Float64, Value{Key: "x", Value: "2.5"}, "[2.5 2.5 2.5]"},
+		{arrow.BinaryTypes.String, Value{Key: "s", Value: "a"}, `["a" "a" "a"]`},
+		{arrow.BinaryTypes.String, Value{Key: "s"}, `["" "" ""]`},
+		{arrow.PrimitiveTypes.Int64, Value{Key: "n", Null: true}, "[(null) (null) (null)]"},
+	}
+	for _, tt := range tests {
+		arr, err := Array(pool, tt.dt, tt.v, 3)
+		if err != nil {
+			t.Errorf("%+v: %v", tt.v, err)
+			continue
+		}
+		if got := arr.String(); got != tt.want || !arrow.TypeEqual(arr.DataType(), tt.dt) {
+			t.Errorf("%+v: got %s %s, want %s %s", tt.v, arr.DataType(), got, tt.dt, tt.want)
+		}
+		arr.Release()
+	}
+
+	for _, tt := range []struct {
+		dt arrow.DataType
+		v  Value
+	}{
+		{arrow.PrimitiveTypes.Int64, Value{Key: "n", Value: "1.5"}},
+		{arrow.PrimitiveTypes.Float64, Value{Key: "x", Value: ""}},
+		{arrow.FixedWidthTypes.Boolean, Value{Key: "b", Value: "true"}},
+	} {
+		if arr, err := Array(pool, tt.dt, tt.v, 3); err == nil {
+			arr.Release()
+			t.Errorf("%s %+v: want error", tt.dt, tt.v)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	filters, err := ParseFilters([]string{"dt=2024-01-*", "region=us-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{"dt=2024-01-05/region=us-east", true},
+		{"region=us-west/dt=2024-01-31", true},
+		{"dt=2024-02-01/region=us-east", false},
+		{"dt=2024-01-05/region=eu-west", false},
+		// A filter on a key the path lacks fails.
+		{"dt=2024-01-05", false},
+		{"dt=2024-01-05/region=" + DefaultPartition, false},
+	}
+	for _, tt := range tests {
+		values, err := Parse(tt.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := Match(filters, values); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+
+	// Null values match as the default partition name, and no filters
+	// match everything.
+	null := []Value{{Key: "region", Null: true}}
+	if !Match([]Filter{{Key: "region", Pattern: "__HIVE_*"}}, null) || !Match(nil, null) {
+		t.Error("null region: want a match")
+	}
+	if !Match([]Filter{{Key: "region", Pattern: ""}}, []Value{{Key: "region"}}) {
+		t.Error("empty region: want a match for an empty pattern")
+	}
+}
+
+func TestParseFiltersErrors(t *testing.T) {
+	for _, entry := range []string{"dt", "=2024", "dt=[2024"} {
+		if _, err := ParseFilters([]string{entry}); err == nil {
+			t.Errorf("%q: want error", entry)
+		}
+	}
+}
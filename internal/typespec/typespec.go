@@ -0,0 +1,169 @@
+// Package typespec parses column type overrides such as
+// value:float64,ts:timestamp[ms]. Types are named as Arrow prints them
+// (int64, float64, utf8, timestamp[ms, tz=UTC], decimal128(10, 2), ...),
+// with the aliases int, float, double, string, boolean and date.
+package typespec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+var named = map[string]arrow.DataType{
+	"int8":         arrow.PrimitiveTypes.Int8,
+	"int16":        arrow.PrimitiveTypes.Int16,
+	"int32":        arrow.PrimitiveTypes.Int32,
+	"int64":        arrow.PrimitiveTypes.Int64,
+	"int":          arrow.PrimitiveTypes.Int64,
+	"uint8":        arrow.PrimitiveTypes.Uint8,
+	"uint16":       arrow.PrimitiveTypes.Uint16,
+	"uint32":       arrow.PrimitiveTypes.Uint32,
+	"uint64":       arrow.PrimitiveTypes.Uint64,
+	"float16":      arrow.FixedWidthTypes.Float16,
+	"float32":      arrow.PrimitiveTypes.Float32,
+	"float64":      arrow.PrimitiveTypes.Float64,
+	"float":        arrow.PrimitiveTypes.Float64,
+	"double":       arrow.PrimitiveTypes.Float64,
+	"bool":         arrow.FixedWidthTypes.Boolean,
+	"boolean":      arrow.FixedWidthTypes.Boolean,
+	"utf8":         arrow.BinaryTypes.String,
+	"string":       arrow.BinaryTypes.String,
+	"large_utf8":   arrow.BinaryTypes.LargeString,
+	"binary":       arrow.BinaryTypes.Binary,
+	"large_binary": arrow.BinaryTypes.LargeBinary,
+	"date32":       arrow.FixedWidthTypes.Date32,
+	"date":         arrow.FixedWidthTypes.Date32,
+	"date64":       arrow.FixedWidthTypes.Date64,
+}
+
+var units = map[string]arrow.TimeUnit{
+	"s":  arrow.Second,
+	"ms": arrow.Millisecond,
+	"us": arrow.Microsecond,
+	"ns": arrow.Nanosecond,
+}
+
+// Parse parses a comma-separated list of name:type pairs.
+func Parse(spec string) (map[string]arrow.DataType, error) {
+	types := map[string]arrow.DataType{}
+	for _, entry := range split(spec) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, typ, ok := strings.Cut(entry, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("schema entry %q: want name:type", entry)
+		}
+		dt, err := ParseType(typ)
+		if err != nil {
+			return nil, fmt.Errorf("schema entry %q: %w", entry, err)
+		}
+		types[strings.TrimSpace(name)] = dt
+	}
+	return types, nil
+}
+
+// split splits spec at the commas outside brackets and parentheses.
+func split(spec string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, c := range spec {
+		switch c {
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, spec[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, spec[start:])
+}
+
+// ParseType parses a single type name.
+func ParseType(s string) (arrow.DataType, error) {
+	s = strings.TrimSpace(s)
+	if dt, ok := named[strings.ToLower(s)]; ok {
+		return dt, nil
+	}
+	base, args, err := arguments(s)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(base) {
+	case "timestamp":
+		unit, tz := arrow.Nanosecond, ""
+		if len(args) > 0 {
+			if unit, err = timeUnit(args[0]); err != nil {
+				return nil, err
+			}
+		}
+		if len(args) > 1 {
+			tz = strings.TrimPrefix(args[1], "tz=")
+		}
+		if len(args) > 2 {
+			return nil, fmt.Errorf("type %q: want timestamp[unit] or timestamp[unit, tz]", s)
+		}
+		return &arrow.TimestampType{Unit: unit, TimeZone: tz}, nil
+	case "time32":
+		unit := arrow.Second
+		if len(args) == 1 {
+			if unit, err = timeUnit(args[0]); err != nil {
+				return nil, err
+			}
+		} else if len(args) > 1 {
+			return nil, fmt.Errorf("type %q: want time32[unit]", s)
+		}
+		if unit != arrow.Second && unit != arrow.Millisecond {
+			return nil, fmt.Errorf("type %q: time32 takes s or ms", s)
+		}
+		return &arrow.Time32Type{Unit: unit}, nil
+	case "decimal", "decimal128", "decimal256":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("type %q: want %s(precision, scale)", s, base)
+		}
+		prec, err1 := strconv.Atoi(args[0])
+		scale, err2 := strconv.Atoi(args[1])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("type %q: precision and scale must be integers", s)
+		}
+		if strings.EqualFold(base, "decimal256") {
+			return &arrow.Decimal256Type{Precision: int32(prec), Scale: int32(scale)}, nil
+		}
+		return &arrow.Decimal128Type{Precision: int32(prec), Scale: int32(scale)}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", s)
+}
+
+// arguments splits s into a base name and the arguments between the
+// brackets or parentheses following it, if any.
+func arguments(s string) (string, []string, error) {
+	i := strings.IndexAny(s, "[(")
+	if i < 0 {
+		return s, nil, nil
+	}
+	closing := map[byte]byte{'[': ']', '(': ')'}[s[i]]
+	if s[len(s)-1] != closing {
+		return "", nil, fmt.Errorf("type %q: missing %q", s, closing)
+	}
+	var args []string
+	for _, a := range strings.Split(s[i+1:len(s)-1], ",") {
+		args = append(args, strings.TrimSpace(a))
+	}
+	return s[:i], args, nil
+}
+
+func timeUnit(s string) (arrow.TimeUnit, error) {
+	unit, ok := units[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown time unit %q (want s, ms, us or ns)", s)
+	}
+	return unit, nil
+}
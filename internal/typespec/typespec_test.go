@@ -0,0 +1,135 @@
+package typespec
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// format prints types as name:type, sorted by name.
+func format(types map[string]arrow.DataType) string {
+	var parts []string
+	for name, dt := range types {
+		parts = append(parts, name+":"+dt.String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"", ""},
+		{"v:float64", "v:float64"},
+		{" a : int , b:String,", "a:int64 b:utf8"},
+		// Commas inside brackets and parentheses do not end an entry.
+		{"ts:timestamp[ms, tz=UTC],d:decimal128(10, 2)", "d:decimal(10, 2) ts:timestamp[ms, tz=UTC]"},
+		{"v:int32,v:bool", "v:bool"},
+	}
+	for _, tt := range tests {
+		types, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tt.spec, err)
+			continue
+		}
+		if got := format(types); got != tt.want {
+			t.Errorf("%q: got %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"v", "want name:type"},
+		{" :int64", "want name:type"},
+		{"a:int64,b", `"b": want name:type`},
+		{"v:nope", `unknown type "nope"`},
+		{"v:timestamp[xs]", "unknown time unit"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.spec)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got %v, want an error containing %q", tt.spec, err, tt.want)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want arrow.DataType
+	}{
+		{"float", arrow.PrimitiveTypes.Float64},
+		{"DOUBLE", arrow.PrimitiveTypes.Float64},
+		{"date", arrow.FixedWidthTypes.Date32},
+		{"large_utf8", arrow.BinaryTypes.LargeString},
+		{"timestamp", &arrow.TimestampType{Unit: arrow.Nanosecond}},
+		{"timestamp[s]", &arrow.TimestampType{Unit: arrow.Second}},
+		{"timestamp[us, Europe/Paris]", &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "Europe/Paris"}},
+		{"time32", &arrow.Time32Type{Unit: arrow.Second}},
+		{"time32[ms]", &arrow.Time32Type{Unit: arrow.Millisecond}},
+		{"decimal(5, 1)", &arrow.Decimal128Type{Precision: 5, Scale: 1}},
+		{"decimal256(40,3)", &arrow.Decimal256Type{Precision: 40, Scale: 3}},
+	}
+	for _, tt := range tests {
+		got, err := ParseType(tt.typ)
+		if err != nil {
+			t.Errorf("%s: %v", tt.typ, err)
+			continue
+		}
+		if !arrow.TypeEqual(got, tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.typ, got, tt.want)
+		}
+	}
+
+	// Types read back from the names Arrow prints for them.
+	for _, dt := range []arrow.DataType{
+		arrow.PrimitiveTypes.Uint16,
+		arrow.FixedWidthTypes.Float16,
+		arrow.BinaryTypes.LargeBinary,
+		arrow.FixedWidthTypes.Date64,
+		&arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"},
+		&arrow.Time32Type{Unit: arrow.Millisecond},
+		&arrow.Decimal128Type{Precision: 10, Scale: 2},
+	} {
+		got, err := ParseType(dt.String())
+		if err != nil {
+			t.Errorf("%s: %v", dt, err)
+			continue
+		}
+		if !arrow.TypeEqual(got, dt) {
+			t.Errorf("%s: got %s", dt, got)
+		}
+	}
+}
+
+func TestParseTypeErrors(t *testing.T) {
+	tests := []struct {
+		typ  string
+		want string
+	}{
+		{"", "unknown type"},
+		{"list<int64>", "unknown type"},
+		{"timestamp[ms", "missing ']'"},
+		{"decimal(10, 2", "missing ')'"},
+		{"timestamp[]", "unknown time unit"},
+		{"timestamp[ms, UTC, x]", "want timestamp[unit]"},
+		{"time32[us]", "time32 takes s or ms"},
+		{"time32[s, ms]", "want time32[unit]"},
+		{"decimal(10)", "want decimal(precision, scale)"},
+		{"decimal128(a, 2)", "must be integers"},
+	}
+	for _, tt := range tests {
+		_, err := ParseType(tt.typ)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: got %v, want an error containing %q", tt.typ, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package supercharged
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// Names of the columns ToRecord returns besides ScoreColumn and
+// AnomalyColumn.
+const (
+	IndexColumn  = "index"
+	ValueColumn  = "value"
+	PValueColumn = "p_value"
+)
+
+// ToRecord returns the Result as a record with one row per analyzed value:
+// its position (IndexColumn), the value if it was flagged (ValueColumn, null
+// otherwise, as a Result keeps only the flagged values), its score
+// (ScoreColumn) and whether it was flagged (AnomalyColumn), plus PValueColumn
+// when the detector computes p-values. Use Annotate instead to keep every
+// value of the analyzed column. The caller must Release the record.
+func (r *Result) ToRecord() arrow.Record {
+	mem := memory.DefaultAllocator
+	n := r.Mask.Len()
+	index := array.NewInt64Builder(mem)
+	defer index.Release()
+	value := array.NewFloat64Builder(mem)
+	defer value.Release()
+	index.Reserve(n)
+	value.Reserve(n)
+	flagged := 0
+	for i := 0; i < n; i++ {
+		index.Append(int64(i))
+		if flagged < r.Indices.Len() && r.Indices.Value(flagged) == int64(i) {
+			value.Append(r.Values.Value(flagged))
+			flagged++
+		} else {
+			value.AppendNull()
+		}
+	}
+	indexArr, valueArr := index.NewArray(), value.NewArray()
+	defer indexArr.Release()
+	defer valueArr.Release()
+
+	fields := []arrow.Field{
+		{Name: IndexColumn, Type: arrow.PrimitiveTypes.Int64},
+		{Name: ValueColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: ScoreColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: AnomalyColumn, Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+	}
+	cols := []arrow.Array{indexArr, valueArr, r.Zscore, r.Mask}
+	if r.PValues != nil {
+		fields = append(fields, arrow.Field{Name: PValueColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true})
+		cols = append(cols, r.PValues)
+	}
+	return array.NewRecord(arrow.NewSchema(fields, nil), cols, int64(n))
+}
+
+// WriteIPC writes the Result's ToRecord to w as an Arrow IPC stream, which
+// pyarrow (pa.ipc.open_stream), pandas (via pyarrow) and polars
+// (pl.read_ipc_stream) read without conversion.
+func (r *Result) WriteIPC(w io.Writer) error {
+	rec := r.ToRecord()
+	defer rec.Release()
+	return WriteIPC(w, rec)
+}
+
+// WriteIPC writes rec to w as an Arrow IPC stream of one record batch.
+func WriteIPC(w io.Writer, rec arrow.Record) error {
+	iw := ipc.NewWriter(w, ipc.WithSchema(rec.Schema()), ipc.WithAllocator(memory.DefaultAllocator))
+	if err := iw.Write(rec); err != nil {
+		iw.Close()
+		return fmt.Errorf("write ipc: %w", err)
+	}
+	if err := iw.Close(); err != nil {
+		return fmt.Errorf("write ipc: %w", err)
+	}
+	return nil
+}
+
+// ReadIPC reads an Arrow IPC stream, such as one written by WriteIPC or by
+// pyarrow, and returns its record batches joined into one record. The caller
+// must Release the record.
+func ReadIPC(r io.Reader) (arrow.Record, error) {
+	mem := memory.DefaultAllocator
+	ir, err := ipc.NewReader(r, ipc.WithAllocator(mem))
+	if err != nil {
+		return nil, fmt.Errorf("read ipc: %w", err)
+	}
+	defer ir.Release()
+	var batches []arrow.Record
+	defer func() {
+		for _, b := range batches {
+			b.Release()
+		}
+	}()
+	var rows int64
+	for ir.Next() {
+		rec := ir.Record()
+		rec.Retain()
+		batches = append(batches, rec)
+		rows += rec.NumRows()
+	}
+	if err := ir.Err(); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("read ipc: %w", err)
+	}
+	schema := ir.Schema()
+	cols := make([]arrow.Array, schema.NumFields())
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	for i, f := range schema.Fields() {
+		parts := make([]arrow.Array, len(batches))
+		for j, b := range batches {
+			parts[j] = b.Column(i)
+		}
+		if len(parts) == 0 {
+			cols[i] = array.MakeArrayOfNull(mem, f.Type, 0)
+			continue
+		}
+		if cols[i], err = array.Concatenate(parts, mem); err != nil {
+			return nil, fmt.Errorf("read ipc: %w", err)
+		}
+	}
+	return array.NewRecord(schema, cols, rows), nil
+}
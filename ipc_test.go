@@ -0,0 +1,94 @@
+package supercharged
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestResultToRecord(t *testing.T) {
+	b := array.NewFloat64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.AppendValues([]float64{10, 11, 9, 10, 12, 8, 10, 30, 11, 9}, nil)
+	b.AppendNull()
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	res, err := GrubbsDetector{}.Detect(context.Background(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	rec := res.ToRecord()
+	defer rec.Release()
+
+	want := []string{IndexColumn, ValueColumn, ScoreColumn, AnomalyColumn, PValueColumn}
+	if int(rec.NumCols()) != len(want) || rec.NumRows() != 11 {
+		t.Fatalf("record is %d x %d, want 11 x %d", rec.NumRows(), rec.NumCols(), len(want))
+	}
+	for i, name := range want {
+		if rec.ColumnName(i) != name {
+			t.Errorf("column %d = %s, want %s", i, rec.ColumnName(i), name)
+		}
+	}
+	index := rec.Column(0).(*array.Int64)
+	value := rec.Column(1).(*array.Float64)
+	flag := rec.Column(3).(*array.Boolean)
+	for i := 0; i < 11; i++ {
+		if index.Value(i) != int64(i) {
+			t.Errorf("index %d = %d", i, index.Value(i))
+		}
+		if flag.IsValid(i) && flag.Value(i) != value.IsValid(i) {
+			t.Errorf("row %d: flagged %v but value valid %v", i, flag.Value(i), value.IsValid(i))
+		}
+	}
+	if !value.IsValid(7) || value.Value(7) != 30 {
+		t.Errorf("value at row 7 = %v, want 30", value.Value(7))
+	}
+
+	var buf bytes.Buffer
+	if err := res.WriteIPC(&buf); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadIPC(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer back.Release()
+	if !array.RecordEqual(rec, back) {
+		t.Errorf("IPC round trip changed the record")
+	}
+}
+
+func TestReadIPCBatches(t *testing.T) {
+	var buf bytes.Buffer
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Float64}}, nil)
+	w := ipc.NewWriter(&buf, ipc.WithSchema(schema))
+	for _, vals := range [][]float64{{1, 2}, {3}} {
+		rec := float64Record(t, "v", vals)
+		if err := w.Write(rec); err != nil {
+			t.Fatal(err)
+		}
+		rec.Release()
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := ReadIPC(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	v := rec.Column(0).(*array.Float64).Float64Values()
+	if len(v) != 3 || v[0] != 1 || v[2] != 3 {
+		t.Errorf("values = %v, want [1 2 3]", v)
+	}
+	if _, err := ReadIPC(bytes.NewReader([]byte("not arrow"))); err == nil {
+		t.Error("expected an error for a non-IPC stream")
+	}
+}
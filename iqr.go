@@ -0,0 +1,99 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// DefaultIQRMultiplier is Tukey's fence multiplier for "outside" values.
+const DefaultIQRMultiplier = 1.5
+
+// normalIQR is the interquartile range of the standard normal distribution.
+const normalIQR = 1.349
+
+// IQRDetector flags values outside the Tukey fences Q1 - K*IQR and
+// Q3 + K*IQR. Scores are distances from the midhinge (Q1+Q3)/2 in units of
+// the IQR, so a value is flagged when its absolute score is at least K + 0.5.
+// When more than half the values are equal the IQR is zero, and it is
+// estimated instead as 1.349 standard deviations, taken from the mean
+// absolute deviation from the midhinge as MADDetector does; only a constant
+// column fails, with ErrZeroScale.
+type IQRDetector struct {
+	// K is the fence multiplier; zero means DefaultIQRMultiplier.
+	K float64
+	// Approximate estimates quartiles with a t-digest instead of sorting,
+	// trading a little accuracy for bounded memory on large arrays.
+	Approximate bool
+	// Compression configures the t-digest when Approximate is set.
+	Compression float64
+}
+
+var _ Detector = IQRDetector{}
+
+// Detect implements Detector.
+func (d IQRDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	f, err := d.fit(ctx, []*array.Float64{floatCol})
+	if err != nil {
+		return nil, err
+	}
+	return scoreAgainst(ctx, floatCol, f.center, f.scale, f.threshold, f.stats)
+}
+
+func (d IQRDetector) fit(ctx context.Context, cols []*array.Float64) (fitted, error) {
+	k := d.K
+	if k == 0 {
+		k = DefaultIQRMultiplier
+	}
+	q1, q3 := quantilePair(cols, 0.25, 0.75, d.Approximate, d.Compression)
+	center, scale := (q1+q3)/2, q3-q1
+	if scale == 0 {
+		scale = normalIQR * meanADScale * meanAbsDeviation(cols, center)
+	}
+	return fitted{center: center, scale: scale, threshold: k + 0.5, stats: accumulateAll(ctx, cols)}, nil
+}
+
+// quantilePair returns the lo-th and hi-th quantiles of the non-null values
+// of cols, exactly or from a t-digest.
+func quantilePair(cols []*array.Float64, lo, hi float64, approximate bool, compression float64) (float64, float64) {
+	if approximate {
+		td := NewTDigest(compression)
+		for _, col := range cols {
+			for i := 0; i < col.Len(); i++ {
+				if col.IsValid(i) {
+					td.Add(col.Value(i))
+				}
+			}
+		}
+		return td.Quantile(lo), td.Quantile(hi)
+	}
+	vals := nonNullValues(cols...)
+	sort.Float64s(vals)
+	return quantileSorted(vals, lo), quantileSorted(vals, hi)
+}
+
+// quantileSorted returns the q-th quantile of sorted values using linear
+// interpolation between closest ranks. It returns NaN for an empty slice.
+func quantileSorted(sorted []float64, q float64) float64 {
+	n := len(sorted)
+	if n == 0 {
+		return math.NaN()
+	}
+	h := float64(n-1) * q
+	lo := int(math.Floor(h))
+	if lo >= n-1 {
+		return sorted[n-1]
+	}
+	if lo < 0 {
+		return sorted[0]
+	}
+	return sorted[lo] + (h-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
@@ -0,0 +1,72 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestIQRDetector(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	vals := array.NewFloat64Builder(pool)
+	defer vals.Release()
+	vals.AppendValues([]float64{-40, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 60}, nil)
+	col := vals.NewFloat64Array()
+	defer col.Release()
+
+	for _, approx := range []bool{false, true} {
+		res, err := IQRDetector{Approximate: approx}.Detect(context.Background(), col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < res.Mask.Len(); i++ {
+			if want := i == 0 || i == 11; res.Mask.Value(i) != want {
+				t.Errorf("approx=%t: mask[%d] = %t, want %t", approx, i, res.Mask.Value(i), want)
+			}
+		}
+		res.Release()
+	}
+}
+
+func TestQuantileSorted(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4}
+	cases := map[float64]float64{0: 1, 0.25: 1.75, 0.5: 2.5, 1: 4}
+	for q, want := range cases {
+		if got := quantileSorted(sorted, q); got != want {
+			t.Errorf("quantile(%v) = %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestIQRZeroInflated(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+	col := zeroInflated(pool)
+	defer col.Release()
+
+	for _, approx := range []bool{false, true} {
+		res, err := IQRDetector{Approximate: approx}.Detect(ctx, col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.AnomalousIndices(); !slices.Equal(got, []int64{99}) {
+			t.Errorf("approx=%t: indices = %v, want [99]", approx, got)
+		}
+		res.Release()
+	}
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{4, 4, 4}, nil)
+	constant := b.NewFloat64Array()
+	defer constant.Release()
+	if _, err := (IQRDetector{}).Detect(ctx, constant); !errors.Is(err, ErrZeroScale) {
+		t.Errorf("constant: err = %v, want ErrZeroScale", err)
+	}
+}
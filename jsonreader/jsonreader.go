@@ -0,0 +1,232 @@
+// Package jsonreader provides a streaming newline-delimited JSON reader for Arrow.
+package jsonreader
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/TFMV/supercharged/internal/decompress"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DefaultInferenceRows is the number of lines InferSchemaFromJSON inspects.
+const DefaultInferenceRows = 1000
+
+// JSONReader streams Arrow Records from newline-delimited JSON.
+type JSONReader struct {
+	allocator memory.Allocator
+	schema    *arrow.Schema
+	reader    *array.JSONReader
+}
+
+// NewJSONReader creates a streaming JSONReader with provided schema. Object
+// keys not in the schema are ignored, and gzip or zstd input is decompressed.
+// Records are allocated from mem; nil means a new Go allocator.
+func NewJSONReader(r io.Reader, schema *arrow.Schema, mem memory.Allocator, opts ...array.Option) *JSONReader {
+	allocator := mem
+	if allocator == nil {
+		allocator = memory.NewGoAllocator()
+	}
+	defaultOpts := []array.Option{
+		array.WithAllocator(allocator),
+		array.WithChunk(1024),
+	}
+	allOpts := append(defaultOpts, opts...)
+	reader := array.NewJSONReader(decompress.NewReader(r), schema, allOpts...)
+	return &JSONReader{allocator: allocator, schema: schema, reader: reader}
+}
+
+// Schema returns the schema records are decoded with.
+func (jr *JSONReader) Schema() *arrow.Schema {
+	return jr.schema
+}
+
+// Chan returns a channel of records; caller must Release each.
+func (jr *JSONReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		defer jr.reader.Release()
+		for jr.reader.Next() {
+			rec := jr.reader.Record()
+			rec.Retain()
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+		if err := jr.reader.Err(); err != nil {
+			errs <- fmt.Errorf("json read error: %w", err)
+		}
+	}()
+	return recs, errs
+}
+
+// ReadSingleColumn concatenates all chunks for a named column.
+func (jr *JSONReader) ReadSingleColumn(ctx context.Context, columnName string) (arrow.Array, error) {
+	rec, err := jr.ReadColumns(ctx, []string{columnName})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+// ReadChunked reads the whole input and returns the named column with one
+// chunk per batch, without concatenating them.
+func (jr *JSONReader) ReadChunked(ctx context.Context, columnName string) (*arrow.Chunked, error) {
+	if len(jr.schema.FieldIndices(columnName)) == 0 {
+		return nil, fmt.Errorf("column %s not found", columnName)
+	}
+	recs, errs := jr.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, columnName)
+}
+
+// ReadColumns reads the whole input and returns a single record holding the
+// named columns, each concatenated across chunks. A nil columns slice keeps
+// every column of the schema.
+func (jr *JSONReader) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range jr.schema.Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	for _, name := range columns {
+		if len(jr.schema.FieldIndices(name)) == 0 {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+	}
+	recs, errs := jr.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, jr.allocator)
+}
+
+// InferSchemaFromJSON infers a schema from up to rows lines of
+// newline-delimited JSON (DefaultInferenceRows if rows <= 0). Fields appear in
+// first-seen order. Integers widen to float64 when any row holds a fraction,
+// and conflicting types fall back to string. Nested objects and arrays are
+// left out of the schema.
+func InferSchemaFromJSON(r io.Reader, rows int) (*arrow.Schema, error) {
+	if rows <= 0 {
+		rows = DefaultInferenceRows
+	}
+	var (
+		order []string
+		types = make(map[string]arrow.DataType)
+	)
+	sc := bufio.NewScanner(decompress.NewReader(r))
+	sc.Buffer(make([]byte, 64<<10), 16<<20)
+	seen := 0
+	for seen < rows && sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		seen++
+		err := decodeObject(line, func(k string, v any) {
+			t, ok := jsonType(v)
+			if !ok {
+				return
+			}
+			prev, known := types[k]
+			if !known {
+				order = append(order, k)
+			}
+			types[k] = promote(prev, t)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error inferring schema: line %d: %w", seen, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("error inferring schema: %w", err)
+	}
+	if seen == 0 {
+		return nil, fmt.Errorf("no data found for schema inference")
+	}
+
+	fields := make([]arrow.Field, 0, len(order))
+	for _, name := range order {
+		t := types[name]
+		if t == nil {
+			t = arrow.BinaryTypes.String
+		}
+		fields = append(fields, arrow.Field{Name: name, Type: t, Nullable: true})
+	}
+	return arrow.NewSchema(fields, nil), nil
+}
+
+// decodeObject calls fn for each key of the JSON object in line, in order.
+func decodeObject(line []byte, fn func(key string, value any)) error {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return fmt.Errorf("expected object, got %v", tok)
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		fn(tok.(string), v)
+	}
+	return nil
+}
+
+// jsonType maps a decoded JSON value to an Arrow type. A nil type means the
+// value was null; ok is false for nested values.
+func jsonType(v any) (arrow.DataType, bool) {
+	switch x := v.(type) {
+	case nil:
+		return nil, true
+	case bool:
+		return arrow.FixedWidthTypes.Boolean, true
+	case string:
+		return arrow.BinaryTypes.String, true
+	case json.Number:
+		if _, err := x.Int64(); err == nil {
+			return arrow.PrimitiveTypes.Int64, true
+		}
+		return arrow.PrimitiveTypes.Float64, true
+	default:
+		return nil, false
+	}
+}
+
+// promote returns the narrowest type able to hold values of both a and b.
+func promote(a, b arrow.DataType) arrow.DataType {
+	switch {
+	case a == nil:
+		return b
+	case b == nil || arrow.TypeEqual(a, b):
+		return a
+	case isNumeric(a) && isNumeric(b):
+		return arrow.PrimitiveTypes.Float64
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+func isNumeric(t arrow.DataType) bool {
+	return t.ID() == arrow.INT64 || t.ID() == arrow.FLOAT64
+}
@@ -0,0 +1,167 @@
+package jsonreader
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// fields formats the fields of schema as name:type, in order.
+func fields(schema *arrow.Schema) string {
+	var parts []string
+	for _, f := range schema.Fields() {
+		parts = append(parts, f.Name+":"+f.Type.String())
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestInferSchemaFromJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		rows  int
+		want  string
+	}{
+		{"types", `{"i": 1, "f": 1.5, "s": "x", "b": true}`, 0, "i:int64 f:float64 s:utf8 b:bool"},
+		{"first seen order", "{\"b\": 1}\n{\"a\": 1, \"b\": 2}", 0, "b:int64 a:int64"},
+		{"fraction widens", "{\"v\": 1}\n{\"v\": 2.5}\n{\"v\": 3}", 0, "v:float64"},
+		{"conflict", "{\"v\": 1}\n{\"v\": \"x\"}", 0, "v:utf8"},
+		{"null then value", "{\"v\": null}\n{\"v\": 4}", 0, "v:int64"},
+		{"only nulls", `{"v": null}`, 0, "v:utf8"},
+		{"nested left out", `{"o": {"a": 1}, "l": [1, 2], "v": 1}`, 0, "v:int64"},
+		{"blank lines", "\n{\"v\": 1}\n\n  \n{\"w\": true}\n", 0, "v:int64 w:bool"},
+		{"rows limit", "{\"v\": 1}\n{\"v\": \"x\"}", 1, "v:int64"},
+	}
+	for _, tt := range tests {
+		schema, err := InferSchemaFromJSON(strings.NewReader(tt.input), tt.rows)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := fields(schema); got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+		for _, f := range schema.Fields() {
+			if !f.Nullable {
+				t.Errorf("%s: field %s is not nullable", tt.name, f.Name)
+			}
+		}
+	}
+}
+
+func TestInferSchemaFromJSONErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		// A JSON array is one document, not lines of objects.
+		{"array", `[{"v": 1}, {"v": 2}]`, "line 1: expected object"},
+		{"array over lines", "[\n{\"v\": 1}\n]", "line 1: expected object"},
+		{"malformed line", "{\"v\": 1}\n{\"v\": }", "line 2"},
+		{"scalar line", "{\"v\": 1}\n42", "line 2: expected object"},
+		{"empty", "\n\n", "no data"},
+	}
+	for _, tt := range tests {
+		_, err := InferSchemaFromJSON(strings.NewReader(tt.input), 0)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestJSONReader(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	// Keys outside the schema are ignored, and a missing key reads as null.
+	input := "{\"v\": 1.5, \"s\": \"a\", \"extra\": 1}\n" +
+		"{\"v\": null, \"s\": \"b\"}\n" +
+		"\n" +
+		"{\"s\": null}\n" +
+		"{\"v\": 4, \"s\": \"d\"}\n"
+	schema, err := InferSchemaFromJSON(strings.NewReader(input), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fields(schema), "v:float64 s:utf8 extra:int64"; got != want {
+		t.Fatalf("schema = %s, want %s", got, want)
+	}
+	jr := NewJSONReader(strings.NewReader(input), schema, pool)
+	rec, err := jr.ReadColumns(context.Background(), []string{"v", "s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	if rec.NumRows() != 4 {
+		t.Fatalf("got %d rows, want 4", rec.NumRows())
+	}
+	v := rec.Column(0).(*array.Float64)
+	s := rec.Column(1).(*array.String)
+	if v.Value(0) != 1.5 || v.IsValid(1) || v.IsValid(2) || v.Value(3) != 4 {
+		t.Errorf("v = %v", v)
+	}
+	if s.Value(1) != "b" || s.IsValid(2) || s.NullN() != 1 {
+		t.Errorf("s = %v", s)
+	}
+}
+
+func TestJSONReaderGzip(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("{\"v\": 1}\n{\"v\": 2}\n{\"v\": 3}\n"))
+	zw.Close()
+	data := buf.Bytes()
+
+	schema, err := InferSchemaFromJSON(bytes.NewReader(data), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	col, err := NewJSONReader(bytes.NewReader(data), schema, pool).ReadSingleColumn(context.Background(), "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer col.Release()
+	if ints := col.(*array.Int64).Int64Values(); len(ints) != 3 || ints[2] != 3 {
+		t.Errorf("v = %v, want [1 2 3]", col)
+	}
+}
+
+func TestJSONReaderErrors(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Int64, Nullable: true}}, nil)
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"malformed line", "{\"v\": 1}\n{\"v\": \n{\"v\": 3}\n"},
+		{"wrong type", "{\"v\": 1}\n{\"v\": \"x\"}\n"},
+		{"array", `[{"v": 1}, {"v": 2}]`},
+	}
+	for _, tt := range tests {
+		rec, err := NewJSONReader(strings.NewReader(tt.input), schema, pool).ReadColumns(context.Background(), nil)
+		if err == nil {
+			rec.Release()
+			t.Errorf("%s: want error", tt.name)
+		}
+	}
+
+	jr := NewJSONReader(strings.NewReader(`{"v": 1}`), schema, pool)
+	if _, err := jr.ReadColumns(context.Background(), []string{"w"}); err == nil {
+		t.Error("missing column: want error")
+	}
+	if _, err := jr.ReadChunked(context.Background(), "w"); err == nil {
+		t.Error("missing column: want error")
+	}
+}
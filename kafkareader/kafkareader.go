@@ -0,0 +1,349 @@
+// Package kafkareader streams Arrow records decoded from Kafka messages.
+package kafkareader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TFMV/supercharged/jsonreader"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	arrowavro "github.com/apache/arrow-go/v18/arrow/avro"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/hamba/avro/v2"
+	"github.com/segmentio/kafka-go"
+)
+
+// Format is the encoding of message values.
+type Format string
+
+const (
+	// FormatJSON is one JSON object per message.
+	FormatJSON Format = "json"
+	// FormatAvro is one Avro binary-encoded record per message.
+	FormatAvro Format = "avro"
+)
+
+// DefaultBatchSize is the most messages decoded into one record.
+const DefaultBatchSize = 1024
+
+// DefaultBatchTimeout is how long a batch waits for more messages after its
+// first one arrives.
+const DefaultBatchTimeout = time.Second
+
+// Config describes the topic to consume and how its messages are encoded.
+type Config struct {
+	Brokers []string
+	Topic   string
+	// GroupID is the consumer group; offsets are committed after each batch
+	// is handed off. Empty means partition 0 without committing.
+	GroupID string
+	// Offset is where reading partition 0 starts when GroupID is empty, as
+	// when resuming from Offsets; zero means the first message.
+	Offset int64
+	Format Format
+	// AvroSchema is the writer schema, as JSON, for FormatAvro.
+	AvroSchema string
+	// Framed marks Avro values as carrying the Confluent schema-registry
+	// header (a zero magic byte and a 4-byte schema id), which is skipped.
+	Framed bool
+	// BatchSize and BatchTimeout bound each record; zero means the defaults.
+	BatchSize    int
+	BatchTimeout time.Duration
+}
+
+// OffsetsKey is the schema metadata key under which the last record decoded
+// from each batch of messages carries, per partition, the offset following
+// the batch, as comma-separated partition:offset pairs. Once that record is
+// processed, a consumer can resume from those offsets.
+const OffsetsKey = "kafka.offsets"
+
+// Offsets returns the partition offsets rec carries under OffsetsKey, and
+// whether it carries any.
+func Offsets(rec arrow.Record) (map[int]int64, bool) {
+	md := rec.Schema().Metadata()
+	v, ok := md.GetValue(OffsetsKey)
+	if !ok {
+		return nil, false
+	}
+	offsets := map[int]int64{}
+	for _, pair := range strings.Split(v, ",") {
+		p, o, ok := strings.Cut(pair, ":")
+		partition, err1 := strconv.Atoi(p)
+		offset, err2 := strconv.ParseInt(o, 10, 64)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, false
+		}
+		offsets[partition] = offset
+	}
+	return offsets, true
+}
+
+// ParseAddress splits "broker1:9092,broker2:9092/topic" into brokers and
+// topic.
+func ParseAddress(address string) ([]string, string, error) {
+	i := strings.LastIndexByte(address, '/')
+	if i <= 0 || i == len(address)-1 {
+		return nil, "", fmt.Errorf("kafka address %q: want brokers/topic", address)
+	}
+	return strings.Split(address[:i], ","), address[i+1:], nil
+}
+
+// KafkaReader consumes a topic and decodes each batch of messages into a
+// record. JSON schemas are inferred from the first batch with integer fields
+// widened to float64, so later fractional values still decode; Avro schemas
+// come from Config.AvroSchema.
+type KafkaReader struct {
+	allocator memory.Allocator
+	config    Config
+	reader    *kafka.Reader
+	avro      avro.Schema
+	schema    *arrow.Schema
+	used      bool
+}
+
+// NewKafkaReader validates cfg and creates a reader for its topic. Records
+// are allocated from mem; nil means a new Go allocator.
+func NewKafkaReader(cfg Config, mem memory.Allocator) (*KafkaReader, error) {
+	allocator := mem
+	if allocator == nil {
+		allocator = memory.NewGoAllocator()
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.BatchTimeout <= 0 {
+		cfg.BatchTimeout = DefaultBatchTimeout
+	}
+	kr := &KafkaReader{allocator: allocator, config: cfg}
+	switch cfg.Format {
+	case FormatJSON, "":
+		kr.config.Format = FormatJSON
+	case FormatAvro:
+		if cfg.AvroSchema == "" {
+			return nil, fmt.Errorf("avro messages need a schema")
+		}
+		s, err := avro.Parse(cfg.AvroSchema)
+		if err != nil {
+			return nil, fmt.Errorf("parse avro schema: %w", err)
+		}
+		schema, err := arrowavro.ArrowSchemaFromAvro(s)
+		if err != nil {
+			return nil, fmt.Errorf("convert avro schema: %w", err)
+		}
+		kr.avro, kr.schema = s, schema
+	default:
+		return nil, fmt.Errorf("unknown message format %q", cfg.Format)
+	}
+	kr.reader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers: cfg.Brokers,
+		Topic:   cfg.Topic,
+		GroupID: cfg.GroupID,
+	})
+	if cfg.GroupID == "" && cfg.Offset > 0 {
+		if err := kr.reader.SetOffset(cfg.Offset); err != nil {
+			kr.reader.Close()
+			return nil, fmt.Errorf("kafka offset %d: %w", cfg.Offset, err)
+		}
+	}
+	return kr, nil
+}
+
+// Schema returns the schema records are decoded with, or nil for JSON
+// messages before the first batch.
+func (kr *KafkaReader) Schema() *arrow.Schema {
+	return kr.schema
+}
+
+// Chan consumes the topic until ctx is done and sends one record per batch
+// of messages; caller must Release each.
+func (kr *KafkaReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	if kr.used {
+		close(recs)
+		errs <- fmt.Errorf("kafka stream can only be read once")
+		close(errs)
+		return recs, errs
+	}
+	kr.used = true
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		if err := kr.run(ctx, recs); err != nil && ctx.Err() == nil {
+			errs <- err
+		}
+	}()
+	return recs, errs
+}
+
+func (kr *KafkaReader) run(ctx context.Context, recs chan<- arrow.Record) error {
+	for {
+		msgs, err := kr.fetch(ctx)
+		if err != nil {
+			return err
+		}
+		if err := kr.decode(ctx, msgs, recs); err != nil {
+			return err
+		}
+		if kr.config.GroupID != "" {
+			if err := kr.reader.CommitMessages(ctx, msgs...); err != nil {
+				return fmt.Errorf("kafka commit: %w", err)
+			}
+		}
+	}
+}
+
+// fetch blocks for one message, then collects more until the batch is full
+// or BatchTimeout has passed.
+func (kr *KafkaReader) fetch(ctx context.Context) ([]kafka.Message, error) {
+	first, err := kr.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("kafka fetch: %w", err)
+	}
+	msgs := []kafka.Message{first}
+	wait, cancel := context.WithTimeout(ctx, kr.config.BatchTimeout)
+	defer cancel()
+	for len(msgs) < kr.config.BatchSize {
+		m, err := kr.reader.FetchMessage(wait)
+		if errors.Is(err, context.DeadlineExceeded) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("kafka fetch: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, nil
+}
+
+// decode turns msgs into newline-delimited JSON and sends the records read
+// back from it under the topic's schema, the last marked with the offsets
+// following msgs.
+func (kr *KafkaReader) decode(ctx context.Context, msgs []kafka.Message, recs chan<- arrow.Record) error {
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		if err := kr.appendLine(&buf, m.Value); err != nil {
+			return fmt.Errorf("decode message at partition %d offset %d: %w", m.Partition, m.Offset, err)
+		}
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+	if kr.schema == nil {
+		schema, err := jsonreader.InferSchemaFromJSON(bytes.NewReader(buf.Bytes()), 0)
+		if err != nil {
+			return fmt.Errorf("infer schema: %w", err)
+		}
+		kr.schema = widenIntegers(schema)
+	}
+	send := func(rec arrow.Record) {
+		select {
+		case recs <- rec:
+		case <-ctx.Done():
+			rec.Release()
+		}
+	}
+	in, errs := jsonreader.NewJSONReader(&buf, kr.schema, kr.allocator).Chan(ctx)
+	// Hold each record back until the next arrives, to mark the last.
+	var last arrow.Record
+	for rec := range in {
+		if last != nil {
+			send(last)
+		}
+		last = rec
+	}
+	if err := <-errs; err != nil {
+		if last != nil {
+			last.Release()
+		}
+		return err
+	}
+	if last != nil {
+		send(withOffsets(last, msgs))
+	}
+	return nil
+}
+
+// withOffsets returns rec with the offsets following msgs in its schema
+// metadata under OffsetsKey, releasing rec.
+func withOffsets(rec arrow.Record, msgs []kafka.Message) arrow.Record {
+	defer rec.Release()
+	next := map[int]int64{}
+	for _, m := range msgs {
+		if m.Offset+1 > next[m.Partition] {
+			next[m.Partition] = m.Offset + 1
+		}
+	}
+	partitions := make([]int, 0, len(next))
+	for p := range next {
+		partitions = append(partitions, p)
+	}
+	sort.Ints(partitions)
+	pairs := make([]string, len(partitions))
+	for i, p := range partitions {
+		pairs[i] = fmt.Sprintf("%d:%d", p, next[p])
+	}
+	md := rec.Schema().Metadata()
+	md = arrow.NewMetadata(append(append([]string(nil), md.Keys()...), OffsetsKey), append(append([]string(nil), md.Values()...), strings.Join(pairs, ",")))
+	schema := arrow.NewSchema(rec.Schema().Fields(), &md)
+	return array.NewRecord(schema, rec.Columns(), rec.NumRows())
+}
+
+// appendLine writes value to buf as a single line of JSON. Empty values,
+// such as tombstones, are skipped.
+func (kr *KafkaReader) appendLine(buf *bytes.Buffer, value []byte) error {
+	if len(value) == 0 {
+		return nil
+	}
+	if kr.config.Format == FormatAvro {
+		if kr.config.Framed {
+			if len(value) < 5 || value[0] != 0 {
+				return fmt.Errorf("missing schema registry header")
+			}
+			value = value[5:]
+		}
+		var m map[string]any
+		if err := avro.Unmarshal(kr.avro, value, &m); err != nil {
+			return err
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		value = b
+	} else {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, value); err != nil {
+			return err
+		}
+		value = compact.Bytes()
+	}
+	buf.Write(value)
+	buf.WriteByte('\n')
+	return nil
+}
+
+// widenIntegers returns schema with its integer fields replaced by float64.
+func widenIntegers(schema *arrow.Schema) *arrow.Schema {
+	fields := schema.Fields()
+	for i, f := range fields {
+		if arrow.IsInteger(f.Type.ID()) {
+			fields[i].Type = arrow.PrimitiveTypes.Float64
+		}
+	}
+	md := schema.Metadata()
+	return arrow.NewSchema(fields, &md)
+}
+
+// Close stops consuming and leaves the consumer group.
+func (kr *KafkaReader) Close() error {
+	return kr.reader.Close()
+}
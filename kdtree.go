@@ -0,0 +1,109 @@
+package supercharged
+
+import (
+	"container/heap"
+	"math"
+	"sort"
+)
+
+// kdTree indexes points for k-nearest-neighbor queries.
+type kdTree struct {
+	points [][]float64
+	root   *kdNode
+}
+
+type kdNode struct {
+	point       int
+	axis        int
+	left, right *kdNode
+}
+
+func newKDTree(points [][]float64) *kdTree {
+	idx := make([]int, len(points))
+	for i := range idx {
+		idx[i] = i
+	}
+	t := &kdTree{points: points}
+	t.root = t.build(idx, 0)
+	return t
+}
+
+func (t *kdTree) build(idx []int, depth int) *kdNode {
+	if len(idx) == 0 {
+		return nil
+	}
+	axis := depth % len(t.points[idx[0]])
+	sort.Slice(idx, func(a, b int) bool { return t.points[idx[a]][axis] < t.points[idx[b]][axis] })
+	mid := len(idx) / 2
+	return &kdNode{
+		point: idx[mid],
+		axis:  axis,
+		left:  t.build(idx[:mid], depth+1),
+		right: t.build(idx[mid+1:], depth+1),
+	}
+}
+
+// neighbor is a point index and its distance from the query.
+type neighbor struct {
+	point int
+	dist  float64
+}
+
+// nearest returns the k points closest to points[q], excluding q itself,
+// ordered by increasing distance.
+func (t *kdTree) nearest(q, k int) []neighbor {
+	h := &neighborHeap{}
+	var search func(n *kdNode)
+	search = func(n *kdNode) {
+		if n == nil {
+			return
+		}
+		if n.point != q {
+			d := euclidean(t.points[q], t.points[n.point])
+			if h.Len() < k {
+				heap.Push(h, neighbor{n.point, d})
+			} else if d < (*h)[0].dist {
+				(*h)[0] = neighbor{n.point, d}
+				heap.Fix(h, 0)
+			}
+		}
+		diff := t.points[q][n.axis] - t.points[n.point][n.axis]
+		near, far := n.left, n.right
+		if diff > 0 {
+			near, far = far, near
+		}
+		search(near)
+		if h.Len() < k || math.Abs(diff) < (*h)[0].dist {
+			search(far)
+		}
+	}
+	search(t.root)
+	out := make([]neighbor, h.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(h).(neighbor)
+	}
+	return out
+}
+
+func euclidean(a, b []float64) float64 {
+	var s float64
+	for i := range a {
+		d := a[i] - b[i]
+		s += d * d
+	}
+	return math.Sqrt(s)
+}
+
+// neighborHeap is a max-heap on distance holding the current k best.
+type neighborHeap []neighbor
+
+func (h neighborHeap) Len() int           { return len(h) }
+func (h neighborHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h neighborHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *neighborHeap) Push(x any)        { *h = append(*h, x.(neighbor)) }
+func (h *neighborHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
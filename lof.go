@@ -0,0 +1,141 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// DefaultLOFNeighbors is the neighborhood size used when LOFDetector.K is zero.
+const DefaultLOFNeighbors = 20
+
+// DefaultLOFThreshold is the outlier factor at or above which LOFDetector
+// flags a row when no threshold is given.
+const DefaultLOFThreshold = 1.5
+
+// LOFDetector scores rows by their Local Outlier Factor: the average local
+// reachability density of a row's K nearest neighbors divided by its own.
+// Scores near 1 mean the row is as dense as its neighborhood; rows in sparse
+// regions score higher. Because density is judged locally it finds outliers
+// between clusters of different spread that global statistics miss.
+//
+// Like MahalanobisDetector, Detect takes a struct array of numeric variables
+// or a single numeric array, and rows with a null get a null score and mask.
+// Zscore holds the outlier factors. Neighbors are found with a k-d tree.
+type LOFDetector struct {
+	// K is the number of neighbors; zero means DefaultLOFNeighbors. It is
+	// capped at one less than the number of complete rows.
+	K int
+	// Threshold is the factor to flag at; zero means DefaultLOFThreshold.
+	Threshold float64
+}
+
+var _ Detector = LOFDetector{}
+
+// DetectColumns runs d over the named columns of rec.
+func (d LOFDetector) DetectColumns(ctx context.Context, rec arrow.Record, columns []string) (*Result, error) {
+	st, err := structOfColumns(rec, columns)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Release()
+	return d.Detect(ctx, st)
+}
+
+// Detect implements Detector.
+func (d LOFDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	v, err := newVariables(ctx, col)
+	if err != nil {
+		return nil, fmt.Errorf("lof: %w", err)
+	}
+	defer v.release()
+	threshold := d.Threshold
+	if threshold == 0 {
+		threshold = DefaultLOFThreshold
+	}
+
+	var rows []int
+	var points [][]float64
+	for i := 0; i < col.Len(); i++ {
+		if v.complete(i) {
+			pt := make([]float64, len(v.cols))
+			v.row(i, pt)
+			rows = append(rows, i)
+			points = append(points, pt)
+		}
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("lof: need at least 2 complete rows, got %d", len(points))
+	}
+	k := d.K
+	if k <= 0 {
+		k = DefaultLOFNeighbors
+	}
+	k = min(k, len(points)-1)
+	factors := localOutlierFactors(newKDTree(points), k)
+
+	mem := compute.GetAllocator(ctx)
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	scores.Reserve(col.Len())
+	mask.Reserve(col.Len())
+	next := 0
+	for i := 0; i < col.Len(); i++ {
+		if next < len(rows) && rows[next] == i {
+			f := factors[next]
+			scores.Append(f)
+			mask.Append(f >= threshold)
+			next++
+			continue
+		}
+		scores.AppendNull()
+		mask.AppendNull()
+	}
+
+	lofs := scores.NewFloat64Array()
+	defer lofs.Release()
+	lofs.Retain()
+	return newResult(ctx, lofs, mask.NewBooleanArray(), lofs, accumulate(lofs))
+}
+
+// localOutlierFactors computes the LOF of every point in t.
+func localOutlierFactors(t *kdTree, k int) []float64 {
+	n := len(t.points)
+	neighbors := make([][]neighbor, n)
+	kdist := make([]float64, n)
+	for i := range neighbors {
+		neighbors[i] = t.nearest(i, k)
+		kdist[i] = neighbors[i][len(neighbors[i])-1].dist
+	}
+
+	// Local reachability density: the inverse mean reachability distance,
+	// infinite when a point sits on k or more duplicates.
+	lrd := make([]float64, n)
+	for i, nb := range neighbors {
+		var sum float64
+		for _, o := range nb {
+			sum += math.Max(kdist[o.point], o.dist)
+		}
+		lrd[i] = float64(len(nb)) / sum
+	}
+
+	lof := make([]float64, n)
+	for i, nb := range neighbors {
+		var sum float64
+		for _, o := range nb {
+			if math.IsInf(lrd[o.point], 1) && math.IsInf(lrd[i], 1) {
+				sum++
+				continue
+			}
+			sum += lrd[o.point] / lrd[i]
+		}
+		lof[i] = sum / float64(len(nb))
+	}
+	return lof
+}
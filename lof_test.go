@@ -0,0 +1,82 @@
+package supercharged
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestLOFDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "x", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "y", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	xb := b.Field(0).(*array.Float64Builder)
+	yb := b.Field(1).(*array.Float64Builder)
+	r := rand.New(rand.NewSource(1))
+	// A tight cluster, a loose one, and a point just outside the tight
+	// cluster that a global threshold would not single out.
+	for i := 0; i < 50; i++ {
+		xb.Append(r.NormFloat64() * 0.1)
+		yb.Append(r.NormFloat64() * 0.1)
+	}
+	for i := 0; i < 50; i++ {
+		xb.Append(20 + r.NormFloat64()*3)
+		yb.Append(20 + r.NormFloat64()*3)
+	}
+	xb.Append(1.5)
+	yb.Append(1.5)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	res, err := LOFDetector{K: 10}.DetectColumns(ctx, rec, []string{"x", "y"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	if !res.Mask.Value(100) {
+		t.Errorf("expected the isolated point to be flagged, lof = %.2f", res.Zscore.Value(100))
+	}
+	for i := 0; i < 100; i++ {
+		if res.Zscore.Value(i) >= res.Zscore.Value(100) {
+			t.Errorf("row %d lof %.2f >= isolated point's %.2f", i, res.Zscore.Value(i), res.Zscore.Value(100))
+		}
+	}
+}
+
+func TestKDTreeNearest(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	points := make([][]float64, 200)
+	for i := range points {
+		points[i] = []float64{r.Float64(), r.Float64(), r.Float64()}
+	}
+	tree := newKDTree(points)
+	for q := 0; q < len(points); q += 17 {
+		var want []float64
+		for i, p := range points {
+			if i != q {
+				want = append(want, euclidean(points[q], p))
+			}
+		}
+		sort.Float64s(want)
+		got := tree.nearest(q, 5)
+		for i, nb := range got {
+			if nb.dist != want[i] {
+				t.Fatalf("query %d neighbor %d: dist %v, want %v", q, i, nb.dist, want[i])
+			}
+		}
+	}
+}
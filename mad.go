@@ -0,0 +1,126 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// DefaultMADScale makes the MAD a consistent estimator of the standard
+// deviation for normally distributed data.
+const DefaultMADScale = 1.4826
+
+// meanADScale, sqrt(pi/2), makes the mean absolute deviation a consistent
+// estimator of the standard deviation for normally distributed data.
+const meanADScale = 1.2533
+
+// MADDetector flags values whose robust z-score, (x - median) / (Scale * MAD),
+// is at least Threshold in absolute value. Unlike the mean and standard
+// deviation, the median and MAD are not dragged by the outliers themselves.
+// When more than half the values are equal, as in zero-inflated data, the
+// MAD is zero and the scale falls back to 1.2533 times the mean absolute
+// deviation from the median; only a constant column fails, with
+// ErrZeroScale.
+type MADDetector struct {
+	Threshold float64
+	// Scale multiplies the MAD; zero means DefaultMADScale.
+	Scale float64
+}
+
+var _ Detector = MADDetector{}
+
+// Detect implements Detector.
+func (d MADDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	f, err := d.fit(ctx, []*array.Float64{floatCol})
+	if err != nil {
+		return nil, err
+	}
+	return scoreAgainst(ctx, floatCol, f.center, f.scale, f.threshold, f.stats)
+}
+
+func (d MADDetector) fit(ctx context.Context, cols []*array.Float64) (fitted, error) {
+	scale := d.Scale
+	if scale == 0 {
+		scale = DefaultMADScale
+	}
+	median, mad := medianAndMAD(nonNullValues(cols...))
+	if mad == 0 {
+		return fitted{center: median, scale: meanADScale * meanAbsDeviation(cols, median), threshold: d.Threshold, stats: accumulateAll(ctx, cols)}, nil
+	}
+	return fitted{center: median, scale: scale * mad, threshold: d.Threshold, stats: accumulateAll(ctx, cols)}, nil
+}
+
+// meanAbsDeviation returns the mean absolute deviation of the non-null
+// values of cols from center, which is zero only when they all equal it.
+func meanAbsDeviation(cols []*array.Float64, center float64) float64 {
+	var sum float64
+	var n int
+	for _, col := range cols {
+		for i := 0; i < col.Len(); i++ {
+			if col.IsValid(i) {
+				sum += math.Abs(col.Value(i) - center)
+				n++
+			}
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / float64(n)
+}
+
+// computeMedianAndMAD returns the median of the non-null values of col and
+// their median absolute deviation from it.
+func computeMedianAndMAD(col *array.Float64) (median, mad float64) {
+	return medianAndMAD(nonNullValues(col))
+}
+
+// medianAndMAD returns the median of vals and their median absolute
+// deviation from it, reordering vals in place.
+func medianAndMAD(vals []float64) (median, mad float64) {
+	if len(vals) == 0 {
+		return 0, 0
+	}
+	median = medianInPlace(vals)
+	for i, v := range vals {
+		vals[i] = math.Abs(v - median)
+	}
+	mad = medianInPlace(vals)
+	return
+}
+
+// nonNullValues copies the non-null values of cols into a new slice.
+func nonNullValues(cols ...*array.Float64) []float64 {
+	var n int
+	for _, col := range cols {
+		n += col.Len() - col.NullN()
+	}
+	vals := make([]float64, 0, n)
+	for _, col := range cols {
+		for i := 0; i < col.Len(); i++ {
+			if col.IsNull(i) {
+				continue
+			}
+			vals = append(vals, col.Value(i))
+		}
+	}
+	return vals
+}
+
+// medianInPlace sorts vals and returns their median.
+func medianInPlace(vals []float64) float64 {
+	sort.Float64s(vals)
+	n := len(vals)
+	if n%2 == 1 {
+		return vals[n/2]
+	}
+	return (vals[n/2-1] + vals[n/2]) / 2
+}
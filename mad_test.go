@@ -0,0 +1,92 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestMADDetector(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	vals := array.NewFloat64Builder(pool)
+	defer vals.Release()
+	vals.AppendValues([]float64{10, 11, 9, 10, 12, 8, 10, 500, 11, 9}, nil)
+	col := vals.NewFloat64Array()
+	defer col.Release()
+
+	res, err := MADDetector{Threshold: 3.5}.Detect(context.Background(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	for i := 0; i < res.Mask.Len(); i++ {
+		if want := i == 7; res.Mask.Value(i) != want {
+			t.Errorf("mask[%d] = %t, want %t (score %v)", i, res.Mask.Value(i), want, res.Zscore.Value(i))
+		}
+	}
+}
+
+func TestComputeMedianAndMAD(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	vals := array.NewFloat64Builder(pool)
+	defer vals.Release()
+	vals.AppendValues([]float64{1, 1, 2, 2, 4, 6, 9}, nil)
+	vals.AppendNull()
+	col := vals.NewFloat64Array()
+	defer col.Release()
+
+	median, mad := computeMedianAndMAD(col)
+	if median != 2 || mad != 1 {
+		t.Errorf("got median=%v mad=%v, want 2 and 1", median, mad)
+	}
+}
+
+// zeroInflated returns 80 zeros, 1 to 19 and an outlier of 500 at row 99,
+// so the median, the MAD and the IQR are all zero.
+func zeroInflated(pool memory.Allocator) *array.Float64 {
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues(make([]float64, 80), nil)
+	for v := 1; v < 20; v++ {
+		b.Append(float64(v))
+	}
+	b.Append(500)
+	return b.NewFloat64Array()
+}
+
+func TestMADZeroInflated(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+	col := zeroInflated(pool)
+	defer col.Release()
+
+	res, err := MADDetector{Threshold: 3.5}.Detect(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if got := res.AnomalousIndices(); !slices.Equal(got, []int64{99}) {
+		t.Errorf("indices = %v, want [99]", got)
+	}
+	// The scale is 1.2533 times the mean absolute deviation, 6.9.
+	if got, want := res.Zscore.Value(99), 500/(meanADScale*6.9); math.Abs(got-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", got, want)
+	}
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{4, 4, 4}, nil)
+	constant := b.NewFloat64Array()
+	defer constant.Release()
+	if _, err := (MADDetector{Threshold: 3.5}).Detect(ctx, constant); !errors.Is(err, ErrZeroScale) {
+		t.Errorf("constant: err = %v, want ErrZeroScale", err)
+	}
+}
@@ -0,0 +1,174 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// MahalanobisDetector flags rows whose Mahalanobis distance from the mean
+// vector, under the covariance of all variables, is at least
+// Threshold. Unlike per-column z-scores it catches combinations that are
+// unusual even when each value is ordinary on its own.
+//
+// Detect takes a struct array whose fields are the numeric variables (a plain
+// numeric array is one variable); use DetectColumns for columns of a record.
+// Rows with a null in any variable get a null score and mask. Zscore holds
+// the distances, and the Result's Values, Mean and StdDev describe those
+// distances rather than any input column.
+type MahalanobisDetector struct {
+	Threshold float64
+}
+
+var _ Detector = MahalanobisDetector{}
+
+// DetectColumns runs d over the named columns of rec.
+func (d MahalanobisDetector) DetectColumns(ctx context.Context, rec arrow.Record, columns []string) (*Result, error) {
+	st, err := structOfColumns(rec, columns)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Release()
+	return d.Detect(ctx, st)
+}
+
+// Detect implements Detector.
+func (d MahalanobisDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	v, err := newVariables(ctx, col)
+	if err != nil {
+		return nil, fmt.Errorf("mahalanobis: %w", err)
+	}
+	defer v.release()
+	n, p := col.Len(), len(v.cols)
+
+	mean, cov, rows := covariance(v.cols, n, v.complete)
+	if rows < 2 {
+		return nil, fmt.Errorf("mahalanobis: need at least 2 complete rows, got %d", rows)
+	}
+	inv, err := invert(cov)
+	if err != nil {
+		return nil, fmt.Errorf("mahalanobis: %w", err)
+	}
+
+	mem := compute.GetAllocator(ctx)
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	scores.Reserve(n)
+	mask.Reserve(n)
+	diff := make([]float64, p)
+	for i := 0; i < n; i++ {
+		if !v.complete(i) {
+			scores.AppendNull()
+			mask.AppendNull()
+			continue
+		}
+		v.row(i, diff)
+		for j := range diff {
+			diff[j] -= mean[j]
+		}
+		var q float64
+		for a := 0; a < p; a++ {
+			for b := 0; b < p; b++ {
+				q += diff[a] * inv[a][b] * diff[b]
+			}
+		}
+		dist := math.Sqrt(math.Max(q, 0))
+		scores.Append(dist)
+		mask.Append(dist >= d.Threshold)
+	}
+
+	distances := scores.NewFloat64Array()
+	defer distances.Release()
+	distances.Retain()
+	return newResult(ctx, distances, mask.NewBooleanArray(), distances, accumulate(distances))
+}
+
+// covariance returns the mean vector and population covariance matrix of the
+// rows of vars for which use is true, along with the number of such rows.
+func covariance(vars []*array.Float64, n int, use func(int) bool) ([]float64, [][]float64, int) {
+	p := len(vars)
+	mean := make([]float64, p)
+	cov := make([][]float64, p)
+	for j := range cov {
+		cov[j] = make([]float64, p)
+	}
+	// Welford's update generalized to a co-moment matrix.
+	delta := make([]float64, p)
+	rows := 0
+	for i := 0; i < n; i++ {
+		if !use(i) {
+			continue
+		}
+		rows++
+		for j, v := range vars {
+			delta[j] = v.Value(i) - mean[j]
+			mean[j] += delta[j] / float64(rows)
+		}
+		for a := 0; a < p; a++ {
+			for b, vb := range vars {
+				cov[a][b] += delta[a] * (vb.Value(i) - mean[b])
+			}
+		}
+	}
+	if rows > 0 {
+		for a := range cov {
+			for b := range cov[a] {
+				cov[a][b] /= float64(rows)
+			}
+		}
+	}
+	return mean, cov, rows
+}
+
+// invert returns the inverse of the square matrix m by Gauss-Jordan
+// elimination with partial pivoting. m is left unchanged.
+func invert(m [][]float64) ([][]float64, error) {
+	p := len(m)
+	a := make([][]float64, p)
+	inv := make([][]float64, p)
+	var tol float64
+	for i := range m {
+		tol = math.Max(tol, math.Abs(m[i][i]))
+	}
+	tol *= 1e-12
+	for i := range m {
+		a[i] = append([]float64(nil), m[i]...)
+		inv[i] = make([]float64, p)
+		inv[i][i] = 1
+	}
+	for c := 0; c < p; c++ {
+		pivot := c
+		for r := c + 1; r < p; r++ {
+			if math.Abs(a[r][c]) > math.Abs(a[pivot][c]) {
+				pivot = r
+			}
+		}
+		if math.Abs(a[pivot][c]) <= tol {
+			return nil, fmt.Errorf("covariance matrix is singular (are some columns constant or collinear?)")
+		}
+		a[c], a[pivot] = a[pivot], a[c]
+		inv[c], inv[pivot] = inv[pivot], inv[c]
+		scale := a[c][c]
+		for k := 0; k < p; k++ {
+			a[c][k] /= scale
+			inv[c][k] /= scale
+		}
+		for r := 0; r < p; r++ {
+			if r == c || a[r][c] == 0 {
+				continue
+			}
+			f := a[r][c]
+			for k := 0; k < p; k++ {
+				a[r][k] -= f * a[c][k]
+				inv[r][k] -= f * inv[c][k]
+			}
+		}
+	}
+	return inv, nil
+}
@@ -0,0 +1,61 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestMahalanobisDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "height", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "weight", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	// Weight tracks height closely; the last row is ordinary on each axis
+	// but far off the trend.
+	h := []float64{150, 155, 160, 165, 170, 175, 180, 185, 190, 152, 188, 150}
+	w := []float64{50, 55, 60, 65, 70, 75, 80, 85, 90, 52, 88, 90}
+	b.Field(0).(*array.Float64Builder).AppendValues(h, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues(w, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	res, err := MahalanobisDetector{Threshold: 3}.DetectColumns(ctx, rec, []string{"height", "weight"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	for i := 0; i < res.Mask.Len(); i++ {
+		if want := i == 11; res.Mask.Value(i) != want {
+			t.Errorf("mask[%d] = %t (distance %.2f), want %t", i, res.Mask.Value(i), res.Zscore.Value(i), want)
+		}
+	}
+
+	// A single variable reduces to the absolute z-score.
+	z, err := MahalanobisDetector{Threshold: 3}.DetectColumns(ctx, rec, []string{"height"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer z.Release()
+	col := rec.Column(0).(*array.Float64)
+	s := accumulate(col)
+	if got, want := z.Zscore.Value(0), math.Abs(150-s.mean)/s.stdDev(); math.Abs(got-want) > 1e-9 {
+		t.Errorf("distance[0] = %v, want %v", got, want)
+	}
+
+	if _, err := (MahalanobisDetector{}).DetectColumns(ctx, rec, []string{"height", "height"}); err == nil {
+		t.Errorf("expected error for singular covariance")
+	}
+}
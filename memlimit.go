@@ -0,0 +1,110 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// ErrMemoryLimit is returned, wrapped with the sizes involved, when
+// detection under WithMemoryLimit would allocate past the limit.
+var ErrMemoryLimit = errors.New("memory limit exceeded")
+
+// scoreBytesPerRow estimates what scoring takes per input row: a float64
+// score and the mask bit, rounded up for the flagged rows' indices and
+// values.
+const scoreBytesPerRow = 9
+
+type memoryLimitKey struct{}
+
+// WithMemoryLimit caps the memory detection allocates through its allocator
+// at limit bytes. Chunks cast to float64 for detection are spilled to
+// temporary Arrow IPC files and mapped back from disk once the memory in use
+// leaves less room than scoring needs; when the limit would still be
+// exceeded, detection fails with ErrMemoryLimit instead of running out of
+// memory. The limit is checked before and after each cast, concatenation and
+// scoring pass, so a pass's own temporaries may briefly go past it, and the
+// input column itself is not counted. Spilling needs a Unix system;
+// elsewhere the limit is enforced without it.
+func WithMemoryLimit(limit int64) Option {
+	return func(o *options) { o.memoryLimit = limit }
+}
+
+// ContextWithMemoryLimit returns a copy of ctx whose compute allocator counts
+// its allocations against a budget of limit bytes, shared by all detection
+// run under it; see WithMemoryLimit. The limit is checked by
+// DetectAnomalies, DetectAnomaliesChunked, DetectChunked and Detect, not by
+// a Detector's own Detect method.
+func ContextWithMemoryLimit(ctx context.Context, limit int64) context.Context {
+	b := &memoryBudget{Allocator: compute.GetAllocator(ctx), limit: limit}
+	return compute.WithAllocator(context.WithValue(ctx, memoryLimitKey{}, b), b)
+}
+
+// budgetFrom returns the memory budget carried by ctx, nil if none.
+func budgetFrom(ctx context.Context) *memoryBudget {
+	b, _ := ctx.Value(memoryLimitKey{}).(*memoryBudget)
+	return b
+}
+
+// memoryBudget is an allocator that tracks the bytes it has handed out.
+// Allocators cannot fail, and compute kernels allocate on goroutines of their
+// own, so the limit is enforced between the steps of detection by
+// checkMemory rather than by the allocator.
+type memoryBudget struct {
+	memory.Allocator
+	limit int64
+	used  atomic.Int64
+}
+
+func (b *memoryBudget) Allocate(size int) []byte {
+	b.used.Add(int64(size))
+	return b.Allocator.Allocate(size)
+}
+
+func (b *memoryBudget) Reallocate(size int, buf []byte) []byte {
+	b.used.Add(int64(size - len(buf)))
+	return b.Allocator.Reallocate(size, buf)
+}
+
+func (b *memoryBudget) Free(buf []byte) {
+	b.used.Add(-int64(len(buf)))
+	b.Allocator.Free(buf)
+}
+
+// checkMemory fails with ErrMemoryLimit when the budget ctx carries, if any,
+// has less than n bytes free, so that a step of detection needing n bytes
+// fails before it allocates them, and one that went past the limit fails
+// once it is done.
+func checkMemory(ctx context.Context, n int64) error {
+	b := budgetFrom(ctx)
+	if b == nil {
+		return nil
+	}
+	if used := b.used.Load(); used+n > b.limit {
+		return fmt.Errorf("%w: need %d bytes with %d of %d in use", ErrMemoryLimit, n, used, b.limit)
+	}
+	return nil
+}
+
+// spillIfTight spills arr, which detection allocated, when the budget ctx
+// carries leaves less than reserve bytes free. It returns arr itself, with
+// no cleanup, when there is no budget, room enough or no way to spill;
+// otherwise it releases arr and returns the array mapped back from disk and
+// the func that unmaps it; see spill.
+func spillIfTight(ctx context.Context, arr arrow.Array, reserve int64) (arrow.Array, func(), error) {
+	b := budgetFrom(ctx)
+	if b == nil || !canSpill || b.used.Load() <= b.limit-reserve {
+		return arr, nil, nil
+	}
+	mapped, unmap, err := spill(arr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("spill: %w", err)
+	}
+	arr.Release()
+	return mapped, unmap, nil
+}
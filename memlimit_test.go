@@ -0,0 +1,114 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// int64Chunks returns a chunked int64 column of n chunks of size rows, with
+// one outlier in the last chunk.
+func int64Chunks(n, size int) *arrow.Chunked {
+	var parts []arrow.Array
+	for c := 0; c < n; c++ {
+		b := array.NewInt64Builder(memory.DefaultAllocator)
+		for i := 0; i < size; i++ {
+			v := int64(100 + (c*size+i)%7)
+			if c == n-1 && i == size/2 {
+				v = 10000
+			}
+			b.Append(v)
+		}
+		parts = append(parts, b.NewArray())
+		b.Release()
+	}
+	chunked := arrow.NewChunked(arrow.PrimitiveTypes.Int64, parts)
+	for _, p := range parts {
+		p.Release()
+	}
+	return chunked
+}
+
+func TestWithMemoryLimitError(t *testing.T) {
+	b := array.NewFloat64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	for i := 0; i < 100000; i++ {
+		b.Append(float64(i % 10))
+	}
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	for _, opts := range [][]Option{
+		{WithMemoryLimit(1 << 10)},
+		{WithMemoryLimit(1 << 10), WithParallelism(4)},
+		{WithMemoryLimit(1 << 10), WithMethod(MethodRolling)},
+	} {
+		res, err := DetectAnomalies(context.Background(), col, opts...)
+		if !errors.Is(err, ErrMemoryLimit) {
+			if res != nil {
+				res.Release()
+			}
+			t.Fatalf("err = %v, want ErrMemoryLimit", err)
+		}
+	}
+
+	// The same input fits a generous limit.
+	res, err := DetectAnomalies(context.Background(), col, WithMemoryLimit(64<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Release()
+}
+
+func TestWithMemoryLimitSpill(t *testing.T) {
+	if !canSpill {
+		t.Skip("spilling is not supported on this system")
+	}
+	chunked := int64Chunks(10, 10000)
+	defer chunked.Release()
+
+	want, err := DetectAnomaliesChunked(context.Background(), chunked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Release()
+
+	// Holding all cast chunks and their scores takes about 1.6MB, more than
+	// the limit, so some chunks must be spilled.
+	ctx := ContextWithMemoryLimit(context.Background(), 1500<<10)
+	budget := budgetFrom(ctx)
+	for _, d := range []Detector{ZScoreDetector{Threshold: 3}, MADDetector{Threshold: 3}} {
+		got, err := DetectChunked(ctx, d, chunked)
+		if err != nil {
+			t.Fatalf("%T: %v", d, err)
+		}
+		if len(got.unmap) == 0 {
+			t.Errorf("%T: nothing spilled", d)
+		}
+		if d == (ZScoreDetector{Threshold: 3}) {
+			if !slices.Equal(got.Indices(), want.Indices()) {
+				t.Errorf("indices = %v, want %v", got.Indices(), want.Indices())
+			}
+			last := len(got.Chunks) - 1
+			if g, w := got.Chunks[last].Zscore.Value(5000), want.Chunks[last].Zscore.Value(5000); g != w {
+				t.Errorf("score = %v, want %v", g, w)
+			}
+		}
+		got.Release()
+		if used := budget.used.Load(); used != 0 {
+			t.Errorf("%T: %d bytes still counted after Release", d, used)
+		}
+	}
+
+	// Spilling cannot make room for the scores themselves.
+	ctx = compute.WithAllocator(context.Background(), memory.NewGoAllocator())
+	if _, err := DetectChunked(ContextWithMemoryLimit(ctx, 200<<10), ZScoreDetector{Threshold: 3}, chunked); !errors.Is(err, ErrMemoryLimit) {
+		t.Fatalf("err = %v, want ErrMemoryLimit", err)
+	}
+}
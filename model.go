@@ -0,0 +1,256 @@
+package supercharged
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// ModelVersion is the format version written by Model.Save.
+const ModelVersion = 1
+
+// Baseline is the fitted statistics of a reference column, or of one group
+// of it: the center and scale values are scored against, and the count,
+// mean and standard deviation of the reference values.
+type Baseline struct {
+	Center float64 `json:"center"`
+	Scale  float64 `json:"scale"`
+	Count  int64   `json:"count"`
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"std_dev"`
+	// Quantiles holds the reference quantiles the center and scale derive
+	// from, keyed by level, for MethodIQR and MethodPercentile.
+	Quantiles map[string]float64 `json:"quantiles,omitempty"`
+}
+
+// stats returns the baseline as running statistics for a Result.
+func (b Baseline) stats() runningStats {
+	return runningStats{n: b.Count, mean: b.Mean, m2: b.StdDev * b.StdDev * float64(b.Count)}
+}
+
+// Model is a detector fitted on a reference period, so that new data can be
+// scored against the same statistics without recomputing them: fit once with
+// Fit or FitGrouped, Save, and later LoadModel and Detect. Scores are
+// (x - Center) / Scale, flagged at Threshold, as for the detector it was
+// fitted from.
+type Model struct {
+	Version   int      `json:"version"`
+	Method    Method   `json:"method"`
+	Column    string   `json:"column,omitempty"`
+	Threshold float64  `json:"threshold"`
+	Baseline  Baseline `json:"baseline"`
+	// GroupBy names the key column of a model fitted by FitGrouped, and
+	// Groups holds the baseline of each key. Keys not seen in the reference
+	// data are scored against Baseline.
+	GroupBy string              `json:"group_by,omitempty"`
+	Groups  map[string]Baseline `json:"groups,omitempty"`
+}
+
+var _ Detector = (*Model)(nil)
+
+// Fit computes the statistics d scores against over col. d must be one of
+// the z-score, MAD, IQR and percentile detectors, whose scores depend only
+// on a fitted center and scale. A column that does not vary fails with
+// ErrZeroScale. The caller may set Column before saving.
+func Fit(ctx context.Context, d Detector, col arrow.Array) (*Model, error) {
+	f, ok := d.(fitter)
+	if !ok {
+		return nil, fmt.Errorf("%s cannot be fitted; use the zscore, mad, iqr or percentile method", detectorName(d))
+	}
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	fit, err := f.fit(ctx, []*array.Float64{floatCol})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkScale(fit.scale); err != nil {
+		return nil, err
+	}
+	m := &Model{
+		Version:   ModelVersion,
+		Threshold: fit.threshold,
+		Baseline: Baseline{
+			Center: fit.center,
+			Scale:  fit.scale,
+			Count:  fit.stats.n,
+			Mean:   fit.stats.mean,
+			StdDev: fit.stats.stdDev(),
+		},
+	}
+	switch d := d.(type) {
+	case ZScoreDetector:
+		m.Method = MethodZScore
+	case MADDetector:
+		m.Method = MethodMAD
+	case IQRDetector:
+		m.Method = MethodIQR
+		m.Baseline.Quantiles = map[string]float64{
+			"0.25": fit.center - fit.scale/2,
+			"0.75": fit.center + fit.scale/2,
+		}
+	case PercentileDetector:
+		m.Method = MethodPercentile
+		q, _ := upperQuantile(d.Q)
+		m.Baseline.Quantiles = map[string]float64{
+			fmt.Sprint(1 - q): fit.center - fit.scale,
+			fmt.Sprint(q):     fit.center + fit.scale,
+		}
+	}
+	return m, nil
+}
+
+// FitGrouped computes a z-score baseline for valueCol within each group of
+// rec sharing a keyCol value, as DetectAnomaliesGrouped does, along with one
+// for the whole column. A group that does not vary gets a zero scale, and
+// its rows are left unscored by DetectRecord.
+func FitGrouped(ctx context.Context, rec arrow.Record, valueCol, keyCol string, threshold float64) (*Model, error) {
+	vidx := rec.Schema().FieldIndices(valueCol)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", valueCol)
+	}
+	kidx := rec.Schema().FieldIndices(keyCol)
+	if len(kidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", keyCol)
+	}
+	floatCol, err := toFloat64(ctx, rec.Column(vidx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", valueCol, err)
+	}
+	defer floatCol.Release()
+	keys := rec.Column(kidx[0])
+
+	groups := make(map[string]*runningStats)
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) || keys.IsNull(i) {
+			continue
+		}
+		k := keys.ValueStr(i)
+		s := groups[k]
+		if s == nil {
+			s = &runningStats{}
+			groups[k] = s
+		}
+		s.add(floatCol.Value(i))
+	}
+	all := accumulate(floatCol)
+	m := &Model{
+		Version:   ModelVersion,
+		Method:    MethodZScore,
+		Column:    valueCol,
+		Threshold: threshold,
+		Baseline:  zBaseline(all),
+		GroupBy:   keyCol,
+		Groups:    make(map[string]Baseline, len(groups)),
+	}
+	for k, s := range groups {
+		m.Groups[k] = zBaseline(*s)
+	}
+	return m, nil
+}
+
+// zBaseline is the z-score baseline of s.
+func zBaseline(s runningStats) Baseline {
+	return Baseline{Center: s.mean, Scale: s.stdDev(), Count: s.n, Mean: s.mean, StdDev: s.stdDev()}
+}
+
+// Detect implements Detector, scoring col against the model's Baseline. A
+// grouped model needs the key column; use DetectRecord.
+func (m *Model) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if m.GroupBy != "" {
+		return nil, fmt.Errorf("model is grouped by %s; score a record with DetectRecord", m.GroupBy)
+	}
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	return scoreAgainst(ctx, floatCol, m.Baseline.Center, m.Baseline.Scale, m.Threshold, m.Baseline.stats())
+}
+
+// DetectRecord scores the model's Column of rec, against the baseline of each
+// row's group for a grouped model. Rows of a group fitted with a zero scale
+// get a null score and are never flagged.
+func (m *Model) DetectRecord(ctx context.Context, rec arrow.Record) (*Result, error) {
+	vidx := rec.Schema().FieldIndices(m.Column)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", m.Column)
+	}
+	if m.GroupBy == "" {
+		return m.Detect(ctx, rec.Column(vidx[0]))
+	}
+	kidx := rec.Schema().FieldIndices(m.GroupBy)
+	if len(kidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", m.GroupBy)
+	}
+	floatCol, err := toFloat64(ctx, rec.Column(vidx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", m.Column, err)
+	}
+	defer floatCol.Release()
+	keys := rec.Column(kidx[0])
+
+	mem := compute.GetAllocator(ctx)
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			scores.AppendNull()
+			mask.AppendNull()
+			continue
+		}
+		b := m.Baseline
+		if keys.IsValid(i) {
+			if g, ok := m.Groups[keys.ValueStr(i)]; ok {
+				b = g
+			}
+		}
+		if b.Scale == 0 {
+			scores.AppendNull()
+			mask.Append(false)
+			continue
+		}
+		z := (floatCol.Value(i) - b.Center) / b.Scale
+		scores.Append(z)
+		mask.Append(math.Abs(z) >= m.Threshold)
+	}
+	return newResult(ctx, floatCol, mask.NewBooleanArray(), scores.NewFloat64Array(), m.Baseline.stats())
+}
+
+// Save writes the model to path as JSON.
+func (m *Model) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode model: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write model: %w", err)
+	}
+	return nil
+}
+
+// LoadModel reads a model written by Save.
+func LoadModel(path string) (*Model, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read model: %w", err)
+	}
+	var m Model
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("decode model %s: %w", path, err)
+	}
+	if m.Version != ModelVersion {
+		return nil, fmt.Errorf("model %s has version %d, want %d", path, m.Version, ModelVersion)
+	}
+	return &m, nil
+}
@@ -0,0 +1,158 @@
+package supercharged
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestModelSaveLoad(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	ref := array.NewFloat64Builder(pool)
+	defer ref.Release()
+	ref.AppendValues([]float64{10, 11, 9, 10, 12, 8, 10, 11, 9, 10}, nil)
+	refArr := ref.NewFloat64Array()
+	defer refArr.Release()
+
+	ctx := context.Background()
+	m, err := Fit(ctx, MADDetector{Threshold: 3}, refArr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := m.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadModel(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Method != MethodMAD || loaded.Baseline.Center != m.Baseline.Center || loaded.Baseline.Scale != m.Baseline.Scale {
+		t.Errorf("loaded %+v, want %+v", loaded, m)
+	}
+
+	// New data scored against the reference: 30 is far out even though it is
+	// typical of the new batch itself.
+	next := array.NewFloat64Builder(pool)
+	defer next.Release()
+	next.AppendValues([]float64{30, 30, 30, 10}, nil)
+	nextArr := next.NewFloat64Array()
+	defer nextArr.Release()
+	res, err := loaded.Detect(ctx, nextArr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if got := res.AnomalousIndices(); len(got) != 3 {
+		t.Errorf("indices = %v, want [0 1 2]", got)
+	}
+
+	if _, err := Fit(ctx, HampelDetector{HalfWindow: 3, Threshold: 3}, refArr); err == nil {
+		t.Errorf("expected error fitting a windowed detector")
+	}
+}
+
+func TestModelGrouped(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "cpu", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	for i := 0; i < 20; i++ {
+		b.Field(0).(*array.StringBuilder).Append("a")
+		b.Field(1).(*array.Float64Builder).Append(float64(10 + i%2))
+		b.Field(0).(*array.StringBuilder).Append("b")
+		b.Field(1).(*array.Float64Builder).Append(float64(80 + i%2))
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := context.Background()
+	m, err := FitGrouped(ctx, rec, "cpu", "host", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Groups) != 2 || m.Groups["b"].Mean != 80.5 {
+		t.Fatalf("groups = %+v", m.Groups)
+	}
+
+	// 80 is normal for b but not for a.
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "b"}, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{80, 80}, nil)
+	next := b.NewRecord()
+	defer next.Release()
+	res, err := m.DetectRecord(ctx, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if got := res.AnomalousIndices(); len(got) != 1 || got[0] != 0 {
+		t.Errorf("indices = %v, want [0]", got)
+	}
+	if _, err := m.Detect(ctx, next.Column(1)); err == nil {
+		t.Errorf("expected error scoring a grouped model without keys")
+	}
+}
+
+func TestModelZeroScale(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "cpu", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	// Host c was constant over the reference period.
+	for i := 0; i < 20; i++ {
+		b.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "c"}, nil)
+		b.Field(1).(*array.Float64Builder).AppendValues([]float64{float64(10 + i%2), 50}, nil)
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	fb := array.NewFloat64Builder(pool)
+	defer fb.Release()
+	fb.AppendValues([]float64{50, 50, 50}, nil)
+	flat := fb.NewFloat64Array()
+	defer flat.Release()
+	for _, d := range []Detector{ZScoreDetector{Threshold: 3}, MADDetector{Threshold: 3}} {
+		if _, err := Fit(ctx, d, flat); !errors.Is(err, ErrZeroScale) {
+			t.Errorf("%T: got %v fitting a constant column, want ErrZeroScale", d, err)
+		}
+	}
+
+	m, err := FitGrouped(ctx, rec, "cpu", "host", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"c", "c", "a"}, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{50, 90, 90}, nil)
+	next := b.NewRecord()
+	defer next.Release()
+	res, err := m.DetectRecord(ctx, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if res.Zscore.IsValid(0) || res.Zscore.IsValid(1) {
+		t.Errorf("scores = %v, want null for group c", res.Zscore)
+	}
+	if got := res.AnomalousIndices(); len(got) != 1 || got[0] != 2 {
+		t.Errorf("indices = %v, want [2]", got)
+	}
+}
@@ -0,0 +1,92 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// structOfColumns combines the named columns of rec into one struct array
+// for multivariate detectors. The caller must Release it.
+func structOfColumns(rec arrow.Record, columns []string) (*array.Struct, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns to analyze")
+	}
+	cols := make([]arrow.Array, len(columns))
+	for i, name := range columns {
+		idx := rec.Schema().FieldIndices(name)
+		if len(idx) == 0 {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+		cols[i] = rec.Column(idx[0])
+	}
+	st, err := array.NewStructArray(cols, columns)
+	if err != nil {
+		return nil, fmt.Errorf("combine columns: %w", err)
+	}
+	return st, nil
+}
+
+// variables holds the dimensions of a multivariate input as Float64 arrays.
+type variables struct {
+	parent arrow.Array
+	cols   []*array.Float64
+}
+
+// newVariables splits col into its numeric variables: the fields of a struct
+// array, or col itself for a plain numeric array. The caller must release.
+func newVariables(ctx context.Context, col arrow.Array) (*variables, error) {
+	fields := []arrow.Array{col}
+	names := []string{""}
+	if st, ok := col.(*array.Struct); ok {
+		fields, names = fields[:0], names[:0]
+		for j := 0; j < st.NumField(); j++ {
+			fields = append(fields, st.Field(j))
+			names = append(names, st.DataType().(*arrow.StructType).Field(j).Name)
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("no variables")
+		}
+	}
+	v := &variables{parent: col}
+	for j, f := range fields {
+		c, err := toFloat64(ctx, f)
+		if err != nil {
+			v.release()
+			if names[j] != "" {
+				return nil, fmt.Errorf("field %s: %w", names[j], err)
+			}
+			return nil, err
+		}
+		v.cols = append(v.cols, c)
+	}
+	return v, nil
+}
+
+func (v *variables) release() {
+	for _, c := range v.cols {
+		c.Release()
+	}
+}
+
+// complete reports whether row i has a value for every variable.
+func (v *variables) complete(i int) bool {
+	if v.parent.IsNull(i) {
+		return false
+	}
+	for _, c := range v.cols {
+		if c.IsNull(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// row copies the values of row i into dst.
+func (v *variables) row(i int, dst []float64) {
+	for j, c := range v.cols {
+		dst[j] = c.Value(i)
+	}
+}
@@ -0,0 +1,110 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// NonFinitePolicy says how detectors treat NaN and ±Inf input values, which
+// would otherwise poison the mean and variance.
+type NonFinitePolicy string
+
+// Supported non-finite policies.
+const (
+	// NonFiniteDrop leaves NaN and Inf out of the statistics, like nulls,
+	// and never flags them. It is the default.
+	NonFiniteDrop NonFinitePolicy = "drop"
+	// NonFiniteFlag leaves NaN and Inf out of the statistics and flags
+	// every such row. It has no effect under NullPolicyImputeMean, which
+	// fills them in.
+	NonFiniteFlag NonFinitePolicy = "flag"
+	// NonFiniteError fails detection when the input has any NaN or Inf.
+	NonFiniteError NonFinitePolicy = "error"
+)
+
+type nonFinitePolicyKey struct{}
+
+// ContextWithNonFinitePolicy returns a copy of ctx carrying p, which
+// detectors apply to their input. DetectAnomalies does this for
+// WithNonFinitePolicy.
+func ContextWithNonFinitePolicy(ctx context.Context, p NonFinitePolicy) context.Context {
+	return context.WithValue(ctx, nonFinitePolicyKey{}, p)
+}
+
+func nonFinitePolicyFrom(ctx context.Context) NonFinitePolicy {
+	if p, ok := ctx.Value(nonFinitePolicyKey{}).(NonFinitePolicy); ok && p != "" {
+		return p
+	}
+	return NonFiniteDrop
+}
+
+func (p NonFinitePolicy) validate() error {
+	switch p {
+	case "", NonFiniteDrop, NonFiniteFlag, NonFiniteError:
+		return nil
+	default:
+		return fmt.Errorf("unknown non-finite policy %q", p)
+	}
+}
+
+func isNonFinite(v float64) bool {
+	return math.IsNaN(v) || math.IsInf(v, 0)
+}
+
+// applyNonFinitePolicy turns NaN and Inf values of col into nulls, taking
+// ownership of col. The rebuilt array keeps each dropped value in the data
+// buffer under its cleared validity bit, and zero under every original null,
+// so nullKinds can later tell the two apart.
+func applyNonFinitePolicy(ctx context.Context, col *array.Float64) (*array.Float64, error) {
+	var bad int
+	for i, v := range col.Float64Values() {
+		if col.IsValid(i) && isNonFinite(v) {
+			bad++
+		}
+	}
+	if bad == 0 && col.NullN() == 0 {
+		return col, nil
+	}
+	if bad > 0 && nonFinitePolicyFrom(ctx) == NonFiniteError {
+		col.Release()
+		return nil, fmt.Errorf("input has %d NaN or Inf values", bad)
+	}
+	defer col.Release()
+
+	vals := make([]float64, col.Len())
+	valid := make([]bool, col.Len())
+	for i, v := range col.Float64Values() {
+		if col.IsValid(i) {
+			vals[i], valid[i] = v, !isNonFinite(v)
+		}
+	}
+	b := array.NewFloat64Builder(compute.GetAllocator(ctx))
+	defer b.Release()
+	b.AppendValues(vals, valid)
+	return b.NewFloat64Array(), nil
+}
+
+// nullKind classifies a null row of a column from toFloat64.
+type nullKind int
+
+const (
+	kindNull nullKind = iota
+	kindNaN
+	kindInf
+)
+
+// nullKindOf reports why row i of col, which must be null, has no value.
+func nullKindOf(col *array.Float64, i int) nullKind {
+	switch v := col.Float64Values()[i]; {
+	case math.IsNaN(v):
+		return kindNaN
+	case math.IsInf(v, 0):
+		return kindInf
+	default:
+		return kindNull
+	}
+}
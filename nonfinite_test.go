@@ -0,0 +1,67 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestNonFinitePolicy(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{10, 11, math.NaN(), 9, 10, math.Inf(1), 12, 8, 10, 500, 11}, nil)
+	b.AppendNull()
+	col := b.NewFloat64Array()
+	defer col.Release()
+	ctx := context.Background()
+
+	res, err := DetectAnomalies(ctx, col, WithThreshold(2.5), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.IsNaN(res.Mean) || math.IsInf(res.Mean, 0) {
+		t.Errorf("mean = %v, want finite", res.Mean)
+	}
+	if res.Indices.Len() != 1 || res.Indices.Value(0) != 9 {
+		t.Errorf("drop: indices = %v, want [9]", res.Indices)
+	}
+	if res.Nulls != 1 || res.NaNs != 1 || res.Infs != 1 {
+		t.Errorf("counts = %d/%d/%d, want 1/1/1", res.Nulls, res.NaNs, res.Infs)
+	}
+	res.Release()
+
+	res, err = DetectAnomalies(ctx, col, WithThreshold(2.5), WithNonFinitePolicy(NonFiniteFlag), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Indices.Int64Values(); len(got) != 3 || got[0] != 2 || got[1] != 5 || got[2] != 9 {
+		t.Errorf("flag: indices = %v, want [2 5 9]", got)
+	}
+	res.Release()
+
+	if _, err := DetectAnomalies(ctx, col, WithNonFinitePolicy(NonFiniteError)); err == nil {
+		t.Errorf("expected error for NaN input")
+	}
+	// A null-only error policy is not tripped by NaN, only by the null.
+	head := array.NewSlice(col, 0, 11)
+	defer head.Release()
+	if res, err := DetectAnomalies(ctx, head, WithNullPolicy(NullPolicyError)); err != nil {
+		t.Errorf("null policy error tripped by NaN: %v", err)
+	} else {
+		res.Release()
+	}
+
+	sum, err := Stats(col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Count != 9 || sum.Nulls != 1 || sum.NaNs != 1 || sum.Infs != 1 || sum.Max != 500 {
+		t.Errorf("summary = %+v", sum)
+	}
+}
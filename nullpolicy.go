@@ -0,0 +1,121 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// NullPolicy says how detectors treat null input values.
+type NullPolicy string
+
+// Supported null policies.
+const (
+	// NullPolicySkip leaves nulls out of the statistics; null rows get a
+	// null score and are never flagged. It is the default.
+	NullPolicySkip NullPolicy = "skip"
+	// NullPolicyFlagAsAnomaly leaves nulls out of the statistics but flags
+	// every null row, for inputs where a missing value is itself a fault.
+	NullPolicyFlagAsAnomaly NullPolicy = "flag"
+	// NullPolicyError fails detection when the input has any null.
+	NullPolicyError NullPolicy = "error"
+	// NullPolicyImputeMean replaces nulls with the mean of the non-null
+	// values before detection, so they are scored like any other value.
+	NullPolicyImputeMean NullPolicy = "impute-mean"
+)
+
+type nullPolicyKey struct{}
+
+// ContextWithNullPolicy returns a copy of ctx carrying p, which detectors
+// apply to their input. DetectAnomalies does this for WithNullPolicy; use it
+// when calling a Detector directly.
+func ContextWithNullPolicy(ctx context.Context, p NullPolicy) context.Context {
+	return context.WithValue(ctx, nullPolicyKey{}, p)
+}
+
+func nullPolicyFrom(ctx context.Context) NullPolicy {
+	if p, ok := ctx.Value(nullPolicyKey{}).(NullPolicy); ok && p != "" {
+		return p
+	}
+	return NullPolicySkip
+}
+
+func (p NullPolicy) validate() error {
+	switch p {
+	case "", NullPolicySkip, NullPolicyFlagAsAnomaly, NullPolicyError, NullPolicyImputeMean:
+		return nil
+	default:
+		return fmt.Errorf("unknown null policy %q", p)
+	}
+}
+
+// applyNullPolicy enforces the input side of the policy on col, taking
+// ownership of it.
+func applyNullPolicy(ctx context.Context, col *array.Float64) (*array.Float64, error) {
+	if col.NullN() == 0 {
+		return col, nil
+	}
+	switch nullPolicyFrom(ctx) {
+	case NullPolicyError:
+		var n int
+		for i := 0; i < col.Len(); i++ {
+			if col.IsNull(i) && nullKindOf(col, i) == kindNull {
+				n++
+			}
+		}
+		if n == 0 {
+			return col, nil
+		}
+		col.Release()
+		return nil, fmt.Errorf("input has %d null values", n)
+	case NullPolicyImputeMean:
+		defer col.Release()
+		mean := accumulate(col).mean
+		b := array.NewFloat64Builder(compute.GetAllocator(ctx))
+		defer b.Release()
+		b.Reserve(col.Len())
+		for i := 0; i < col.Len(); i++ {
+			if col.IsNull(i) {
+				b.Append(mean)
+			} else {
+				b.Append(col.Value(i))
+			}
+		}
+		return b.NewFloat64Array(), nil
+	default:
+		return col, nil
+	}
+}
+
+// maskNulls resolves the mask for rows where col is null, or where the mask
+// itself is null: dropped NaN and Inf values are flagged under NonFiniteFlag,
+// and true nulls under NullPolicyFlagAsAnomaly; nothing else is. It takes
+// ownership of mask.
+func maskNulls(ctx context.Context, col *array.Float64, mask *array.Boolean) *array.Boolean {
+	if col.NullN() == 0 && mask.NullN() == 0 {
+		return mask
+	}
+	defer mask.Release()
+	flagNull := nullPolicyFrom(ctx) == NullPolicyFlagAsAnomaly
+	flagNonFinite := nonFinitePolicyFrom(ctx) == NonFiniteFlag
+	b := array.NewBooleanBuilder(compute.GetAllocator(ctx))
+	defer b.Release()
+	b.Reserve(mask.Len())
+	for i := 0; i < mask.Len(); i++ {
+		switch {
+		case col.IsNull(i):
+			if nullKindOf(col, i) == kindNull {
+				b.Append(flagNull)
+			} else {
+				b.Append(flagNonFinite)
+			}
+		case mask.IsNull(i):
+			b.Append(false)
+		default:
+			b.Append(mask.Value(i))
+		}
+	}
+	return b.NewBooleanArray()
+}
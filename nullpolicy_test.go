@@ -0,0 +1,58 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestNullPolicy(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{10, 11, 9, 10}, nil)
+	b.AppendNull()
+	b.AppendValues([]float64{12, 8, 10, 500, 11}, nil)
+	col := b.NewFloat64Array()
+	defer col.Release()
+	ctx := context.Background()
+
+	for _, c := range []struct {
+		policy  NullPolicy
+		method  Method
+		flagged []int64
+	}{
+		{NullPolicySkip, MethodZScore, []int64{8}},
+		{NullPolicySkip, MethodRolling, []int64{8}},
+		{NullPolicyFlagAsAnomaly, MethodZScore, []int64{4, 8}},
+		{NullPolicyFlagAsAnomaly, MethodRolling, []int64{4, 8}},
+		{NullPolicyImputeMean, MethodZScore, []int64{8}},
+	} {
+		res, err := DetectAnomalies(ctx, col, WithNullPolicy(c.policy), WithMethod(c.method), WithWindow(3), WithThreshold(2.5), WithAllocator(pool))
+		if err != nil {
+			t.Fatalf("%s/%s: %v", c.policy, c.method, err)
+		}
+		if res.Mask.NullN() != 0 {
+			t.Errorf("%s/%s: mask has %d nulls", c.policy, c.method, res.Mask.NullN())
+		}
+		if got := res.Indices.Int64Values(); fmt.Sprint(got) != fmt.Sprint(c.flagged) {
+			t.Errorf("%s/%s: indices = %v, want %v", c.policy, c.method, got, c.flagged)
+		}
+		if c.policy == NullPolicyImputeMean && !res.Zscore.IsValid(4) {
+			t.Errorf("impute-mean: expected a score for the imputed row")
+		}
+		res.Release()
+	}
+
+	if _, err := DetectAnomalies(ctx, col, WithNullPolicy(NullPolicyError)); err == nil {
+		t.Errorf("expected error for null input")
+	}
+	if _, err := DetectAnomalies(ctx, col, WithNullPolicy("bogus")); err == nil {
+		t.Errorf("expected error for unknown policy")
+	}
+}
@@ -0,0 +1,219 @@
+// Package objstore reads objects from S3, Google Cloud Storage and plain
+// HTTP(S) servers, either as a stream or by byte ranges, without
+// downloading them first.
+package objstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// IsURL reports whether path names a remote object this package can open.
+func IsURL(path string) bool {
+	for _, scheme := range []string{"s3://", "gs://", "http://", "https://"} {
+		if strings.HasPrefix(path, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config holds credentials and endpoints. The zero value reads public
+// objects anonymously.
+type Config struct {
+	// S3 signing credentials; requests are unsigned when AccessKeyID is
+	// empty.
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Region is the S3 region, us-east-1 if empty.
+	Region string
+	// Endpoint replaces the AWS S3 endpoint (e.g. for MinIO); buckets are
+	// then addressed path-style.
+	Endpoint string
+	// GCSToken is an OAuth2 access token sent to Google Cloud Storage.
+	GCSToken string
+	// Client is the HTTP client; nil means http.DefaultClient.
+	Client *http.Client
+}
+
+// ConfigFromEnv reads the standard AWS_* variables and
+// GOOGLE_OAUTH_ACCESS_TOKEN.
+func ConfigFromEnv() Config {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	return Config{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Region:          region,
+		Endpoint:        endpoint,
+		GCSToken:        os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"),
+	}
+}
+
+// Object is a remote object of known size. It implements io.ReaderAt and
+// io.Seeker with HTTP range requests, so formats needing random access, such
+// as Parquet, read only the bytes they use.
+type Object struct {
+	ctx    context.Context
+	cfg    Config
+	url    *url.URL
+	bucket string
+	size   int64
+	offset int64
+}
+
+// Open resolves rawURL (s3://bucket/key, gs://bucket/object or an http(s)
+// URL) and fetches its size.
+func Open(ctx context.Context, rawURL string, cfg Config) (*Object, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("object url: %w", err)
+	}
+	o := &Object{ctx: ctx, cfg: cfg}
+	switch u.Scheme {
+	case "s3":
+		o.bucket = u.Host
+		o.url, err = s3URL(cfg, u.Host, strings.TrimPrefix(u.Path, "/"))
+		if err != nil {
+			return nil, err
+		}
+	case "gs":
+		o.url = &url.URL{Scheme: "https", Host: "storage.googleapis.com", Path: "/" + u.Host + u.Path}
+	case "http", "https":
+		o.url = u
+	default:
+		return nil, fmt.Errorf("object url: unsupported scheme %q", u.Scheme)
+	}
+
+	resp, err := o.do(http.MethodHead, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	o.size = resp.ContentLength
+	return o, nil
+}
+
+// s3URL returns the HTTPS URL of key in bucket.
+func s3URL(cfg Config, bucket, key string) (*url.URL, error) {
+	if cfg.Endpoint != "" {
+		u, err := url.Parse(cfg.Endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("s3 endpoint: %w", err)
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + bucket + "/" + key
+		return u, nil
+	}
+	region := cfg.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &url.URL{Scheme: "https", Host: bucket + ".s3." + region + ".amazonaws.com", Path: "/" + key}, nil
+}
+
+// Size returns the object's length in bytes, or -1 if the server did not
+// report it.
+func (o *Object) Size() int64 { return o.size }
+
+// Stream returns the whole object as a stream; the caller must Close it.
+func (o *Object) Stream() (io.ReadCloser, error) {
+	resp, err := o.do(http.MethodGet, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ReadAt implements io.ReaderAt with a range request.
+func (o *Object) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if o.size >= 0 && off >= o.size {
+		return 0, io.EOF
+	}
+	resp, err := o.do(http.MethodGet, "bytes="+strconv.FormatInt(off, 10)+"-"+strconv.FormatInt(off+int64(len(p))-1, 10))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Seek implements io.Seeker; it only moves the offset used by Read.
+func (o *Object) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += o.offset
+	case io.SeekEnd:
+		offset += o.size
+	default:
+		return 0, fmt.Errorf("seek: invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("seek: negative offset")
+	}
+	o.offset = offset
+	return offset, nil
+}
+
+// Read reads from the offset set by Seek.
+func (o *Object) Read(p []byte) (int, error) {
+	n, err := o.ReadAt(p, o.offset)
+	o.offset += int64(n)
+	return n, err
+}
+
+// do sends an authenticated request, optionally for a byte range, and
+// checks the status.
+func (o *Object) do(method, byteRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(o.ctx, method, o.url.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if byteRange != "" {
+		req.Header.Set("Range", byteRange)
+	}
+	switch {
+	case o.bucket != "" && o.cfg.AccessKeyID != "":
+		region := o.cfg.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		signV4(req, o.cfg.AccessKeyID, o.cfg.SecretAccessKey, o.cfg.SessionToken, region, now())
+	case o.url.Host == "storage.googleapis.com" && o.cfg.GCSToken != "":
+		req.Header.Set("Authorization", "Bearer "+o.cfg.GCSToken)
+	}
+	client := o.cfg.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", o.url.Redacted(), err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetch %s: %s", o.url.Redacted(), resp.Status)
+	}
+	return resp, nil
+}
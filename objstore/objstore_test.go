@@ -0,0 +1,207 @@
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTrip is an http.RoundTripper that answers every request itself.
+type roundTrip func(*http.Request) (*http.Response, error)
+
+func (f roundTrip) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestIsURL(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"s3://bucket/key.parquet", true},
+		{"gs://bucket/object.csv", true},
+		{"http://example.com/a.csv", true},
+		{"https://example.com/a.csv", true},
+		{"data/a.csv", false},
+		{"/tmp/s3://a", false},
+		{"ftp://example.com/a.csv", false},
+	}
+	for _, tt := range tests {
+		if got := IsURL(tt.path); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOpenURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		cfg  Config
+		want string
+	}{
+		{"s3://bucket/dir/a.parquet", Config{}, "https://bucket.s3.us-east-1.amazonaws.com/dir/a.parquet"},
+		{"s3://bucket/a b.csv", Config{Region: "eu-west-1"}, "https://bucket.s3.eu-west-1.amazonaws.com/a%20b.csv"},
+		{"s3://bucket/a.csv", Config{Endpoint: "http://localhost:9000/"}, "http://localhost:9000/bucket/a.csv"},
+		{"s3://bucket/a.csv", Config{Endpoint: "http://minio/base"}, "http://minio/base/bucket/a.csv"},
+		{"gs://bucket/dir/a.csv", Config{}, "https://storage.googleapis.com/bucket/dir/a.csv"},
+		{"https://example.com/a.csv?x=1", Config{}, "https://example.com/a.csv?x=1"},
+	}
+	for _, tt := range tests {
+		var got string
+		tt.cfg.Client = &http.Client{Transport: roundTrip(func(req *http.Request) (*http.Response, error) {
+			got = req.URL.String()
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, ContentLength: 7}, nil
+		})}
+		o, err := Open(context.Background(), tt.url, tt.cfg)
+		if err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: requested %s, want %s", tt.url, got, tt.want)
+		}
+		if o.Size() != 7 {
+			t.Errorf("%s: size = %d, want 7", tt.url, o.Size())
+		}
+	}
+
+	for _, bad := range []string{"ftp://example.com/a.csv", "s3://bucket/a.csv\x7f"} {
+		if _, err := Open(context.Background(), bad, Config{}); err == nil {
+			t.Errorf("%q: want error", bad)
+		}
+	}
+}
+
+func TestOpenAuth(t *testing.T) {
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		url  string
+		cfg  Config
+		want string
+	}{
+		{"s3://bucket/a.csv", Config{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+			"AWS4-HMAC-SHA256 Credential=AKID/20130524/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, "},
+		{"s3://bucket/a.csv", Config{}, ""},
+		{"gs://bucket/a.csv", Config{GCSToken: "tok"}, "Bearer tok"},
+		// Credentials are only sent to the service they belong to.
+		{"https://example.com/a.csv", Config{AccessKeyID: "AKID", GCSToken: "tok"}, ""},
+	}
+	for _, tt := range tests {
+		var got string
+		tt.cfg.Client = &http.Client{Transport: roundTrip(func(req *http.Request) (*http.Response, error) {
+			got = req.Header.Get("Authorization")
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+		})}
+		if _, err := Open(context.Background(), tt.url, tt.cfg); err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		if !strings.HasPrefix(got, tt.want) || (tt.want == "") != (got == "") {
+			t.Errorf("%s: Authorization = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestObjectHTTP(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/data.csv" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method == http.MethodGet {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		http.ServeContent(w, r, "data.csv", time.Time{}, bytes.NewReader(data))
+	}))
+	defer srv.Close()
+
+	o, err := Open(context.Background(), srv.URL+"/data.csv", Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Size() != int64(len(data)) {
+		t.Errorf("size = %d, want %d", o.Size(), len(data))
+	}
+
+	rc, err := o.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil || string(all) != string(data) {
+		t.Errorf("Stream = %q, %v", all, err)
+	}
+
+	p := make([]byte, 4)
+	if n, err := o.ReadAt(p, 10); n != 4 || err != nil || string(p) != "abcd" {
+		t.Errorf("ReadAt(10) = %d, %v, %q", n, err, p[:n])
+	}
+	// A read past the end returns what is there and io.EOF.
+	if n, err := o.ReadAt(p, 18); n != 2 || err != io.EOF || string(p[:n]) != "ij" {
+		t.Errorf("ReadAt(18) = %d, %v, %q", n, err, p[:n])
+	}
+	if n, err := o.ReadAt(p, 20); n != 0 || err != io.EOF {
+		t.Errorf("ReadAt(20) = %d, %v", n, err)
+	}
+
+	if off, err := o.Seek(-5, io.SeekEnd); off != 15 || err != nil {
+		t.Fatalf("Seek = %d, %v", off, err)
+	}
+	if n, err := o.Read(p); n != 4 || err != nil || string(p) != "fghi" {
+		t.Errorf("Read = %d, %v, %q", n, err, p[:n])
+	}
+	if off, _ := o.Seek(0, io.SeekCurrent); off != 19 {
+		t.Errorf("offset = %d, want 19", off)
+	}
+	if _, err := o.Seek(-1, io.SeekStart); err == nil {
+		t.Error("negative seek: want error")
+	}
+
+	want := []string{"", "bytes=10-13", "bytes=18-21", "bytes=15-18"}
+	if strings.Join(ranges, " ") != strings.Join(want, " ") {
+		t.Errorf("ranges = %q, want %q", ranges, want)
+	}
+
+	if _, err := Open(context.Background(), srv.URL+"/missing.csv", Config{}); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("missing object: got %v, want a 404 error", err)
+	}
+}
+
+func TestObjectS3Endpoint(t *testing.T) {
+	// Against a custom endpoint the bucket is in the path and requests are
+	// still signed.
+	var path, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		http.ServeContent(w, r, "a.csv", time.Time{}, strings.NewReader("v\n1\n"))
+	}))
+	defer srv.Close()
+
+	cfg := Config{AccessKeyID: "AKID", SecretAccessKey: "secret", Region: "eu-west-1", Endpoint: srv.URL}
+	o, err := Open(context.Background(), "s3://bucket/dir/a.csv", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := o.Stream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	if b, _ := io.ReadAll(rc); string(b) != "v\n1\n" {
+		t.Errorf("body = %q", b)
+	}
+	if path != "/bucket/dir/a.csv" {
+		t.Errorf("path = %s, want /bucket/dir/a.csv", path)
+	}
+	if !strings.Contains(auth, "Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
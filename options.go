@@ -0,0 +1,318 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// DefaultThreshold is the score threshold used when WithThreshold is not given.
+const DefaultThreshold = 3.0
+
+// Method names a detection algorithm for WithMethod.
+type Method string
+
+// Supported detection methods.
+const (
+	MethodZScore  Method = "zscore"
+	MethodMAD     Method = "mad"
+	MethodIQR     Method = "iqr"
+	MethodRolling Method = "rolling"
+	// MethodMahalanobis scores rows over several columns jointly; see
+	// MahalanobisDetector.
+	MethodMahalanobis Method = "mahalanobis"
+	// MethodLOF scores rows by Local Outlier Factor; see LOFDetector.
+	MethodLOF Method = "lof"
+	// MethodGrubbs and MethodESD are significance tests at WithAlpha; see
+	// GrubbsDetector and ESDDetector.
+	MethodGrubbs Method = "grubbs"
+	MethodESD    Method = "esd"
+	// MethodPercentile flags the values beyond the WithQuantile tails; see
+	// PercentileDetector.
+	MethodPercentile Method = "percentile"
+	// MethodHampel flags spikes against a centered rolling median; see
+	// HampelDetector.
+	MethodHampel Method = "hampel"
+	// MethodCategory flags rare categories of a string or dictionary
+	// column; see CategoryDetector.
+	MethodCategory Method = "category"
+	// MethodPattern flags string values of unusual length or shape; see
+	// StringDetector.
+	MethodPattern Method = "pattern"
+	// MethodFlagRate flags bursts in the rate of a boolean flag over the
+	// trailing WithWindow rows; see FlagRateDetector.
+	MethodFlagRate Method = "flag-rate"
+)
+
+// DefaultWindow is the rolling window size used when WithWindow is not given.
+const DefaultWindow = 30
+
+// Option configures DetectAnomalies and NewDetector.
+type Option func(*options)
+
+type options struct {
+	threshold       float64
+	thresholdSet    bool
+	allocator       memory.Allocator
+	method          Method
+	window          int
+	approximate     bool
+	neighbors       int
+	alpha           float64
+	quantile        float64
+	minCount        int64
+	patternShare    float64
+	patternShareSet bool
+	nullPolicy      NullPolicy
+	nonFinite       NonFinitePolicy
+	parallelism     int
+	fastPath        bool
+	exactIntegers   bool
+	// contamination, when positive, keeps only that fraction of the
+	// highest scores; see ContaminationDetector.
+	contamination float64
+	// upper and lower, when either is positive, replace the symmetric
+	// threshold; see BoundedDetector.
+	upper, lower float64
+	// memoryLimit, when positive, caps detection's allocations; see
+	// WithMemoryLimit.
+	memoryLimit int64
+	// filter and expr scope and derive what a Pipeline scores in a record.
+	filter *Filter
+	expr   *Expr
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{threshold: DefaultThreshold, method: MethodZScore, window: DefaultWindow}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithThreshold sets the score threshold; for MethodIQR it is the fence
+// multiplier k, for MethodLOF the outlier factor and for MethodCategory the
+// negative Pearson residual.
+func WithThreshold(threshold float64) Option {
+	return func(o *options) {
+		o.threshold = threshold
+		o.thresholdSet = true
+	}
+}
+
+// WithAllocator sets the allocator used for casts, compute kernels and result
+// arrays.
+func WithAllocator(mem memory.Allocator) Option {
+	return func(o *options) { o.allocator = mem }
+}
+
+// WithMethod selects the detection algorithm (MethodZScore by default).
+func WithMethod(m Method) Option {
+	return func(o *options) { o.method = m }
+}
+
+// WithWindow sets the trailing window size for MethodRolling and
+// MethodFlagRate, and the centered window size (half on each side) for
+// MethodHampel.
+func WithWindow(n int) Option {
+	return func(o *options) { o.window = n }
+}
+
+// WithApproximate makes quantile-based methods use a t-digest instead of an
+// exact sort.
+func WithApproximate(approximate bool) Option {
+	return func(o *options) { o.approximate = approximate }
+}
+
+// WithNeighbors sets the neighborhood size for MethodLOF.
+func WithNeighbors(k int) Option {
+	return func(o *options) { o.neighbors = k }
+}
+
+// WithAlpha sets the significance level for MethodGrubbs and MethodESD.
+func WithAlpha(alpha float64) Option {
+	return func(o *options) { o.alpha = alpha }
+}
+
+// WithQuantile sets the upper quantile for MethodPercentile, e.g. 0.999 to
+// flag the top and bottom 0.1%.
+func WithQuantile(q float64) Option {
+	return func(o *options) { o.quantile = q }
+}
+
+// WithMinCount flags categories seen fewer than n times for MethodCategory.
+// Unless WithThreshold is also given, it replaces the residual test.
+func WithMinCount(n int64) Option {
+	return func(o *options) { o.minCount = n }
+}
+
+// WithPatternShare sets the pattern share below which MethodPattern flags a
+// value (DefaultPatternShare by default); zero disables the pattern test.
+func WithPatternShare(share float64) Option {
+	return func(o *options) {
+		o.patternShare = share
+		o.patternShareSet = true
+	}
+}
+
+// WithNullPolicy sets how null input values are treated (NullPolicySkip by
+// default).
+func WithNullPolicy(p NullPolicy) Option {
+	return func(o *options) { o.nullPolicy = p }
+}
+
+// WithNonFinitePolicy sets how NaN and Inf input values are treated
+// (NonFiniteDrop by default).
+func WithNonFinitePolicy(p NonFinitePolicy) Option {
+	return func(o *options) { o.nonFinite = p }
+}
+
+// WithParallelism spreads detection over n worker goroutines, each
+// summarizing and scoring its own chunk of the input; n <= 0 means
+// GOMAXPROCS. It applies to the z-score, MAD, IQR and percentile methods.
+func WithParallelism(n int) Option {
+	return func(o *options) {
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		o.parallelism = n
+	}
+}
+
+// WithFastPath computes scores and the mask in one loop over the value
+// buffer rather than through Arrow compute kernels. It applies to the
+// z-score, MAD, IQR and percentile methods.
+func WithFastPath(enabled bool) Option {
+	return func(o *options) { o.fastPath = enabled }
+}
+
+// WithContamination flags the given fraction of values with the largest
+// absolute scores under the chosen method instead of applying its threshold;
+// see ContaminationDetector.
+func WithContamination(fraction float64) Option {
+	return func(o *options) { o.contamination = fraction }
+}
+
+// WithBounds flags values scoring at least upper or at most -lower instead of
+// applying the symmetric threshold; zero disables a side. See
+// BoundedDetector.
+func WithBounds(upper, lower float64) Option {
+	return func(o *options) { o.upper, o.lower = upper, lower }
+}
+
+// context returns ctx carrying the configured allocator, memory limit,
+// parallelism, fast path and null and non-finite policies, if any.
+func (o *options) context(ctx context.Context) context.Context {
+	if o.fastPath {
+		ctx = ContextWithFastPath(ctx, true)
+	}
+	if o.exactIntegers {
+		ctx = ContextWithExactIntegers(ctx, true)
+	}
+	if o.parallelism > 0 {
+		ctx = ContextWithParallelism(ctx, o.parallelism)
+	}
+	if o.nonFinite != "" {
+		ctx = ContextWithNonFinitePolicy(ctx, o.nonFinite)
+	}
+	if o.nullPolicy != "" {
+		ctx = ContextWithNullPolicy(ctx, o.nullPolicy)
+	}
+	if o.allocator != nil {
+		ctx = compute.WithAllocator(ctx, o.allocator)
+	}
+	if o.memoryLimit > 0 {
+		ctx = ContextWithMemoryLimit(ctx, o.memoryLimit)
+	}
+	return ctx
+}
+
+// NewDetector builds the Detector described by opts. WithAllocator,
+// WithNullPolicy, WithNonFinitePolicy, WithParallelism, WithFastPath,
+// WithExactIntegers and WithMemoryLimit only take effect through
+// DetectAnomalies; when calling the Detector directly, set them on the
+// context with compute.WithAllocator, ContextWithNullPolicy,
+// ContextWithNonFinitePolicy, ContextWithParallelism, ContextWithFastPath,
+// ContextWithExactIntegers and ContextWithMemoryLimit.
+func NewDetector(opts ...Option) (Detector, error) {
+	return newOptions(opts).detector()
+}
+
+func (o *options) detector() (Detector, error) {
+	if err := o.nullPolicy.validate(); err != nil {
+		return nil, err
+	}
+	if err := o.nonFinite.validate(); err != nil {
+		return nil, err
+	}
+	d, err := o.methodDetector()
+	if err != nil {
+		return nil, err
+	}
+	bounded := o.upper > 0 || o.lower > 0
+	switch {
+	case o.contamination != 0 && bounded:
+		return nil, fmt.Errorf("contamination cannot be combined with upper and lower bounds")
+	case o.contamination != 0:
+		if o.contamination < 0 || o.contamination >= 1 {
+			return nil, fmt.Errorf("contamination must be in (0, 1), got %v", o.contamination)
+		}
+		return ContaminationDetector{Base: d, Fraction: o.contamination}, nil
+	case bounded:
+		return BoundedDetector{Base: d, Upper: o.upper, Lower: o.lower}, nil
+	}
+	return d, nil
+}
+
+// methodDetector builds the detector for o.method.
+func (o *options) methodDetector() (Detector, error) {
+	switch o.method {
+	case "", MethodZScore:
+		return ZScoreDetector{Threshold: o.threshold}, nil
+	case MethodMAD:
+		return MADDetector{Threshold: o.threshold}, nil
+	case MethodIQR:
+		k := DefaultIQRMultiplier
+		if o.thresholdSet {
+			k = o.threshold
+		}
+		return IQRDetector{K: k, Approximate: o.approximate}, nil
+	case MethodRolling:
+		return WindowedDetector{Window: o.window, Threshold: o.threshold}, nil
+	case MethodMahalanobis:
+		return MahalanobisDetector{Threshold: o.threshold}, nil
+	case MethodLOF:
+		lof := LOFDetector{K: o.neighbors}
+		if o.thresholdSet {
+			lof.Threshold = o.threshold
+		}
+		return lof, nil
+	case MethodGrubbs:
+		return GrubbsDetector{Alpha: o.alpha}, nil
+	case MethodESD:
+		return ESDDetector{Alpha: o.alpha}, nil
+	case MethodPercentile:
+		return PercentileDetector{Q: o.quantile, Approximate: o.approximate}, nil
+	case MethodHampel:
+		return HampelDetector{HalfWindow: max(1, o.window/2), Threshold: o.threshold}, nil
+	case MethodCategory:
+		cd := CategoryDetector{MinCount: o.minCount, Threshold: o.threshold}
+		if o.minCount > 0 && !o.thresholdSet {
+			cd.Threshold = 0
+		}
+		return cd, nil
+	case MethodPattern:
+		sd := StringDetector{Threshold: o.threshold, MinPatternShare: DefaultPatternShare}
+		if o.patternShareSet {
+			sd.MinPatternShare = o.patternShare
+		}
+		return sd, nil
+	case MethodFlagRate:
+		return FlagRateDetector{Window: o.window, Threshold: o.threshold}, nil
+	default:
+		return nil, fmt.Errorf("unknown method %q", o.method)
+	}
+}
@@ -0,0 +1,162 @@
+package supercharged
+
+import (
+	"context"
+	"runtime"
+	"sync"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+type parallelismKey struct{}
+
+// ContextWithParallelism returns a copy of ctx under which chunked detection
+// runs on up to n worker goroutines; n <= 0 means GOMAXPROCS.
+func ContextWithParallelism(ctx context.Context, n int) context.Context {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	return context.WithValue(ctx, parallelismKey{}, n)
+}
+
+// parallelismFrom returns the worker count carried by ctx, 1 if none.
+func parallelismFrom(ctx context.Context) int {
+	if n, ok := ctx.Value(parallelismKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// parallelFor calls fn(i) for every i in [0, n) on up to workers goroutines
+// and returns the first error. Once an error occurs remaining indices are
+// skipped.
+func parallelFor(workers, n int, fn func(i int) error) error {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		failed   = make(chan struct{})
+		next     = make(chan int)
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if err := fn(i); err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case next <- i:
+		case <-failed:
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+	return firstErr
+}
+
+// accumulateAll computes running statistics for each array, in parallel under
+// ContextWithParallelism, and merges them in order.
+func accumulateAll(ctx context.Context, cols []*array.Float64) runningStats {
+	partial := make([]runningStats, len(cols))
+	_ = parallelFor(parallelismFrom(ctx), len(cols), func(i int) error {
+		partial[i] = accumulate(cols[i])
+		return nil
+	})
+	var s runningStats
+	for _, p := range partial {
+		s.merge(p)
+	}
+	return s
+}
+
+// detectSplit runs a fitter over col split into one slice per worker and
+// reassembles a single Result, so DetectAnomalies can use every worker on an
+// unchunked array.
+func detectSplit(ctx context.Context, d Detector, col arrow.Array, workers int) (*Result, error) {
+	n := int64(col.Len())
+	step := (n + int64(workers) - 1) / int64(workers)
+	var parts []arrow.Array
+	for lo := int64(0); lo < n; lo += step {
+		parts = append(parts, array.NewSlice(col, lo, min(lo+step, n)))
+	}
+	chunked := arrow.NewChunked(col.DataType(), parts)
+	for _, p := range parts {
+		p.Release()
+	}
+	defer chunked.Release()
+
+	res, err := detectChunked(ctx, d, chunked)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Release()
+	return res.merge(ctx)
+}
+
+// merge concatenates the per-chunk Results into one over the whole column.
+func (r *ChunkedResult) merge(ctx context.Context) (*Result, error) {
+	mem := compute.GetAllocator(ctx)
+	masks := make([]arrow.Array, len(r.Chunks))
+	scores := make([]arrow.Array, len(r.Chunks))
+	values := make([]arrow.Array, len(r.Chunks))
+	out := &Result{}
+	for i, c := range r.Chunks {
+		masks[i], scores[i], values[i] = c.Mask, c.Zscore, c.Values
+		out.Nulls += c.Nulls
+		out.NaNs += c.NaNs
+		out.Infs += c.Infs
+		out.Inexact += c.Inexact
+	}
+	if len(r.Chunks) > 0 {
+		out.Mean, out.StdDev = r.Chunks[0].Mean, r.Chunks[0].StdDev
+	}
+
+	mask, err := array.Concatenate(masks, mem)
+	if err != nil {
+		return nil, err
+	}
+	out.Mask = mask.(*array.Boolean)
+	zscore, err := array.Concatenate(scores, mem)
+	if err != nil {
+		out.Release()
+		return nil, err
+	}
+	out.Zscore = zscore.(*array.Float64)
+	vals, err := array.Concatenate(values, mem)
+	if err != nil {
+		out.Release()
+		return nil, err
+	}
+	out.Values = vals.(*array.Float64)
+
+	indices := array.NewInt64Builder(mem)
+	defer indices.Release()
+	indices.AppendValues(r.Indices(), nil)
+	out.Indices = indices.NewInt64Array()
+	return out, nil
+}
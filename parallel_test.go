@@ -0,0 +1,62 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestWithParallelism(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	rng := rand.New(rand.NewSource(1))
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	for i := 0; i < 10000; i++ {
+		switch {
+		case i%2500 == 7:
+			b.Append(50)
+		case i%997 == 0:
+			b.AppendNull()
+		default:
+			b.Append(rng.NormFloat64())
+		}
+	}
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	for _, m := range []Method{MethodZScore, MethodMAD, MethodIQR} {
+		want, err := DetectAnomalies(ctx, col, WithMethod(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DetectAnomalies(ctx, col, WithMethod(m), WithParallelism(4))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Mask.Len() != col.Len() || got.Nulls != want.Nulls {
+			t.Errorf("%s: mask length %d, nulls %d; want %d, %d", m, got.Mask.Len(), got.Nulls, col.Len(), want.Nulls)
+		}
+		if math.Abs(got.Mean-want.Mean) > 1e-12 || math.Abs(got.StdDev-want.StdDev) > 1e-12 {
+			t.Errorf("%s: stats %v/%v, want %v/%v", m, got.Mean, got.StdDev, want.Mean, want.StdDev)
+		}
+		if g, w := got.Indices.Int64Values(), want.Indices.Int64Values(); len(g) != len(w) {
+			t.Errorf("%s: flagged %d rows, want %d", m, len(g), len(w))
+		} else {
+			for i := range w {
+				if g[i] != w[i] || got.Values.Value(i) != want.Values.Value(i) {
+					t.Errorf("%s: row %d: got %d, want %d", m, i, g[i], w[i])
+				}
+			}
+		}
+		want.Release()
+		got.Release()
+	}
+}
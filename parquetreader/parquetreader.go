@@ -0,0 +1,246 @@
+// Package parquetreader provides a streaming Parquet reader for Arrow.
+package parquetreader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/TFMV/supercharged/internal/mmap"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/file"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// DefaultBatchSize is the number of rows per record emitted by Chan.
+const DefaultBatchSize = 64 * 1024
+
+// ParquetReader streams Arrow Records from a Parquet file, decoding only the
+// projected columns.
+type ParquetReader struct {
+	allocator memory.Allocator
+	file      *file.Reader
+	// unmap, when the file is memory-mapped, unmaps it on Close.
+	unmap   func() error
+	reader  *pqarrow.FileReader
+	full    *arrow.Schema
+	schema  *arrow.Schema
+	columns []int
+}
+
+// NewParquetReader creates a ParquetReader over r. If columns is non-empty only
+// those top-level columns are read from the file; otherwise all are. Records
+// are allocated from mem; nil means a new Go allocator.
+func NewParquetReader(r parquet.ReaderAtSeeker, columns []string, mem memory.Allocator) (*ParquetReader, error) {
+	pf, err := file.NewParquetReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	return newReader(pf, columns, mem)
+}
+
+// OpenParquetFile opens the Parquet file at path; see NewParquetReader.
+func OpenParquetFile(path string, columns []string, mem memory.Allocator) (*ParquetReader, error) {
+	pf, err := file.OpenParquetFile(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	return newReader(pf, columns, mem)
+}
+
+// MapParquetFile opens the Parquet file at path like OpenParquetFile, but
+// reads it from a memory mapping through small buffered streams, so that
+// column chunks are paged in from disk as they are decoded instead of each
+// being copied whole onto the heap. Only the decoded records are allocated
+// from mem. It fails where mmap.Supported is false.
+func MapParquetFile(path string, columns []string, mem memory.Allocator) (*ParquetReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	data, unmap, err := mmap.Map(f)
+	f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	props := parquet.NewReaderProperties(nil)
+	props.BufferedStreamEnabled = true
+	pf, err := file.NewParquetReader(bytes.NewReader(data), file.WithReadProps(props))
+	if err != nil {
+		unmap()
+		return nil, fmt.Errorf("open parquet: %w", err)
+	}
+	pr, err := newReader(pf, columns, mem)
+	if err != nil {
+		unmap()
+		return nil, err
+	}
+	pr.unmap = unmap
+	return pr, nil
+}
+
+func newReader(pf *file.Reader, columns []string, mem memory.Allocator) (*ParquetReader, error) {
+	allocator := mem
+	if allocator == nil {
+		allocator = memory.NewGoAllocator()
+	}
+	fr, err := pqarrow.NewFileReader(pf, pqarrow.ArrowReadProperties{BatchSize: DefaultBatchSize}, allocator)
+	if err != nil {
+		pf.Close()
+		return nil, fmt.Errorf("arrow reader: %w", err)
+	}
+	full, err := fr.Schema()
+	if err != nil {
+		pf.Close()
+		return nil, fmt.Errorf("arrow schema: %w", err)
+	}
+
+	pr := &ParquetReader{allocator: allocator, file: pf, reader: fr, full: full, schema: full}
+	if len(columns) == 0 {
+		return pr, nil
+	}
+	fields, leaves, err := pr.project(columns)
+	if err != nil {
+		pf.Close()
+		return nil, err
+	}
+	pr.schema, pr.columns = arrow.NewSchema(fields, nil), leaves
+	return pr, nil
+}
+
+// project resolves top-level column names to their fields and the Parquet
+// leaf columns that must be decoded for them.
+func (pr *ParquetReader) project(columns []string) ([]arrow.Field, []int, error) {
+	fields := make([]arrow.Field, 0, len(columns))
+	var leaves []int
+	for _, name := range columns {
+		idx := pr.full.FieldIndices(name)
+		if len(idx) == 0 {
+			return nil, nil, fmt.Errorf("column %s not found", name)
+		}
+		fields = append(fields, pr.full.Field(idx[0]))
+		leaves = append(leaves, leafIndices(pr.reader, idx[0])...)
+	}
+	return fields, leaves, nil
+}
+
+// leafIndices returns the Parquet leaf column indices backing a top-level field.
+func leafIndices(fr *pqarrow.FileReader, field int) []int {
+	var out []int
+	var walk func(f pqarrow.SchemaField)
+	walk = func(f pqarrow.SchemaField) {
+		if f.IsLeaf() {
+			out = append(out, f.ColIndex)
+			return
+		}
+		for _, child := range f.Children {
+			walk(child)
+		}
+	}
+	walk(fr.Manifest.Fields[field])
+	return out
+}
+
+// Schema returns the schema of the projected columns.
+func (pr *ParquetReader) Schema() *arrow.Schema {
+	return pr.schema
+}
+
+// NumRows returns the total number of rows in the file.
+func (pr *ParquetReader) NumRows() int64 {
+	return pr.file.NumRows()
+}
+
+// Close releases the underlying file and its mapping, if any.
+func (pr *ParquetReader) Close() error {
+	err := pr.file.Close()
+	if pr.unmap != nil {
+		if uerr := pr.unmap(); err == nil {
+			err = uerr
+		}
+	}
+	return err
+}
+
+// Chan returns a channel of records; caller must Release each.
+func (pr *ParquetReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	return pr.records(ctx, pr.columns)
+}
+
+// records streams batches decoding only the given leaf columns.
+func (pr *ParquetReader) records(ctx context.Context, leaves []int) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		rr, err := pr.reader.GetRecordReader(ctx, leaves, nil)
+		if err != nil {
+			errs <- fmt.Errorf("parquet read error: %w", err)
+			return
+		}
+		defer rr.Release()
+		for rr.Next() {
+			rec := rr.Record()
+			rec.Retain()
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+		// The record reader reports io.EOF once it is exhausted.
+		if err := rr.Err(); err != nil && !errors.Is(err, io.EOF) {
+			errs <- fmt.Errorf("parquet read error: %w", err)
+		}
+	}()
+	return recs, errs
+}
+
+// ReadSingleColumn concatenates all batches for a named column.
+func (pr *ParquetReader) ReadSingleColumn(ctx context.Context, columnName string) (arrow.Array, error) {
+	rec, err := pr.ReadColumns(ctx, []string{columnName})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+// ReadChunked returns the named column with one chunk per batch, without
+// concatenating them. Only that column is decoded from the file.
+func (pr *ParquetReader) ReadChunked(ctx context.Context, columnName string) (*arrow.Chunked, error) {
+	_, leaves, err := pr.project([]string{columnName})
+	if err != nil {
+		return nil, err
+	}
+	recs, errs := pr.records(ctx, leaves)
+	return collect.Chunked(ctx, recs, errs, columnName)
+}
+
+// ReadColumns returns a single record holding the named columns, each
+// concatenated across batches. Only those columns are decoded from the file.
+// A nil columns slice keeps every projected column.
+func (pr *ParquetReader) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range pr.schema.Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	_, leaves, err := pr.project(columns)
+	if err != nil {
+		return nil, err
+	}
+
+	recs, errs := pr.records(ctx, leaves)
+	return collect.Columns(ctx, recs, errs, columns, pr.allocator)
+}
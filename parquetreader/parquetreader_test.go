@@ -0,0 +1,197 @@
+package parquetreader
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	"github.com/TFMV/supercharged/internal/mmap"
+	"github.com/TFMV/supercharged/parquetwriter"
+)
+
+// writeFile writes three rows, with a null in every column, to a Parquet
+// file in a temporary directory and returns its path.
+func writeFile(t *testing.T, mem memory.Allocator) string {
+	t.Helper()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Millisecond}, Nullable: true},
+		{Name: "v", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		{Name: "s", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1709296200000, 0, 1709296260000}, []bool{true, false, true})
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{1.5, 0, -2}, []bool{true, false, true})
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"a", "b", ""}, []bool{true, true, false})
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	path := filepath.Join(t.TempDir(), "in.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := parquetwriter.NewParquetWriter(f, schema, mem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// checkRecord checks that rec holds the rows written by writeFile.
+func checkRecord(t *testing.T, rec arrow.Record) {
+	t.Helper()
+	if rec.NumRows() != 3 || rec.NumCols() != 3 {
+		t.Fatalf("got %d rows and %d columns, want 3 and 3", rec.NumRows(), rec.NumCols())
+	}
+	// Naive timestamps are written as UTC, and read back so.
+	if got, want := rec.Schema().Field(0).Type.String(), "timestamp[ms, tz=UTC]"; got != want {
+		t.Errorf("ts type = %s, want %s", got, want)
+	}
+	ts := rec.Column(0).(*array.Timestamp)
+	if ts.Value(0) != 1709296200000 || ts.IsValid(1) || ts.Value(2) != 1709296260000 {
+		t.Errorf("ts = %v", ts)
+	}
+	v := rec.Column(1).(*array.Float64)
+	if v.Value(0) != 1.5 || v.IsValid(1) || v.Value(2) != -2 {
+		t.Errorf("v = %v", v)
+	}
+	s := rec.Column(2).(*array.String)
+	if s.Value(0) != "a" || s.Value(1) != "b" || s.IsValid(2) {
+		t.Errorf("s = %v", s)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := context.Background()
+	path := writeFile(t, pool)
+
+	pr, err := OpenParquetFile(path, nil, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	if pr.NumRows() != 3 {
+		t.Errorf("NumRows = %d, want 3", pr.NumRows())
+	}
+	rec, err := pr.ReadColumns(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	checkRecord(t, rec)
+
+	col, err := pr.ReadChunked(ctx, "v")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer col.Release()
+	if col.Len() != 3 || col.NullN() != 1 {
+		t.Errorf("chunked v has %d rows and %d nulls, want 3 and 1", col.Len(), col.NullN())
+	}
+}
+
+func TestMapParquetFile(t *testing.T) {
+	if !mmap.Supported {
+		t.Skip("mmap not supported")
+	}
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	path := writeFile(t, pool)
+
+	pr, err := MapParquetFile(path, nil, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	rec, err := pr.ReadColumns(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	checkRecord(t, rec)
+}
+
+func TestProjection(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := context.Background()
+	path := writeFile(t, pool)
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pr, err := NewParquetReader(f, []string{"s", "v"}, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	if s := pr.Schema(); s.NumFields() != 2 || s.Field(0).Name != "s" || s.Field(1).Name != "v" {
+		t.Errorf("schema = %v, want s and v", s)
+	}
+
+	recs, errs := pr.Chan(ctx)
+	rows := int64(0)
+	for rec := range recs {
+		if rec.NumCols() != 2 {
+			t.Errorf("batch has %d columns, want 2", rec.NumCols())
+		}
+		rows += rec.NumRows()
+		rec.Release()
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if rows != 3 {
+		t.Errorf("got %d rows, want 3", rows)
+	}
+
+	col, err := pr.ReadSingleColumn(ctx, "s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer col.Release()
+	if col.Len() != 3 || col.NullN() != 1 {
+		t.Errorf("s = %v", col)
+	}
+}
+
+func TestMissingColumn(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := context.Background()
+	path := writeFile(t, pool)
+
+	if _, err := OpenParquetFile(path, []string{"w"}, pool); err == nil {
+		t.Error("open with missing column: want error")
+	}
+	pr, err := OpenParquetFile(path, nil, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	if _, err := pr.ReadColumns(ctx, []string{"v", "w"}); err == nil {
+		t.Error("ReadColumns: want error")
+	}
+	if _, err := pr.ReadChunked(ctx, "w"); err == nil {
+		t.Error("ReadChunked: want error")
+	}
+	if _, err := OpenParquetFile(filepath.Join(t.TempDir(), "none.parquet"), nil, pool); err == nil {
+		t.Error("missing file: want error")
+	}
+}
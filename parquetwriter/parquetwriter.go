@@ -0,0 +1,100 @@
+// Package parquetwriter writes Arrow records, such as annotated anomaly
+// results, as Parquet.
+package parquetwriter
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/apache/arrow-go/v18/parquet"
+	"github.com/apache/arrow-go/v18/parquet/compress"
+	"github.com/apache/arrow-go/v18/parquet/pqarrow"
+)
+
+// ParquetWriter writes records of a fixed schema to a Parquet file, one row
+// group per record.
+type ParquetWriter struct {
+	writer *pqarrow.FileWriter
+	// zoned is the schema written, with timestamps without a time zone
+	// marked as UTC, or nil when there are none.
+	zoned *arrow.Schema
+}
+
+// NewParquetWriter creates a ParquetWriter for records of schema, compressed
+// with Snappy and storing the Arrow schema so readers recover the exact
+// types. Timestamps without a time zone are written as UTC, as they are read
+// everywhere else, so that readers see instants rather than wall-clock
+// times. Buffers are allocated from mem; nil means a new Go allocator.
+func NewParquetWriter(w io.Writer, schema *arrow.Schema, mem memory.Allocator) (*ParquetWriter, error) {
+	allocator := mem
+	if allocator == nil {
+		allocator = memory.NewGoAllocator()
+	}
+	props := parquet.NewWriterProperties(
+		parquet.WithAllocator(allocator),
+		parquet.WithCompression(compress.Codecs.Snappy),
+	)
+	arrowProps := pqarrow.NewArrowWriterProperties(
+		pqarrow.WithAllocator(allocator),
+		pqarrow.WithStoreSchema(),
+	)
+	pw := &ParquetWriter{}
+	fields := schema.Fields()
+	for i, f := range fields {
+		if dt, ok := f.Type.(*arrow.TimestampType); ok && dt.TimeZone == "" {
+			fields[i].Type = &arrow.TimestampType{Unit: dt.Unit, TimeZone: "UTC"}
+			md := schema.Metadata()
+			pw.zoned = arrow.NewSchema(fields, &md)
+		}
+	}
+	if pw.zoned != nil {
+		schema = pw.zoned
+	}
+	// Hide any Close method so that finishing the file leaves w open.
+	fw, err := pqarrow.NewFileWriter(schema, struct{ io.Writer }{w}, props, arrowProps)
+	if err != nil {
+		return nil, fmt.Errorf("parquet writer: %w", err)
+	}
+	pw.writer = fw
+	return pw, nil
+}
+
+// Write writes the rows of rec, which must match the writer's schema.
+func (pw *ParquetWriter) Write(rec arrow.Record) error {
+	if pw.zoned != nil {
+		rec = pw.zone(rec)
+		defer rec.Release()
+	}
+	if err := pw.writer.Write(rec); err != nil {
+		return fmt.Errorf("parquet write error: %w", err)
+	}
+	return nil
+}
+
+// zone returns rec with the types of the written schema, sharing its
+// buffers.
+func (pw *ParquetWriter) zone(rec arrow.Record) arrow.Record {
+	cols := make([]arrow.Array, rec.NumCols())
+	for i, col := range rec.Columns() {
+		data := col.Data()
+		zoned := array.NewData(pw.zoned.Field(i).Type, data.Len(), data.Buffers(), data.Children(), data.NullN(), data.Offset())
+		cols[i] = array.MakeFromData(zoned)
+		zoned.Release()
+	}
+	out := array.NewRecord(pw.zoned, cols, rec.NumRows())
+	for _, c := range cols {
+		c.Release()
+	}
+	return out
+}
+
+// Close writes the file footer. It does not close the underlying writer.
+func (pw *ParquetWriter) Close() error {
+	if err := pw.writer.Close(); err != nil {
+		return fmt.Errorf("parquet write error: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// DefaultPatternShare is the pattern share below which StringDetector flags
+// a value when WithPatternShare is not given.
+const DefaultPatternShare = 0.01
+
+// StringDetector flags malformed values in a string column, such as IDs or
+// email addresses, by their shape rather than their content. A value is
+// flagged when the robust z-score of its length, (len - median) /
+// (DefaultMADScale * MAD), is at least Threshold in absolute value, or when
+// its character-class pattern accounts for less than MinPatternShare of the
+// non-null values. Either test is disabled by a zero value. A MAD below one
+// character counts as one, so in a column of fixed-length IDs a value a
+// few characters off stands out while one character of drift does not.
+//
+// The pattern maps upper-case letters to A, other letters to a, digits to 9
+// and white space to a space, keeps punctuation, and collapses runs of the
+// same class: "jane.doe@example.com" becomes "a.a@a.a".
+//
+// The Result's Zscore holds the length scores and Values the lengths (in
+// characters) of the flagged values; use FilterOriginal for the strings.
+type StringDetector struct {
+	Threshold       float64
+	MinPatternShare float64
+}
+
+var _ Detector = StringDetector{}
+
+// Detect implements Detector.
+func (d StringDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if d.Threshold <= 0 && d.MinPatternShare <= 0 {
+		return nil, fmt.Errorf("string detection needs a positive threshold or pattern share")
+	}
+	value, err := stringValues(col)
+	if err != nil {
+		return nil, err
+	}
+
+	mem := compute.GetAllocator(ctx)
+	lb := array.NewFloat64Builder(mem)
+	defer lb.Release()
+	patterns := make([]string, col.Len())
+	shares := make(map[string]int64)
+	var lengths []float64
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			lb.AppendNull()
+			continue
+		}
+		s := value(i)
+		n := float64(len([]rune(s)))
+		lb.Append(n)
+		lengths = append(lengths, n)
+		patterns[i] = stringPattern(s)
+		shares[patterns[i]]++
+	}
+	lengthCol := lb.NewFloat64Array()
+	defer lengthCol.Release()
+
+	median, mad := medianAndMAD(append([]float64(nil), lengths...))
+	// Lengths are whole characters; a zero MAD counts as one.
+	scale := DefaultMADScale * math.Max(mad, 1)
+
+	sb := array.NewFloat64Builder(mem)
+	defer sb.Release()
+	mb := array.NewBooleanBuilder(mem)
+	defer mb.Release()
+	total := float64(len(lengths))
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			sb.AppendNull()
+			mb.AppendNull()
+			continue
+		}
+		z := (lengthCol.Value(i) - median) / scale
+		sb.Append(z)
+		rare := d.MinPatternShare > 0 && float64(shares[patterns[i]])/total < d.MinPatternShare
+		mb.Append(rare || (d.Threshold > 0 && math.Abs(z) >= d.Threshold))
+	}
+	return newResult(ctx, lengthCol, mb.NewBooleanArray(), sb.NewFloat64Array(), accumulate(lengthCol))
+}
+
+// stringValues returns an accessor for the string values of col.
+func stringValues(col arrow.Array) (func(int) string, error) {
+	switch c := col.(type) {
+	case *array.String:
+		return c.Value, nil
+	case *array.LargeString:
+		return c.Value, nil
+	case *array.StringView:
+		return c.Value, nil
+	case *array.Dictionary:
+		return c.ValueStr, nil
+	}
+	return nil, fmt.Errorf("string detection needs a string column, got %s", col.DataType())
+}
+
+// stringPattern returns the character-class pattern of s.
+func stringPattern(s string) string {
+	var b strings.Builder
+	var last rune
+	for _, r := range s {
+		switch {
+		case unicode.IsUpper(r):
+			r = 'A'
+		case unicode.IsLetter(r):
+			r = 'a'
+		case unicode.IsDigit(r):
+			r = '9'
+		case unicode.IsSpace(r):
+			r = ' '
+		}
+		if r != last || !(r == 'A' || r == 'a' || r == '9' || r == ' ') {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestStringDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewStringBuilder(pool)
+	defer b.Release()
+	for i := 0; i < 200; i++ {
+		b.Append(fmt.Sprintf("user%03d@example.com", i))
+	}
+	b.Append("not-an-email")                  // 200: rare pattern
+	b.Append("user999@example.com.evil.test") // 201: odd length and pattern
+	b.AppendNull()
+	arr := b.NewStringArray()
+	defer arr.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	res, err := DetectAnomalies(ctx, arr, WithMethod(MethodPattern), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if got := res.AnomalousIndices(); len(got) != 2 || got[0] != 200 || got[1] != 201 {
+		t.Errorf("indices = %v, want [200 201]", got)
+	}
+
+	// Length alone: the pattern of row 200 is tolerated, its length is not.
+	res2, err := DetectAnomalies(ctx, arr, WithMethod(MethodPattern), WithPatternShare(0), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res2.Release()
+	if got := res2.AnomalousIndices(); len(got) != 2 || res2.Values.Value(1) != 29 {
+		t.Errorf("length-only indices = %v, values = %v", got, res2.Values)
+	}
+
+	if got := stringPattern("Jane.Doe42@example.com"); got != "Aa.Aa9@a.a" {
+		t.Errorf("stringPattern = %q", got)
+	}
+}
@@ -0,0 +1,138 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// DefaultPercentile is the upper quantile used when a percentile detector's
+// Q is zero: the top and bottom 0.1% are flagged.
+const DefaultPercentile = 0.999
+
+// PercentileDetector flags values at or above the Q-th quantile or at or
+// below the (1-Q)-th, so the threshold is a fraction of the data rather than
+// a number of standard deviations. Scores are distances from the midpoint of
+// the two quantiles in units of half their spread, so a value is flagged
+// when its absolute score is at least 1.
+type PercentileDetector struct {
+	// Q is the upper quantile, in (0.5, 1); zero means DefaultPercentile.
+	Q float64
+	// Approximate estimates the quantiles with a t-digest instead of sorting.
+	Approximate bool
+	// Compression configures the t-digest when Approximate is set.
+	Compression float64
+}
+
+var _ Detector = PercentileDetector{}
+
+// Detect implements Detector.
+func (d PercentileDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if _, err := upperQuantile(d.Q); err != nil {
+		return nil, err
+	}
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+
+	f, err := d.fit(ctx, []*array.Float64{floatCol})
+	if err != nil {
+		return nil, err
+	}
+	return scoreAgainst(ctx, floatCol, f.center, f.scale, f.threshold, f.stats)
+}
+
+func (d PercentileDetector) fit(ctx context.Context, cols []*array.Float64) (fitted, error) {
+	q, err := upperQuantile(d.Q)
+	if err != nil {
+		return fitted{}, err
+	}
+	lo, hi := quantilePair(cols, 1-q, q, d.Approximate, d.Compression)
+	return fitted{center: (lo + hi) / 2, scale: (hi - lo) / 2, threshold: 1, stats: accumulateAll(ctx, cols)}, nil
+}
+
+// StreamingPercentileDetector is the streaming counterpart of
+// PercentileDetector: it folds each batch into a t-digest and scores the
+// batch against the quantiles of everything seen so far, in memory bounded
+// by the digest's compression.
+type StreamingPercentileDetector struct {
+	Column string
+	Q      float64
+
+	digest *TDigest
+	stats  runningStats
+}
+
+// NewStreamingPercentileDetector returns a StreamingPercentileDetector for
+// the named column.
+func NewStreamingPercentileDetector(column string, q float64) *StreamingPercentileDetector {
+	return &StreamingPercentileDetector{Column: column, Q: q, digest: NewTDigest(DefaultCompression)}
+}
+
+// Update folds the batch into the digest and returns a Result for the batch's
+// rows. While the quantiles seen so far coincide, the rows are left unscored
+// and unflagged. The caller must Release the Result.
+func (d *StreamingPercentileDetector) Update(ctx context.Context, rec arrow.Record) (*Result, error) {
+	q, err := upperQuantile(d.Q)
+	if err != nil {
+		return nil, err
+	}
+	idx := rec.Schema().FieldIndices(d.Column)
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("column %s not found", d.Column)
+	}
+	floatCol, err := toFloat64(ctx, rec.Column(idx[0]))
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsValid(i) {
+			d.digest.Add(floatCol.Value(i))
+		}
+	}
+	d.stats.merge(accumulate(floatCol))
+	lo, hi := d.digest.Quantile(1-q), d.digest.Quantile(q)
+	if hi == lo {
+		return unscored(ctx, floatCol, d.stats)
+	}
+	return scoreAgainst(ctx, floatCol, (lo+hi)/2, (hi-lo)/2, 1, d.stats)
+}
+
+// Count returns the number of non-null values seen so far.
+func (d *StreamingPercentileDetector) Count() int64 { return d.digest.Count() }
+
+// State returns a snapshot of the digest and running statistics.
+func (d *StreamingPercentileDetector) State() StreamState {
+	return StreamState{Count: d.stats.n, Mean: d.stats.mean, M2: d.stats.m2, Digest: d.digest.clone()}
+}
+
+// Restore replaces the digest and running statistics with a snapshot taken
+// by State.
+func (d *StreamingPercentileDetector) Restore(s StreamState) error {
+	if s.Digest == nil {
+		return fmt.Errorf("invalid stream state: no quantile digest")
+	}
+	if s.Count < 0 || s.M2 < 0 {
+		return fmt.Errorf("invalid stream state: count %d, M2 %v", s.Count, s.M2)
+	}
+	d.digest = s.Digest.clone()
+	d.stats = runningStats{n: s.Count, mean: s.Mean, m2: s.M2}
+	return nil
+}
+
+// upperQuantile validates q, substituting DefaultPercentile for zero.
+func upperQuantile(q float64) (float64, error) {
+	if q == 0 {
+		return DefaultPercentile, nil
+	}
+	if q <= 0.5 || q >= 1 {
+		return 0, fmt.Errorf("quantile must be in (0.5, 1), got %v", q)
+	}
+	return q, nil
+}
@@ -0,0 +1,136 @@
+package supercharged
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestPercentileDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	for i := 0; i < 1000; i++ {
+		b.Append(float64(i))
+	}
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	for _, approx := range []bool{false, true} {
+		res, err := PercentileDetector{Q: 0.99, Approximate: approx}.Detect(ctx, col)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The top and bottom 1% of 0..999, give or take the boundary rows.
+		if n := res.Indices.Len(); n < 18 || n > 22 {
+			t.Errorf("approx=%t: flagged %d values, want about 20", approx, n)
+		}
+		if !res.Mask.Value(0) || !res.Mask.Value(999) || res.Mask.Value(500) {
+			t.Errorf("approx=%t: expected both tails flagged and the middle not", approx)
+		}
+		res.Release()
+	}
+
+	if _, err := (PercentileDetector{Q: 0.3}).Detect(ctx, col); err == nil {
+		t.Errorf("expected error for quantile below 0.5")
+	}
+}
+
+func TestStreamingPercentileDetector(t *testing.T) {
+	d := NewStreamingPercentileDetector("v", 0.99)
+	var flagged int
+	for batch := 0; batch < 10; batch++ {
+		vals := make([]float64, 100)
+		for i := range vals {
+			vals[i] = float64((i*37 + batch) % 100)
+		}
+		if batch == 9 {
+			vals[50] = 1000
+		}
+		rec := float64Record(t, "v", vals)
+		res, err := d.Update(context.Background(), rec)
+		rec.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if batch == 9 && !res.Mask.Value(50) {
+			t.Errorf("expected the spike in the last batch to be flagged")
+		}
+		flagged += res.Indices.Len()
+		res.Release()
+	}
+	if d.Count() != 1000 {
+		t.Errorf("count = %d, want 1000", d.Count())
+	}
+	if flagged > 100 {
+		t.Errorf("flagged %d of 1000 values at q=0.99", flagged)
+	}
+}
+
+func TestStreamingPercentileDetectorConstantStart(t *testing.T) {
+	d := NewStreamingPercentileDetector("v", 0.99)
+	for _, vals := range [][]float64{{5}, {5, 5, 5}} {
+		rec := float64Record(t, "v", vals)
+		res, err := d.Update(context.Background(), rec)
+		rec.Release()
+		if err != nil {
+			t.Fatalf("%v: %v", vals, err)
+		}
+		if res.Zscore.NullN() != len(vals) || res.Indices.Len() != 0 {
+			t.Errorf("%v: got %d null scores and %d flagged, want all null and none flagged", vals, res.Zscore.NullN(), res.Indices.Len())
+		}
+		res.Release()
+	}
+}
+
+func TestStreamingPercentileDetectorRestore(t *testing.T) {
+	first := NewStreamingPercentileDetector("v", 0.99)
+	vals := make([]float64, 500)
+	for i := range vals {
+		vals[i] = float64(i * 37 % 100)
+	}
+	rec := float64Record(t, "v", vals)
+	res, err := first.Update(context.Background(), rec)
+	rec.Release()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Release()
+
+	// The state survives a JSON round trip, as in a checkpoint file.
+	data, err := json.Marshal(first.State())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state StreamState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	resumed := NewStreamingPercentileDetector("v", 0.99)
+	if err := resumed.Restore(state); err != nil {
+		t.Fatal(err)
+	}
+	if resumed.Count() != 500 {
+		t.Errorf("count = %d, want 500", resumed.Count())
+	}
+	rec = float64Record(t, "v", []float64{50, 1000})
+	defer rec.Release()
+	res, err = resumed.Update(context.Background(), rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if res.Mask.Value(0) || !res.Mask.Value(1) {
+		t.Errorf("flags after restore = %v, %v; want false, true", res.Mask.Value(0), res.Mask.Value(1))
+	}
+	if err := resumed.Restore(StreamState{Count: 1}); err == nil {
+		t.Error("expected an error for a state without a digest")
+	}
+}
@@ -0,0 +1,118 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// Pipeline is detection configured once and applied to many arrays or
+// records: NewPipeline validates its options, builds the Detector and takes
+// the Filter and Expr already parsed, so each call only scores. It suits hot
+// loops such as serving requests, where DetectAnomalies would redo all of
+// that per call. A Pipeline is safe for concurrent use.
+type Pipeline struct {
+	o        *options
+	detector Detector
+	filter   *Filter
+	expr     *Expr
+}
+
+// WithFilter makes Pipeline.DetectRecord score only the rows of each record
+// for which f holds. DetectAnomalies ignores it.
+func WithFilter(f *Filter) Option {
+	return func(o *options) { o.filter = f }
+}
+
+// WithExpr makes Pipeline.DetectRecord score e evaluated over each record
+// instead of a column. DetectAnomalies ignores it.
+func WithExpr(e *Expr) Option {
+	return func(o *options) { o.expr = e }
+}
+
+// NewPipeline builds the Pipeline described by opts, which take effect as
+// they do for DetectAnomalies.
+func NewPipeline(opts ...Option) (*Pipeline, error) {
+	o := newOptions(opts)
+	d, err := o.detector()
+	if err != nil {
+		return nil, err
+	}
+	return &Pipeline{o: o, detector: d, filter: o.filter, expr: o.expr}, nil
+}
+
+// Detector returns the Detector the Pipeline scores with.
+func (p *Pipeline) Detector() Detector {
+	return p.detector
+}
+
+// Detect scores col as DetectAnomalies does.
+func (p *Pipeline) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	return p.detect(p.o.context(ctx), col)
+}
+
+// detect is Detect under a ctx already carrying the Pipeline's settings.
+func (p *Pipeline) detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	ctx, end := traceDetect(ctx, "Pipeline.Detect", p.detector, col.Len())
+	res, err := detectArray(ctx, p.detector, col, p.o.parallelism)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	end(res.Indices.Len(), nil)
+	return res, nil
+}
+
+// DetectChunked scores col as DetectAnomaliesChunked does.
+func (p *Pipeline) DetectChunked(ctx context.Context, col *arrow.Chunked) (*ChunkedResult, error) {
+	return DetectChunked(p.o.context(ctx), p.detector, col)
+}
+
+// DetectRecord scores the named column of rec, or the WithExpr expression
+// when column is empty, over the rows WithFilter keeps. It returns those
+// rows, to which the Result's indices refer, along with the Result; without
+// a filter they are rec itself, retained. The caller must Release both.
+func (p *Pipeline) DetectRecord(ctx context.Context, rec arrow.Record, column string) (arrow.Record, *Result, error) {
+	if (column == "") == (p.expr == nil) {
+		return nil, nil, fmt.Errorf("want either a column or an expression to score")
+	}
+	ctx = p.o.context(ctx)
+	rows := rec
+	if p.filter != nil {
+		kept, err := p.filter.Apply(ctx, rec)
+		if err != nil {
+			return nil, nil, err
+		}
+		rows = kept
+	} else {
+		rec.Retain()
+	}
+	col, err := p.target(ctx, rows, column)
+	if err != nil {
+		rows.Release()
+		return nil, nil, err
+	}
+	defer col.Release()
+	res, err := p.detect(ctx, col)
+	if err != nil {
+		rows.Release()
+		return nil, nil, err
+	}
+	return rows, res, nil
+}
+
+// target returns the column of rec to score: the named one, or the
+// expression evaluated over rec.
+func (p *Pipeline) target(ctx context.Context, rec arrow.Record, column string) (arrow.Array, error) {
+	if p.expr != nil {
+		return p.expr.Eval(ctx, rec)
+	}
+	idx := rec.Schema().FieldIndices(column)
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("column %s not found", column)
+	}
+	col := rec.Column(idx[0])
+	col.Retain()
+	return col, nil
+}
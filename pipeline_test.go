@@ -0,0 +1,132 @@
+package supercharged
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestPipelineDetect(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]float64{10, 11, 9, 10, 12, 10, 9, 11, 60, 10, 11, 9}, nil)
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	opts := []Option{WithMethod(MethodMAD), WithThreshold(3.5), WithAllocator(pool)}
+	want, err := DetectAnomalies(context.Background(), col, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer want.Release()
+
+	p, err := NewPipeline(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := p.Detect(context.Background(), col)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer got.Release()
+			if !slices.Equal(got.AnomalousIndices(), want.AnomalousIndices()) {
+				t.Errorf("indices = %v, want %v", got.AnomalousIndices(), want.AnomalousIndices())
+			}
+		}()
+	}
+	wg.Wait()
+
+	if _, err := NewPipeline(WithMethod("nope")); err == nil {
+		t.Error("unknown method accepted")
+	}
+}
+
+func TestPipelineDetectRecord(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "region", Type: arrow.BinaryTypes.String},
+		{Name: "in", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "out", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	for i := 0; i < 20; i++ {
+		region, out := "us", 10.0+float64(i%3)
+		if i%2 == 1 {
+			// The other region's ratios are far off, but filtered out.
+			region, out = "eu", 1000
+		}
+		if i == 10 {
+			out = 100
+		}
+		b.Field(0).(*array.StringBuilder).Append(region)
+		b.Field(1).(*array.Float64Builder).Append(10)
+		b.Field(2).(*array.Float64Builder).Append(out)
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	filter, err := ParseFilter("region == 'us'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expr, err := ParseExpr(`out / "in"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPipeline(WithThreshold(2.5), WithFilter(filter), WithExpr(expr), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, res, err := p.DetectRecord(context.Background(), rec, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Release()
+	defer res.Release()
+	if rows.NumRows() != 10 {
+		t.Fatalf("scored %d rows, want 10", rows.NumRows())
+	}
+	if got := res.AnomalousIndices(); !slices.Equal(got, []int64{5}) {
+		t.Errorf("indices = %v, want [5]", got)
+	}
+
+	if _, _, err := p.DetectRecord(context.Background(), rec, "out"); err == nil {
+		t.Error("column accepted alongside an expression")
+	}
+	plain, err := NewPipeline(WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := plain.DetectRecord(context.Background(), rec, ""); err == nil {
+		t.Error("no column or expression accepted")
+	}
+	if _, _, err := plain.DetectRecord(context.Background(), rec, "missing"); err == nil {
+		t.Error("missing column accepted")
+	}
+	rows, res, err = plain.DetectRecord(context.Background(), rec, "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows.NumRows() != rec.NumRows() {
+		t.Errorf("scored %d rows, want %d", rows.NumRows(), rec.NumRows())
+	}
+	rows.Release()
+	res.Release()
+}
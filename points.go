@@ -0,0 +1,76 @@
+package supercharged
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// Point is a flagged row placed in time: when it occurred, its value and its
+// score.
+type Point struct {
+	Row   int64     `json:"row"`
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+	Score float64   `json:"score"`
+}
+
+// timeLayouts are the string forms Points parses, most specific first.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"}
+
+// Points returns the flagged rows, in order, with their time from timeCol.
+// timeCol must be aligned with the analyzed array and hold timestamps, dates
+// or strings in RFC 3339, "2006-01-02 15:04:05" or "2006-01-02" form; strings
+// without a zone are taken as UTC. Rows with a null time get the zero Time.
+func (r *Result) Points(timeCol arrow.Array) ([]Point, error) {
+	if timeCol.Len() != r.Mask.Len() {
+		return nil, fmt.Errorf("time column has %d rows, result has %d", timeCol.Len(), r.Mask.Len())
+	}
+	points := make([]Point, r.Indices.Len())
+	for i := range points {
+		row := r.Indices.Value(i)
+		t, err := timeAt(timeCol, int(row))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		points[i] = Point{Row: row, Time: t, Value: r.Values.Value(i), Score: r.Zscore.Value(int(row))}
+	}
+	return points, nil
+}
+
+// timeAt returns the time held at row i of col.
+func timeAt(col arrow.Array, i int) (time.Time, error) {
+	if col.IsNull(i) {
+		return time.Time{}, nil
+	}
+	switch c := col.(type) {
+	case *array.Timestamp:
+		unit := c.DataType().(*arrow.TimestampType).Unit
+		return c.Value(i).ToTime(unit), nil
+	case *array.Date32:
+		return c.Value(i).ToTime(), nil
+	case *array.Date64:
+		return c.Value(i).ToTime(), nil
+	case *array.String:
+		return parseTime(c.Value(i))
+	case *array.LargeString:
+		return parseTime(c.Value(i))
+	}
+	return time.Time{}, fmt.Errorf("unsupported time column type %s", col.DataType())
+}
+
+func parseTime(s string) (time.Time, error) {
+	return parseTimeIn(s, time.UTC)
+}
+
+// parseTimeIn is parseTime with strings without a zone taken in loc.
+func parseTimeIn(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse time %q", s)
+}
@@ -0,0 +1,71 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestResultPoints(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	vb := array.NewFloat64Builder(pool)
+	defer vb.Release()
+	tb := array.NewStringBuilder(pool)
+	defer tb.Release()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 20; i++ {
+		v := float64(10 + i%3)
+		if i == 12 {
+			v = 100
+		}
+		vb.Append(v)
+		tb.Append(start.Add(time.Duration(i) * time.Minute).Format("2006-01-02 15:04:05"))
+	}
+	vals := vb.NewFloat64Array()
+	defer vals.Release()
+	times := tb.NewStringArray()
+	defer times.Release()
+
+	res, err := DetectAnomalies(context.Background(), vals)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	points, err := res.Points(times)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("points = %v, want one", points)
+	}
+	p := points[0]
+	if p.Row != 12 || p.Value != 100 || !p.Time.Equal(start.Add(12*time.Minute)) || p.Score < 3 {
+		t.Errorf("point = %+v", p)
+	}
+
+	ts := array.NewTimestampBuilder(pool, &arrow.TimestampType{Unit: arrow.Second})
+	defer ts.Release()
+	for i := 0; i < 20; i++ {
+		ts.Append(arrow.Timestamp(start.Add(time.Duration(i) * time.Minute).Unix()))
+	}
+	tsArr := ts.NewTimestampArray()
+	defer tsArr.Release()
+	points, err = res.Points(tsArr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !points[0].Time.Equal(start.Add(12 * time.Minute)) {
+		t.Errorf("timestamp point time = %v", points[0].Time)
+	}
+
+	if _, err := res.Points(vals); err == nil {
+		t.Errorf("expected error for a float64 time column")
+	}
+}
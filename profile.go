@@ -0,0 +1,143 @@
+package supercharged
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// ColumnProfile summarizes one column of a dataset.
+type ColumnProfile struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Rows  int64  `json:"rows"`
+	Nulls int64  `json:"nulls"`
+	// Distinct estimates the number of distinct non-null values with a
+	// HyperLogLog sketch, typically within 1-2%.
+	Distinct int64 `json:"distinct"`
+	// Stats describes the values of numeric columns, as Stats does, and is
+	// nil for other types.
+	Stats *Summary `json:"stats,omitempty"`
+}
+
+// Profiler accumulates column profiles over a stream of records sharing a
+// schema, in memory independent of the number of rows.
+type Profiler struct {
+	schema  *arrow.Schema
+	columns []*columnProfiler
+}
+
+type columnProfiler struct {
+	rows, nulls int64
+	distinct    *hyperLogLog
+	numeric     bool
+	stats       runningStats
+	summary     Summary
+}
+
+// NewProfiler returns a Profiler for records of schema.
+func NewProfiler(schema *arrow.Schema) *Profiler {
+	p := &Profiler{schema: schema}
+	for _, f := range schema.Fields() {
+		p.columns = append(p.columns, &columnProfiler{
+			distinct: newHyperLogLog(),
+			numeric:  isNumericType(f.Type),
+			summary:  Summary{Min: math.NaN(), Max: math.NaN()},
+		})
+	}
+	return p
+}
+
+// Add folds rec into the profiles.
+func (p *Profiler) Add(rec arrow.Record) error {
+	if !rec.Schema().Equal(p.schema) {
+		return fmt.Errorf("record schema does not match profiler schema")
+	}
+	for i, c := range p.columns {
+		if err := c.add(rec.Column(i)); err != nil {
+			return fmt.Errorf("profile %s: %w", p.schema.Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+func (c *columnProfiler) add(col arrow.Array) error {
+	c.rows += int64(col.Len())
+	c.nulls += int64(col.NullN())
+	c.addDistinct(col)
+	if !c.numeric {
+		return nil
+	}
+	s, err := Stats(col)
+	if err != nil {
+		return err
+	}
+	c.summary.NaNs += s.NaNs
+	c.summary.Infs += s.Infs
+	if s.Count > 0 {
+		if c.stats.n == 0 || s.Min < c.summary.Min {
+			c.summary.Min = s.Min
+		}
+		if c.stats.n == 0 || s.Max > c.summary.Max {
+			c.summary.Max = s.Max
+		}
+		c.stats.merge(runningStats{n: s.Count, mean: s.Mean, m2: s.Variance * float64(s.Count)})
+	}
+	return nil
+}
+
+// addDistinct hashes the non-null values of col into the sketch.
+func (c *columnProfiler) addDistinct(col arrow.Array) {
+	var add func(i int)
+	switch a := col.(type) {
+	case *array.String:
+		add = func(i int) { c.distinct.addString(a.Value(i)) }
+	case *array.Int64:
+		add = func(i int) { c.distinct.addUint64(uint64(a.Value(i))) }
+	case *array.Float64:
+		add = func(i int) { c.distinct.addUint64(math.Float64bits(a.Value(i))) }
+	default:
+		add = func(i int) { c.distinct.addString(col.ValueStr(i)) }
+	}
+	for i := 0; i < col.Len(); i++ {
+		if col.IsValid(i) {
+			add(i)
+		}
+	}
+}
+
+// Profiles returns the profile of each column so far, in schema order.
+func (p *Profiler) Profiles() []ColumnProfile {
+	out := make([]ColumnProfile, len(p.columns))
+	for i, c := range p.columns {
+		f := p.schema.Field(i)
+		out[i] = ColumnProfile{Name: f.Name, Type: f.Type.String(), Rows: c.rows, Nulls: c.nulls, Distinct: c.distinct.estimate()}
+		if c.numeric {
+			s := c.summary
+			s.Count, s.Nulls = c.stats.n, c.nulls
+			s.Mean, s.Variance, s.StdDev = c.stats.mean, c.stats.variance(), c.stats.stdDev()
+			out[i].Stats = &s
+		}
+	}
+	return out
+}
+
+// ProfileRecord profiles every column of rec.
+func ProfileRecord(rec arrow.Record) ([]ColumnProfile, error) {
+	p := NewProfiler(rec.Schema())
+	if err := p.Add(rec); err != nil {
+		return nil, err
+	}
+	return p.Profiles(), nil
+}
+
+// isNumericType reports whether Stats accepts columns of t, including
+// dictionary-encoded ones.
+func isNumericType(t arrow.DataType) bool {
+	if dt, ok := t.(*arrow.DictionaryType); ok {
+		t = dt.ValueType
+	}
+	return arrow.IsInteger(t.ID()) || arrow.IsFloating(t.ID())
+}
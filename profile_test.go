@@ -0,0 +1,66 @@
+package supercharged
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestProfiler(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "value", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	p := NewProfiler(schema)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	// Two batches: values 1..4, then 5..8 and a null row; hosts h0 and h1.
+	for batch := 0; batch < 2; batch++ {
+		for i := 1; i <= 4; i++ {
+			b.Field(0).(*array.StringBuilder).Append(fmt.Sprint("h", i%2))
+			b.Field(1).(*array.Int64Builder).Append(int64(batch*4 + i))
+		}
+		if batch == 1 {
+			b.Field(0).(*array.StringBuilder).AppendNull()
+			b.Field(1).(*array.Int64Builder).AppendNull()
+		}
+		rec := b.NewRecord()
+		if err := p.Add(rec); err != nil {
+			t.Fatal(err)
+		}
+		rec.Release()
+	}
+
+	profiles := p.Profiles()
+	host, value := profiles[0], profiles[1]
+	if host.Rows != 9 || host.Nulls != 1 || host.Distinct != 2 || host.Stats != nil {
+		t.Errorf("host profile = %+v", host)
+	}
+	if value.Type != "int64" || value.Nulls != 1 || value.Distinct != 8 {
+		t.Errorf("value profile = %+v", value)
+	}
+	s := value.Stats
+	wantStd := math.Sqrt(5.25) // population variance of 1..8
+	if s.Count != 8 || s.Min != 1 || s.Max != 8 || s.Mean != 4.5 || math.Abs(s.StdDev-wantStd) > 1e-12 {
+		t.Errorf("value stats = %+v", *s)
+	}
+}
+
+func TestHyperLogLogEstimate(t *testing.T) {
+	h := newHyperLogLog()
+	const n = 100000
+	for i := 0; i < n; i++ {
+		h.addUint64(uint64(i))
+		h.addUint64(uint64(i)) // duplicates must not count
+	}
+	if got := h.estimate(); math.Abs(float64(got-n))/n > 0.03 {
+		t.Errorf("estimate = %d, want about %d", got, n)
+	}
+}
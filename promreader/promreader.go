@@ -0,0 +1,267 @@
+// Package promreader reads the result of a Prometheus range query as Arrow
+// records.
+package promreader
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TFMV/supercharged/internal/collect"
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// Column names of every record, ahead of one string column per label.
+const (
+	SeriesColumn    = "series"
+	TimestampColumn = "timestamp"
+	ValueColumn     = "value"
+)
+
+// Query is a PromQL range query.
+type Query struct {
+	Expr       string
+	Start, End time.Time
+	Step       time.Duration
+}
+
+// PromReader holds the samples of a range query, one record per series,
+// with the series' label set, a millisecond timestamp and the value, plus a
+// column per label name (other than __name__) for grouping.
+type PromReader struct {
+	allocator memory.Allocator
+	schema    *arrow.Schema
+	records   []arrow.Record
+}
+
+// queryResponse is the body of /api/v1/query_range.
+type queryResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Values [][2]any          `json:"values"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// NewPromReader runs q against the Prometheus server at baseURL (credentials
+// in the URL are sent as basic auth) and decodes the resulting matrix. A nil
+// client means http.DefaultClient. Records are allocated from mem; nil means
+// a new Go allocator.
+func NewPromReader(ctx context.Context, baseURL string, q Query, client *http.Client, mem memory.Allocator) (*PromReader, error) {
+	allocator := mem
+	if allocator == nil {
+		allocator = memory.NewGoAllocator()
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	body, err := queryRange(ctx, client, baseURL, q)
+	if err != nil {
+		return nil, err
+	}
+	if body.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("prometheus query: got %s result, want matrix", body.Data.ResultType)
+	}
+
+	labels := map[string]bool{}
+	for _, s := range body.Data.Result {
+		for name := range s.Metric {
+			if name != "__name__" && name != SeriesColumn && name != TimestampColumn && name != ValueColumn {
+				labels[name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := []arrow.Field{
+		{Name: SeriesColumn, Type: arrow.BinaryTypes.String},
+		{Name: TimestampColumn, Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+		{Name: ValueColumn, Type: arrow.PrimitiveTypes.Float64},
+	}
+	for _, name := range names {
+		fields = append(fields, arrow.Field{Name: name, Type: arrow.BinaryTypes.String, Nullable: true})
+	}
+	pr := &PromReader{allocator: allocator, schema: arrow.NewSchema(fields, nil)}
+
+	for _, s := range body.Data.Result {
+		rec, err := pr.seriesRecord(s.Metric, s.Values, names)
+		if err != nil {
+			pr.Close()
+			return nil, err
+		}
+		pr.records = append(pr.records, rec)
+	}
+	return pr, nil
+}
+
+// queryRange posts q to the range query endpoint and decodes the response.
+func queryRange(ctx context.Context, client *http.Client, baseURL string, q Query) (*queryResponse, error) {
+	form := url.Values{
+		"query": {q.Expr},
+		"start": {strconv.FormatFloat(float64(q.Start.UnixMilli())/1000, 'f', -1, 64)},
+		"end":   {strconv.FormatFloat(float64(q.End.UnixMilli())/1000, 'f', -1, 64)},
+		"step":  {strconv.FormatFloat(q.Step.Seconds(), 'f', -1, 64)},
+	}
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/api/v1/query_range"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("prometheus query: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus query: %w", err)
+	}
+	defer resp.Body.Close()
+	var body queryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("prometheus query: %s: %w", resp.Status, err)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("prometheus query: %s: %s", body.ErrorType, body.Error)
+	}
+	return &body, nil
+}
+
+// seriesRecord builds the record for one series from its [time, "value"]
+// sample pairs.
+func (pr *PromReader) seriesRecord(metric map[string]string, values [][2]any, labels []string) (arrow.Record, error) {
+	b := array.NewRecordBuilder(pr.allocator, pr.schema)
+	defer b.Release()
+	series := seriesName(metric)
+	ts := b.Field(1).(*array.TimestampBuilder)
+	vals := b.Field(2).(*array.Float64Builder)
+	for _, sample := range values {
+		t, ok := sample[0].(float64)
+		str, ok2 := sample[1].(string)
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("prometheus query: malformed sample %v in %s", sample, series)
+		}
+		v, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus query: sample %q in %s: %w", str, series, err)
+		}
+		b.Field(0).(*array.StringBuilder).Append(series)
+		ts.Append(arrow.Timestamp(math.Round(t * 1000)))
+		vals.Append(v)
+		for i, name := range labels {
+			lb := b.Field(3 + i).(*array.StringBuilder)
+			if l, ok := metric[name]; ok {
+				lb.Append(l)
+			} else {
+				lb.AppendNull()
+			}
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+// seriesName formats a label set the way Prometheus does:
+// name{label="value", ...}.
+func seriesName(metric map[string]string) string {
+	names := make([]string, 0, len(metric))
+	for name := range metric {
+		if name != "__name__" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(metric[name])
+	}
+	return metric["__name__"] + "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// Schema returns the schema of the records.
+func (pr *PromReader) Schema() *arrow.Schema {
+	return pr.schema
+}
+
+// Chan returns a channel with one record per series; caller must Release
+// each.
+func (pr *PromReader) Chan(ctx context.Context) (<-chan arrow.Record, <-chan error) {
+	recs := make(chan arrow.Record)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(recs)
+		defer close(errs)
+		for _, rec := range pr.records {
+			rec.Retain()
+			select {
+			case recs <- rec:
+			case <-ctx.Done():
+				rec.Release()
+				return
+			}
+		}
+	}()
+	return recs, errs
+}
+
+// ReadSingleColumn concatenates a named column across series.
+func (pr *PromReader) ReadSingleColumn(ctx context.Context, columnName string) (arrow.Array, error) {
+	rec, err := pr.ReadColumns(ctx, []string{columnName})
+	if err != nil {
+		return nil, err
+	}
+	defer rec.Release()
+	col := rec.Column(0)
+	col.Retain()
+	return col, nil
+}
+
+// ReadChunked returns the named column with one chunk per series.
+func (pr *PromReader) ReadChunked(ctx context.Context, columnName string) (*arrow.Chunked, error) {
+	if len(pr.schema.FieldIndices(columnName)) == 0 {
+		return nil, fmt.Errorf("column %s not found", columnName)
+	}
+	recs, errs := pr.Chan(ctx)
+	return collect.Chunked(ctx, recs, errs, columnName)
+}
+
+// ReadColumns returns a single record holding the named columns, each
+// concatenated across series. A nil columns slice keeps every column.
+func (pr *PromReader) ReadColumns(ctx context.Context, columns []string) (arrow.Record, error) {
+	if columns == nil {
+		for _, f := range pr.schema.Fields() {
+			columns = append(columns, f.Name)
+		}
+	}
+	for _, name := range columns {
+		if len(pr.schema.FieldIndices(name)) == 0 {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+	}
+	if len(pr.records) == 0 {
+		return nil, fmt.Errorf("prometheus query returned no series")
+	}
+	recs, errs := pr.Chan(ctx)
+	return collect.Columns(ctx, recs, errs, columns, pr.allocator)
+}
+
+// Close releases the records.
+func (pr *PromReader) Close() error {
+	for _, rec := range pr.records {
+		rec.Release()
+	}
+	pr.records = nil
+	return nil
+}
@@ -0,0 +1,179 @@
+package promreader
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// matrix is a query_range response with two series. One lacks the job
+// label and has a NaN sample.
+const matrix = `{
+	"status": "success",
+	"data": {
+		"resultType": "matrix",
+		"result": [
+			{
+				"metric": {"__name__": "up", "instance": "a:9100", "job": "node"},
+				"values": [[1700000000, "1"], [1700000015.5, "0"]]
+			},
+			{
+				"metric": {"__name__": "up", "instance": "b:9100"},
+				"values": [[1700000000, "NaN"]]
+			}
+		]
+	}
+}`
+
+// request is what server saw of the last request.
+type request struct {
+	form       url.Values
+	user, pass string
+}
+
+// server serves body from the range query endpoint with the given status.
+func server(t *testing.T, status int, body string) (*httptest.Server, *request) {
+	t.Helper()
+	last := new(request)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/prom/api/v1/query_range" || r.Method != http.MethodPost {
+			t.Errorf("got %s %s, want POST /prom/api/v1/query_range", r.Method, r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		last.form = r.PostForm
+		last.user, last.pass, _ = r.BasicAuth()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, last
+}
+
+func TestPromReader(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := context.Background()
+	srv, req := server(t, http.StatusOK, matrix)
+
+	q := Query{
+		Expr:  "up",
+		Start: time.UnixMilli(1700000000000),
+		End:   time.UnixMilli(1700000060500),
+		Step:  15 * time.Second,
+	}
+	base := strings.Replace(srv.URL, "http://", "http://user:pass@", 1) + "/prom/"
+	pr, err := NewPromReader(ctx, base, q, srv.Client(), pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+
+	form := req.form
+	if form.Get("query") != "up" || form.Get("start") != "1700000000" || form.Get("end") != "1700000060.5" || form.Get("step") != "15" {
+		t.Errorf("form = %v", form)
+	}
+	if req.user != "user" || req.pass != "pass" {
+		t.Errorf("basic auth = %q, %q, want user and pass", req.user, req.pass)
+	}
+
+	want := "series:utf8 timestamp:timestamp[ms, tz=UTC] value:float64 instance:utf8 job:utf8"
+	var fields []string
+	for _, f := range pr.Schema().Fields() {
+		fields = append(fields, f.Name+":"+f.Type.String())
+	}
+	if got := strings.Join(fields, " "); got != want {
+		t.Errorf("schema = %s, want %s", got, want)
+	}
+
+	rec, err := pr.ReadColumns(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rec.Release()
+	if rec.NumRows() != 3 {
+		t.Fatalf("got %d rows, want 3", rec.NumRows())
+	}
+	series := rec.Column(0).(*array.String)
+	if series.Value(0) != `up{instance="a:9100", job="node"}` || series.Value(2) != `up{instance="b:9100"}` {
+		t.Errorf("series = %v", series)
+	}
+	ts := rec.Column(1).(*array.Timestamp)
+	if ts.Value(0) != 1700000000000 || ts.Value(1) != 1700000015500 || ts.Value(2) != 1700000000000 {
+		t.Errorf("timestamp = %v", ts)
+	}
+	vals := rec.Column(2).(*array.Float64)
+	if vals.Value(0) != 1 || vals.Value(1) != 0 || !math.IsNaN(vals.Value(2)) {
+		t.Errorf("value = %v", vals)
+	}
+	if job := rec.Column(4).(*array.String); job.Value(1) != "node" || job.IsValid(2) {
+		t.Errorf("job = %v, want null for the second series", job)
+	}
+
+	col, err := pr.ReadChunked(ctx, ValueColumn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer col.Release()
+	if len(col.Chunks()) != 2 || col.Len() != 3 {
+		t.Errorf("got %d chunks of %d rows, want one per series", len(col.Chunks()), col.Len())
+	}
+	if _, err := pr.ReadSingleColumn(ctx, "nope"); err == nil {
+		t.Error("missing column: want error")
+	}
+}
+
+func TestPromReaderErrors(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"query error", http.StatusBadRequest, `{"status": "error", "errorType": "bad_data", "error": "parse error"}`, "bad_data: parse error"},
+		{"not json", http.StatusBadGateway, "<html>", "502 Bad Gateway"},
+		{"vector", http.StatusOK, `{"status": "success", "data": {"resultType": "vector", "result": []}}`, "got vector result"},
+		{"malformed sample", http.StatusOK, `{"status": "success", "data": {"resultType": "matrix", "result": [
+			{"metric": {"__name__": "up"}, "values": [[1700000000, "1"], [1700000015, 2]]}]}}`, "malformed sample"},
+		{"bad value", http.StatusOK, `{"status": "success", "data": {"resultType": "matrix", "result": [
+			{"metric": {"__name__": "up"}, "values": [[1700000000, "x"]]}]}}`, `sample "x" in up{}`},
+	}
+	for _, tt := range tests {
+		srv, _ := server(t, tt.status, tt.body)
+		pr, err := NewPromReader(ctx, srv.URL+"/prom", Query{Expr: "up"}, nil, pool)
+		if err == nil {
+			pr.Close()
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: got %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+
+	// An empty matrix has a schema but no rows to read.
+	srv, _ := server(t, http.StatusOK, `{"status": "success", "data": {"resultType": "matrix", "result": []}}`)
+	pr, err := NewPromReader(ctx, srv.URL+"/prom", Query{Expr: "up"}, nil, pool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	if pr.Schema().NumFields() != 3 {
+		t.Errorf("schema = %v, want the three fixed columns", pr.Schema())
+	}
+	if _, err := pr.ReadColumns(ctx, nil); err == nil || !strings.Contains(err.Error(), "no series") {
+		t.Errorf("got %v, want a no series error", err)
+	}
+}
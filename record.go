@@ -0,0 +1,147 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// DetectAnomaliesRecord runs z-score detection over each named column of rec
+// and returns the Results keyed by column name. The caller must Release each
+// Result.
+func DetectAnomaliesRecord(ctx context.Context, rec arrow.Record, columns []string, threshold float64) (map[string]*Result, error) {
+	return DetectRecord(ctx, ZScoreDetector{Threshold: threshold}, rec, columns)
+}
+
+// DetectRecord runs d over each named column of rec and returns the Results
+// keyed by column name. On error no Results are returned.
+func DetectRecord(ctx context.Context, d Detector, rec arrow.Record, columns []string) (map[string]*Result, error) {
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no columns to analyze")
+	}
+	results := make(map[string]*Result, len(columns))
+	release := func() {
+		for _, r := range results {
+			r.Release()
+		}
+	}
+	for _, name := range columns {
+		if _, dup := results[name]; dup {
+			continue
+		}
+		idx := rec.Schema().FieldIndices(name)
+		if len(idx) == 0 {
+			release()
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+		res, err := d.Detect(ctx, rec.Column(idx[0]))
+		if err != nil {
+			release()
+			return nil, fmt.Errorf("column %s: %w", name, err)
+		}
+		results[name] = res
+	}
+	return results, nil
+}
+
+// AnomalousRows runs d over the named column of rec and returns the flagged
+// rows with every column of rec, in input order, together with the column's
+// Result. Result.Indices gives the original row number of each returned row.
+// The caller must Release both.
+func AnomalousRows(ctx context.Context, d Detector, rec arrow.Record, column string) (arrow.Record, *Result, error) {
+	idx := rec.Schema().FieldIndices(column)
+	if len(idx) == 0 {
+		return nil, nil, fmt.Errorf("column %s not found", column)
+	}
+	res, err := d.Detect(ctx, rec.Column(idx[0]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("column %s: %w", column, err)
+	}
+	rows, err := FilterRecord(ctx, rec, res.Mask)
+	if err != nil {
+		res.Release()
+		return nil, nil, fmt.Errorf("filter rows: %w", err)
+	}
+	return rows, res, nil
+}
+
+// FilterRecord returns the rows of rec where mask is true, as
+// compute.FilterRecordBatch does, except that dictionary-encoded columns,
+// which Arrow's filter kernel does not take, keep their dictionary and have
+// their indices filtered. The caller must Release the returned record.
+func FilterRecord(ctx context.Context, rec arrow.Record, mask *array.Boolean) (arrow.Record, error) {
+	cols := make([]arrow.Array, 0, rec.NumCols())
+	defer func() {
+		for _, c := range cols {
+			c.Release()
+		}
+	}()
+	for _, col := range rec.Columns() {
+		out, err := filterArray(ctx, col, mask)
+		if err != nil {
+			return nil, err
+		}
+		cols = append(cols, out)
+	}
+	var n int64
+	for i := 0; i < mask.Len(); i++ {
+		if mask.IsValid(i) && mask.Value(i) {
+			n++
+		}
+	}
+	return array.NewRecord(rec.Schema(), cols, n), nil
+}
+
+// filterArray is compute.FilterArray with dictionary-encoded columns
+// filtered by their indices.
+func filterArray(ctx context.Context, col arrow.Array, mask *array.Boolean) (arrow.Array, error) {
+	dict, ok := col.(*array.Dictionary)
+	if !ok {
+		return compute.FilterArray(ctx, col, mask, *compute.DefaultFilterOptions())
+	}
+	indices, err := compute.FilterArray(ctx, dict.Indices(), mask, *compute.DefaultFilterOptions())
+	if err != nil {
+		return nil, err
+	}
+	defer indices.Release()
+	return array.NewDictionaryArray(dict.DataType(), indices, dict.Dictionary()), nil
+}
+
+// takeArray is compute.TakeArray with dictionary-encoded columns taken by
+// their indices.
+func takeArray(ctx context.Context, col arrow.Array, idx *array.Int64) (arrow.Array, error) {
+	dict, ok := col.(*array.Dictionary)
+	if !ok {
+		return compute.TakeArray(ctx, col, idx)
+	}
+	indices, err := compute.TakeArray(ctx, dict.Indices(), idx)
+	if err != nil {
+		return nil, err
+	}
+	defer indices.Release()
+	return array.NewDictionaryArray(dict.DataType(), indices, dict.Dictionary()), nil
+}
+
+// Names of the columns Annotate appends.
+const (
+	ScoreColumn   = "zscore"
+	AnomalyColumn = "is_anomaly"
+)
+
+// Annotate returns rec with res's scores and mask appended as the
+// ScoreColumn and AnomalyColumn columns. res must come from a column of rec.
+// The caller must Release the returned record.
+func Annotate(rec arrow.Record, res *Result) (arrow.Record, error) {
+	if int64(res.Mask.Len()) != rec.NumRows() {
+		return nil, fmt.Errorf("result has %d rows, record has %d", res.Mask.Len(), rec.NumRows())
+	}
+	fields := append(rec.Schema().Fields(),
+		arrow.Field{Name: ScoreColumn, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+		arrow.Field{Name: AnomalyColumn, Type: arrow.FixedWidthTypes.Boolean, Nullable: true},
+	)
+	cols := append(append([]arrow.Array(nil), rec.Columns()...), res.Zscore, res.Mask)
+	return array.NewRecord(arrow.NewSchema(fields, nil), cols, rec.NumRows()), nil
+}
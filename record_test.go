@@ -0,0 +1,150 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestDetectAnomaliesRecord(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "a", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "b", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "s", Type: arrow.BinaryTypes.String},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.Float64Builder).AppendValues([]float64{1, 2, 3, 100, 2}, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{-50, 2, 3, 1, 2}, nil)
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"x", "y", "z", "w", "v"}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	results, err := DetectAnomaliesRecord(context.Background(), rec, []string{"a", "b"}, 1.99)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for _, r := range results {
+			r.Release()
+		}
+	}()
+	if !results["a"].Mask.Value(3) {
+		t.Errorf("expected a[3] to be anomalous")
+	}
+	if !results["b"].Mask.Value(0) {
+		t.Errorf("expected b[0] to be anomalous")
+	}
+
+	if _, err := DetectAnomaliesRecord(context.Background(), rec, []string{"a", "missing"}, 2); err == nil {
+		t.Errorf("expected error for missing column")
+	}
+	if _, err := DetectAnomaliesRecord(context.Background(), rec, []string{"s"}, 2); err == nil {
+		t.Errorf("expected error for string column")
+	}
+}
+
+func TestAnomalousRows(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.StringBuilder).AppendValues([]string{"a", "b", "c", "d", "e"}, nil)
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{1, 2, 3, 100, 2}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	rows, res, err := AnomalousRows(ctx, ZScoreDetector{Threshold: 1.99}, rec, "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Release()
+	defer res.Release()
+
+	if rows.NumRows() != 1 || rows.NumCols() != 2 {
+		t.Fatalf("got %dx%d rows, want 1x2", rows.NumRows(), rows.NumCols())
+	}
+	if got := rows.Column(0).(*array.String).Value(0); got != "d" {
+		t.Errorf("host = %q, want d", got)
+	}
+	if res.Indices.Value(0) != 3 {
+		t.Errorf("index = %d, want 3", res.Indices.Value(0))
+	}
+
+	if _, _, err := AnomalousRows(ctx, ZScoreDetector{}, rec, "missing"); err == nil {
+		t.Errorf("expected error for missing column")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	rec := float64Record(t, "value", []float64{1, 2, 3, 100, 2})
+	defer rec.Release()
+	ctx := compute.WithAllocator(context.Background(), pool)
+	res, err := ZScoreDetector{Threshold: 1.99}.Detect(ctx, rec.Column(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	out, err := Annotate(rec, res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	if out.NumCols() != 3 || out.ColumnName(1) != ScoreColumn || out.ColumnName(2) != AnomalyColumn {
+		t.Fatalf("schema = %v", out.Schema())
+	}
+	if !out.Column(2).(*array.Boolean).Value(3) || out.Column(2).(*array.Boolean).Value(0) {
+		t.Errorf("is_anomaly = %v, want only row 3", out.Column(2))
+	}
+}
+
+func TestFilterRecordKeepsDictionaries(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	dt := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "host", Type: dt},
+		{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	for _, h := range []string{"a", "b", "a", "c", "b"} {
+		if err := b.Field(0).(*array.BinaryDictionaryBuilder).AppendString(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{1, 2, 3, 100, 2}, nil)
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := compute.WithAllocator(context.Background(), pool)
+	rows, res, err := AnomalousRows(ctx, ZScoreDetector{Threshold: 1.99}, rec, "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Release()
+	defer res.Release()
+
+	if rows.NumRows() != 1 || !arrow.TypeEqual(rows.Schema().Field(0).Type, dt) {
+		t.Fatalf("got %d rows of %s, want 1 of %s", rows.NumRows(), rows.Schema().Field(0).Type, dt)
+	}
+	if got := rows.Column(0).ValueStr(0); got != "c" {
+		t.Errorf("host = %q, want c", got)
+	}
+}
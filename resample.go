@@ -0,0 +1,137 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// Aggregation names how Resample combines the values in a time bucket.
+type Aggregation string
+
+// Supported aggregations.
+const (
+	AggMean  Aggregation = "mean"
+	AggMax   Aggregation = "max"
+	AggMin   Aggregation = "min"
+	AggSum   Aggregation = "sum"
+	AggCount Aggregation = "count"
+)
+
+// maxBuckets bounds the series Resample builds, so a tiny bucket over a long
+// span fails rather than exhausting memory.
+const maxBuckets = 1 << 24
+
+// bucketAcc accumulates the values of one bucket.
+type bucketAcc struct {
+	n             int64
+	sum, min, max float64
+}
+
+// Resample aggregates valueCol of rec into fixed buckets of timeCol, turning
+// irregular events into a regular series for detection. The result has two
+// columns: timeCol, the UTC start of each bucket in milliseconds, and
+// valueCol, the aggregate as float64. Buckets run from the earliest to the
+// latest event with none skipped; empty ones count zero and are null for the
+// other aggregations. Rows with a null time or value are left out, except
+// that AggCount counts rows with a null value. timeCol is read as by
+// Result.Points. The caller must Release the record.
+func Resample(ctx context.Context, rec arrow.Record, valueCol, timeCol string, bucket time.Duration, agg Aggregation) (arrow.Record, error) {
+	if bucket <= 0 {
+		return nil, fmt.Errorf("bucket must be positive, got %v", bucket)
+	}
+	switch agg {
+	case AggMean, AggMax, AggMin, AggSum, AggCount:
+	default:
+		return nil, fmt.Errorf("unknown aggregation %q", agg)
+	}
+	vidx := rec.Schema().FieldIndices(valueCol)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", valueCol)
+	}
+	tidx := rec.Schema().FieldIndices(timeCol)
+	if len(tidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", timeCol)
+	}
+	times := rec.Column(tidx[0])
+
+	var values *array.Float64
+	if agg != AggCount {
+		f, err := castFloat64(ctx, rec.Column(vidx[0]))
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", valueCol, err)
+		}
+		defer f.Release()
+		values = f
+	}
+
+	width := bucket.Milliseconds()
+	if width == 0 {
+		return nil, fmt.Errorf("bucket must be at least 1ms, got %v", bucket)
+	}
+	buckets := map[int64]*bucketAcc{}
+	first, last := int64(math.MaxInt64), int64(math.MinInt64)
+	for i := 0; i < int(rec.NumRows()); i++ {
+		if times.IsNull(i) || (values != nil && values.IsNull(i)) {
+			continue
+		}
+		t, err := timeAt(times, i)
+		if err != nil {
+			return nil, fmt.Errorf("column %s row %d: %w", timeCol, i, err)
+		}
+		ms := t.UnixMilli()
+		key := ms - ((ms%width)+width)%width
+		b := buckets[key]
+		if b == nil {
+			b = &bucketAcc{min: math.Inf(1), max: math.Inf(-1)}
+			buckets[key] = b
+		}
+		b.n++
+		if values != nil {
+			v := values.Value(i)
+			b.sum += v
+			b.min = math.Min(b.min, v)
+			b.max = math.Max(b.max, v)
+		}
+		first, last = min(first, key), max(last, key)
+	}
+
+	if len(buckets) > 0 && (last-first)/width >= maxBuckets {
+		return nil, fmt.Errorf("%v buckets over %v would exceed %d", bucket, time.Duration(last-first)*time.Millisecond, maxBuckets)
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: timeCol, Type: &arrow.TimestampType{Unit: arrow.Millisecond, TimeZone: "UTC"}},
+		{Name: valueCol, Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	}, nil)
+	rb := array.NewRecordBuilder(compute.GetAllocator(ctx), schema)
+	defer rb.Release()
+	tb := rb.Field(0).(*array.TimestampBuilder)
+	vb := rb.Field(1).(*array.Float64Builder)
+	for key := first; len(buckets) > 0 && key <= last; key += width {
+		tb.Append(arrow.Timestamp(key))
+		b := buckets[key]
+		switch {
+		case agg == AggCount && b == nil:
+			vb.Append(0)
+		case b == nil:
+			vb.AppendNull()
+		case agg == AggCount:
+			vb.Append(float64(b.n))
+		case agg == AggMean:
+			vb.Append(b.sum / float64(b.n))
+		case agg == AggMax:
+			vb.Append(b.max)
+		case agg == AggMin:
+			vb.Append(b.min)
+		case agg == AggSum:
+			vb.Append(b.sum)
+		}
+	}
+	return rb.NewRecord(), nil
+}
@@ -0,0 +1,72 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestResample(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Second}},
+		{Name: "v", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Two events in the first 5m bucket, none in the second, one in the third.
+	for _, e := range []struct {
+		offset time.Duration
+		v      float64
+	}{{time.Minute, 2}, {4 * time.Minute, 6}, {11 * time.Minute, 5}} {
+		b.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(start.Add(e.offset).Unix()))
+		b.Field(1).(*array.Float64Builder).Append(e.v)
+	}
+	rec := b.NewRecord()
+	defer rec.Release()
+
+	ctx := context.Background()
+	tests := []struct {
+		agg  Aggregation
+		want []float64
+	}{
+		{AggMean, []float64{4, 0, 5}},
+		{AggMax, []float64{6, 0, 5}},
+		{AggCount, []float64{2, 0, 1}},
+		{AggSum, []float64{8, 0, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.agg), func(t *testing.T) {
+			out, err := Resample(ctx, rec, "v", "ts", 5*time.Minute, tt.agg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Release()
+			if out.NumRows() != 3 {
+				t.Fatalf("got %d buckets, want 3", out.NumRows())
+			}
+			ts := out.Column(0).(*array.Timestamp)
+			if got := ts.Value(2).ToTime(arrow.Millisecond); !got.Equal(start.Add(10 * time.Minute)) {
+				t.Errorf("third bucket starts at %v", got)
+			}
+			vals := out.Column(1).(*array.Float64)
+			for i, want := range tt.want {
+				empty := i == 1 && tt.agg != AggCount
+				if empty != vals.IsNull(i) || (!empty && vals.Value(i) != want) {
+					t.Errorf("bucket %d = %v, want %v", i, vals.GetOneForMarshal(i), want)
+				}
+			}
+		})
+	}
+
+	if _, err := Resample(ctx, rec, "v", "ts", 5*time.Minute, "median"); err == nil {
+		t.Errorf("expected error for an unknown aggregation")
+	}
+}
@@ -0,0 +1,353 @@
+// Package rules checks declared data-quality expectations, such as value
+// ranges, patterns, uniqueness, ordering and null fractions, over streams of
+// Arrow records, alongside statistical anomaly detection.
+package rules
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"gopkg.in/yaml.v3"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+// Rule names, as reported in Failure.Rule.
+const (
+	RuleMin             = "min"
+	RuleMax             = "max"
+	RulePattern         = "pattern"
+	RuleUnique          = "unique"
+	RuleMonotonic       = "monotonic"
+	RuleNotNull         = "not_null"
+	RuleMaxNullFraction = "max_null_fraction"
+	RuleAnomaly         = "anomaly"
+)
+
+// Monotonic orderings for Column.Monotonic.
+const (
+	Increasing         = "increasing"
+	Decreasing         = "decreasing"
+	StrictlyIncreasing = "strictly_increasing"
+	StrictlyDecreasing = "strictly_decreasing"
+)
+
+// DefaultMaxExamples is how many failing rows each Failure keeps when
+// Spec.MaxExamples is zero.
+const DefaultMaxExamples = 5
+
+// Spec is a set of expectations, usually loaded from YAML:
+//
+//	max_examples: 5
+//	columns:
+//	  - name: latency_ms
+//	    min: 0
+//	    max: 60000
+//	    max_null_fraction: 0.01
+//	    detect: true
+//	  - name: request_id
+//	    pattern: '^[0-9a-f]{32}$'
+//	    unique: true
+//	    not_null: true
+//	  - name: ts
+//	    monotonic: increasing
+type Spec struct {
+	Columns []Column `yaml:"columns"`
+	// MaxExamples bounds the failing rows kept per rule; zero means
+	// DefaultMaxExamples.
+	MaxExamples int `yaml:"max_examples"`
+}
+
+// Column holds the expectations for one column. Unset fields are not checked.
+type Column struct {
+	Name string `yaml:"name"`
+	// Min and Max bound numeric values, inclusively.
+	Min *float64 `yaml:"min"`
+	Max *float64 `yaml:"max"`
+	// Pattern is a regular expression every value must match, applied to
+	// the string form of non-string values.
+	Pattern string `yaml:"pattern"`
+	Unique  bool   `yaml:"unique"`
+	// Monotonic is increasing, decreasing, strictly_increasing or
+	// strictly_decreasing, over numeric and temporal values in row order.
+	Monotonic string `yaml:"monotonic"`
+	NotNull   bool   `yaml:"not_null"`
+	// MaxNullFraction bounds the share of null values over the whole input.
+	MaxNullFraction *float64 `yaml:"max_null_fraction"`
+	// Detect also flags statistical anomalies, scoring values against
+	// running z-score statistics at Threshold (anomaly.DefaultThreshold if
+	// zero).
+	Detect    bool    `yaml:"detect"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// Load reads a Spec from a YAML file.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes a Spec from YAML, rejecting unknown keys.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("parse rules: %w", err)
+	}
+	return &spec, nil
+}
+
+// Example is one failing row.
+type Example struct {
+	Row   int64  `json:"row"`
+	Value string `json:"value"`
+	// Score is the anomaly score, for RuleAnomaly.
+	Score float64 `json:"score,omitempty"`
+}
+
+// Failure reports a rule broken by the input: how many rows broke it, and
+// the first of them. Column-level rules such as RuleMaxNullFraction have no
+// examples and a Count of one.
+type Failure struct {
+	Column   string    `json:"column"`
+	Rule     string    `json:"rule"`
+	Count    int64     `json:"count"`
+	Message  string    `json:"message"`
+	Examples []Example `json:"examples,omitempty"`
+}
+
+// checker holds the compiled expectations and running state for a column.
+type checker struct {
+	spec    Column
+	pattern *regexp.Regexp
+	seen    map[string]int64
+	last    float64
+	hasLast bool
+	nulls   int64
+	rows    int64
+	detect  *anomaly.StreamingDetector
+	fails   map[string]*Failure
+}
+
+// Validator evaluates a Spec over record batches, in input order.
+type Validator struct {
+	checkers    []*checker
+	maxExamples int
+	rows        int64
+	// order lists failures as they first occur.
+	order []*Failure
+}
+
+// NewValidator compiles spec.
+func NewValidator(spec *Spec) (*Validator, error) {
+	v := &Validator{maxExamples: spec.MaxExamples}
+	if v.maxExamples == 0 {
+		v.maxExamples = DefaultMaxExamples
+	}
+	for _, c := range spec.Columns {
+		if c.Name == "" {
+			return nil, fmt.Errorf("rules: column without a name")
+		}
+		ch := &checker{spec: c, fails: map[string]*Failure{}}
+		if c.Pattern != "" {
+			re, err := regexp.Compile(c.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rules: column %s pattern: %w", c.Name, err)
+			}
+			ch.pattern = re
+		}
+		switch c.Monotonic {
+		case "", Increasing, Decreasing, StrictlyIncreasing, StrictlyDecreasing:
+		default:
+			return nil, fmt.Errorf("rules: column %s: unknown monotonic ordering %q", c.Name, c.Monotonic)
+		}
+		if c.Unique {
+			ch.seen = map[string]int64{}
+		}
+		if c.Detect {
+			threshold := c.Threshold
+			if threshold == 0 {
+				threshold = anomaly.DefaultThreshold
+			}
+			ch.detect = anomaly.NewStreamingDetector(c.Name, threshold)
+		}
+		v.checkers = append(v.checkers, ch)
+	}
+	return v, nil
+}
+
+// Add checks the rows of rec, which continue those of earlier calls.
+func (v *Validator) Add(ctx context.Context, rec arrow.Record) error {
+	for _, ch := range v.checkers {
+		idx := rec.Schema().FieldIndices(ch.spec.Name)
+		if len(idx) == 0 {
+			return fmt.Errorf("column %s not found", ch.spec.Name)
+		}
+		if err := v.check(ctx, ch, rec, rec.Column(idx[0])); err != nil {
+			return fmt.Errorf("column %s: %w", ch.spec.Name, err)
+		}
+	}
+	v.rows += rec.NumRows()
+	return nil
+}
+
+func (v *Validator) check(ctx context.Context, ch *checker, rec arrow.Record, col arrow.Array) error {
+	c := ch.spec
+	var nums []float64
+	if c.Min != nil || c.Max != nil || c.Monotonic != "" {
+		var err error
+		if nums, err = numericValues(ctx, col); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < col.Len(); i++ {
+		row := v.rows + int64(i)
+		ch.rows++
+		if col.IsNull(i) {
+			ch.nulls++
+			if c.NotNull {
+				v.fail(ch, RuleNotNull, "null values", Example{Row: row})
+			}
+			continue
+		}
+		str := col.ValueStr(i)
+		if nums != nil {
+			x := nums[i]
+			if c.Min != nil && x < *c.Min {
+				v.fail(ch, RuleMin, fmt.Sprintf("values below %v", *c.Min), Example{Row: row, Value: str})
+			}
+			if c.Max != nil && x > *c.Max {
+				v.fail(ch, RuleMax, fmt.Sprintf("values above %v", *c.Max), Example{Row: row, Value: str})
+			}
+			if c.Monotonic != "" {
+				if ch.hasLast && !ordered(c.Monotonic, ch.last, x) {
+					v.fail(ch, RuleMonotonic, "values out of "+strings.ReplaceAll(c.Monotonic, "_", " ")+" order", Example{Row: row, Value: str})
+				}
+				ch.last, ch.hasLast = x, true
+			}
+		}
+		if ch.pattern != nil && !ch.pattern.MatchString(str) {
+			v.fail(ch, RulePattern, "values not matching "+c.Pattern, Example{Row: row, Value: str})
+		}
+		if ch.seen != nil {
+			if first, dup := ch.seen[str]; dup {
+				v.fail(ch, RuleUnique, "duplicate values", Example{Row: row, Value: fmt.Sprintf("%s (first at row %d)", str, first)})
+			} else {
+				ch.seen[str] = row
+			}
+		}
+	}
+	if ch.detect != nil {
+		res, err := ch.detect.Update(ctx, rec)
+		if err != nil {
+			return err
+		}
+		defer res.Release()
+		for j := 0; j < res.Indices.Len(); j++ {
+			i := int(res.Indices.Value(j))
+			v.fail(ch, RuleAnomaly, "statistical anomalies", Example{Row: v.rows + int64(i), Value: col.ValueStr(i), Score: res.Zscore.Value(i)})
+		}
+	}
+	return nil
+}
+
+// fail records a failing row for rule.
+func (v *Validator) fail(ch *checker, rule, message string, ex Example) {
+	f := ch.fails[rule]
+	if f == nil {
+		f = &Failure{Column: ch.spec.Name, Rule: rule, Message: message}
+		ch.fails[rule] = f
+		v.order = append(v.order, f)
+	}
+	f.Count++
+	if len(f.Examples) < v.maxExamples {
+		f.Examples = append(f.Examples, ex)
+	}
+}
+
+// Failures returns the rules broken so far, in order of first failure,
+// followed by the column-level rules over all rows seen.
+func (v *Validator) Failures() []Failure {
+	out := make([]Failure, 0, len(v.order))
+	for _, f := range v.order {
+		out = append(out, *f)
+	}
+	for _, ch := range v.checkers {
+		limit := ch.spec.MaxNullFraction
+		if limit == nil || ch.rows == 0 {
+			continue
+		}
+		if frac := float64(ch.nulls) / float64(ch.rows); frac > *limit {
+			out = append(out, Failure{
+				Column:  ch.spec.Name,
+				Rule:    RuleMaxNullFraction,
+				Count:   1,
+				Message: fmt.Sprintf("null fraction %.4g exceeds %v (%d of %d rows)", frac, *limit, ch.nulls, ch.rows),
+			})
+		}
+	}
+	return out
+}
+
+// Rows returns the number of rows checked.
+func (v *Validator) Rows() int64 {
+	return v.rows
+}
+
+// ordered reports whether next may follow prev under the ordering.
+func ordered(order string, prev, next float64) bool {
+	switch order {
+	case Increasing:
+		return next >= prev
+	case Decreasing:
+		return next <= prev
+	case StrictlyIncreasing:
+		return next > prev
+	case StrictlyDecreasing:
+		return next < prev
+	}
+	return true
+}
+
+// numericValues returns the values of a numeric or temporal column as
+// float64, with NaN at nulls.
+func numericValues(ctx context.Context, col arrow.Array) ([]float64, error) {
+	var target arrow.DataType = arrow.PrimitiveTypes.Float64
+	switch col.DataType().ID() {
+	case arrow.TIMESTAMP, arrow.DATE32, arrow.DATE64, arrow.TIME32, arrow.TIME64, arrow.DURATION:
+		target = arrow.PrimitiveTypes.Int64
+	default:
+		if !arrow.IsInteger(col.DataType().ID()) && !arrow.IsFloating(col.DataType().ID()) && col.DataType().ID() != arrow.DECIMAL128 {
+			return nil, fmt.Errorf("range and order rules need a numeric or temporal column, got %s", col.DataType())
+		}
+	}
+	cast, err := compute.CastArray(ctx, col, compute.UnsafeCastOptions(target))
+	if err != nil {
+		return nil, fmt.Errorf("cast %s to %s: %w", col.DataType(), target, err)
+	}
+	defer cast.Release()
+	out := make([]float64, col.Len())
+	for i := range out {
+		switch {
+		case cast.IsNull(i):
+			out[i] = math.NaN()
+		case target == arrow.PrimitiveTypes.Int64:
+			out[i] = float64(cast.(*array.Int64).Value(i))
+		default:
+			out[i] = cast.(*array.Float64).Value(i)
+		}
+	}
+	return out, nil
+}
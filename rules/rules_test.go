@@ -0,0 +1,184 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// batch decodes a record of schema from a JSON array of rows. The caller
+// must Release it.
+func batch(t *testing.T, mem memory.Allocator, schema *arrow.Schema, rows string) arrow.Record {
+	t.Helper()
+	rec, _, err := array.RecordFromJSON(mem, schema, strings.NewReader(rows))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+// validate adds each batch of rows, decoded with schema, to a Validator for
+// spec and returns its failures.
+func validate(t *testing.T, spec string, schema *arrow.Schema, batches ...string) []Failure {
+	t.Helper()
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	s, err := Parse([]byte(spec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewValidator(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rows := range batches {
+		rec := batch(t, pool, schema, rows)
+		err := v.Add(ctx, rec)
+		rec.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return v.Failures()
+}
+
+// summary formats failures as rule:column=count, in order.
+func summary(failures []Failure) string {
+	var parts []string
+	for _, f := range failures {
+		parts = append(parts, fmt.Sprintf("%s:%s=%d", f.Rule, f.Column, f.Count))
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestValidator(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "id", Type: arrow.BinaryTypes.String, Nullable: true},
+		{Name: "v", Type: arrow.PrimitiveTypes.Float64, Nullable: true},
+	}, nil)
+	spec := `
+columns:
+  - name: v
+    min: 0
+    max: 100
+    not_null: true
+    max_null_fraction: 0.2
+  - name: id
+    pattern: '^[ab]$'
+    unique: true
+`
+	// Row numbers continue across batches, as does uniqueness.
+	failures := validate(t, spec, schema,
+		`[{"id": "a", "v": 1}, {"id": "b", "v": 150}, {"id": "a", "v": null}]`,
+		`[{"id": "c", "v": -5}, {"id": "c", "v": null}, {"id": "b", "v": 100}]`,
+	)
+	want := "max:v=1 not_null:v=2 unique:id=3 min:v=1 pattern:id=2 max_null_fraction:v=1"
+	if got := summary(failures); got != want {
+		t.Fatalf("got  %s\nwant %s", got, want)
+	}
+	unique := failures[2].Examples
+	if len(unique) != 3 || unique[0] != (Example{Row: 2, Value: "a (first at row 0)"}) || unique[2].Row != 5 {
+		t.Errorf("unique examples = %+v", unique)
+	}
+	if ex := failures[3].Examples; len(ex) != 1 || ex[0] != (Example{Row: 3, Value: "-5"}) {
+		t.Errorf("min examples = %+v", ex)
+	}
+	if f := failures[5]; f.Examples != nil || !strings.Contains(f.Message, "2 of 6 rows") {
+		t.Errorf("null fraction failure = %+v", f)
+	}
+}
+
+func TestValidatorMonotonic(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: &arrow.TimestampType{Unit: arrow.Second}, Nullable: true},
+		{Name: "n", Type: arrow.PrimitiveTypes.Int64, Nullable: true},
+	}, nil)
+	spec := `
+max_examples: 1
+columns:
+  - name: ts
+    monotonic: strictly_increasing
+  - name: n
+    monotonic: decreasing
+`
+	// Nulls are skipped; the order carries over into the second batch.
+	failures := validate(t, spec, schema,
+		`[{"ts": "2024-01-01 00:00:00", "n": 5}, {"ts": "2024-01-01 00:00:00", "n": 5}, {"ts": null, "n": 6}]`,
+		`[{"ts": "2023-12-31 00:00:00", "n": 4}, {"ts": "2024-01-02 00:00:00", "n": null}]`,
+	)
+	if got, want := summary(failures), "monotonic:ts=2 monotonic:n=1"; got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+	if ex := failures[0].Examples; len(ex) != 1 || ex[0].Row != 1 {
+		t.Errorf("examples = %+v, want row 1 alone", ex)
+	}
+	if ex := failures[1].Examples; len(ex) != 1 || ex[0] != (Example{Row: 2, Value: "6"}) {
+		t.Errorf("examples = %+v", ex)
+	}
+}
+
+func TestValidatorDetect(t *testing.T) {
+	schema := arrow.NewSchema([]arrow.Field{{Name: "v", Type: arrow.PrimitiveTypes.Float64}}, nil)
+	var rows []string
+	for i := range 200 {
+		v := float64(i % 5)
+		if i == 150 {
+			v = 1000
+		}
+		rows = append(rows, fmt.Sprintf(`{"v": %v}`, v))
+	}
+	failures := validate(t, "columns: [{name: v, detect: true}]", schema, "["+strings.Join(rows, ",")+"]")
+	if len(failures) != 1 || failures[0].Rule != RuleAnomaly || failures[0].Count != 1 {
+		t.Fatalf("failures = %+v", failures)
+	}
+	if ex := failures[0].Examples[0]; ex.Row != 150 || ex.Value != "1000" || ex.Score < 3 {
+		t.Errorf("example = %+v", ex)
+	}
+}
+
+func TestValidatorErrors(t *testing.T) {
+	for _, spec := range []string{
+		"columns: [{min: 1}]",
+		"columns: [{name: v, pattern: '('}]",
+		"columns: [{name: v, monotonic: sideways}]",
+	} {
+		s, err := Parse([]byte(spec))
+		if err != nil {
+			t.Fatalf("%s: %v", spec, err)
+		}
+		if _, err := NewValidator(s); err == nil {
+			t.Errorf("%s: want error", spec)
+		}
+	}
+	if _, err := Parse([]byte("columns: [{name: v, maximum: 1}]")); err == nil {
+		t.Error("unknown key: want error")
+	}
+
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+	schema := arrow.NewSchema([]arrow.Field{{Name: "s", Type: arrow.BinaryTypes.String}}, nil)
+	rec := batch(t, pool, schema, `[{"s": "x"}]`)
+	defer rec.Release()
+	for _, spec := range []string{"columns: [{name: s, min: 0}]", "columns: [{name: t}]"} {
+		s, err := Parse([]byte(spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := NewValidator(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := v.Add(ctx, rec); err == nil {
+			t.Errorf("%s: want error", spec)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// Decomposition splits a series into trend, seasonal and residual components
+// with value = Trend + Seasonal + Residual. Each array is aligned with the
+// input and is null where the input is.
+type Decomposition struct {
+	Trend    *array.Float64
+	Seasonal *array.Float64
+	Residual *array.Float64
+}
+
+// Release frees memory associated with the Decomposition.
+func (d *Decomposition) Release() {
+	for _, a := range []*array.Float64{d.Trend, d.Seasonal, d.Residual} {
+		if a != nil {
+			a.Release()
+		}
+	}
+}
+
+// Decompose performs an STL-style decomposition of col, an evenly spaced
+// series in time order with the given period in rows (e.g. 24 for hourly data
+// with daily seasonality). The trend is a centered moving median over one
+// period of the deseasonalized series and the seasonal component is the
+// median detrended value at each phase, centered to sum to zero; medians
+// rather than means keep the anomalies out of both, so they show up
+// undiluted in the residual.
+func Decompose(ctx context.Context, col arrow.Array, period int) (*Decomposition, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	if period < 2 {
+		return nil, fmt.Errorf("period must be at least 2, got %d", period)
+	}
+	n := floatCol.Len()
+	if n-floatCol.NullN() < 2*period {
+		return nil, fmt.Errorf("need at least two periods (%d values), got %d", 2*period, n-floatCol.NullN())
+	}
+
+	// Alternate between smoothing the deseasonalized series into a trend and
+	// averaging the detrended series by phase, as STL's inner loop does, so
+	// the truncated windows at either end see little seasonality.
+	trend := make([]float64, n)
+	season := make([]float64, period)
+	for iter := 0; iter < seasonalPasses; iter++ {
+		movingMedian(floatCol, season, period, trend)
+		phaseMedians(floatCol, trend, season)
+	}
+
+	mem := compute.GetAllocator(ctx)
+	tb, sb, rb := array.NewFloat64Builder(mem), array.NewFloat64Builder(mem), array.NewFloat64Builder(mem)
+	defer tb.Release()
+	defer sb.Release()
+	defer rb.Release()
+	for i := 0; i < n; i++ {
+		if floatCol.IsNull(i) {
+			tb.AppendNull()
+			sb.AppendNull()
+			rb.AppendNull()
+			continue
+		}
+		s := season[i%period]
+		tb.Append(trend[i])
+		sb.Append(s)
+		rb.Append(floatCol.Value(i) - trend[i] - s)
+	}
+	return &Decomposition{Trend: tb.NewFloat64Array(), Seasonal: sb.NewFloat64Array(), Residual: rb.NewFloat64Array()}, nil
+}
+
+// seasonalPasses is the number of trend/seasonal refinement passes.
+const seasonalPasses = 3
+
+// movingMedian sets trend[i] to the median of the deseasonalized values
+// within half a period of row i, or NaN if there are none.
+func movingMedian(col *array.Float64, season []float64, period int, trend []float64) {
+	n, half := col.Len(), period/2
+	window := make([]float64, 0, 2*half+1)
+	for i := 0; i < n; i++ {
+		window = window[:0]
+		for j := max(0, i-half); j <= min(n-1, i+half); j++ {
+			if col.IsValid(j) {
+				window = append(window, col.Value(j)-season[j%period])
+			}
+		}
+		trend[i] = math.NaN()
+		if len(window) > 0 {
+			trend[i] = medianInPlace(window)
+		}
+	}
+}
+
+// phaseMedians sets season[p] to the median detrended value at phase p,
+// centered so the season sums to zero.
+func phaseMedians(col *array.Float64, trend, season []float64) {
+	period := len(season)
+	phases := make([][]float64, period)
+	for i := 0; i < col.Len(); i++ {
+		if col.IsValid(i) && !math.IsNaN(trend[i]) {
+			phases[i%period] = append(phases[i%period], col.Value(i)-trend[i])
+		}
+	}
+	var offset float64
+	for p, vals := range phases {
+		season[p] = 0
+		if len(vals) > 0 {
+			season[p] = medianInPlace(vals)
+		}
+		offset += season[p]
+	}
+	offset /= float64(period)
+	for p := range season {
+		season[p] -= offset
+	}
+}
+
+// SeasonalDetector removes trend and seasonality with Decompose and runs
+// Residual over what is left, so regular daily or weekly swings are not
+// mistaken for anomalies and do not mask real ones. The input must be in time
+// order; see DetectAnomaliesSeasonal to order by a timestamp column. Scores,
+// Mean and StdDev refer to the residuals, while Values holds the original
+// flagged values.
+type SeasonalDetector struct {
+	// Period is the season length in rows.
+	Period int
+	// Residual scores the residuals; nil means a MADDetector at
+	// DefaultThreshold.
+	Residual Detector
+}
+
+var _ Detector = SeasonalDetector{}
+
+// Detect implements Detector.
+func (d SeasonalDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	dec, err := Decompose(ctx, floatCol, d.Period)
+	if err != nil {
+		return nil, err
+	}
+	defer dec.Release()
+
+	residual := d.Residual
+	if residual == nil {
+		residual = MADDetector{Threshold: DefaultThreshold}
+	}
+	res, err := residual.Detect(ctx, dec.Residual)
+	if err != nil {
+		return nil, fmt.Errorf("residuals: %w", err)
+	}
+	defer res.Release()
+	res.Mask.Retain()
+	res.Zscore.Retain()
+	out, err := newResult(ctx, floatCol, res.Mask, res.Zscore, runningStats{})
+	if err != nil {
+		return nil, err
+	}
+	out.Mean, out.StdDev = res.Mean, res.StdDev
+	return out, nil
+}
+
+// DetectAnomaliesSeasonal orders rec by timeCol, runs SeasonalDetector over
+// valueCol with the given period and residual detector (nil for the default),
+// and returns a Result aligned with the original row order of rec.
+func DetectAnomaliesSeasonal(ctx context.Context, rec arrow.Record, valueCol, timeCol string, period int, residual Detector) (*Result, error) {
+	vidx := rec.Schema().FieldIndices(valueCol)
+	if len(vidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", valueCol)
+	}
+	tidx := rec.Schema().FieldIndices(timeCol)
+	if len(tidx) == 0 {
+		return nil, fmt.Errorf("column %s not found", timeCol)
+	}
+	order, err := timeOrder(ctx, rec.Column(tidx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", timeCol, err)
+	}
+	floatCol, err := toFloat64(ctx, rec.Column(vidx[0]))
+	if err != nil {
+		return nil, fmt.Errorf("column %s: %w", valueCol, err)
+	}
+	defer floatCol.Release()
+
+	sorted, err := takeFloat64(ctx, floatCol, order)
+	if err != nil {
+		return nil, err
+	}
+	defer sorted.Release()
+	res, err := SeasonalDetector{Period: period, Residual: residual}.Detect(ctx, sorted)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Release()
+
+	// Scatter back to input order: row order[i] of the input is row i sorted.
+	inverse := make([]int64, len(order))
+	for i, row := range order {
+		inverse[row] = int64(i)
+	}
+	inv := array.NewInt64Builder(compute.GetAllocator(ctx))
+	defer inv.Release()
+	inv.AppendValues(inverse, nil)
+	invArr := inv.NewInt64Array()
+	defer invArr.Release()
+
+	mask, err := compute.TakeArray(ctx, res.Mask, invArr)
+	if err != nil {
+		return nil, fmt.Errorf("reorder mask: %w", err)
+	}
+	zscore, err := compute.TakeArray(ctx, res.Zscore, invArr)
+	if err != nil {
+		mask.Release()
+		return nil, fmt.Errorf("reorder scores: %w", err)
+	}
+	out, err := newResult(ctx, floatCol, mask.(*array.Boolean), zscore.(*array.Float64), runningStats{})
+	if err != nil {
+		return nil, err
+	}
+	out.Mean, out.StdDev = res.Mean, res.StdDev
+	return out, nil
+}
+
+// timeOrder returns the row indices of col in ascending order, nulls last.
+// Timestamp, date, time and numeric columns are supported.
+func timeOrder(ctx context.Context, col arrow.Array) ([]int, error) {
+	var keys []float64
+	switch col.DataType().ID() {
+	case arrow.TIMESTAMP, arrow.DATE32, arrow.DATE64, arrow.TIME32, arrow.TIME64, arrow.DURATION:
+		ints, err := compute.CastArray(ctx, col, compute.UnsafeCastOptions(arrow.PrimitiveTypes.Int64))
+		if err != nil {
+			return nil, fmt.Errorf("cast %s to int64: %w", col.DataType(), err)
+		}
+		defer ints.Release()
+		keys = make([]float64, col.Len())
+		for i, v := range ints.(*array.Int64).Int64Values() {
+			keys[i] = float64(v)
+		}
+	default:
+		f, err := castFloat64(ctx, col)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Release()
+		keys = append([]float64(nil), f.Float64Values()...)
+	}
+	order := make([]int, col.Len())
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ra, rb := order[a], order[b]
+		if col.IsNull(ra) || col.IsNull(rb) {
+			return col.IsValid(ra) && col.IsNull(rb)
+		}
+		return keys[ra] < keys[rb]
+	})
+	return order, nil
+}
+
+// takeFloat64 gathers col at the given row indices.
+func takeFloat64(ctx context.Context, col *array.Float64, rows []int) (*array.Float64, error) {
+	b := array.NewInt64Builder(compute.GetAllocator(ctx))
+	defer b.Release()
+	for _, r := range rows {
+		b.Append(int64(r))
+	}
+	idx := b.NewInt64Array()
+	defer idx.Release()
+	out, err := compute.TakeArray(ctx, col, idx)
+	if err != nil {
+		return nil, fmt.Errorf("reorder values: %w", err)
+	}
+	return out.(*array.Float64), nil
+}
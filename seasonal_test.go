@@ -0,0 +1,93 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// dailySeries returns ten days of hourly values with a strong daily cycle, a
+// gentle trend and a small anomaly at row spike that is well inside the
+// overall range of the series.
+func dailySeries(spike int) []float64 {
+	r := rand.New(rand.NewSource(1))
+	vals := make([]float64, 240)
+	for i := range vals {
+		vals[i] = 100 + 50*math.Sin(2*math.Pi*float64(i)/24) + 0.1*float64(i) + r.NormFloat64()
+	}
+	vals[spike] += 25
+	return vals
+}
+
+func TestSeasonalDetector(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	b.AppendValues(dailySeries(100), nil)
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	plain, err := MADDetector{Threshold: 5}.Detect(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Release()
+	if plain.Mask.Value(100) {
+		t.Fatalf("spike should be hidden by seasonality without decomposition")
+	}
+
+	res, err := SeasonalDetector{Period: 24, Residual: MADDetector{Threshold: 5}}.Detect(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if res.Indices.Len() != 1 || res.Indices.Value(0) != 100 {
+		t.Errorf("indices = %v, want [100]", res.Indices)
+	}
+	if res.Values.Value(0) != col.Value(100) {
+		t.Errorf("value = %v, want original %v", res.Values.Value(0), col.Value(100))
+	}
+
+	if _, err := (SeasonalDetector{Period: 200}).Detect(ctx, col); err == nil {
+		t.Errorf("expected error for series shorter than two periods")
+	}
+}
+
+func TestDetectAnomaliesSeasonal(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "ts", Type: arrow.FixedWidthTypes.Timestamp_s},
+		{Name: "value", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	rb := array.NewRecordBuilder(pool, schema)
+	defer rb.Release()
+	// Rows arrive newest first.
+	vals := dailySeries(100)
+	for i := len(vals) - 1; i >= 0; i-- {
+		rb.Field(0).(*array.TimestampBuilder).Append(arrow.Timestamp(1700000000 + 3600*i))
+		rb.Field(1).(*array.Float64Builder).Append(vals[i])
+	}
+	rec := rb.NewRecord()
+	defer rec.Release()
+
+	res, err := DetectAnomaliesSeasonal(ctx, rec, "value", "ts", 24, MADDetector{Threshold: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	if want := int64(len(vals) - 1 - 100); res.Indices.Len() != 1 || res.Indices.Value(0) != want {
+		t.Errorf("indices = %v, want [%d]", res.Indices, want)
+	}
+}
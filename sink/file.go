@@ -0,0 +1,39 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// File appends each event as a line of JSON to the file at Path, creating
+// it if needed. The file is opened for every Send, so it may be rotated
+// between calls.
+type File struct {
+	Path string
+}
+
+// Send implements Sink. The lines of one call are written together.
+func (f File) Send(ctx context.Context, events []Event) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	out, err := os.OpenFile(f.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("write to %s: %w", f.Path, err)
+	}
+	if _, err := out.Write(buf.Bytes()); err != nil {
+		out.Close()
+		return fmt.Errorf("write to %s: %w", f.Path, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("write to %s: %w", f.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package sink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Kafka publishes each event as a JSON message to a topic.
+type Kafka struct {
+	Writer *kafka.Writer
+}
+
+// Send implements Sink.
+func (k Kafka) Send(ctx context.Context, events []Event) error {
+	msgs := make([]kafka.Message, len(events))
+	for i, e := range events {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		msgs[i] = kafka.Message{Value: b}
+	}
+	if err := k.Writer.WriteMessages(ctx, msgs...); err != nil {
+		return fmt.Errorf("write to %s: %w", k.Writer.Topic, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package sink
+
+import (
+	"context"
+	"net/http"
+)
+
+// PagerDutyURL is the PagerDuty Events API v2 endpoint.
+const PagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers one PagerDuty incident per Send through the Events API
+// v2, with the events attached as custom details.
+type PagerDuty struct {
+	RoutingKey string
+	// Source names the monitored system in the incident; empty means
+	// "supercharged".
+	Source string
+	// Severity is critical, error, warning or info; empty means warning.
+	Severity string
+	// URL overrides PagerDutyURL.
+	URL string
+	// Client sends the request; nil means http.DefaultClient.
+	Client *http.Client
+}
+
+// Send implements Sink.
+func (p PagerDuty) Send(ctx context.Context, events []Event) error {
+	source, severity, url := p.Source, p.Severity, p.URL
+	if source == "" {
+		source = "supercharged"
+	}
+	if severity == "" {
+		severity = "warning"
+	}
+	if url == "" {
+		url = PagerDutyURL
+	}
+	return postJSON(ctx, p.Client, url, map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"payload": map[string]any{
+			"summary":        summary(events),
+			"source":         source,
+			"severity":       severity,
+			"timestamp":      events[0].Time,
+			"custom_details": map[string]any{"anomalies": events},
+		},
+	})
+}
@@ -0,0 +1,106 @@
+// Package sink delivers detected anomalies to external systems such as
+// webhooks, Slack and PagerDuty.
+package sink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Event is one flagged row.
+type Event struct {
+	// Column is the scored column and Row its position in the input, or
+	// among the rows seen so far for streaming sources.
+	Column string    `json:"column"`
+	Row    int64     `json:"row"`
+	Value  float64   `json:"value"`
+	Score  float64   `json:"score"`
+	Mean   float64   `json:"mean"`
+	StdDev float64   `json:"stddev"`
+	Time   time.Time `json:"time"`
+}
+
+// Sink delivers a group of events: a single anomaly, or every anomaly of a
+// batch, depending on how the caller notifies. events is never empty.
+type Sink interface {
+	Send(ctx context.Context, events []Event) error
+}
+
+// Multi sends to each of its sinks in turn and joins their errors, so one
+// failing destination does not stop the others.
+type Multi []Sink
+
+// Send implements Sink.
+func (m Multi) Send(ctx context.Context, events []Event) error {
+	var errs []error
+	for _, s := range m {
+		errs = append(errs, s.Send(ctx, events))
+	}
+	return errors.Join(errs...)
+}
+
+// Webhook POSTs {"anomalies": [...]} as JSON to URL.
+type Webhook struct {
+	URL string
+	// Client sends the request; nil means http.DefaultClient.
+	Client *http.Client
+}
+
+// Send implements Sink.
+func (w Webhook) Send(ctx context.Context, events []Event) error {
+	return postJSON(ctx, w.Client, w.URL, map[string]any{"anomalies": events})
+}
+
+// postJSON POSTs body as JSON to url and fails on a non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, url string, body any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("post %s: %w", url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("post %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post %s: %s: %s", url, resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// summary describes events in one line, for chat and paging services.
+func summary(events []Event) string {
+	if len(events) == 1 {
+		e := events[0]
+		return fmt.Sprintf("anomaly in %s at row %d: value=%v score=%.2f", e.Column, e.Row, e.Value, e.Score)
+	}
+	worst := events[0]
+	for _, e := range events[1:] {
+		if abs(e.Score) > abs(worst.Score) {
+			worst = e
+		}
+	}
+	return fmt.Sprintf("%d anomalies in %s, worst at row %d: value=%v score=%.2f", len(events), worst.Column, worst.Row, worst.Value, worst.Score)
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,151 @@
+package sink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var events = []Event{
+	{Column: "v", Row: 3, Value: 90, Score: 4.5, Mean: 2, StdDev: 1, Time: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	{Column: "v", Row: 7, Value: -80, Score: -6.25, Mean: 2, StdDev: 1, Time: time.Date(2024, 3, 1, 12, 0, 1, 0, time.UTC)},
+}
+
+// receiver is an httptest server that answers with status and keeps the
+// decoded bodies it was sent.
+func receiver(t *testing.T, status int) (*httptest.Server, *[]map[string]json.RawMessage) {
+	t.Helper()
+	var bodies []map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		var body map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+		io.WriteString(w, "  rejected\n")
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func TestWebhook(t *testing.T) {
+	srv, bodies := receiver(t, http.StatusAccepted)
+	if err := (Webhook{URL: srv.URL}).Send(context.Background(), events); err != nil {
+		t.Fatal(err)
+	}
+	if len(*bodies) != 1 {
+		t.Fatalf("got %d requests, want 1", len(*bodies))
+	}
+	var got []Event
+	if err := json.Unmarshal((*bodies)[0]["anomalies"], &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, events) {
+		t.Errorf("got  %+v\nwant %+v", got, events)
+	}
+}
+
+func TestWebhookErrors(t *testing.T) {
+	srv, _ := receiver(t, http.StatusInternalServerError)
+	err := Webhook{URL: srv.URL}.Send(context.Background(), events)
+	if err == nil || !strings.Contains(err.Error(), "500 Internal Server Error: rejected") {
+		t.Errorf("got %v, want the status and body", err)
+	}
+
+	srv.Close()
+	if err := (Webhook{URL: srv.URL}).Send(context.Background(), events); err == nil {
+		t.Error("closed server: want error")
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.ndjson")
+	f := File{Path: path}
+	// Later calls append to the file.
+	for _, batch := range [][]Event{events[:1], events[1:]} {
+		if err := f.Send(context.Background(), batch); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	var got []Event
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		var e Event
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("%v in %s", err, sc.Text())
+		}
+		got = append(got, e)
+	}
+	if !reflect.DeepEqual(got, events) {
+		t.Errorf("got  %+v\nwant %+v", got, events)
+	}
+
+	bad := File{Path: filepath.Join(t.TempDir(), "missing", "alerts.ndjson")}
+	if err := bad.Send(context.Background(), events); err == nil {
+		t.Error("missing directory: want error")
+	}
+}
+
+func TestSlackAndPagerDuty(t *testing.T) {
+	srv, bodies := receiver(t, http.StatusOK)
+	ctx := context.Background()
+	if err := (Slack{WebhookURL: srv.URL}).Send(ctx, events); err != nil {
+		t.Fatal(err)
+	}
+	if err := (PagerDuty{RoutingKey: "key", URL: srv.URL}).Send(ctx, events[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	var text string
+	json.Unmarshal((*bodies)[0]["text"], &text)
+	want := ":rotating_light: 2 anomalies in v, worst at row 7: value=-80 score=-6.25\n" +
+		"• row 3: value=90 score=4.50\n• row 7: value=-80 score=-6.25"
+	if text != want {
+		t.Errorf("slack text = %q, want %q", text, want)
+	}
+
+	var payload struct {
+		Summary  string `json:"summary"`
+		Source   string `json:"source"`
+		Severity string `json:"severity"`
+	}
+	json.Unmarshal((*bodies)[1]["payload"], &payload)
+	if payload.Summary != "anomaly in v at row 3: value=90 score=4.50" || payload.Source != "supercharged" || payload.Severity != "warning" {
+		t.Errorf("pagerduty payload = %+v", payload)
+	}
+}
+
+func TestMulti(t *testing.T) {
+	// A failing sink does not stop the ones after it.
+	path := filepath.Join(t.TempDir(), "alerts.ndjson")
+	srv, bodies := receiver(t, http.StatusBadGateway)
+	err := Multi{Webhook{URL: srv.URL}, File{Path: path}}.Send(context.Background(), events)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("got %v, want the webhook's error", err)
+	}
+	if len(*bodies) != 1 {
+		t.Errorf("got %d webhook requests, want 1", len(*bodies))
+	}
+	if b, err := os.ReadFile(path); err != nil || strings.Count(string(b), "\n") != 2 {
+		t.Errorf("file = %q, %v, want two lines", b, err)
+	}
+}
@@ -0,0 +1,34 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// maxSlackLines caps the rows listed in one Slack message.
+const maxSlackLines = 20
+
+// Slack posts a message to a Slack incoming webhook.
+type Slack struct {
+	WebhookURL string
+	// Client sends the request; nil means http.DefaultClient.
+	Client *http.Client
+}
+
+// Send implements Sink.
+func (s Slack) Send(ctx context.Context, events []Event) error {
+	var text strings.Builder
+	text.WriteString(":rotating_light: " + summary(events))
+	if len(events) > 1 {
+		for i, e := range events {
+			if i == maxSlackLines {
+				fmt.Fprintf(&text, "\n… and %d more", len(events)-i)
+				break
+			}
+			fmt.Fprintf(&text, "\n• row %d: value=%v score=%.2f", e.Row, e.Value, e.Score)
+		}
+	}
+	return postJSON(ctx, s.Client, s.WebhookURL, map[string]string{"text": text.String()})
+}
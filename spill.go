@@ -0,0 +1,60 @@
+package supercharged
+
+import (
+	"os"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+
+	"github.com/TFMV/supercharged/internal/mmap"
+)
+
+// canSpill reports whether spill is available: it needs a memory mapping.
+const canSpill = mmap.Supported
+
+// spill writes arr to a temporary Arrow IPC file and returns it read back
+// from a memory mapping of the file, so its buffers live in the page cache
+// instead of the heap, and the func that unmaps it, to call once nothing
+// uses the array or arrays sharing its buffers. The file is removed at once;
+// the mapping keeps its pages until unmapped.
+func spill(arr arrow.Array) (arrow.Array, func(), error) {
+	f, err := os.CreateTemp("", "supercharged-spill-*.arrow")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	defer os.Remove(f.Name())
+
+	schema := arrow.NewSchema([]arrow.Field{{Name: "spill", Type: arr.DataType(), Nullable: true}}, nil)
+	rec := array.NewRecord(schema, []arrow.Array{arr}, int64(arr.Len()))
+	defer rec.Release()
+	w, err := ipc.NewFileWriter(f, ipc.WithSchema(schema))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := w.Write(rec); err != nil {
+		w.Close()
+		return nil, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, nil, err
+	}
+	data, unmap, err := mmap.Map(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := ipc.NewMappedFileReader(data)
+	if err == nil {
+		rec, err = r.RecordAt(0)
+		r.Close()
+	}
+	if err != nil {
+		unmap()
+		return nil, nil, err
+	}
+	defer rec.Release()
+	mapped := rec.Column(0)
+	mapped.Retain()
+	return mapped, func() { unmap() }, nil
+}
@@ -0,0 +1,119 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+)
+
+// Summary holds descriptive statistics over the non-null values of a column.
+// Variance and StdDev are population statistics.
+type Summary struct {
+	Count    int64   `json:"count"`
+	Nulls    int64   `json:"nulls"`
+	NaNs     int64   `json:"nans"`
+	Infs     int64   `json:"infs"`
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"`
+	StdDev   float64 `json:"stddev"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+}
+
+// Stats computes a Summary of col in a single numerically stable pass.
+// Non-Float64 numeric columns are cast first. NaN and Inf values are counted
+// and otherwise ignored, like nulls. Min and Max are NaN when the column has
+// no finite values.
+func Stats(col arrow.Array) (Summary, error) {
+	floatCol, err := toFloat64(context.Background(), col)
+	if err != nil {
+		return Summary{}, err
+	}
+	defer floatCol.Release()
+
+	sum := Summary{Min: math.NaN(), Max: math.NaN()}
+	var s runningStats
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			switch nullKindOf(floatCol, i) {
+			case kindNaN:
+				sum.NaNs++
+			case kindInf:
+				sum.Infs++
+			default:
+				sum.Nulls++
+			}
+			continue
+		}
+		v := floatCol.Value(i)
+		if s.n == 0 || v < sum.Min {
+			sum.Min = v
+		}
+		if s.n == 0 || v > sum.Max {
+			sum.Max = v
+		}
+		s.add(v)
+	}
+	sum.Count = s.n
+	sum.Mean = s.mean
+	sum.Variance = s.variance()
+	sum.StdDev = s.stdDev()
+	return sum, nil
+}
+
+// accumulate folds the non-null values of col into a runningStats.
+func accumulate(col *array.Float64) runningStats {
+	var s runningStats
+	for i := 0; i < col.Len(); i++ {
+		if col.IsValid(i) {
+			s.add(col.Value(i))
+		}
+	}
+	return s
+}
+
+// runningStats accumulates count, mean and the sum of squared deviations
+// (M2) using Welford's online algorithm, so values can be folded in one at a
+// time without a second pass.
+type runningStats struct {
+	n    int64
+	mean float64
+	m2   float64
+}
+
+func (s *runningStats) add(x float64) {
+	s.n++
+	delta := x - s.mean
+	s.mean += delta / float64(s.n)
+	s.m2 += delta * (x - s.mean)
+}
+
+// merge combines two partial accumulators (Chan et al.'s parallel update).
+func (s *runningStats) merge(o runningStats) {
+	if o.n == 0 {
+		return
+	}
+	if s.n == 0 {
+		*s = o
+		return
+	}
+	n := s.n + o.n
+	delta := o.mean - s.mean
+	s.mean += delta * float64(o.n) / float64(n)
+	s.m2 += o.m2 + delta*delta*float64(s.n)*float64(o.n)/float64(n)
+	s.n = n
+}
+
+// variance returns the population variance.
+func (s *runningStats) variance() float64 {
+	if s.n == 0 {
+		return 0
+	}
+	return s.m2 / float64(s.n)
+}
+
+func (s *runningStats) stdDev() float64 {
+	return math.Sqrt(s.variance())
+}
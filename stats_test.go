@@ -0,0 +1,45 @@
+package supercharged
+
+import (
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestStats(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	b := array.NewInt64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]int64{4, 7, 13, 16}, nil)
+	b.AppendNull()
+	col := b.NewArray()
+	defer col.Release()
+
+	s, err := Stats(col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Summary{Count: 4, Nulls: 1, Mean: 10, Variance: 22.5, StdDev: math.Sqrt(22.5), Min: 4, Max: 16}
+	if s != want {
+		t.Errorf("Stats = %+v, want %+v", s, want)
+	}
+}
+
+func TestComputeMeanAndVarianceLargeOffset(t *testing.T) {
+	// The naive sum-of-squares formula loses all precision here.
+	pool := memory.NewGoAllocator()
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	for _, v := range []float64{4, 7, 13, 16} {
+		b.Append(1e9 + v)
+	}
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	mean, variance := computeMeanAndVariance(col)
+	if mean != 1e9+10 || math.Abs(variance-22.5) > 1e-6 {
+		t.Errorf("got mean=%v variance=%v, want %v and 22.5", mean, variance, 1e9+10)
+	}
+}
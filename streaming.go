@@ -0,0 +1,82 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/arrow-go/v18/arrow"
+)
+
+// StreamState is a snapshot of a streaming detector's statistics, for
+// checkpointing a long-running stream and resuming it after a restart
+// without reading its history again. It encodes as JSON.
+type StreamState struct {
+	Count int64   `json:"count"`
+	Mean  float64 `json:"mean"`
+	M2    float64 `json:"m2"`
+	// Digest is the quantile sketch of StreamingPercentileDetector.
+	Digest *TDigest `json:"digest,omitempty"`
+}
+
+// StreamingDetector scores record batches of an unbounded input one at a time.
+// It keeps running statistics (Welford's online mean and variance) across
+// batches, so memory use is independent of the total input size.
+type StreamingDetector struct {
+	Column    string
+	Threshold float64
+
+	stats runningStats
+}
+
+// NewStreamingDetector returns a StreamingDetector for the named Float64 column.
+func NewStreamingDetector(column string, threshold float64) *StreamingDetector {
+	return &StreamingDetector{Column: column, Threshold: threshold}
+}
+
+// Update folds the batch into the running statistics and returns a Result for
+// the batch's rows, scored against the statistics of everything seen so far
+// (including the batch itself). Until the values seen so far vary, the rows
+// are left unscored and unflagged. The caller must Release the Result.
+func (d *StreamingDetector) Update(ctx context.Context, rec arrow.Record) (*Result, error) {
+	idx := rec.Schema().FieldIndices(d.Column)
+	if len(idx) == 0 {
+		return nil, fmt.Errorf("column %s not found", d.Column)
+	}
+	col := rec.Column(idx[0])
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+
+	d.stats.merge(accumulate(floatCol))
+	scale := d.stats.stdDev()
+	if scale == 0 {
+		return unscored(ctx, floatCol, d.stats)
+	}
+	return scoreAgainst(ctx, floatCol, d.stats.mean, scale, d.Threshold, d.stats)
+}
+
+// Count returns the number of non-null values seen so far.
+func (d *StreamingDetector) Count() int64 { return d.stats.n }
+
+// State returns a snapshot of the running statistics.
+func (d *StreamingDetector) State() StreamState {
+	return StreamState{Count: d.stats.n, Mean: d.stats.mean, M2: d.stats.m2}
+}
+
+// Restore replaces the running statistics with a snapshot taken by State,
+// so scoring continues where an earlier detector left off.
+func (d *StreamingDetector) Restore(s StreamState) error {
+	if s.Count < 0 || s.M2 < 0 {
+		return fmt.Errorf("invalid stream state: count %d, M2 %v", s.Count, s.M2)
+	}
+	d.stats = runningStats{n: s.Count, mean: s.Mean, m2: s.M2}
+	return nil
+}
+
+// Mean returns the running mean.
+func (d *StreamingDetector) Mean() float64 { return d.stats.mean }
+
+// StdDev returns the running population standard deviation.
+func (d *StreamingDetector) StdDev() float64 { return d.stats.stdDev() }
@@ -0,0 +1,135 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func float64Record(t *testing.T, name string, vals []float64) arrow.Record {
+	t.Helper()
+	pool := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{{Name: name, Type: arrow.PrimitiveTypes.Float64}}, nil)
+	b := array.NewRecordBuilder(pool, schema)
+	defer b.Release()
+	b.Field(0).(*array.Float64Builder).AppendValues(vals, nil)
+	return b.NewRecord()
+}
+
+func TestStreamingDetector(t *testing.T) {
+	d := NewStreamingDetector("v", 2.5)
+	batches := [][]float64{
+		{10, 11, 9, 10, 12},
+		{8, 10, 11, 9, 10},
+		{11, 9, 100, 10, 10},
+	}
+	var flagged []int
+	offset := 0
+	for _, vals := range batches {
+		rec := float64Record(t, "v", vals)
+		res, err := d.Update(context.Background(), rec)
+		rec.Release()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < res.Mask.Len(); i++ {
+			if res.Mask.Value(i) {
+				flagged = append(flagged, offset+i)
+			}
+		}
+		offset += len(vals)
+		res.Release()
+	}
+	if len(flagged) != 1 || flagged[0] != 12 {
+		t.Errorf("flagged = %v, want [12]", flagged)
+	}
+	if d.Count() != 15 {
+		t.Errorf("count = %d, want 15", d.Count())
+	}
+}
+
+func TestStreamingDetectorConstantStart(t *testing.T) {
+	// A one-row batch and repeats of it do not vary, so are left unscored
+	// rather than failing; scoring starts once the values spread.
+	d := NewStreamingDetector("v", 2.5)
+	for _, vals := range [][]float64{{5}, {5, 5, 5}, {5, 6, 4, 5, 100}} {
+		rec := float64Record(t, "v", vals)
+		res, err := d.Update(context.Background(), rec)
+		rec.Release()
+		if err != nil {
+			t.Fatalf("%v: %v", vals, err)
+		}
+		if len(vals) < 5 && (res.Zscore.NullN() != len(vals) || res.Indices.Len() != 0) {
+			t.Errorf("%v: got %d null scores and %d flagged, want all null and none flagged", vals, res.Zscore.NullN(), res.Indices.Len())
+		}
+		if len(vals) == 5 && (res.Zscore.NullN() != 0 || res.Indices.Len() != 1 || res.Indices.Value(0) != 4) {
+			t.Errorf("%v: got %d null scores and flagged %v, want row 4", vals, res.Zscore.NullN(), res.Indices.Int64Values())
+		}
+		res.Release()
+	}
+}
+
+func TestRunningStatsMerge(t *testing.T) {
+	var a, b, all runningStats
+	for i := 0; i < 100; i++ {
+		x := float64(i*i%17) + 1e6
+		all.add(x)
+		if i < 40 {
+			a.add(x)
+		} else {
+			b.add(x)
+		}
+	}
+	a.merge(b)
+	if a.n != all.n || math.Abs(a.mean-all.mean) > 1e-9 || math.Abs(a.variance()-all.variance()) > 1e-6 {
+		t.Errorf("merged = %+v, want %+v", a, all)
+	}
+}
+
+func TestStreamingDetectorRestore(t *testing.T) {
+	batches := [][]float64{
+		{10, 11, 9, 10, 12},
+		{8, 10, 11, 9, 10},
+		{11, 9, 100, 10, 10},
+	}
+	whole := NewStreamingDetector("v", 2.5)
+	first := NewStreamingDetector("v", 2.5)
+	resumed := NewStreamingDetector("v", 2.5)
+	update := func(d *StreamingDetector, vals []float64) []bool {
+		rec := float64Record(t, "v", vals)
+		defer rec.Release()
+		res, err := d.Update(context.Background(), rec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Release()
+		mask := make([]bool, res.Mask.Len())
+		for i := range mask {
+			mask[i] = res.Mask.Value(i)
+		}
+		return mask
+	}
+	for _, vals := range batches[:2] {
+		update(whole, vals)
+		update(first, vals)
+	}
+	if err := resumed.Restore(first.State()); err != nil {
+		t.Fatal(err)
+	}
+	want, got := update(whole, batches[2]), update(resumed, batches[2])
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d flagged %v after restore, want %v", i, got[i], want[i])
+		}
+	}
+	if resumed.Count() != whole.Count() || resumed.Mean() != whole.Mean() || resumed.StdDev() != whole.StdDev() {
+		t.Errorf("restored stats %d/%v/%v, want %d/%v/%v", resumed.Count(), resumed.Mean(), resumed.StdDev(), whole.Count(), whole.Mean(), whole.StdDev())
+	}
+	if err := resumed.Restore(StreamState{Count: -1}); err == nil {
+		t.Error("expected an error for a negative count")
+	}
+}
@@ -2,7 +2,9 @@ package supercharged
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
@@ -10,10 +12,59 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/scalar"
 )
 
+// ErrZeroScale is returned by the batch detectors that fit one center and
+// scale to the whole column, such as ZScoreDetector, MADDetector and
+// IQRDetector, and by Fit, when the values do not vary and no score can be
+// computed. The streaming detectors leave rows unscored until the values
+// vary instead, and the rolling, Hampel, grouped and grouped-model
+// detectors do so row by row or group by group.
+var ErrZeroScale = errors.New("zero scale: the values do not vary")
+
+// checkScale fails with ErrZeroScale for a scale that scores cannot be
+// divided by.
+func checkScale(scale float64) error {
+	if scale == 0 {
+		return ErrZeroScale
+	}
+	return nil
+}
+
 // Result holds mask and z-scores for anomalies.
 type Result struct {
 	Mask   *array.Boolean
 	Zscore *array.Float64
+
+	// Indices holds the row positions of flagged values, in order, and
+	// Values the original values at those rows.
+	Indices *array.Int64
+	Values  *array.Float64
+
+	// PValues holds per-value p-values for detectors based on a
+	// significance test, such as GrubbsDetector and ESDDetector, and is nil
+	// otherwise.
+	PValues *array.Float64
+
+	// Mean and StdDev are the population statistics of the non-null input
+	// values (of the data seen so far, for streaming detection).
+	Mean   float64
+	StdDev float64
+
+	// Nulls, NaNs and Infs count the input values left out of the
+	// statistics for each reason.
+	Nulls int64
+	NaNs  int64
+	Infs  int64
+
+	// Inexact counts the int64 and uint64 input values float64 cannot hold
+	// exactly, which the statistics and scores see rounded unless
+	// WithExactIntegers is set. DetectAnomalies, DetectAnomaliesChunked,
+	// DetectChunked and Detect count them; a Detector's own Detect method
+	// does not.
+	Inexact int64
+
+	// scored is the column the scores were computed from, kept under
+	// withScored.
+	scored *array.Float64
 }
 
 // Release frees memory associated with the Result.
@@ -24,102 +75,239 @@ func (r *Result) Release() {
 	if r.Zscore != nil {
 		r.Zscore.Release()
 	}
+	if r.Indices != nil {
+		r.Indices.Release()
+	}
+	if r.Values != nil {
+		r.Values.Release()
+	}
+	if r.PValues != nil {
+		r.PValues.Release()
+	}
+	if r.scored != nil {
+		r.scored.Release()
+	}
 }
 
-// computeMeanAndVariance calculates mean and variance for a Float64 array
-func computeMeanAndVariance(col *array.Float64) (mean, variance float64) {
-	var sum, sumsq float64
-	var count int
-	for i := 0; i < col.Len(); i++ {
-		if col.IsNull(i) {
-			continue
+// FilterOriginal returns the values of col at the flagged rows, in order,
+// using the Arrow filter kernel; dictionary-encoded columns keep their
+// dictionary. col must have the length of the analyzed array; it may be the
+// original column before any cast, or any other column aligned with it. The
+// caller must Release the returned array.
+func (r *Result) FilterOriginal(ctx context.Context, col arrow.Array) (arrow.Array, error) {
+	if col.Len() != r.Mask.Len() {
+		return nil, fmt.Errorf("column has %d rows, result has %d", col.Len(), r.Mask.Len())
+	}
+	out, err := filterArray(ctx, col, r.Mask)
+	if err != nil {
+		return nil, fmt.Errorf("filter values: %w", err)
+	}
+	return out, nil
+}
+
+// AnomalousIndices returns the flagged row positions as a slice, ready for
+// compute.TakeArray or indexing into other data aligned with the input.
+func (r *Result) AnomalousIndices() []int64 {
+	return append([]int64(nil), r.Indices.Int64Values()...)
+}
+
+// newResult assembles a Result from the mask and scores computed over col,
+// resolving null rows by the null policy and extracting the flagged rows and
+// their raw values. It takes ownership of mask and zscore.
+func newResult(ctx context.Context, col *array.Float64, mask *array.Boolean, zscore *array.Float64, stats runningStats) (*Result, error) {
+	mask = maskNulls(ctx, col, mask)
+	res := &Result{Mask: mask, Zscore: zscore, Mean: stats.mean, StdDev: stats.stdDev()}
+	if keep, _ := ctx.Value(scoredKey{}).(bool); keep {
+		col.Retain()
+		res.scored = col
+	}
+	if col.NullN() > 0 {
+		for i := 0; i < col.Len(); i++ {
+			if col.IsNull(i) {
+				switch nullKindOf(col, i) {
+				case kindNaN:
+					res.NaNs++
+				case kindInf:
+					res.Infs++
+				default:
+					res.Nulls++
+				}
+			}
 		}
-		v := col.Value(i)
-		sum += v
-		sumsq += v * v
-		count++
-	}
-	if count == 0 {
-		return 0, 0
-	}
-	mean = sum / float64(count)
-	// Population variance: sum of squared differences from mean
-	variance = 0
-	for i := 0; i < col.Len(); i++ {
-		if col.IsNull(i) {
-			continue
+	}
+
+	if err := res.extractFlagged(ctx, col); err != nil {
+		res.Release()
+		return nil, err
+	}
+	return res, nil
+}
+
+// extractFlagged fills in the Indices of the rows r.Mask flags and their
+// Values taken from col, cast to float64 unless col is already.
+func (r *Result) extractFlagged(ctx context.Context, col arrow.Array) error {
+	indices := array.NewInt64Builder(compute.GetAllocator(ctx))
+	defer indices.Release()
+	for i := 0; i < r.Mask.Len(); i++ {
+		if r.Mask.IsValid(i) && r.Mask.Value(i) {
+			indices.Append(int64(i))
 		}
-		diff := col.Value(i) - mean
-		variance += diff * diff
 	}
-	variance /= float64(count)
-	return
+	r.Indices = indices.NewInt64Array()
+
+	values, err := filterArray(ctx, col, r.Mask)
+	if err != nil {
+		return fmt.Errorf("filter values: %w", err)
+	}
+	defer values.Release()
+	if r.Values, err = castFloat64(ctx, values); err != nil {
+		return err
+	}
+	return nil
 }
 
-// DetectAnomalies computes z-scores and a boolean mask using Arrow compute functions.
-func DetectAnomalies(ctx context.Context, col arrow.Array, threshold float64) (*Result, error) {
-	// Ensure we have a Float64 array
-	floatCol, ok := col.(*array.Float64)
-	if !ok {
-		return nil, fmt.Errorf("input must be Float64 array, got %T", col)
+// computeMeanAndVariance calculates mean and population variance for a
+// Float64 array in a single pass using Welford's algorithm, which stays
+// accurate when the mean is large relative to the spread.
+func computeMeanAndVariance(col *array.Float64) (mean, variance float64) {
+	s := accumulate(col)
+	return s.mean, s.variance()
+}
+
+// DetectAnomalies computes scores and a boolean mask using Arrow compute
+// functions. By default it flags values whose absolute z-score is at least
+// DefaultThreshold; see Option for the available settings. With
+// WithParallelism the array is split across workers.
+func DetectAnomalies(ctx context.Context, col arrow.Array, opts ...Option) (*Result, error) {
+	o := newOptions(opts)
+	d, err := o.detector()
+	if err != nil {
+		return nil, err
 	}
+	ctx, end := traceDetect(o.context(ctx), "DetectAnomalies", d, col.Len())
+	res, err := detectArray(ctx, d, col, o.parallelism)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	end(res.Indices.Len(), nil)
+	return res, nil
+}
 
-	// 1. Compute mean and variance manually
-	mean, variance := computeMeanAndVariance(floatCol)
+// detectArray runs d over col, split across workers when there are several,
+// within the limit of ContextWithMemoryLimit if ctx carries one.
+func detectArray(ctx context.Context, d Detector, col arrow.Array, workers int) (*Result, error) {
+	if err := checkMemory(ctx, int64(col.Len())*scoreBytesPerRow); err != nil {
+		return nil, err
+	}
+	var (
+		res *Result
+		err error
+	)
+	if _, ok := d.(fitter); ok && workers > 1 && col.Len() > 0 {
+		res, err = detectSplit(ctx, d, col, workers)
+	} else {
+		res, err = d.Detect(ctx, col)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkMemory(ctx, 0); err != nil {
+		res.Release()
+		return nil, err
+	}
+	res.Inexact = inexactInts(col)
+	return res, nil
+}
+
+// ZScoreDetector flags values whose absolute z-score is at least Threshold.
+type ZScoreDetector struct {
+	Threshold float64
+}
 
-	// 2. Create scalars for broadcasting
-	meanScalar := scalar.NewFloat64Scalar(mean)
-	varianceScalar := scalar.NewFloat64Scalar(variance)
+// Detect implements Detector.
+func (d ZScoreDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	if f, ok := nativeFloat32(col); ok {
+		stats := accumulate32(f)
+		return scoreFloat32(ctx, f, stats.mean, math.Sqrt(stats.variance()), d.Threshold, stats)
+	}
 
-	// 3. Compute standard deviation using Arrow compute
-	stdDevResult, err := compute.CallFunction(ctx, "sqrt", nil, compute.NewDatum(varianceScalar))
+	// Ensure we have a Float64 array, casting other numeric types
+	floatCol, err := toFloat64(ctx, col)
 	if err != nil {
-		return nil, fmt.Errorf("sqrt computation: %w", err)
+		return nil, err
+	}
+	defer floatCol.Release()
+
+	if exactIntegersFrom(ctx) && wideInt(col.DataType()) {
+		var s exactSums
+		s.add(col)
+		fit := d.fitExact(&s, int64(floatCol.Len()-floatCol.NullN()))
+		return scoreExact(ctx, col, floatCol, &s, fit)
 	}
-	defer stdDevResult.Release()
 
-	stdDevDatum := stdDevResult.(*compute.ScalarDatum)
-	stdDev := stdDevDatum.Value.(*scalar.Float64).Value
-	stdDevScalar := scalar.NewFloat64Scalar(stdDev)
+	// 1. Compute mean and variance manually
+	stats := accumulate(floatCol)
+
+	// 2-3. The standard deviation is a single value, not worth a compute
+	// kernel call
+	stdDev := math.Sqrt(stats.variance())
+
+	return scoreAgainst(ctx, floatCol, stats.mean, stdDev, d.Threshold, stats)
+}
+
+// scoreAgainst standardizes col as (x - center) / scale and flags values
+// whose absolute score is at least threshold. stats describes col for the
+// Result. Under ContextWithFastPath it defers to scoreDirect. A zero scale
+// fails with ErrZeroScale on either path.
+func scoreAgainst(ctx context.Context, col *array.Float64, center, scale, threshold float64, stats runningStats) (*Result, error) {
+	if err := checkScale(scale); err != nil {
+		return nil, err
+	}
+	if fastPathFrom(ctx) {
+		return scoreDirect(ctx, col, center, scale, threshold, stats)
+	}
+	meanScalar := scalar.NewFloat64Scalar(center)
+	stdDevScalar := scalar.NewFloat64Scalar(scale)
 
 	// 4. Subtract mean from each value
-	diffResult, err := compute.CallFunction(ctx, "subtract", nil, compute.NewDatum(col), compute.NewDatum(meanScalar))
+	colDatum := compute.NewDatum(col)
+	defer colDatum.Release()
+	diffResult, err := compute.CallFunction(ctx, "subtract", nil, colDatum, compute.NewDatum(meanScalar))
 	if err != nil {
 		return nil, fmt.Errorf("subtract computation: %w", err)
 	}
-	defer diffResult.Release()
 
-	// 5. Divide by standard deviation to get z-scores
+	// 5. Divide by standard deviation to get z-scores; the differences are
+	// not needed past this point
 	zscoreResult, err := compute.CallFunction(ctx, "divide", nil, diffResult, compute.NewDatum(stdDevScalar))
+	diffResult.Release()
 	if err != nil {
 		return nil, fmt.Errorf("divide computation: %w", err)
 	}
-
-	// 6. Take absolute value of z-scores
-	absResult, err := compute.CallFunction(ctx, "abs", nil, zscoreResult)
-	if err != nil {
-		return nil, fmt.Errorf("abs computation: %w", err)
-	}
-	defer absResult.Release()
+	defer zscoreResult.Release()
 
 	// Get z-scores array
 	zscoreDatum := zscoreResult.(*compute.ArrayDatum)
 	zscore := array.MakeFromData(zscoreDatum.Value).(*array.Float64)
 
-	// 7. Compare with threshold using Arrow compute
-	thresholdScalar := scalar.NewFloat64Scalar(threshold)
-	compResult, err := compute.CallFunction(ctx, "greater_equal", nil, absResult, compute.NewDatum(thresholdScalar))
-	if err != nil {
-		return nil, fmt.Errorf("threshold comparison: %w", err)
-	}
-	defer compResult.Release()
+	// 6-7. Flag |z| >= threshold in one pass, without materializing the
+	// absolute values
+	mask := thresholdMask(ctx, zscore, threshold)
 
-	// Get the boolean mask from the comparison result
-	maskDatum := compResult.(*compute.ArrayDatum)
-	mask := array.MakeFromData(maskDatum.Value).(*array.Boolean)
+	return newResult(ctx, col, mask, zscore, stats)
+}
 
-	return &Result{
-		Mask:   mask,
-		Zscore: zscore,
-	}, nil
+// unscored returns a Result for col with null scores and nothing flagged,
+// for a stream whose values have not varied yet. stats describes the
+// stream for the Result.
+func unscored(ctx context.Context, col *array.Float64, stats runningStats) (*Result, error) {
+	mem := compute.GetAllocator(ctx)
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	scores.AppendNulls(col.Len())
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	mask.AppendValues(make([]bool, col.Len()), nil)
+	return newResult(ctx, col, mask.NewBooleanArray(), scores.NewFloat64Array(), stats)
 }
@@ -12,6 +12,32 @@ import (
 
 // BenchmarkDetectAnomalies benchmarks the anomaly detection on synthetic data
 func BenchmarkDetectAnomalies(b *testing.B) {
+	benchmarkDetectAnomalies(b, WithThreshold(2.5))
+}
+
+// BenchmarkDetectAnomaliesFastPath is BenchmarkDetectAnomalies with the
+// single-loop kernel in place of the Arrow compute chain.
+func BenchmarkDetectAnomaliesFastPath(b *testing.B) {
+	benchmarkDetectAnomalies(b, WithThreshold(2.5), WithFastPath(true))
+}
+
+// BenchmarkPipeline is BenchmarkDetectAnomalies through a Pipeline built
+// once, as serve does, rather than options resolved per call.
+func BenchmarkPipeline(b *testing.B) {
+	p, err := NewPipeline(WithThreshold(2.5))
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkDetect(b, p.Detect)
+}
+
+func benchmarkDetectAnomalies(b *testing.B, opts ...Option) {
+	benchmarkDetect(b, func(ctx context.Context, col arrow.Array) (*Result, error) {
+		return DetectAnomalies(ctx, col, opts...)
+	})
+}
+
+func benchmarkDetect(b *testing.B, detect func(context.Context, arrow.Array) (*Result, error)) {
 	sizes := []int{1_000, 10_000, 100_000, 1_000_000}
 	for _, size := range sizes {
 		b.Run(fmt.Sprintf("Size_%d", size), func(b *testing.B) {
@@ -38,7 +64,7 @@ func BenchmarkDetectAnomalies(b *testing.B) {
 			b.ResetTimer()
 
 			for b.Loop() {
-				result, err := DetectAnomalies(ctx, data, 2.5)
+				result, err := detect(ctx, data)
 				if err != nil {
 					b.Fatalf("error: %v", err)
 				}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 )
 
@@ -18,15 +19,143 @@ func TestDetectAnomalies(t *testing.T) {
 	col := vals.NewFloat64Array()
 	defer col.Release()
 
-	res, err := DetectAnomalies(context.Background(), col, 1.99)
+	res, err := DetectAnomalies(context.Background(), col, WithThreshold(1.99))
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer res.Mask.Release()
-	defer res.Zscore.Release()
+	defer res.Release()
 
 	// Expect the '100' to be flagged
 	if !res.Mask.Value(3) {
 		t.Errorf("expected index 3 to be anomalous")
 	}
 }
+
+func TestZScoreDetectorImplementsDetector(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	vals := array.NewFloat64Builder(pool)
+	defer vals.Release()
+	vals.AppendValues([]float64{1, 2, 3, 100, 2}, nil)
+	col := vals.NewFloat64Array()
+	defer col.Release()
+
+	var d Detector = ZScoreDetector{Threshold: 1.99}
+	res, err := d.Detect(context.Background(), col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	for i := 0; i < res.Mask.Len(); i++ {
+		if want := i == 3; res.Mask.Value(i) != want {
+			t.Errorf("mask[%d] = %t, want %t", i, res.Mask.Value(i), want)
+		}
+	}
+}
+
+func TestDetectAnomaliesOptions(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	vals := array.NewFloat64Builder(pool)
+	defer vals.Release()
+	vals.AppendValues([]float64{10, 11, 9, 10, 12, 8, 10, 500, 11, 9}, nil)
+	col := vals.NewFloat64Array()
+	defer col.Release()
+
+	for _, m := range []Method{MethodZScore, MethodMAD, MethodIQR} {
+		res, err := DetectAnomalies(context.Background(), col,
+			WithMethod(m), WithThreshold(2.5), WithAllocator(pool))
+		if err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+		if !res.Mask.Value(7) {
+			t.Errorf("%s: expected index 7 to be anomalous", m)
+		}
+		res.Release()
+	}
+
+	if _, err := DetectAnomalies(context.Background(), col, WithMethod("bogus")); err == nil {
+		t.Errorf("expected error for unknown method")
+	}
+}
+
+func TestResultIndicesAndValues(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	vals := array.NewFloat64Builder(pool)
+	defer vals.Release()
+	vals.AppendValues([]float64{10, 11, 9, 10, 12, 8, 10, 500, 11, 9}, nil)
+	vals.AppendNull()
+	col := vals.NewFloat64Array()
+	defer col.Release()
+
+	res, err := DetectAnomalies(context.Background(), col, WithThreshold(2.5), WithAllocator(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	if res.Indices.Len() != 1 || res.Indices.Value(0) != 7 {
+		t.Fatalf("indices = %v, want [7]", res.Indices)
+	}
+	if res.Values.Len() != 1 || res.Values.Value(0) != 500 {
+		t.Fatalf("values = %v, want [500]", res.Values)
+	}
+	want, err := Stats(col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Mean != want.Mean || res.StdDev != want.StdDev {
+		t.Errorf("mean/stddev = %v/%v, want %v/%v", res.Mean, res.StdDev, want.Mean, want.StdDev)
+	}
+}
+
+func TestResultFilterOriginal(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	ints := array.NewInt32Builder(pool)
+	defer ints.Release()
+	ints.AppendValues([]int32{10, 11, 9, 10, 12, 8, 10, 500, 11, 9}, nil)
+	col := ints.NewInt32Array()
+	defer col.Release()
+	names := array.NewStringBuilder(pool)
+	defer names.Release()
+	names.AppendValues([]string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}, nil)
+	labels := names.NewStringArray()
+	defer labels.Release()
+
+	res, err := DetectAnomalies(ctx, col, WithThreshold(2.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	orig, err := res.FilterOriginal(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer orig.Release()
+	if v := orig.(*array.Int32); v.Len() != 1 || v.Value(0) != 500 {
+		t.Errorf("original values = %v, want [500]", v)
+	}
+	label, err := res.FilterOriginal(ctx, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer label.Release()
+	if v := label.(*array.String); v.Len() != 1 || v.Value(0) != "h" {
+		t.Errorf("labels = %v, want [h]", v)
+	}
+	if idx := res.AnomalousIndices(); len(idx) != 1 || idx[0] != 7 {
+		t.Errorf("indices = %v, want [7]", idx)
+	}
+	short := array.NewSlice(col, 0, 3)
+	defer short.Release()
+	if _, err := res.FilterOriginal(ctx, short); err == nil {
+		t.Errorf("expected error for misaligned column")
+	}
+}
@@ -0,0 +1,242 @@
+package supercharged
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// DefaultCompression is the t-digest compression used when none is given.
+// Larger values keep more centroids and give more accurate quantiles.
+const DefaultCompression = 100
+
+type centroid struct {
+	Mean   float64 `json:"mean"`
+	Weight float64 `json:"weight"`
+}
+
+// TDigest is a merging t-digest: a compact sketch of a distribution that
+// estimates quantiles with bounded memory and particularly good accuracy in
+// the tails. It accepts values one at a time, so it suits streaming inputs.
+type TDigest struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	count       float64
+	min, max    float64
+}
+
+// NewTDigest returns an empty digest. A compression of zero or less means
+// DefaultCompression.
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &TDigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// Add records a single value. NaN values are ignored.
+func (t *TDigest) Add(x float64) {
+	if math.IsNaN(x) {
+		return
+	}
+	t.buffer = append(t.buffer, centroid{Mean: x, Weight: 1})
+	t.count++
+	t.min = math.Min(t.min, x)
+	t.max = math.Max(t.max, x)
+	if len(t.buffer) >= int(8*t.compression) {
+		t.compress()
+	}
+}
+
+// Merge folds the contents of other into t.
+func (t *TDigest) Merge(other *TDigest) {
+	if other == nil || other.count == 0 {
+		return
+	}
+	t.buffer = append(t.buffer, other.centroids...)
+	t.buffer = append(t.buffer, other.buffer...)
+	t.count += other.count
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+	t.compress()
+}
+
+// tdigestJSON is the encoding of a TDigest; the count is the sum of the
+// centroid weights.
+type tdigestJSON struct {
+	Compression float64    `json:"compression"`
+	Centroids   []centroid `json:"centroids"`
+	Min         float64    `json:"min,omitempty"`
+	Max         float64    `json:"max,omitempty"`
+}
+
+// MarshalJSON encodes the digest's centroids, compressing buffered values
+// into them first.
+func (t *TDigest) MarshalJSON() ([]byte, error) {
+	t.compress()
+	enc := tdigestJSON{Compression: t.compression, Centroids: t.centroids}
+	if t.count > 0 {
+		enc.Min, enc.Max = t.min, t.max
+	}
+	return json.Marshal(enc)
+}
+
+// UnmarshalJSON decodes a digest written by MarshalJSON.
+func (t *TDigest) UnmarshalJSON(data []byte) error {
+	var enc tdigestJSON
+	if err := json.Unmarshal(data, &enc); err != nil {
+		return err
+	}
+	d := NewTDigest(enc.Compression)
+	for _, c := range enc.Centroids {
+		if c.Weight <= 0 {
+			return fmt.Errorf("t-digest centroid with weight %v", c.Weight)
+		}
+		d.count += c.Weight
+	}
+	sort.Slice(enc.Centroids, func(i, j int) bool { return enc.Centroids[i].Mean < enc.Centroids[j].Mean })
+	d.centroids = enc.Centroids
+	if d.count > 0 {
+		d.min, d.max = enc.Min, enc.Max
+	}
+	*t = *d
+	return nil
+}
+
+// clone returns a copy of t that shares no memory with it.
+func (t *TDigest) clone() *TDigest {
+	c := *t
+	c.centroids = append([]centroid(nil), t.centroids...)
+	c.buffer = append([]centroid(nil), t.buffer...)
+	return &c
+}
+
+// Count returns the number of values added.
+func (t *TDigest) Count() int64 {
+	return int64(t.count)
+}
+
+// Quantile estimates the q-th quantile (0 <= q <= 1). It returns NaN for an
+// empty digest.
+func (t *TDigest) Quantile(q float64) float64 {
+	t.compress()
+	n := len(t.centroids)
+	switch {
+	case n == 0:
+		return math.NaN()
+	case q <= 0:
+		return t.min
+	case q >= 1:
+		return t.max
+	case n == 1:
+		return t.centroids[0].Mean
+	}
+
+	index := q * t.count
+	first := t.centroids[0]
+	if index < first.Weight/2 {
+		return t.min + (index/(first.Weight/2))*(first.Mean-t.min)
+	}
+	last := t.centroids[n-1]
+	if index >= t.count-last.Weight/2 {
+		tail := (index - (t.count - last.Weight/2)) / (last.Weight / 2)
+		return last.Mean + tail*(t.max-last.Mean)
+	}
+
+	// Walk the centroid midpoints and interpolate between neighbours.
+	cum := first.Weight / 2
+	for i := 0; i < n-1; i++ {
+		a, b := t.centroids[i], t.centroids[i+1]
+		step := (a.Weight + b.Weight) / 2
+		if index < cum+step {
+			return a.Mean + (index-cum)/step*(b.Mean-a.Mean)
+		}
+		cum += step
+	}
+	return last.Mean
+}
+
+// CDF estimates the fraction of the values added that are at most x, the
+// inverse of Quantile. It returns NaN for an empty digest.
+func (t *TDigest) CDF(x float64) float64 {
+	t.compress()
+	n := len(t.centroids)
+	switch {
+	case n == 0:
+		return math.NaN()
+	case x < t.min:
+		return 0
+	case x >= t.max:
+		return 1
+	case n == 1:
+		return (x - t.min) / (t.max - t.min)
+	}
+
+	first := t.centroids[0]
+	if x < first.Mean {
+		return (x - t.min) / (first.Mean - t.min) * first.Weight / 2 / t.count
+	}
+	last := t.centroids[n-1]
+	if x >= last.Mean {
+		tail := (x - last.Mean) / (t.max - last.Mean)
+		return (t.count - last.Weight/2 + tail*last.Weight/2) / t.count
+	}
+
+	// Walk the centroid midpoints as Quantile does and interpolate.
+	cum := first.Weight / 2
+	for i := 0; i < n-1; i++ {
+		a, b := t.centroids[i], t.centroids[i+1]
+		step := (a.Weight + b.Weight) / 2
+		if x < b.Mean {
+			return (cum + (x-a.Mean)/(b.Mean-a.Mean)*step) / t.count
+		}
+		cum += step
+	}
+	return 1
+}
+
+// compress merges buffered values into the centroid list, keeping each
+// centroid within the size bound implied by the k1 scale function.
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	t.buffer = t.buffer[:0]
+	sort.Slice(all, func(i, j int) bool { return all[i].Mean < all[j].Mean })
+
+	out := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	var soFar float64
+	limit := t.weightLimit(0)
+	for _, c := range all[1:] {
+		proposed := cur.Weight + c.Weight
+		if soFar+proposed <= limit {
+			cur.Mean += (c.Mean - cur.Mean) * c.Weight / proposed
+			cur.Weight = proposed
+			continue
+		}
+		soFar += cur.Weight
+		out = append(out, cur)
+		limit = t.weightLimit(soFar)
+		cur = c
+	}
+	t.centroids = append(out, cur)
+}
+
+// weightLimit returns the cumulative weight up to which the centroid starting
+// at soFar may grow.
+func (t *TDigest) weightLimit(soFar float64) float64 {
+	q := soFar / t.count
+	k := t.compression/(2*math.Pi)*math.Asin(2*q-1) + 1
+	if k >= t.compression/4 {
+		return t.count
+	}
+	return (math.Sin(k*2*math.Pi/t.compression) + 1) / 2 * t.count
+}
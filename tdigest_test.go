@@ -0,0 +1,112 @@
+package supercharged
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestTDigestQuantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	td := NewTDigest(0)
+	vals := make([]float64, 100_000)
+	for i := range vals {
+		vals[i] = rng.NormFloat64()
+		td.Add(vals[i])
+	}
+	sort.Float64s(vals)
+
+	for _, q := range []float64{0.001, 0.01, 0.25, 0.5, 0.75, 0.99, 0.999} {
+		// Compare in rank space: the estimate should sit near the q-th rank.
+		got := td.Quantile(q)
+		rank := float64(sort.SearchFloat64s(vals, got)) / float64(len(vals))
+		if math.Abs(rank-q) > 0.001 {
+			t.Errorf("quantile(%v) = %v has rank %v", q, got, rank)
+		}
+	}
+	if td.Count() != int64(len(vals)) {
+		t.Errorf("count = %d, want %d", td.Count(), len(vals))
+	}
+}
+
+func TestTDigestCDF(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	td := NewTDigest(0)
+	vals := make([]float64, 100_000)
+	for i := range vals {
+		vals[i] = rng.NormFloat64()
+		td.Add(vals[i])
+	}
+	td.Add(50)
+	vals = append(vals, 50)
+	sort.Float64s(vals)
+
+	for _, x := range []float64{-3, -1, 0, 0.5, 2, 3} {
+		want := float64(sort.SearchFloat64s(vals, x)) / float64(len(vals))
+		if got := td.CDF(x); math.Abs(got-want) > 0.001 {
+			t.Errorf("cdf(%v) = %v, want %v", x, got, want)
+		}
+	}
+	// The tails stay exact enough to see a single outlier.
+	if got := td.CDF(49); got >= 1 || got < 1-2.0/float64(len(vals)) {
+		t.Errorf("cdf(49) = %v, want just under 1", got)
+	}
+	if td.CDF(-100) != 0 || td.CDF(50) != 1 {
+		t.Error("want 0 below the minimum and 1 at the maximum")
+	}
+	if got := NewTDigest(0).CDF(0); !math.IsNaN(got) {
+		t.Errorf("empty digest: got %v, want NaN", got)
+	}
+}
+
+func TestTDigestMerge(t *testing.T) {
+	a, b := NewTDigest(0), NewTDigest(0)
+	for i := 0; i < 1000; i++ {
+		a.Add(float64(i))
+		b.Add(float64(i + 1000))
+	}
+	a.Merge(b)
+	if got := a.Quantile(0.5); math.Abs(got-1000) > 10 {
+		t.Errorf("median after merge = %v, want ~1000", got)
+	}
+	if a.Quantile(0) != 0 || a.Quantile(1) != 1999 {
+		t.Errorf("extremes = %v, %v", a.Quantile(0), a.Quantile(1))
+	}
+}
+
+func TestTDigestJSON(t *testing.T) {
+	d := NewTDigest(50)
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 5000; i++ {
+		d.Add(rng.NormFloat64())
+	}
+	data, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got TDigest
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Count() != d.Count() {
+		t.Errorf("count = %d, want %d", got.Count(), d.Count())
+	}
+	for _, q := range []float64{0, 0.01, 0.5, 0.99, 1} {
+		if got.Quantile(q) != d.Quantile(q) {
+			t.Errorf("quantile %v = %v, want %v", q, got.Quantile(q), d.Quantile(q))
+		}
+	}
+
+	data, err = json.Marshal(NewTDigest(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Count() != 0 || !math.IsNaN(got.Quantile(0.5)) {
+		t.Errorf("empty digest round trip: count %d, median %v", got.Count(), got.Quantile(0.5))
+	}
+}
@@ -0,0 +1,78 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName names the tracer and meter DetectAnomalies,
+// DetectAnomaliesChunked, DetectChunked and Detect report through. They use the
+// global OpenTelemetry providers, which are no-ops until the application
+// installs an SDK.
+const InstrumentationName = "github.com/TFMV/supercharged"
+
+var (
+	tracer = otel.Tracer(InstrumentationName)
+	meter  = otel.Meter(InstrumentationName)
+
+	rowsCounter, _ = meter.Int64Counter("supercharged.detect.rows",
+		metric.WithDescription("Values scored for anomalies."), metric.WithUnit("{row}"))
+	anomaliesCounter, _ = meter.Int64Counter("supercharged.detect.anomalies",
+		metric.WithDescription("Values flagged as anomalies."), metric.WithUnit("{row}"))
+	durationHistogram, _ = meter.Float64Histogram("supercharged.detect.duration",
+		metric.WithDescription("Time spent in one detection."), metric.WithUnit("s"))
+)
+
+// Detect runs d over col with the span and metrics DetectAnomalies records,
+// for detectors built directly rather than through options.
+func Detect(ctx context.Context, d Detector, col arrow.Array) (*Result, error) {
+	ctx, end := traceDetect(ctx, "Detect", d, col.Len())
+	res, err := detectArray(ctx, d, col, 1)
+	if err != nil {
+		end(0, err)
+		return nil, err
+	}
+	end(res.Indices.Len(), nil)
+	return res, nil
+}
+
+// traceDetect starts a span named name for d over rows values. The returned
+// function ends it, recording the anomaly count or err, and adds the run to
+// the detection metrics.
+func traceDetect(ctx context.Context, name string, d Detector, rows int) (context.Context, func(anomalies int, err error)) {
+	attrs := []attribute.KeyValue{attribute.String("supercharged.detector", detectorName(d))}
+	ctx, span := tracer.Start(ctx, name, trace.WithAttributes(append(attrs, attribute.Int("supercharged.rows", rows))...))
+	start := time.Now()
+	return ctx, func(anomalies int, err error) {
+		defer span.End()
+		set := metric.WithAttributes(attrs...)
+		durationHistogram.Record(ctx, time.Since(start).Seconds(), set)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return
+		}
+		span.SetAttributes(attribute.Int("supercharged.anomalies", anomalies))
+		rowsCounter.Add(ctx, int64(rows), set)
+		anomaliesCounter.Add(ctx, int64(anomalies), set)
+	}
+}
+
+// detectorName is the type name of d without its package, e.g.
+// "MADDetector".
+func detectorName(d Detector) string {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", d), "*")
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,44 @@
+package supercharged
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestDetectAnomaliesTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	defer otel.SetTracerProvider(prev)
+
+	b := array.NewFloat64Builder(memory.DefaultAllocator)
+	defer b.Release()
+	b.AppendValues([]float64{1, 2, 1, 2, 1, 2, 1, 2, 1, 50}, nil)
+	arr := b.NewFloat64Array()
+	defer arr.Release()
+
+	res, err := DetectAnomalies(context.Background(), arr, WithMethod(MethodMAD))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Release()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Name != "DetectAnomalies" {
+		t.Fatalf("spans = %v, want one DetectAnomalies span", spans)
+	}
+	attrs := map[string]any{}
+	for _, kv := range spans[0].Attributes {
+		attrs[string(kv.Key)] = kv.Value.AsInterface()
+	}
+	if attrs["supercharged.detector"] != "MADDetector" || attrs["supercharged.rows"] != int64(10) || attrs["supercharged.anomalies"] != int64(1) {
+		t.Errorf("attributes = %v", attrs)
+	}
+}
@@ -0,0 +1,190 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// TimeFormat names how ParseTimes reads a column: as a count of seconds,
+// milliseconds, microseconds or nanoseconds since the Unix epoch, as RFC 3339
+// strings, or, for any other value, as strings in that Go time layout such
+// as "02/01/2006 15:04". The empty format reads the strings Result.Points
+// does.
+type TimeFormat string
+
+// Supported time formats besides layouts.
+const (
+	EpochSeconds TimeFormat = "epoch_s"
+	EpochMillis  TimeFormat = "epoch_ms"
+	EpochMicros  TimeFormat = "epoch_us"
+	EpochNanos   TimeFormat = "epoch_ns"
+	RFC3339      TimeFormat = "rfc3339"
+)
+
+// TimestampType is the type of the columns ParseTimes returns.
+var TimestampType = &arrow.TimestampType{Unit: arrow.Nanosecond, TimeZone: "UTC"}
+
+// epochUnit returns the length of f's unit, or zero when f is not an epoch
+// format.
+func (f TimeFormat) epochUnit() time.Duration {
+	switch f {
+	case EpochSeconds, "epoch":
+		return time.Second
+	case EpochMillis:
+		return time.Millisecond
+	case EpochMicros:
+		return time.Microsecond
+	case EpochNanos:
+		return time.Nanosecond
+	}
+	return 0
+}
+
+// ParseTimes returns col read in format f as a TimestampType column. Epoch
+// formats take integer, floating-point or numeric string columns; the others
+// take strings. Timestamp and date columns are converted whatever f is.
+// Naive times, that is strings without a zone, dates and timestamps without
+// a time zone, are wall-clock times in loc, or UTC when loc is nil. Nulls
+// stay null, and a value that does not parse is an error. The caller must
+// Release the result.
+func ParseTimes(ctx context.Context, col arrow.Array, f TimeFormat, loc *time.Location) (arrow.Array, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	bld := array.NewTimestampBuilder(compute.GetAllocator(ctx), TimestampType)
+	defer bld.Release()
+	bld.Reserve(col.Len())
+
+	unit := f.epochUnit()
+	id := col.DataType().ID()
+	var ints *array.Int64
+	var floats *array.Float64
+	switch {
+	case id == arrow.TIMESTAMP || id == arrow.DATE32 || id == arrow.DATE64:
+		unit = 0
+	case unit > 0 && arrow.IsInteger(id):
+		out, err := compute.CastArray(ctx, col, compute.SafeCastOptions(arrow.PrimitiveTypes.Int64))
+		if err != nil {
+			return nil, fmt.Errorf("cast %s to int64: %w", col.DataType(), err)
+		}
+		defer out.Release()
+		ints = out.(*array.Int64)
+	case unit > 0 && arrow.IsFloating(id):
+		out, err := castFloat64(ctx, col)
+		if err != nil {
+			return nil, err
+		}
+		defer out.Release()
+		floats = out
+	case id != arrow.STRING && id != arrow.LARGE_STRING:
+		return nil, fmt.Errorf("cannot read %s column as %s times", col.DataType(), f)
+	}
+
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			bld.AppendNull()
+			continue
+		}
+		var (
+			ns  int64
+			err error
+		)
+		switch {
+		case ints != nil:
+			ns, err = epochInt(ints.Value(i), unit)
+		case floats != nil:
+			ns, err = epochFloat(floats.Value(i), unit)
+		case unit > 0:
+			ns, err = epochString(stringAt(col, i), unit)
+		default:
+			ns, err = timeNanos(col, i, f, loc)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		bld.Append(arrow.Timestamp(ns))
+	}
+	return bld.NewArray(), nil
+}
+
+func stringAt(col arrow.Array, i int) string {
+	if c, ok := col.(*array.LargeString); ok {
+		return c.Value(i)
+	}
+	return col.(*array.String).Value(i)
+}
+
+// epochInt returns v units in nanoseconds.
+func epochInt(v int64, unit time.Duration) (int64, error) {
+	if n := int64(unit); v > math.MaxInt64/n || v < math.MinInt64/n {
+		return 0, fmt.Errorf("epoch value %d out of range", v)
+	}
+	return v * int64(unit), nil
+}
+
+// epochFloat returns v units in nanoseconds, rounded.
+func epochFloat(v float64, unit time.Duration) (int64, error) {
+	ns := math.Round(v * float64(unit))
+	if math.IsNaN(ns) || ns >= math.MaxInt64 || ns < math.MinInt64 {
+		return 0, fmt.Errorf("epoch value %v out of range", v)
+	}
+	return int64(ns), nil
+}
+
+func epochString(s string, unit time.Duration) (int64, error) {
+	s = strings.TrimSpace(s)
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return epochInt(v, unit)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("cannot parse %q as an epoch time", s)
+	}
+	return epochFloat(v, unit)
+}
+
+// timeNanos returns the time at row i of col in nanoseconds, parsing
+// strings in format f and taking naive times in loc.
+func timeNanos(col arrow.Array, i int, f TimeFormat, loc *time.Location) (int64, error) {
+	var (
+		t   time.Time
+		err error
+	)
+	switch col.DataType().ID() {
+	case arrow.STRING, arrow.LARGE_STRING:
+		s := stringAt(col, i)
+		if f == "" {
+			if t, err = parseTimeIn(strings.TrimSpace(s), loc); err != nil {
+				return 0, err
+			}
+			break
+		}
+		layout := string(f)
+		if f == RFC3339 {
+			layout = time.RFC3339Nano
+		}
+		if t, err = time.ParseInLocation(layout, strings.TrimSpace(s), loc); err != nil {
+			return 0, fmt.Errorf("cannot parse %q as %s", s, f)
+		}
+	default:
+		if t, err = timeAt(col, i); err != nil {
+			return 0, err
+		}
+		if ts, ok := col.DataType().(*arrow.TimestampType); !ok || ts.TimeZone == "" {
+			// timeAt reads the wall clock as UTC.
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+		}
+	}
+	if t.Year() < 1678 || t.Year() > 2261 {
+		return 0, fmt.Errorf("time %s out of range", t.Format(time.RFC3339))
+	}
+	return t.UnixNano(), nil
+}
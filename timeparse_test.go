@@ -0,0 +1,118 @@
+package supercharged
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestParseTimes(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+
+	ib := array.NewInt64Builder(pool)
+	defer ib.Release()
+	ib.AppendValues([]int64{1767225600000, 1767225660500}, nil)
+	ib.AppendNull()
+	millis := ib.NewArray()
+	defer millis.Release()
+
+	fb := array.NewFloat64Builder(pool)
+	defer fb.Release()
+	fb.AppendValues([]float64{1767225600, 1767225660.5}, nil)
+	seconds := fb.NewArray()
+	defer seconds.Release()
+
+	sb := array.NewStringBuilder(pool)
+	defer sb.Release()
+	sb.AppendValues([]string{"01/01/2026 00:00", "01/01/2026 00:01"}, nil)
+	layout := sb.NewArray()
+	defer layout.Release()
+	sb.AppendValues([]string{"2026-01-01T01:00:00+01:00", "2026-01-01T00:01:00.5Z"}, nil)
+	rfc := sb.NewArray()
+	defer rfc.Release()
+	sb.AppendValues([]string{"1767225600", "1767225660.5"}, nil)
+	epochStrings := sb.NewArray()
+	defer epochStrings.Release()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ctx := compute.WithAllocator(context.Background(), pool)
+	tests := []struct {
+		name   string
+		col    arrow.Array
+		format TimeFormat
+		want   []time.Duration
+	}{
+		{"epoch_ms", millis, EpochMillis, []time.Duration{0, time.Minute + 500*time.Millisecond}},
+		{"epoch_s float", seconds, EpochSeconds, []time.Duration{0, time.Minute + 500*time.Millisecond}},
+		{"epoch strings", epochStrings, "epoch", []time.Duration{0, time.Minute + 500*time.Millisecond}},
+		{"layout", layout, "02/01/2006 15:04", []time.Duration{0, time.Minute}},
+		{"rfc3339", rfc, RFC3339, []time.Duration{0, time.Minute + 500*time.Millisecond}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := ParseTimes(ctx, tt.col, tt.format, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Release()
+			if !arrow.TypeEqual(out.DataType(), TimestampType) {
+				t.Fatalf("type %s, want %s", out.DataType(), TimestampType)
+			}
+			if out.Len() != tt.col.Len() || out.NullN() != tt.col.NullN() {
+				t.Fatalf("got %d rows with %d nulls, want %d with %d", out.Len(), out.NullN(), tt.col.Len(), tt.col.NullN())
+			}
+			ts := out.(*array.Timestamp)
+			for i, want := range tt.want {
+				if got := ts.Value(i).ToTime(arrow.Nanosecond); !got.Equal(start.Add(want)) {
+					t.Errorf("row %d: got %v, want %v", i, got, start.Add(want))
+				}
+			}
+		})
+	}
+
+	// Naive times are wall-clock times in loc; zoned ones keep their instant.
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	tb := array.NewTimestampBuilder(pool, &arrow.TimestampType{Unit: arrow.Second})
+	defer tb.Release()
+	tb.Append(arrow.Timestamp(start.Unix()))
+	naive := tb.NewArray()
+	defer naive.Release()
+	sb.AppendValues([]string{"2026-01-01 00:00:00", "2026-01-01T00:00:00Z"}, nil)
+	mixed := sb.NewArray()
+	defer mixed.Release()
+	for _, tt := range []struct {
+		col  arrow.Array
+		want []time.Time
+	}{
+		{naive, []time.Time{start.Add(5 * time.Hour)}},
+		{mixed, []time.Time{start.Add(5 * time.Hour), start}},
+	} {
+		out, err := ParseTimes(ctx, tt.col, "", loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, want := range tt.want {
+			if got := out.(*array.Timestamp).Value(i).ToTime(arrow.Nanosecond); !got.Equal(want) {
+				t.Errorf("%s row %d in %s: got %v, want %v", tt.col.DataType(), i, loc, got, want)
+			}
+		}
+		out.Release()
+	}
+
+	if _, err := ParseTimes(ctx, layout, RFC3339, nil); err == nil || !strings.Contains(err.Error(), "row 0") {
+		t.Errorf("layout strings as RFC 3339: got %v, want an error for row 0", err)
+	}
+	if _, err := ParseTimes(ctx, seconds, "2006-01-02", nil); err == nil {
+		t.Error("floats read with a layout: want an error")
+	}
+}
@@ -0,0 +1,434 @@
+// Package transform rescales and transforms numeric Arrow columns as a
+// pre-processing step before detection: z-score standardization, robust
+// median/MAD scaling, min-max scaling, log and Box-Cox transforms, and
+// differencing and linear detrending of ordered series. Each returns a new
+// float64 array and leaves its input alone; Apply runs them over the columns
+// of a record, and Parse looks them up by name.
+package transform
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+
+	anomaly "github.com/TFMV/supercharged"
+)
+
+// Func transforms a numeric column into a new float64 array of the same
+// length. Null input rows are null in the output. The caller must Release
+// the result.
+type Func func(ctx context.Context, col arrow.Array) (*array.Float64, error)
+
+var (
+	_ Func = ZScore
+	_ Func = Robust
+	_ Func = MinMax
+	_ Func = Log
+	_ Func = Detrend
+)
+
+// Parse returns the transform named by spec: zscore, robust, minmax, log,
+// diff, diff2, detrend, or boxcox, either as boxcox=lambda or bare, which
+// estimates lambda from each column with BoxCoxLambda. Several specs
+// separated by commas are applied in order, e.g. "log,diff".
+func Parse(spec string) (Func, error) {
+	var fns []Func
+	for _, part := range strings.Split(spec, ",") {
+		name, arg, hasArg := strings.Cut(strings.TrimSpace(part), "=")
+		if hasArg && name != "boxcox" {
+			return nil, fmt.Errorf("transform %s takes no parameter", name)
+		}
+		var fn Func
+		switch name {
+		case "zscore":
+			fn = ZScore
+		case "robust":
+			fn = Robust
+		case "minmax":
+			fn = MinMax
+		case "log":
+			fn = Log
+		case "diff":
+			fn = Diff(1)
+		case "diff2":
+			fn = Diff(2)
+		case "detrend":
+			fn = Detrend
+		case "boxcox":
+			if !hasArg {
+				fn = boxCoxEstimated
+				break
+			}
+			lambda, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("boxcox lambda %q: %w", arg, err)
+			}
+			fn = BoxCox(lambda)
+		default:
+			return nil, fmt.Errorf("unknown transform %q", name)
+		}
+		fns = append(fns, fn)
+	}
+	if len(fns) == 1 {
+		return fns[0], nil
+	}
+	return chain(fns), nil
+}
+
+// chain applies fns in order.
+func chain(fns []Func) Func {
+	return func(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+		col.Retain()
+		for _, fn := range fns {
+			out, err := fn(ctx, col)
+			col.Release()
+			if err != nil {
+				return nil, err
+			}
+			col = out
+		}
+		return col.(*array.Float64), nil
+	}
+}
+
+// ZScore standardizes col as (x - mean) / stddev, using the population
+// statistics of its finite values. A column with no spread is only centered.
+func ZScore(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	return rescale(ctx, col, func(vals []float64) (float64, float64) {
+		var n, mean, m2 float64
+		for _, v := range vals {
+			n++
+			delta := v - mean
+			mean += delta / n
+			m2 += delta * (v - mean)
+		}
+		if n == 0 {
+			return 0, 1
+		}
+		return mean, math.Sqrt(m2 / n)
+	})
+}
+
+// Robust scales col as (x - median) / (anomaly.DefaultMADScale * MAD), the
+// robust z-score of anomaly.MADDetector, which outliers in col do not drag
+// the way they drag the mean and standard deviation. A column whose MAD is
+// zero is only centered.
+func Robust(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	return rescale(ctx, col, func(vals []float64) (float64, float64) {
+		if len(vals) == 0 {
+			return 0, 1
+		}
+		sorted := append([]float64(nil), vals...)
+		median := medianInPlace(sorted)
+		for i, v := range sorted {
+			sorted[i] = math.Abs(v - median)
+		}
+		return median, anomaly.DefaultMADScale * medianInPlace(sorted)
+	})
+}
+
+// MinMax scales col into [0, 1] as (x - min) / (max - min) over its finite
+// values. A column with no spread maps to 0.
+func MinMax(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	return rescale(ctx, col, func(vals []float64) (float64, float64) {
+		if len(vals) == 0 {
+			return 0, 1
+		}
+		lo, hi := vals[0], vals[0]
+		for _, v := range vals[1:] {
+			lo, hi = min(lo, v), max(hi, v)
+		}
+		return lo, hi - lo
+	})
+}
+
+// Log returns the natural logarithm of col, which must be positive; see
+// BoxCox.
+func Log(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	return BoxCox(0)(ctx, col)
+}
+
+// BoxCox returns the Box-Cox transform with parameter lambda,
+// (x^lambda - 1) / lambda, or ln(x) when lambda is 0, which makes right-skewed
+// positive data such as latencies or sizes closer to normal before scoring.
+// The transform fails on a zero or negative value; NaN stays NaN. See
+// BoxCoxLambda for estimating lambda.
+func BoxCox(lambda float64) Func {
+	return func(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+		in, err := toFloat64(ctx, col)
+		if err != nil {
+			return nil, err
+		}
+		defer in.Release()
+		if err := checkPositive(in); err != nil {
+			return nil, err
+		}
+		return apply(ctx, in, func(_ int, x float64) float64 { return boxCox(x, lambda) }), nil
+	}
+}
+
+// BoxCoxLambda estimates the Box-Cox parameter for col by maximum
+// likelihood, searching [-5, 5]. col must be positive, with at least two
+// distinct finite values.
+func BoxCoxLambda(ctx context.Context, col arrow.Array) (float64, error) {
+	in, err := toFloat64(ctx, col)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Release()
+	if err := checkPositive(in); err != nil {
+		return 0, err
+	}
+	vals := finiteValues(in)
+	if len(vals) < 2 || slices.Min(vals) == slices.Max(vals) {
+		return 0, fmt.Errorf("box-cox: need at least two distinct values")
+	}
+	var sumLog float64
+	for _, v := range vals {
+		sumLog += math.Log(v)
+	}
+	n := float64(len(vals))
+	// The log-likelihood of lambda, up to a constant.
+	llf := func(lambda float64) float64 {
+		var mean, m2, k float64
+		for _, v := range vals {
+			y := boxCox(v, lambda)
+			k++
+			delta := y - mean
+			mean += delta / k
+			m2 += delta * (y - mean)
+		}
+		return (lambda-1)*sumLog - n/2*math.Log(m2/n)
+	}
+	// Golden-section search; the log-likelihood is concave in lambda.
+	const phi = 0.6180339887498949
+	lo, hi := -5.0, 5.0
+	a, b := hi-phi*(hi-lo), lo+phi*(hi-lo)
+	fa, fb := llf(a), llf(b)
+	for hi-lo > 1e-6 {
+		if fa < fb {
+			lo, a, fa = a, b, fb
+			b = lo + phi*(hi-lo)
+			fb = llf(b)
+		} else {
+			hi, b, fb = b, a, fa
+			a = hi - phi*(hi-lo)
+			fa = llf(a)
+		}
+	}
+	return (lo + hi) / 2, nil
+}
+
+// boxCoxEstimated is BoxCox with the lambda BoxCoxLambda estimates for col.
+func boxCoxEstimated(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	lambda, err := BoxCoxLambda(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	return BoxCox(lambda)(ctx, col)
+}
+
+// Diff returns the transform taking differences of the given order along
+// the rows of an ordered column: x[i] - x[i-1] for order 1, and the
+// differences of those for order 2, which turns a linear or quadratic trend
+// into a constant, so a z-score sees the changes between rows rather than
+// the level. The output stays aligned with the input: its first order rows
+// are null, as is every row whose value or a predecessor's it depends on is.
+func Diff(order int) Func {
+	return func(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+		if order < 1 {
+			return nil, fmt.Errorf("difference order must be at least 1, got %d", order)
+		}
+		in, err := toFloat64(ctx, col)
+		if err != nil {
+			return nil, err
+		}
+		vals := slices.Clone(in.Float64Values())
+		valid := make([]bool, len(vals))
+		for i := range vals {
+			valid[i] = in.IsValid(i)
+		}
+		in.Release()
+		for range order {
+			for i := len(vals) - 1; i >= 0; i-- {
+				if i == 0 || !valid[i-1] {
+					valid[i] = false
+					continue
+				}
+				vals[i] -= vals[i-1]
+			}
+		}
+		b := array.NewFloat64Builder(compute.GetAllocator(ctx))
+		defer b.Release()
+		b.AppendValues(vals, valid)
+		return b.NewFloat64Array(), nil
+	}
+}
+
+// Detrend subtracts the least-squares line through the finite values of an
+// ordered column against their row numbers, leaving the deviations from a
+// linear trend. A column with fewer than two finite values is only
+// centered.
+func Detrend(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	in, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Release()
+	// Welford-style updates of the means and co-moments, which stay accurate
+	// for long series with a large offset.
+	var n, meanX, meanY, cxy, cxx float64
+	for i, v := range in.Float64Values() {
+		if !in.IsValid(i) || math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		n++
+		dx := float64(i) - meanX
+		meanX += dx / n
+		meanY += (v - meanY) / n
+		cxy += dx * (v - meanY)
+		cxx += dx * (float64(i) - meanX)
+	}
+	var slope float64
+	if cxx > 0 {
+		slope = cxy / cxx
+	}
+	intercept := meanY - slope*meanX
+	return apply(ctx, in, func(i int, x float64) float64 {
+		return x - (intercept + slope*float64(i))
+	}), nil
+}
+
+// Apply returns a copy of rec with each column named in fns replaced by its
+// transform, typed float64; the other columns are shared with rec. The
+// caller must Release the result.
+func Apply(ctx context.Context, rec arrow.Record, fns map[string]Func) (arrow.Record, error) {
+	for name := range fns {
+		if len(rec.Schema().FieldIndices(name)) == 0 {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+	}
+	fields := append([]arrow.Field(nil), rec.Schema().Fields()...)
+	cols := make([]arrow.Array, len(fields))
+	defer func() {
+		for _, c := range cols {
+			if c != nil {
+				c.Release()
+			}
+		}
+	}()
+	for i, f := range fields {
+		fn, ok := fns[f.Name]
+		if !ok {
+			cols[i] = rec.Column(i)
+			cols[i].Retain()
+			continue
+		}
+		out, err := fn(ctx, rec.Column(i))
+		if err != nil {
+			return nil, fmt.Errorf("transform %s: %w", f.Name, err)
+		}
+		cols[i] = out
+		fields[i].Type, fields[i].Nullable = arrow.PrimitiveTypes.Float64, true
+	}
+	meta := rec.Schema().Metadata()
+	schema := arrow.NewSchema(fields, &meta)
+	return array.NewRecord(schema, cols, rec.NumRows()), nil
+}
+
+// rescale maps col to (x - center) / scale for the center and scale stats
+// computes from its finite values. A zero scale, from a column without
+// spread, is taken as 1.
+func rescale(ctx context.Context, col arrow.Array, stats func(vals []float64) (center, scale float64)) (*array.Float64, error) {
+	in, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Release()
+	center, scale := stats(finiteValues(in))
+	if scale == 0 || math.IsNaN(scale) {
+		scale = 1
+	}
+	return apply(ctx, in, func(_ int, x float64) float64 { return (x - center) / scale }), nil
+}
+
+// apply returns f of each row number and value of col, keeping its nulls.
+func apply(ctx context.Context, col *array.Float64, f func(i int, x float64) float64) *array.Float64 {
+	b := array.NewFloat64Builder(compute.GetAllocator(ctx))
+	defer b.Release()
+	b.Reserve(col.Len())
+	for i, v := range col.Float64Values() {
+		if col.IsNull(i) {
+			b.AppendNull()
+			continue
+		}
+		b.UnsafeAppend(f(i, v))
+	}
+	return b.NewFloat64Array()
+}
+
+// toFloat64 returns col as a float64 array, casting integer, floating-point
+// and decimal columns with Arrow's cast kernel.
+func toFloat64(ctx context.Context, col arrow.Array) (*array.Float64, error) {
+	if f, ok := col.(*array.Float64); ok {
+		f.Retain()
+		return f, nil
+	}
+	id := col.DataType().ID()
+	if !arrow.IsInteger(id) && !arrow.IsFloating(id) && !arrow.IsDecimal(id) {
+		return nil, fmt.Errorf("unsupported array type %s", col.DataType())
+	}
+	// Unsafe: large integers lose precision rather than failing the cast.
+	out, err := compute.CastArray(ctx, col, compute.UnsafeCastOptions(arrow.PrimitiveTypes.Float64))
+	if err != nil {
+		return nil, fmt.Errorf("cast %s to float64: %w", col.DataType(), err)
+	}
+	return out.(*array.Float64), nil
+}
+
+// finiteValues copies the valid, finite values of col.
+func finiteValues(col *array.Float64) []float64 {
+	vals := make([]float64, 0, col.Len()-col.NullN())
+	for i, v := range col.Float64Values() {
+		if col.IsValid(i) && !math.IsNaN(v) && !math.IsInf(v, 0) {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+// checkPositive fails on the first valid value of col that is zero or
+// negative, which the log and Box-Cox transforms are not defined for.
+func checkPositive(col *array.Float64) error {
+	for i, v := range col.Float64Values() {
+		if col.IsValid(i) && v <= 0 {
+			return fmt.Errorf("row %d: value %v is not positive", i, v)
+		}
+	}
+	return nil
+}
+
+func boxCox(x, lambda float64) float64 {
+	if lambda == 0 {
+		return math.Log(x)
+	}
+	return (math.Pow(x, lambda) - 1) / lambda
+}
+
+// medianInPlace sorts vals and returns their median.
+func medianInPlace(vals []float64) float64 {
+	sort.Float64s(vals)
+	n := len(vals)
+	if n%2 == 1 {
+		return vals[n/2]
+	}
+	return (vals[n/2-1] + vals[n/2]) / 2
+}
@@ -0,0 +1,377 @@
+package transform
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+// null stands for a null row in the values given to floats and check.
+var null = math.Inf(-1)
+
+// floats builds a float64 array of vals, with rows equal to null left null.
+// The caller must Release it.
+func floats(mem memory.Allocator, vals ...float64) *array.Float64 {
+	b := array.NewFloat64Builder(mem)
+	defer b.Release()
+	for _, v := range vals {
+		if v == null {
+			b.AppendNull()
+		} else {
+			b.Append(v)
+		}
+	}
+	return b.NewFloat64Array()
+}
+
+// check reports the rows of got that differ from want by more than 1e-9,
+// null matching only a null row and NaN only NaN.
+func check(t *testing.T, name string, got *array.Float64, want ...float64) {
+	t.Helper()
+	if got.Len() != len(want) {
+		t.Errorf("%s: got %d rows, want %d", name, got.Len(), len(want))
+		return
+	}
+	for i, w := range want {
+		switch g := got.Value(i); {
+		case w == null:
+			if got.IsValid(i) {
+				t.Errorf("%s: row %d = %v, want null", name, i, g)
+			}
+		case got.IsNull(i):
+			t.Errorf("%s: row %d is null, want %v", name, i, w)
+		case math.IsNaN(w):
+			if !math.IsNaN(g) {
+				t.Errorf("%s: row %d = %v, want NaN", name, i, g)
+			}
+		case math.Abs(g-w) > 1e-9:
+			t.Errorf("%s: row %d = %v, want %v", name, i, g, w)
+		}
+	}
+}
+
+func TestRescale(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// Mean 5 and standard deviation 2; median 4.5 and MAD 0.5. The null and
+	// NaN rows are left out of the statistics and kept in the output.
+	col := floats(pool, 2, 4, 4, 4, 5, 5, 7, 9, null, math.NaN())
+	defer col.Release()
+	madScale := 1.4826 * 0.5
+	tests := []struct {
+		name string
+		fn   Func
+		want []float64
+	}{
+		{"zscore", ZScore, []float64{-1.5, -0.5, -0.5, -0.5, 0, 0, 1, 2, null, math.NaN()}},
+		{"robust", Robust, []float64{-2.5 / madScale, -0.5 / madScale, -0.5 / madScale, -0.5 / madScale,
+			0.5 / madScale, 0.5 / madScale, 2.5 / madScale, 4.5 / madScale, null, math.NaN()}},
+		{"minmax", MinMax, []float64{0, 2.0 / 7, 2.0 / 7, 2.0 / 7, 3.0 / 7, 3.0 / 7, 5.0 / 7, 1, null, math.NaN()}},
+	}
+	for _, tt := range tests {
+		got, err := tt.fn(ctx, col)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		check(t, tt.name, got, tt.want...)
+		got.Release()
+	}
+}
+
+func TestRescaleNoSpread(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// A constant or empty column is centered rather than divided by zero.
+	for _, vals := range [][]float64{{3, 3, null, 3}, {}} {
+		col := floats(pool, vals...)
+		for name, fn := range map[string]Func{"zscore": ZScore, "robust": Robust, "minmax": MinMax} {
+			got, err := fn(ctx, col)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			want := make([]float64, len(vals))
+			for i, v := range vals {
+				if v == null {
+					want[i] = null
+				}
+			}
+			check(t, name, got, want...)
+			got.Release()
+		}
+		col.Release()
+	}
+
+	// So is one whose MAD alone is zero.
+	col := floats(pool, 1, 1, 1, 1, 9)
+	defer col.Release()
+	got, err := Robust(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Release()
+	check(t, "robust zero mad", got, 0, 0, 0, 0, 8)
+}
+
+func TestTransformInts(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	b := array.NewInt64Builder(pool)
+	defer b.Release()
+	b.AppendValues([]int64{0, 5, 10}, []bool{true, false, true})
+	col := b.NewInt64Array()
+	defer col.Release()
+	got, err := MinMax(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Release()
+	check(t, "minmax", got, 0, null, 1)
+
+	sb := array.NewStringBuilder(pool)
+	defer sb.Release()
+	sb.Append("x")
+	str := sb.NewStringArray()
+	defer str.Release()
+	if _, err := ZScore(ctx, str); err == nil {
+		t.Error("string column: want error")
+	}
+}
+
+func TestBoxCox(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	col := floats(pool, 1, 4, null, math.E)
+	defer col.Release()
+	got, err := BoxCox(0.5)(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, "boxcox 0.5", got, 0, 2, null, 2*(math.Sqrt(math.E)-1))
+	got.Release()
+	got, err = Log(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, "log", got, 0, math.Log(4), null, 1)
+	got.Release()
+
+	bad := floats(pool, 1, 0, 2)
+	defer bad.Release()
+	if _, err := Log(ctx, bad); err == nil {
+		t.Error("zero value: want error")
+	}
+	if _, err := BoxCoxLambda(ctx, bad); err == nil {
+		t.Error("lambda of a zero value: want error")
+	}
+}
+
+func TestBoxCoxLambda(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// Log-normal data is made normal by the log, lambda 0, and squared
+	// normal data by the square root, lambda 0.5.
+	rng := rand.New(rand.NewPCG(1, 2))
+	logNormal := make([]float64, 5000)
+	squared := make([]float64, len(logNormal))
+	for i := range logNormal {
+		logNormal[i] = math.Exp(rng.NormFloat64())
+		squared[i] = math.Pow(10+rng.NormFloat64(), 2)
+	}
+	for _, tt := range []struct {
+		name string
+		vals []float64
+		want float64
+	}{
+		{"log-normal", logNormal, 0},
+		{"squared normal", squared, 0.5},
+	} {
+		col := floats(pool, tt.vals...)
+		lambda, err := BoxCoxLambda(ctx, col)
+		col.Release()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if math.Abs(lambda-tt.want) > 0.05 {
+			t.Errorf("%s: lambda = %v, want about %v", tt.name, lambda, tt.want)
+		}
+	}
+
+	col := floats(pool, 2, 2, null, 2)
+	defer col.Release()
+	if _, err := BoxCoxLambda(ctx, col); err == nil {
+		t.Error("constant column: want error")
+	}
+	if _, err := boxCoxEstimated(ctx, col); err == nil {
+		t.Error("estimated boxcox of a constant column: want error")
+	}
+}
+
+func TestParse(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	col := floats(pool, 1, math.E, null, math.E*math.E)
+	defer col.Release()
+	for spec, want := range map[string][]float64{
+		"log":            {0, 1, null, 2},
+		"boxcox=0":       {0, 1, null, 2},
+		" log , minmax ": {0, 0.5, null, 1},
+		"log,zscore":     {-1.224744871391589, 0, null, 1.224744871391589},
+	} {
+		fn, err := Parse(spec)
+		if err != nil {
+			t.Fatalf("%q: %v", spec, err)
+		}
+		got, err := fn(ctx, col)
+		if err != nil {
+			t.Fatalf("%q: %v", spec, err)
+		}
+		check(t, spec, got, want...)
+		got.Release()
+	}
+
+	for _, spec := range []string{"", "scale", "zscore=1", "boxcox=", "boxcox=x", "log,,zscore"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("%q: want error", spec)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	ib := array.NewInt64Builder(pool)
+	defer ib.Release()
+	ib.AppendValues([]int64{1, 2, 3}, nil)
+	a := ib.NewInt64Array()
+	defer a.Release()
+	b := floats(pool, 7, 8, 9)
+	defer b.Release()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "a", Type: arrow.PrimitiveTypes.Int64},
+		{Name: "b", Type: arrow.PrimitiveTypes.Float64},
+	}, nil)
+	rec := array.NewRecord(schema, []arrow.Array{a, b}, 3)
+	defer rec.Release()
+
+	out, err := Apply(ctx, rec, map[string]Func{"a": MinMax})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Release()
+	if !arrow.TypeEqual(out.Schema().Field(0).Type, arrow.PrimitiveTypes.Float64) || out.Column(1) != arrow.Array(b) {
+		t.Errorf("got schema %v", out.Schema())
+	}
+	check(t, "a", out.Column(0).(*array.Float64), 0, 0.5, 1)
+
+	if _, err := Apply(ctx, rec, map[string]Func{"c": MinMax}); err == nil {
+		t.Error("missing column: want error")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// The first order rows are null, as is every row depending on a null.
+	col := floats(pool, 1, 3, 6, null, 15, 21, 28)
+	defer col.Release()
+	squares := floats(pool, 0, 1, 4, 9, 16, 25)
+	defer squares.Release()
+	tests := []struct {
+		name  string
+		order int
+		col   *array.Float64
+		want  []float64
+	}{
+		{"diff", 1, col, []float64{null, 2, 3, null, null, 6, 7}},
+		{"diff2", 2, col, []float64{null, null, 1, null, null, null, 1}},
+		{"diff2 of squares", 2, squares, []float64{null, null, 2, 2, 2, 2}},
+	}
+	for _, tt := range tests {
+		got, err := Diff(tt.order)(ctx, tt.col)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		check(t, tt.name, got, tt.want...)
+		got.Release()
+	}
+
+	// The input is left alone.
+	check(t, "input", col, 1, 3, 6, null, 15, 21, 28)
+	if _, err := Diff(0)(ctx, col); err == nil {
+		t.Error("order 0: want error")
+	}
+}
+
+func TestDetrend(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+
+	// 1e6 + 2i with a spike of 10 at row 5; the null and NaN rows are left
+	// out of the fit and stay aligned.
+	vals := make([]float64, 12)
+	for i := range vals {
+		vals[i] = 1e6 + 2*float64(i)
+	}
+	line := floats(pool, vals...)
+	defer line.Release()
+	vals[3], vals[5], vals[8] = null, vals[5]+10, math.NaN()
+	col := floats(pool, vals...)
+	defer col.Release()
+
+	got, err := Detrend(ctx, line)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(t, "line", got, make([]float64, 12)...)
+	got.Release()
+
+	got, err = Detrend(ctx, col)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Release()
+	if !got.IsNull(3) || !math.IsNaN(got.Value(8)) {
+		t.Errorf("rows 3 and 8 = %v, %v; want null and NaN", got.Value(3), got.Value(8))
+	}
+	// The spike is the largest deviation, and the trend is gone around it.
+	for i := range vals {
+		if i == 3 || i == 8 {
+			continue
+		}
+		if d := math.Abs(got.Value(i)); i == 5 && d < 8 || i != 5 && d > 2 {
+			t.Errorf("row %d = %v", i, got.Value(i))
+		}
+	}
+
+	one := floats(pool, null, 7)
+	defer one.Release()
+	got1, err := Detrend(ctx, one)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got1.Release()
+	check(t, "one value", got1, null, 0)
+}
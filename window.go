@@ -0,0 +1,108 @@
+package supercharged
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+)
+
+// DetectAnomaliesWindowed scores each value against the mean and standard
+// deviation of the preceding window values rather than the whole column.
+// It is shorthand for WindowedDetector{Window: window, Threshold: threshold}.Detect.
+func DetectAnomaliesWindowed(ctx context.Context, col arrow.Array, window int, threshold float64) (*Result, error) {
+	return WindowedDetector{Window: window, Threshold: threshold}.Detect(ctx, col)
+}
+
+// WindowedDetector computes rolling z-scores over a trailing window of the
+// previous Window non-null values, so level shifts and trends only affect
+// scores until the window catches up. The current value is excluded from its
+// own window, and rows without a full window of history, or whose window
+// does not vary, get a null score and are never flagged.
+type WindowedDetector struct {
+	Window    int
+	Threshold float64
+}
+
+var _ Detector = WindowedDetector{}
+
+// Detect implements Detector.
+func (d WindowedDetector) Detect(ctx context.Context, col arrow.Array) (*Result, error) {
+	floatCol, err := toFloat64(ctx, col)
+	if err != nil {
+		return nil, err
+	}
+	defer floatCol.Release()
+	if d.Window < 2 {
+		return nil, fmt.Errorf("window must be at least 2, got %d", d.Window)
+	}
+
+	mem := compute.GetAllocator(ctx)
+	scores := array.NewFloat64Builder(mem)
+	defer scores.Release()
+	mask := array.NewBooleanBuilder(mem)
+	defer mask.Release()
+	scores.Reserve(floatCol.Len())
+	mask.Reserve(floatCol.Len())
+
+	ring := make([]float64, d.Window)
+	var w slidingStats
+	// run counts the trailing values equal to last.
+	var run int
+	var last float64
+	for i := 0; i < floatCol.Len(); i++ {
+		if floatCol.IsNull(i) {
+			scores.AppendNull()
+			mask.Append(false)
+			continue
+		}
+		x := floatCol.Value(i)
+		// The removals leave rounding in m2, so a flat window is told by
+		// the run of equal values rather than by a zero variance.
+		full := w.n == int64(d.Window)
+		if full && run < d.Window && w.variance() > 0 {
+			z := (x - w.mean) / math.Sqrt(w.variance())
+			scores.Append(z)
+			mask.Append(math.Abs(z) >= d.Threshold)
+		} else {
+			scores.AppendNull()
+			mask.Append(false)
+		}
+		if full {
+			w.remove(ring[w.head])
+		}
+		if run > 0 && x == last {
+			run++
+		} else {
+			run, last = 1, x
+		}
+		ring[w.head] = x
+		w.head = (w.head + 1) % d.Window
+		w.add(x)
+	}
+
+	return newResult(ctx, floatCol, mask.NewBooleanArray(), scores.NewFloat64Array(), accumulate(floatCol))
+}
+
+// slidingStats extends runningStats with removal so it can track a window.
+type slidingStats struct {
+	runningStats
+	head int
+}
+
+func (s *slidingStats) remove(x float64) {
+	if s.n <= 1 {
+		s.runningStats = runningStats{}
+		return
+	}
+	delta := x - s.mean
+	s.n--
+	s.mean -= delta / float64(s.n)
+	s.m2 -= delta * (x - s.mean)
+	if s.m2 < 0 {
+		s.m2 = 0
+	}
+}
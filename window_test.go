@@ -0,0 +1,91 @@
+package supercharged
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/compute"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+)
+
+func TestDetectAnomaliesWindowed(t *testing.T) {
+	pool := memory.NewGoAllocator()
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	// A level shift from ~10 to ~50 at index 20 followed by a spike at 35.
+	for i := 0; i < 40; i++ {
+		base := 10.0
+		if i >= 20 {
+			base = 50
+		}
+		v := base + float64(i%3) - 1
+		if i == 35 {
+			v = 80
+		}
+		b.Append(v)
+	}
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	res, err := DetectAnomaliesWindowed(context.Background(), col, 8, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+
+	var flagged []int
+	for i := 0; i < res.Mask.Len(); i++ {
+		if res.Mask.Value(i) {
+			flagged = append(flagged, i)
+		}
+	}
+	// The shift is flagged only until the window adapts; later points are not.
+	for _, i := range flagged {
+		if i != 35 && (i < 20 || i > 27) {
+			t.Errorf("unexpected anomaly at %d (flagged %v)", i, flagged)
+		}
+	}
+	if !res.Mask.Value(35) || !res.Mask.Value(20) {
+		t.Errorf("expected shift at 20 and spike at 35, flagged %v", flagged)
+	}
+	if res.Zscore.IsValid(7) || !res.Zscore.IsValid(8) {
+		t.Errorf("expected null scores only before the window fills")
+	}
+
+	if _, err := DetectAnomaliesWindowed(context.Background(), col, 1, 3); err == nil {
+		t.Errorf("expected error for window < 2")
+	}
+}
+
+func TestWindowedConstantRun(t *testing.T) {
+	pool := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer pool.AssertSize(t, 0)
+	ctx := compute.WithAllocator(context.Background(), pool)
+	b := array.NewFloat64Builder(pool)
+	defer b.Release()
+	// A flat stretch at 10, then a step to 20 and scattered values again.
+	b.AppendValues([]float64{1, 3, 2, 10, 10, 10, 10, 10, 20, 12, 14, 11}, nil)
+	col := b.NewFloat64Array()
+	defer col.Release()
+
+	res, err := DetectAnomaliesWindowed(ctx, col, 4, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Release()
+	// Rows 7 and 8 follow four 10s, so have no spread to be scored against.
+	for i := 0; i < res.Zscore.Len(); i++ {
+		flat := i == 7 || i == 8
+		if res.Zscore.IsValid(i) && (flat || math.IsInf(res.Zscore.Value(i), 0) || math.IsNaN(res.Zscore.Value(i))) {
+			t.Errorf("row %d: score %v, want null", i, res.Zscore.Value(i))
+		}
+		if flat && res.Mask.Value(i) {
+			t.Errorf("row %d flagged on a flat window", i)
+		}
+	}
+	if !res.Zscore.IsValid(9) {
+		t.Errorf("row 9: want a score once the window varies")
+	}
+}